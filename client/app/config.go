@@ -13,6 +13,7 @@ import (
 
 	"decred.org/dcrdex/client/core"
 	"decred.org/dcrdex/client/mm"
+	"decred.org/dcrdex/client/notify"
 	"decred.org/dcrdex/client/rpcserver"
 	"decred.org/dcrdex/client/webserver"
 	"decred.org/dcrdex/dex"
@@ -107,6 +108,9 @@ type CoreConfig struct {
 	NoAutoDBBackup     bool `long:"no-db-backup" description:"Disable creation of a database backup on shutdown."`
 	UnlockCoinsOnLogin bool `long:"release-wallet-coins" description:"On login or wallet creation, instruct the wallet to release any coins that it may have locked."`
 
+	RetainDays   int `long:"retaindays" description:"Move inactive orders older than this many days from the app database to the archive database. Archived orders can still be viewed. 0 keeps all orders."`
+	RetainOrders int `long:"retainorders" description:"Keep only this many of the most recent inactive orders per market in the app database, moving older orders to the archive database. 0 keeps all orders."`
+
 	ExtensionModeFile string `long:"extension-mode-file" description:"path to a file that specifies options for running core as an extension."`
 }
 
@@ -135,6 +139,12 @@ type MMConfig struct {
 	EventLogDBPath string `long:"eventLogDBPath"`
 }
 
+// NotifyConfig encapsulates the settings for outbound notifications.
+type NotifyConfig struct {
+	NotifyConfigPath string `long:"notifyconfig" description:"Path to a JSON file with the outbound notification sinks (webhooks, email, commands). Webhook and email sinks can also be configured from the web server settings. Command sinks can only be configured in this file."`
+	NotifyQueuePath  string `long:"notifyqueue" description:"Path to a file where undelivered outbound notifications are queued."`
+}
+
 // Config is the common application configuration definition. This composite
 // struct captures the configuration needed for core and both web and rpc
 // servers, as well as some application-level directives.
@@ -144,6 +154,7 @@ type Config struct {
 	WebConfig
 	LogConfig
 	MMConfig
+	NotifyConfig
 	// AppData and ConfigPath should be parsed from the command-line,
 	// as it makes no sense to set these in the config file itself. If no values
 	// are assigned, defaults will be used.
@@ -158,12 +169,15 @@ type Config struct {
 	CPUProfile string `long:"cpuprofile" description:"File for CPU profiling."`
 	ShowVer    bool   `short:"V" long:"version" description:"Display version information and exit"`
 	Language   string `long:"lang" description:"BCP 47 tag for preferred language, e.g. en-GB, fr, zh-CN"`
+	// Profiles are the names of additional profiles. Each profile has its own
+	// database, app password and seed.
+	Profiles []string `long:"profile" description:"Name of an additional profile with its own database, app password and seed. May be specified multiple times."`
 }
 
 // Web creates a configuration for the webserver. This is a Config method
 // instead of a WebConfig method because Language is an app-level setting used
 // by both core and rpcserver.
-func (cfg *Config) Web(c *core.Core, mm *mm.MarketMaker, log dex.Logger, utc bool) *webserver.Config {
+func (cfg *Config) Web(c *core.Core, mm *mm.MarketMaker, notifier *notify.Notifier, log dex.Logger, utc bool) *webserver.Config {
 	addr := cfg.WebAddr
 	host, _, err := net.SplitHostPort(addr)
 	if err == nil && host != "" {
@@ -179,6 +193,11 @@ func (cfg *Config) Web(c *core.Core, mm *mm.MarketMaker, log dex.Logger, utc boo
 		mmCore = mm
 	}
 
+	var notifierCore webserver.NotifierCore
+	if notifier != nil {
+		notifierCore = notifier
+	}
+
 	var certFile, keyFile string
 	if cfg.WebTLS || (ip != nil && !ip.IsLoopback() && !ip.IsPrivate()) || (ip == nil && addr != "localhost") {
 		certFile = filepath.Join(cfg.AppData, "web.cert")
@@ -189,6 +208,7 @@ func (cfg *Config) Web(c *core.Core, mm *mm.MarketMaker, log dex.Logger, utc boo
 		DataDir:         filepath.Join(cfg.AppData, "srv"),
 		Core:            c,
 		MarketMaker:     mmCore,
+		Notifier:        notifierCore,
 		Addr:            cfg.WebAddr,
 		CustomSiteDir:   cfg.SiteDir,
 		Logger:          log,
@@ -220,6 +240,8 @@ func (cfg *Config) Core(log dex.Logger) *core.Config {
 		NoAutoWalletLock:   cfg.NoAutoWalletLock,
 		NoAutoDBBackup:     cfg.NoAutoDBBackup,
 		ExtensionModeFile:  cfg.ExtensionModeFile,
+		RetainDays:         cfg.RetainDays,
+		RetainOrders:       cfg.RetainOrders,
 		TheOneHost:         cfg.TheOneHost,
 	}
 }
@@ -354,6 +376,33 @@ func ResolveConfig(appData string, cfg *Config) error {
 		cfg.MMConfig.EventLogDBPath = defaultMMEventLogDBPath
 	}
 
+	netDirectory := filepath.Dir(defaultDBPath)
+	if cfg.NotifyConfig.NotifyConfigPath == "" {
+		cfg.NotifyConfig.NotifyConfigPath = filepath.Join(netDirectory, "notify.json")
+	}
+
+	if cfg.NotifyConfig.NotifyQueuePath == "" {
+		cfg.NotifyConfig.NotifyQueuePath = filepath.Join(netDirectory, "notify_queue.json")
+	}
+
+	if cfg.RetainDays < 0 || cfg.RetainOrders < 0 {
+		return fmt.Errorf("retaindays and retainorders cannot be negative")
+	}
+
+	profiles := make(map[string]bool, len(cfg.Profiles))
+	for _, name := range cfg.Profiles {
+		if !profileNameRegexp.MatchString(name) {
+			return fmt.Errorf("invalid profile name %q. only letters, numbers, '-' and '_' are allowed", name)
+		}
+		if name == webserver.DefaultProfile {
+			return fmt.Errorf("profile name %q is reserved", name)
+		}
+		if profiles[name] {
+			return fmt.Errorf("duplicate profile %q", name)
+		}
+		profiles[name] = true
+	}
+
 	return nil
 }
 
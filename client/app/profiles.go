@@ -0,0 +1,136 @@
+// This code is available on the terms of the project LICENSE.md file,
+// also available online at https://blueoakcouncil.org/license/1.0.0.
+
+package app
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sync"
+
+	"decred.org/dcrdex/client/core"
+	"decred.org/dcrdex/client/mm"
+	"decred.org/dcrdex/client/notify"
+	"decred.org/dcrdex/client/rpcserver"
+	"decred.org/dcrdex/client/webserver"
+	"decred.org/dcrdex/dex"
+)
+
+// profileNameRegexp matches valid profile names, which are also used as
+// directory names.
+var profileNameRegexp = regexp.MustCompile(`^[a-zA-Z0-9_-]+$`)
+
+// Profile is an additional profile with its own Core, MarketMaker and
+// Notifier.
+type Profile struct {
+	Name        string
+	Core        *core.Core
+	MarketMaker *mm.MarketMaker
+	Notifier    *notify.Notifier
+}
+
+// Profiles are the additional profiles started with RunProfiles.
+type Profiles []*Profile
+
+// RPC creates the rpcserver profiles.
+func (ps Profiles) RPC() map[string]*rpcserver.Profile {
+	profiles := make(map[string]*rpcserver.Profile, len(ps))
+	for _, p := range ps {
+		profiles[p.Name] = &rpcserver.Profile{Core: p.Core, MarketMaker: p.MarketMaker}
+	}
+	return profiles
+}
+
+// Web creates the webserver profiles.
+func (ps Profiles) Web() map[string]*webserver.Profile {
+	profiles := make(map[string]*webserver.Profile, len(ps))
+	for _, p := range ps {
+		profiles[p.Name] = &webserver.Profile{Core: p.Core, MarketMaker: p.MarketMaker, Notifier: p.Notifier}
+	}
+	return profiles
+}
+
+// Profile creates a Config for the named profile. The database, market making
+// and notification files of the profile are in the profiles/[name] directory
+// next to the default profile's database, so wallets, accounts and
+// notification sinks are not shared between profiles.
+func (cfg *Config) Profile(name string) (*Config, error) {
+	if !profileNameRegexp.MatchString(name) {
+		return nil, fmt.Errorf("invalid profile name %q", name)
+	}
+	profileDir := filepath.Join(filepath.Dir(cfg.DBPath), "profiles", name)
+	if err := os.MkdirAll(profileDir, 0700); err != nil {
+		return nil, fmt.Errorf("failed to create profile directory: %w", err)
+	}
+	profileCfg := *cfg
+	profileCfg.Profiles = nil
+	profileCfg.DBPath = filepath.Join(profileDir, "dexc.db")
+	profileCfg.MMConfig = MMConfig{
+		BotConfigPath:  filepath.Join(profileDir, "mm_cfg.json"),
+		EventLogDBPath: filepath.Join(profileDir, "eventlog.db"),
+	}
+	profileCfg.NotifyConfigPath = filepath.Join(profileDir, "notify.json")
+	profileCfg.NotifyQueuePath = filepath.Join(profileDir, "notify_queue.json")
+	return &profileCfg, nil
+}
+
+// RunProfiles creates and runs the Core, MarketMaker and Notifier of each of
+// the profiles in cfg.Profiles. The returned WaitGroup is done when all of the
+// profiles have stopped after ctx is canceled.
+func RunProfiles(ctx context.Context, cfg *Config, logMaker *dex.LoggerMaker) (Profiles, *sync.WaitGroup, error) {
+	var wg sync.WaitGroup
+	profiles := make(Profiles, 0, len(cfg.Profiles))
+	for _, name := range cfg.Profiles {
+		profileCfg, err := cfg.Profile(name)
+		if err != nil {
+			return nil, &wg, err
+		}
+		c, err := core.New(profileCfg.Core(logMaker.Logger("CORE").SubLogger(name)))
+		if err != nil {
+			return nil, &wg, fmt.Errorf("error creating core for profile %q: %w", name, err)
+		}
+		marketMaker, err := mm.NewMarketMaker(c, profileCfg.MMConfig.EventLogDBPath,
+			profileCfg.MMConfig.BotConfigPath, logMaker.Logger("MM").SubLogger(name))
+		if err != nil {
+			return nil, &wg, fmt.Errorf("error creating market maker for profile %q: %w", name, err)
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			c.Run(ctx)
+		}()
+		<-c.Ready()
+
+		mmCM := dex.NewConnectionMaster(marketMaker)
+		if err := mmCM.ConnectOnce(ctx); err != nil {
+			return nil, &wg, fmt.Errorf("error connecting market maker for profile %q: %w", name, err)
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			mmCM.Wait()
+		}()
+
+		notifier, err := notify.New(c, profileCfg.NotifyConfigPath, profileCfg.NotifyQueuePath,
+			logMaker.Logger("NTFY").SubLogger(name))
+		if err != nil {
+			return nil, &wg, fmt.Errorf("error creating notifier for profile %q: %w", name, err)
+		}
+		notifierCM := dex.NewConnectionMaster(notifier)
+		if err := notifierCM.ConnectOnce(ctx); err != nil {
+			return nil, &wg, fmt.Errorf("error connecting notifier for profile %q: %w", name, err)
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			notifierCM.Wait()
+		}()
+
+		profiles = append(profiles, &Profile{Name: name, Core: c, MarketMaker: marketMaker, Notifier: notifier})
+	}
+	return profiles, &wg, nil
+}
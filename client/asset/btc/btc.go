@@ -75,6 +75,9 @@ const (
 	walletTypeRPC      = "bitcoindRPC"
 	walletTypeSPV      = "SPV"
 	walletTypeElectrum = "electrumRPC"
+	// walletTypeExternalSigner is a watch-only bitcoind wallet with an
+	// external signer.
+	walletTypeExternalSigner = "externalSigner"
 
 	swapFeeBumpKey      = "swapfeebump"
 	splitKey            = "swapsplit"
@@ -205,6 +208,16 @@ var (
 		MultiFundingOpts: MultiFundingOpts,
 	}
 
+	externalSignerWalletDefinition = &asset.WalletDefinition{
+		Type: walletTypeExternalSigner,
+		Tab:  "External signer",
+		Description: "Connect to a watch-only bitcoind wallet, and sign " +
+			"transactions with an external signer, such as a hardware wallet",
+		DefaultConfigPath: dexbtc.SystemConfigPath("bitcoin"),
+		ConfigOpts: append(append(RPCConfigOpts("Bitcoin", "8332"), ExternalSignerConfigOpts...),
+			CommonConfigOpts("BTC", false)...),
+	}
+
 	// WalletInfo defines some general information about a Bitcoin wallet.
 	WalletInfo = &asset.WalletInfo{
 		Name:              "Bitcoin",
@@ -214,6 +227,7 @@ var (
 			spvWalletDefinition,
 			rpcWalletDefinition,
 			electrumWalletDefinition,
+			externalSignerWalletDefinition,
 		},
 		LegacyWalletIndex: 1,
 	}
@@ -839,6 +853,12 @@ type baseWallet struct {
 	pendingTxsMtx sync.RWMutex
 	pendingTxs    map[chainhash.Hash]ExtendedWalletTx
 
+	// cpfpChildren are the child transactions broadcast by BumpFee, keyed by
+	// the parent tx hash. They are stored in the tx history, and restored
+	// from the unconfirmed txs on startup.
+	cpfpMtx      sync.Mutex
+	cpfpChildren map[chainhash.Hash]*cpfpChild
+
 	// receiveTxLastQuery stores the last block height at which the wallet
 	// was queried for recieve transactions. This is also stored in the
 	// txHistoryDB.
@@ -847,6 +867,8 @@ type baseWallet struct {
 	txHistoryDB atomic.Value // *BadgerTxDB
 
 	ar *AddressRecycler
+
+	coinCtl *coinControl
 }
 
 func (w *baseWallet) fallbackFeeRate() uint64 {
@@ -917,6 +939,8 @@ type ExchangeWalletCustom struct {
 var _ asset.Wallet = (*intermediaryWallet)(nil)
 var _ asset.Accelerator = (*ExchangeWalletAccelerator)(nil)
 var _ asset.Accelerator = (*ExchangeWalletSPV)(nil)
+var _ asset.FeeBumper = (*ExchangeWalletAccelerator)(nil)
+var _ asset.FeeBumper = (*ExchangeWalletSPV)(nil)
 var _ asset.Withdrawer = (*baseWallet)(nil)
 var _ asset.FeeRater = (*baseWallet)(nil)
 var _ asset.Rescanner = (*ExchangeWalletSPV)(nil)
@@ -932,6 +956,7 @@ var _ asset.Authenticator = (*ExchangeWalletCustom)(nil)
 var _ asset.AddressReturner = (*baseWallet)(nil)
 var _ asset.WalletHistorian = (*ExchangeWalletSPV)(nil)
 var _ asset.NewAddresser = (*baseWallet)(nil)
+var _ asset.CoinController = (*baseWallet)(nil)
 
 // RecoveryCfg is the information that is transferred from the old wallet
 // to the new one when the wallet is recovered.
@@ -1131,6 +1156,8 @@ func NewWallet(cfg *asset.WalletConfig, logger dex.Logger, net dex.Network) (ass
 		}
 		cloneCFG.MinElectrumVersion = *ver
 		return ElectrumWallet(cloneCFG)
+	case walletTypeExternalSigner:
+		return newExternalSignerWallet(cloneCFG)
 	default:
 		makeCustomWallet, ok := customWalletConstructors[cfg.Type]
 		if !ok {
@@ -1316,6 +1343,11 @@ func newUnconnectedWallet(cfg *BTCCloneCFG, walletCfg *WalletConfig) (*baseWalle
 		return nil, err
 	}
 
+	coinCtl, err := newCoinControl(filepath.Join(walletDir, "coin-control.json"), cfg.Logger)
+	if err != nil {
+		return nil, err
+	}
+
 	var feeCache *feeRateCache
 	if cfg.ExternalFeeEstimator != nil {
 		feeCache = &feeRateCache{
@@ -1351,8 +1383,10 @@ func newUnconnectedWallet(cfg *BTCCloneCFG, walletCfg *WalletConfig) (*baseWalle
 		txVersion:         txVersion,
 		Network:           cfg.Network,
 		pendingTxs:        make(map[chainhash.Hash]ExtendedWalletTx),
+		cpfpChildren:      make(map[chainhash.Hash]*cpfpChild),
 		walletDir:         walletDir,
 		ar:                addressRecyler,
+		coinCtl:           coinCtl,
 	}
 	w.cfgV.Store(baseCfg)
 
@@ -1495,6 +1529,7 @@ func (btc *baseWallet) setNode(node Wallet) {
 			return btc.stringAddr(addr, btc.chainParams)
 		},
 	)
+	btc.cm.SetFrozenFilter(btc.coinCtl.isFrozen)
 }
 
 func (btc *intermediaryWallet) prepareRedemptionFinder() {
@@ -1613,6 +1648,12 @@ func (btc *baseWallet) startTxHistoryDB(ctx context.Context) (*sync.WaitGroup, e
 	}
 	btc.pendingTxsMtx.Unlock()
 
+	for _, tx := range pendingTxs {
+		if txHash, err := chainhash.NewHashFromStr(tx.ID); err == nil {
+			btc.restoreCPFPChild(txHash, tx.WalletTransaction)
+		}
+	}
+
 	lastQuery, err := db.GetLastReceiveTxQuery()
 	if errors.Is(err, ErrNeverQueried) {
 		lastQuery = 0
@@ -2492,15 +2533,20 @@ func (btc *baseWallet) FundOrder(ord *asset.Order) (asset.Coins, []dex.Bytes, ui
 
 	reserves := btc.bondReserves.Load()
 	minConfs := uint32(0)
-	coins, fundingCoins, spents, redeemScripts, inputsSize, sum, err := btc.cm.Fund(reserves, minConfs, true,
-		orderEnough(ord.Value, ord.MaxSwapCount, bumpedMaxRate, btc.initTxSizeBase, btc.initTxSize, btc.segwit, useSplit))
+	fund := func() (asset.Coins, map[OutPoint]*UTxO, []*Output, []dex.Bytes, uint64, uint64, error) {
+		enough := orderEnough(ord.Value, ord.MaxSwapCount, bumpedMaxRate, btc.initTxSizeBase, btc.initTxSize, btc.segwit, useSplit)
+		if len(ord.Coins) > 0 { // restricted to coins chosen by the user
+			return btc.fundWithCoins(ord.Coins, true, enough)
+		}
+		return btc.cm.Fund(reserves, minConfs, true, enough)
+	}
+	coins, fundingCoins, spents, redeemScripts, inputsSize, sum, err := fund()
 	if err != nil {
-		if !useSplit && reserves > 0 {
+		if !useSplit && reserves > 0 && len(ord.Coins) == 0 {
 			// Force a split if funding failure may be due to reserves.
 			btc.log.Infof("Retrying order funding with a forced split transaction to help respect reserves.")
 			useSplit = true
-			coins, fundingCoins, spents, redeemScripts, inputsSize, sum, err = btc.cm.Fund(reserves, minConfs, true,
-				orderEnough(ord.Value, ord.MaxSwapCount, bumpedMaxRate, btc.initTxSizeBase, btc.initTxSize, btc.segwit, useSplit))
+			coins, fundingCoins, spents, redeemScripts, inputsSize, sum, err = fund()
 			extraSplitOutput = reserves + btc.BondsFeeBuffer(ord.FeeSuggestion)
 		}
 		if err != nil {
@@ -3535,6 +3581,239 @@ func (btc *ExchangeWalletSPV) PreAccelerate(swapCoins, accelerationCoins []dex.B
 	return preAccelerate(btc.baseWallet, swapCoins, accelerationCoins, changeCoin, requiredForRemainingSwaps, feeSuggestion)
 }
 
+// cpfpChild is a child transaction broadcast by BumpFee.
+type cpfpChild struct {
+	txHash *chainhash.Hash
+	fee    uint64
+	size   uint64
+}
+
+// AdditionalData keys for a cpfpChild's acceleration tx in the tx history.
+const (
+	cpfpParentKey = "cpfpParent"
+	cpfpSizeKey   = "cpfpSize"
+)
+
+// restoreCPFPChild restores the cpfpChild from an unconfirmed acceleration tx
+// in the tx history.
+func (btc *baseWallet) restoreCPFPChild(txHash *chainhash.Hash, wt *asset.WalletTransaction) {
+	if wt.Type != asset.Acceleration || wt.AdditionalData[cpfpParentKey] == "" {
+		return
+	}
+	parentHash, err := chainhash.NewHashFromStr(wt.AdditionalData[cpfpParentKey])
+	if err != nil {
+		btc.log.Errorf("Invalid CPFP parent txid for %s: %v", txHash, err)
+		return
+	}
+	size, err := strconv.ParseUint(wt.AdditionalData[cpfpSizeKey], 10, 64)
+	if err != nil {
+		btc.log.Errorf("Invalid CPFP tx size for %s: %v", txHash, err)
+		return
+	}
+	btc.cpfpMtx.Lock()
+	btc.cpfpChildren[*parentHash] = &cpfpChild{
+		txHash: txHash,
+		fee:    wt.Fees,
+		size:   size,
+	}
+	btc.cpfpMtx.Unlock()
+}
+
+// TxFeeRate returns the effective fee rate of the transaction that created
+// coinID, including any child transaction broadcast by BumpFee.
+func (btc *ExchangeWalletAccelerator) TxFeeRate(coinID dex.Bytes) (uint64, bool, error) {
+	return txFeeRate(btc.baseWallet, coinID)
+}
+
+// TxFeeRate returns the effective fee rate of the transaction that created
+// coinID, including any child transaction broadcast by BumpFee.
+func (btc *ExchangeWalletSPV) TxFeeRate(coinID dex.Bytes) (uint64, bool, error) {
+	return txFeeRate(btc.baseWallet, coinID)
+}
+
+// BumpFee raises the effective fee rate of the unconfirmed transaction that
+// created coinID to newFeeRate. If replace is true, a refund transaction that
+// signals replaceability is replaced. Otherwise, a child transaction spending
+// the coin back to the wallet is broadcast.
+func (btc *ExchangeWalletAccelerator) BumpFee(coinID, contract dex.Bytes, newFeeRate uint64, replace bool) (dex.Bytes, error) {
+	return bumpFee(btc.baseWallet, coinID, contract, newFeeRate, replace)
+}
+
+// BumpFee raises the effective fee rate of the unconfirmed transaction that
+// created coinID to newFeeRate. If replace is true, a refund transaction that
+// signals replaceability is replaced. Otherwise, a child transaction spending
+// the coin back to the wallet is broadcast.
+func (btc *ExchangeWalletSPV) BumpFee(coinID, contract dex.Bytes, newFeeRate uint64, replace bool) (dex.Bytes, error) {
+	return bumpFee(btc.baseWallet, coinID, contract, newFeeRate, replace)
+}
+
+// txFee returns the fees paid by a wallet transaction. The fees are taken from
+// the tx history if available, since the previous outputs of a redeem are not
+// wallet transactions.
+func (btc *baseWallet) txFee(tx *wire.MsgTx) (uint64, error) {
+	if txHistoryDB := btc.txDB(); txHistoryDB != nil {
+		wt, err := txHistoryDB.GetTx(btc.hashTx(tx).String())
+		if err == nil && wt.Fees > 0 {
+			return wt.Fees, nil
+		}
+	}
+	return btc.getTxFee(tx)
+}
+
+// unconfirmedWalletTx retrieves the transaction that created coinID, and
+// returns nil if it is already mined.
+func (btc *baseWallet) unconfirmedWalletTx(coinID dex.Bytes) (*wire.MsgTx, *chainhash.Hash, uint32, error) {
+	txHash, vout, err := decodeCoinID(coinID)
+	if err != nil {
+		return nil, nil, 0, err
+	}
+	gtr, err := btc.node.GetWalletTransaction(txHash)
+	if err != nil {
+		return nil, nil, 0, err
+	}
+	if gtr.Confirmations > 0 {
+		return nil, txHash, vout, nil
+	}
+	tx, err := btc.deserializeTx(gtr.Bytes)
+	if err != nil {
+		return nil, nil, 0, err
+	}
+	return tx, txHash, vout, nil
+}
+
+func txFeeRate(btc *baseWallet, coinID dex.Bytes) (uint64, bool, error) {
+	tx, txHash, _, err := btc.unconfirmedWalletTx(coinID)
+	if err != nil {
+		return 0, false, err
+	}
+	if tx == nil {
+		return 0, true, nil
+	}
+	fee, err := btc.txFee(tx)
+	if err != nil {
+		return 0, false, err
+	}
+	size := btc.calcTxSize(tx)
+	btc.cpfpMtx.Lock()
+	if child := btc.cpfpChildren[*txHash]; child != nil {
+		fee += child.fee
+		size += child.size
+	}
+	btc.cpfpMtx.Unlock()
+	return fee / size, false, nil
+}
+
+func bumpFee(btc *baseWallet, coinID, contract dex.Bytes, newFeeRate uint64, replace bool) (dex.Bytes, error) {
+	tx, txHash, vout, err := btc.unconfirmedWalletTx(coinID)
+	if err != nil {
+		return nil, err
+	}
+	if tx == nil {
+		return nil, fmt.Errorf("transaction %s is already mined", txHash)
+	}
+	fee, err := btc.txFee(tx)
+	if err != nil {
+		return nil, fmt.Errorf("error getting fees of %s: %w", txHash, err)
+	}
+	// A refund signaling replaceability (BIP 125) has a single input and
+	// output, and can be re-signed with the contract.
+	if replace && len(contract) > 0 && len(tx.TxIn) == 1 && len(tx.TxOut) == 1 &&
+		tx.TxIn[0].Sequence < wire.MaxTxInSequenceNum-1 {
+		return btc.replaceRefund(tx, txHash, fee, contract, newFeeRate)
+	}
+	return btc.cpfp(tx, txHash, vout, fee, newFeeRate)
+}
+
+// replaceRefund replaces a refund transaction with one paying newFeeRate to the
+// same address.
+func (btc *baseWallet) replaceRefund(tx *wire.MsgTx, txHash *chainhash.Hash, fee uint64, contract dex.Bytes, newFeeRate uint64) (dex.Bytes, error) {
+	if fee >= btc.calcTxSize(tx)*newFeeRate {
+		return nil, fmt.Errorf("refund %s already pays a fee rate of at least %d", txHash, newFeeRate)
+	}
+	_, addrs, _, err := txscript.ExtractPkScriptAddrs(tx.TxOut[0].PkScript, btc.chainParams)
+	if err != nil || len(addrs) != 1 {
+		return nil, fmt.Errorf("error decoding refund address of %s: %v", txHash, err)
+	}
+	val := fee + uint64(tx.TxOut[0].Value)
+	prevOut := tx.TxIn[0].PreviousOutPoint
+	newTx, err := btc.refundTx(&prevOut.Hash, prevOut.Index, contract, val, addrs[0], newFeeRate)
+	if err != nil {
+		return nil, fmt.Errorf("error creating replacement refund tx: %w", err)
+	}
+	newHash, err := btc.broadcastTx(newTx)
+	if err != nil {
+		return nil, err
+	}
+	btc.log.Infof("Replaced refund %s with %s at fee rate %d", txHash, newHash, newFeeRate)
+	btc.removeTxFromHistory(txHash)
+	btc.addTxToHistory(&asset.WalletTransaction{
+		Type:   asset.Refund,
+		ID:     newHash.String(),
+		Amount: val,
+		Fees:   val - uint64(newTx.TxOut[0].Value),
+	}, newHash, true)
+	return ToCoinID(newHash, 0), nil
+}
+
+// cpfp broadcasts a child transaction spending the vout output of the parent
+// to a new wallet address, paying enough fees to bring the effective fee rate
+// of both transactions to newFeeRate.
+func (btc *baseWallet) cpfp(parent *wire.MsgTx, parentHash *chainhash.Hash, vout uint32, parentFee, newFeeRate uint64) (dex.Bytes, error) {
+	if int(vout) >= len(parent.TxOut) {
+		return nil, fmt.Errorf("tx %s has no output %d", parentHash, vout)
+	}
+	btc.cpfpMtx.Lock()
+	defer btc.cpfpMtx.Unlock()
+	if child := btc.cpfpChildren[*parentHash]; child != nil {
+		// The output is spent by the previous child. Replacing the child
+		// would also work, but just let it ride.
+		return nil, fmt.Errorf("tx %s was already bumped by %s", parentHash, child.txHash)
+	}
+	parentSize := btc.calcTxSize(parent)
+	if parentFee >= parentSize*newFeeRate {
+		return nil, fmt.Errorf("tx %s already pays a fee rate of at least %d", parentHash, newFeeRate)
+	}
+	additionalFees := parentSize*newFeeRate - parentFee
+	output := NewOutput(parentHash, vout, uint64(parent.TxOut[vout].Value))
+	baseTx, totalIn, _, err := btc.fundedTx(asset.Coins{output})
+	if err != nil {
+		return nil, err
+	}
+	addr, err := btc.node.ExternalAddress()
+	if err != nil {
+		return nil, fmt.Errorf("error creating address: %w", err)
+	}
+	childTx, change, txFee, err := btc.signTxAndAddChange(baseTx, addr, totalIn, additionalFees, newFeeRate)
+	if err != nil {
+		return nil, err
+	}
+	if change == nil {
+		return nil, fmt.Errorf("output %s is too small to pay for a fee bump", output)
+	}
+	childHash, err := btc.broadcastTx(childTx)
+	if err != nil {
+		return nil, err
+	}
+	fees := txFee + additionalFees
+	childSize := btc.calcTxSize(childTx)
+	btc.cpfpChildren[*parentHash] = &cpfpChild{
+		txHash: childHash,
+		fee:    fees,
+		size:   childSize,
+	}
+	btc.log.Infof("Bumped fee rate of %s to %d with child %s", parentHash, newFeeRate, childHash)
+	btc.addTxToHistory(&asset.WalletTransaction{
+		Type: asset.Acceleration,
+		ID:   childHash.String(),
+		Fees: fees,
+		AdditionalData: map[string]string{
+			cpfpParentKey: parentHash.String(),
+			cpfpSizeKey:   strconv.FormatUint(childSize, 10),
+		},
+	}, childHash, true)
+	return ToCoinID(parentHash, vout), nil
+}
+
 // maxAccelerationRate returns the max rate to which an order can be
 // accelerated, if the max rate is less than rateNeeded. If the max rate is
 // greater than rateNeeded, rateNeeded is returned.
@@ -3862,10 +4141,22 @@ func (btc *baseWallet) Swap(swaps *asset.Swaps) ([]asset.Receipt, asset.Coin, ui
 	}
 	txHash := btc.hashTx(msgTx)
 
-	// Prepare the receipts.
+	// Prepare the receipts. Backup refund transactions are not prepared with
+	// an external signer, since each would be another signing request. The
+	// refund is signed if and when it is needed.
+	_, external := btc.node.(externalSigner)
 	receipts := make([]asset.Receipt, 0, swapCount)
 	for i, contract := range swaps.Contracts {
 		output := NewOutput(txHash, uint32(i), contract.Value)
+		receipt := &SwapReceipt{
+			Output:         output,
+			SwapContract:   contracts[i],
+			ExpirationTime: time.Unix(int64(contract.LockTime), 0).UTC(),
+		}
+		receipts = append(receipts, receipt)
+		if external {
+			continue
+		}
 		refundAddr := refundAddrs[i]
 		signedRefundTx, err := btc.refundTx(output.txHash(), output.vout(), contracts[i],
 			contract.Value, refundAddr, swaps.FeeRate)
@@ -3877,12 +4168,7 @@ func (btc *baseWallet) Swap(swaps *asset.Swaps) ([]asset.Receipt, asset.Coin, ui
 		if err != nil {
 			return nil, nil, 0, fmt.Errorf("error serializing refund tx: %w", err)
 		}
-		receipts = append(receipts, &SwapReceipt{
-			Output:            output,
-			SwapContract:      contracts[i],
-			ExpirationTime:    time.Unix(int64(contract.LockTime), 0).UTC(),
-			SignedRefundBytes: refundBuff.Bytes(),
-		})
+		receipt.SignedRefundBytes = refundBuff.Bytes()
 	}
 
 	// Refund txs prepared and signed. Can now broadcast the swap(s).
@@ -4032,13 +4318,12 @@ func (btc *baseWallet) Redeem(form *asset.RedeemForm) ([]dex.Bytes, asset.Coin,
 		// output, so we can provide a dummy that always returns a wire.TxOut
 		// with a nil pkScript that so IsPayToTaproot returns false.
 		sigHashes := txscript.NewTxSigHashes(msgTx, new(txscript.CannedPrevOutputFetcher))
+		redeemSigs, redeemPubKeys, err := btc.createWitnessSigs(msgTx, contracts, addresses, values, sigHashes)
+		if err != nil {
+			return nil, nil, 0, err
+		}
 		for i, r := range form.Redemptions {
-			contract := contracts[i]
-			redeemSig, redeemPubKey, err := btc.createWitnessSig(msgTx, i, contract, addresses[i], values[i], sigHashes)
-			if err != nil {
-				return nil, nil, 0, err
-			}
-			msgTx.TxIn[i].Witness = dexbtc.RedeemP2WSHContract(contract, redeemSig, redeemPubKey, r.Secret)
+			msgTx.TxIn[i].Witness = dexbtc.RedeemP2WSHContract(contracts[i], redeemSigs[i], redeemPubKeys[i], r.Secret)
 		}
 	} else {
 		for i, r := range form.Redemptions {
@@ -4114,6 +4399,13 @@ func (btc *baseWallet) SignMessage(coin asset.Coin, msg dex.Bytes) (pubkeys, sig
 	if utxo == nil {
 		return nil, nil, fmt.Errorf("no utxo found for %s", op)
 	}
+	if extSigner, is := btc.node.(externalSigner); is {
+		sig, pubkey, err := extSigner.signHash(utxo.Address, chainhash.HashB(msg))
+		if err != nil {
+			return nil, nil, err
+		}
+		return []dex.Bytes{pubkey}, []dex.Bytes{sig}, nil
+	}
 	privKey, err := btc.node.PrivKeyForAddress(utxo.Address)
 	if err != nil {
 		return nil, nil, err
@@ -4345,10 +4637,11 @@ func (btc *baseWallet) refundTx(txHash *chainhash.Hash, vout uint32, contract de
 	msgTx.LockTime = uint32(lockTime)
 	prevOut := wire.NewOutPoint(txHash, vout)
 	txIn := wire.NewTxIn(prevOut, []byte{}, nil)
-	// Enable the OP_CHECKLOCKTIMEVERIFY opcode to be used.
+	// Enable the OP_CHECKLOCKTIMEVERIFY opcode to be used, and signal
+	// replaceability so that the fee can be bumped.
 	//
 	// https://github.com/bitcoin/bips/blob/master/bip-0125.mediawiki#Spending_wallet_policy
-	txIn.Sequence = wire.MaxTxInSequenceNum - 1
+	txIn.Sequence = wire.MaxTxInSequenceNum - 2
 	msgTx.AddTxIn(txIn)
 	// Calculate fees and add the change output.
 
@@ -4385,11 +4678,12 @@ func (btc *baseWallet) refundTx(txHash *chainhash.Hash, vout uint32, contract de
 
 	if btc.segwit {
 		sigHashes := txscript.NewTxSigHashes(msgTx, new(txscript.CannedPrevOutputFetcher))
-		refundSig, refundPubKey, err := btc.createWitnessSig(msgTx, 0, contract, sender, int64(val), sigHashes)
+		refundSigs, refundPubKeys, err := btc.createWitnessSigs(msgTx, [][]byte{contract},
+			[]btcutil.Address{sender}, []int64{int64(val)}, sigHashes)
 		if err != nil {
-			return nil, fmt.Errorf("createWitnessSig: %w", err)
+			return nil, fmt.Errorf("createWitnessSigs: %w", err)
 		}
-		txIn.Witness = dexbtc.RefundP2WSHContract(contract, refundSig, refundPubKey)
+		txIn.Witness = dexbtc.RefundP2WSHContract(contract, refundSigs[0], refundPubKeys[0])
 
 	} else {
 		prevScript, err := btc.scriptHashScript(contract)
@@ -4420,7 +4714,7 @@ func (btc *baseWallet) DepositAddress() (string, error) {
 	if err != nil {
 		return "", err
 	}
-	if btc.node.Locked() {
+	if _, external := btc.node.(externalSigner); external || btc.node.Locked() {
 		return addrStr, nil
 	}
 
@@ -4504,7 +4798,7 @@ func (btc *baseWallet) AddressUsed(addrStr string) (bool, error) {
 // the value. feeRate is in units of sats/byte.
 // Withdraw satisfies asset.Withdrawer.
 func (btc *baseWallet) Withdraw(address string, value, feeRate uint64) (asset.Coin, error) {
-	txHash, vout, sent, err := btc.send(address, value, btc.feeRateWithFallback(feeRate), true)
+	txHash, vout, sent, err := btc.send(address, value, btc.feeRateWithFallback(feeRate), true, nil)
 	if err != nil {
 		return nil, err
 	}
@@ -4515,7 +4809,7 @@ func (btc *baseWallet) Withdraw(address string, value, feeRate uint64) (asset.Co
 // Withdraw, which subtracts the tx fees from the amount sent. feeRate is in
 // units of sats/byte.
 func (btc *baseWallet) Send(address string, value, feeRate uint64) (asset.Coin, error) {
-	txHash, vout, sent, err := btc.send(address, value, btc.feeRateWithFallback(feeRate), false)
+	txHash, vout, sent, err := btc.send(address, value, btc.feeRateWithFallback(feeRate), false, nil)
 	if err != nil {
 		return nil, err
 	}
@@ -4548,7 +4842,7 @@ func (btc *baseWallet) ValidateSecret(secret, secretHash []byte) bool {
 // send the value to the address, with the given fee rate. If subtract is true,
 // the fees will be subtracted from the value. If false, the fees are in
 // addition to the value. feeRate is in units of sats/byte.
-func (btc *baseWallet) send(address string, val uint64, feeRate uint64, subtract bool) (*chainhash.Hash, uint32, uint64, error) {
+func (btc *baseWallet) send(address string, val uint64, feeRate uint64, subtract bool, coinIDs []dex.Bytes) (*chainhash.Hash, uint32, uint64, error) {
 	addr, err := btc.decodeAddr(address, btc.chainParams)
 	if err != nil {
 		return nil, 0, 0, fmt.Errorf("invalid address: %s", address)
@@ -4571,8 +4865,14 @@ func (btc *baseWallet) send(address string, val uint64, feeRate uint64, subtract
 	}
 
 	enough := SendEnough(val, feeRate, subtract, uint64(baseSize), btc.segwit, true)
-	minConfs := uint32(0)
-	coins, _, _, _, inputsSize, _, err := btc.cm.Fund(btc.bondReserves.Load(), minConfs, false, enough)
+	var coins asset.Coins
+	var inputsSize uint64
+	if len(coinIDs) > 0 { // the coins were chosen by the user
+		coins, _, _, _, inputsSize, _, err = btc.fundWithCoins(coinIDs, false, enough)
+	} else {
+		minConfs := uint32(0)
+		coins, _, _, _, inputsSize, _, err = btc.cm.Fund(btc.bondReserves.Load(), minConfs, false, enough)
+	}
 	if err != nil {
 		return nil, 0, 0, fmt.Errorf("error funding transaction: %w", err)
 	}
@@ -4831,10 +5131,20 @@ func (btc *baseWallet) signTxAndAddChange(baseTx *wire.MsgTx, addr btcutil.Addre
 		return nil, nil, 0, fmt.Errorf(s, a...)
 	}
 
+	signTx := btc.node.SignTx
+	extSigner, external := btc.node.(externalSigner)
+	if external {
+		// Converge on the fees with placeholder signatures, and only send the
+		// final transaction to the external signer.
+		signTx = func(tx *wire.MsgTx) (*wire.MsgTx, error) {
+			return extSigner.stubSignTx(tx), nil
+		}
+	}
+
 	// Sign the transaction to get an initial size estimate and calculate whether
 	// a change output would be dust.
 	sigCycles := 1
-	msgTx, err := btc.node.SignTx(baseTx)
+	msgTx, err := signTx(baseTx)
 	if err != nil {
 		return makeErr("signing error: %v, raw tx: %x", err, btc.wireBytes(baseTx))
 	}
@@ -4879,7 +5189,7 @@ func (btc *baseWallet) signTxAndAddChange(baseTx *wire.MsgTx, addr btcutil.Addre
 		for {
 			// Sign the transaction with the change output and compute new size.
 			sigCycles++
-			msgTx, err = btc.node.SignTx(baseTx)
+			msgTx, err = signTx(baseTx)
 			if err != nil {
 				return makeErr("signing error: %v, raw tx: %x", err, btc.wireBytes(baseTx))
 			}
@@ -4920,6 +5230,13 @@ func (btc *baseWallet) signTxAndAddChange(baseTx *wire.MsgTx, addr btcutil.Addre
 			changeOutput.Value, btc.hashTx(msgTx))
 	}
 
+	if external {
+		msgTx, err = btc.node.SignTx(baseTx)
+		if err != nil {
+			return makeErr("signing error: %w, raw tx: %x", err, btc.wireBytes(baseTx))
+		}
+	}
+
 	txHash := btc.hashTx(msgTx)
 
 	fee := totalIn - totalOut
@@ -4993,6 +5310,24 @@ func (btc *baseWallet) createWitnessSig(tx *wire.MsgTx, idx int, pkScript []byte
 	return sig, privKey.PubKey().SerializeCompressed(), nil
 }
 
+// createWitnessSigs creates signatures for segwit swap contract inputs. The
+// contracts must be spent by the first len(contracts) inputs of the tx.
+func (btc *baseWallet) createWitnessSigs(tx *wire.MsgTx, contracts [][]byte, addrs []btcutil.Address,
+	vals []int64, sigHashes *txscript.TxSigHashes) (sigs, pubkeys [][]byte, err error) {
+	if extSigner, is := btc.node.(externalSigner); is {
+		// One signing request for all inputs.
+		return extSigner.signContractInputs(tx, contracts, addrs, vals)
+	}
+	sigs, pubkeys = make([][]byte, len(contracts)), make([][]byte, len(contracts))
+	for i, contract := range contracts {
+		sigs[i], pubkeys[i], err = btc.createWitnessSig(tx, i, contract, addrs[i], vals[i], sigHashes)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+	return sigs, pubkeys, nil
+}
+
 // ValidateAddress checks that the provided address is valid.
 func (btc *baseWallet) ValidateAddress(address string) bool {
 	_, err := btc.decodeAddr(address, btc.chainParams)
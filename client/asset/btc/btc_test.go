@@ -15,6 +15,7 @@ import (
 	"os"
 	"reflect"
 	"sort"
+	"strconv"
 	"strings"
 	"sync"
 	"testing"
@@ -194,6 +195,7 @@ type testData struct {
 	ownedAddresses    map[string]bool
 	ownsAddress       bool
 	locked            bool
+	addrInfo          *GetAddressInfoResult
 }
 
 func newTestData() *testData {
@@ -498,7 +500,16 @@ func (c *tRawRequester) RawRequest(_ context.Context, method string, params []js
 		return json.Marshal(resp)
 	case methodGetWalletInfo:
 		return json.Marshal(&GetWalletInfoResult{UnlockedUntil: nil /* unencrypted -> unlocked */})
+	case methodWalletProcessPSBT:
+		var b64 string
+		if err := json.Unmarshal(params[0], &b64); err != nil {
+			return nil, err
+		}
+		return json.Marshal(map[string]any{"psbt": b64, "complete": false})
 	case methodGetAddressInfo:
+		if c.addrInfo != nil {
+			return json.Marshal(c.addrInfo)
+		}
 		var addr string
 		err := json.Unmarshal(params[0], &addr)
 		if err != nil {
@@ -5450,6 +5461,123 @@ func testAccelerateOrder(t *testing.T, segwit bool, walletType string) {
 	}
 }
 
+func TestBumpFee(t *testing.T) {
+	w, node, shutdown := tNewWallet(true, walletTypeRPC)
+	defer shutdown()
+	wallet := &ExchangeWalletAccelerator{&ExchangeWalletFullNode{w, &authAddOn{w.node}}}
+	node.newAddress = tP2WPKHAddr
+	node.changeAddr = tP2WPKHAddr
+	node.signFunc = func(tx *wire.MsgTx) {
+		signFunc(tx, 0, true)
+	}
+
+	addWalletTx := func(tx *wire.MsgTx, confs uint64) {
+		b, _ := serializeMsgTx(tx)
+		node.getTransactionMap[tx.TxHash().String()] = &GetTransactionResult{Bytes: b, Confirmations: confs}
+	}
+	p2wpkh, _ := txscript.PayToAddrScript(btcAddr(true))
+
+	// A redemption paying 2 sats/vB is bumped with a child.
+	prevTx := makeRawTx([]dex.Bytes{p2wpkh}, []*wire.TxIn{dummyInput()})
+	prevTx.TxOut[0].Value = 1e6
+	prevHash := prevTx.TxHash()
+	addWalletTx(prevTx, 1)
+	parent := makeRawTx([]dex.Bytes{p2wpkh}, []*wire.TxIn{wire.NewTxIn(wire.NewOutPoint(&prevHash, 0), nil, nil)})
+	signFunc(parent, 0, true)
+	parentFee := dexbtc.MsgTxVBytes(parent) * 2
+	parent.TxOut[0].Value = int64(1e6 - parentFee)
+	parentHash := parent.TxHash()
+	addWalletTx(parent, 0)
+	coinID := ToCoinID(&parentHash, 0)
+
+	feeRate, confirmed, err := wallet.TxFeeRate(coinID)
+	if err != nil {
+		t.Fatalf("TxFeeRate error: %v", err)
+	}
+	if confirmed || feeRate != 2 {
+		t.Fatalf("wrong fee rate %d, confirmed = %t", feeRate, confirmed)
+	}
+	// Already paying enough.
+	if _, err := wallet.BumpFee(coinID, nil, 2, false); err == nil {
+		t.Fatalf("no error for bumping to the current rate")
+	}
+	newCoinID, err := wallet.BumpFee(coinID, nil, 50, true)
+	if err != nil {
+		t.Fatalf("BumpFee error: %v", err)
+	}
+	if !bytes.Equal(newCoinID, coinID) {
+		t.Fatalf("coin ID changed for CPFP")
+	}
+	child := node.sentRawTx
+	if child == nil || child.TxIn[0].PreviousOutPoint.Hash != parentHash {
+		t.Fatalf("child tx not sent")
+	}
+	if feeRate, _, _ = wallet.TxFeeRate(coinID); feeRate < 50 {
+		t.Fatalf("effective fee rate %d < 50", feeRate)
+	}
+	if _, err := wallet.BumpFee(coinID, nil, 100, false); err == nil {
+		t.Fatalf("no error for bumping twice")
+	}
+	// The child is restored from its tx history entry after a restart.
+	storedChild := wallet.cpfpChildren[parentHash]
+	childHash := child.TxHash()
+	wallet.cpfpChildren = make(map[chainhash.Hash]*cpfpChild)
+	wallet.restoreCPFPChild(&childHash, &asset.WalletTransaction{
+		Type: asset.Acceleration,
+		ID:   childHash.String(),
+		Fees: storedChild.fee,
+		AdditionalData: map[string]string{
+			cpfpParentKey: parentHash.String(),
+			cpfpSizeKey:   strconv.FormatUint(storedChild.size, 10),
+		},
+	})
+	if restored := wallet.cpfpChildren[parentHash]; restored == nil || *restored.txHash != childHash ||
+		restored.fee != storedChild.fee || restored.size != storedChild.size {
+		t.Fatalf("CPFP child not restored")
+	}
+	// Mined.
+	node.getTransactionMap[parentHash.String()].Confirmations = 1
+	if _, confirmed, _ = wallet.TxFeeRate(coinID); !confirmed {
+		t.Fatalf("mined tx not confirmed")
+	}
+	if _, err := wallet.BumpFee(coinID, nil, 100, false); err == nil {
+		t.Fatalf("no error for bumping a mined tx")
+	}
+
+	// A refund is replaced.
+	_, _, pkScript, contract, _, _, _ := makeSwapContract(true, -time.Hour)
+	privBytes, _ := hex.DecodeString("b07209eec1a8fb6cfe5cb6ace36567406971a75c330db7101fb21bc679bc5330")
+	privKey, _ := btcec.PrivKeyFromBytes(privBytes)
+	node.privKeyForAddr, _ = btcutil.NewWIF(privKey, &chaincfg.MainNetParams, true)
+	swapTx := makeRawTx([]dex.Bytes{pkScript}, []*wire.TxIn{dummyInput()})
+	swapTx.TxOut[0].Value = 1e6
+	swapHash := swapTx.TxHash()
+	addWalletTx(swapTx, 1)
+	refundTx, err := wallet.refundTx(&swapHash, 0, contract, 1e6, btcAddr(true), 2)
+	if err != nil {
+		t.Fatalf("refundTx error: %v", err)
+	}
+	refundHash := refundTx.TxHash()
+	addWalletTx(refundTx, 0)
+	refundCoinID := ToCoinID(&refundHash, 0)
+	newCoinID, err = wallet.BumpFee(refundCoinID, contract, 50, true)
+	if err != nil {
+		t.Fatalf("BumpFee error for refund: %v", err)
+	}
+	replacement := node.sentRawTx
+	replacementHash := replacement.TxHash()
+	if replacementHash == refundHash || !bytes.Equal(newCoinID, ToCoinID(&replacementHash, 0)) {
+		t.Fatalf("refund not replaced")
+	}
+	if replacement.TxIn[0].PreviousOutPoint != refundTx.TxIn[0].PreviousOutPoint ||
+		!bytes.Equal(replacement.TxOut[0].PkScript, refundTx.TxOut[0].PkScript) {
+		t.Fatalf("replacement spends or pays differently")
+	}
+	if fee := 1e6 - replacement.TxOut[0].Value; uint64(fee) < dexbtc.MsgTxVBytes(replacement)*50 {
+		t.Fatalf("replacement fee %d too low", fee)
+	}
+}
+
 func TestGetTxFee(t *testing.T) {
 	runRubric(t, testGetTxFee)
 }
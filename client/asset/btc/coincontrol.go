@@ -0,0 +1,235 @@
+// This code is available on the terms of the project LICENSE.md file,
+// also available online at https://blueoakcouncil.org/license/1.0.0.
+
+package btc
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"sync"
+
+	"decred.org/dcrdex/client/asset"
+	"decred.org/dcrdex/dex"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+)
+
+// maxUTXOLabelLen is the maximum length of a UTXO label.
+const maxUTXOLabelLen = 100
+
+// coinMeta is the user-defined metadata for a UTXO.
+type coinMeta struct {
+	TxID   string `json:"txid"`
+	Vout   uint32 `json:"vout"`
+	Label  string `json:"label,omitempty"`
+	Frozen bool   `json:"frozen,omitempty"`
+}
+
+// coinControl stores UTXO labels and frozen coins, persisted as JSON in the
+// wallet directory.
+type coinControl struct {
+	path string
+	log  dex.Logger
+
+	mtx   sync.RWMutex
+	coins map[OutPoint]*coinMeta
+}
+
+// newCoinControl loads any stored coin metadata from the file at path.
+func newCoinControl(path string, log dex.Logger) (*coinControl, error) {
+	cc := &coinControl{
+		path:  path,
+		log:   log,
+		coins: make(map[OutPoint]*coinMeta),
+	}
+	b, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return cc, nil
+		}
+		return nil, fmt.Errorf("error reading coin control file: %w", err)
+	}
+	var metas []*coinMeta
+	if err := json.Unmarshal(b, &metas); err != nil {
+		return nil, fmt.Errorf("error decoding coin control file: %w", err)
+	}
+	for _, m := range metas {
+		txHash, err := chainhash.NewHashFromStr(m.TxID)
+		if err != nil {
+			return nil, fmt.Errorf("invalid txid %q in coin control file: %w", m.TxID, err)
+		}
+		cc.coins[NewOutPoint(txHash, m.Vout)] = m
+	}
+	return cc, nil
+}
+
+// store writes the coin metadata to file. The mtx must be held.
+func (cc *coinControl) store() error {
+	metas := make([]*coinMeta, 0, len(cc.coins))
+	for _, m := range cc.coins {
+		metas = append(metas, m)
+	}
+	sort.Slice(metas, func(i, j int) bool {
+		if metas[i].TxID == metas[j].TxID {
+			return metas[i].Vout < metas[j].Vout
+		}
+		return metas[i].TxID < metas[j].TxID
+	})
+	b, err := json.MarshalIndent(metas, "", "    ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(cc.path, b, 0600)
+}
+
+// update modifies the metadata for the outpoints, dropping entries with no
+// label that are not frozen, and writes the result to file.
+func (cc *coinControl) update(pts []OutPoint, f func(*coinMeta)) error {
+	cc.mtx.Lock()
+	defer cc.mtx.Unlock()
+	for _, pt := range pts {
+		m := cc.coins[pt]
+		if m == nil {
+			m = &coinMeta{TxID: pt.TxHash.String(), Vout: pt.Vout}
+		}
+		f(m)
+		if m.Label == "" && !m.Frozen {
+			delete(cc.coins, pt)
+		} else {
+			cc.coins[pt] = m
+		}
+	}
+	return cc.store()
+}
+
+// meta returns a copy of the metadata for the outpoint.
+func (cc *coinControl) meta(pt OutPoint) coinMeta {
+	cc.mtx.RLock()
+	defer cc.mtx.RUnlock()
+	if m := cc.coins[pt]; m != nil {
+		return *m
+	}
+	return coinMeta{}
+}
+
+// isFrozen checks whether the outpoint is frozen.
+func (cc *coinControl) isFrozen(pt OutPoint) bool {
+	cc.mtx.RLock()
+	defer cc.mtx.RUnlock()
+	m := cc.coins[pt]
+	return m != nil && m.Frozen
+}
+
+// decodeCoinIDs converts the coin IDs to outpoints.
+func decodeCoinIDs(coinIDs []dex.Bytes) ([]OutPoint, error) {
+	pts := make([]OutPoint, 0, len(coinIDs))
+	for _, coinID := range coinIDs {
+		txHash, vout, err := decodeCoinID(coinID)
+		if err != nil {
+			return nil, err
+		}
+		pts = append(pts, NewOutPoint(txHash, vout))
+	}
+	return pts, nil
+}
+
+// fundWithCoins attempts to satisfy the EnoughFunc with a subset of the
+// specified coins. Bond reserves are not considered.
+func (btc *baseWallet) fundWithCoins(coinIDs []dex.Bytes, lock bool, enough EnoughFunc) (
+	asset.Coins, map[OutPoint]*UTxO, []*Output, []dex.Bytes, uint64, uint64, error) {
+	pts, err := decodeCoinIDs(coinIDs)
+	if err != nil {
+		return nil, nil, nil, nil, 0, 0, err
+	}
+	utxos, err := btc.cm.SelectedUTXOs(pts)
+	if err != nil {
+		return nil, nil, nil, nil, 0, 0, err
+	}
+	return btc.cm.FundWithUTXOs(utxos, 0, lock, enough)
+}
+
+// ListUTXOs lists the wallet's unspent outputs, including those that are
+// frozen or locked. Part of the asset.CoinController interface.
+func (btc *baseWallet) ListUTXOs() ([]*asset.WalletUTXO, error) {
+	unspents, err := btc.node.ListUnspent()
+	if err != nil {
+		return nil, err
+	}
+	utxos := make([]*asset.WalletUTXO, 0, len(unspents))
+	unspent := make(map[OutPoint]bool, len(unspents))
+	add := func(txHash *chainhash.Hash, vout uint32, addr string, amt uint64, confs uint32, locked bool) {
+		pt := NewOutPoint(txHash, vout)
+		if unspent[pt] {
+			return
+		}
+		unspent[pt] = true
+		m := btc.coinCtl.meta(pt)
+		utxos = append(utxos, &asset.WalletUTXO{
+			CoinID:  ToCoinID(txHash, vout),
+			TxID:    txHash.String(),
+			Vout:    vout,
+			Address: addr,
+			Amount:  amt,
+			Confs:   confs,
+			Label:   m.Label,
+			Frozen:  m.Frozen,
+			Locked:  locked,
+		})
+	}
+	for _, u := range unspents {
+		if !u.Spendable {
+			continue
+		}
+		txHash, err := chainhash.NewHashFromStr(u.TxID)
+		if err != nil {
+			return nil, fmt.Errorf("error decoding txid in ListUnspentResult: %w", err)
+		}
+		pt := NewOutPoint(txHash, u.Vout)
+		add(txHash, u.Vout, u.Address, toSatoshi(u.Amount), u.Confirmations, btc.cm.LockedOutput(pt) != nil)
+	}
+	for _, u := range btc.cm.LockedUTXOs() {
+		add(u.TxHash, u.Vout, u.Address, u.Amount, 0, true)
+	}
+	sort.Slice(utxos, func(i, j int) bool { return utxos[i].Amount > utxos[j].Amount })
+	return utxos, nil
+}
+
+// SetUTXOLabel sets the label for the coin. An empty label removes any
+// existing label. Part of the asset.CoinController interface.
+func (btc *baseWallet) SetUTXOLabel(coinID dex.Bytes, label string) error {
+	if len(label) > maxUTXOLabelLen {
+		return fmt.Errorf("label exceeds %d characters", maxUTXOLabelLen)
+	}
+	pts, err := decodeCoinIDs([]dex.Bytes{coinID})
+	if err != nil {
+		return err
+	}
+	return btc.coinCtl.update(pts, func(m *coinMeta) { m.Label = label })
+}
+
+// FreezeUTXOs freezes or unfreezes the coins. Frozen coins are excluded from
+// automatic coin selection. Part of the asset.CoinController interface.
+func (btc *baseWallet) FreezeUTXOs(coinIDs []dex.Bytes, freeze bool) error {
+	pts, err := decodeCoinIDs(coinIDs)
+	if err != nil {
+		return err
+	}
+	return btc.coinCtl.update(pts, func(m *coinMeta) { m.Frozen = freeze })
+}
+
+// SendWithCoins sends the value to the address, spending only from the
+// specified coins. Frozen coins may be spent if they are specified explicitly.
+// Bond reserves are not enforced for the chosen coins. feeRate is in units of
+// sats/byte. Part of the asset.CoinController interface.
+func (btc *baseWallet) SendWithCoins(address string, value, feeRate uint64, subtract bool, coinIDs []dex.Bytes) (asset.Coin, error) {
+	if len(coinIDs) == 0 {
+		return nil, errors.New("no coins specified")
+	}
+	txHash, vout, sent, err := btc.send(address, value, btc.feeRateWithFallback(feeRate), subtract, coinIDs)
+	if err != nil {
+		return nil, err
+	}
+	return NewOutput(txHash, vout, sent), nil
+}
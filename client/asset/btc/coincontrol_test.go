@@ -0,0 +1,148 @@
+//go:build !spvlive && !harness
+
+package btc
+
+import (
+	"path/filepath"
+	"testing"
+
+	"decred.org/dcrdex/client/asset"
+	"decred.org/dcrdex/dex"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcd/wire"
+)
+
+func TestCoinControl(t *testing.T) {
+	wallet, node, shutdown := tNewWallet(true, walletTypeRPC)
+	defer shutdown()
+
+	node.signFunc = func(tx *wire.MsgTx) {
+		signFunc(tx, 0, true)
+	}
+	node.setTxFee = true
+	node.changeAddr = btcAddr(true).String()
+	node.listLockUnspent = []*RPCOutpoint{}
+
+	addr := btcAddr(true)
+	pkScript, _ := txscript.PayToAddrScript(addr)
+	var coinIDs []dex.Bytes
+	var txHashes []chainhash.Hash
+	for i, amt := range []float64{1, 2, 5} {
+		txHash := chainhash.Hash{byte(i + 1)}
+		txHashes = append(txHashes, txHash)
+		coinIDs = append(coinIDs, ToCoinID(&txHash, 0))
+		node.listUnspent = append(node.listUnspent, &ListUnspentResult{
+			TxID:          txHash.String(),
+			Address:       addr.String(),
+			Amount:        amt,
+			Confirmations: 1,
+			ScriptPubKey:  pkScript,
+			SafePtr:       boolPtr(true),
+			Spendable:     true,
+		})
+	}
+	coinA, coinB, coinC := coinIDs[0], coinIDs[1], coinIDs[2]
+
+	if err := wallet.SetUTXOLabel(coinA, "cold storage"); err != nil {
+		t.Fatalf("SetUTXOLabel error: %v", err)
+	}
+	if err := wallet.FreezeUTXOs([]dex.Bytes{coinC}, true); err != nil {
+		t.Fatalf("FreezeUTXOs error: %v", err)
+	}
+
+	utxos, err := wallet.ListUTXOs()
+	if err != nil {
+		t.Fatalf("ListUTXOs error: %v", err)
+	}
+	if len(utxos) != 3 {
+		t.Fatalf("expected 3 utxos, got %d", len(utxos))
+	}
+	for _, u := range utxos {
+		switch u.CoinID.String() {
+		case coinA.String():
+			if u.Label != "cold storage" || u.Frozen {
+				t.Fatalf("wrong metadata for coin A: %+v", u)
+			}
+		case coinC.String():
+			if !u.Frozen || u.Amount != toSatoshi(5) {
+				t.Fatalf("wrong metadata for coin C: %+v", u)
+			}
+		}
+	}
+
+	// Only the frozen coin is large enough.
+	if _, err := wallet.Send(addr.String(), toSatoshi(4), defaultFee); err == nil {
+		t.Fatalf("no error sending with only a frozen coin large enough")
+	}
+
+	// The frozen coin can be spent explicitly.
+	coin, err := wallet.SendWithCoins(addr.String(), toSatoshi(4), defaultFee, false, []dex.Bytes{coinC})
+	if err != nil {
+		t.Fatalf("SendWithCoins error: %v", err)
+	}
+	if coin.Value() != toSatoshi(4) {
+		t.Fatalf("wrong sent value %d", coin.Value())
+	}
+	if len(node.sentRawTx.TxIn) != 1 || node.sentRawTx.TxIn[0].PreviousOutPoint.Hash != txHashes[2] {
+		t.Fatalf("wrong inputs for send with coins")
+	}
+
+	// Not enough in the chosen coins.
+	if _, err := wallet.SendWithCoins(addr.String(), toSatoshi(4), defaultFee, false, []dex.Bytes{coinA, coinB}); err == nil {
+		t.Fatalf("no error for insufficient chosen coins")
+	}
+
+	// Unknown coin.
+	unknownHash := chainhash.Hash{0xff}
+	if _, err := wallet.SendWithCoins(addr.String(), toSatoshi(1), defaultFee, false, []dex.Bytes{ToCoinID(&unknownHash, 0)}); err == nil {
+		t.Fatalf("no error for an unknown coin")
+	}
+
+	// Order funding from chosen coins.
+	ord := &asset.Order{
+		Value:         toSatoshi(0.5),
+		MaxSwapCount:  1,
+		MaxFeeRate:    tBTC.MaxFeeRate,
+		FeeSuggestion: 10,
+		Coins:         []dex.Bytes{coinB},
+	}
+	coins, _, _, err := wallet.FundOrder(ord)
+	if err != nil {
+		t.Fatalf("FundOrder error: %v", err)
+	}
+	if len(coins) != 1 || coins[0].ID().String() != coinB.String() {
+		t.Fatalf("order not funded with the chosen coin")
+	}
+	// The locked coin is listed as locked.
+	utxos, _ = wallet.ListUTXOs()
+	for _, u := range utxos {
+		if u.CoinID.String() == coinB.String() && !u.Locked {
+			t.Fatalf("funding coin not locked")
+		}
+	}
+	// Chosen coins that are already locked are rejected.
+	if _, _, _, err := wallet.FundOrder(ord); err == nil {
+		t.Fatalf("no error funding with a locked coin")
+	}
+
+	// Labels and frozen coins are persisted.
+	cc, err := newCoinControl(filepath.Join(wallet.walletDir, "coin-control.json"), tLogger)
+	if err != nil {
+		t.Fatalf("error loading coin control file: %v", err)
+	}
+	if !cc.isFrozen(NewOutPoint(&txHashes[2], 0)) || cc.meta(NewOutPoint(&txHashes[0], 0)).Label != "cold storage" {
+		t.Fatalf("coin metadata not persisted")
+	}
+
+	// Unfreezing and clearing the label removes the entries.
+	if err := wallet.FreezeUTXOs([]dex.Bytes{coinC}, false); err != nil {
+		t.Fatalf("FreezeUTXOs error: %v", err)
+	}
+	if err := wallet.SetUTXOLabel(coinA, ""); err != nil {
+		t.Fatalf("SetUTXOLabel error: %v", err)
+	}
+	if len(wallet.coinCtl.coins) != 0 {
+		t.Fatalf("expected no coin metadata, got %d entries", len(wallet.coinCtl.coins))
+	}
+}
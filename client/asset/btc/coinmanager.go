@@ -53,6 +53,9 @@ type CoinManager struct {
 	listLocked  func() ([]*RPCOutpoint, error)
 	getTxOut    func(txHash *chainhash.Hash, vout uint32) (*wire.TxOut, error)
 	stringAddr  func(btcutil.Address) (string, error)
+	// isFrozen, if set, identifies coins that the user has excluded from
+	// automatic coin selection.
+	isFrozen func(OutPoint) bool
 
 	lockedOutputs map[OutPoint]*UTxO
 }
@@ -338,6 +341,12 @@ func (c *CoinManager) SpendableUTXOs(confs uint32) ([]*CompositeUTXO, map[OutPoi
 }
 
 func (c *CoinManager) spendableUTXOs(confs uint32) ([]*CompositeUTXO, map[OutPoint]*CompositeUTXO, uint64, error) {
+	return c.unlockedUTXOs(confs, false)
+}
+
+// unlockedUTXOs is like spendableUTXOs, but frozen coins are only excluded if
+// withFrozen is false.
+func (c *CoinManager) unlockedUTXOs(confs uint32, withFrozen bool) ([]*CompositeUTXO, map[OutPoint]*CompositeUTXO, uint64, error) {
 	unspents, err := c.listUnspent()
 	if err != nil {
 		return nil, nil, 0, err
@@ -359,6 +368,9 @@ func (c *CoinManager) spendableUTXOs(confs uint32) ([]*CompositeUTXO, map[OutPoi
 			c.log.Warnf("Known order-funding coin %s returned by listunspent!", pt)
 			delete(utxoMap, pt)
 			relock = append(relock, &Output{pt, utxo.Amount})
+		} else if !withFrozen && c.isFrozen != nil && c.isFrozen(pt) {
+			delete(utxoMap, pt)
+			sum -= utxo.Amount
 		} else { // in-place filter maintaining order
 			utxos[i] = utxo
 			i++
@@ -373,6 +385,37 @@ func (c *CoinManager) spendableUTXOs(confs uint32) ([]*CompositeUTXO, map[OutPoi
 	return utxos, utxoMap, sum, nil
 }
 
+// SetFrozenFilter sets the function used to identify frozen coins, which are
+// excluded from automatic coin selection.
+func (c *CoinManager) SetFrozenFilter(isFrozen func(OutPoint) bool) {
+	c.mtx.Lock()
+	c.isFrozen = isFrozen
+	c.mtx.Unlock()
+}
+
+// SelectedUTXOs returns the unlocked UTXOs for the specified outpoints, e.g.
+// for funding with coins chosen by the user. Frozen coins are included. An
+// error is returned if any of the outpoints is not an unlocked UTXO.
+func (c *CoinManager) SelectedUTXOs(pts []OutPoint) ([]*CompositeUTXO, error) {
+	c.mtx.RLock()
+	defer c.mtx.RUnlock()
+	_, utxoMap, _, err := c.unlockedUTXOs(0, true)
+	if err != nil {
+		return nil, err
+	}
+	utxos := make([]*CompositeUTXO, 0, len(pts))
+	for _, pt := range pts {
+		utxo, found := utxoMap[pt]
+		if !found {
+			return nil, fmt.Errorf("coin %s is not an unlocked wallet UTXO", pt)
+		}
+		delete(utxoMap, pt) // no duplicates
+		utxos = append(utxos, utxo)
+	}
+	sort.Slice(utxos, func(i, j int) bool { return utxos[i].Amount < utxos[j].Amount })
+	return utxos, nil
+}
+
 // ReturnCoins makes the locked utxos available for use again.
 func (c *CoinManager) ReturnCoins(unspents asset.Coins) error {
 	if unspents == nil { // not just empty to make this harder to do accidentally
@@ -556,6 +599,17 @@ func (c *CoinManager) UnlockOutPoints(pts []OutPoint) {
 	c.mtx.Unlock()
 }
 
+// LockedUTXOs returns the utxos currently locked by the CoinManager.
+func (c *CoinManager) LockedUTXOs() []*UTxO {
+	c.mtx.RLock()
+	defer c.mtx.RUnlock()
+	utxos := make([]*UTxO, 0, len(c.lockedOutputs))
+	for _, utxo := range c.lockedOutputs {
+		utxos = append(utxos, utxo)
+	}
+	return utxos
+}
+
 // LockedOutput returns the currently locked utxo represented by the provided
 // outpoint, or nil if there is no record of the utxo in the local map.
 func (c *CoinManager) LockedOutput(pt OutPoint) *UTxO {
@@ -0,0 +1,481 @@
+// This code is available on the terms of the project LICENSE.md file,
+// also available online at https://blueoakcouncil.org/license/1.0.0.
+
+package btc
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"decred.org/dcrdex/client/asset"
+	"decred.org/dcrdex/dex"
+	"decred.org/dcrdex/dex/config"
+	"github.com/btcsuite/btcd/btcec/v2"
+	"github.com/btcsuite/btcd/btcec/v2/ecdsa"
+	"github.com/btcsuite/btcd/btcutil"
+	"github.com/btcsuite/btcd/btcutil/psbt"
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcd/wire"
+)
+
+const (
+	// defaultSignerTimeout is how long to wait for the external signer if the
+	// signertimeout setting is not set.
+	defaultSignerTimeout = 10 * time.Minute
+)
+
+var (
+	// signingJobWait is how long Swap, Redeem and Refund wait for a signature
+	// before returning asset.ErrSignaturePending.
+	signingJobWait = 3 * time.Second
+
+	errNoPrivateKeys = errors.New("private keys are held by the external signer")
+
+	// ExternalSignerConfigOpts are the options for the external signer.
+	ExternalSignerConfigOpts = []*asset.ConfigOption{
+		{
+			Key:         "signerdir",
+			DisplayName: "Signer Directory",
+			Description: "Directory for exchanging PSBTs with the signer. Unsigned " +
+				"transactions are written as <txid>.psbt, and the signer should " +
+				"write <txid>.signed.psbt.",
+		},
+		{
+			Key:         "signercmd",
+			DisplayName: "Signer Command",
+			Description: "Signer command line, e.g. 'hwi -f <fingerprint>', that " +
+				"implements 'signtx <psbt>' and 'signhash <hash> <path>'. Used " +
+				"instead of the signer directory if set.",
+		},
+		{
+			Key:          "signertimeout",
+			DisplayName:  "Signer Timeout",
+			Description:  "Minutes to wait for a signature before giving up on a transaction.",
+			DefaultValue: uint64(defaultSignerTimeout / time.Minute),
+		},
+	}
+)
+
+// ExternalSignerConfig is the configuration for the external signer.
+type ExternalSignerConfig struct {
+	SignerDir     string `ini:"signerdir"`
+	SignerCommand string `ini:"signercmd"`
+	// SignerTimeout is in minutes.
+	SignerTimeout uint64 `ini:"signertimeout"`
+}
+
+// newSigner creates the Signer and parses the timeout from the wallet
+// settings.
+func newSigner(settings map[string]string, log dex.Logger) (Signer, time.Duration, error) {
+	cfg := new(ExternalSignerConfig)
+	if err := config.Unmapify(settings, cfg); err != nil {
+		return nil, 0, fmt.Errorf("error parsing external signer config: %w", err)
+	}
+	timeout := defaultSignerTimeout
+	if cfg.SignerTimeout > 0 {
+		timeout = time.Duration(cfg.SignerTimeout) * time.Minute
+	}
+	switch {
+	case cfg.SignerCommand != "":
+		s, err := newCommandSigner(cfg.SignerCommand, log)
+		return s, timeout, err
+	case cfg.SignerDir != "":
+		s, err := newFileSigner(dex.CleanAndExpandPath(cfg.SignerDir), log)
+		return s, timeout, err
+	default:
+		return nil, 0, errors.New("no signer directory or command configured")
+	}
+}
+
+// externalSignerClient is a watch-only bitcoind wallet that has transactions
+// signed by a Signer.
+type externalSignerClient struct {
+	*rpcClient
+	signer  Signer
+	timeout time.Duration
+}
+
+var _ Wallet = (*externalSignerClient)(nil)
+var _ externalSigner = (*externalSignerClient)(nil)
+
+func (wc *externalSignerClient) signerContext() (context.Context, context.CancelFunc) {
+	ctx := wc.ctx
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	return context.WithTimeout(ctx, wc.timeout)
+}
+
+// signPSBT has the Signer sign the packet, and checks that the signer did not
+// change the transaction.
+func (wc *externalSignerClient) signPSBT(packet *psbt.Packet) (*psbt.Packet, error) {
+	ctx, cancel := wc.signerContext()
+	defer cancel()
+	signed, err := wc.signer.SignPSBT(ctx, packet)
+	if err != nil {
+		return nil, err
+	}
+	if signed.UnsignedTx.TxHash() != packet.UnsignedTx.TxHash() || len(signed.Inputs) != len(packet.Inputs) {
+		return nil, errors.New("signer returned a different transaction")
+	}
+	return signed, nil
+}
+
+// SignTx has the wallet add the input details to a PSBT, and has the Signer
+// sign it.
+func (wc *externalSignerClient) SignTx(inTx *wire.MsgTx) (*wire.MsgTx, error) {
+	packet, err := psbt.NewFromUnsignedTx(unsignedCopy(inTx))
+	if err != nil {
+		return nil, fmt.Errorf("error creating psbt: %w", err)
+	}
+	b64, err := packet.B64Encode()
+	if err != nil {
+		return nil, fmt.Errorf("error encoding psbt: %w", err)
+	}
+	var res struct {
+		PSBT string `json:"psbt"`
+	}
+	// args: psbt sign sighashtype bip32derivs
+	if err := wc.call(methodWalletProcessPSBT, anylist{b64, false, "ALL", true}, &res); err != nil {
+		return nil, fmt.Errorf("error processing psbt: %w", err)
+	}
+	if packet, err = decodePSBT([]byte(res.PSBT)); err != nil {
+		return nil, fmt.Errorf("error decoding processed psbt: %w", err)
+	}
+	signed, err := wc.signPSBT(packet)
+	if err != nil {
+		return nil, fmt.Errorf("external signer error: %w", err)
+	}
+	if err := psbt.MaybeFinalizeAll(signed); err != nil {
+		return nil, fmt.Errorf("error finalizing signed psbt: %w", err)
+	}
+	return psbt.Extract(signed)
+}
+
+// stubSignTx adds placeholder P2WPKH witnesses of the maximum size to a copy
+// of the tx.
+func (wc *externalSignerClient) stubSignTx(tx *wire.MsgTx) *wire.MsgTx {
+	stubTx := unsignedCopy(tx)
+	for _, txIn := range stubTx.TxIn {
+		txIn.Witness = wire.TxWitness{make([]byte, 73), make([]byte, 33)}
+	}
+	return stubTx
+}
+
+// keyOrigin gets the pubkey and derivation path for the address.
+func (wc *externalSignerClient) keyOrigin(addr btcutil.Address) (*psbt.Bip32Derivation, error) {
+	ai, err := wc.getAddressInfo(addr, methodGetAddressInfo)
+	if err != nil {
+		return nil, err
+	}
+	if !ai.IsMine || len(ai.PubKey) == 0 || ai.HDKeyPath == "" || ai.HDMasterFingerprint == "" {
+		return nil, fmt.Errorf("no key origin for address %s", addr)
+	}
+	fingerprint, err := parseFingerprint(ai.HDMasterFingerprint)
+	if err != nil {
+		return nil, err
+	}
+	path, err := parseBIP32Path(ai.HDKeyPath)
+	if err != nil {
+		return nil, err
+	}
+	return &psbt.Bip32Derivation{
+		PubKey:               ai.PubKey,
+		MasterKeyFingerprint: fingerprint,
+		Bip32Path:            path,
+	}, nil
+}
+
+// signContractInputs has the Signer sign the contract inputs in a single
+// request.
+func (wc *externalSignerClient) signContractInputs(tx *wire.MsgTx, contracts [][]byte,
+	addrs []btcutil.Address, vals []int64) (sigs, pubkeys [][]byte, err error) {
+
+	packet, err := psbt.NewFromUnsignedTx(unsignedCopy(tx))
+	if err != nil {
+		return nil, nil, fmt.Errorf("error creating psbt: %w", err)
+	}
+	keys := make([]*psbt.Bip32Derivation, len(contracts))
+	for i, contract := range contracts {
+		if keys[i], err = wc.keyOrigin(addrs[i]); err != nil {
+			return nil, nil, err
+		}
+		scriptHash := sha256.Sum256(contract)
+		p2wsh, err := txscript.NewScriptBuilder().AddOp(txscript.OP_0).AddData(scriptHash[:]).Script()
+		if err != nil {
+			return nil, nil, err
+		}
+		pIn := &packet.Inputs[i]
+		pIn.WitnessUtxo = wire.NewTxOut(vals[i], p2wsh)
+		pIn.WitnessScript = contract
+		pIn.SighashType = txscript.SigHashAll
+		pIn.Bip32Derivation = []*psbt.Bip32Derivation{keys[i]}
+	}
+	signed, err := wc.signPSBT(packet)
+	if err != nil {
+		return nil, nil, fmt.Errorf("external signer error: %w", err)
+	}
+	sigHashes := txscript.NewTxSigHashes(packet.UnsignedTx, new(txscript.CannedPrevOutputFetcher))
+	sigs, pubkeys = make([][]byte, len(contracts)), make([][]byte, len(contracts))
+	for i, key := range keys {
+		for _, ps := range signed.Inputs[i].PartialSigs {
+			if bytes.Equal(ps.PubKey, key.PubKey) {
+				sigs[i] = ps.Signature
+				break
+			}
+		}
+		if len(sigs[i]) == 0 {
+			return nil, nil, fmt.Errorf("signer did not sign input %d", i)
+		}
+		hash, err := txscript.CalcWitnessSigHash(contracts[i], sigHashes, txscript.SigHashAll, packet.UnsignedTx, i, vals[i])
+		if err != nil {
+			return nil, nil, err
+		}
+		if err := verifySig(sigs[i][:len(sigs[i])-1], key.PubKey, hash); err != nil {
+			return nil, nil, fmt.Errorf("invalid signature for input %d: %w", i, err)
+		}
+		pubkeys[i] = key.PubKey
+	}
+	return sigs, pubkeys, nil
+}
+
+// signHash has the Signer sign the hash with the address' key.
+func (wc *externalSignerClient) signHash(addrStr string, hash []byte) (sig, pubkey []byte, err error) {
+	addr, err := wc.decodeAddr(addrStr, wc.chainParams)
+	if err != nil {
+		return nil, nil, err
+	}
+	key, err := wc.keyOrigin(addr)
+	if err != nil {
+		return nil, nil, err
+	}
+	ctx, cancel := wc.signerContext()
+	defer cancel()
+	sig, err = wc.signer.SignHash(ctx, hash, key)
+	if err != nil {
+		return nil, nil, fmt.Errorf("external signer error: %w", err)
+	}
+	if err := verifySig(sig, key.PubKey, hash); err != nil {
+		return nil, nil, err
+	}
+	return sig, key.PubKey, nil
+}
+
+// PrivKeyForAddress always errors. The private keys are with the signer.
+func (wc *externalSignerClient) PrivKeyForAddress(string) (*btcec.PrivateKey, error) {
+	return nil, errNoPrivateKeys
+}
+
+// WalletUnlock is a no-op. There are no keys to unlock.
+func (wc *externalSignerClient) WalletUnlock([]byte) error {
+	return nil
+}
+
+// WalletLock is a no-op.
+func (wc *externalSignerClient) WalletLock() error {
+	return nil
+}
+
+// Locked is always false.
+func (wc *externalSignerClient) Locked() bool {
+	return false
+}
+
+// unsignedCopy copies the tx without any input signatures.
+func unsignedCopy(tx *wire.MsgTx) *wire.MsgTx {
+	txCopy := tx.Copy()
+	for _, txIn := range txCopy.TxIn {
+		txIn.SignatureScript = nil
+		txIn.Witness = nil
+	}
+	return txCopy
+}
+
+// verifySig verifies a DER-encoded signature.
+func verifySig(sig, pubkey, hash []byte) error {
+	pk, err := btcec.ParsePubKey(pubkey)
+	if err != nil {
+		return fmt.Errorf("invalid pubkey: %w", err)
+	}
+	s, err := ecdsa.ParseDERSignature(sig)
+	if err != nil {
+		return fmt.Errorf("invalid signature encoding: %w", err)
+	}
+	if !s.Verify(hash, pk) {
+		return errors.New("signature verification failed")
+	}
+	return nil
+}
+
+// signingJob is a Swap, Redeem or Refund that is running in the background
+// while it waits on the external signer.
+type signingJob struct {
+	// id identifies the request, since a swap key only identifies the
+	// funding coins.
+	id   string
+	done chan struct{}
+	res  any
+	err  error
+}
+
+// signingJobs tracks the background signing jobs. Swap, Redeem and Refund
+// start a job and return asset.ErrSignaturePending until the job is done. The
+// caller retries with the same request to get the result.
+type signingJobs struct {
+	log  dex.Logger
+	mtx  sync.Mutex
+	jobs map[string]*signingJob
+}
+
+func (s *signingJobs) do(key, id string, f func() (any, error)) (any, error) {
+	s.mtx.Lock()
+	job, found := s.jobs[key]
+	if found && job.id != id {
+		defer s.mtx.Unlock()
+		select {
+		case <-job.done:
+			// The transaction was likely broadcast, but the caller has moved
+			// on. Nothing can be done but to log it.
+			delete(s.jobs, key)
+			s.log.Errorf("Discarding abandoned signing request %s. result = %v, error = %v", job.id, job.res, job.err)
+		default:
+		}
+		return nil, fmt.Errorf("a different request is awaiting a signature for %s", key)
+	}
+	if !found {
+		job = &signingJob{id: id, done: make(chan struct{})}
+		s.jobs[key] = job
+		go func() {
+			job.res, job.err = f()
+			close(job.done)
+		}()
+	}
+	s.mtx.Unlock()
+
+	select {
+	case <-job.done:
+		s.mtx.Lock()
+		delete(s.jobs, key)
+		s.mtx.Unlock()
+		return job.res, job.err
+	case <-time.After(signingJobWait):
+		return nil, asset.ErrSignaturePending
+	}
+}
+
+// ExchangeWalletExternalSigner is a watch-only bitcoind RPC wallet with an
+// external signer. Swap, Redeem and Refund return asset.ErrSignaturePending
+// while waiting on the signer.
+type ExchangeWalletExternalSigner struct {
+	*intermediaryWallet
+	jobs *signingJobs
+}
+
+var _ asset.Wallet = (*ExchangeWalletExternalSigner)(nil)
+
+// newExternalSignerWallet creates a watch-only RPC wallet with the configured
+// Signer.
+func newExternalSignerWallet(cfg *BTCCloneCFG) (*ExchangeWalletExternalSigner, error) {
+	signer, timeout, err := newSigner(cfg.WalletCFG.Settings, cfg.Logger)
+	if err != nil {
+		return nil, err
+	}
+	iw, err := btcCloneWallet(cfg)
+	if err != nil {
+		return nil, err
+	}
+	iw.setNode(&externalSignerClient{
+		rpcClient: iw.node.(*rpcClient),
+		signer:    signer,
+		timeout:   timeout,
+	})
+	return &ExchangeWalletExternalSigner{
+		intermediaryWallet: iw,
+		jobs: &signingJobs{
+			log:  cfg.Logger,
+			jobs: make(map[string]*signingJob),
+		},
+	}, nil
+}
+
+type swapResult struct {
+	receipts []asset.Receipt
+	change   asset.Coin
+	fees     uint64
+}
+
+type redeemResult struct {
+	coinIDs []dex.Bytes
+	coin    asset.Coin
+	fees    uint64
+}
+
+// Swap sends the swaps in a single transaction once signed. If the signature
+// is not available yet, asset.ErrSignaturePending is returned, and the caller
+// should retry with the same swaps.
+func (btc *ExchangeWalletExternalSigner) Swap(swaps *asset.Swaps) ([]asset.Receipt, asset.Coin, uint64, error) {
+	inputs := make([]string, 0, len(swaps.Inputs))
+	for _, coin := range swaps.Inputs {
+		inputs = append(inputs, coin.String())
+	}
+	secretHashes := make([]string, 0, len(swaps.Contracts))
+	for _, c := range swaps.Contracts {
+		secretHashes = append(secretHashes, hex.EncodeToString(c.SecretHash))
+	}
+	res, err := btc.jobs.do("swap:"+strings.Join(inputs, ","), strings.Join(secretHashes, ","), func() (any, error) {
+		receipts, change, fees, err := btc.intermediaryWallet.Swap(swaps)
+		if err == nil {
+			btc.log.Infof("Externally signed swap transaction sent. Receipts: %v", receipts)
+		}
+		return &swapResult{receipts, change, fees}, err
+	})
+	if err != nil {
+		return nil, nil, 0, err
+	}
+	r := res.(*swapResult)
+	return r.receipts, r.change, r.fees, nil
+}
+
+// Redeem sends the redemption transaction once signed. If the signature is
+// not available yet, asset.ErrSignaturePending is returned, and the caller
+// should retry with the same redemptions.
+func (btc *ExchangeWalletExternalSigner) Redeem(form *asset.RedeemForm) ([]dex.Bytes, asset.Coin, uint64, error) {
+	coins := make([]string, 0, len(form.Redemptions))
+	for _, r := range form.Redemptions {
+		if r.Spends == nil || r.Spends.Coin == nil {
+			return nil, nil, 0, fmt.Errorf("no audit info")
+		}
+		coins = append(coins, r.Spends.Coin.String())
+	}
+	key := strings.Join(coins, ",")
+	res, err := btc.jobs.do("redeem:"+key, key, func() (any, error) {
+		coinIDs, coin, fees, err := btc.intermediaryWallet.Redeem(form)
+		return &redeemResult{coinIDs, coin, fees}, err
+	})
+	if err != nil {
+		return nil, nil, 0, err
+	}
+	r := res.(*redeemResult)
+	return r.coinIDs, r.coin, r.fees, nil
+}
+
+// Refund sends the refund transaction once signed. If the signature is not
+// available yet, asset.ErrSignaturePending is returned, and the caller should
+// retry.
+func (btc *ExchangeWalletExternalSigner) Refund(coinID, contract dex.Bytes, feeRate uint64) (dex.Bytes, error) {
+	key := coinID.String()
+	res, err := btc.jobs.do("refund:"+key, key, func() (any, error) {
+		return btc.intermediaryWallet.Refund(coinID, contract, feeRate)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return res.(dex.Bytes), nil
+}
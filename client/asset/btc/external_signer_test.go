@@ -0,0 +1,292 @@
+//go:build !spvlive && !harness
+
+package btc
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"decred.org/dcrdex/client/asset"
+	"github.com/btcsuite/btcd/btcec/v2"
+	"github.com/btcsuite/btcd/btcec/v2/ecdsa"
+	"github.com/btcsuite/btcd/btcutil/psbt"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcd/wire"
+)
+
+type tSigner struct {
+	priv     *btcec.PrivateKey
+	release  chan struct{}
+	requests atomic.Int32
+	badSig   bool
+}
+
+func (s *tSigner) SignPSBT(ctx context.Context, packet *psbt.Packet) (*psbt.Packet, error) {
+	s.requests.Add(1)
+	if s.release != nil {
+		select {
+		case <-s.release:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+	tx := packet.UnsignedTx
+	pubKey := s.priv.PubKey().SerializeCompressed()
+	sigHashes := txscript.NewTxSigHashes(tx, new(txscript.CannedPrevOutputFetcher))
+	for i := range packet.Inputs {
+		pIn := &packet.Inputs[i]
+		if pIn.WitnessScript == nil {
+			// A wallet input. Finalize with a placeholder witness.
+			var buf bytes.Buffer
+			psbt.WriteTxWitness(&buf, wire.TxWitness{make([]byte, 72), pubKey})
+			pIn.FinalScriptWitness = buf.Bytes()
+			continue
+		}
+		hash, err := txscript.CalcWitnessSigHash(pIn.WitnessScript, sigHashes, txscript.SigHashAll, tx, i, pIn.WitnessUtxo.Value)
+		if err != nil {
+			return nil, err
+		}
+		if s.badSig {
+			hash = make([]byte, 32)
+		}
+		sig := append(ecdsa.Sign(s.priv, hash).Serialize(), byte(txscript.SigHashAll))
+		pIn.PartialSigs = append(pIn.PartialSigs, &psbt.PartialSig{PubKey: pubKey, Signature: sig})
+	}
+	return packet, nil
+}
+
+func (s *tSigner) SignHash(ctx context.Context, hash []byte, key *psbt.Bip32Derivation) ([]byte, error) {
+	return ecdsa.Sign(s.priv, hash).Serialize(), nil
+}
+
+func tExternalSignerWallet(t *testing.T) (*ExchangeWalletExternalSigner, *testData, *tSigner, func()) {
+	iw, node, shutdown := tNewWallet(true, walletTypeRPC)
+	privBytes, _ := hex.DecodeString("b07209eec1a8fb6cfe5cb6ace36567406971a75c330db7101fb21bc679bc5330")
+	priv, _ := btcec.PrivKeyFromBytes(privBytes)
+	signer := &tSigner{priv: priv}
+	iw.setNode(&externalSignerClient{
+		rpcClient: iw.node.(*rpcClient),
+		signer:    signer,
+		timeout:   time.Second,
+	})
+	node.newAddress = tP2WPKHAddr
+	node.changeAddr = tP2WPKHAddr
+	node.addrInfo = &GetAddressInfoResult{
+		IsMine:              true,
+		PubKey:              priv.PubKey().SerializeCompressed(),
+		HDKeyPath:           "m/84'/0'/0'/0/1",
+		HDMasterFingerprint: "b940190e",
+	}
+	w := &ExchangeWalletExternalSigner{
+		intermediaryWallet: iw,
+		jobs:               &signingJobs{log: tLogger, jobs: make(map[string]*signingJob)},
+	}
+	return w, node, signer, shutdown
+}
+
+func TestExternalSignerSwap(t *testing.T) {
+	wallet, node, signer, shutdown := tExternalSignerWallet(t)
+	defer shutdown()
+	defer func(d time.Duration) { signingJobWait = d }(signingJobWait)
+	signingJobWait = 50 * time.Millisecond
+
+	swaps := &asset.Swaps{
+		Inputs: asset.Coins{NewOutput(tTxHash, 0, toSatoshi(3))},
+		Contracts: []*asset.Contract{{
+			Address:    tP2WPKHAddr,
+			Value:      toSatoshi(1),
+			SecretHash: randBytes(32),
+			LockTime:   uint64(time.Now().Unix()),
+		}},
+		FeeRate: tBTC.MaxFeeRate,
+	}
+
+	signer.release = make(chan struct{})
+	_, _, _, err := wallet.Swap(swaps)
+	if !errors.Is(err, asset.ErrSignaturePending) {
+		t.Fatalf("expected ErrSignaturePending, got %v", err)
+	}
+	// A different swap with the same inputs is rejected.
+	otherSwaps := *swaps
+	otherSwaps.Contracts = []*asset.Contract{{
+		Address:    tP2WPKHAddr,
+		Value:      toSatoshi(1),
+		SecretHash: randBytes(32),
+		LockTime:   uint64(time.Now().Unix()),
+	}}
+	if _, _, _, err = wallet.Swap(&otherSwaps); err == nil || errors.Is(err, asset.ErrSignaturePending) {
+		t.Fatalf("expected an error for a different swap with the same inputs, got %v", err)
+	}
+
+	close(signer.release)
+	receipts, _, _, err := wallet.Swap(swaps)
+	if err != nil {
+		t.Fatalf("swap error after signing: %v", err)
+	}
+	if n := signer.requests.Load(); n != 1 {
+		t.Fatalf("expected 1 signing request, got %d", n)
+	}
+	if len(receipts) != 1 || len(receipts[0].SignedRefund()) != 0 {
+		t.Fatalf("wrong receipts")
+	}
+	if node.sentRawTx == nil || len(node.sentRawTx.TxIn[0].Witness) != 2 {
+		t.Fatalf("signed swap not sent")
+	}
+
+	// The signer times out.
+	signer.release = make(chan struct{})
+	swaps.Contracts[0].SecretHash = randBytes(32)
+	for {
+		_, _, _, err = wallet.Swap(swaps)
+		if !errors.Is(err, asset.ErrSignaturePending) {
+			break
+		}
+	}
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected a timeout, got %v", err)
+	}
+}
+
+func TestExternalSignerRedeem(t *testing.T) {
+	wallet, node, signer, shutdown := tExternalSignerWallet(t)
+	defer shutdown()
+
+	secret, _, _, contract, addr, _, lockTime := makeSwapContract(true, time.Hour*12)
+	form := &asset.RedeemForm{
+		Redemptions: []*asset.Redemption{{
+			Spends: &asset.AuditInfo{
+				Coin:       NewOutput(tTxHash, 0, toSatoshi(5)),
+				Contract:   contract,
+				Recipient:  addr.String(),
+				Expiration: lockTime,
+			},
+			Secret: secret,
+		}},
+	}
+
+	if _, _, _, err := wallet.Redeem(form); err != nil {
+		t.Fatalf("redeem error: %v", err)
+	}
+	witness := node.sentRawTx.TxIn[0].Witness
+	if len(witness) != 5 || !bytes.Equal(witness[1], signer.priv.PubKey().SerializeCompressed()) {
+		t.Fatalf("wrong redeem witness")
+	}
+
+	// An invalid signature is rejected.
+	node.sentRawTx = nil
+	signer.badSig = true
+	if _, _, _, err := wallet.Redeem(form); err == nil {
+		t.Fatalf("no error for a bad signature")
+	}
+	if node.sentRawTx != nil {
+		t.Fatalf("redeem sent with a bad signature")
+	}
+	signer.badSig = false
+
+	// No key origin.
+	node.addrInfo.HDKeyPath = ""
+	if _, _, _, err := wallet.Redeem(form); err == nil {
+		t.Fatalf("no error for missing key origin")
+	}
+}
+
+func TestFileSigner(t *testing.T) {
+	defer func(d time.Duration) { signerPollInterval = d }(signerPollInterval)
+	signerPollInterval = 10 * time.Millisecond
+
+	dir := t.TempDir()
+	s, err := newFileSigner(dir, tLogger)
+	if err != nil {
+		t.Fatalf("newFileSigner error: %v", err)
+	}
+
+	tx := wire.NewMsgTx(wire.TxVersion)
+	tx.AddTxIn(wire.NewTxIn(wire.NewOutPoint(tTxHash, 0), nil, nil))
+	tx.AddTxOut(wire.NewTxOut(1e5, randBytes(22)))
+	packet, _ := psbt.NewFromUnsignedTx(tx)
+	txid := tx.TxHash().String()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	errC := make(chan error, 1)
+	go func() {
+		signed, err := s.SignPSBT(ctx, packet)
+		if err == nil && signed.UnsignedTx.TxHash() != tx.TxHash() {
+			err = errors.New("wrong tx")
+		}
+		errC <- err
+	}()
+
+	reqPath := filepath.Join(dir, txid+".psbt")
+	var b []byte
+	for b == nil {
+		if b, _ = os.ReadFile(reqPath); b == nil {
+			time.Sleep(10 * time.Millisecond)
+		}
+	}
+	// Respond with the binary encoding.
+	var buf bytes.Buffer
+	req, err := decodePSBT(b)
+	if err != nil {
+		t.Fatalf("error decoding request: %v", err)
+	}
+	req.Serialize(&buf)
+	if err := os.WriteFile(filepath.Join(dir, txid+".signed.psbt"), buf.Bytes(), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := <-errC; err != nil {
+		t.Fatalf("SignPSBT error: %v", err)
+	}
+	if _, err := os.Stat(reqPath); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("request not removed")
+	}
+
+	// Hash signing times out without a response.
+	hash := chainhash.HashB([]byte("msg"))
+	shortCtx, shortCancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer shortCancel()
+	key := &psbt.Bip32Derivation{PubKey: randBytes(33), Bip32Path: []uint32{0x80000054, 0}}
+	if _, err := s.SignHash(shortCtx, hash, key); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected a timeout, got %v", err)
+	}
+	// The request is reused on the next attempt.
+	sig := randBytes(70)
+	os.WriteFile(filepath.Join(dir, hex.EncodeToString(hash)+".sig"), []byte(hex.EncodeToString(sig)+"\n"), 0600)
+	gotSig, err := s.SignHash(ctx, hash, key)
+	if err != nil {
+		t.Fatalf("SignHash error: %v", err)
+	}
+	if !bytes.Equal(gotSig, sig) {
+		t.Fatalf("wrong signature")
+	}
+}
+
+func TestBIP32Path(t *testing.T) {
+	path, err := parseBIP32Path("m/84'/1h/0'/1/5")
+	if err != nil {
+		t.Fatalf("parseBIP32Path error: %v", err)
+	}
+	if s := bip32PathString(path); s != "m/84'/1'/0'/1/5" {
+		t.Fatalf("wrong path string %s", s)
+	}
+	for _, bad := range []string{"", "84'/0", "m/x", "m/2147483648"} {
+		if _, err := parseBIP32Path(bad); err == nil {
+			t.Fatalf("no error for %q", bad)
+		}
+	}
+	fp, err := parseFingerprint("b940190e")
+	if err != nil {
+		t.Fatalf("parseFingerprint error: %v", err)
+	}
+	if s := fingerprintString(fp); s != "b940190e" {
+		t.Fatalf("wrong fingerprint string %s", s)
+	}
+}
@@ -62,6 +62,7 @@ const (
 	methodFundRawTransaction   = "fundrawtransaction"
 	methodListSinceBlock       = "listsinceblock"
 	methodGetReceivedByAddress = "getreceivedbyaddress"
+	methodWalletProcessPSBT    = "walletprocesspsbt"
 )
 
 // IsTxNotFoundErr will return true if the error indicates that the requested
@@ -0,0 +1,293 @@
+// This code is available on the terms of the project LICENSE.md file,
+// also available online at https://blueoakcouncil.org/license/1.0.0.
+
+package btc
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"decred.org/dcrdex/dex"
+	"github.com/btcsuite/btcd/btcutil/hdkeychain"
+	"github.com/btcsuite/btcd/btcutil/psbt"
+)
+
+// signerPollInterval is how often the fileSigner checks for a response.
+var signerPollInterval = time.Second
+
+// Signer signs transactions and messages for a wallet that does not hold its
+// own private keys, e.g. a watch-only bitcoind wallet backed by a hardware
+// wallet or an offline signing machine. Both methods should block until the
+// signature is available or the context is canceled.
+type Signer interface {
+	// SignPSBT signs the inputs of the packet for which the signer has keys.
+	// The input's Bip32Derivation identify the keys. The returned packet must
+	// have the same unsigned transaction, with PartialSigs added.
+	SignPSBT(ctx context.Context, packet *psbt.Packet) (*psbt.Packet, error)
+	// SignHash creates a DER-encoded ECDSA signature of the 32-byte hash with
+	// the key identified by the derivation.
+	SignHash(ctx context.Context, hash []byte, key *psbt.Bip32Derivation) ([]byte, error)
+}
+
+// fileSigner is a Signer that exchanges files with the signer through a
+// directory, e.g. a directory synced with an air-gapped machine or a
+// removable drive. Requests are written as <txid>.psbt (base64) and
+// <hash>.sighash.json. The signer responds with <txid>.signed.psbt (base64 or
+// binary) and <hash>.sig (hex-encoded DER signature).
+type fileSigner struct {
+	dir string
+	log dex.Logger
+}
+
+var _ Signer = (*fileSigner)(nil)
+
+func newFileSigner(dir string, log dex.Logger) (*fileSigner, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, fmt.Errorf("error creating signer directory: %w", err)
+	}
+	return &fileSigner{dir: dir, log: log}, nil
+}
+
+// SignPSBT writes the packet to the exchange directory and waits for the
+// signed packet.
+func (s *fileSigner) SignPSBT(ctx context.Context, packet *psbt.Packet) (*psbt.Packet, error) {
+	b64, err := packet.B64Encode()
+	if err != nil {
+		return nil, fmt.Errorf("error encoding psbt: %w", err)
+	}
+	txid := packet.UnsignedTx.TxHash().String()
+	reqPath := filepath.Join(s.dir, txid+".psbt")
+	respPath := filepath.Join(s.dir, txid+".signed.psbt")
+	// A request for the same transaction may already be waiting from a
+	// previous attempt. Don't overwrite it, since the signer might be reading
+	// it now.
+	if _, err := os.Stat(reqPath); errors.Is(err, os.ErrNotExist) {
+		if err := os.WriteFile(reqPath, []byte(b64), 0600); err != nil {
+			return nil, fmt.Errorf("error writing psbt: %w", err)
+		}
+		s.log.Infof("Transaction %s is awaiting a signature. Unsigned PSBT written to %s", txid, reqPath)
+	}
+	b, err := s.waitForFile(ctx, respPath)
+	if err != nil {
+		return nil, err
+	}
+	signed, err := decodePSBT(b)
+	if err != nil {
+		return nil, fmt.Errorf("error decoding signed psbt from %s: %w", respPath, err)
+	}
+	os.Remove(reqPath)
+	os.Remove(respPath)
+	return signed, nil
+}
+
+// sigHashRequest is the file signer's request to sign a hash.
+type sigHashRequest struct {
+	Hash        dex.Bytes `json:"hash"`
+	PubKey      dex.Bytes `json:"pubkey"`
+	Fingerprint string    `json:"fingerprint"`
+	Path        string    `json:"path"`
+}
+
+// SignHash writes a signing request to the exchange directory and waits for
+// the signature.
+func (s *fileSigner) SignHash(ctx context.Context, hash []byte, key *psbt.Bip32Derivation) ([]byte, error) {
+	hashStr := hex.EncodeToString(hash)
+	reqPath := filepath.Join(s.dir, hashStr+".sighash.json")
+	respPath := filepath.Join(s.dir, hashStr+".sig")
+	if _, err := os.Stat(reqPath); errors.Is(err, os.ErrNotExist) {
+		b, err := json.MarshalIndent(&sigHashRequest{
+			Hash:        hash,
+			PubKey:      key.PubKey,
+			Fingerprint: fingerprintString(key.MasterKeyFingerprint),
+			Path:        bip32PathString(key.Bip32Path),
+		}, "", "    ")
+		if err != nil {
+			return nil, err
+		}
+		if err := os.WriteFile(reqPath, b, 0600); err != nil {
+			return nil, fmt.Errorf("error writing signing request: %w", err)
+		}
+		s.log.Infof("Message hash %s is awaiting a signature. Request written to %s", hashStr, reqPath)
+	}
+	b, err := s.waitForFile(ctx, respPath)
+	if err != nil {
+		return nil, err
+	}
+	sig, err := hex.DecodeString(string(bytes.TrimSpace(b)))
+	if err != nil {
+		return nil, fmt.Errorf("error decoding signature from %s: %w", respPath, err)
+	}
+	os.Remove(reqPath)
+	os.Remove(respPath)
+	return sig, nil
+}
+
+// waitForFile polls for the file until it exists or the context is canceled.
+func (s *fileSigner) waitForFile(ctx context.Context, path string) ([]byte, error) {
+	ticker := time.NewTicker(signerPollInterval)
+	defer ticker.Stop()
+	for {
+		b, err := os.ReadFile(path)
+		if err == nil && len(b) > 0 {
+			return b, nil
+		}
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return nil, err
+		}
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return nil, fmt.Errorf("timed out waiting for %s: %w", filepath.Base(path), ctx.Err())
+		}
+	}
+}
+
+// commandSigner is a Signer that runs an external command, following the
+// conventions of the Hardware Wallet Interface (HWI) command line tool.
+// Transactions are signed with
+//
+//	<cmd> signtx <base64 psbt>
+//
+// which must print {"psbt": "<base64 psbt>"}, as HWI does. Hashes are signed
+// with
+//
+//	<cmd> signhash <hex hash> <path>
+//
+// which must print {"signature": "<hex DER signature>"}. HWI does not sign
+// raw hashes, so a command wrapping HWI must provide signhash itself.
+type commandSigner struct {
+	cmd  string
+	args []string
+	log  dex.Logger
+}
+
+var _ Signer = (*commandSigner)(nil)
+
+func newCommandSigner(cmdLine string, log dex.Logger) (*commandSigner, error) {
+	fields := strings.Fields(cmdLine)
+	if len(fields) == 0 {
+		return nil, errors.New("no signer command")
+	}
+	return &commandSigner{cmd: fields[0], args: fields[1:], log: log}, nil
+}
+
+func (s *commandSigner) run(ctx context.Context, thing any, args ...string) error {
+	cmd := exec.CommandContext(ctx, s.cmd, append(s.args, args...)...)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return fmt.Errorf("signer command error: %w, stderr: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	var errResp struct {
+		Error string `json:"error"`
+	}
+	if json.Unmarshal(out, &errResp) == nil && errResp.Error != "" {
+		return fmt.Errorf("signer error: %s", errResp.Error)
+	}
+	return json.Unmarshal(out, thing)
+}
+
+// SignPSBT runs the signtx command.
+func (s *commandSigner) SignPSBT(ctx context.Context, packet *psbt.Packet) (*psbt.Packet, error) {
+	b64, err := packet.B64Encode()
+	if err != nil {
+		return nil, fmt.Errorf("error encoding psbt: %w", err)
+	}
+	var resp struct {
+		PSBT string `json:"psbt"`
+	}
+	if err := s.run(ctx, &resp, "signtx", b64); err != nil {
+		return nil, err
+	}
+	return decodePSBT([]byte(resp.PSBT))
+}
+
+// SignHash runs the signhash command.
+func (s *commandSigner) SignHash(ctx context.Context, hash []byte, key *psbt.Bip32Derivation) ([]byte, error) {
+	var resp struct {
+		Signature dex.Bytes `json:"signature"`
+	}
+	if err := s.run(ctx, &resp, "signhash", hex.EncodeToString(hash), bip32PathString(key.Bip32Path)); err != nil {
+		return nil, err
+	}
+	if len(resp.Signature) == 0 {
+		return nil, errors.New("signer returned no signature")
+	}
+	return resp.Signature, nil
+}
+
+// decodePSBT decodes a base64 or binary PSBT.
+func decodePSBT(b []byte) (*psbt.Packet, error) {
+	b = bytes.TrimSpace(b)
+	isBinary := bytes.HasPrefix(b, []byte("psbt\xff"))
+	return psbt.NewFromRawBytes(bytes.NewReader(b), !isBinary)
+}
+
+// fingerprintString encodes a master key fingerprint the way Bitcoin Core and
+// HWI display them.
+func fingerprintString(fp uint32) string {
+	b := []byte{byte(fp), byte(fp >> 8), byte(fp >> 16), byte(fp >> 24)}
+	return hex.EncodeToString(b)
+}
+
+// parseFingerprint parses a hex master key fingerprint.
+func parseFingerprint(s string) (uint32, error) {
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		return 0, err
+	}
+	if len(b) != 4 {
+		return 0, fmt.Errorf("invalid fingerprint length %d", len(b))
+	}
+	return uint32(b[0]) | uint32(b[1])<<8 | uint32(b[2])<<16 | uint32(b[3])<<24, nil
+}
+
+// bip32PathString encodes the path, e.g. m/84'/0'/0'/0/1.
+func bip32PathString(path []uint32) string {
+	var sb strings.Builder
+	sb.WriteString("m")
+	for _, i := range path {
+		sb.WriteString("/")
+		if i >= hdkeychain.HardenedKeyStart {
+			sb.WriteString(strconv.FormatUint(uint64(i-hdkeychain.HardenedKeyStart), 10))
+			sb.WriteString("'")
+		} else {
+			sb.WriteString(strconv.FormatUint(uint64(i), 10))
+		}
+	}
+	return sb.String()
+}
+
+// parseBIP32Path parses a path like m/84'/0'/0'/0/1. Hardened indexes may be
+// indicated with ' or h.
+func parseBIP32Path(s string) ([]uint32, error) {
+	parts := strings.Split(s, "/")
+	if len(parts) == 0 || parts[0] != "m" {
+		return nil, fmt.Errorf("invalid path %q", s)
+	}
+	path := make([]uint32, 0, len(parts)-1)
+	for _, p := range parts[1:] {
+		var offset uint32
+		if strings.HasSuffix(p, "'") || strings.HasSuffix(p, "h") {
+			offset = hdkeychain.HardenedKeyStart
+			p = p[:len(p)-1]
+		}
+		i, err := strconv.ParseUint(p, 10, 31)
+		if err != nil {
+			return nil, fmt.Errorf("invalid path %q: %w", s, err)
+		}
+		path = append(path, uint32(i)+offset)
+	}
+	return path, nil
+}
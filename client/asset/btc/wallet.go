@@ -68,6 +68,18 @@ type walletTxChecker interface {
 	checkWalletTx(txid string) ([]byte, uint32, error)
 }
 
+// externalSigner is implemented by a Wallet that does not have private keys,
+// and has its inputs and messages signed by an external Signer. SignTx will be
+// called only once per transaction, so stubSignTx provides placeholder
+// signatures for size estimates.
+type externalSigner interface {
+	stubSignTx(tx *wire.MsgTx) *wire.MsgTx
+	// signContractInputs signs the contracts spent by the first len(contracts)
+	// inputs of the segwit tx.
+	signContractInputs(tx *wire.MsgTx, contracts [][]byte, addrs []btcutil.Address, vals []int64) (sigs, pubkeys [][]byte, err error)
+	signHash(addr string, hash []byte) (sig, pubkey []byte, err error)
+}
+
 // tipNotifier can be implemented if the Wallet is able to provide a stream of
 // blocks as they are finished being processed.
 type tipNotifier interface {
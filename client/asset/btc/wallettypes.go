@@ -133,8 +133,9 @@ type GetWalletInfoResult struct {
 
 // GetAddressInfoResult models some of the data from the getaddressinfo command.
 type GetAddressInfoResult struct {
-	IsMine     bool   `json:"ismine"`
-	Descriptor string `json:"desc"` // e.g. "wpkh([b940190e/84'/1'/0'/0/0]0300034...)#0pfw7rck"
+	IsMine     bool      `json:"ismine"`
+	Descriptor string    `json:"desc"`   // e.g. "wpkh([b940190e/84'/1'/0'/0/0]0300034...)#0pfw7rck"
+	PubKey     dex.Bytes `json:"pubkey"` // only for single-key addresses
 
 	// The following fields are unused by DEX, but modeled here for completeness
 	// and debugging:
@@ -0,0 +1,334 @@
+// This code is available on the terms of the project LICENSE.md file,
+// also available online at https://blueoakcouncil.org/license/1.0.0.
+
+package dcr
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"sync"
+
+	"decred.org/dcrdex/client/asset"
+	"decred.org/dcrdex/dex"
+	dexdcr "decred.org/dcrdex/dex/networks/dcr"
+	"github.com/decred/dcrd/chaincfg/chainhash"
+	"github.com/decred/dcrd/txscript/v4/stdaddr"
+	"github.com/decred/dcrd/wire"
+)
+
+const (
+	// coinControlFileName is the name of the file in the wallet directory
+	// that stores UTXO labels and frozen coins.
+	coinControlFileName = "coin-control.json"
+	// maxUTXOLabelLen is the maximum length of a UTXO label.
+	maxUTXOLabelLen = 100
+)
+
+// coinMeta is the user-defined metadata for a UTXO.
+type coinMeta struct {
+	TxID   string `json:"txid"`
+	Vout   uint32 `json:"vout"`
+	Label  string `json:"label,omitempty"`
+	Frozen bool   `json:"frozen,omitempty"`
+}
+
+// coinControl stores UTXO labels and frozen coins, persisted as JSON in the
+// wallet directory.
+type coinControl struct {
+	path string
+
+	mtx   sync.RWMutex
+	coins map[outPoint]*coinMeta
+}
+
+// newCoinControl loads any stored coin metadata from the file at path.
+func newCoinControl(path string) (*coinControl, error) {
+	cc := &coinControl{
+		path:  path,
+		coins: make(map[outPoint]*coinMeta),
+	}
+	b, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return cc, nil
+		}
+		return nil, fmt.Errorf("error reading coin control file: %w", err)
+	}
+	var metas []*coinMeta
+	if err := json.Unmarshal(b, &metas); err != nil {
+		return nil, fmt.Errorf("error decoding coin control file: %w", err)
+	}
+	for _, m := range metas {
+		txHash, err := chainhash.NewHashFromStr(m.TxID)
+		if err != nil {
+			return nil, fmt.Errorf("invalid txid %q in coin control file: %w", m.TxID, err)
+		}
+		cc.coins[newOutPoint(txHash, m.Vout)] = m
+	}
+	return cc, nil
+}
+
+// update modifies the metadata for the outpoints, dropping entries with no
+// label that are not frozen, and writes the result to file.
+func (cc *coinControl) update(pts []outPoint, f func(*coinMeta)) error {
+	cc.mtx.Lock()
+	defer cc.mtx.Unlock()
+	for _, pt := range pts {
+		m := cc.coins[pt]
+		if m == nil {
+			m = &coinMeta{TxID: pt.txHash.String(), Vout: pt.vout}
+		}
+		f(m)
+		if m.Label == "" && !m.Frozen {
+			delete(cc.coins, pt)
+		} else {
+			cc.coins[pt] = m
+		}
+	}
+	metas := make([]*coinMeta, 0, len(cc.coins))
+	for _, m := range cc.coins {
+		metas = append(metas, m)
+	}
+	sort.Slice(metas, func(i, j int) bool {
+		if metas[i].TxID == metas[j].TxID {
+			return metas[i].Vout < metas[j].Vout
+		}
+		return metas[i].TxID < metas[j].TxID
+	})
+	b, err := json.MarshalIndent(metas, "", "    ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(cc.path, b, 0600)
+}
+
+// meta returns a copy of the metadata for the outpoint.
+func (cc *coinControl) meta(pt outPoint) coinMeta {
+	cc.mtx.RLock()
+	defer cc.mtx.RUnlock()
+	if m := cc.coins[pt]; m != nil {
+		return *m
+	}
+	return coinMeta{}
+}
+
+// isFrozen checks whether the outpoint is frozen.
+func (cc *coinControl) isFrozen(pt outPoint) bool {
+	cc.mtx.RLock()
+	defer cc.mtx.RUnlock()
+	m := cc.coins[pt]
+	return m != nil && m.Frozen
+}
+
+// decodeCoinIDs converts the coin IDs to outpoints.
+func decodeCoinIDs(coinIDs []dex.Bytes) ([]outPoint, error) {
+	pts := make([]outPoint, 0, len(coinIDs))
+	for _, coinID := range coinIDs {
+		txHash, vout, err := decodeCoinID(coinID)
+		if err != nil {
+			return nil, err
+		}
+		pts = append(pts, newOutPoint(txHash, vout))
+	}
+	return pts, nil
+}
+
+// selectedUTXOs returns the unlocked UTXOs for the specified outpoints,
+// sorted in ascending order by amount. Frozen coins are included. An error is
+// returned if any of the outpoints is not an unlocked UTXO. The fundingMtx
+// must be held.
+func (dcr *ExchangeWallet) selectedUTXOs(pts []outPoint) ([]*compositeUTXO, error) {
+	utxos, err := dcr.unlockedUTXOs(true)
+	if err != nil {
+		return nil, err
+	}
+	utxoMap := make(map[outPoint]*compositeUTXO, len(utxos))
+	for _, utxo := range utxos {
+		txHash, err := chainhash.NewHashFromStr(utxo.rpc.TxID)
+		if err != nil {
+			return nil, fmt.Errorf("error decoding txid: %w", err)
+		}
+		utxoMap[newOutPoint(txHash, utxo.rpc.Vout)] = utxo
+	}
+	selected := make([]*compositeUTXO, 0, len(pts))
+	for _, pt := range pts {
+		utxo, found := utxoMap[pt]
+		if !found {
+			return nil, fmt.Errorf("coin %s is not an unlocked wallet UTXO", pt)
+		}
+		delete(utxoMap, pt) // no duplicates
+		selected = append(selected, utxo)
+	}
+	sort.Slice(selected, func(i, j int) bool { return selected[i].rpc.Amount < selected[j].rpc.Amount })
+	return selected, nil
+}
+
+// fundWithCoins is like fund, but only a subset of the specified coins may be
+// selected. Bond reserves are not considered.
+func (dcr *ExchangeWallet) fundWithCoins(coinIDs []dex.Bytes,
+	enough func(sum uint64, size uint32, unspent *compositeUTXO) (bool, uint64)) (
+	coins asset.Coins, redeemScripts []dex.Bytes, sum, size uint64, err error) {
+
+	pts, err := decodeCoinIDs(coinIDs)
+	if err != nil {
+		return nil, nil, 0, 0, err
+	}
+
+	dcr.fundingMtx.Lock()
+	defer dcr.fundingMtx.Unlock()
+
+	utxos, err := dcr.selectedUTXOs(pts)
+	if err != nil {
+		return nil, nil, 0, 0, err
+	}
+	coins, redeemScripts, _, sum, size, err = dcr.fundInternalWithUTXOs(utxos, 0, enough, true)
+	return coins, redeemScripts, sum, size, err
+}
+
+// ListUTXOs lists the wallet's unspent outputs, including those that are
+// frozen or locked for orders. Part of the asset.CoinController interface.
+func (dcr *ExchangeWallet) ListUTXOs() ([]*asset.WalletUTXO, error) {
+	accts := dcr.wallet.Accounts()
+	unspents, err := dcr.wallet.Unspents(dcr.ctx, accts.PrimaryAccount)
+	if err != nil {
+		return nil, err
+	}
+	if accts.TradingAccount != "" {
+		tradingAcctUnspents, err := dcr.wallet.Unspents(dcr.ctx, accts.TradingAccount)
+		if err != nil {
+			return nil, err
+		}
+		unspents = append(unspents, tradingAcctUnspents...)
+	}
+
+	utxos := make([]*asset.WalletUTXO, 0, len(unspents))
+	listed := make(map[outPoint]bool, len(unspents))
+	add := func(txHash *chainhash.Hash, vout uint32, addr string, amt uint64, confs int64, locked bool) {
+		pt := newOutPoint(txHash, vout)
+		if listed[pt] {
+			return
+		}
+		listed[pt] = true
+		m := dcr.coinCtl.meta(pt)
+		if confs < 0 {
+			confs = 0
+		}
+		utxos = append(utxos, &asset.WalletUTXO{
+			CoinID:  toCoinID(txHash, vout),
+			TxID:    txHash.String(),
+			Vout:    vout,
+			Address: addr,
+			Amount:  amt,
+			Confs:   uint32(confs),
+			Label:   m.Label,
+			Frozen:  m.Frozen,
+			Locked:  locked,
+		})
+	}
+
+	dcr.fundingMtx.RLock()
+	defer dcr.fundingMtx.RUnlock()
+	for _, u := range unspents {
+		if !u.Spendable {
+			continue
+		}
+		txHash, err := chainhash.NewHashFromStr(u.TxID)
+		if err != nil {
+			return nil, fmt.Errorf("error decoding txid: %w", err)
+		}
+		_, locked := dcr.fundingCoins[newOutPoint(txHash, u.Vout)]
+		add(txHash, u.Vout, u.Address, toAtoms(u.Amount), u.Confirmations, locked)
+	}
+	for _, fc := range dcr.fundingCoins {
+		add(fc.op.txHash(), fc.op.vout(), fc.addr, fc.op.value, 0, true)
+	}
+	sort.Slice(utxos, func(i, j int) bool { return utxos[i].Amount > utxos[j].Amount })
+	return utxos, nil
+}
+
+// SetUTXOLabel sets the label for the coin. An empty label removes any
+// existing label. Part of the asset.CoinController interface.
+func (dcr *ExchangeWallet) SetUTXOLabel(coinID dex.Bytes, label string) error {
+	if len(label) > maxUTXOLabelLen {
+		return fmt.Errorf("label exceeds %d characters", maxUTXOLabelLen)
+	}
+	pts, err := decodeCoinIDs([]dex.Bytes{coinID})
+	if err != nil {
+		return err
+	}
+	return dcr.coinCtl.update(pts, func(m *coinMeta) { m.Label = label })
+}
+
+// FreezeUTXOs freezes or unfreezes the coins. Frozen coins are excluded from
+// automatic coin selection. Part of the asset.CoinController interface.
+func (dcr *ExchangeWallet) FreezeUTXOs(coinIDs []dex.Bytes, freeze bool) error {
+	pts, err := decodeCoinIDs(coinIDs)
+	if err != nil {
+		return err
+	}
+	return dcr.coinCtl.update(pts, func(m *coinMeta) { m.Frozen = freeze })
+}
+
+// SendWithCoins sends the value to the address, spending only from the
+// specified coins. Frozen coins may be spent if they are specified explicitly.
+// Bond reserves are not enforced for the chosen coins. feeRate is in units of
+// atoms/byte. Part of the asset.CoinController interface.
+func (dcr *ExchangeWallet) SendWithCoins(address string, value, feeRate uint64, subtract bool, coinIDs []dex.Bytes) (asset.Coin, error) {
+	if len(coinIDs) == 0 {
+		return nil, errors.New("no coins specified")
+	}
+	if value == 0 {
+		return nil, errors.New("cannot send value = 0")
+	}
+	addr, err := stdaddr.DecodeAddress(address, dcr.chainParams)
+	if err != nil {
+		return nil, fmt.Errorf("invalid address: %s", address)
+	}
+	feeRate = dcr.feeRateWithFallback(feeRate)
+
+	baseSize := uint32(dexdcr.MsgTxOverhead + dexdcr.P2PKHOutputSize*2)
+	reportChange := dcr.wallet.Accounts().UnmixedAccount == "" // otherwise change goes to unmixed account
+	enough := sendEnough(value, feeRate, subtract, baseSize, reportChange)
+	coins, _, _, _, err := dcr.fundWithCoins(coinIDs, enough)
+	if err != nil {
+		return nil, fmt.Errorf("unable to send %s DCR with the specified coins: %w", amount(value), err)
+	}
+
+	msgTx, sentVal, err := dcr.sendCoins(coins, addr, nil, value, 0, feeRate, subtract)
+	if err != nil {
+		if _, retErr := dcr.returnCoins(coins); retErr != nil {
+			dcr.log.Errorf("Failed to unlock coins: %v", retErr)
+		}
+		return nil, err
+	}
+
+	var totalIn, totalOut uint64
+	for _, coin := range coins {
+		totalIn += coin.Value()
+	}
+	for _, txOut := range msgTx.TxOut {
+		totalOut += uint64(txOut.Value)
+	}
+
+	selfSend, err := dcr.OwnsDepositAddress(address)
+	if err != nil {
+		dcr.log.Errorf("error checking if address %q is owned: %v", address, err)
+	}
+	txType := asset.Send
+	if selfSend {
+		txType = asset.SelfSend
+	}
+
+	dcr.addTxToHistory(&asset.WalletTransaction{
+		Type:      txType,
+		ID:        msgTx.CachedTxHash().String(),
+		Amount:    sentVal,
+		Fees:      totalIn - totalOut,
+		Recipient: &address,
+	}, msgTx.CachedTxHash(), true)
+
+	return newOutput(msgTx.CachedTxHash(), 0, sentVal, wire.TxTreeRegular), nil
+}
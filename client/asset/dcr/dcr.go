@@ -691,6 +691,8 @@ type ExchangeWallet struct {
 		sync.RWMutex
 		progress *rescanProgress // nil = no rescan in progress
 	}
+
+	coinCtl *coinControl
 }
 
 func (dcr *ExchangeWallet) config() *exchangeWalletConfig {
@@ -708,6 +710,7 @@ var _ asset.Authenticator = (*ExchangeWallet)(nil)
 var _ asset.TicketBuyer = (*ExchangeWallet)(nil)
 var _ asset.WalletHistorian = (*ExchangeWallet)(nil)
 var _ asset.NewAddresser = (*ExchangeWallet)(nil)
+var _ asset.CoinController = (*ExchangeWallet)(nil)
 
 type block struct {
 	height int64
@@ -835,6 +838,11 @@ func unconnectedWallet(cfg *asset.WalletConfig, dcrCfg *walletConfig, chainParam
 
 	vspFilepath := filepath.Join(dir, vspFileName)
 
+	coinCtl, err := newCoinControl(filepath.Join(dir, coinControlFileName))
+	if err != nil {
+		return nil, err
+	}
+
 	w := &ExchangeWallet{
 		log:                 logger,
 		chainParams:         chainParams,
@@ -851,6 +859,7 @@ func unconnectedWallet(cfg *asset.WalletConfig, dcrCfg *walletConfig, chainParam
 		subsidyCache:        blockchain.NewSubsidyCache(chainParams),
 		pendingTxs:          make(map[chainhash.Hash]*btc.ExtendedWalletTx),
 		walletDir:           dir,
+		coinCtl:             coinCtl,
 	}
 
 	if b, err := os.ReadFile(vspFilepath); err == nil {
@@ -1898,10 +1907,18 @@ func (dcr *ExchangeWallet) FundOrder(ord *asset.Order) (asset.Coins, []dex.Bytes
 
 	changeForReserves := useSplit && dcr.wallet.Accounts().UnmixedAccount == ""
 	reserves := dcr.bondReserves.Load()
-	coins, redeemScripts, sum, inputsSize, err := dcr.fund(reserves,
-		orderEnough(ord.Value, ord.MaxSwapCount, bumpedMaxRate, changeForReserves))
+	var coins asset.Coins
+	var redeemScripts []dex.Bytes
+	var sum, inputsSize uint64
+	if len(ord.Coins) > 0 { // restricted to coins chosen by the user
+		coins, redeemScripts, sum, inputsSize, err = dcr.fundWithCoins(ord.Coins,
+			orderEnough(ord.Value, ord.MaxSwapCount, bumpedMaxRate, changeForReserves))
+	} else {
+		coins, redeemScripts, sum, inputsSize, err = dcr.fund(reserves,
+			orderEnough(ord.Value, ord.MaxSwapCount, bumpedMaxRate, changeForReserves))
+	}
 	if err != nil {
-		if !changeForReserves && reserves > 0 { // split not selected, or it's a mixing account where change isn't usable
+		if !changeForReserves && reserves > 0 && len(ord.Coins) == 0 { // split not selected, or it's a mixing account where change isn't usable
 			// Force a split if funding failure may be due to reserves.
 			dcr.log.Infof("Retrying order funding with a forced split transaction to help respect reserves.")
 			useSplit = true
@@ -2562,8 +2579,15 @@ func (dcr *ExchangeWallet) fund(keep uint64, // leave utxos for this reserve amt
 	return coins, redeemScripts, sum, size, err
 }
 
-// spendableUTXOs generates a slice of spendable *compositeUTXO.
+// spendableUTXOs generates a slice of spendable *compositeUTXO. Frozen coins
+// are excluded.
 func (dcr *ExchangeWallet) spendableUTXOs() ([]*compositeUTXO, error) {
+	return dcr.unlockedUTXOs(false)
+}
+
+// unlockedUTXOs is like spendableUTXOs, but frozen coins are only excluded if
+// withFrozen is false.
+func (dcr *ExchangeWallet) unlockedUTXOs(withFrozen bool) ([]*compositeUTXO, error) {
 	accts := dcr.wallet.Accounts()
 	unspents, err := dcr.wallet.Unspents(dcr.ctx, accts.PrimaryAccount)
 	if err != nil {
@@ -2580,6 +2604,17 @@ func (dcr *ExchangeWallet) spendableUTXOs() ([]*compositeUTXO, error) {
 		}
 		unspents = append(unspents, tradingAcctSpendables...)
 	}
+	if !withFrozen {
+		var i int
+		for _, u := range unspents {
+			if txHash, err := chainhash.NewHashFromStr(u.TxID); err == nil && dcr.coinCtl.isFrozen(newOutPoint(txHash, u.Vout)) {
+				continue
+			}
+			unspents[i] = u
+			i++
+		}
+		unspents = unspents[:i]
+	}
 	if len(unspents) == 0 {
 		return nil, fmt.Errorf("insufficient funds. 0 DCR available to spend in account %q", accts.PrimaryAccount)
 	}
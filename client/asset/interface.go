@@ -36,6 +36,8 @@ const (
 	WalletTraitHistorian                              // This wallet can return its transaction history
 	WalletTraitFundsMixer                             // The wallet can mix funds.
 	WalletTraitDynamicSwapper                         // The wallet has dynamic fees.
+	WalletTraitFeeBumper                              // The wallet can bump the fees of its redeem and refund transactions.
+	WalletTraitCoinController                         // The wallet can list, label and freeze its UTXOs.
 )
 
 // IsRescanner tests if the WalletTrait has the WalletTraitRescanner bit set.
@@ -149,6 +151,18 @@ func (wt WalletTrait) IsDynamicSwapper() bool {
 	return wt&WalletTraitDynamicSwapper != 0
 }
 
+// IsFeeBumper tests if the WalletTrait has the WalletTraitFeeBumper bit set,
+// which indicates the wallet implements the FeeBumper interface.
+func (wt WalletTrait) IsFeeBumper() bool {
+	return wt&WalletTraitFeeBumper != 0
+}
+
+// IsCoinController tests if the WalletTrait has the WalletTraitCoinController
+// bit set, which indicates the wallet implements the CoinController interface.
+func (wt WalletTrait) IsCoinController() bool {
+	return wt&WalletTraitCoinController != 0
+}
+
 // DetermineWalletTraits returns the WalletTrait bitset for the provided Wallet.
 func DetermineWalletTraits(w Wallet) (t WalletTrait) {
 	if _, is := w.(Rescanner); is {
@@ -205,6 +219,12 @@ func DetermineWalletTraits(w Wallet) (t WalletTrait) {
 	if _, is := w.(DynamicSwapper); is {
 		t |= WalletTraitDynamicSwapper
 	}
+	if _, is := w.(FeeBumper); is {
+		t |= WalletTraitFeeBumper
+	}
+	if _, is := w.(CoinController); is {
+		t |= WalletTraitCoinController
+	}
 	return t
 }
 
@@ -253,6 +273,11 @@ const (
 	// that has not been approved.
 	ErrUnapprovedToken = dex.ErrorKind("token not approved")
 	ErrApprovalPending = dex.ErrorKind("approval pending")
+	// ErrSignaturePending is returned from Swap, Redeem or Refund when the
+	// transaction has been handed to an external signer but is not yet
+	// signed. The caller should retry the same request later, and the wallet
+	// will broadcast the transaction once the signature is available.
+	ErrSignaturePending = dex.ErrorKind("signature pending")
 
 	// InternalNodeLoggerName is the name for a logger that is used to fine
 	// tune log levels for only loggers using this name.
@@ -846,6 +871,89 @@ type Accelerator interface {
 		requiredForRemainingSwaps, feeSuggestion uint64) (uint64, *XYRange, *EarlyAcceleration, error)
 }
 
+// FeeBumper is a wallet that can raise the fee rate of its unconfirmed redeem
+// and refund transactions. Swap transactions are accelerated with an
+// Accelerator instead, since the swap coin IDs must not change.
+type FeeBumper interface {
+	// TxFeeRate returns the effective fee rate of the transaction that
+	// created coinID, including any child transactions the wallet has
+	// broadcast to bump it. If the transaction is mined, confirmed will be
+	// true and the fee rate is not meaningful.
+	TxFeeRate(coinID dex.Bytes) (feeRate uint64, confirmed bool, err error)
+	// BumpFee raises the effective fee rate of the unconfirmed transaction
+	// that created coinID to newFeeRate. If replace is true and the
+	// transaction can be replaced (RBF), the wallet may broadcast a
+	// replacement, and the ID of the replacement's corresponding coin is
+	// returned. Otherwise, the wallet broadcasts a child transaction spending
+	// the coin (CPFP), and coinID is returned. contract is the swap contract
+	// spent by the transaction, which is required to re-sign a replacement.
+	BumpFee(coinID, contract dex.Bytes, newFeeRate uint64, replace bool) (dex.Bytes, error)
+}
+
+// WalletUTXO is an unspent output controlled by a UTXO-based wallet.
+type WalletUTXO struct {
+	CoinID  dex.Bytes `json:"coinID"`
+	TxID    string    `json:"txID"`
+	Vout    uint32    `json:"vout"`
+	Address string    `json:"address"`
+	Amount  uint64    `json:"amount"`
+	Confs   uint32    `json:"confs"`
+	Label   string    `json:"label,omitempty"`
+	// Frozen coins are never selected automatically for sends, bonds or
+	// order funding.
+	Frozen bool `json:"frozen"`
+	// Locked coins are currently reserved, e.g. funding an order or bond.
+	Locked bool `json:"locked"`
+}
+
+// CoinController is a UTXO-based wallet that allows the user to inspect,
+// label, and freeze individual coins, and to choose the coins spent by a send.
+// Explicit coins for order funding are specified with Order.Coins.
+type CoinController interface {
+	// ListUTXOs lists the wallet's unspent outputs, including frozen and
+	// locked coins.
+	ListUTXOs() ([]*WalletUTXO, error)
+	// SetUTXOLabel sets the label for the coin. An empty label removes any
+	// existing label.
+	SetUTXOLabel(coinID dex.Bytes, label string) error
+	// FreezeUTXOs freezes or unfreezes the coins. Frozen coins are excluded
+	// from automatic coin selection.
+	FreezeUTXOs(coinIDs []dex.Bytes, freeze bool) error
+	// SendWithCoins sends the value to the address, spending only from the
+	// specified coins. Any change is returned to the wallet. If subtract is
+	// true, the fees are subtracted from the value. Frozen coins may be
+	// spent if they are specified explicitly.
+	SendWithCoins(address string, value, feeRate uint64, subtract bool, coinIDs []dex.Bytes) (Coin, error)
+}
+
+// TransparentExposure is a trade transaction that exposed funds on the
+// transparent chain of a wallet with a shielded pool.
+type TransparentExposure struct {
+	TxID      string          `json:"txID"`
+	Type      TransactionType `json:"type"`
+	Timestamp uint64          `json:"timestamp"`
+	// Addresses are the transparent addresses paid by the transaction.
+	Addresses []string `json:"addresses"`
+	// Reshielded are the Addresses whose funds have since been moved back
+	// into the shielded pool.
+	Reshielded []string `json:"reshielded"`
+}
+
+// PrivacyReport summarizes the transparent exposure of a wallet's trading.
+type PrivacyReport struct {
+	// Trades are the swap, redeem, refund and order funding transactions
+	// with transparent outputs, newest first.
+	Trades []*TransparentExposure `json:"trades"`
+	// TransparentBalance is the value still held at transparent addresses.
+	TransparentBalance uint64 `json:"transparentBalance"`
+}
+
+// PrivacyReporter is a wallet with a shielded pool that can report how its
+// trading has exposed funds on the transparent chain.
+type PrivacyReporter interface {
+	PrivacyReport() (*PrivacyReport, error)
+}
+
 // TokenConfig is required to OpenTokenWallet.
 type TokenConfig struct {
 	// AssetID of the token.
@@ -1414,6 +1522,9 @@ type Order struct {
 	// Options are options that corresponds to PreSwap.Options, as well as
 	// their values.
 	Options map[string]string
+	// Coins, if non-empty, restricts funding to a subset of the specified
+	// coins. Only supported by CoinController wallets.
+	Coins []dex.Bytes
 
 	// The following fields are only used for some assets where the redeemed/to
 	// asset may require funds in this "from" asset. For example, buying ERC20
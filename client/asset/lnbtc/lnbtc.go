@@ -0,0 +1,1097 @@
+// This code is available on the terms of the project LICENSE.md file,
+// also available online at https://blueoakcouncil.org/license/1.0.0.
+
+// Package lnbtc provides an exchange wallet for Bitcoin on the Lightning
+// Network, backed by an LND node.
+//
+// A Lightning swap is a payment to the counterparty's node that is locked by an
+// HTLC with the match's secret hash. The counterparty accepts the HTLC into a
+// hold invoice when auditing the contract, and settles the invoice with the
+// secret to redeem. If the invoice is never settled, the HTLC times out and the
+// funds return to the payer, so a refund requires no action beyond waiting for
+// the payment to fail.
+package lnbtc
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"decred.org/dcrdex/client/asset"
+	"decred.org/dcrdex/dex"
+	"decred.org/dcrdex/dex/config"
+	dexlnbtc "decred.org/dcrdex/dex/networks/lnbtc"
+	"github.com/btcsuite/btcd/btcec/v2"
+)
+
+const (
+	version = 0
+	BipID   = dexlnbtc.BipID
+
+	walletTypeLND = "lnd"
+
+	// defaultRoutingFee is the default fee limit, in sats, for a single swap
+	// payment when the server does not provide a max fee rate.
+	defaultRoutingFee = 10
+	// pollInterval is how often the node is polled for new blocks.
+	pollInterval = 10 * time.Second
+	// paymentRetryInterval is how often a swap payment that the
+	// counterparty has not yet accepted is retried.
+	paymentRetryInterval = 20 * time.Second
+	// requestTimeout is the timeout for LND requests not bound to a caller's
+	// context.
+	requestTimeout = 30 * time.Second
+)
+
+var errNotConnected = errors.New("wallet not connected")
+
+var (
+	configOpts = []*asset.ConfigOption{
+		{
+			Key:          "resthost",
+			DisplayName:  "REST Address",
+			Description:  "LND REST interface address, e.g. https://127.0.0.1:8080",
+			DefaultValue: "https://127.0.0.1:8080",
+		},
+		{
+			Key:         "macaroonpath",
+			DisplayName: "Macaroon Path",
+			Description: "Path to an LND macaroon with invoice, offchain and signer permissions, e.g. admin.macaroon",
+			Required:    true,
+		},
+		{
+			Key:         "tlscertpath",
+			DisplayName: "TLS Certificate Path",
+			Description: "Path to LND's tls.cert. Leave blank if the REST interface uses a certificate signed by a public CA.",
+		},
+	}
+	// WalletInfo defines some general information about a Lightning wallet.
+	WalletInfo = &asset.WalletInfo{
+		Name:              "Bitcoin (Lightning)",
+		SupportedVersions: []uint32{version},
+		UnitInfo:          dexlnbtc.UnitInfo,
+		AvailableWallets: []*asset.WalletDefinition{{
+			Type:        walletTypeLND,
+			Tab:         "External",
+			Description: "Connect to an LND node",
+			ConfigOpts:  configOpts,
+		}},
+	}
+)
+
+func init() {
+	asset.Register(BipID, &Driver{})
+}
+
+// Driver implements asset.Driver.
+type Driver struct{}
+
+// Open creates the Lightning exchange wallet.
+func (d *Driver) Open(cfg *asset.WalletConfig, logger dex.Logger, network dex.Network) (asset.Wallet, error) {
+	return NewWallet(cfg, logger, network)
+}
+
+// DecodeCoinID creates a human-readable representation of a coin ID for a
+// Lightning payment, which is the payment hash.
+func (d *Driver) DecodeCoinID(coinID []byte) (string, error) {
+	if len(coinID) != dexlnbtc.SecretHashSize {
+		// Funding coins are the UTF-8 encoded node pubkey.
+		if len(coinID) == dexlnbtc.PubKeySize*2 {
+			return string(coinID), nil
+		}
+		return "", fmt.Errorf("invalid coin ID length %d", len(coinID))
+	}
+	return hex.EncodeToString(coinID), nil
+}
+
+// Info returns basic information about the wallet and asset.
+func (d *Driver) Info() *asset.WalletInfo {
+	return WalletInfo
+}
+
+type walletConfig struct {
+	RESTHost     string `ini:"resthost"`
+	MacaroonPath string `ini:"macaroonpath"`
+	TLSCertPath  string `ini:"tlscertpath"`
+}
+
+// lnClient is the subset of the lndClient methods used by the wallet. An
+// interface enables testing with a stub node.
+type lnClient interface {
+	getInfo(ctx context.Context) (*nodeInfo, error)
+	channelBalance(ctx context.Context) (*channelBalance, error)
+	addHoldInvoice(ctx context.Context, paymentHash []byte, value uint64, cltvExpiry uint32, expiry time.Duration, memo string) (string, error)
+	settleInvoice(ctx context.Context, preimage []byte) error
+	cancelInvoice(ctx context.Context, paymentHash []byte) error
+	lookupInvoice(ctx context.Context, paymentHash []byte) (*invoice, error)
+	addInvoice(ctx context.Context, value uint64, memo string) (string, error)
+	decodePayReq(ctx context.Context, payReqStr string) (*payReq, error)
+	sendPayment(ctx context.Context, dest, paymentHash []byte, payReq string, value uint64, cltvDelta uint32, feeLimit uint64) (*payment, error)
+	lookupPayment(ctx context.Context, paymentHash []byte) (*payment, error)
+	signMessage(ctx context.Context, msg []byte) (string, error)
+}
+
+// pendingSwap is a swap payment that the counterparty has not yet accepted.
+// The swap value and max routing fee remain locked until the payment is
+// dispatched or abandoned.
+type pendingSwap struct {
+	contract *dexlnbtc.Contract
+	feeLimit uint64
+}
+
+func (s *pendingSwap) lockedAmt() uint64 {
+	return s.contract.Value + s.feeLimit
+}
+
+// ExchangeWallet is a wallet backed by an LND node.
+type ExchangeWallet struct {
+	ctx  context.Context // set in Connect
+	log  dex.Logger
+	net  dex.Network
+	emit *asset.WalletEmitter
+	node lnClient
+
+	pubKey    []byte // set in Connect
+	pubKeyStr string
+	tip       atomic.Uint32
+
+	lockedMtx sync.Mutex
+	locked    uint64
+
+	pendingMtx sync.Mutex
+	pending    map[[32]byte]*pendingSwap
+}
+
+var _ asset.Wallet = (*ExchangeWallet)(nil)
+var _ asset.AccountLocker = (*ExchangeWallet)(nil)
+
+// NewWallet is the exported constructor by which the DEX will import the
+// exchange wallet.
+func NewWallet(cfg *asset.WalletConfig, logger dex.Logger, network dex.Network) (*ExchangeWallet, error) {
+	var walletCfg walletConfig
+	if err := config.Unmapify(cfg.Settings, &walletCfg); err != nil {
+		return nil, fmt.Errorf("error parsing wallet config: %w", err)
+	}
+	if walletCfg.RESTHost == "" {
+		walletCfg.RESTHost = "https://127.0.0.1:8080"
+	}
+	if !strings.Contains(walletCfg.RESTHost, "://") {
+		walletCfg.RESTHost = "https://" + walletCfg.RESTHost
+	}
+	if walletCfg.MacaroonPath == "" {
+		return nil, errors.New("no macaroon path provided")
+	}
+	node, err := newLNDClient(walletCfg.RESTHost, walletCfg.MacaroonPath, walletCfg.TLSCertPath)
+	if err != nil {
+		return nil, err
+	}
+	return newWallet(node, cfg.Emit, logger, network), nil
+}
+
+func newWallet(node lnClient, emit *asset.WalletEmitter, logger dex.Logger, network dex.Network) *ExchangeWallet {
+	return &ExchangeWallet{
+		log:     logger,
+		net:     network,
+		emit:    emit,
+		node:    node,
+		pending: make(map[[32]byte]*pendingSwap),
+	}
+}
+
+// lndNetworks maps the dex.Network to LND's network name.
+var lndNetworks = map[dex.Network]string{
+	dex.Mainnet: "mainnet",
+	dex.Testnet: "testnet",
+	dex.Simnet:  "regtest",
+}
+
+// Connect connects to the node and starts the block and payment monitors.
+// Satisfies the dex.Connector interface.
+func (w *ExchangeWallet) Connect(ctx context.Context) (*sync.WaitGroup, error) {
+	info, err := w.node.getInfo(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error connecting to LND: %w", err)
+	}
+	if len(info.Chains) == 0 || info.Chains[0].Chain != "bitcoin" {
+		return nil, fmt.Errorf("LND node is not a bitcoin node")
+	}
+	if net := info.Chains[0].Network; net != lndNetworks[w.net] {
+		return nil, fmt.Errorf("LND node is on %s, but we are on %s", net, w.net)
+	}
+	pubKey, err := hex.DecodeString(info.IdentityPubkey)
+	if err != nil || len(pubKey) != dexlnbtc.PubKeySize {
+		return nil, fmt.Errorf("invalid node pubkey %q", info.IdentityPubkey)
+	}
+	w.ctx = ctx
+	w.pubKey = pubKey
+	w.pubKeyStr = info.IdentityPubkey
+	w.tip.Store(info.BlockHeight)
+	w.log.Infof("Connected to LND %s node %s (%s) at height %d", info.Version, info.Alias, info.IdentityPubkey, info.BlockHeight)
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		w.monitor(ctx)
+	}()
+	return &wg, nil
+}
+
+// monitor polls the node for new blocks and retries swap payments that the
+// counterparty has not yet accepted.
+func (w *ExchangeWallet) monitor(ctx context.Context) {
+	pollTicker := time.NewTicker(pollInterval)
+	defer pollTicker.Stop()
+	retryTicker := time.NewTicker(paymentRetryInterval)
+	defer retryTicker.Stop()
+	for {
+		select {
+		case <-pollTicker.C:
+			info, err := w.node.getInfo(ctx)
+			if err != nil {
+				w.log.Errorf("getinfo error: %v", err)
+				continue
+			}
+			if info.BlockHeight != w.tip.Swap(info.BlockHeight) {
+				w.emit.TipChange(uint64(info.BlockHeight))
+			}
+		case <-retryTicker.C:
+			w.retryPendingSwaps(ctx)
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// retryPendingSwaps attempts to dispatch swap payments that previously failed
+// because the counterparty had not yet added the hold invoice. Pending swaps
+// are abandoned once their lock time passes.
+func (w *ExchangeWallet) retryPendingSwaps(ctx context.Context) {
+	w.pendingMtx.Lock()
+	pending := make([]*pendingSwap, 0, len(w.pending))
+	for h, s := range w.pending {
+		if time.Now().After(s.contract.Expiration()) {
+			w.log.Warnf("Abandoning swap payment %x. Counterparty never accepted the HTLC.", h)
+			delete(w.pending, h)
+			w.unlockFunds(s.lockedAmt())
+			continue
+		}
+		pending = append(pending, s)
+	}
+	w.pendingMtx.Unlock()
+
+	for _, s := range pending {
+		if w.dispatchSwap(ctx, s) {
+			w.removePendingSwap(s.contract.SecretHash)
+		}
+	}
+}
+
+// removePendingSwap stops retries of the swap payment and unlocks the funds
+// reserved for it.
+func (w *ExchangeWallet) removePendingSwap(secretHash [32]byte) {
+	w.pendingMtx.Lock()
+	s, found := w.pending[secretHash]
+	delete(w.pending, secretHash)
+	w.pendingMtx.Unlock()
+	if found {
+		w.unlockFunds(s.lockedAmt())
+	}
+}
+
+// requestContext is a context for a node request, derived from the Connect
+// context.
+func (w *ExchangeWallet) requestContext(timeout time.Duration) (context.Context, context.CancelFunc, error) {
+	if w.ctx == nil {
+		return nil, nil, errNotConnected
+	}
+	ctx, cancel := context.WithTimeout(w.ctx, timeout)
+	return ctx, cancel, nil
+}
+
+// dispatchSwap sends the swap payment, returning true if the payment is in
+// flight or otherwise no longer needs to be retried.
+func (w *ExchangeWallet) dispatchSwap(ctx context.Context, s *pendingSwap) bool {
+	c := s.contract
+	ctx, cancel := context.WithTimeout(ctx, requestTimeout)
+	defer cancel()
+	p, err := w.node.sendPayment(ctx, c.Payee[:], c.SecretHash[:], "", c.Value,
+		dexlnbtc.CLTVDelta(c.Expiration(), time.Now()), s.feeLimit)
+	if err != nil {
+		w.log.Debugf("Error sending swap payment %x: %v", c.SecretHash, err)
+		return false
+	}
+	switch p.Status {
+	case paymentStatusInFlight, paymentStatusSucceeded:
+		w.log.Infof("Swap payment %x of %s is %s", c.SecretHash, w.amtString(c.Value), p.Status)
+		return true
+	}
+	if p.FailureReason == failureReasonIncorrectDetails {
+		w.log.Debugf("Swap payment %x not yet accepted by counterparty", c.SecretHash)
+	} else {
+		w.log.Warnf("Swap payment %x failed: %s", c.SecretHash, p.FailureReason)
+	}
+	return false
+}
+
+func (w *ExchangeWallet) amtString(v uint64) string {
+	return dexlnbtc.UnitInfo.FormatAtoms(v)
+}
+
+// Info returns basic information about the wallet and asset.
+func (w *ExchangeWallet) Info() *asset.WalletInfo {
+	return WalletInfo
+}
+
+// Balance returns the wallet's channel balance. Funds locked in outgoing
+// HTLCs are reported as locked.
+func (w *ExchangeWallet) Balance() (*asset.Balance, error) {
+	ctx, cancel, err := w.requestContext(requestTimeout)
+	if err != nil {
+		return nil, err
+	}
+	defer cancel()
+	bal, err := w.node.channelBalance(ctx)
+	if err != nil {
+		return nil, err
+	}
+	w.lockedMtx.Lock()
+	locked := w.locked
+	w.lockedMtx.Unlock()
+	local := uint64(bal.LocalBalance.Sat)
+	var avail uint64
+	if local > locked {
+		avail = local - locked
+	}
+	return &asset.Balance{
+		Available: avail,
+		Immature:  uint64(bal.PendingOpenLocal.Sat),
+		Locked:    locked + uint64(bal.UnsettledLocalBalace.Sat),
+	}, nil
+}
+
+// lockFunds locks the amount if it is available.
+func (w *ExchangeWallet) lockFunds(amt uint64) error {
+	bal, err := w.Balance()
+	if err != nil {
+		return err
+	}
+	if bal.Available < amt {
+		return fmt.Errorf("%w: %s available, %s requested", asset.ErrInsufficientBalance,
+			w.amtString(bal.Available), w.amtString(amt))
+	}
+	w.lockedMtx.Lock()
+	w.locked += amt
+	w.lockedMtx.Unlock()
+	return nil
+}
+
+func (w *ExchangeWallet) unlockFunds(amt uint64) {
+	w.lockedMtx.Lock()
+	defer w.lockedMtx.Unlock()
+	if amt > w.locked {
+		w.log.Errorf("Attempting to unlock more than locked. %d > %d", amt, w.locked)
+		w.locked = 0
+		return
+	}
+	w.locked -= amt
+}
+
+// FundOrder locks the order value and the maximum routing fees.
+func (w *ExchangeWallet) FundOrder(ord *asset.Order) (asset.Coins, []dex.Bytes, uint64, error) {
+	amt := ord.Value + ord.MaxSwapCount*w.routingFeeLimit(ord.MaxFeeRate)
+	if err := w.lockFunds(amt); err != nil {
+		return nil, nil, 0, err
+	}
+	return asset.Coins{w.fundingCoin(amt)}, []dex.Bytes{nil}, 0, nil
+}
+
+// FundMultiOrder funds multiple orders, stopping at the first order that
+// can't be funded.
+func (w *ExchangeWallet) FundMultiOrder(ord *asset.MultiOrder, maxLock uint64) ([]asset.Coins, [][]dex.Bytes, uint64, error) {
+	coins := make([]asset.Coins, 0, len(ord.Values))
+	redeemScripts := make([][]dex.Bytes, 0, len(ord.Values))
+	var total uint64
+	for _, v := range ord.Values {
+		amt := v.Value + v.MaxSwapCount*w.routingFeeLimit(ord.MaxFeeRate)
+		if maxLock > 0 && total+amt > maxLock {
+			break
+		}
+		if err := w.lockFunds(amt); err != nil {
+			if len(coins) == 0 {
+				return nil, nil, 0, err
+			}
+			break
+		}
+		total += amt
+		coins = append(coins, asset.Coins{w.fundingCoin(amt)})
+		redeemScripts = append(redeemScripts, []dex.Bytes{nil})
+	}
+	return coins, redeemScripts, 0, nil
+}
+
+// routingFeeLimit is the routing fee limit for a single swap payment. The
+// server's fee rate for Lightning is the max routing fee per payment.
+func (w *ExchangeWallet) routingFeeLimit(maxFeeRate uint64) uint64 {
+	if maxFeeRate == 0 {
+		return defaultRoutingFee
+	}
+	return maxFeeRate
+}
+
+// MaxOrder generates information about the maximum order size and associated
+// fees that the wallet can support for the given lot size.
+func (w *ExchangeWallet) MaxOrder(form *asset.MaxOrderForm) (*asset.SwapEstimate, error) {
+	bal, err := w.Balance()
+	if err != nil {
+		return nil, err
+	}
+	feeLimit := w.routingFeeLimit(form.MaxFeeRate)
+	lots := bal.Available / (form.LotSize + feeLimit)
+	return w.estimateSwap(lots, form.LotSize, feeLimit, form.FeeSuggestion), nil
+}
+
+// PreSwap gets order estimates based on the available funds.
+func (w *ExchangeWallet) PreSwap(form *asset.PreSwapForm) (*asset.PreSwap, error) {
+	feeLimit := w.routingFeeLimit(form.MaxFeeRate)
+	est := w.estimateSwap(form.Lots, form.LotSize, feeLimit, form.FeeSuggestion)
+	if bal, err := w.Balance(); err != nil {
+		return nil, err
+	} else if bal.Available < est.Value+est.MaxFees {
+		return nil, fmt.Errorf("%w: %s available, %s required", asset.ErrInsufficientBalance,
+			w.amtString(bal.Available), w.amtString(est.Value+est.MaxFees))
+	}
+	return &asset.PreSwap{Estimate: est}, nil
+}
+
+func (w *ExchangeWallet) estimateSwap(lots, lotSize, feeLimit, feeSuggestion uint64) *asset.SwapEstimate {
+	if feeSuggestion > feeLimit {
+		feeSuggestion = feeLimit
+	}
+	var bestCase uint64
+	if lots > 0 {
+		bestCase = feeSuggestion
+	}
+	return &asset.SwapEstimate{
+		Lots:               lots,
+		Value:              lots * lotSize,
+		MaxFees:            lots * feeLimit,
+		RealisticWorstCase: lots * feeSuggestion,
+		RealisticBestCase:  bestCase,
+		FeeReservesPerLot:  feeLimit,
+	}
+}
+
+// PreRedeem generates an estimate of the range of redemption fees. Settling a
+// hold invoice is free.
+func (w *ExchangeWallet) PreRedeem(*asset.PreRedeemForm) (*asset.PreRedeem, error) {
+	return &asset.PreRedeem{Estimate: &asset.RedeemEstimate{}}, nil
+}
+
+// ReturnCoins unlocks the funding coins. A nil Coins unlocks all order
+// funding. Funds reserved for swaps not yet dispatched remain locked.
+func (w *ExchangeWallet) ReturnCoins(coins asset.Coins) error {
+	if coins == nil {
+		var pendingLocked uint64
+		w.pendingMtx.Lock()
+		for _, s := range w.pending {
+			pendingLocked += s.lockedAmt()
+		}
+		w.pendingMtx.Unlock()
+		w.lockedMtx.Lock()
+		w.locked = pendingLocked
+		w.lockedMtx.Unlock()
+		return nil
+	}
+	var amt uint64
+	for _, c := range coins {
+		fc, is := c.(*fundingCoin)
+		if !is {
+			return fmt.Errorf("unknown coin type %T", c)
+		}
+		amt += fc.amt
+	}
+	w.unlockFunds(amt)
+	return nil
+}
+
+// FundingCoins re-locks the funding coins with the recovery IDs.
+func (w *ExchangeWallet) FundingCoins(ids []dex.Bytes) (asset.Coins, error) {
+	coins := make(asset.Coins, 0, len(ids))
+	var amt uint64
+	for _, id := range ids {
+		fc, err := decodeFundingCoin(id)
+		if err != nil {
+			return nil, err
+		}
+		if !strings.EqualFold(fc.pubKey, w.pubKeyStr) {
+			return nil, fmt.Errorf("funding coin is for node %s, not %s", fc.pubKey, w.pubKeyStr)
+		}
+		amt += fc.amt
+		coins = append(coins, fc)
+	}
+	if err := w.lockFunds(amt); err != nil {
+		return nil, err
+	}
+	return coins, nil
+}
+
+// Swap dispatches HTLC payments to the counterparties' nodes. A payment that
+// the counterparty has not yet accepted is retried in the background until
+// the contract lock time.
+func (w *ExchangeWallet) Swap(swaps *asset.Swaps) ([]asset.Receipt, asset.Coin, uint64, error) {
+	var inputsValue, swapValue uint64
+	for _, c := range swaps.Inputs {
+		inputsValue += c.Value()
+	}
+	contracts := make([]*dexlnbtc.Contract, 0, len(swaps.Contracts))
+	for _, sc := range swaps.Contracts {
+		payee, err := hex.DecodeString(sc.Address)
+		if err != nil || len(payee) != dexlnbtc.PubKeySize {
+			return nil, nil, 0, fmt.Errorf("invalid node pubkey %q", sc.Address)
+		}
+		if len(sc.SecretHash) != dexlnbtc.SecretHashSize {
+			return nil, nil, 0, fmt.Errorf("invalid secret hash length %d", len(sc.SecretHash))
+		}
+		c := &dexlnbtc.Contract{
+			SecretHash: [32]byte(sc.SecretHash),
+			LockTime:   sc.LockTime,
+			Value:      sc.Value,
+			Payee:      [33]byte(payee),
+		}
+		contracts = append(contracts, c)
+		swapValue += sc.Value
+	}
+	feeLimit := w.routingFeeLimit(swaps.FeeRate)
+	maxCost := swapValue + uint64(len(contracts))*feeLimit
+	if maxCost > inputsValue {
+		return nil, nil, 0, fmt.Errorf("swap value + max fees %d > inputs value %d", maxCost, inputsValue)
+	}
+	if w.ctx == nil {
+		return nil, nil, 0, errNotConnected
+	}
+
+	receipts := make([]asset.Receipt, 0, len(contracts))
+	var pendingLocked uint64
+	for _, c := range contracts {
+		s := &pendingSwap{contract: c, feeLimit: feeLimit}
+		if !w.dispatchSwap(w.ctx, s) {
+			w.pendingMtx.Lock()
+			w.pending[c.SecretHash] = s
+			w.pendingMtx.Unlock()
+			pendingLocked += s.lockedAmt()
+		}
+		receipts = append(receipts, &swapReceipt{
+			contract: c,
+			coin:     &paymentCoin{hash: c.SecretHash, value: c.Value},
+		})
+	}
+
+	// The inputs are no longer locked as order funding. Routing fees are
+	// paid from the channel balance when the HTLCs settle. Funds for swaps
+	// that are not yet dispatched stay locked until they are.
+	w.unlockFunds(inputsValue - pendingLocked)
+	var change asset.Coin
+	if swaps.LockChange {
+		changeAmt := inputsValue - maxCost
+		if err := w.lockFunds(changeAmt); err != nil {
+			w.log.Errorf("Error locking change: %v", err)
+		} else {
+			change = w.fundingCoin(changeAmt)
+		}
+	}
+	return receipts, change, 0, nil
+}
+
+// Redeem settles the hold invoices for the counterparties' swaps.
+func (w *ExchangeWallet) Redeem(form *asset.RedeemForm) ([]dex.Bytes, asset.Coin, uint64, error) {
+	ins := make([]dex.Bytes, 0, len(form.Redemptions))
+	var total uint64
+	var lastHash [32]byte
+	for _, r := range form.Redemptions {
+		c, err := dexlnbtc.DecodeContract(r.Spends.Contract)
+		if err != nil {
+			return nil, nil, 0, err
+		}
+		if !dexlnbtc.ValidateSecret(r.Secret, c.SecretHash[:]) {
+			return nil, nil, 0, fmt.Errorf("invalid secret for payment hash %x", c.SecretHash)
+		}
+		ctx, cancel, err := w.requestContext(requestTimeout)
+		if err != nil {
+			return nil, nil, 0, err
+		}
+		err = w.node.settleInvoice(ctx, r.Secret)
+		cancel()
+		if err != nil {
+			return nil, nil, 0, fmt.Errorf("error settling invoice %x: %w", c.SecretHash, err)
+		}
+		ins = append(ins, c.SecretHash[:])
+		total += r.Spends.Coin.Value()
+		lastHash = c.SecretHash
+	}
+	return ins, &paymentCoin{hash: lastHash, value: total}, 0, nil
+}
+
+// SignMessage signs the message with the node's identity key.
+func (w *ExchangeWallet) SignMessage(_ asset.Coin, msg dex.Bytes) (pubkeys, sigs []dex.Bytes, err error) {
+	ctx, cancel, err := w.requestContext(requestTimeout)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer cancel()
+	sigStr, err := w.node.signMessage(ctx, msg)
+	if err != nil {
+		return nil, nil, err
+	}
+	sig, err := dexlnbtc.DecodeZBase32(sigStr)
+	if err != nil {
+		return nil, nil, err
+	}
+	return []dex.Bytes{w.pubKey}, []dex.Bytes{sig}, nil
+}
+
+// AuditContract adds a hold invoice for the counterparty's swap, if it
+// doesn't already exist, and checks that the HTLC has been accepted. An
+// asset.CoinNotFoundError is returned until the HTLC is accepted.
+func (w *ExchangeWallet) AuditContract(coinID, contract, _ dex.Bytes, _ bool) (*asset.AuditInfo, error) {
+	c, err := dexlnbtc.DecodeContract(contract)
+	if err != nil {
+		return nil, err
+	}
+	if len(coinID) != dexlnbtc.SecretHashSize || [32]byte(coinID) != c.SecretHash {
+		return nil, fmt.Errorf("coin ID %x does not match contract payment hash %x", coinID, c.SecretHash)
+	}
+	if c.Payee != [33]byte(w.pubKey) {
+		return nil, fmt.Errorf("contract payee %x is not our node", c.Payee)
+	}
+	ctx, cancel, err := w.requestContext(requestTimeout)
+	if err != nil {
+		return nil, err
+	}
+	defer cancel()
+	inv, err := w.node.lookupInvoice(ctx, c.SecretHash[:])
+	if errors.Is(err, errNotFound) {
+		expiry := time.Until(c.Expiration())
+		if expiry <= 0 {
+			return nil, fmt.Errorf("contract %x has expired", c.SecretHash)
+		}
+		if _, err = w.node.addHoldInvoice(ctx, c.SecretHash[:], c.Value, dexlnbtc.MinCLTVDelta, expiry, "DEX swap"); err != nil {
+			return nil, fmt.Errorf("error adding hold invoice: %w", err)
+		}
+		w.log.Infof("Added hold invoice %x for %s", c.SecretHash, w.amtString(c.Value))
+		return nil, asset.CoinNotFoundError
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error looking up invoice: %w", err)
+	}
+	switch inv.State {
+	case invoiceStateOpen:
+		return nil, asset.CoinNotFoundError
+	case invoiceStateCanceled:
+		return nil, fmt.Errorf("hold invoice %x was canceled", c.SecretHash)
+	}
+	// ACCEPTED or SETTLED.
+	if paid := uint64(inv.AmtPaidSat); paid < c.Value {
+		return nil, fmt.Errorf("HTLC amount %d < contract value %d", paid, c.Value)
+	}
+	return &asset.AuditInfo{
+		Recipient:  w.pubKeyStr,
+		Expiration: c.Expiration(),
+		Coin:       &paymentCoin{hash: c.SecretHash, value: c.Value},
+		Contract:   contract,
+		SecretHash: c.SecretHash[:],
+	}, nil
+}
+
+// ContractLockTimeExpired returns true if the contract's lock time has passed.
+func (w *ExchangeWallet) ContractLockTimeExpired(ctx context.Context, contract dex.Bytes) (bool, time.Time, error) {
+	c, err := dexlnbtc.DecodeContract(contract)
+	if err != nil {
+		return false, time.Time{}, err
+	}
+	expired, err := w.LockTimeExpired(ctx, c.Expiration())
+	return expired, c.Expiration(), err
+}
+
+// LockTimeExpired returns true if the lock time has passed. HTLC expiry is by
+// block height, but the lock time is a close approximation.
+func (w *ExchangeWallet) LockTimeExpired(_ context.Context, lockTime time.Time) (bool, error) {
+	return time.Now().After(lockTime), nil
+}
+
+// FindRedemption waits for our swap payment to be settled by the counterparty,
+// and returns the secret.
+func (w *ExchangeWallet) FindRedemption(ctx context.Context, coinID, _ dex.Bytes) (redemptionCoin, secret dex.Bytes, err error) {
+	for {
+		p, err := w.node.lookupPayment(ctx, coinID)
+		if err != nil && !errors.Is(err, errNotFound) {
+			return nil, nil, err
+		}
+		if p != nil {
+			switch p.Status {
+			case paymentStatusSucceeded:
+				secret, err := hex.DecodeString(p.PaymentPreimage)
+				if err != nil {
+					return nil, nil, fmt.Errorf("invalid preimage %q", p.PaymentPreimage)
+				}
+				return coinID, secret, nil
+			case paymentStatusFailed:
+				return nil, nil, asset.ErrSwapRefunded
+			}
+		}
+		select {
+		case <-time.After(pollInterval):
+		case <-ctx.Done():
+			return nil, nil, ctx.Err()
+		}
+	}
+}
+
+// Refund stops any retries of the swap payment and reports whether the HTLC
+// has timed out. Lightning refunds happen automatically when the HTLC fails,
+// so no transaction is broadcast. An asset.CoinNotFoundError is returned if
+// the counterparty has already redeemed.
+func (w *ExchangeWallet) Refund(coinID, contract dex.Bytes, _ uint64) (dex.Bytes, error) {
+	c, err := dexlnbtc.DecodeContract(contract)
+	if err != nil {
+		return nil, err
+	}
+	w.removePendingSwap(c.SecretHash)
+
+	ctx, cancel, err := w.requestContext(requestTimeout)
+	if err != nil {
+		return nil, err
+	}
+	defer cancel()
+	p, err := w.node.lookupPayment(ctx, coinID)
+	if errors.Is(err, errNotFound) {
+		// Never accepted by the counterparty.
+		return coinID, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	switch p.Status {
+	case paymentStatusFailed:
+		return coinID, nil
+	case paymentStatusSucceeded:
+		return nil, asset.CoinNotFoundError
+	}
+	return nil, fmt.Errorf("swap payment %x is still in flight", c.SecretHash)
+}
+
+// DepositAddress returns a zero-amount invoice that can be paid to deposit
+// funds into the wallet.
+func (w *ExchangeWallet) DepositAddress() (string, error) {
+	ctx, cancel, err := w.requestContext(requestTimeout)
+	if err != nil {
+		return "", err
+	}
+	defer cancel()
+	return w.node.addInvoice(ctx, 0, "Bison Wallet deposit")
+}
+
+// OwnsDepositAddress checks that the payment request is payable to our node.
+func (w *ExchangeWallet) OwnsDepositAddress(addr string) (bool, error) {
+	ctx, cancel, err := w.requestContext(requestTimeout)
+	if err != nil {
+		return false, err
+	}
+	defer cancel()
+	pr, err := w.node.decodePayReq(ctx, addr)
+	if err != nil {
+		return false, err
+	}
+	return strings.EqualFold(pr.Destination, w.pubKeyStr), nil
+}
+
+// RedemptionAddress is our node's public key.
+func (w *ExchangeWallet) RedemptionAddress() (string, error) {
+	return w.pubKeyStr, nil
+}
+
+// SwapConfirmations checks the status of a swap. For our own swaps, the
+// outgoing payment is checked. For the counterparty's swaps, the hold invoice
+// is checked. An HTLC that is in flight or accepted has 1 confirmation.
+func (w *ExchangeWallet) SwapConfirmations(ctx context.Context, coinID dex.Bytes, _ dex.Bytes, _ time.Time) (confs uint32, spent bool, err error) {
+	p, err := w.node.lookupPayment(ctx, coinID)
+	if err == nil {
+		switch p.Status {
+		case paymentStatusSucceeded:
+			return 1, true, nil
+		case paymentStatusFailed:
+			return 0, false, asset.ErrSwapRefunded
+		}
+		return 1, false, nil
+	}
+	if !errors.Is(err, errNotFound) {
+		return 0, false, err
+	}
+	inv, err := w.node.lookupInvoice(ctx, coinID)
+	if errors.Is(err, errNotFound) {
+		return 0, false, asset.CoinNotFoundError
+	}
+	if err != nil {
+		return 0, false, err
+	}
+	switch inv.State {
+	case invoiceStateAccepted:
+		return 1, false, nil
+	case invoiceStateSettled:
+		return 1, true, nil
+	case invoiceStateCanceled:
+		return 0, false, asset.ErrSwapRefunded
+	}
+	return 0, false, nil
+}
+
+// ValidateSecret checks that the secret hashes to the secret hash.
+func (w *ExchangeWallet) ValidateSecret(secret, secretHash []byte) bool {
+	return dexlnbtc.ValidateSecret(secret, secretHash)
+}
+
+// SyncStatus is the node's chain and graph sync status.
+func (w *ExchangeWallet) SyncStatus() (*asset.SyncStatus, error) {
+	ctx, cancel, err := w.requestContext(requestTimeout)
+	if err != nil {
+		return nil, err
+	}
+	defer cancel()
+	info, err := w.node.getInfo(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &asset.SyncStatus{
+		Synced:       info.SyncedToChain && info.SyncedToGraph,
+		TargetHeight: uint64(info.BlockHeight),
+		Blocks:       uint64(info.BlockHeight),
+	}, nil
+}
+
+// RegFeeConfirmations is not supported for Lightning.
+func (w *ExchangeWallet) RegFeeConfirmations(context.Context, dex.Bytes) (uint32, error) {
+	return 0, asset.ErrUnsupported
+}
+
+// Send pays the BOLT11 payment request. If the payment request has no
+// amount, the value is paid. Otherwise, value must match the invoice amount.
+func (w *ExchangeWallet) Send(addr string, value, feeRate uint64) (asset.Coin, error) {
+	ctx, cancel, err := w.requestContext(requestTimeout * 2)
+	if err != nil {
+		return nil, err
+	}
+	defer cancel()
+	pr, err := w.node.decodePayReq(ctx, addr)
+	if err != nil {
+		return nil, fmt.Errorf("error decoding payment request: %w", err)
+	}
+	amt := value
+	if pr.NumSatoshis != 0 {
+		if uint64(pr.NumSatoshis) != value {
+			return nil, fmt.Errorf("invoice amount %d != send value %d", pr.NumSatoshis, value)
+		}
+		amt = 0
+	}
+	p, err := w.node.sendPayment(ctx, nil, nil, addr, amt, 0, w.routingFeeLimit(feeRate))
+	if err != nil {
+		return nil, err
+	}
+	if p.Status != paymentStatusSucceeded {
+		return nil, fmt.Errorf("payment failed: %s", p.FailureReason)
+	}
+	hash, err := hex.DecodeString(p.PaymentHash)
+	if err != nil || len(hash) != dexlnbtc.SecretHashSize {
+		return nil, fmt.Errorf("invalid payment hash %q", p.PaymentHash)
+	}
+	return &paymentCoin{hash: [32]byte(hash), value: value}, nil
+}
+
+// ValidateAddress checks that the address is either a node public key, for
+// swaps, or a BOLT11 payment request, for sends.
+func (w *ExchangeWallet) ValidateAddress(addr string) bool {
+	if b, err := hex.DecodeString(addr); err == nil {
+		_, err = btcec.ParsePubKey(b)
+		return err == nil
+	}
+	ctx, cancel, err := w.requestContext(requestTimeout)
+	if err != nil {
+		return false
+	}
+	defer cancel()
+	_, err = w.node.decodePayReq(ctx, addr)
+	return err == nil
+}
+
+// ConfirmRedemption checks that the hold invoice is settled, and attempts to
+// settle it again if it is still only accepted.
+func (w *ExchangeWallet) ConfirmRedemption(coinID dex.Bytes, redemption *asset.Redemption, _ uint64) (*asset.ConfirmRedemptionStatus, error) {
+	ctx, cancel, err := w.requestContext(requestTimeout)
+	if err != nil {
+		return nil, err
+	}
+	defer cancel()
+	inv, err := w.node.lookupInvoice(ctx, redemption.Spends.SecretHash)
+	if err != nil {
+		return nil, err
+	}
+	switch inv.State {
+	case invoiceStateSettled:
+		return &asset.ConfirmRedemptionStatus{Confs: 1, Req: 1, CoinID: coinID}, nil
+	case invoiceStateAccepted:
+		if err := w.node.settleInvoice(ctx, redemption.Secret); err != nil {
+			return nil, fmt.Errorf("error settling invoice: %w", err)
+		}
+		return &asset.ConfirmRedemptionStatus{Confs: 0, Req: 1, CoinID: coinID}, nil
+	}
+	return nil, fmt.Errorf("cannot confirm redemption of invoice in state %s", inv.State)
+}
+
+// SingleLotSwapRefundFees returns the max routing fee for a single swap.
+// Refunds are free.
+func (w *ExchangeWallet) SingleLotSwapRefundFees(_ uint32, feeRate uint64, _ bool) (uint64, uint64, error) {
+	return w.routingFeeLimit(feeRate), 0, nil
+}
+
+// SingleLotRedeemFees returns zero. Settling an invoice is free.
+func (w *ExchangeWallet) SingleLotRedeemFees(uint32, uint64) (uint64, error) {
+	return 0, nil
+}
+
+// StandardSendFee returns the routing fee limit for a send.
+func (w *ExchangeWallet) StandardSendFee(feeRate uint64) uint64 {
+	return w.routingFeeLimit(feeRate)
+}
+
+// MaxFundingFees returns zero. There are no funding transactions.
+func (w *ExchangeWallet) MaxFundingFees(uint32, uint64, map[string]string) uint64 {
+	return 0
+}
+
+// ReserveNRedemptions returns zero. Redemptions are free.
+func (w *ExchangeWallet) ReserveNRedemptions(uint64, uint32, uint64) (uint64, error) {
+	return 0, nil
+}
+
+// ReReserveRedemption is a no-op. Redemptions are free.
+func (w *ExchangeWallet) ReReserveRedemption(uint64) error {
+	return nil
+}
+
+// UnlockRedemptionReserves is a no-op. Redemptions are free.
+func (w *ExchangeWallet) UnlockRedemptionReserves(uint64) {}
+
+// ReserveNRefunds returns zero. Refunds are free.
+func (w *ExchangeWallet) ReserveNRefunds(uint64, uint32, uint64) (uint64, error) {
+	return 0, nil
+}
+
+// ReReserveRefund is a no-op. Refunds are free.
+func (w *ExchangeWallet) ReReserveRefund(uint64) error {
+	return nil
+}
+
+// UnlockRefundReserves is a no-op. Refunds are free.
+func (w *ExchangeWallet) UnlockRefundReserves(uint64) {}
+
+func (w *ExchangeWallet) fundingCoin(amt uint64) *fundingCoin {
+	return &fundingCoin{pubKey: w.pubKeyStr, amt: amt}
+}
+
+// fundingCoin is channel balance locked for an order.
+type fundingCoin struct {
+	pubKey string
+	amt    uint64
+}
+
+var _ asset.RecoveryCoin = (*fundingCoin)(nil)
+
+// ID is the UTF-8 encoded node pubkey, which is the account for account-based
+// order funding.
+func (c *fundingCoin) ID() dex.Bytes {
+	return []byte(c.pubKey)
+}
+
+func (c *fundingCoin) String() string {
+	return fmt.Sprintf("node: %s, amount: %d", c.pubKey, c.amt)
+}
+
+func (c *fundingCoin) Value() uint64 {
+	return c.amt
+}
+
+func (c *fundingCoin) TxID() string {
+	return ""
+}
+
+// RecoveryID is the node pubkey hex followed by the 8-byte amount.
+func (c *fundingCoin) RecoveryID() dex.Bytes {
+	b := make([]byte, len(c.pubKey)+8)
+	copy(b, c.pubKey)
+	binary.BigEndian.PutUint64(b[len(c.pubKey):], c.amt)
+	return b
+}
+
+func decodeFundingCoin(id []byte) (*fundingCoin, error) {
+	if len(id) != dexlnbtc.PubKeySize*2+8 {
+		return nil, fmt.Errorf("invalid funding coin ID length %d", len(id))
+	}
+	return &fundingCoin{
+		pubKey: string(id[:dexlnbtc.PubKeySize*2]),
+		amt:    binary.BigEndian.Uint64(id[dexlnbtc.PubKeySize*2:]),
+	}, nil
+}
+
+// paymentCoin is a Lightning payment, identified by the payment hash.
+type paymentCoin struct {
+	hash  [32]byte
+	value uint64
+}
+
+func (c *paymentCoin) ID() dex.Bytes {
+	return c.hash[:]
+}
+
+func (c *paymentCoin) String() string {
+	return hex.EncodeToString(c.hash[:])
+}
+
+func (c *paymentCoin) Value() uint64 {
+	return c.value
+}
+
+func (c *paymentCoin) TxID() string {
+	return c.String()
+}
+
+// swapReceipt implements asset.Receipt.
+type swapReceipt struct {
+	contract *dexlnbtc.Contract
+	coin     *paymentCoin
+}
+
+func (r *swapReceipt) Expiration() time.Time {
+	return r.contract.Expiration()
+}
+
+func (r *swapReceipt) Coin() asset.Coin {
+	return r.coin
+}
+
+func (r *swapReceipt) Contract() dex.Bytes {
+	return r.contract.Encode()
+}
+
+func (r *swapReceipt) String() string {
+	return fmt.Sprintf("{ payment hash = %x, payee = %x }", r.contract.SecretHash, r.contract.Payee)
+}
+
+// SignedRefund is nil. Lightning refunds require no transaction.
+func (r *swapReceipt) SignedRefund() dex.Bytes {
+	return nil
+}
@@ -0,0 +1,357 @@
+package lnbtc
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"testing"
+	"time"
+
+	"decred.org/dcrdex/client/asset"
+	"decred.org/dcrdex/dex"
+	dexlnbtc "decred.org/dcrdex/dex/networks/lnbtc"
+	"github.com/btcsuite/btcd/btcec/v2"
+)
+
+var tLogger = dex.StdOutLogger("LNTEST", dex.LevelTrace)
+
+type tNode struct {
+	info       *nodeInfo
+	bal        *channelBalance
+	invoices   map[[32]byte]*invoice
+	payments   map[[32]byte]*payment
+	sendResult *payment
+	sendErr    error
+	sent       int
+	settled    [][]byte
+}
+
+func newTNode(pubKey string) *tNode {
+	return &tNode{
+		info: &nodeInfo{
+			IdentityPubkey: pubKey,
+			BlockHeight:    100,
+			SyncedToChain:  true,
+			SyncedToGraph:  true,
+			Chains:         []chain{{Chain: "bitcoin", Network: "regtest"}},
+		},
+		bal:      &channelBalance{LocalBalance: amount{Sat: 1e6}},
+		invoices: make(map[[32]byte]*invoice),
+		payments: make(map[[32]byte]*payment),
+	}
+}
+
+func (n *tNode) getInfo(context.Context) (*nodeInfo, error) {
+	return n.info, nil
+}
+
+func (n *tNode) channelBalance(context.Context) (*channelBalance, error) {
+	return n.bal, nil
+}
+
+func (n *tNode) addHoldInvoice(_ context.Context, paymentHash []byte, value uint64, _ uint32, _ time.Duration, _ string) (string, error) {
+	n.invoices[[32]byte(paymentHash)] = &invoice{State: invoiceStateOpen, Value: jsonInt(value)}
+	return "lnbcrt1", nil
+}
+
+func (n *tNode) settleInvoice(_ context.Context, preimage []byte) error {
+	n.settled = append(n.settled, preimage)
+	h := sha256.Sum256(preimage)
+	if inv := n.invoices[h]; inv != nil {
+		inv.State = invoiceStateSettled
+	}
+	return nil
+}
+
+func (n *tNode) cancelInvoice(context.Context, []byte) error {
+	return nil
+}
+
+func (n *tNode) lookupInvoice(_ context.Context, paymentHash []byte) (*invoice, error) {
+	inv, found := n.invoices[[32]byte(paymentHash)]
+	if !found {
+		return nil, errNotFound
+	}
+	return inv, nil
+}
+
+func (n *tNode) addInvoice(context.Context, uint64, string) (string, error) {
+	return "lnbcrt1", nil
+}
+
+func (n *tNode) decodePayReq(context.Context, string) (*payReq, error) {
+	return &payReq{Destination: n.info.IdentityPubkey}, nil
+}
+
+func (n *tNode) sendPayment(_ context.Context, _, paymentHash []byte, _ string, _ uint64, _ uint32, _ uint64) (*payment, error) {
+	n.sent++
+	if n.sendErr != nil {
+		return nil, n.sendErr
+	}
+	if n.sendResult.Status != paymentStatusFailed {
+		n.payments[[32]byte(paymentHash)] = n.sendResult
+	}
+	return n.sendResult, nil
+}
+
+func (n *tNode) lookupPayment(_ context.Context, paymentHash []byte) (*payment, error) {
+	p, found := n.payments[[32]byte(paymentHash)]
+	if !found {
+		return nil, errNotFound
+	}
+	return p, nil
+}
+
+func (n *tNode) signMessage(context.Context, []byte) (string, error) {
+	return "", nil
+}
+
+func tNewWallet(t *testing.T) (*ExchangeWallet, *tNode, func()) {
+	t.Helper()
+	priv, _ := btcec.NewPrivateKey()
+	node := newTNode(hex.EncodeToString(priv.PubKey().SerializeCompressed()))
+	emit := asset.NewWalletEmitter(make(chan asset.WalletNotification, 16), BipID, tLogger)
+	w := newWallet(node, emit, tLogger, dex.Simnet)
+	ctx, cancel := context.WithCancel(context.Background())
+	wg, err := w.Connect(ctx)
+	if err != nil {
+		cancel()
+		t.Fatalf("Connect error: %v", err)
+	}
+	return w, node, func() {
+		cancel()
+		wg.Wait()
+	}
+}
+
+func TestConnect(t *testing.T) {
+	node := newTNode("02")
+	node.info.Chains[0].Network = "mainnet"
+	w := newWallet(node, nil, tLogger, dex.Simnet)
+	if _, err := w.Connect(context.Background()); err == nil {
+		t.Fatalf("no error for wrong network")
+	}
+	// Requests before Connect error instead of panicking.
+	if _, err := w.Balance(); !errors.Is(err, errNotConnected) {
+		t.Fatalf("expected not connected error, got %v", err)
+	}
+	if w.ValidateAddress("lnbcrt1") {
+		t.Fatalf("payment request validated without a connection")
+	}
+}
+
+func TestFundOrder(t *testing.T) {
+	w, node, shutdown := tNewWallet(t)
+	defer shutdown()
+
+	ord := &asset.Order{Value: 1e5, MaxSwapCount: 2, MaxFeeRate: 100}
+	coins, _, _, err := w.FundOrder(ord)
+	if err != nil {
+		t.Fatalf("FundOrder error: %v", err)
+	}
+	if coins[0].Value() != 1e5+200 {
+		t.Fatalf("wrong funded amount %d", coins[0].Value())
+	}
+	bal, _ := w.Balance()
+	if bal.Available != 1e6-1e5-200 {
+		t.Fatalf("wrong available balance %d", bal.Available)
+	}
+
+	// Recover the funding coin.
+	recoveryID := coins[0].(asset.RecoveryCoin).RecoveryID()
+	if err := w.ReturnCoins(coins); err != nil {
+		t.Fatalf("ReturnCoins error: %v", err)
+	}
+	if _, err := w.FundingCoins([]dex.Bytes{recoveryID}); err != nil {
+		t.Fatalf("FundingCoins error: %v", err)
+	}
+	if bal, _ = w.Balance(); bal.Available != 1e6-1e5-200 {
+		t.Fatalf("wrong available balance after FundingCoins %d", bal.Available)
+	}
+
+	// Too much.
+	node.bal.LocalBalance.Sat = 1e5
+	if _, _, _, err = w.FundOrder(ord); !errors.Is(err, asset.ErrInsufficientBalance) {
+		t.Fatalf("expected insufficient balance error, got %v", err)
+	}
+}
+
+func tContract(payee string, value uint64) (*asset.Contract, []byte) {
+	secret := make([]byte, 32)
+	secret[0] = byte(value)
+	h := sha256.Sum256(secret)
+	return &asset.Contract{
+		Address:    payee,
+		Value:      value,
+		SecretHash: h[:],
+		LockTime:   uint64(time.Now().Add(time.Hour).Unix()),
+	}, secret
+}
+
+func TestSwap(t *testing.T) {
+	w, node, shutdown := tNewWallet(t)
+	defer shutdown()
+
+	coins, _, _, err := w.FundOrder(&asset.Order{Value: 2e5, MaxSwapCount: 2, MaxFeeRate: 10})
+	if err != nil {
+		t.Fatalf("FundOrder error: %v", err)
+	}
+
+	priv, _ := btcec.NewPrivateKey()
+	payee := hex.EncodeToString(priv.PubKey().SerializeCompressed())
+	c, _ := tContract(payee, 1e5)
+	swaps := &asset.Swaps{
+		Inputs:     coins,
+		Contracts:  []*asset.Contract{c},
+		FeeRate:    10,
+		LockChange: true,
+	}
+
+	// The counterparty has not yet added the hold invoice.
+	node.sendResult = &payment{Status: paymentStatusFailed, FailureReason: failureReasonIncorrectDetails}
+	receipts, change, _, err := w.Swap(swaps)
+	if err != nil {
+		t.Fatalf("Swap error: %v", err)
+	}
+	if len(w.pending) != 1 {
+		t.Fatalf("expected 1 pending swap, got %d", len(w.pending))
+	}
+	if change == nil || change.Value() != 2e5+20-1e5-10 {
+		t.Fatalf("wrong change %v", change)
+	}
+	// The pending swap's value and max fee stay locked, along with the change.
+	if w.locked != 2e5+20 {
+		t.Fatalf("wrong locked amount %d with pending swap", w.locked)
+	}
+	ctr, err := dexlnbtc.DecodeContract(receipts[0].Contract())
+	if err != nil {
+		t.Fatalf("error decoding receipt contract: %v", err)
+	}
+	if ctr.Value != c.Value || hex.EncodeToString(ctr.Payee[:]) != payee {
+		t.Fatalf("wrong receipt contract")
+	}
+
+	// Now it is accepted.
+	node.sendResult = &payment{Status: paymentStatusInFlight}
+	w.retryPendingSwaps(context.Background())
+	if len(w.pending) != 0 {
+		t.Fatalf("pending swap not removed")
+	}
+	if w.locked != change.Value() {
+		t.Fatalf("wrong locked amount %d after dispatch", w.locked)
+	}
+	confs, spent, err := w.SwapConfirmations(context.Background(), receipts[0].Coin().ID(), nil, time.Time{})
+	if err != nil || confs != 1 || spent {
+		t.Fatalf("wrong swap confirmations. confs = %d, spent = %t, err = %v", confs, spent, err)
+	}
+
+	// In flight can't be refunded.
+	if _, err := w.Refund(receipts[0].Coin().ID(), receipts[0].Contract(), 0); err == nil {
+		t.Fatalf("no error refunding in-flight payment")
+	}
+	node.sendResult.Status = paymentStatusFailed
+	if _, err := w.Refund(receipts[0].Coin().ID(), receipts[0].Contract(), 0); err != nil {
+		t.Fatalf("error refunding failed payment: %v", err)
+	}
+	node.sendResult.Status = paymentStatusSucceeded
+	if _, err := w.Refund(receipts[0].Coin().ID(), receipts[0].Contract(), 0); !errors.Is(err, asset.CoinNotFoundError) {
+		t.Fatalf("expected CoinNotFoundError for redeemed swap, got %v", err)
+	}
+
+	// Bad payee.
+	c.Address = "abcd"
+	if _, _, _, err := w.Swap(swaps); err == nil {
+		t.Fatalf("no error for bad payee")
+	}
+}
+
+func TestAuditAndRedeem(t *testing.T) {
+	w, node, shutdown := tNewWallet(t)
+	defer shutdown()
+
+	c, secret := tContract(w.pubKeyStr, 1e5)
+	ctr := &dexlnbtc.Contract{
+		SecretHash: [32]byte(c.SecretHash),
+		LockTime:   c.LockTime,
+		Value:      c.Value,
+		Payee:      [33]byte(w.pubKey),
+	}
+	contract := ctr.Encode()
+
+	// First audit adds the hold invoice.
+	if _, err := w.AuditContract(c.SecretHash, contract, nil, false); !errors.Is(err, asset.CoinNotFoundError) {
+		t.Fatalf("expected CoinNotFoundError before acceptance, got %v", err)
+	}
+	inv := node.invoices[ctr.SecretHash]
+	if inv == nil {
+		t.Fatalf("hold invoice not added")
+	}
+	if _, err := w.AuditContract(c.SecretHash, contract, nil, false); !errors.Is(err, asset.CoinNotFoundError) {
+		t.Fatalf("expected CoinNotFoundError for open invoice, got %v", err)
+	}
+
+	// Underpaid.
+	inv.State = invoiceStateAccepted
+	inv.AmtPaidSat = 1e5 - 1
+	if _, err := w.AuditContract(c.SecretHash, contract, nil, false); err == nil {
+		t.Fatalf("no error for underpaid HTLC")
+	}
+
+	inv.AmtPaidSat = 1e5
+	ai, err := w.AuditContract(c.SecretHash, contract, nil, false)
+	if err != nil {
+		t.Fatalf("AuditContract error: %v", err)
+	}
+	if ai.Coin.Value() != 1e5 || !ai.Expiration.Equal(ctr.Expiration()) {
+		t.Fatalf("wrong audit info")
+	}
+
+	// Wrong payee.
+	otherCtr := *ctr
+	otherCtr.Payee[1]++
+	if _, err := w.AuditContract(c.SecretHash, otherCtr.Encode(), nil, false); err == nil {
+		t.Fatalf("no error for wrong payee")
+	}
+
+	// Bad secret.
+	redemption := &asset.Redemption{Spends: ai, Secret: secret}
+	if _, _, _, err := w.Redeem(&asset.RedeemForm{Redemptions: []*asset.Redemption{{Spends: ai, Secret: secret[1:]}}}); err == nil {
+		t.Fatalf("no error for bad secret")
+	}
+	ins, out, _, err := w.Redeem(&asset.RedeemForm{Redemptions: []*asset.Redemption{redemption}})
+	if err != nil {
+		t.Fatalf("Redeem error: %v", err)
+	}
+	if len(ins) != 1 || out.Value() != 1e5 || len(node.settled) != 1 {
+		t.Fatalf("wrong redeem results")
+	}
+	status, err := w.ConfirmRedemption(out.ID(), redemption, 0)
+	if err != nil {
+		t.Fatalf("ConfirmRedemption error: %v", err)
+	}
+	if status.Confs != 1 {
+		t.Fatalf("redemption not confirmed")
+	}
+}
+
+func TestFindRedemption(t *testing.T) {
+	w, node, shutdown := tNewWallet(t)
+	defer shutdown()
+
+	_, secret := tContract(w.pubKeyStr, 1e5)
+	h := sha256.Sum256(secret)
+	node.payments[h] = &payment{Status: paymentStatusSucceeded, PaymentPreimage: hex.EncodeToString(secret)}
+	_, foundSecret, err := w.FindRedemption(context.Background(), h[:], nil)
+	if err != nil {
+		t.Fatalf("FindRedemption error: %v", err)
+	}
+	if !w.ValidateSecret(foundSecret, h[:]) {
+		t.Fatalf("wrong secret")
+	}
+
+	node.payments[h].Status = paymentStatusFailed
+	if _, _, err = w.FindRedemption(context.Background(), h[:], nil); !errors.Is(err, asset.ErrSwapRefunded) {
+		t.Fatalf("expected ErrSwapRefunded, got %v", err)
+	}
+}
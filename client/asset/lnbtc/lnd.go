@@ -0,0 +1,372 @@
+// This code is available on the terms of the project LICENSE.md file,
+// also available online at https://blueoakcouncil.org/license/1.0.0.
+
+package lnbtc
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// LND invoice states.
+const (
+	invoiceStateOpen     = "OPEN"
+	invoiceStateSettled  = "SETTLED"
+	invoiceStateCanceled = "CANCELED"
+	invoiceStateAccepted = "ACCEPTED"
+)
+
+// LND payment statuses.
+const (
+	paymentStatusInFlight  = "IN_FLIGHT"
+	paymentStatusSucceeded = "SUCCEEDED"
+	paymentStatusFailed    = "FAILED"
+)
+
+// failureReasonIncorrectDetails is the payment failure reason when the payee
+// has no invoice for the payment hash. For a swap, this usually means that the
+// counterparty has not yet audited the contract and added the hold invoice.
+const failureReasonIncorrectDetails = "FAILURE_REASON_INCORRECT_PAYMENT_DETAILS"
+
+// errNotFound is returned from lndClient methods when the node responds with
+// a 404 or an equivalent "not found" error.
+var errNotFound = errors.New("not found")
+
+// lndClient is a client for the REST interface of an LND node. Only the
+// subset of the API needed for hold invoice swaps is implemented.
+type lndClient struct {
+	host     string
+	macaroon string
+	http     *http.Client
+}
+
+// newLNDClient creates an lndClient. The macaroon and TLS certificate are
+// loaded from the file system. If tlsCertPath is empty, the system's root CAs
+// are used.
+func newLNDClient(host, macaroonPath, tlsCertPath string) (*lndClient, error) {
+	mac, err := os.ReadFile(macaroonPath)
+	if err != nil {
+		return nil, fmt.Errorf("error reading macaroon file: %w", err)
+	}
+	tlsCfg := &tls.Config{MinVersion: tls.VersionTLS12}
+	if tlsCertPath != "" {
+		pem, err := os.ReadFile(tlsCertPath)
+		if err != nil {
+			return nil, fmt.Errorf("error reading TLS certificate: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, errors.New("invalid TLS certificate")
+		}
+		tlsCfg.RootCAs = pool
+	}
+	return &lndClient{
+		host:     host,
+		macaroon: hex.EncodeToString(mac),
+		http: &http.Client{
+			Transport: &http.Transport{TLSClientConfig: tlsCfg},
+		},
+	}, nil
+}
+
+type lndError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func (c *lndClient) request(ctx context.Context, method, path string, in any) (*http.Response, error) {
+	var body io.Reader
+	if in != nil {
+		b, err := json.Marshal(in)
+		if err != nil {
+			return nil, fmt.Errorf("error encoding request: %w", err)
+		}
+		body = bytes.NewReader(b)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, c.host+path, body)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Grpc-Metadata-macaroon", c.macaroon)
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode == http.StatusOK {
+		return resp, nil
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, errNotFound
+	}
+	var lndErr lndError
+	if err := json.NewDecoder(resp.Body).Decode(&lndErr); err != nil || lndErr.Message == "" {
+		return nil, fmt.Errorf("%s %s: %s", method, path, resp.Status)
+	}
+	// LND returns a 500 with a gRPC status code for unknown invoices.
+	if lndErr.Code == 5 /* codes.NotFound */ || strings.Contains(lndErr.Message, "unable to locate invoice") {
+		return nil, errNotFound
+	}
+	return nil, fmt.Errorf("%s %s: %s", method, path, lndErr.Message)
+}
+
+func (c *lndClient) call(ctx context.Context, method, path string, in, out any) error {
+	resp, err := c.request(ctx, method, path, in)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if out == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+// jsonInt is an integer that LND encodes as a JSON string.
+type jsonInt uint64
+
+func (i *jsonInt) UnmarshalJSON(b []byte) error {
+	b = bytes.Trim(b, `"`)
+	if len(b) == 0 {
+		*i = 0
+		return nil
+	}
+	v, err := strconv.ParseUint(string(b), 10, 64)
+	if err != nil {
+		return err
+	}
+	*i = jsonInt(v)
+	return nil
+}
+
+type nodeInfo struct {
+	IdentityPubkey string  `json:"identity_pubkey"`
+	Alias          string  `json:"alias"`
+	Version        string  `json:"version"`
+	BlockHeight    uint32  `json:"block_height"`
+	SyncedToChain  bool    `json:"synced_to_chain"`
+	SyncedToGraph  bool    `json:"synced_to_graph"`
+	NumPeers       uint32  `json:"num_peers"`
+	NumActiveChans uint32  `json:"num_active_channels"`
+	Chains         []chain `json:"chains"`
+}
+
+type chain struct {
+	Chain   string `json:"chain"`
+	Network string `json:"network"`
+}
+
+func (c *lndClient) getInfo(ctx context.Context) (*nodeInfo, error) {
+	var info nodeInfo
+	return &info, c.call(ctx, http.MethodGet, "/v1/getinfo", nil, &info)
+}
+
+type amount struct {
+	Sat jsonInt `json:"sat"`
+}
+
+type channelBalance struct {
+	LocalBalance         amount `json:"local_balance"`
+	RemoteBalance        amount `json:"remote_balance"`
+	PendingOpenLocal     amount `json:"pending_open_local_balance"`
+	UnsettledLocalBalace amount `json:"unsettled_local_balance"`
+}
+
+func (c *lndClient) channelBalance(ctx context.Context) (*channelBalance, error) {
+	var bal channelBalance
+	return &bal, c.call(ctx, http.MethodGet, "/v1/balance/channels", nil, &bal)
+}
+
+type invoice struct {
+	PaymentRequest string  `json:"payment_request"`
+	State          string  `json:"state"`
+	Value          jsonInt `json:"value"`
+	AmtPaidSat     jsonInt `json:"amt_paid_sat"`
+	CLTVExpiry     jsonInt `json:"cltv_expiry"`
+	SettleDate     jsonInt `json:"settle_date"`
+}
+
+// addHoldInvoice adds a hold invoice for the payment hash. The invoice will
+// accept, but not settle, an HTLC for the hash until settleInvoice or
+// cancelInvoice is called.
+func (c *lndClient) addHoldInvoice(ctx context.Context, paymentHash []byte, value uint64, cltvExpiry uint32, expiry time.Duration, memo string) (string, error) {
+	req := map[string]any{
+		"hash":        paymentHash,
+		"value":       strconv.FormatUint(value, 10),
+		"cltv_expiry": strconv.FormatUint(uint64(cltvExpiry), 10),
+		"expiry":      strconv.FormatInt(int64(expiry/time.Second), 10),
+		"memo":        memo,
+	}
+	var resp struct {
+		PaymentRequest string `json:"payment_request"`
+	}
+	return resp.PaymentRequest, c.call(ctx, http.MethodPost, "/v2/invoices/hodl", req, &resp)
+}
+
+// settleInvoice settles an accepted hold invoice with the preimage.
+func (c *lndClient) settleInvoice(ctx context.Context, preimage []byte) error {
+	return c.call(ctx, http.MethodPost, "/v2/invoices/settle", map[string]any{"preimage": preimage}, nil)
+}
+
+// cancelInvoice cancels a hold invoice, failing any accepted HTLCs back to the
+// payer.
+func (c *lndClient) cancelInvoice(ctx context.Context, paymentHash []byte) error {
+	return c.call(ctx, http.MethodPost, "/v2/invoices/cancel", map[string]any{"payment_hash": paymentHash}, nil)
+}
+
+// lookupInvoice looks up the invoice for the payment hash. errNotFound is
+// returned if the node has no such invoice.
+func (c *lndClient) lookupInvoice(ctx context.Context, paymentHash []byte) (*invoice, error) {
+	var inv invoice
+	return &inv, c.call(ctx, http.MethodGet, "/v1/invoice/"+hex.EncodeToString(paymentHash), nil, &inv)
+}
+
+// addInvoice adds a standard invoice, used for deposits.
+func (c *lndClient) addInvoice(ctx context.Context, value uint64, memo string) (string, error) {
+	req := map[string]any{
+		"value": strconv.FormatUint(value, 10),
+		"memo":  memo,
+	}
+	var resp struct {
+		PaymentRequest string `json:"payment_request"`
+	}
+	return resp.PaymentRequest, c.call(ctx, http.MethodPost, "/v1/invoices", req, &resp)
+}
+
+type payReq struct {
+	Destination string  `json:"destination"`
+	PaymentHash string  `json:"payment_hash"`
+	NumSatoshis jsonInt `json:"num_satoshis"`
+	Expiry      jsonInt `json:"expiry"`
+	Timestamp   jsonInt `json:"timestamp"`
+}
+
+// decodePayReq decodes a BOLT11 payment request.
+func (c *lndClient) decodePayReq(ctx context.Context, payReqStr string) (*payReq, error) {
+	var pr payReq
+	return &pr, c.call(ctx, http.MethodGet, "/v1/payreq/"+url.PathEscape(payReqStr), nil, &pr)
+}
+
+type payment struct {
+	PaymentHash     string  `json:"payment_hash"`
+	ValueSat        jsonInt `json:"value_sat"`
+	FeeSat          jsonInt `json:"fee_sat"`
+	PaymentPreimage string  `json:"payment_preimage"`
+	Status          string  `json:"status"`
+	FailureReason   string  `json:"failure_reason"`
+	CreationTimeNs  jsonInt `json:"creation_time_ns"`
+	HTLCs           []*struct {
+		Status string `json:"status"`
+	} `json:"htlcs"`
+}
+
+// sendPayment dispatches a payment and returns the first update in which the
+// payment is in flight, succeeded, or failed. The payment continues in the
+// node after sendPayment returns. If paymentHash is non-nil, the payment is
+// sent directly to the dest node without an invoice, which is how swap HTLCs
+// are created.
+func (c *lndClient) sendPayment(ctx context.Context, dest, paymentHash []byte, payReq string, value uint64, cltvDelta uint32, feeLimit uint64) (*payment, error) {
+	req := map[string]any{
+		"timeout_seconds": 60,
+		"fee_limit_sat":   strconv.FormatUint(feeLimit, 10),
+	}
+	if payReq != "" {
+		req["payment_request"] = payReq
+	} else {
+		req["dest"] = dest
+		req["payment_hash"] = paymentHash
+		req["final_cltv_delta"] = cltvDelta
+	}
+	if value > 0 {
+		req["amt"] = strconv.FormatUint(value, 10)
+	}
+	resp, err := c.request(ctx, http.MethodPost, "/v2/router/send", req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	// The response is a stream of newline-delimited payment updates.
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		var update struct {
+			Result *payment  `json:"result"`
+			Error  *lndError `json:"error"`
+		}
+		if err := json.Unmarshal(scanner.Bytes(), &update); err != nil {
+			return nil, fmt.Errorf("error decoding payment update: %w", err)
+		}
+		if update.Error != nil {
+			return nil, errors.New(update.Error.Message)
+		}
+		if update.Result == nil {
+			continue
+		}
+		switch update.Result.Status {
+		case paymentStatusInFlight:
+			// A swap payment stays in flight until the payee settles or
+			// cancels the hold invoice, so return as soon as an HTLC is
+			// dispatched. Payments start IN_FLIGHT before a route is found.
+			if payReq == "" && len(update.Result.HTLCs) > 0 {
+				return update.Result, nil
+			}
+		case paymentStatusSucceeded, paymentStatusFailed:
+			return update.Result, nil
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return nil, errors.New("payment stream closed without a final update")
+}
+
+// lookupPayment finds the outgoing payment with the payment hash. errNotFound
+// is returned if there is no payment.
+func (c *lndClient) lookupPayment(ctx context.Context, paymentHash []byte) (*payment, error) {
+	var resp struct {
+		Payments []*payment `json:"payments"`
+	}
+	// TODO: Use the TrackPaymentV2 stream when streaming is needed elsewhere.
+	// ListPayments with include_incomplete returns in-flight payments too.
+	if err := c.call(ctx, http.MethodGet, "/v1/payments?include_incomplete=true&reversed=true&max_payments=1000", nil, &resp); err != nil {
+		return nil, err
+	}
+	h := hex.EncodeToString(paymentHash)
+	// A payment hash may have failed attempts followed by a successful one,
+	// so prefer any non-failed payment.
+	var found *payment
+	for _, p := range resp.Payments {
+		if p.PaymentHash != h {
+			continue
+		}
+		if found == nil || found.Status == paymentStatusFailed {
+			found = p
+		}
+	}
+	if found == nil {
+		return nil, errNotFound
+	}
+	return found, nil
+}
+
+// signMessage signs the message with the node's identity key, returning the
+// zbase32-encoded signature.
+func (c *lndClient) signMessage(ctx context.Context, msg []byte) (string, error) {
+	var resp struct {
+		Signature string `json:"signature"`
+	}
+	return resp.Signature, c.call(ctx, http.MethodPost, "/v1/signmessage", map[string]any{"msg": msg}, &resp)
+}
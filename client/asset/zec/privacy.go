@@ -0,0 +1,336 @@
+// This code is available on the terms of the project LICENSE.md file,
+// also available online at https://blueoakcouncil.org/license/1.0.0.
+
+package zec
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"decred.org/dcrdex/client/asset"
+	"decred.org/dcrdex/client/asset/btc"
+	dexbtc "decred.org/dcrdex/dex/networks/btc"
+	dexzec "decred.org/dcrdex/dex/networks/zec"
+	"github.com/btcsuite/btcd/btcutil"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcd/wire"
+)
+
+// Swap contracts are transparent scripts, so every trade moves funds through
+// the transparent pool. The privacy tags added to the WalletTransaction's
+// AdditionalData describe how each transaction in the wallet's history exposed
+// funds on the transparent chain, so that users can audit which transactions
+// are linkable.
+const (
+	// privacyTagKey is the AdditionalData key for the privacy class of the
+	// transaction.
+	privacyTagKey = "privacy"
+	// transparentAddrsTagKey is the AdditionalData key for a comma-separated
+	// list of the transparent addresses paid by the transaction.
+	transparentAddrsTagKey = "transparentAddresses"
+	// shieldedFromTagKey is the AdditionalData key for the transparent
+	// address whose funds were moved into the shielded pool by an automatic
+	// shielding transaction.
+	shieldedFromTagKey = "shieldedFrom"
+
+	// privacyTransparent is a fully transparent transaction, e.g. a swap,
+	// redeem, or refund.
+	privacyTransparent = "transparent"
+	// privacyDeshield moves shielded funds to transparent outputs, e.g. an
+	// order funding split.
+	privacyDeshield = "deshield"
+	// privacyShield moves transparent funds into the shielded pool.
+	privacyShield = "shield"
+	// privacyMixed spends transparent inputs and has both transparent and
+	// shielded components.
+	privacyMixed = "mixed"
+	// privacyShielded has no transparent inputs or outputs.
+	privacyShielded = "shielded"
+
+	// shieldFeeFactor limits automatic shielding to funds worth at least
+	// shieldFeeFactor times the shielding tx fees.
+	shieldFeeFactor = 10
+	// nActionsOrchardShield is the number of orchard actions in a shielding
+	// transaction. A single orchard output is padded to two actions.
+	nActionsOrchardShield = 2
+)
+
+// privacyClass categorizes the transaction by how it exposes funds in the
+// transparent pool.
+func privacyClass(tx *dexzec.Tx) string {
+	shielded := tx.NActionsOrchard > 0 || tx.NSpendsSapling > 0 || tx.NOutputsSapling > 0 || tx.NJoinSplit > 0
+	tIn, tOut := len(tx.TxIn) > 0, len(tx.TxOut) > 0
+	switch {
+	case !shielded:
+		return privacyTransparent
+	case tIn && tOut:
+		return privacyMixed
+	case tIn:
+		return privacyShield
+	case tOut:
+		return privacyDeshield
+	default:
+		return privacyShielded
+	}
+}
+
+// transparentOutputAddrs lists the transparent addresses paid by the
+// transaction.
+func (w *zecWallet) transparentOutputAddrs(tx *dexzec.Tx) []string {
+	addrs := make([]string, 0, len(tx.TxOut))
+	for _, txOut := range tx.TxOut {
+		_, btcAddrs, _, err := txscript.ExtractPkScriptAddrs(txOut.PkScript, w.btcParams)
+		if err != nil {
+			continue
+		}
+		for _, btcAddr := range btcAddrs {
+			addr, err := dexzec.EncodeAddress(btcAddr, w.addrParams)
+			if err != nil {
+				continue
+			}
+			addrs = append(addrs, addr)
+		}
+	}
+	return addrs
+}
+
+// tagPrivacy adds the privacy tags to the WalletTransaction.
+func (w *zecWallet) tagPrivacy(wt *asset.WalletTransaction, tx *dexzec.Tx) {
+	if wt.AdditionalData == nil {
+		wt.AdditionalData = make(map[string]string)
+	}
+	wt.AdditionalData[privacyTagKey] = privacyClass(tx)
+	if addrs := w.transparentOutputAddrs(tx); len(addrs) > 0 {
+		wt.AdditionalData[transparentAddrsTagKey] = strings.Join(addrs, ",")
+	}
+}
+
+// transparentFunds are the confirmed, unlocked funds at a transparent address.
+type transparentFunds struct {
+	addr  string
+	value uint64
+	n     int
+	utxos []*btc.UTxO
+}
+
+// fees are the ZIP-317 fees for moving the funds into the shielded pool.
+func (f *transparentFunds) fees() uint64 {
+	inputsSize := uint64(f.n)*dexbtc.RedeemP2PKHInputSize + uint64(wire.VarIntSerializeSize(uint64(f.n)))
+	return dexzec.TxFeesZIP317(inputsSize, 0, 0, 0, 0, nActionsOrchardShield)
+}
+
+// shieldableFunds groups the confirmed transparent outputs by address.
+// Addresses with any locked outputs are skipped, since z_sendmany selects
+// inputs by address, and locked outputs are reserved for orders and swaps.
+func (w *zecWallet) shieldableFunds() ([]*transparentFunds, error) {
+	unspents, err := listUnspent(w)
+	if err != nil {
+		return nil, fmt.Errorf("listunspent error: %w", err)
+	}
+	lockedOutpoints, err := listLockUnspent(w, w.log)
+	if err != nil {
+		return nil, fmt.Errorf("listlockunspent error: %w", err)
+	}
+	lockedAddrs := make(map[string]bool, len(lockedOutpoints))
+	for _, rpcOP := range lockedOutpoints {
+		txHash, err := chainhash.NewHashFromStr(rpcOP.TxID)
+		if err != nil {
+			return nil, err
+		}
+		addr, err := w.outputAddress(txHash, rpcOP.Vout)
+		if err != nil {
+			return nil, fmt.Errorf("error finding address for locked output %s:%d: %w", txHash, rpcOP.Vout, err)
+		}
+		lockedAddrs[addr] = true
+	}
+	for _, utxo := range w.cm.LockedUTXOs() {
+		lockedAddrs[utxo.Address] = true
+	}
+
+	byAddr := make(map[string]*transparentFunds)
+	for _, u := range unspents {
+		if u.Address == "" || !u.Spendable || u.Confirmations < minOrchardConfs {
+			continue
+		}
+		txHash, err := chainhash.NewHashFromStr(u.TxID)
+		if err != nil {
+			return nil, fmt.Errorf("error decoding txid %q: %w", u.TxID, err)
+		}
+		f := byAddr[u.Address]
+		if f == nil {
+			f = &transparentFunds{addr: u.Address}
+			byAddr[u.Address] = f
+		}
+		amt := toZats(u.Amount)
+		f.value += amt
+		f.n++
+		f.utxos = append(f.utxos, &btc.UTxO{TxHash: txHash, Vout: u.Vout, Address: u.Address, Amount: amt})
+	}
+
+	funds := make([]*transparentFunds, 0, len(byAddr))
+	for addr, f := range byAddr {
+		if lockedAddrs[addr] || f.value < f.fees()*shieldFeeFactor {
+			continue
+		}
+		funds = append(funds, f)
+	}
+	sort.Slice(funds, func(i, j int) bool { return funds[i].value > funds[j].value })
+	return funds, nil
+}
+
+// outputAddress is the address paid by the wallet transaction output.
+func (w *zecWallet) outputAddress(txHash *chainhash.Hash, vout uint32) (string, error) {
+	if utxo := w.cm.LockedOutput(btc.NewOutPoint(txHash, vout)); utxo != nil {
+		return utxo.Address, nil
+	}
+	tx, err := getWalletTransaction(w, txHash)
+	if err != nil {
+		return "", err
+	}
+	txOut, err := btc.TxOutFromTxBytes(tx.Bytes, vout, deserializeTx, hashTx)
+	if err != nil {
+		return "", err
+	}
+	_, addrs, _, err := txscript.ExtractPkScriptAddrs(txOut.PkScript, w.btcParams)
+	if err != nil {
+		return "", err
+	}
+	if len(addrs) != 1 {
+		return "", fmt.Errorf("expected 1 address, got %d", len(addrs))
+	}
+	return dexzec.EncodeAddress(addrs[0], w.addrParams)
+}
+
+// shieldTransparentFunds moves the wallet's unreserved transparent funds, e.g.
+// from redemptions, refunds, and swap change, back into the shielded pool.
+func (w *zecWallet) shieldTransparentFunds() {
+	funds, err := w.reserveShieldableFunds()
+	if err != nil {
+		w.log.Errorf("Error finding transparent funds to shield: %v", err)
+		return
+	}
+	if len(funds) == 0 {
+		return
+	}
+	var reserved []btc.OutPoint
+	for _, f := range funds {
+		for _, u := range f.utxos {
+			reserved = append(reserved, btc.NewOutPoint(u.TxHash, u.Vout))
+		}
+	}
+	defer w.cm.UnlockOutPoints(reserved)
+
+	toAddr, err := w.lastShieldedAddress()
+	if err != nil {
+		w.log.Errorf("Error getting shielded address: %v", err)
+		return
+	}
+	for _, f := range funds {
+		fees := f.fees()
+		amt := f.value - fees
+		txHash, err := w.sendOne(w.ctx, f.addr, toAddr, amt, AllowRevealedSenders)
+		if err != nil {
+			w.log.Errorf("Error shielding %s from %s: %v", btcutil.Amount(amt), f.addr, err)
+			continue
+		}
+		w.log.Infof("Shielded %s from %d outputs at transparent address %s in transaction %s",
+			btcutil.Amount(amt), f.n, f.addr, txHash)
+		w.addTxToHistory(&asset.WalletTransaction{
+			Type:   asset.SelfSend,
+			ID:     txHash.String(),
+			Amount: amt,
+			Fees:   fees,
+			AdditionalData: map[string]string{
+				shieldedFromTagKey: f.addr,
+			},
+		}, txHash, true)
+	}
+}
+
+// reserveShieldableFunds finds the shieldable funds and locks their outputs,
+// so that order funding will not select them while the shielding transactions
+// are created. fundingMtx is only held during selection, since waiting for
+// z_sendmany operations can take a while. The caller must unlock the outputs.
+func (w *zecWallet) reserveShieldableFunds() ([]*transparentFunds, error) {
+	w.fundingMtx.Lock()
+	defer w.fundingMtx.Unlock()
+	funds, err := w.shieldableFunds()
+	if err != nil {
+		return nil, err
+	}
+	for _, f := range funds {
+		w.cm.LockUTXOs(f.utxos)
+	}
+	return funds, nil
+}
+
+// PrivacyReport lists the trade transactions that exposed funds on the
+// transparent chain, and which of their transparent addresses have since been
+// shielded. Satisfies asset.PrivacyReporter.
+func (w *zecWallet) PrivacyReport() (*asset.PrivacyReport, error) {
+	txHistoryDB := w.txDB()
+	if txHistoryDB == nil {
+		return nil, fmt.Errorf("tx database not initialized")
+	}
+	txs, err := txHistoryDB.GetTxs(0, nil, true)
+	if err != nil {
+		return nil, fmt.Errorf("error retrieving tx history: %w", err)
+	}
+	shielded := make(map[string]bool)
+	for _, wt := range txs {
+		if addr := wt.AdditionalData[shieldedFromTagKey]; addr != "" {
+			shielded[addr] = true
+		}
+	}
+	report := &asset.PrivacyReport{Trades: make([]*asset.TransparentExposure, 0)}
+	for _, wt := range txs {
+		switch wt.Type {
+		case asset.Swap, asset.Redeem, asset.Refund, asset.Split:
+		default:
+			continue
+		}
+		addrsStr := wt.AdditionalData[transparentAddrsTagKey]
+		if addrsStr == "" {
+			continue
+		}
+		exp := &asset.TransparentExposure{
+			TxID:       wt.ID,
+			Type:       wt.Type,
+			Timestamp:  wt.Timestamp,
+			Addresses:  strings.Split(addrsStr, ","),
+			Reshielded: make([]string, 0),
+		}
+		for _, addr := range exp.Addresses {
+			if shielded[addr] {
+				exp.Reshielded = append(exp.Reshielded, addr)
+			}
+		}
+		report.Trades = append(report.Trades, exp)
+	}
+	unspents, err := listUnspent(w)
+	if err != nil {
+		return nil, fmt.Errorf("listunspent error: %w", err)
+	}
+	for _, u := range unspents {
+		report.TransparentBalance += toZats(u.Amount)
+	}
+	return report, nil
+}
+
+// autoShield shields transparent funds whenever a new block is reported, if
+// the autoshield setting is enabled.
+func (w *zecWallet) autoShield(ctx context.Context) {
+	for {
+		select {
+		case <-w.shieldSignal:
+			if w.walletCfg.Load().(*WalletConfig).AutoShield {
+				w.shieldTransparentFunds()
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
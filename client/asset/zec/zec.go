@@ -125,6 +125,15 @@ var (
 				"Used only for standing-type orders, e.g. limit orders without immediate time-in-force.",
 			IsBoolean: true,
 		},
+		{
+			Key:         "autoshield",
+			DisplayName: "Automatically shield transparent funds",
+			Description: "Move confirmed transparent funds, such as redemptions, refunds, and swap change, back into the shielded pool " +
+				"once they are no longer reserved for an order. This limits the linkage of your trades on the transparent chain, but " +
+				"network fees are paid for each shielding transaction, and funds must be moved out of the shielded pool again to " +
+				"fund new orders.",
+			IsBoolean: true,
+		},
 	}
 	// WalletInfo defines some general information about a Zcash wallet.
 	WalletInfo = &asset.WalletInfo{
@@ -186,6 +195,7 @@ func (d *Driver) MinLotSize(maxFeeRate uint64) uint64 {
 // WalletConfig are wallet-level configuration settings.
 type WalletConfig struct {
 	UseSplitTx       bool   `ini:"txsplit"`
+	AutoShield       bool   `ini:"autoshield"`
 	RedeemConfTarget uint64 `ini:"redeemconftarget"`
 	ActivelyUsed     bool   `ini:"special_activelyUsed"` // injected by core
 }
@@ -268,10 +278,11 @@ func NewWallet(cfg *asset.WalletConfig, logger dex.Logger, net dex.Network) (ass
 		decodeAddr: func(addr string, net *chaincfg.Params) (btcutil.Address, error) {
 			return dexzec.DecodeAddress(addr, addrParams, btcParams)
 		},
-		ar:         ar,
-		node:       cl,
-		walletDir:  cfg.DataDir,
-		pendingTxs: make(map[chainhash.Hash]*btc.ExtendedWalletTx),
+		ar:           ar,
+		node:         cl,
+		walletDir:    cfg.DataDir,
+		pendingTxs:   make(map[chainhash.Hash]*btc.ExtendedWalletTx),
+		shieldSignal: make(chan struct{}, 1),
 	}
 	zw.walletCfg.Store(&walletCfg)
 	zw.prepareCoinManager()
@@ -324,6 +335,11 @@ type zecWallet struct {
 
 	reserves atomic.Uint64
 
+	// fundingMtx prevents automatic shielding from spending transparent
+	// outputs while an order is being funded.
+	fundingMtx   sync.Mutex
+	shieldSignal chan struct{}
+
 	pendingTxsMtx sync.RWMutex
 	pendingTxs    map[chainhash.Hash]*btc.ExtendedWalletTx
 
@@ -337,6 +353,7 @@ var _ asset.FeeRater = (*zecWallet)(nil)
 var _ asset.Wallet = (*zecWallet)(nil)
 var _ asset.WalletHistorian = (*zecWallet)(nil)
 var _ asset.NewAddresser = (*zecWallet)(nil)
+var _ asset.PrivacyReporter = (*zecWallet)(nil)
 
 // TODO: Implement LiveReconfigurer
 // var _ asset.LiveReconfigurer = (*zecWallet)(nil)
@@ -422,6 +439,12 @@ func (w *zecWallet) Connect(ctx context.Context) (*sync.WaitGroup, error) {
 		w.monitorPeers(ctx)
 	}()
 
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		w.autoShield(ctx)
+	}()
+
 	wg.Add(1)
 	go func() {
 		defer wg.Done()
@@ -679,6 +702,11 @@ func (w *zecWallet) reportNewTip(ctx context.Context, newTip *btc.BlockVector) {
 	w.rf.ReportNewTip(ctx, prevTip, newTip)
 
 	w.syncTxHistory(uint64(newTip.Height))
+
+	select {
+	case w.shieldSignal <- struct{}{}:
+	default:
+	}
 }
 
 type swapOptions struct {
@@ -688,6 +716,9 @@ type swapOptions struct {
 }
 
 func (w *zecWallet) FundOrder(ord *asset.Order) (asset.Coins, []dex.Bytes, uint64, error) {
+	w.fundingMtx.Lock()
+	defer w.fundingMtx.Unlock()
+
 	ordValStr := btcutil.Amount(ord.Value).String()
 	w.log.Debugf("Attempting to fund Zcash order, maxFeeRate = %d, max swaps = %d",
 		ord.MaxFeeRate, ord.MaxSwapCount)
@@ -1693,6 +1724,9 @@ func (w *zecWallet) FindRedemption(ctx context.Context, coinID, contract dex.Byt
 }
 
 func (w *zecWallet) FundMultiOrder(mo *asset.MultiOrder, maxLock uint64) (coins []asset.Coins, redeemScripts [][]dex.Bytes, fundingFees uint64, err error) {
+	w.fundingMtx.Lock()
+	defer w.fundingMtx.Unlock()
+
 	w.log.Debugf("Attempting to fund a multi-order for ZEC")
 
 	var totalRequiredForOrders uint64
@@ -2891,6 +2925,14 @@ func (w *zecWallet) addTxToHistory(wt *asset.WalletTransaction, txHash *chainhas
 		return
 	}
 
+	if wt.AdditionalData[privacyTagKey] == "" {
+		if tx, err := getTransaction(w, txHash); err != nil {
+			w.log.Errorf("Error retrieving tx %s for privacy tags: %v", txHash, err)
+		} else {
+			w.tagPrivacy(wt, tx.Tx)
+		}
+	}
+
 	ewt := &btc.ExtendedWalletTx{
 		WalletTransaction: wt,
 		Submitted:         submitted,
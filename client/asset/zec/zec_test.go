@@ -1528,3 +1528,169 @@ func TestFundMultiOrder(t *testing.T) {
 		t.Fatalf("error for simple path: %v", err)
 	}
 }
+
+func TestShieldTransparentFunds(t *testing.T) {
+	w, cl, shutdown := tNewWallet()
+	defer shutdown()
+	defer cl.checkEmptiness(t)
+
+	w.lastAddress.Store(tUnifiedAddr)
+
+	const lockedAddr = "tmLockedAddr"
+	const smallAddr = "tmSmallAddr"
+	lockedHash := chainhash.Hash{0x02}
+	w.cm.LockUTXOs([]*btc.UTxO{{TxHash: &lockedHash, Vout: 0, Address: lockedAddr, Amount: 1e8}})
+
+	unspents := []*btc.ListUnspentResult{
+		{TxID: tTxID, Vout: 0, Address: tAddr, Amount: 1, Confirmations: 1, Spendable: true},
+		{TxID: tTxID, Vout: 1, Address: tAddr, Amount: 0.5, Confirmations: 2, Spendable: true},
+		// Unconfirmed
+		{TxID: tTxID, Vout: 2, Address: tAddr, Amount: 3, Confirmations: 0, Spendable: true},
+		// Address has a locked output.
+		{TxID: lockedHash.String(), Vout: 1, Address: lockedAddr, Amount: 2, Confirmations: 1, Spendable: true},
+		// Not worth the fees.
+		{TxID: tTxID, Vout: 3, Address: smallAddr, Amount: 0.0001, Confirmations: 1, Spendable: true},
+	}
+
+	f := &transparentFunds{n: 2}
+	expAmt := uint64(1.5e8) - f.fees()
+
+	var fromAddr string
+	var recips []*zSendManyRecipient
+	checkSendMany := func(args []json.RawMessage) (json.RawMessage, error) {
+		if err := json.Unmarshal(args[0], &fromAddr); err != nil {
+			t.Fatalf("error decoding from address: %v", err)
+		}
+		if err := json.Unmarshal(args[1], &recips); err != nil {
+			t.Fatalf("error decoding recipients: %v", err)
+		}
+		return json.Marshal("operationid123")
+	}
+
+	cl.queueResponse("listunspent", unspents)
+	cl.queueResponse("listlockunspent", []*btc.RPCOutpoint{})
+	cl.queueResponse(methodZSendMany, checkSendMany)
+	cl.queueResponse(methodZGetOperationResult, []*operationStatus{{
+		Status: "success",
+		Result: &opResult{TxID: tTxID},
+	}})
+	w.shieldTransparentFunds()
+
+	if fromAddr != tAddr {
+		t.Fatalf("wrong shielding address %q", fromAddr)
+	}
+	if len(recips) != 1 || recips[0].Address != tUnifiedAddr || toZats(recips[0].Amount) != expAmt {
+		t.Fatalf("wrong shielding recipients %+v, expected %d to %s", recips, expAmt, tUnifiedAddr)
+	}
+
+	// Nothing to shield.
+	cl.queueResponse("listunspent", unspents[3:])
+	cl.queueResponse("listlockunspent", []*btc.RPCOutpoint{})
+	w.shieldTransparentFunds()
+}
+
+func TestPrivacyReport(t *testing.T) {
+	w, cl, shutdown := tNewWallet()
+	defer shutdown()
+	defer cl.checkEmptiness(t)
+
+	if _, err := w.PrivacyReport(); err == nil {
+		t.Fatalf("no error without a tx history db")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	db := btc.NewBadgerTxDB(t.TempDir(), tLogger)
+	wg, err := db.Connect(ctx)
+	if err != nil {
+		t.Fatalf("error connecting to tx history db: %v", err)
+	}
+	defer func() {
+		cancel()
+		wg.Wait()
+	}()
+	w.txHistoryDB.Store(db)
+
+	const exposedAddr, reshieldedAddr = "tmExposed", "tmReshielded"
+	for i, wt := range []*asset.WalletTransaction{
+		{Type: asset.Swap, ID: "01", AdditionalData: map[string]string{transparentAddrsTagKey: exposedAddr}},
+		{Type: asset.Redeem, ID: "02", AdditionalData: map[string]string{transparentAddrsTagKey: reshieldedAddr}},
+		{Type: asset.SelfSend, ID: "03", AdditionalData: map[string]string{shieldedFromTagKey: reshieldedAddr}},
+		{Type: asset.Send, ID: "04", AdditionalData: map[string]string{transparentAddrsTagKey: exposedAddr}},
+	} {
+		wt.BlockNumber = uint64(i + 1)
+		if err := db.StoreTx(&btc.ExtendedWalletTx{WalletTransaction: wt, Submitted: true}); err != nil {
+			t.Fatalf("error storing tx: %v", err)
+		}
+	}
+
+	cl.queueResponse("listunspent", []*btc.ListUnspentResult{
+		{TxID: tTxID, Address: exposedAddr, Amount: 1},
+		{TxID: tTxID, Vout: 1, Address: exposedAddr, Amount: 0.5},
+	})
+	report, err := w.PrivacyReport()
+	if err != nil {
+		t.Fatalf("PrivacyReport error: %v", err)
+	}
+	if report.TransparentBalance != 1.5e8 {
+		t.Fatalf("wrong transparent balance %d", report.TransparentBalance)
+	}
+	if len(report.Trades) != 2 {
+		t.Fatalf("expected 2 trade exposures, got %d", len(report.Trades))
+	}
+	for _, exp := range report.Trades {
+		switch exp.TxID {
+		case "01":
+			if len(exp.Reshielded) != 0 {
+				t.Fatalf("swap address reported as reshielded")
+			}
+		case "02":
+			if len(exp.Reshielded) != 1 || exp.Reshielded[0] != reshieldedAddr {
+				t.Fatalf("redeem address not reported as reshielded")
+			}
+		default:
+			t.Fatalf("unexpected exposure %s", exp.TxID)
+		}
+	}
+}
+
+func TestPrivacyClass(t *testing.T) {
+	tests := []struct {
+		name    string
+		tIn     bool
+		tOut    bool
+		orchard uint64
+		exp     string
+	}{
+		{name: "swap", tIn: true, tOut: true, exp: privacyTransparent},
+		{name: "deshield", tOut: true, orchard: 2, exp: privacyDeshield},
+		{name: "shield", tIn: true, orchard: 2, exp: privacyShield},
+		{name: "mixed", tIn: true, tOut: true, orchard: 2, exp: privacyMixed},
+		{name: "shielded", orchard: 2, exp: privacyShielded},
+	}
+	for _, tt := range tests {
+		tx := dexzec.NewTxFromMsgTx(wire.NewMsgTx(dexzec.VersionNU5), dexzec.MaxExpiryHeight)
+		if tt.tIn {
+			tx.TxIn = append(tx.TxIn, dummyInput())
+		}
+		if tt.tOut {
+			tx.TxOut = append(tx.TxOut, wire.NewTxOut(1, tP2PKH))
+		}
+		tx.NActionsOrchard = tt.orchard
+		if class := privacyClass(tx); class != tt.exp {
+			t.Fatalf("%s: expected %q, got %q", tt.name, tt.exp, class)
+		}
+	}
+
+	w, _, shutdown := tNewWallet()
+	defer shutdown()
+	tx := makeRawTx([]dex.Bytes{tP2PKH}, []*wire.TxIn{dummyInput()})
+	wt := new(asset.WalletTransaction)
+	w.tagPrivacy(wt, tx)
+	if wt.AdditionalData[privacyTagKey] != privacyTransparent {
+		t.Fatalf("wrong privacy tag %q", wt.AdditionalData[privacyTagKey])
+	}
+	if wt.AdditionalData[transparentAddrsTagKey] == "" {
+		t.Fatalf("no transparent addresses tagged")
+	}
+}
@@ -66,6 +66,7 @@ import (
 	"decred.org/dcrdex/client/asset"
 	"decred.org/dcrdex/client/core"
 	"decred.org/dcrdex/client/mm"
+	"decred.org/dcrdex/client/notify"
 	"decred.org/dcrdex/client/rpcserver"
 	"decred.org/dcrdex/client/webserver"
 	"decred.org/dcrdex/dex"
@@ -202,6 +203,19 @@ func mainCore() error {
 		cm.Wait()
 	}()
 
+	notifier, err := notify.New(clientCore, cfg.NotifyConfigPath, cfg.NotifyQueuePath, logMaker.Logger("NTFY"))
+	if err != nil {
+		return fmt.Errorf("error creating notifier: %w", err)
+	}
+	notifierCM := dex.NewConnectionMaster(notifier)
+	if err := notifierCM.ConnectOnce(appCtx); err != nil {
+		return fmt.Errorf("error connecting notifier: %w", err)
+	}
+	defer func() {
+		cancel()
+		notifierCM.Wait()
+	}()
+
 	if cfg.RPCOn {
 		rpcSrv, err := rpcserver.New(cfg.RPC(clientCore, marketMaker, logMaker.Logger("RPC")))
 		if err != nil {
@@ -222,7 +236,7 @@ func mainCore() error {
 		}()
 	}
 
-	webSrv, err := webserver.New(cfg.Web(clientCore, marketMaker, logMaker.Logger("WEB"), utc))
+	webSrv, err := webserver.New(cfg.Web(clientCore, marketMaker, notifier, logMaker.Logger("WEB"), utc))
 	if err != nil {
 		return fmt.Errorf("failed creating web server: %w", err)
 	}
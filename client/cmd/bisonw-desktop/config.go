@@ -4,6 +4,7 @@
 package main
 
 import (
+	"errors"
 	"fmt"
 	"os"
 	"runtime"
@@ -49,6 +50,11 @@ func configure() (*Config, error) {
 		return nil, err
 	}
 
+	// Additional profiles are only supported by bisonw.
+	if len(cfg.Profiles) > 0 {
+		return nil, errors.New("profiles are not supported by the desktop app")
+	}
+
 	// Resolve unset fields.
 	return &cfg, app.ResolveConfig(appData, &cfg.Config)
 }
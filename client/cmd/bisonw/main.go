@@ -22,6 +22,7 @@ import (
 	_ "decred.org/dcrdex/client/asset/importall"
 	"decred.org/dcrdex/client/core"
 	"decred.org/dcrdex/client/mm"
+	"decred.org/dcrdex/client/notify"
 	"decred.org/dcrdex/client/rpcserver"
 	"decred.org/dcrdex/client/webserver"
 	"decred.org/dcrdex/dex"
@@ -92,13 +93,27 @@ func runCore(cfg *app.Config) error {
 		return fmt.Errorf("error creating market maker: %w", err)
 	}
 
+	// Start any additional profiles.
+	profiles, profilesWG, err := app.RunProfiles(appCtx, cfg, logMaker)
+	defer func() {
+		cancel()
+		profilesWG.Wait()
+	}()
+	if err != nil {
+		return fmt.Errorf("error starting profiles: %w", err)
+	}
+	clientCores := []*core.Core{clientCore}
+	for _, p := range profiles {
+		clientCores = append(clientCores, p.Core)
+	}
+
 	// Catch interrupt signal (e.g. ctrl+c), prompting to shutdown if the user
 	// is logged in, and there are active orders or matches.
 	killChan := make(chan os.Signal, 1)
 	signal.Notify(killChan, os.Interrupt)
 	go func() {
 		for range killChan {
-			if promptShutdown(clientCore) {
+			if promptShutdown(clientCores) {
 				log.Infof("Shutting down...")
 				cancel()
 				return
@@ -133,8 +148,23 @@ func runCore(cfg *app.Config) error {
 		}
 	}
 
+	notifier, err := notify.New(clientCore, cfg.NotifyConfigPath, cfg.NotifyQueuePath, logMaker.Logger("NTFY"))
+	if err != nil {
+		return fmt.Errorf("error creating notifier: %w", err)
+	}
+	notifierCM := dex.NewConnectionMaster(notifier)
+	if err := notifierCM.ConnectOnce(appCtx); err != nil {
+		return fmt.Errorf("error connecting notifier: %w", err)
+	}
+	defer func() {
+		cancel()
+		notifierCM.Wait()
+	}()
+
 	if cfg.RPCOn {
-		rpcSrv, err := rpcserver.New(cfg.RPC(clientCore, marketMaker, logMaker.Logger("RPC")))
+		rpcCfg := cfg.RPC(clientCore, marketMaker, logMaker.Logger("RPC"))
+		rpcCfg.Profiles = profiles.RPC()
+		rpcSrv, err := rpcserver.New(rpcCfg)
 		if err != nil {
 			return fmt.Errorf("failed to create rpc server: %w", err)
 		}
@@ -154,7 +184,9 @@ func runCore(cfg *app.Config) error {
 	}
 
 	if !cfg.NoWeb {
-		webSrv, err := webserver.New(cfg.Web(clientCore, marketMaker, logMaker.Logger("WEB"), utc))
+		webCfg := cfg.Web(clientCore, marketMaker, notifier, logMaker.Logger("WEB"), utc)
+		webCfg.Profiles = profiles.Web()
+		webSrv, err := webserver.New(webCfg)
 		if err != nil {
 			return fmt.Errorf("failed creating web server: %w", err)
 		}
@@ -182,23 +214,25 @@ func runCore(cfg *app.Config) error {
 	return nil
 }
 
-// promptShutdown checks if there are active orders and asks confirmation to
-// shutdown if there are. The return value indicates if it is safe to stop Core
-// or if the user has confirmed they want to shutdown with active orders.
-func promptShutdown(clientCore *core.Core) bool {
+// promptShutdown checks if there are active orders in any of the profiles and
+// asks confirmation to shutdown if there are. The return value indicates if it
+// is safe to stop Core or if the user has confirmed they want to shutdown with
+// active orders.
+func promptShutdown(clientCores []*core.Core) bool {
 	log.Infof("Attempting to logout...")
 	// Do not allow Logout hanging to prevent shutdown.
 	res := make(chan bool, 1)
 	go func() {
 		// Only block logout if err is ActiveOrdersLogoutErr.
-		var ok bool
-		err := clientCore.Logout()
-		if err == nil {
-			ok = true
-		} else if !errors.Is(err, core.ActiveOrdersLogoutErr) {
-			log.Errorf("Unexpected logout error: %v", err)
-			ok = true
-		} // else not ok => prompt
+		ok := true
+		for _, clientCore := range clientCores {
+			err := clientCore.Logout()
+			if errors.Is(err, core.ActiveOrdersLogoutErr) {
+				ok = false // prompt
+			} else if err != nil {
+				log.Errorf("Unexpected logout error: %v", err)
+			}
+		}
 		res <- ok
 	}()
 
@@ -38,6 +38,7 @@ type config struct {
 	Config       string   `short:"C" long:"config" description:"Path to configuration file"`
 	RPCUser      string   `short:"u" long:"rpcuser" description:"RPC username"`
 	RPCPass      string   `short:"P" long:"rpcpass" default-mask:"-" description:"RPC password"`
+	APIKey       string   `long:"apikey" default-mask:"-" description:"RPC server API key token, used instead of the RPC username and password"`
 	RPCAddr      string   `short:"a" long:"rpcaddr" description:"RPC server to connect to"`
 	RPCCert      string   `short:"c" long:"rpccert" description:"RPC server certificate chain for validation"`
 	PrintJSON    bool     `short:"j" long:"json" description:"Print json messages sent and received"`
@@ -45,6 +46,7 @@ type config struct {
 	ProxyUser    string   `long:"proxyuser" description:"Username for proxy server"`
 	ProxyPass    string   `long:"proxypass" default-mask:"-" description:"Password for proxy server"`
 	PasswordArgs []string `short:"p" long:"passarg" description:"Password arguments to bypass stdin prompts."`
+	Profile      string   `long:"profile" description:"Name of the bisonw profile to send the request to. The default profile is used if not set."`
 	Testnet      bool     `long:"testnet" description:"use testnet"`
 	Simnet       bool     `long:"simnet" description:"use simnet"`
 }
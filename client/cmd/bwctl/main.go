@@ -47,22 +47,30 @@ func main() {
 // promptPasswords is a map of routes to password prompts. Passwords are
 // prompted in the order given.
 var promptPasswords = map[string][]string{
-	"discoveracct":      {"App password:"},
-	"init":              {"Set new app password:"},
-	"login":             {"App password:"},
-	"newwallet":         {"App password:", "Wallet password:"},
-	"openwallet":        {"App password:"},
-	"register":          {"App password:"},
-	"postbond":          {"App password:"},
-	"trade":             {"App password:"},
-	"withdraw":          {"App password:"},
-	"send":              {"App password:"},
-	"appseed":           {"App password:"},
-	"startmarketmaking": {"App password:"},
-	"multitrade":        {"App password:"},
-	"purchasetickets":   {"App password:"},
-	"startmmbot":        {"App password:"},
-	"withdrawbchspv":    {"App password"},
+	"discoveracct":           {"App password:"},
+	"init":                   {"Set new app password:"},
+	"login":                  {"App password:"},
+	"newwallet":              {"App password:", "Wallet password:"},
+	"openwallet":             {"App password:"},
+	"register":               {"App password:"},
+	"postbond":               {"App password:"},
+	"trade":                  {"App password:"},
+	"withdraw":               {"App password:"},
+	"send":                   {"App password:"},
+	"appseed":                {"App password:"},
+	"startmarketmaking":      {"App password:"},
+	"multitrade":             {"App password:"},
+	"purchasetickets":        {"App password:"},
+	"startmmbot":             {"App password:"},
+	"withdrawbchspv":         {"App password"},
+	"exportbackup":           {"App password:"},
+	"restorebackup":          {"Backup app password:"},
+	"createapikey":           {"App password:"},
+	"addaddress":             {"App password:"},
+	"removeaddress":          {"App password:"},
+	"setwithdrawalpolicy":    {"App password:"},
+	"createconditionalorder": {"App password:"},
+	"createmanagedorder":     {"App password:"},
 }
 
 // optionalTextFiles is a map of routes to arg index for routes that should read
@@ -180,8 +188,9 @@ func run(ctx context.Context) error {
 	}
 
 	payload := &rpcserver.RawParams{
-		PWArgs: pws,
-		Args:   params,
+		PWArgs:  pws,
+		Args:    params,
+		Profile: cfg.Profile,
 	}
 
 	// Create a request using the parsedArgs.
@@ -0,0 +1,249 @@
+// This code is available on the terms of the project LICENSE.md file,
+// also available online at https://blueoakcouncil.org/license/1.0.0.
+
+package core
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"decred.org/dcrdex/dex"
+	"decred.org/dcrdex/dex/encode"
+	"decred.org/dcrdex/dex/encrypt"
+)
+
+// API key scopes. The RPC server maps each route to a required scope.
+const (
+	APIScopeRead     = "read"
+	APIScopeTrade    = "trade"
+	APIScopeMM       = "mm"
+	APIScopeWithdraw = "withdraw"
+)
+
+var apiScopes = map[string]bool{
+	APIScopeRead:     true,
+	APIScopeTrade:    true,
+	APIScopeMM:       true,
+	APIScopeWithdraw: true,
+}
+
+const (
+	apiKeyIDSize     = 8
+	apiKeySecretSize = 32
+)
+
+// APIKey is a scoped key for the RPC server. API keys are stored encrypted with
+// the app password, and are only usable while the app is logged in.
+type APIKey struct {
+	ID     string   `json:"id"`
+	Name   string   `json:"name"`
+	Scopes []string `json:"scopes"`
+	// AddressAllowlist, if set, restricts sends and withdrawals with the key
+	// to the listed addresses, keyed by asset ID. Sends of assets that are not
+	// in the allowlist are not allowed.
+	AddressAllowlist map[uint32][]string `json:"addressAllowlist,omitempty"`
+	// Expiration is the UNIX time, in seconds, after which the key is not
+	// valid. Zero means no expiration.
+	Expiration int64 `json:"expiration,omitempty"`
+	// Created is the UNIX time, in seconds, that the key was created.
+	Created int64 `json:"created"`
+}
+
+// HasScope checks whether the key has the scope.
+func (k *APIKey) HasScope(scope string) bool {
+	for _, s := range k.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// AllowsAddress checks whether the key can be used to send funds to the
+// address.
+func (k *APIKey) AllowsAddress(assetID uint32, addr string) bool {
+	if k.AddressAllowlist == nil {
+		return true
+	}
+	for _, a := range k.AddressAllowlist[assetID] {
+		if a == addr {
+			return true
+		}
+	}
+	return false
+}
+
+// Expired checks whether the key has expired.
+func (k *APIKey) Expired() bool {
+	return k.Expiration != 0 && time.Now().Unix() > k.Expiration
+}
+
+// APIKeyForm is the information necessary to create an API key.
+type APIKeyForm struct {
+	Name             string              `json:"name"`
+	Scopes           []string            `json:"scopes"`
+	AddressAllowlist map[uint32][]string `json:"addressAllowlist"`
+	// Expiration is the UNIX time, in seconds, after which the key is not
+	// valid. Zero means no expiration.
+	Expiration int64 `json:"expiration"`
+}
+
+// apiKey is an APIKey with the hash of its secret. Only the hash is stored, so
+// the token cannot be recovered from the DB.
+type apiKey struct {
+	*APIKey
+	SecretHash dex.Bytes `json:"secretHash"`
+}
+
+// CreateAPIKey creates a new API key. The returned token is used to
+// authenticate with the RPC server, and cannot be retrieved again.
+func (c *Core) CreateAPIKey(pw []byte, form *APIKeyForm) (*APIKey, string, error) {
+	if len(form.Scopes) == 0 {
+		return nil, "", errors.New("no scopes specified")
+	}
+	for _, scope := range form.Scopes {
+		if !apiScopes[scope] {
+			return nil, "", fmt.Errorf("unknown scope %q", scope)
+		}
+	}
+	now := time.Now().Unix()
+	if form.Expiration != 0 && form.Expiration <= now {
+		return nil, "", errors.New("expiration is in the past")
+	}
+	for assetID, addrs := range form.AddressAllowlist {
+		for _, addr := range addrs {
+			if addr == "" {
+				return nil, "", fmt.Errorf("empty %s address in allowlist", unbip(assetID))
+			}
+		}
+	}
+
+	crypter, err := c.encryptionKey(pw)
+	if err != nil {
+		return nil, "", codedError(passwordErr, err)
+	}
+	defer crypter.Close()
+
+	secret := encode.RandomBytes(apiKeySecretSize)
+	secretHash := sha256.Sum256(secret)
+	key := &apiKey{
+		APIKey: &APIKey{
+			ID:               hex.EncodeToString(encode.RandomBytes(apiKeyIDSize)),
+			Name:             form.Name,
+			Scopes:           form.Scopes,
+			AddressAllowlist: form.AddressAllowlist,
+			Expiration:       form.Expiration,
+			Created:          now,
+		},
+		SecretHash: secretHash[:],
+	}
+	b, err := json.Marshal(key)
+	if err != nil {
+		return nil, "", fmt.Errorf("error encoding API key: %w", err)
+	}
+	encKey, err := crypter.Encrypt(b)
+	if err != nil {
+		return nil, "", fmt.Errorf("error encrypting API key: %w", err)
+	}
+	if err := c.db.StoreAPIKey(key.ID, encKey); err != nil {
+		return nil, "", fmt.Errorf("error storing API key: %w", err)
+	}
+
+	c.apiKeysMtx.Lock()
+	if c.apiKeys != nil {
+		c.apiKeys[key.ID] = key
+	}
+	c.apiKeysMtx.Unlock()
+
+	return key.APIKey, key.ID + "." + hex.EncodeToString(secret), nil
+}
+
+// loadAPIKeys decrypts the API keys stored in the DB. The keys are usable until
+// logout.
+func (c *Core) loadAPIKeys(crypter encrypt.Crypter) error {
+	encKeys, err := c.db.APIKeys()
+	if err != nil {
+		return err
+	}
+	keys := make(map[string]*apiKey, len(encKeys))
+	for id, encKey := range encKeys {
+		b, err := crypter.Decrypt(encKey)
+		if err != nil {
+			c.log.Errorf("Error decrypting API key %s: %v", id, err)
+			continue
+		}
+		var key apiKey
+		if err := json.Unmarshal(b, &key); err != nil || key.APIKey == nil {
+			c.log.Errorf("Error decoding API key %s: %v", id, err)
+			continue
+		}
+		keys[id] = &key
+	}
+	c.apiKeysMtx.Lock()
+	c.apiKeys = keys
+	c.apiKeysMtx.Unlock()
+	return nil
+}
+
+// APIKeys lists the API keys. The app must be logged in.
+func (c *Core) APIKeys() ([]*APIKey, error) {
+	c.apiKeysMtx.RLock()
+	defer c.apiKeysMtx.RUnlock()
+	if c.apiKeys == nil {
+		return nil, errors.New("not logged in")
+	}
+	keys := make([]*APIKey, 0, len(c.apiKeys))
+	for _, key := range c.apiKeys {
+		keys = append(keys, key.APIKey)
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i].Created < keys[j].Created })
+	return keys, nil
+}
+
+// RevokeAPIKey deletes the API key.
+func (c *Core) RevokeAPIKey(id string) error {
+	c.apiKeysMtx.Lock()
+	defer c.apiKeysMtx.Unlock()
+	if c.apiKeys != nil && c.apiKeys[id] == nil {
+		return fmt.Errorf("unknown API key %q", id)
+	}
+	if err := c.db.DeleteAPIKey(id); err != nil {
+		return fmt.Errorf("error deleting API key: %w", err)
+	}
+	delete(c.apiKeys, id)
+	return nil
+}
+
+// AuthorizeAPIKey checks the API key token, and returns the APIKey if the
+// token is valid and the key has not expired.
+func (c *Core) AuthorizeAPIKey(token string) (*APIKey, error) {
+	id, secretHex, found := strings.Cut(token, ".")
+	if !found {
+		return nil, errors.New("malformed API key")
+	}
+	secret, err := hex.DecodeString(secretHex)
+	if err != nil || len(secret) != apiKeySecretSize {
+		return nil, errors.New("malformed API key")
+	}
+	c.apiKeysMtx.RLock()
+	key := c.apiKeys[id]
+	c.apiKeysMtx.RUnlock()
+	if key == nil {
+		return nil, errors.New("unknown API key")
+	}
+	secretHash := sha256.Sum256(secret)
+	if subtle.ConstantTimeCompare(secretHash[:], key.SecretHash) != 1 {
+		return nil, errors.New("invalid API key")
+	}
+	if key.Expired() {
+		return nil, errors.New("API key expired")
+	}
+	return key.APIKey, nil
+}
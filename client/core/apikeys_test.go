@@ -0,0 +1,91 @@
+//go:build !harness && !botlive
+
+package core
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAPIKeys(t *testing.T) {
+	rig := newTestRig()
+	defer rig.shutdown()
+	tCore := rig.core
+
+	form := &APIKeyForm{
+		Name:             "bot",
+		Scopes:           []string{APIScopeRead, APIScopeWithdraw},
+		AddressAllowlist: map[uint32][]string{42: {"Dsaddr"}},
+	}
+	for _, bad := range []*APIKeyForm{
+		{Name: "no scopes"},
+		{Scopes: []string{"admin"}},
+		{Scopes: []string{APIScopeRead}, Expiration: time.Now().Add(-time.Hour).Unix()},
+	} {
+		if _, _, err := tCore.CreateAPIKey(tPW, bad); err == nil {
+			t.Fatalf("no error for bad form %+v", bad)
+		}
+	}
+
+	// Keys are loaded at login.
+	if _, err := tCore.APIKeys(); err == nil {
+		t.Fatalf("no error listing keys before login")
+	}
+	key, token, err := tCore.CreateAPIKey(tPW, form)
+	if err != nil {
+		t.Fatalf("CreateAPIKey error: %v", err)
+	}
+	if _, err := tCore.AuthorizeAPIKey(token); err == nil {
+		t.Fatalf("authorized before login")
+	}
+	if err := tCore.loadAPIKeys(rig.crypter); err != nil {
+		t.Fatalf("loadAPIKeys error: %v", err)
+	}
+	keys, err := tCore.APIKeys()
+	if err != nil || len(keys) != 1 || keys[0].ID != key.ID {
+		t.Fatalf("wrong keys listed: %v", err)
+	}
+
+	authedKey, err := tCore.AuthorizeAPIKey(token)
+	if err != nil {
+		t.Fatalf("AuthorizeAPIKey error: %v", err)
+	}
+	if !authedKey.HasScope(APIScopeWithdraw) || authedKey.HasScope(APIScopeTrade) {
+		t.Fatalf("wrong scopes")
+	}
+	if !authedKey.AllowsAddress(42, "Dsaddr") || authedKey.AllowsAddress(42, "Dsother") || authedKey.AllowsAddress(0, "bc1addr") {
+		t.Fatalf("allowlist not enforced")
+	}
+
+	// Wrong secret.
+	badToken := token[:len(token)-2] + "00"
+	if strings.HasSuffix(token, "00") {
+		badToken = token[:len(token)-2] + "11"
+	}
+	if _, err := tCore.AuthorizeAPIKey(badToken); err == nil {
+		t.Fatalf("authorized with wrong secret")
+	}
+	if _, err := tCore.AuthorizeAPIKey("abc"); err == nil {
+		t.Fatalf("authorized malformed token")
+	}
+
+	// Expired.
+	tCore.apiKeys[key.ID].Expiration = time.Now().Add(-time.Second).Unix()
+	if _, err := tCore.AuthorizeAPIKey(token); err == nil {
+		t.Fatalf("authorized expired key")
+	}
+
+	if err := tCore.RevokeAPIKey(key.ID); err != nil {
+		t.Fatalf("RevokeAPIKey error: %v", err)
+	}
+	if _, err := tCore.AuthorizeAPIKey(token); err == nil {
+		t.Fatalf("authorized revoked key")
+	}
+	if len(rig.db.apiKeys) != 0 {
+		t.Fatalf("key not deleted from DB")
+	}
+	if err := tCore.RevokeAPIKey(key.ID); err == nil {
+		t.Fatalf("no error revoking unknown key")
+	}
+}
@@ -0,0 +1,74 @@
+// This code is available on the terms of the project LICENSE.md file,
+// also available online at https://blueoakcouncil.org/license/1.0.0.
+
+package core
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"time"
+
+	"decred.org/dcrdex/client/db"
+	"decred.org/dcrdex/client/db/bolt"
+	"decred.org/dcrdex/dex"
+)
+
+// archiveDBFilename is the name of the archive database file, which is in the
+// same directory as the app database.
+const archiveDBFilename = "archive.db"
+
+// pruneInterval is how often the order retention policy is applied.
+var pruneInterval = 24 * time.Hour
+
+// openArchiveDB opens the archive database if a retention policy is configured
+// or if the archive database already exists, so that previously archived
+// orders can still be viewed. The returned db.DB is nil if there is no archive.
+func openArchiveDB(cfg *Config) (db.DB, error) {
+	archivePath := filepath.Join(filepath.Dir(cfg.DBPath), archiveDBFilename)
+	if cfg.RetainDays <= 0 && cfg.RetainOrders <= 0 && !dex.FileExists(archivePath) {
+		return nil, nil
+	}
+	return bolt.NewDB(archivePath, cfg.Logger.SubLogger("ARCH"), bolt.Opts{})
+}
+
+// pruneOrdersLoop applies the order retention policy on startup and then
+// every pruneInterval.
+func (c *Core) pruneOrdersLoop(ctx context.Context) {
+	for {
+		if n, err := c.pruneOrders(ctx); err != nil {
+			c.log.Errorf("Error pruning orders: %v", err)
+		} else if n > 0 {
+			c.log.Infof("Moved %d inactive orders to the archive database", n)
+		}
+		select {
+		case <-time.After(pruneInterval):
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// pruneOrders moves the inactive orders that are outside of the retention
+// policy, and their matches, from the app database to the archive database.
+func (c *Core) pruneOrders(ctx context.Context) (int, error) {
+	if c.archive == nil {
+		return 0, nil
+	}
+	var olderThan *time.Time
+	if c.cfg.RetainDays > 0 {
+		t := time.Now().AddDate(0, 0, -c.cfg.RetainDays)
+		olderThan = &t
+	}
+	return c.db.PruneOrders(ctx, olderThan, c.cfg.RetainOrders, func(ord *db.MetaOrder, matches []*db.MetaMatch) error {
+		if err := c.archive.UpdateOrder(ord); err != nil {
+			return fmt.Errorf("error archiving order %s: %w", ord.Order.ID(), err)
+		}
+		for _, m := range matches {
+			if err := c.archive.UpdateMatch(m); err != nil {
+				return fmt.Errorf("error archiving match %s: %w", m.MatchID, err)
+			}
+		}
+		return nil
+	})
+}
@@ -0,0 +1,91 @@
+//go:build !harness && !botlive
+
+package core
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"decred.org/dcrdex/client/db"
+	"decred.org/dcrdex/client/db/bolt"
+	"decred.org/dcrdex/dex/encode"
+	"decred.org/dcrdex/dex/order"
+)
+
+func TestPruneOrders(t *testing.T) {
+	rig := newTestRig()
+	defer rig.shutdown()
+	tCore := rig.core
+
+	// No archive, nothing to do.
+	if n, err := tCore.pruneOrders(tCtx); err != nil || n != 0 {
+		t.Fatalf("pruned %d orders without an archive, err = %v", n, err)
+	}
+	if ords, err := tCore.Orders(&OrderFilter{Archived: true}); err != nil || len(ords) != 0 {
+		t.Fatalf("unexpected archived orders %v, err = %v", ords, err)
+	}
+
+	archive, err := bolt.NewDB(filepath.Join(t.TempDir(), archiveDBFilename), tLogger)
+	if err != nil {
+		t.Fatalf("error creating archive db: %v", err)
+	}
+	ctx, cancel := context.WithCancel(tCtx)
+	defer cancel()
+	go archive.Run(ctx)
+	tCore.archive = archive
+	tCore.cfg.RetainDays = 30
+	tCore.cfg.RetainOrders = 100
+
+	lo, dbOrder, _, _ := makeLimitOrder(rig.dc, true, dcrBtcLotSize, dcrBtcRateStep*100)
+	dbOrder.MetaData.Status = order.OrderStatusExecuted
+	dbOrder.MetaData.Proof.DEXSig = encode.RandomBytes(73)
+	var mid order.MatchID
+	copy(mid[:], encode.RandomBytes(32))
+	rig.db.matchesForOID = []*db.MetaMatch{{
+		UserMatch: &order.UserMatch{
+			OrderID:  lo.ID(),
+			MatchID:  mid,
+			Quantity: dcrBtcLotSize,
+			Rate:     dcrBtcRateStep * 100,
+			Status:   order.MatchConfirmed,
+			Side:     order.Maker,
+		},
+		MetaData: &db.MatchMetaData{
+			DEX:   tDexHost,
+			Base:  tUTXOAssetA.ID,
+			Quote: tUTXOAssetB.ID,
+		},
+	}}
+	rig.db.prunedOrders = []*db.MetaOrder{dbOrder}
+
+	n, err := tCore.pruneOrders(tCtx)
+	if err != nil {
+		t.Fatalf("pruneOrders error: %v", err)
+	}
+	if n != 1 {
+		t.Fatalf("expected 1 order pruned, got %d", n)
+	}
+	if rig.db.pruneKeepPerMarket != 100 || rig.db.pruneOlderThan == nil ||
+		time.Since(*rig.db.pruneOlderThan) < 30*24*time.Hour-time.Minute {
+		t.Fatalf("wrong retention policy")
+	}
+
+	// The archived order can be queried read-only.
+	ords, err := tCore.Orders(&OrderFilter{Archived: true})
+	if err != nil {
+		t.Fatalf("Orders error: %v", err)
+	}
+	if len(ords) != 1 || ords[0].ID.String() != lo.ID().String() || len(ords[0].Matches) != 1 {
+		t.Fatalf("archived order not found")
+	}
+	rig.db.orderErr = tErr // not in the app db
+	ord, err := tCore.Order(lo.ID().Bytes())
+	if err != nil {
+		t.Fatalf("Order error: %v", err)
+	}
+	if ord.ID.String() != lo.ID().String() {
+		t.Fatalf("wrong archived order")
+	}
+}
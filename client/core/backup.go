@@ -0,0 +1,300 @@
+// This code is available on the terms of the project LICENSE.md file,
+// also available online at https://blueoakcouncil.org/license/1.0.0.
+
+package core
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"decred.org/dcrdex/dex"
+)
+
+const (
+	// backupVersion is the version of the backup archive format.
+	backupVersion = 1
+	// backupManifestName is the name of the manifest in the backup archive.
+	backupManifestName = "manifest.json"
+	// backupAppDBName is the name of the app DB in the backup archive.
+	backupAppDBName = "app.db"
+	// restoreSuffix is appended to the path of a file restored from a backup.
+	// The staged file replaces the original on the next start, when the file
+	// is not in use. See ApplyStagedRestore.
+	restoreSuffix = ".restore"
+)
+
+// backupMagic identifies a Bison Wallet backup.
+var backupMagic = []byte("BWBACKUP")
+
+// BackupFile is a file included in a full-state backup, in addition to the app
+// DB and the archive DB. The app DB includes the wallet and DEX account
+// configurations.
+type BackupFile struct {
+	// Name is the name of the file in the backup archive.
+	Name string
+	// Path is the location of the file. When restoring, the file is staged to
+	// replace the file at Path on the next start.
+	Path string
+	// WriteTo, if set, writes a consistent snapshot of the file, e.g. for a
+	// database that is open. Otherwise, the file at Path is copied, if it
+	// exists.
+	WriteTo func(w io.Writer) error
+}
+
+// BackupManifest describes the contents of a backup.
+type BackupManifest struct {
+	Version uint16      `json:"version"`
+	Net     dex.Network `json:"net"`
+	// Created is the creation time of the backup, in milliseconds.
+	Created int64    `json:"created"`
+	Files   []string `json:"files"`
+}
+
+// ExportBackup creates a backup of the app DB, the archive DB if there is one,
+// and the specified files, e.g. market maker configuration and event log. The
+// backup is encrypted with the app password.
+func (c *Core) ExportBackup(pw []byte, files []*BackupFile) ([]byte, error) {
+	crypter, err := c.encryptionKey(pw)
+	if err != nil {
+		return nil, fmt.Errorf("password error: %w", err)
+	}
+	crypter.Close()
+
+	tmpDir, err := os.MkdirTemp("", "bwbackup")
+	if err != nil {
+		return nil, fmt.Errorf("error creating temporary directory: %w", err)
+	}
+	defer os.RemoveAll(tmpDir)
+	dbPath := filepath.Join(tmpDir, backupAppDBName)
+	if err := c.db.BackupTo(dbPath, true, true); err != nil {
+		return nil, fmt.Errorf("error backing up app DB: %w", err)
+	}
+
+	manifest := &BackupManifest{
+		Version: backupVersion,
+		Net:     c.net,
+		Created: time.Now().UnixMilli(),
+	}
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	addFile := func(name string, writeTo func(io.Writer) error) error {
+		w, err := zw.Create(name)
+		if err != nil {
+			return err
+		}
+		if err := writeTo(w); err != nil {
+			return fmt.Errorf("error writing %s: %w", name, err)
+		}
+		manifest.Files = append(manifest.Files, name)
+		return nil
+	}
+	if err := addFile(backupAppDBName, copyFileTo(dbPath)); err != nil {
+		return nil, err
+	}
+	if c.archive != nil {
+		archivePath := filepath.Join(tmpDir, archiveDBFilename)
+		if err := c.archive.BackupTo(archivePath, true, true); err != nil {
+			return nil, fmt.Errorf("error backing up archive DB: %w", err)
+		}
+		if err := addFile(archiveDBFilename, copyFileTo(archivePath)); err != nil {
+			return nil, err
+		}
+	}
+	for _, f := range files {
+		if f.Name == backupAppDBName || f.Name == archiveDBFilename || f.Name == backupManifestName {
+			return nil, fmt.Errorf("reserved backup file name %q", f.Name)
+		}
+		writeTo := f.WriteTo
+		if writeTo == nil {
+			if _, err := os.Stat(f.Path); errors.Is(err, os.ErrNotExist) {
+				continue
+			}
+			writeTo = copyFileTo(f.Path)
+		}
+		if err := addFile(f.Name, writeTo); err != nil {
+			return nil, err
+		}
+	}
+	w, err := zw.Create(backupManifestName)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.NewEncoder(w).Encode(manifest); err != nil {
+		return nil, fmt.Errorf("error encoding manifest: %w", err)
+	}
+	if err := zw.Close(); err != nil {
+		return nil, fmt.Errorf("error finalizing archive: %w", err)
+	}
+
+	backupCrypter := c.newCrypter(pw)
+	defer backupCrypter.Close()
+	enc, err := backupCrypter.Encrypt(buf.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("encryption error: %w", err)
+	}
+	params := backupCrypter.Serialize()
+
+	// magic | uint16 version | uint16 params length | params | ciphertext
+	b := make([]byte, 0, len(backupMagic)+4+len(params)+len(enc))
+	b = append(b, backupMagic...)
+	b = binary.BigEndian.AppendUint16(b, backupVersion)
+	b = binary.BigEndian.AppendUint16(b, uint16(len(params)))
+	b = append(b, params...)
+	return append(b, enc...), nil
+}
+
+// RestoreBackup restores a backup created by ExportBackup. The backup can only
+// be restored before the app is initialized. The app DB and the specified
+// files are staged to replace the current files when the app is restarted,
+// after which the app password is the password used to create the backup.
+// Files in the backup that are not the app DB and are not specified are
+// ignored.
+func (c *Core) RestoreBackup(pw, backup []byte, files []*BackupFile) (*BackupManifest, error) {
+	if c.IsInitialized() {
+		return nil, errors.New("a backup can only be restored before the app is initialized")
+	}
+
+	hdrLen := len(backupMagic) + 4
+	if len(backup) < hdrLen || !bytes.Equal(backup[:len(backupMagic)], backupMagic) {
+		return nil, errors.New("not a Bison Wallet backup")
+	}
+	if ver := binary.BigEndian.Uint16(backup[len(backupMagic):]); ver > backupVersion {
+		return nil, fmt.Errorf("unsupported backup version %d", ver)
+	}
+	paramsLen := int(binary.BigEndian.Uint16(backup[len(backupMagic)+2:]))
+	if len(backup) < hdrLen+paramsLen {
+		return nil, errors.New("backup is truncated")
+	}
+	crypter, err := c.reCrypter(pw, backup[hdrLen:hdrLen+paramsLen])
+	if err != nil {
+		return nil, fmt.Errorf("error deserializing backup key: %w", err)
+	}
+	defer crypter.Close()
+	archive, err := crypter.Decrypt(backup[hdrLen+paramsLen:])
+	if err != nil {
+		return nil, errors.New("wrong password or corrupt backup")
+	}
+
+	zr, err := zip.NewReader(bytes.NewReader(archive), int64(len(archive)))
+	if err != nil {
+		return nil, fmt.Errorf("error reading archive: %w", err)
+	}
+	entries := make(map[string]*zip.File, len(zr.File))
+	for _, f := range zr.File {
+		entries[f.Name] = f
+	}
+	manifestFile := entries[backupManifestName]
+	if manifestFile == nil {
+		return nil, errors.New("backup has no manifest")
+	}
+	var manifest BackupManifest
+	if err := readZipJSON(manifestFile, &manifest); err != nil {
+		return nil, fmt.Errorf("error reading manifest: %w", err)
+	}
+	if manifest.Version == 0 || manifest.Version > backupVersion {
+		return nil, fmt.Errorf("unsupported manifest version %d", manifest.Version)
+	}
+	if manifest.Net != c.net {
+		return nil, fmt.Errorf("backup is for %s, not %s", manifest.Net, c.net)
+	}
+	if entries[backupAppDBName] == nil {
+		return nil, errors.New("backup has no app DB")
+	}
+
+	dests := map[string]string{
+		backupAppDBName:   c.cfg.DBPath,
+		archiveDBFilename: filepath.Join(filepath.Dir(c.cfg.DBPath), archiveDBFilename),
+	}
+	for _, f := range files {
+		dests[f.Name] = f.Path
+	}
+	var staged []string
+	for _, name := range manifest.Files {
+		dst, found := dests[name]
+		if !found {
+			c.log.Warnf("Ignoring unknown backup file %q", name)
+			continue
+		}
+		f := entries[name]
+		if f == nil {
+			return nil, fmt.Errorf("backup file %q not found in archive", name)
+		}
+		if err := stageRestoreFile(f, dst+restoreSuffix); err != nil {
+			for _, p := range staged {
+				os.Remove(p)
+			}
+			return nil, fmt.Errorf("error staging %s: %w", name, err)
+		}
+		staged = append(staged, dst+restoreSuffix)
+	}
+	c.restorePending.Store(true)
+	c.log.Infof("Backup from %s restored. Restart to complete the restoration.",
+		time.UnixMilli(manifest.Created).Format(time.RFC3339))
+	return &manifest, nil
+}
+
+// ApplyStagedRestore replaces the file at path with a file staged by
+// RestoreBackup, if one exists. ApplyStagedRestore must be called before the
+// file is opened.
+func ApplyStagedRestore(path string) (bool, error) {
+	staged := path + restoreSuffix
+	if _, err := os.Stat(staged); err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return false, nil
+		}
+		return false, err
+	}
+	if err := os.Rename(staged, path); err != nil {
+		return false, fmt.Errorf("error replacing %s with restored file: %w", path, err)
+	}
+	return true, nil
+}
+
+func copyFileTo(path string) func(io.Writer) error {
+	return func(w io.Writer) error {
+		f, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		_, err = io.Copy(w, f)
+		return err
+	}
+}
+
+func readZipJSON(f *zip.File, thing any) error {
+	r, err := f.Open()
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+	return json.NewDecoder(r).Decode(thing)
+}
+
+func stageRestoreFile(f *zip.File, dst string) error {
+	if err := os.MkdirAll(filepath.Dir(dst), 0700); err != nil {
+		return err
+	}
+	r, err := f.Open()
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+	w, err := os.OpenFile(dst, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(w, r); err != nil {
+		w.Close()
+		return err
+	}
+	return w.Close()
+}
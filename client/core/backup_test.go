@@ -0,0 +1,90 @@
+//go:build !harness && !botlive
+
+package core
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestBackup(t *testing.T) {
+	rig := newTestRig()
+	defer rig.shutdown()
+	tCore := rig.core
+
+	dir := t.TempDir()
+	cfgPath, logPath := filepath.Join(dir, "mm_cfg.json"), filepath.Join(dir, "eventlog.db")
+	if err := os.WriteFile(cfgPath, []byte("{}"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	files := []*BackupFile{
+		{Name: "mm_cfg.json", Path: cfgPath},
+		{Name: "eventlog.db", Path: logPath, WriteTo: func(w io.Writer) error {
+			_, err := w.Write([]byte("events"))
+			return err
+		}},
+		{Name: "missing", Path: filepath.Join(dir, "missing")},
+	}
+
+	// The archive DB is included when there is one.
+	tCore.archive = &TDB{}
+
+	backup, err := tCore.ExportBackup(tPW, files)
+	if err != nil {
+		t.Fatalf("ExportBackup error: %v", err)
+	}
+
+	// Initialized clients can't restore.
+	if _, err := tCore.RestoreBackup(tPW, backup, files); err == nil {
+		t.Fatalf("no error restoring to initialized client")
+	}
+	tCore.credentials = nil
+
+	// Bad backups.
+	if _, err := tCore.RestoreBackup(tPW, backup[:10], files); err == nil {
+		t.Fatalf("no error for truncated backup")
+	}
+	badVer := bytes.Clone(backup)
+	badVer[len(backupMagic)+1] = backupVersion + 1
+	if _, err := tCore.RestoreBackup(tPW, badVer, files); err == nil {
+		t.Fatalf("no error for unknown version")
+	}
+	rig.crypter.(*tCrypter).decryptErr = tErr
+	if _, err := tCore.RestoreBackup(tPW, backup, files); err == nil {
+		t.Fatalf("no error for wrong password")
+	}
+	rig.crypter.(*tCrypter).decryptErr = nil
+
+	tCore.cfg.DBPath = filepath.Join(dir, "dexc.db")
+	manifest, err := tCore.RestoreBackup(tPW, backup, files)
+	if err != nil {
+		t.Fatalf("RestoreBackup error: %v", err)
+	}
+	if len(manifest.Files) != 4 || manifest.Net != tCore.net {
+		t.Fatalf("wrong manifest %+v", manifest)
+	}
+	if _, err := tCore.InitializeClient(tPW, nil); err == nil {
+		t.Fatalf("no error initializing with a pending restore")
+	}
+
+	for path, want := range map[string]string{
+		tCore.cfg.DBPath:                      "tdb",
+		filepath.Join(dir, archiveDBFilename): "tdb",
+		cfgPath:                               "{}",
+		logPath:                               "events",
+	} {
+		restored, err := ApplyStagedRestore(path)
+		if err != nil || !restored {
+			t.Fatalf("%s not restored: %v", path, err)
+		}
+		if b, _ := os.ReadFile(path); string(b) != want {
+			t.Fatalf("wrong contents for %s: %q", path, b)
+		}
+	}
+	if restored, err := ApplyStagedRestore(cfgPath); err != nil || restored {
+		t.Fatalf("restored twice: %v", err)
+	}
+}
@@ -113,6 +113,10 @@ type bookie struct {
 
 	base, quote           uint32
 	baseUnits, quoteUnits dex.UnitInfo
+
+	// stop is called when there are no feeds and the close timer has
+	// expired.
+	stop func()
 }
 
 func defaultUnitInfo(symbol string) dex.UnitInfo {
@@ -170,6 +174,30 @@ func newBookie(dc *dexConnection, base, quote uint32, binSizes []string, logger
 		quote:        quote,
 		baseUnits:    parseUnitInfo(base),
 		quoteUnits:   parseUnitInfo(quote),
+		stop:         func() { dc.stopBook(base, quote) },
+	}
+}
+
+// newMeshBookie is a constructor for a bookie for a Tatanka mesh market. Mesh
+// markets have no candles. stop is called when there are no subscribers and
+// the close timer has expired.
+func newMeshBookie(base, quote uint32, stop func(), logger dex.Logger) *bookie {
+	parseUnitInfo := func(assetID uint32) dex.UnitInfo {
+		if unitInfo, err := asset.UnitInfo(assetID); err == nil {
+			return unitInfo
+		}
+		return defaultUnitInfo(unbip(assetID))
+	}
+	return &bookie{
+		OrderBook:    orderbook.NewOrderBook(logger.SubLogger("book")),
+		candleCaches: make(map[string]*candleCache),
+		log:          logger,
+		feeds:        make(map[uint32]*bookFeed, 1),
+		base:         base,
+		quote:        quote,
+		baseUnits:    parseUnitInfo(base),
+		quoteUnits:   parseUnitInfo(quote),
+		stop:         stop,
 	}
 }
 
@@ -337,7 +365,7 @@ func (b *bookie) closeFeed(feedID uint32) {
 
 			// Call the close func if there are no more feeds.
 			if numFeeds == 0 {
-				b.dc.stopBook(b.base, b.quote)
+				b.stop()
 			}
 		})
 		b.timerMtx.Unlock()
@@ -535,6 +563,14 @@ func (dc *dexConnection) unsubscribe(base, quote uint32) error {
 // receive order book updates. The BookFeed must be Close()d when it is no
 // longer in use.
 func (c *Core) SyncBook(host string, base, quote uint32) (*orderbook.OrderBook, BookFeed, error) {
+	if IsMeshHost(host) {
+		mc, err := c.meshConnection(host)
+		if err != nil {
+			return nil, nil, err
+		}
+		return mc.syncBook(base, quote)
+	}
+
 	c.connMtx.RLock()
 	dc, found := c.conns[host]
 	c.connMtx.RUnlock()
@@ -548,6 +584,10 @@ func (c *Core) SyncBook(host string, base, quote uint32) (*orderbook.OrderBook,
 // Book fetches the order book. If a subscription doesn't exist, one will be
 // attempted and immediately closed.
 func (c *Core) Book(dex string, base, quote uint32) (*OrderBook, error) {
+	if IsMeshHost(dex) {
+		return c.meshOrderBook(dex, base, quote)
+	}
+
 	dex, err := addrHost(dex)
 	if err != nil {
 		return nil, newError(addressParseErr, "error parsing address: %w", err)
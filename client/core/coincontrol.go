@@ -0,0 +1,67 @@
+// This code is available on the terms of the project LICENSE.md file,
+// also available online at https://blueoakcouncil.org/license/1.0.0.
+
+package core
+
+import (
+	"errors"
+	"fmt"
+
+	"decred.org/dcrdex/client/asset"
+	"decred.org/dcrdex/dex"
+)
+
+// coinController returns the connected wallet for the asset as an
+// asset.CoinController.
+func (c *Core) coinController(assetID uint32) (asset.CoinController, error) {
+	w, err := c.connectedWallet(assetID)
+	if err != nil {
+		return nil, err
+	}
+	coinCtl, is := w.Wallet.(asset.CoinController)
+	if !is {
+		return nil, fmt.Errorf("%s wallet does not support coin control", unbip(assetID))
+	}
+	return coinCtl, nil
+}
+
+// ListUTXOs lists the unspent outputs of a wallet that supports coin control,
+// with any labels, and whether they are frozen or locked.
+func (c *Core) ListUTXOs(assetID uint32) ([]*asset.WalletUTXO, error) {
+	coinCtl, err := c.coinController(assetID)
+	if err != nil {
+		return nil, err
+	}
+	return coinCtl.ListUTXOs()
+}
+
+// SetUTXOLabel sets the label for a coin. An empty label removes the label.
+func (c *Core) SetUTXOLabel(assetID uint32, coinID dex.Bytes, label string) error {
+	coinCtl, err := c.coinController(assetID)
+	if err != nil {
+		return err
+	}
+	return coinCtl.SetUTXOLabel(coinID, label)
+}
+
+// FreezeUTXOs freezes or unfreezes coins. Frozen coins are not selected
+// automatically for sends, bonds, or order funding, but can still be spent by
+// specifying them explicitly.
+func (c *Core) FreezeUTXOs(assetID uint32, coinIDs []dex.Bytes, freeze bool) error {
+	if len(coinIDs) == 0 {
+		return errors.New("no coins specified")
+	}
+	coinCtl, err := c.coinController(assetID)
+	if err != nil {
+		return err
+	}
+	return coinCtl.FreezeUTXOs(coinIDs, freeze)
+}
+
+// SendWithCoins is like Send, but only the specified coins may be spent.
+func (c *Core) SendWithCoins(pw []byte, assetID uint32, value uint64, address string, subtract bool, coinIDs []dex.Bytes) (asset.Coin, error) {
+	if len(coinIDs) == 0 {
+		return nil, errors.New("no coins specified")
+	}
+	return c.send(pw, assetID, value, address, subtract, coinIDs)
+}
@@ -0,0 +1,447 @@
+// This code is available on the terms of the project LICENSE.md file,
+// also available online at https://blueoakcouncil.org/license/1.0.0.
+
+package core
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"decred.org/dcrdex/client/db"
+	"decred.org/dcrdex/dex"
+	"decred.org/dcrdex/dex/encode"
+)
+
+// Conditional order types.
+const (
+	// CondStopLoss places the order when the rate falls to the trigger rate
+	// for a sell, or rises to the trigger rate for a buy.
+	CondStopLoss = "stoploss"
+	// CondTakeProfit places the order when the rate rises to the trigger rate
+	// for a sell, or falls to the trigger rate for a buy.
+	CondTakeProfit = "takeprofit"
+	// CondTrailingStop places the order when the rate falls by the trailing
+	// percentage from the highest rate seen for a sell, or rises by the
+	// trailing percentage from the lowest rate seen for a buy.
+	CondTrailingStop = "trailingstop"
+)
+
+// Conditional order statuses.
+const (
+	CondStatusActive    = "active"
+	CondStatusTriggered = "triggered"
+	CondStatusPlaced    = "placed"
+	CondStatusFailed    = "failed"
+)
+
+// condRetryDelay is how long to wait before resubscribing to a market's book
+// feed for conditional orders after a failure.
+const condRetryDelay = time.Minute
+
+// ConditionalOrderForm is the information necessary to create a conditional
+// order. The order described by Sell, Qty, IsLimit, and Rate is placed with
+// Trade when the rule is triggered. For market buys, Qty is in units of the
+// quote asset.
+type ConditionalOrderForm struct {
+	Host    string `json:"host"`
+	Base    uint32 `json:"base"`
+	Quote   uint32 `json:"quote"`
+	Type    string `json:"type"`
+	Sell    bool   `json:"sell"`
+	Qty     uint64 `json:"qty"`
+	IsLimit bool   `json:"isLimit"`
+	Rate    uint64 `json:"rate"`
+	// TriggerRate is the trigger for stop-loss and take-profit orders, in
+	// message-rate units.
+	TriggerRate uint64 `json:"triggerRate"`
+	// TrailPercent is the trailing distance of a trailing stop, as a
+	// percentage of the rate.
+	TrailPercent float64 `json:"trailPercent"`
+}
+
+// ConditionalOrder is a client-side order rule that places an order when the
+// market rate crosses a trigger. The market rate is the book's mid-gap, or the
+// close rate of the latest candle.
+type ConditionalOrder struct {
+	ID string `json:"id"`
+	ConditionalOrderForm
+	// Extreme is the highest rate seen by a trailing stop sell, or the lowest
+	// rate seen by a trailing stop buy.
+	Extreme uint64 `json:"extreme,omitempty"`
+	// Stamp is the time the order was created, in milliseconds.
+	Stamp   uint64    `json:"stamp"`
+	Status  string    `json:"status"`
+	OrderID dex.Bytes `json:"orderID,omitempty"`
+	Error   string    `json:"error,omitempty"`
+}
+
+func conditionalOrderFromDB(o *db.ConditionalOrder) *ConditionalOrder {
+	return &ConditionalOrder{
+		ID: o.ID,
+		ConditionalOrderForm: ConditionalOrderForm{
+			Host:         o.Host,
+			Base:         o.Base,
+			Quote:        o.Quote,
+			Type:         o.Type,
+			Sell:         o.Sell,
+			Qty:          o.Qty,
+			IsLimit:      o.IsLimit,
+			Rate:         o.Rate,
+			TriggerRate:  o.TriggerRate,
+			TrailPercent: float64(o.TrailPPM) / 1e4,
+		},
+		Extreme: o.Extreme,
+		Stamp:   o.Stamp,
+		Status:  o.Status,
+		OrderID: o.OrderID,
+		Error:   o.Error,
+	}
+}
+
+// conditionalTriggered checks whether the conditional order is triggered at
+// the rate. For trailing stops, the Extreme is updated, and updated is true if
+// it changed.
+func conditionalTriggered(o *db.ConditionalOrder, rate uint64) (triggered, updated bool) {
+	switch o.Type {
+	case CondStopLoss:
+		if o.Sell {
+			return rate <= o.TriggerRate, false
+		}
+		return rate >= o.TriggerRate, false
+	case CondTakeProfit:
+		if o.Sell {
+			return rate >= o.TriggerRate, false
+		}
+		return rate <= o.TriggerRate, false
+	case CondTrailingStop:
+		if o.Extreme == 0 || (o.Sell && rate > o.Extreme) || (!o.Sell && rate < o.Extreme) {
+			o.Extreme = rate
+			return false, true
+		}
+		trail := float64(o.Extreme) * float64(o.TrailPPM) / 1e6
+		if o.Sell {
+			return float64(rate) <= float64(o.Extreme)-trail, false
+		}
+		return float64(rate) >= float64(o.Extreme)+trail, false
+	}
+	return false, false
+}
+
+// condMarketKey is the key for a market's conditional order watcher.
+func condMarketKey(host string, base, quote uint32) string {
+	return host + "|" + marketName(base, quote)
+}
+
+// CreateConditionalOrder creates a conditional order. The order is watched
+// while the app is logged in, and persists across restarts. The order is
+// placed without the app password when triggered, so the wallets must remain
+// unlocked.
+func (c *Core) CreateConditionalOrder(pw []byte, form *ConditionalOrderForm) (*ConditionalOrder, error) {
+	crypter, err := c.encryptionKey(pw)
+	if err != nil {
+		return nil, codedError(passwordErr, err)
+	}
+	crypter.Close()
+
+	if form.Qty == 0 {
+		return nil, newError(orderParamsErr, "zero quantity")
+	}
+	if form.IsLimit && form.Rate == 0 {
+		return nil, newError(orderParamsErr, "zero rate for limit order")
+	}
+	ord := &db.ConditionalOrder{
+		ID:      hex.EncodeToString(encode.RandomBytes(8)),
+		Host:    form.Host,
+		Base:    form.Base,
+		Quote:   form.Quote,
+		Type:    form.Type,
+		Sell:    form.Sell,
+		Qty:     form.Qty,
+		IsLimit: form.IsLimit,
+		Rate:    form.Rate,
+		Stamp:   uint64(time.Now().UnixMilli()),
+		Status:  CondStatusActive,
+	}
+	switch form.Type {
+	case CondStopLoss, CondTakeProfit:
+		if form.TriggerRate == 0 {
+			return nil, newError(orderParamsErr, "zero trigger rate")
+		}
+		ord.TriggerRate = form.TriggerRate
+	case CondTrailingStop:
+		if form.TrailPercent <= 0 || form.TrailPercent >= 100 {
+			return nil, newError(orderParamsErr, "trailing percentage must be between 0 and 100")
+		}
+		ord.TrailPPM = uint64(form.TrailPercent * 1e4)
+	default:
+		return nil, newError(orderParamsErr, "unknown conditional order type %q", form.Type)
+	}
+
+	dc, err := c.registeredDEX(form.Host)
+	if err != nil {
+		return nil, err
+	}
+	mktID := marketName(form.Base, form.Quote)
+	if dc.marketConfig(mktID) == nil {
+		return nil, newError(marketErr, "unknown market %q", mktID)
+	}
+	if _, found := c.wallet(form.Base); !found {
+		return nil, newError(missingWalletErr, "no wallet found for %s", unbip(form.Base))
+	}
+	if _, found := c.wallet(form.Quote); !found {
+		return nil, newError(missingWalletErr, "no wallet found for %s", unbip(form.Quote))
+	}
+	if form.Type == CondTrailingStop {
+		// Start trailing from the current rate, if available.
+		if midGap, err := dc.midGap(form.Base, form.Quote); err == nil {
+			ord.Extreme = midGap
+		}
+	}
+
+	if err := c.db.StoreConditionalOrder(ord); err != nil {
+		return nil, codedError(dbErr, err)
+	}
+
+	c.condMtx.Lock()
+	defer c.condMtx.Unlock()
+	if c.condOrders != nil {
+		c.condOrders[ord.ID] = ord
+		c.watchConditionalMarket(ord.Host, ord.Base, ord.Quote)
+	}
+	return conditionalOrderFromDB(ord), nil
+}
+
+// ConditionalOrders lists the conditional orders, including orders that have
+// been triggered, newest first.
+func (c *Core) ConditionalOrders() ([]*ConditionalOrder, error) {
+	c.condMtx.Lock()
+	defer c.condMtx.Unlock()
+	dbOrds, err := c.db.ConditionalOrders()
+	if err != nil {
+		return nil, codedError(dbErr, err)
+	}
+	ords := make([]*ConditionalOrder, 0, len(dbOrds))
+	for _, o := range dbOrds {
+		if active := c.condOrders[o.ID]; active != nil {
+			o = active // for the latest trailing extreme
+		}
+		ords = append(ords, conditionalOrderFromDB(o))
+	}
+	sort.Slice(ords, func(i, j int) bool { return ords[i].Stamp > ords[j].Stamp })
+	return ords, nil
+}
+
+// RemoveConditionalOrder cancels an active conditional order, or removes an
+// inactive one from the history.
+func (c *Core) RemoveConditionalOrder(id string) error {
+	c.condMtx.Lock()
+	defer c.condMtx.Unlock()
+	if err := c.db.DeleteConditionalOrder(id); err != nil {
+		return codedError(dbErr, err)
+	}
+	if ord := c.condOrders[id]; ord != nil {
+		delete(c.condOrders, id)
+		c.stopIdleConditionalWatcher(ord.Host, ord.Base, ord.Quote)
+	}
+	return nil
+}
+
+// startConditionalOrders loads the active conditional orders and starts
+// watching their markets. startConditionalOrders is called on login.
+func (c *Core) startConditionalOrders() {
+	dbOrds, err := c.db.ConditionalOrders()
+	if err != nil {
+		c.log.Errorf("Error loading conditional orders: %v", err)
+		return
+	}
+	c.condMtx.Lock()
+	defer c.condMtx.Unlock()
+	c.condOrders = make(map[string]*db.ConditionalOrder)
+	c.condWatchers = make(map[string]context.CancelFunc)
+	for _, ord := range dbOrds {
+		if ord.Status != CondStatusActive {
+			continue
+		}
+		c.condOrders[ord.ID] = ord
+		c.watchConditionalMarket(ord.Host, ord.Base, ord.Quote)
+	}
+	if len(c.condOrders) > 0 {
+		c.log.Infof("Watching %d conditional orders", len(c.condOrders))
+	}
+}
+
+// stopConditionalOrders stops watching conditional orders. Active orders are
+// watched again at the next login.
+func (c *Core) stopConditionalOrders() {
+	c.condMtx.Lock()
+	defer c.condMtx.Unlock()
+	for _, cancel := range c.condWatchers {
+		cancel()
+	}
+	if len(c.condOrders) > 0 {
+		c.log.Warnf("%d conditional orders will not be watched until the next login", len(c.condOrders))
+	}
+	c.condOrders, c.condWatchers = nil, nil
+}
+
+// watchConditionalMarket starts watching the market for conditional orders, if
+// it is not already watched. The condMtx must be held.
+func (c *Core) watchConditionalMarket(host string, base, quote uint32) {
+	key := condMarketKey(host, base, quote)
+	if c.condWatchers[key] != nil {
+		return
+	}
+	ctx, cancel := context.WithCancel(c.ctx)
+	c.condWatchers[key] = cancel
+	c.wg.Add(1)
+	go func() {
+		defer c.wg.Done()
+		for {
+			if err := c.runConditionalWatcher(ctx, host, base, quote); err != nil {
+				c.log.Errorf("Error watching %s %s for conditional orders: %v", host, marketName(base, quote), err)
+			}
+			select {
+			case <-time.After(condRetryDelay):
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+}
+
+// stopIdleConditionalWatcher stops watching the market if it has no active
+// conditional orders. The condMtx must be held.
+func (c *Core) stopIdleConditionalWatcher(host string, base, quote uint32) {
+	for _, ord := range c.condOrders {
+		if ord.Host == host && ord.Base == base && ord.Quote == quote {
+			return
+		}
+	}
+	key := condMarketKey(host, base, quote)
+	if cancel := c.condWatchers[key]; cancel != nil {
+		cancel()
+		delete(c.condWatchers, key)
+	}
+}
+
+// runConditionalWatcher subscribes to the market's book feed and candles, and
+// checks the conditional orders with each update until the context is
+// canceled or the feed is closed.
+func (c *Core) runConditionalWatcher(ctx context.Context, host string, base, quote uint32) error {
+	c.connMtx.RLock()
+	dc, found := c.conns[host]
+	c.connMtx.RUnlock()
+	if !found {
+		return fmt.Errorf("unknown DEX %s", host)
+	}
+	_, feed, err := dc.syncBook(base, quote)
+	if err != nil {
+		return err
+	}
+	defer feed.Close()
+
+	dc.cfgMtx.RLock()
+	var binSize string
+	var minDur time.Duration
+	for _, s := range dc.cfg.BinSizes {
+		if dur, err := time.ParseDuration(s); err == nil && (minDur == 0 || dur < minDur) {
+			binSize, minDur = s, dur
+		}
+	}
+	dc.cfgMtx.RUnlock()
+	if binSize != "" {
+		if err := feed.Candles(binSize); err != nil {
+			c.log.Warnf("Error subscribing to %s candles for conditional orders: %v", binSize, err)
+		}
+	}
+
+	for {
+		select {
+		case u, ok := <-feed.Next():
+			if !ok {
+				return errors.New("book feed closed")
+			}
+			var rate uint64
+			if u.Action == CandleUpdateAction {
+				if cu, ok := u.Payload.(*CandleUpdate); ok && cu.Candle != nil {
+					rate = cu.Candle.EndRate
+				}
+			} else if midGap, err := dc.midGap(base, quote); err == nil {
+				rate = midGap
+			}
+			if rate > 0 {
+				c.checkConditionalOrders(host, base, quote, rate)
+			}
+		case <-ctx.Done():
+			return nil
+		}
+	}
+}
+
+// checkConditionalOrders checks the market's conditional orders at the rate,
+// and places the orders that are triggered.
+func (c *Core) checkConditionalOrders(host string, base, quote uint32, rate uint64) {
+	c.condMtx.Lock()
+	defer c.condMtx.Unlock()
+	for id, ord := range c.condOrders {
+		if ord.Host != host || ord.Base != base || ord.Quote != quote {
+			continue
+		}
+		triggered, updated := conditionalTriggered(ord, rate)
+		if !triggered {
+			if updated {
+				if err := c.db.StoreConditionalOrder(ord); err != nil {
+					c.log.Errorf("Error storing conditional order %s: %v", id, err)
+				}
+			}
+			continue
+		}
+		c.log.Infof("Conditional %s order %s triggered at rate %d", ord.Type, id, rate)
+		ord.Status = CondStatusTriggered
+		delete(c.condOrders, id)
+		c.stopIdleConditionalWatcher(host, base, quote)
+		if err := c.db.StoreConditionalOrder(ord); err != nil {
+			// Don't risk placing the order twice.
+			c.log.Errorf("Error storing triggered conditional order %s. Not placing order: %v", id, err)
+			continue
+		}
+		c.wg.Add(1)
+		go func(ord *db.ConditionalOrder) {
+			defer c.wg.Done()
+			c.placeConditionalOrder(ord)
+		}(ord)
+	}
+}
+
+// placeConditionalOrder places the order for a triggered conditional order.
+func (c *Core) placeConditionalOrder(ord *db.ConditionalOrder) {
+	corder, err := c.Trade(nil, &TradeForm{
+		Host:    ord.Host,
+		IsLimit: ord.IsLimit,
+		Sell:    ord.Sell,
+		Base:    ord.Base,
+		Quote:   ord.Quote,
+		Qty:     ord.Qty,
+		Rate:    ord.Rate,
+	})
+	if err != nil {
+		ord.Status = CondStatusFailed
+		ord.Error = err.Error()
+		subject, details := c.formatDetails(TopicConditionalOrderFailed, ord.Type, ord.ID, err)
+		c.notify(newConditionalOrderNote(TopicConditionalOrderFailed, subject, details, db.ErrorLevel, conditionalOrderFromDB(ord)))
+	} else {
+		ord.Status = CondStatusPlaced
+		ord.OrderID = corder.ID
+		subject, details := c.formatDetails(TopicConditionalOrderPlaced, ord.Type, ord.ID, makeOrderToken(corder.ID.String()))
+		c.notify(newConditionalOrderNote(TopicConditionalOrderPlaced, subject, details, db.Success, conditionalOrderFromDB(ord)))
+	}
+	c.condMtx.Lock()
+	defer c.condMtx.Unlock()
+	if err := c.db.StoreConditionalOrder(ord); err != nil {
+		c.log.Errorf("Error storing conditional order %s: %v", ord.ID, err)
+	}
+}
@@ -0,0 +1,141 @@
+//go:build !harness && !botlive
+
+package core
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"decred.org/dcrdex/client/db"
+)
+
+func TestConditionalTriggered(t *testing.T) {
+	tests := []struct {
+		name        string
+		ord         *db.ConditionalOrder
+		rate        uint64
+		wantTrigger bool
+		wantExtreme uint64
+	}{
+		{"stop-loss sell above", &db.ConditionalOrder{Type: CondStopLoss, Sell: true, TriggerRate: 100}, 101, false, 0},
+		{"stop-loss sell at", &db.ConditionalOrder{Type: CondStopLoss, Sell: true, TriggerRate: 100}, 100, true, 0},
+		{"stop-loss buy below", &db.ConditionalOrder{Type: CondStopLoss, TriggerRate: 100}, 99, false, 0},
+		{"stop-loss buy above", &db.ConditionalOrder{Type: CondStopLoss, TriggerRate: 100}, 101, true, 0},
+		{"take-profit sell below", &db.ConditionalOrder{Type: CondTakeProfit, Sell: true, TriggerRate: 100}, 99, false, 0},
+		{"take-profit sell above", &db.ConditionalOrder{Type: CondTakeProfit, Sell: true, TriggerRate: 100}, 101, true, 0},
+		{"take-profit buy below", &db.ConditionalOrder{Type: CondTakeProfit, TriggerRate: 100}, 99, true, 0},
+		{"trailing sell first rate", &db.ConditionalOrder{Type: CondTrailingStop, Sell: true, TrailPPM: 1e5}, 100, false, 100},
+		{"trailing sell new high", &db.ConditionalOrder{Type: CondTrailingStop, Sell: true, TrailPPM: 1e5, Extreme: 100}, 120, false, 120},
+		{"trailing sell within trail", &db.ConditionalOrder{Type: CondTrailingStop, Sell: true, TrailPPM: 1e5, Extreme: 100}, 91, false, 100},
+		{"trailing sell at trail", &db.ConditionalOrder{Type: CondTrailingStop, Sell: true, TrailPPM: 1e5, Extreme: 100}, 90, true, 100},
+		{"trailing buy new low", &db.ConditionalOrder{Type: CondTrailingStop, TrailPPM: 1e5, Extreme: 100}, 80, false, 80},
+		{"trailing buy within trail", &db.ConditionalOrder{Type: CondTrailingStop, TrailPPM: 1e5, Extreme: 100}, 109, false, 100},
+		{"trailing buy at trail", &db.ConditionalOrder{Type: CondTrailingStop, TrailPPM: 1e5, Extreme: 100}, 110, true, 100},
+	}
+	for _, tt := range tests {
+		triggered, _ := conditionalTriggered(tt.ord, tt.rate)
+		if triggered != tt.wantTrigger {
+			t.Fatalf("%s: wanted triggered = %t", tt.name, tt.wantTrigger)
+		}
+		if tt.ord.Extreme != tt.wantExtreme {
+			t.Fatalf("%s: wanted extreme %d, got %d", tt.name, tt.wantExtreme, tt.ord.Extreme)
+		}
+	}
+}
+
+func TestConditionalOrders(t *testing.T) {
+	rig := newTestRig()
+	defer rig.shutdown()
+	tCore := rig.core
+	dcrWallet, _ := newTWallet(tUTXOAssetA.ID)
+	tCore.wallets[tUTXOAssetA.ID] = dcrWallet
+	btcWallet, _ := newTWallet(tUTXOAssetB.ID)
+	tCore.wallets[tUTXOAssetB.ID] = btcWallet
+
+	// Pretend to be logged in, but don't start watching the market.
+	tCore.condOrders = make(map[string]*db.ConditionalOrder)
+	tCore.condWatchers = map[string]context.CancelFunc{
+		condMarketKey(tDexHost, tUTXOAssetA.ID, tUTXOAssetB.ID): func() {},
+	}
+
+	form := &ConditionalOrderForm{
+		Host:        tDexHost,
+		Base:        tUTXOAssetA.ID,
+		Quote:       tUTXOAssetB.ID,
+		Type:        CondStopLoss,
+		Sell:        true,
+		Qty:         1e8,
+		TriggerRate: 1e6,
+	}
+	for _, bad := range []func(f *ConditionalOrderForm){
+		func(f *ConditionalOrderForm) { f.Qty = 0 },
+		func(f *ConditionalOrderForm) { f.IsLimit = true },
+		func(f *ConditionalOrderForm) { f.TriggerRate = 0 },
+		func(f *ConditionalOrderForm) { f.Type = "moonshot" },
+		func(f *ConditionalOrderForm) { f.Type = CondTrailingStop },
+		func(f *ConditionalOrderForm) { f.Host = "unknown.dex" },
+		func(f *ConditionalOrderForm) { f.Quote = 12345 },
+	} {
+		badForm := *form
+		bad(&badForm)
+		if _, err := tCore.CreateConditionalOrder(tPW, &badForm); err == nil {
+			t.Fatalf("no error for bad form %+v", badForm)
+		}
+	}
+
+	ord, err := tCore.CreateConditionalOrder(tPW, form)
+	if err != nil {
+		t.Fatalf("CreateConditionalOrder error: %v", err)
+	}
+	trailForm := *form
+	trailForm.Type, trailForm.TrailPercent = CondTrailingStop, 5
+	trailOrd, err := tCore.CreateConditionalOrder(tPW, &trailForm)
+	if err != nil {
+		t.Fatalf("CreateConditionalOrder error: %v", err)
+	}
+	if ords, _ := tCore.ConditionalOrders(); len(ords) != 2 {
+		t.Fatalf("expected 2 conditional orders, got %d", len(ords))
+	}
+
+	// The trailing stop tracks the high.
+	tCore.checkConditionalOrders(tDexHost, tUTXOAssetA.ID, tUTXOAssetB.ID, 2e6)
+	if rig.db.condOrders[trailOrd.ID].Extreme != 2e6 {
+		t.Fatalf("trailing extreme not stored")
+	}
+	if rig.db.condOrders[ord.ID].Status != CondStatusActive {
+		t.Fatalf("stop-loss triggered early")
+	}
+
+	// Trigger the stop-loss. Without the wallet, the order fails.
+	delete(tCore.wallets, tUTXOAssetA.ID)
+	tCore.checkConditionalOrders(tDexHost, tUTXOAssetA.ID, tUTXOAssetB.ID, 1e6)
+	if len(tCore.condOrders) != 0 {
+		t.Fatalf("triggered orders still active")
+	}
+	failed := func() bool {
+		ords, _ := tCore.ConditionalOrders()
+		for _, o := range ords {
+			if o.Status != CondStatusFailed || o.Error == "" {
+				return false
+			}
+		}
+		return true
+	}
+	// The trailing stop, 5% under 2e6, triggered too.
+	for deadline := time.Now().Add(5 * time.Second); !failed(); {
+		if time.Now().After(deadline) {
+			t.Fatalf("triggered orders not failed")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	// Orders can be removed while logged out.
+	tCore.stopConditionalOrders()
+	if err := tCore.RemoveConditionalOrder(trailOrd.ID); err != nil {
+		t.Fatalf("RemoveConditionalOrder error: %v", err)
+	}
+	if ords, _ := tCore.ConditionalOrders(); len(ords) != 1 || ords[0].ID != ord.ID {
+		t.Fatalf("conditional order not removed")
+	}
+}
@@ -1463,6 +1463,22 @@ type Config struct {
 	// for running core in extension mode, which gives the caller options for
 	// e.g. limiting the ability to configure wallets.
 	ExtensionModeFile string
+	// WithdrawalPolicyDelay is the time before a new address book entry or a
+	// less restrictive withdrawal policy takes effect. The default is 24
+	// hours.
+	WithdrawalPolicyDelay time.Duration
+	// HistoricalRates is the source of historical fiat rates for tax history
+	// exports. If nil, exports only include fiat values when a rates file is
+	// specified.
+	HistoricalRates HistoricalRateSource
+	// RetainDays is the number of days that inactive orders are kept in the
+	// app database. Older orders and their matches are moved to the archive
+	// database. Zero disables pruning by age.
+	RetainDays int
+	// RetainOrders is the number of most recent inactive orders per market
+	// that are kept in the app database. Older orders and their matches are
+	// moved to the archive database. Zero disables pruning by count.
+	RetainOrders int
 
 	TheOneHost string
 }
@@ -1484,6 +1500,7 @@ type Core struct {
 	cfg           *Config
 	log           dex.Logger
 	db            db.DB
+	archive       db.DB // nil if there is no archive database
 	net           dex.Network
 	lockTimeTaker time.Duration
 	lockTimeMaker time.Duration
@@ -1499,8 +1516,38 @@ type Core struct {
 	loggedIn  bool
 	bondXPriv *hdkeychain.ExtendedKey // derived from creds.EncSeed on login
 
+	// apiKeys are the RPC API keys, decrypted on login. apiKeys is nil when
+	// logged out.
+	apiKeysMtx sync.RWMutex
+	apiKeys    map[string]*apiKey
+
 	seedGenerationTime uint64
 
+	// withdrawMtx serializes withdrawals and changes to the address book and
+	// withdrawal policies, so that withdrawal limits are enforced.
+	withdrawMtx sync.Mutex
+
+	// condOrders are the active conditional orders, which are watched while
+	// logged in. condOrders and condWatchers are nil when logged out.
+	condMtx      sync.Mutex
+	condOrders   map[string]*db.ConditionalOrder
+	condWatchers map[string]context.CancelFunc // by condMarketKey
+
+	// managedOrders are the active TWAP and iceberg orders, which are
+	// executed while logged in. managedOrders is nil when logged out.
+	managedMtx    sync.Mutex
+	managedOrders map[string]*managedOrder
+
+	// meshes are the connected Tatanka meshes, keyed by host, which is the
+	// entry node address prefixed with MeshHostPrefix. meshes is nil when
+	// logged out.
+	meshMtx sync.RWMutex
+	meshes  map[string]*meshConnection
+
+	// restorePending is set when a backup has been restored by RestoreBackup,
+	// and the app must be restarted to complete the restoration.
+	restorePending atomic.Bool
+
 	wsConstructor func(*comms.WsCfg) (comms.WsConn, error)
 	newCrypter    func([]byte) encrypt.Crypter
 	reCrypter     func([]byte, []byte) (encrypt.Crypter, error)
@@ -1538,6 +1585,11 @@ type Core struct {
 
 	requestedActionMtx sync.RWMutex
 	requestedActions   map[string]*asset.ActionRequiredNote
+
+	// refundBumps are unconfirmed refunds monitored for fee bumps, keyed by
+	// the refund coin ID.
+	refundBumpsMtx sync.Mutex
+	refundBumps    map[string]*refundBump
 }
 
 // New is the constructor for a new Core.
@@ -1545,6 +1597,11 @@ func New(cfg *Config) (*Core, error) {
 	if cfg.Logger == nil {
 		return nil, fmt.Errorf("Core.Config must specify a Logger")
 	}
+	if restored, err := ApplyStagedRestore(cfg.DBPath); err != nil {
+		return nil, err
+	} else if restored {
+		cfg.Logger.Infof("Restored database from backup")
+	}
 	dbOpts := bolt.Opts{
 		BackupOnShutdown: !cfg.NoAutoDBBackup,
 	}
@@ -1552,6 +1609,15 @@ func New(cfg *Config) (*Core, error) {
 	if err != nil {
 		return nil, fmt.Errorf("database initialization error: %w", err)
 	}
+	if restored, err := ApplyStagedRestore(filepath.Join(filepath.Dir(cfg.DBPath), archiveDBFilename)); err != nil {
+		return nil, err
+	} else if restored {
+		cfg.Logger.Infof("Restored archive database from backup")
+	}
+	archiveDB, err := openArchiveDB(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("archive database initialization error: %w", err)
+	}
 	if cfg.TorProxy != "" {
 		if _, _, err = net.SplitHostPort(cfg.TorProxy); err != nil {
 			return nil, err
@@ -1652,6 +1718,7 @@ func New(cfg *Config) (*Core, error) {
 		rotate:        make(chan struct{}, 1),
 		log:           cfg.Logger,
 		db:            boltDB,
+		archive:       archiveDB,
 		conns:         make(map[string]*dexConnection),
 		wallets:       make(map[uint32]*xcWallet),
 		net:           cfg.Net,
@@ -1676,6 +1743,7 @@ func New(cfg *Config) (*Core, error) {
 
 		notes:            make(chan asset.WalletNotification, 128),
 		requestedActions: make(map[string]*asset.ActionRequiredNote),
+		refundBumps:      make(map[string]*refundBump),
 	}
 
 	c.intl.Store(&locale{
@@ -1711,6 +1779,20 @@ func (c *Core) Run(ctx context.Context) {
 		defer dbWG.Done()
 		c.db.Run(ctxDB)
 	}()
+	if c.archive != nil {
+		dbWG.Add(1)
+		go func() {
+			defer dbWG.Done()
+			c.archive.Run(ctxDB)
+		}()
+	}
+	if c.cfg.RetainDays > 0 || c.cfg.RetainOrders > 0 {
+		c.wg.Add(1)
+		go func() {
+			defer c.wg.Done()
+			c.pruneOrdersLoop(ctx)
+		}()
+	}
 
 	c.wg.Add(1)
 	go func() {
@@ -1963,12 +2045,22 @@ func (c *Core) Exchanges() map[string]*Exchange {
 	for _, dc := range dcs {
 		infos[dc.acct.host] = c.exchangeInfo(dc)
 	}
+	for _, mc := range c.meshConnections() {
+		infos[mc.host] = mc.exchange()
+	}
 	return infos
 }
 
 // Exchange returns an exchange with a certain host. It returns an error if
 // no exchange exists at that host.
 func (c *Core) Exchange(host string) (*Exchange, error) {
+	if IsMeshHost(host) {
+		mc, err := c.meshConnection(host)
+		if err != nil {
+			return nil, err
+		}
+		return mc.exchange(), nil
+	}
 	dc, _, err := c.dex(host)
 	if err != nil {
 		return nil, err
@@ -4413,6 +4505,9 @@ func (c *Core) InitializeClient(pw []byte, restorationSeed *string) (string, err
 	if c.IsInitialized() {
 		return "", fmt.Errorf("already initialized, login instead")
 	}
+	if c.restorePending.Load() {
+		return "", fmt.Errorf("a backup has been restored. Restart to complete the restoration")
+	}
 
 	_, creds, mnemonicSeed, err := c.generateCredentials(pw, restorationSeed)
 	if err != nil {
@@ -4619,6 +4714,9 @@ func (c *Core) Login(pw []byte) error {
 			if err != nil {
 				return false, fmt.Errorf("GenDeepChild error: %w", err)
 			}
+			if err := c.loadAPIKeys(crypter); err != nil {
+				c.log.Errorf("Error loading API keys: %v", err)
+			}
 			c.loggedIn = true
 			return true, nil
 		}
@@ -4639,6 +4737,9 @@ func (c *Core) Login(pw []byte) error {
 		c.resolveActiveTrades(crypter)
 		c.notify(newLoginNote("Connecting to DEX servers..."))
 		c.initializeDEXConnections(crypter)
+		c.startConditionalOrders()
+		c.startManagedOrders()
+		c.startMeshes(crypter)
 
 	}
 
@@ -4919,6 +5020,14 @@ func (c *Core) Logout() error {
 	c.bondXPriv.Zero()
 	c.bondXPriv = nil
 
+	c.apiKeysMtx.Lock()
+	c.apiKeys = nil
+	c.apiKeysMtx.Unlock()
+
+	c.stopConditionalOrders()
+	c.stopManagedOrders()
+	c.stopMeshes()
+
 	c.loggedIn = false
 
 	return nil
@@ -4943,7 +5052,15 @@ func (c *Core) Orders(filter *OrderFilter) ([]*Order, error) {
 		}
 	}
 
-	ords, err := c.db.Orders(&db.OrderFilter{
+	ordDB := c.db
+	if filter.Archived {
+		if c.archive == nil {
+			return []*Order{}, nil
+		}
+		ordDB = c.archive
+	}
+
+	ords, err := ordDB.Orders(&db.OrderFilter{
 		N:        filter.N,
 		Offset:   oid,
 		Hosts:    filter.Hosts,
@@ -4957,7 +5074,7 @@ func (c *Core) Orders(filter *OrderFilter) ([]*Order, error) {
 
 	cords := make([]*Order, 0, len(ords))
 	for _, mOrd := range ords {
-		corder, err := c.coreOrderFromMetaOrder(mOrd)
+		corder, err := coreOrderFromDB(ordDB, mOrd)
 		if err != nil {
 			return nil, err
 		}
@@ -4976,10 +5093,16 @@ func (c *Core) Orders(filter *OrderFilter) ([]*Order, error) {
 // swap coin confirmations will not be set. For active orders, get the
 // *trackedTrade and use the coreOrder method.
 func (c *Core) coreOrderFromMetaOrder(mOrd *db.MetaOrder) (*Order, error) {
+	return coreOrderFromDB(c.db, mOrd)
+}
+
+// coreOrderFromDB is like coreOrderFromMetaOrder, but loads the matches from
+// the provided database.
+func coreOrderFromDB(ordDB db.DB, mOrd *db.MetaOrder) (*Order, error) {
 	corder := coreOrderFromTrade(mOrd.Order, mOrd.MetaData)
 	oid := mOrd.Order.ID()
 	excludeCancels := false // maybe don't include cancel order matches?
-	matches, err := c.db.MatchesForOrder(oid, excludeCancels)
+	matches, err := ordDB.MatchesForOrder(oid, excludeCancels)
 	if err != nil {
 		return nil, fmt.Errorf("MatchesForOrder error loading matches for %s: %w", oid, err)
 	}
@@ -5006,6 +5129,12 @@ func (c *Core) Order(oidB dex.Bytes) (*Order, error) {
 	// Must not be an active order. Get it from the database.
 	mOrd, err := c.db.Order(oid)
 	if err != nil {
+		// It may have been moved to the archive.
+		if c.archive != nil {
+			if mOrd, archiveErr := c.archive.Order(oid); archiveErr == nil {
+				return coreOrderFromDB(c.archive, mOrd)
+			}
+		}
 		return nil, fmt.Errorf("error retrieving order %s: %w", oid, err)
 	}
 
@@ -5362,6 +5491,13 @@ func (c *Core) feeSuggestion(dc *dexConnection, assetID uint32) (feeSuggestion u
 // is true, fees are subtracted from the value else fees are taken from the
 // exchange wallet.
 func (c *Core) Send(pw []byte, assetID uint32, value uint64, address string, subtract bool) (asset.Coin, error) {
+	return c.send(pw, assetID, value, address, subtract, nil)
+}
+
+// send sends or withdraws the value to the address. If coinIDs is non-empty,
+// only the specified coins may be spent, and the wallet must be an
+// asset.CoinController.
+func (c *Core) send(pw []byte, assetID uint32, value uint64, address string, subtract bool, coinIDs []dex.Bytes) (asset.Coin, error) {
 	var crypter encrypt.Crypter
 	// Empty password can be provided if wallet is already unlocked. Webserver
 	// and RPCServer should not allow empty password, but this is used for
@@ -5391,9 +5527,21 @@ func (c *Core) Send(pw []byte, assetID uint32, value uint64, address string, sub
 		return nil, err
 	}
 
+	c.withdrawMtx.Lock()
+	defer c.withdrawMtx.Unlock()
+	if err := c.checkWithdrawal(assetID, value, address); err != nil {
+		return nil, err
+	}
+
 	var coin asset.Coin
 	feeSuggestion := c.feeSuggestionAny(assetID)
-	if !subtract {
+	if len(coinIDs) > 0 {
+		coinCtl, is := wallet.Wallet.(asset.CoinController)
+		if !is {
+			return nil, fmt.Errorf("%s wallet does not support coin control", unbip(assetID))
+		}
+		coin, err = coinCtl.SendWithCoins(address, value, feeSuggestion, subtract, coinIDs)
+	} else if !subtract {
 		coin, err = wallet.Wallet.Send(address, value, feeSuggestion)
 	} else {
 		if withdrawer, isWithdrawer := wallet.Wallet.(asset.Withdrawer); isWithdrawer {
@@ -5408,6 +5556,8 @@ func (c *Core) Send(pw []byte, assetID uint32, value uint64, address string, sub
 		return nil, err
 	}
 
+	c.recordWithdrawal(assetID, value)
+
 	sentValue := wallet.Info().UnitInfo.ConventionalString(coin.Value())
 	subject, details := c.formatDetails(TopicSendSuccess, sentValue, unbip(assetID), address, coin)
 	c.notify(newSendNote(TopicSendSuccess, subject, details, db.Success))
@@ -5820,6 +5970,20 @@ func (c *Core) TxHistory(assetID uint32, n int, refID *string, past bool) ([]*as
 	return wallet.TxHistory(n, refID, past)
 }
 
+// PrivacyReport reports the transparent exposure of trades made with a wallet
+// that has a shielded pool.
+func (c *Core) PrivacyReport(assetID uint32) (*asset.PrivacyReport, error) {
+	w, err := c.connectedWallet(assetID)
+	if err != nil {
+		return nil, err
+	}
+	reporter, is := w.Wallet.(asset.PrivacyReporter)
+	if !is {
+		return nil, fmt.Errorf("%s wallet does not support privacy reports", unbip(assetID))
+	}
+	return reporter.PrivacyReport()
+}
+
 // WalletTransaction returns information about a transaction that the wallet
 // has made or one in which that wallet received funds. This function supports
 // both transaction ID and coin ID.
@@ -5834,6 +5998,14 @@ func (c *Core) WalletTransaction(assetID uint32, txID string) (*asset.WalletTran
 
 // Trade is used to place a market or limit order.
 func (c *Core) Trade(pw []byte, form *TradeForm) (*Order, error) {
+	if IsMeshHost(form.Host) {
+		mc, err := c.meshConnection(form.Host)
+		if err != nil {
+			return nil, err
+		}
+		return c.meshTrade(pw, mc, form)
+	}
+
 	req, err := c.prepareTradeRequest(pw, form)
 	if err != nil {
 		return nil, err
@@ -5851,6 +6023,16 @@ func (c *Core) Trade(pw []byte, form *TradeForm) (*Order, error) {
 // server validation. This helps handle some issues related to UI/UX where
 // server response might take a fairly long time (15 - 20s).
 func (c *Core) TradeAsync(pw []byte, form *TradeForm) (*InFlightOrder, error) {
+	// Mesh orders are placed without waiting on a server, so the order is
+	// placed synchronously and returned without a temporary ID.
+	if IsMeshHost(form.Host) {
+		corder, err := c.Trade(pw, form)
+		if err != nil {
+			return nil, err
+		}
+		return &InFlightOrder{Order: corder}, nil
+	}
+
 	req, err := c.prepareTradeRequest(pw, form)
 	if err != nil {
 		return nil, err
@@ -6249,6 +6431,10 @@ func (c *Core) prepareTradeRequest(pw []byte, form *TradeForm) (*tradeRequest, e
 			qty, assetConfigs.baseAsset.Symbol, rate, mktConf.LotSize)
 	}
 
+	if len(form.Coins) > 0 && !fromWallet.traits.IsCoinController() {
+		return nil, newError(orderParamsErr, "%s wallet does not support coin control", assetConfigs.fromAsset.Symbol)
+	}
+
 	coins, redeemScripts, fundingFees, err := fromWallet.FundOrder(&asset.Order{
 		AssetVersion:  assetConfigs.fromAsset.Version,
 		Value:         fundQty,
@@ -6257,6 +6443,7 @@ func (c *Core) prepareTradeRequest(pw []byte, form *TradeForm) (*tradeRequest, e
 		Immediate:     isImmediate,
 		FeeSuggestion: c.feeSuggestion(dc, assetConfigs.fromAsset.ID),
 		Options:       form.Options,
+		Coins:         form.Coins,
 		RedeemVersion: assetConfigs.toAsset.Version,
 		RedeemAssetID: assetConfigs.toAsset.ID,
 	})
@@ -6593,6 +6780,10 @@ func (c *Core) Cancel(oidB dex.Bytes) error {
 }
 
 func (c *Core) cancelOrder(oid order.OrderID) error {
+	if found, err := c.cancelMeshOrder(oid); found {
+		return err
+	}
+
 	for _, dc := range c.dexConnections() {
 		found, err := c.tryCancel(dc, oid)
 		if err != nil {
@@ -9531,6 +9722,8 @@ func (c *Core) tipChange(assetID uint32) {
 		}
 	}
 
+	c.bumpRefundFees(assetID)
+
 	if _, exists := c.wallet(assetID); exists {
 		// Ensure we always at least update this asset's balance regardless of
 		// trade status changes.
@@ -10174,24 +10367,7 @@ func (c *Core) AccelerateOrder(pw []byte, oidB dex.Bytes, newFeeRate uint64) (st
 	tracker.mtx.Lock()
 	defer tracker.mtx.Unlock()
 
-	swapCoinIDs, accelerationCoins, changeCoinID, requiredForRemainingSwaps, err := tracker.orderAccelerationParameters()
-	if err != nil {
-		return "", err
-	}
-
-	newChangeCoin, txID, err :=
-		tracker.wallets.fromWallet.accelerateOrder(swapCoinIDs, accelerationCoins, changeCoinID, requiredForRemainingSwaps, newFeeRate)
-	if err != nil {
-		return "", err
-	}
-	if newChangeCoin != nil {
-		tracker.metaData.ChangeCoin = order.CoinID(newChangeCoin.ID())
-		tracker.coins[newChangeCoin.ID().String()] = newChangeCoin
-	} else {
-		tracker.metaData.ChangeCoin = nil
-	}
-	tracker.metaData.AccelerationCoins = append(tracker.metaData.AccelerationCoins, tracker.metaData.ChangeCoin)
-	return txID, tracker.db.UpdateOrderMetaData(oid, tracker.metaData)
+	return tracker.accelerate(newFeeRate)
 }
 
 // AccelerationEstimate returns the amount of funds that would be needed to
@@ -356,8 +356,11 @@ type TDB struct {
 	createAccountErr         error
 	addBondErr               error
 	updateOrderErr           error
+	updatedOrder             *db.MetaOrder
+	updatedMatch             *db.MetaMatch
 	activeDEXOrders          []*db.MetaOrder
 	matchesForOID            []*db.MetaMatch
+	filteredOrders           []*db.MetaOrder
 	matchesForOIDErr         error
 	updateMatchChan          chan order.MatchStatus
 	activeMatchOIDs          []order.OrderID
@@ -386,6 +389,16 @@ type TDB struct {
 	deleteInactiveMatchesErr error
 	archivedMatches          int
 	updateAccountInfoErr     error
+	apiKeys                  map[string][]byte
+	addressBook              []*db.AddressBookEntry
+	withdrawPolicies         map[uint32]*db.WithdrawalPolicy
+	withdrawals              map[uint32][][2]uint64 // stamp, value
+	condOrders               map[string]*db.ConditionalOrder
+	managedOrders            map[string]*db.ManagedOrder
+	meshHosts                map[string]*db.MeshHost
+	prunedOrders             []*db.MetaOrder
+	pruneOlderThan           *time.Time
+	pruneKeepPerMarket       int
 }
 
 func (tdb *TDB) Run(context.Context) {}
@@ -439,6 +452,7 @@ func (tdb *TDB) UpdateAccountInfo(ai *db.AccountInfo) error {
 }
 
 func (tdb *TDB) UpdateOrder(m *db.MetaOrder) error {
+	tdb.updatedOrder = m
 	return tdb.updateOrderErr
 }
 
@@ -462,7 +476,7 @@ func (tdb *TDB) Order(oid order.OrderID) (*db.MetaOrder, error) {
 }
 
 func (tdb *TDB) Orders(*db.OrderFilter) ([]*db.MetaOrder, error) {
-	return nil, nil
+	return tdb.filteredOrders, nil
 }
 
 func (tdb *TDB) MarketOrders(dex string, base, quote uint32, n int, since uint64) ([]*db.MetaOrder, error) {
@@ -486,6 +500,7 @@ func (tdb *TDB) LinkOrder(oid, linkedID order.OrderID) error {
 }
 
 func (tdb *TDB) UpdateMatch(m *db.MetaMatch) error {
+	tdb.updatedMatch = m
 	if tdb.updateMatchChan != nil {
 		tdb.updateMatchChan <- m.Status
 	}
@@ -529,11 +544,13 @@ func (tdb *TDB) Wallet([]byte) (*db.Wallet, error) {
 	return tdb.wallet, tdb.walletErr
 }
 
-func (tdb *TDB) SaveNotification(*db.Notification) error            { return nil }
-func (tdb *TDB) BackupTo(dst string, overwrite, compact bool) error { return nil }
-func (tdb *TDB) NotificationsN(int) ([]*db.Notification, error)     { return nil, nil }
-func (tdb *TDB) SavePokes([]*db.Notification) error                 { return nil }
-func (tdb *TDB) LoadPokes() ([]*db.Notification, error)             { return nil, nil }
+func (tdb *TDB) SaveNotification(*db.Notification) error        { return nil }
+func (tdb *TDB) NotificationsN(int) ([]*db.Notification, error) { return nil, nil }
+func (tdb *TDB) SavePokes([]*db.Notification) error             { return nil }
+func (tdb *TDB) LoadPokes() ([]*db.Notification, error)         { return nil, nil }
+func (tdb *TDB) BackupTo(dst string, overwrite, compact bool) error {
+	return os.WriteFile(dst, []byte("tdb"), 0600)
+}
 
 func (tdb *TDB) SetPrimaryCredentials(creds *db.PrimaryCredentials) error {
 	if tdb.setCredsErr != nil {
@@ -551,6 +568,16 @@ func (tdb *TDB) DeleteInactiveMatches(ctx context.Context, olderThan *time.Time,
 	return tdb.archivedMatches, tdb.deleteInactiveMatchesErr
 }
 
+func (tdb *TDB) PruneOrders(ctx context.Context, olderThan *time.Time, keepPerMarket int, perOrderFn func(ord *db.MetaOrder, matches []*db.MetaMatch) error) (int, error) {
+	tdb.pruneOlderThan, tdb.pruneKeepPerMarket = olderThan, keepPerMarket
+	for _, mo := range tdb.prunedOrders {
+		if err := perOrderFn(mo, tdb.matchesForOID); err != nil {
+			return 0, err
+		}
+	}
+	return len(tdb.prunedOrders), nil
+}
+
 func (tdb *TDB) PrimaryCredentials() (*db.PrimaryCredentials, error) {
 	return tdb.creds, nil
 }
@@ -582,6 +609,118 @@ func (tdb *TDB) Backup() error {
 
 func (tdb *TDB) AckNotification(id []byte) error { return nil }
 
+func (tdb *TDB) StoreAPIKey(id string, encKey []byte) error {
+	if tdb.apiKeys == nil {
+		tdb.apiKeys = make(map[string][]byte)
+	}
+	tdb.apiKeys[id] = encKey
+	return nil
+}
+func (tdb *TDB) APIKeys() (map[string][]byte, error) {
+	return tdb.apiKeys, nil
+}
+func (tdb *TDB) DeleteAPIKey(id string) error {
+	delete(tdb.apiKeys, id)
+	return nil
+}
+func (tdb *TDB) StoreAddressBookEntry(entry *db.AddressBookEntry) error {
+	tdb.DeleteAddressBookEntry(entry.AssetID, entry.Address)
+	tdb.addressBook = append(tdb.addressBook, entry)
+	return nil
+}
+func (tdb *TDB) AddressBook() ([]*db.AddressBookEntry, error) {
+	return tdb.addressBook, nil
+}
+func (tdb *TDB) DeleteAddressBookEntry(assetID uint32, addr string) error {
+	for i, e := range tdb.addressBook {
+		if e.AssetID == assetID && e.Address == addr {
+			tdb.addressBook = append(tdb.addressBook[:i], tdb.addressBook[i+1:]...)
+			break
+		}
+	}
+	return nil
+}
+func (tdb *TDB) StoreWithdrawalPolicy(policy *db.WithdrawalPolicy) error {
+	if tdb.withdrawPolicies == nil {
+		tdb.withdrawPolicies = make(map[uint32]*db.WithdrawalPolicy)
+	}
+	tdb.withdrawPolicies[policy.AssetID] = policy
+	return nil
+}
+func (tdb *TDB) WithdrawalPolicies() ([]*db.WithdrawalPolicy, error) {
+	policies := make([]*db.WithdrawalPolicy, 0, len(tdb.withdrawPolicies))
+	for _, p := range tdb.withdrawPolicies {
+		policies = append(policies, p)
+	}
+	return policies, nil
+}
+func (tdb *TDB) RecordWithdrawal(assetID uint32, stamp, value uint64) error {
+	if tdb.withdrawals == nil {
+		tdb.withdrawals = make(map[uint32][][2]uint64)
+	}
+	tdb.withdrawals[assetID] = append(tdb.withdrawals[assetID], [2]uint64{stamp, value})
+	return nil
+}
+func (tdb *TDB) StoreConditionalOrder(ord *db.ConditionalOrder) error {
+	if tdb.condOrders == nil {
+		tdb.condOrders = make(map[string]*db.ConditionalOrder)
+	}
+	ordCopy := *ord
+	tdb.condOrders[ord.ID] = &ordCopy
+	return nil
+}
+func (tdb *TDB) ConditionalOrders() ([]*db.ConditionalOrder, error) {
+	ords := make([]*db.ConditionalOrder, 0, len(tdb.condOrders))
+	for _, ord := range tdb.condOrders {
+		ordCopy := *ord
+		ords = append(ords, &ordCopy)
+	}
+	return ords, nil
+}
+func (tdb *TDB) DeleteConditionalOrder(id string) error {
+	delete(tdb.condOrders, id)
+	return nil
+}
+func (tdb *TDB) StoreManagedOrder(ord *db.ManagedOrder) error {
+	if tdb.managedOrders == nil {
+		tdb.managedOrders = make(map[string]*db.ManagedOrder)
+	}
+	ordCopy := *ord
+	tdb.managedOrders[ord.ID] = &ordCopy
+	return nil
+}
+func (tdb *TDB) ManagedOrders() ([]*db.ManagedOrder, error) {
+	ords := make([]*db.ManagedOrder, 0, len(tdb.managedOrders))
+	for _, ord := range tdb.managedOrders {
+		ordCopy := *ord
+		ords = append(ords, &ordCopy)
+	}
+	return ords, nil
+}
+func (tdb *TDB) StoreMeshHost(h *db.MeshHost) error {
+	if tdb.meshHosts == nil {
+		tdb.meshHosts = make(map[string]*db.MeshHost)
+	}
+	hCopy := *h
+	tdb.meshHosts[h.Addr] = &hCopy
+	return nil
+}
+func (tdb *TDB) MeshHosts() ([]*db.MeshHost, error) {
+	hosts := make([]*db.MeshHost, 0, len(tdb.meshHosts))
+	for _, h := range tdb.meshHosts {
+		hCopy := *h
+		hosts = append(hosts, &hCopy)
+	}
+	return hosts, nil
+}
+func (tdb *TDB) WithdrawnSince(assetID uint32, since uint64) (total uint64, _ error) {
+	for _, w := range tdb.withdrawals[assetID] {
+		if w[0] >= since {
+			total += w[1]
+		}
+	}
+	return total, nil
+}
 func (tdb *TDB) SetLanguage(lang string) error {
 	return nil
 }
@@ -1203,6 +1342,43 @@ func (w *TFeeRater) FeeRate() uint64 {
 	return w.feeRate
 }
 
+type TFeeBumper struct {
+	*TXCWallet
+	netRate     uint64
+	txFeeRate   uint64
+	txConfirmed bool
+	bumpCoinID  dex.Bytes
+	bumpErr     error
+	bumps       []*tFeeBump
+}
+
+type tFeeBump struct {
+	coinID, contract dex.Bytes
+	feeRate          uint64
+	replace          bool
+}
+
+var _ asset.FeeBumper = (*TFeeBumper)(nil)
+
+func (w *TFeeBumper) FeeRate() uint64 {
+	return w.netRate
+}
+
+func (w *TFeeBumper) TxFeeRate(coinID dex.Bytes) (uint64, bool, error) {
+	return w.txFeeRate, w.txConfirmed, nil
+}
+
+func (w *TFeeBumper) BumpFee(coinID, contract dex.Bytes, newFeeRate uint64, replace bool) (dex.Bytes, error) {
+	if w.bumpErr != nil {
+		return nil, w.bumpErr
+	}
+	w.bumps = append(w.bumps, &tFeeBump{coinID, contract, newFeeRate, replace})
+	if w.bumpCoinID != nil {
+		return w.bumpCoinID, nil
+	}
+	return coinID, nil
+}
+
 type TLiveReconfigurer struct {
 	*TXCWallet
 	restart     bool
@@ -8263,6 +8439,121 @@ func TestAccelerateOrder(t *testing.T) {
 	}
 }
 
+func TestAutoFeeBump(t *testing.T) {
+	now := time.Now()
+	for _, tt := range []struct {
+		name                         string
+		feeRate, netRate, maxFeeRate uint64
+		start, deadline              time.Time
+		want                         uint64
+	}{
+		{"too new", 5, 20, 0, now.Add(-time.Minute), time.Time{}, 0},
+		{"at risk", 5, 20, 0, now.Add(-time.Hour), time.Time{}, 20},
+		{"paying enough", 20, 20, 0, now.Add(-time.Hour), time.Time{}, 0},
+		{"urgent", 5, 20, 0, now.Add(-time.Hour), now.Add(time.Minute), 40},
+		{"not urgent", 5, 20, 0, now.Add(-time.Hour), now.Add(2 * time.Hour), 20},
+		{"capped", 5, 20, 30, now.Add(-time.Hour), now.Add(time.Minute), 30},
+		{"no network rate", 5, 0, 0, now.Add(-time.Hour), time.Time{}, 0},
+	} {
+		if got := feeBumpTarget(tt.feeRate, tt.netRate, tt.maxFeeRate, tt.start, tt.deadline); got != tt.want {
+			t.Fatalf("%s: wanted target %d, got %d", tt.name, tt.want, got)
+		}
+	}
+
+	rig := newTestRig()
+	defer rig.shutdown()
+	tCore := rig.core
+	dcrWallet, tDcrWallet := newTWallet(tUTXOAssetA.ID)
+	bumper := &TFeeBumper{TXCWallet: tDcrWallet, netRate: 20, txFeeRate: 5}
+	dcrWallet.Wallet = bumper
+	dcrWallet.traits = asset.DetermineWalletTraits(bumper)
+	tCore.wallets[tUTXOAssetA.ID] = dcrWallet
+	btcWallet, _ := newTWallet(tUTXOAssetB.ID)
+	tCore.wallets[tUTXOAssetB.ID] = btcWallet
+
+	// A buy order redeems DCR.
+	_, dbOrder, preImg, _ := makeLimitOrder(rig.dc, false, 0, 0)
+	walletSet, _, _, err := tCore.walletSet(rig.dc, tUTXOAssetA.ID, tUTXOAssetB.ID, false)
+	if err != nil {
+		t.Fatalf("walletSet error: %v", err)
+	}
+	tracker := newTrackedTrade(dbOrder, preImg, rig.dc, tCore.lockTimeTaker, tCore.lockTimeMaker,
+		rig.db, rig.queue, walletSet, nil, tCore.notify, tCore.formatDetails)
+	tracker.metaData.RedeemMaxFeeRate = 30
+	rig.dc.trades[tracker.ID()] = tracker
+
+	// The redemption has been unconfirmed for 90 minutes, and the
+	// counterparty can refund in 30 minutes.
+	redeemCoin := encode.RandomBytes(36)
+	mid := ordertest.RandomMatchID()
+	match := &matchTracker{
+		MetaMatch: db.MetaMatch{
+			UserMatch: &order.UserMatch{MatchID: mid, Side: order.Maker, Status: order.MakerRedeemed},
+			MetaData: &db.MatchMetaData{Proof: db.MatchProof{
+				MakerRedeem: redeemCoin,
+				Auth: db.MatchAuth{
+					MatchStamp:  uint64(now.Add(-2 * time.Hour).UnixMilli()),
+					RedeemStamp: uint64(now.Add(-90 * time.Minute).UnixMilli()),
+				},
+			}},
+		},
+		counterSwap: &asset.AuditInfo{Expiration: now.Add(30 * time.Minute)},
+	}
+	tracker.matches[mid] = match
+
+	redeems := tracker.pendingRedeemFeeBumps()
+	if len(redeems) != 1 {
+		t.Fatalf("expected 1 redemption to bump, got %d", len(redeems))
+	}
+	tCore.bumpRedeemFees(tracker, redeems)
+	if len(bumper.bumps) != 1 {
+		t.Fatalf("expected 1 bump, got %d", len(bumper.bumps))
+	}
+	if b := bumper.bumps[0]; !b.coinID.Equal(redeemCoin) || b.replace || b.feeRate != 30 {
+		t.Fatalf("wrong redemption bump %+v", b)
+	}
+	// Not again until feeBumpInterval has passed.
+	if redeems = tracker.pendingRedeemFeeBumps(); len(redeems) != 0 {
+		t.Fatalf("redemption bumped again too soon")
+	}
+
+	// A refund is replaced.
+	bumper.bumps = nil
+	refundCoin, newRefundCoin := encode.RandomBytes(36), encode.RandomBytes(36)
+	contract := encode.RandomBytes(50)
+	match.MetaData.Proof.RefundCoin = refundCoin
+	tCore.watchRefund(dcrWallet, refundCoin, contract, 100)
+	// Too new.
+	tCore.bumpRefundFees(tUTXOAssetA.ID)
+	if len(bumper.bumps) != 0 {
+		t.Fatalf("new refund bumped")
+	}
+	tCore.refundBumps[dex.Bytes(refundCoin).String()].start = now.Add(-time.Hour)
+	bumper.bumpCoinID = newRefundCoin
+	tCore.bumpRefundFees(tUTXOAssetA.ID)
+	if len(bumper.bumps) != 1 {
+		t.Fatalf("expected 1 refund bump, got %d", len(bumper.bumps))
+	}
+	if b := bumper.bumps[0]; !b.coinID.Equal(refundCoin) || !b.contract.Equal(contract) || !b.replace || b.feeRate != 20 {
+		t.Fatalf("wrong refund bump %+v", b)
+	}
+	if !bytes.Equal(match.MetaData.Proof.RefundCoin, newRefundCoin) {
+		t.Fatalf("refund coin not updated")
+	}
+	rb := tCore.refundBumps[dex.Bytes(newRefundCoin).String()]
+	if rb == nil || len(tCore.refundBumps) != 1 {
+		t.Fatalf("replacement refund not tracked")
+	}
+
+	// Confirmed refunds are forgotten.
+	rb.lastBump = time.Time{}
+	bumper.txConfirmed = true
+	tCore.bumpRefundFees(tUTXOAssetA.ID)
+	if len(tCore.refundBumps) != 0 {
+		t.Fatalf("confirmed refund still tracked")
+	}
+}
+
 func TestMatchStatusResolution(t *testing.T) {
 	rig := newTestRig()
 	defer rig.shutdown()
@@ -9401,6 +9692,33 @@ func TestSuspectTrades(t *testing.T) {
 		t.Fatalf("suspect swap matches not run or not run separately. expected 2 new calls to Swap, got %d", tDcrWallet.swapCounter-1)
 	}
 
+	// A swap awaiting an external signature is not suspect, and the same group
+	// is retried on the next tick.
+	setSwaps()
+	tDcrWallet.swapErr = asset.ErrSignaturePending
+	_, err = tCore.tick(tracker)
+	if err != nil {
+		t.Fatalf("tick error while awaiting signature: %v", err)
+	}
+	tracker.mtx.Lock()
+	for i, m := range []*matchTracker{swappableMatch1, swappableMatch2} {
+		if m.suspectSwap || m.tickGovernor != nil || !m.swapSigPending || m.swapErr != nil {
+			t.Fatalf("swappable match %d wrong state while awaiting signature", i+1)
+		}
+	}
+	tracker.mtx.Unlock()
+	tDcrWallet.swapErr = nil
+	_, err = tCore.tick(tracker)
+	if err != nil {
+		t.Fatalf("tick error after signature: %v", err)
+	}
+	if tDcrWallet.swapCounter != 2 || len(tDcrWallet.lastSwaps[len(tDcrWallet.lastSwaps)-1].Contracts) != 2 {
+		t.Fatalf("signed swap group not retried")
+	}
+	if swappableMatch1.swapSigPending || swappableMatch2.swapSigPending {
+		t.Fatalf("swapSigPending not cleared")
+	}
+
 	var redeemableMatch1, redeemableMatch2 *matchTracker
 	setRedeems := func() {
 		redeemableMatch1 = newMatch(order.Maker, order.TakerSwapCast)
@@ -52,6 +52,7 @@ const (
 	bondTimeErr
 	bondAssetErr
 	bondPostErr // TODO
+	withdrawalPolicyErr
 )
 
 // Error is an error code and a wrapped error.
@@ -0,0 +1,336 @@
+// This code is available on the terms of the project LICENSE.md file,
+// also available online at https://blueoakcouncil.org/license/1.0.0.
+
+package core
+
+import (
+	"time"
+
+	"decred.org/dcrdex/dex"
+	"decred.org/dcrdex/dex/order"
+)
+
+const (
+	// feeBumpInterval is how long a swap, redeem or refund transaction must
+	// remain unconfirmed before its fee is automatically bumped, and the
+	// minimum time between bump attempts for the same transaction.
+	feeBumpInterval = 30 * time.Minute
+	// feeBumpUrgentMultiplier is applied to the network fee rate once more than
+	// half of the time between a transaction's broadcast and the lock time
+	// that it is racing has passed.
+	feeBumpUrgentMultiplier = 2
+	// swapFeeBumpKey is the feeBumpStamps key for the order's swap chain,
+	// which is accelerated as a whole.
+	swapFeeBumpKey = "swap"
+)
+
+// feeBumpTarget returns the fee rate that an unconfirmed transaction broadcast
+// at start and currently paying feeRate should be bumped to, or 0 if it is not
+// at risk. A transaction is at risk if it has been unconfirmed for at least
+// feeBumpInterval and pays less than the network rate. If more than half of
+// the time between start and deadline has passed, the network rate is
+// multiplied by feeBumpUrgentMultiplier. The target is capped at maxFeeRate.
+func feeBumpTarget(feeRate, netRate, maxFeeRate uint64, start, deadline time.Time) uint64 {
+	age := time.Since(start)
+	if age < feeBumpInterval || netRate == 0 {
+		return 0
+	}
+	target := netRate
+	if !deadline.IsZero() && age > deadline.Sub(start)/2 {
+		target *= feeBumpUrgentMultiplier
+	}
+	if maxFeeRate > 0 && target > maxFeeRate {
+		target = maxFeeRate
+	}
+	if target <= feeRate {
+		return 0
+	}
+	return target
+}
+
+// stampMillis converts a server stamp to a time, falling back to fallback if
+// the stamp is not set.
+func stampMillis(stamp uint64, fallback time.Time) time.Time {
+	if stamp == 0 {
+		return fallback
+	}
+	return time.UnixMilli(int64(stamp))
+}
+
+// feeBumpDue checks whether an attempt to bump the fee of the transaction
+// identified by key is allowed yet.
+// MUST be called with the trackedTrade mutex held.
+func (t *trackedTrade) feeBumpDue(key string) bool {
+	return time.Since(t.feeBumpStamps[key]) >= feeBumpInterval
+}
+
+// stampFeeBump records a fee bump attempt for the transaction identified by
+// key.
+// MUST be called with the trackedTrade mutex write-locked.
+func (t *trackedTrade) stampFeeBump(key string) {
+	if t.feeBumpStamps == nil {
+		t.feeBumpStamps = make(map[string]time.Time)
+	}
+	t.feeBumpStamps[key] = time.Now()
+}
+
+// pendingSwapFeeBump checks whether the order has unconfirmed swaps that may
+// need a fee bump. The start time of the oldest swap, and the earliest lock
+// time of the swaps are returned.
+// MUST be called with the trackedTrade mutex held.
+func (t *trackedTrade) pendingSwapFeeBump() (pending bool, start, deadline time.Time) {
+	if !t.wallets.fromWallet.traits.IsAccelerator() || t.metaData.ChangeCoin == nil ||
+		!t.feeBumpDue(swapFeeBumpKey) {
+		return false, time.Time{}, time.Time{}
+	}
+	for _, match := range t.matches {
+		if match.MetaData.Proof.IsRevoked() {
+			continue
+		}
+		lockTime := t.lockTimeTaker
+		if match.Side == order.Maker {
+			if match.Status != order.MakerSwapCast {
+				continue
+			}
+			lockTime = t.lockTimeMaker
+		} else if match.Status != order.TakerSwapCast {
+			continue
+		}
+		swapStart := stampMillis(match.MetaData.Proof.Auth.InitStamp, match.matchTime())
+		if !pending || swapStart.Before(start) {
+			start = swapStart
+		}
+		if swapDeadline := match.matchTime().Add(lockTime); !pending || swapDeadline.Before(deadline) {
+			deadline = swapDeadline
+		}
+		pending = true
+	}
+	if pending && time.Since(start) < feeBumpInterval {
+		return false, time.Time{}, time.Time{}
+	}
+	return pending, start, deadline
+}
+
+// pendingRedeemFeeBumps returns matches with unconfirmed redemptions that may
+// need a fee bump. Only one match is returned for a batched redemption.
+// MUST be called with the trackedTrade mutex held.
+func (t *trackedTrade) pendingRedeemFeeBumps() []*matchTracker {
+	if !t.wallets.toWallet.traits.IsFeeBumper() {
+		return nil
+	}
+	var matches []*matchTracker
+	redeemCoins := make(map[string]bool)
+	for _, match := range t.matches {
+		if !shouldConfirmRedemption(match) || match.redemptionConfs > 0 || match.counterSwap == nil {
+			continue
+		}
+		redeemCoin := t.redeemCoinID(match).String()
+		if redeemCoins[redeemCoin] || !t.feeBumpDue(redeemCoin) {
+			continue
+		}
+		redeemCoins[redeemCoin] = true
+		start := stampMillis(match.MetaData.Proof.Auth.RedeemStamp, match.matchTime())
+		if time.Since(start) < feeBumpInterval {
+			continue
+		}
+		matches = append(matches, match)
+	}
+	return matches
+}
+
+// redeemCoinID is the ID of our redemption coin for the match.
+func (t *trackedTrade) redeemCoinID(match *matchTracker) dex.Bytes {
+	if match.Side == order.Maker {
+		return dex.Bytes(match.MetaData.Proof.MakerRedeem)
+	}
+	return dex.Bytes(match.MetaData.Proof.TakerRedeem)
+}
+
+// bumpSwapFees accelerates the order's swap chain if it has been unconfirmed
+// for too long and pays less than the network rate.
+// MUST be called with the trackedTrade mutex write-locked.
+func (c *Core) bumpSwapFees(t *trackedTrade, start, deadline time.Time) {
+	t.stampFeeBump(swapFeeBumpKey)
+	wallet := t.wallets.fromWallet
+	swapCoins, accelerationCoins, changeCoin, requiredForRemainingSwaps, err := t.orderAccelerationParameters()
+	if err != nil {
+		c.log.Debugf("Not bumping swap fees for order %s: %v", t.ID(), err)
+		return
+	}
+	netRate := c.feeSuggestionAny(wallet.AssetID, t.dc)
+	feeRate, suggestedRange, _, err := wallet.preAccelerate(swapCoins, accelerationCoins, changeCoin, requiredForRemainingSwaps, netRate)
+	if err != nil {
+		c.log.Debugf("Not bumping swap fees for order %s: %v", t.ID(), err)
+		return
+	}
+	maxFeeRate := t.metaData.MaxFeeRate
+	if maxRate := uint64(suggestedRange.End.X); maxRate > 0 && (maxFeeRate == 0 || maxRate < maxFeeRate) {
+		maxFeeRate = maxRate // can't afford more
+	}
+	newFeeRate := feeBumpTarget(feeRate, netRate, maxFeeRate, start, deadline)
+	if newFeeRate == 0 {
+		return
+	}
+	txID, err := t.accelerate(newFeeRate)
+	if err != nil {
+		c.log.Errorf("Error bumping %s swap fees for order %s from %d to %d: %v",
+			wallet.Symbol, t.ID(), feeRate, newFeeRate, err)
+		return
+	}
+	c.log.Infof("Bumped %s swap fees for order %s from %d to %d with transaction %s",
+		wallet.Symbol, t.ID(), feeRate, newFeeRate, txID)
+}
+
+// bumpRedeemFees bumps the fees of the redemptions for the matches if they pay
+// less than the network rate. The redemption coin IDs are known to the server
+// and counterparty, so the redemptions are never replaced.
+// MUST be called with the trackedTrade mutex write-locked.
+func (c *Core) bumpRedeemFees(t *trackedTrade, matches []*matchTracker) {
+	wallet := t.wallets.toWallet
+	netRate := c.feeSuggestionAny(wallet.AssetID, t.dc)
+	for _, match := range matches {
+		redeemCoin := t.redeemCoinID(match)
+		t.stampFeeBump(redeemCoin.String())
+		feeRate, confirmed, err := wallet.txFeeRate(redeemCoin)
+		if err != nil {
+			c.log.Errorf("Error getting fee rate of %s redemption %s for match %s: %v",
+				wallet.Symbol, coinIDString(wallet.AssetID, redeemCoin), match, err)
+			continue
+		}
+		if confirmed {
+			continue
+		}
+		start := stampMillis(match.MetaData.Proof.Auth.RedeemStamp, match.matchTime())
+		newFeeRate := feeBumpTarget(feeRate, netRate, t.metaData.RedeemMaxFeeRate, start, match.counterSwap.Expiration)
+		if newFeeRate == 0 {
+			continue
+		}
+		if _, err := wallet.bumpFee(redeemCoin, nil, newFeeRate, false); err != nil {
+			c.log.Errorf("Error bumping fee of %s redemption %s for match %s from %d to %d: %v",
+				wallet.Symbol, coinIDString(wallet.AssetID, redeemCoin), match, feeRate, newFeeRate, err)
+			continue
+		}
+		c.log.Infof("Bumped fee of %s redemption %s for match %s from %d to %d",
+			wallet.Symbol, coinIDString(wallet.AssetID, redeemCoin), match, feeRate, newFeeRate)
+	}
+}
+
+// refundBump is an unconfirmed refund that is monitored for fee bumps. Refunded
+// matches are no longer active, so refunds are tracked by Core rather than by
+// the trackedTrade, and are not persisted.
+type refundBump struct {
+	assetID    uint32
+	coinID     dex.Bytes
+	contract   dex.Bytes
+	maxFeeRate uint64
+	start      time.Time
+	lastBump   time.Time
+}
+
+// watchRefund starts monitoring a refund for fee bumps if the wallet is a
+// FeeBumper.
+func (c *Core) watchRefund(wallet *xcWallet, coinID, contract dex.Bytes, maxFeeRate uint64) {
+	if !wallet.traits.IsFeeBumper() {
+		return
+	}
+	c.refundBumpsMtx.Lock()
+	defer c.refundBumpsMtx.Unlock()
+	if c.refundBumps == nil {
+		c.refundBumps = make(map[string]*refundBump)
+	}
+	c.refundBumps[coinID.String()] = &refundBump{
+		assetID:    wallet.AssetID,
+		coinID:     coinID,
+		contract:   contract,
+		maxFeeRate: maxFeeRate,
+		start:      time.Now(),
+	}
+}
+
+// bumpRefundFees bumps the fees of the asset's unconfirmed refunds that pay
+// less than the network rate, replacing them if the wallet can. Refunds are
+// not racing a lock time, but a taker's refund races the maker's redemption.
+func (c *Core) bumpRefundFees(assetID uint32) {
+	// Collect the refunds that are due without holding the lock during wallet
+	// requests. refundMatches calls watchRefund with the trackedTrade mutex
+	// locked, so the trackedTrade mutex must not be locked while holding
+	// refundBumpsMtx.
+	var due []*refundBump
+	c.refundBumpsMtx.Lock()
+	for _, rb := range c.refundBumps {
+		if rb.assetID == assetID && time.Since(rb.lastBump) >= feeBumpInterval &&
+			time.Since(rb.start) >= feeBumpInterval {
+			rb.lastBump = time.Now()
+			due = append(due, rb)
+		}
+	}
+	c.refundBumpsMtx.Unlock()
+	if len(due) == 0 {
+		return
+	}
+	wallet, found := c.wallet(assetID)
+	if !found {
+		return
+	}
+	netRate := c.feeSuggestionAny(assetID)
+	for _, rb := range due {
+		feeRate, confirmed, err := wallet.txFeeRate(rb.coinID)
+		if err != nil {
+			c.log.Errorf("Error getting fee rate of %s refund %s: %v",
+				wallet.Symbol, coinIDString(assetID, rb.coinID), err)
+			continue
+		}
+		if confirmed {
+			c.refundBumpsMtx.Lock()
+			delete(c.refundBumps, rb.coinID.String())
+			c.refundBumpsMtx.Unlock()
+			continue
+		}
+		newFeeRate := feeBumpTarget(feeRate, netRate, rb.maxFeeRate, rb.start, time.Time{})
+		if newFeeRate == 0 {
+			continue
+		}
+		newCoinID, err := wallet.bumpFee(rb.coinID, rb.contract, newFeeRate, true)
+		if err != nil {
+			c.log.Errorf("Error bumping fee of %s refund %s from %d to %d: %v",
+				wallet.Symbol, coinIDString(assetID, rb.coinID), feeRate, newFeeRate, err)
+			continue
+		}
+		c.log.Infof("Bumped fee of %s refund %s from %d to %d (%s)", wallet.Symbol,
+			coinIDString(assetID, rb.coinID), feeRate, newFeeRate, coinIDString(assetID, newCoinID))
+		if rb.coinID.Equal(newCoinID) {
+			continue
+		}
+		// The refund was replaced.
+		c.updateRefundCoin(rb.coinID, newCoinID)
+		c.refundBumpsMtx.Lock()
+		delete(c.refundBumps, rb.coinID.String())
+		rb.coinID = newCoinID
+		c.refundBumps[newCoinID.String()] = rb
+		c.refundBumpsMtx.Unlock()
+	}
+}
+
+// updateRefundCoin updates the RefundCoin of the match that was refunded by a
+// replaced refund transaction.
+func (c *Core) updateRefundCoin(oldCoinID, newCoinID dex.Bytes) {
+	for _, dc := range c.dexConnections() {
+		for _, t := range dc.trackedTrades() {
+			t.mtx.Lock()
+			for _, match := range t.matches {
+				if !oldCoinID.Equal(match.MetaData.Proof.RefundCoin) {
+					continue
+				}
+				match.MetaData.Proof.RefundCoin = order.CoinID(newCoinID)
+				if err := t.db.UpdateMatch(&match.MetaMatch); err != nil {
+					c.log.Errorf("Error storing replaced refund coin for match %s: %v", match, err)
+				}
+				t.mtx.Unlock()
+				return
+			}
+			t.mtx.Unlock()
+		}
+	}
+	// The trade may have been retired already.
+	c.log.Debugf("No active match found for replaced refund %s", oldCoinID)
+}
@@ -73,6 +73,22 @@ var originLocale = map[Topic]*translation{
 		subject:  intl.Translation{T: "Send successful"},
 		template: intl.Translation{Version: 1, T: "Sending %s %s to %s has completed successfully. Tx ID = %s", Notes: "args: [value string, ticker, destination address, coin ID]"},
 	},
+	TopicConditionalOrderPlaced: {
+		subject:  intl.Translation{T: "Conditional order placed"},
+		template: intl.Translation{T: "The %s conditional order %s was triggered and placed order %s.", Notes: "args: [conditional order type, conditional order ID, order token]"},
+	},
+	TopicConditionalOrderFailed: {
+		subject:  intl.Translation{T: "Conditional order failed"},
+		template: intl.Translation{T: "The %s conditional order %s was triggered, but the order could not be placed: %v", Notes: "args: [conditional order type, conditional order ID, error]"},
+	},
+	TopicManagedOrderComplete: {
+		subject:  intl.Translation{T: "Managed order complete"},
+		template: intl.Translation{T: "The %s order %s is complete. %s of %s filled.", Notes: "args: [managed order type, managed order ID, filled quantity, total quantity]"},
+	},
+	TopicManagedOrderFailed: {
+		subject:  intl.Translation{T: "Managed order failed"},
+		template: intl.Translation{T: "The %s order %s stopped because a child order could not be placed: %v", Notes: "args: [managed order type, managed order ID, error]"},
+	},
 	TopicAsyncOrderFailure: {
 		subject:  intl.Translation{T: "In-Flight Order Error"},
 		template: intl.Translation{T: "In-Flight order with ID %v failed: %v", Notes: "args: order ID, error]"},
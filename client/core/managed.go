@@ -0,0 +1,534 @@
+// This code is available on the terms of the project LICENSE.md file,
+// also available online at https://blueoakcouncil.org/license/1.0.0.
+
+package core
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"time"
+
+	"decred.org/dcrdex/client/asset"
+	"decred.org/dcrdex/client/db"
+	"decred.org/dcrdex/dex"
+	"decred.org/dcrdex/dex/encode"
+	"decred.org/dcrdex/dex/order"
+)
+
+// Managed order types.
+const (
+	// ManagedTWAP splits the order into equal slices that are placed at
+	// regular intervals over a duration. A slice that is still booked when
+	// the next slice is due is canceled, and the unfilled quantity is carried
+	// into the next slice. The last slice stays booked until it is filled or
+	// canceled.
+	ManagedTWAP = "twap"
+	// ManagedIceberg keeps a single child order of at most the clip size on
+	// the book, and places the next child when the last one is filled.
+	ManagedIceberg = "iceberg"
+)
+
+// Managed order statuses.
+const (
+	ManagedStatusActive   = "active"
+	ManagedStatusComplete = "complete"
+	ManagedStatusCanceled = "canceled"
+	ManagedStatusFailed   = "failed"
+)
+
+// managedOrderTick is how often the active child order of a managed order is
+// checked.
+var managedOrderTick = 5 * time.Second
+
+// ManagedOrderForm is the information necessary to create a managed order.
+// The parent order is a standing limit order for Qty at Rate, which must be a
+// multiple of the market's lot size.
+type ManagedOrderForm struct {
+	Host  string `json:"host"`
+	Base  uint32 `json:"base"`
+	Quote uint32 `json:"quote"`
+	Type  string `json:"type"`
+	Sell  bool   `json:"sell"`
+	Qty   uint64 `json:"qty"`
+	Rate  uint64 `json:"rate"`
+	// Slices is the number of child orders for a TWAP order. Each slice must
+	// be at least one lot.
+	Slices uint32 `json:"slices,omitempty"`
+	// Duration is the time over which the TWAP slices are placed, in
+	// milliseconds.
+	Duration uint64 `json:"duration,omitempty"`
+	// Clip is the visible quantity of an iceberg order. Must be a multiple of
+	// the lot size.
+	Clip uint64 `json:"clip,omitempty"`
+}
+
+// ManagedOrder is a TWAP or iceberg order, which is executed by the client as
+// a series of child limit orders.
+type ManagedOrder struct {
+	ID string `json:"id"`
+	ManagedOrderForm
+	// Stamp is the time the order was created, in milliseconds.
+	Stamp  uint64 `json:"stamp"`
+	Status string `json:"status"`
+	// Filled is the quantity filled by all child orders, including the
+	// active child.
+	Filled uint64 `json:"filled"`
+	// Children are the IDs of the child orders, oldest first.
+	Children []dex.Bytes `json:"children"`
+	// ActiveChild is the ID of the child order that is not yet complete.
+	ActiveChild dex.Bytes `json:"activeChild,omitempty"`
+	Error       string    `json:"error,omitempty"`
+}
+
+// managedOrder is an active managed order.
+type managedOrder struct {
+	ord    *db.ManagedOrder
+	cancel context.CancelFunc
+}
+
+// managedOrderFromDB converts the *db.ManagedOrder. activeFilled is the
+// quantity filled by the active child order.
+func managedOrderFromDB(o *db.ManagedOrder, activeFilled uint64) *ManagedOrder {
+	children := make([]dex.Bytes, 0, len(o.Children))
+	for _, oid := range o.Children {
+		children = append(children, oid.Bytes())
+	}
+	ord := &ManagedOrder{
+		ID: o.ID,
+		ManagedOrderForm: ManagedOrderForm{
+			Host:     o.Host,
+			Base:     o.Base,
+			Quote:    o.Quote,
+			Type:     o.Type,
+			Sell:     o.Sell,
+			Qty:      o.Qty,
+			Rate:     o.Rate,
+			Slices:   o.Slices,
+			Duration: o.Duration,
+			Clip:     o.Clip,
+		},
+		Stamp:    o.Stamp,
+		Status:   o.Status,
+		Filled:   o.Filled + activeFilled,
+		Children: children,
+		Error:    o.Error,
+	}
+	if o.Active && len(children) > 0 {
+		ord.ActiveChild = children[len(children)-1]
+	}
+	return ord
+}
+
+// managedSliceDue is the time that the next TWAP slice is due, in
+// milliseconds.
+func managedSliceDue(o *db.ManagedOrder) uint64 {
+	return o.Stamp + uint64(o.Placed)*o.Duration/uint64(o.Slices)
+}
+
+// managedNextChild determines the quantity of the next child order of a
+// managed order with no active child. For TWAP orders, slice is the number of
+// slices placed once the child is placed. Slices that were missed, e.g. while
+// logged out, are combined. A zero qty without done means there is nothing to
+// place yet, but slice may still have advanced.
+func managedNextChild(o *db.ManagedOrder, lotSize, now uint64) (qty uint64, slice uint32, done bool) {
+	remaining := (o.Qty - o.Filled) / lotSize * lotSize
+	switch o.Type {
+	case ManagedIceberg:
+		if remaining == 0 {
+			return 0, 0, true
+		}
+		return min(o.Clip/lotSize*lotSize, remaining), 0, false
+	case ManagedTWAP:
+		if o.Placed >= o.Slices || remaining == 0 {
+			return 0, o.Placed, true
+		}
+		if now < managedSliceDue(o) {
+			return 0, o.Placed, false
+		}
+		interval := o.Duration / uint64(o.Slices)
+		slice = o.Slices
+		if interval > 0 {
+			slice = uint32(min(uint64(o.Slices), (now-o.Stamp)/interval+1))
+		}
+		target := o.Qty / lotSize * uint64(slice) / uint64(o.Slices) * lotSize
+		if target <= o.Filled {
+			return 0, slice, false
+		}
+		return min(target-o.Filled, remaining), slice, false
+	}
+	return 0, 0, true
+}
+
+// CreateManagedOrder creates a TWAP or iceberg order. The child orders are
+// placed while the app is logged in, and the managed order persists across
+// restarts. The child orders are placed without the app password, so the
+// wallets must remain unlocked.
+func (c *Core) CreateManagedOrder(pw []byte, form *ManagedOrderForm) (*ManagedOrder, error) {
+	crypter, err := c.encryptionKey(pw)
+	if err != nil {
+		return nil, codedError(passwordErr, err)
+	}
+	crypter.Close()
+
+	if form.Qty == 0 {
+		return nil, newError(orderParamsErr, "zero quantity")
+	}
+	if form.Rate == 0 {
+		return nil, newError(orderParamsErr, "zero rate")
+	}
+	dc, err := c.registeredDEX(form.Host)
+	if err != nil {
+		return nil, err
+	}
+	mktID := marketName(form.Base, form.Quote)
+	mkt := dc.marketConfig(mktID)
+	if mkt == nil {
+		return nil, newError(marketErr, "unknown market %q", mktID)
+	}
+	if form.Qty%mkt.LotSize != 0 {
+		return nil, newError(orderParamsErr, "quantity %d is not a multiple of the lot size %d", form.Qty, mkt.LotSize)
+	}
+	ord := &db.ManagedOrder{
+		ID:     hex.EncodeToString(encode.RandomBytes(8)),
+		Host:   form.Host,
+		Base:   form.Base,
+		Quote:  form.Quote,
+		Type:   form.Type,
+		Sell:   form.Sell,
+		Qty:    form.Qty,
+		Rate:   form.Rate,
+		Stamp:  uint64(time.Now().UnixMilli()),
+		Status: ManagedStatusActive,
+	}
+	switch form.Type {
+	case ManagedTWAP:
+		if form.Slices == 0 || uint64(form.Slices) > form.Qty/mkt.LotSize {
+			return nil, newError(orderParamsErr, "TWAP orders need between 1 and %d slices", form.Qty/mkt.LotSize)
+		}
+		if form.Duration == 0 {
+			return nil, newError(orderParamsErr, "zero duration")
+		}
+		ord.Slices, ord.Duration = form.Slices, form.Duration
+	case ManagedIceberg:
+		if form.Clip == 0 || form.Clip%mkt.LotSize != 0 || form.Clip > form.Qty {
+			return nil, newError(orderParamsErr, "iceberg clip must be a multiple of the lot size, and no more than the quantity")
+		}
+		ord.Clip = form.Clip
+	default:
+		return nil, newError(orderParamsErr, "unknown managed order type %q", form.Type)
+	}
+	if _, found := c.wallet(form.Base); !found {
+		return nil, newError(missingWalletErr, "no wallet found for %s", unbip(form.Base))
+	}
+	if _, found := c.wallet(form.Quote); !found {
+		return nil, newError(missingWalletErr, "no wallet found for %s", unbip(form.Quote))
+	}
+
+	if err := c.db.StoreManagedOrder(ord); err != nil {
+		return nil, codedError(dbErr, err)
+	}
+
+	c.managedMtx.Lock()
+	defer c.managedMtx.Unlock()
+	if c.managedOrders != nil {
+		c.runManagedOrder(ord)
+	}
+	return managedOrderFromDB(ord, 0), nil
+}
+
+// ManagedOrders lists the managed orders, including completed orders, newest
+// first.
+func (c *Core) ManagedOrders() ([]*ManagedOrder, error) {
+	c.managedMtx.Lock()
+	defer c.managedMtx.Unlock()
+	dbOrds, err := c.db.ManagedOrders()
+	if err != nil {
+		return nil, codedError(dbErr, err)
+	}
+	ords := make([]*ManagedOrder, 0, len(dbOrds))
+	for _, o := range dbOrds {
+		if mo := c.managedOrders[o.ID]; mo != nil {
+			o = mo.ord
+		}
+		var activeFilled uint64
+		if o.Active && len(o.Children) > 0 {
+			child := o.Children[len(o.Children)-1]
+			if corder, err := c.Order(child[:]); err == nil {
+				activeFilled = corder.Filled
+			} else {
+				c.log.Errorf("Error retrieving child order %s of managed order %s: %v", child, o.ID, err)
+			}
+		}
+		ords = append(ords, managedOrderFromDB(o, activeFilled))
+	}
+	sort.Slice(ords, func(i, j int) bool { return ords[i].Stamp > ords[j].Stamp })
+	return ords, nil
+}
+
+// CancelManagedOrder stops an active managed order, and cancels its active
+// child order.
+func (c *Core) CancelManagedOrder(id string) error {
+	c.managedMtx.Lock()
+	mo := c.managedOrders[id]
+	if mo == nil {
+		c.managedMtx.Unlock()
+		return newError(unknownOrderErr, "managed order %s is not active", id)
+	}
+	ord := mo.ord
+	// The active child, if any, remains Active so that its fills are counted.
+	ord.Status = ManagedStatusCanceled
+	c.stopManagedOrder(mo)
+	var child *order.OrderID
+	if ord.Active {
+		child = &ord.Children[len(ord.Children)-1]
+	}
+	err := c.db.StoreManagedOrder(ord)
+	note := managedOrderFromDB(ord, 0)
+	c.managedMtx.Unlock()
+	if err != nil {
+		return codedError(dbErr, err)
+	}
+	c.notifyManagedOrderProgress(note)
+
+	if child != nil {
+		return c.cancelManagedChild(*child)
+	}
+	return nil
+}
+
+// cancelManagedChild cancels the child order if it is still active.
+func (c *Core) cancelManagedChild(child order.OrderID) error {
+	corder, err := c.Order(child[:])
+	if err != nil {
+		return fmt.Errorf("error retrieving child order %s: %w", child, err)
+	}
+	if corder.Status.IsActive() && !corder.Cancelling {
+		if err := c.Cancel(child[:]); err != nil {
+			return fmt.Errorf("error canceling child order %s: %w", child, err)
+		}
+	}
+	return nil
+}
+
+// startManagedOrders loads the active managed orders and resumes them.
+// startManagedOrders is called on login.
+func (c *Core) startManagedOrders() {
+	dbOrds, err := c.db.ManagedOrders()
+	if err != nil {
+		c.log.Errorf("Error loading managed orders: %v", err)
+		return
+	}
+	c.managedMtx.Lock()
+	defer c.managedMtx.Unlock()
+	c.managedOrders = make(map[string]*managedOrder)
+	for _, ord := range dbOrds {
+		if ord.Status == ManagedStatusActive {
+			c.runManagedOrder(ord)
+		}
+	}
+	if len(c.managedOrders) > 0 {
+		c.log.Infof("Resumed %d managed orders", len(c.managedOrders))
+	}
+}
+
+// stopManagedOrders stops executing managed orders. Active orders are resumed
+// at the next login.
+func (c *Core) stopManagedOrders() {
+	c.managedMtx.Lock()
+	defer c.managedMtx.Unlock()
+	for _, mo := range c.managedOrders {
+		mo.cancel()
+	}
+	if len(c.managedOrders) > 0 {
+		c.log.Warnf("%d managed orders will be paused until the next login", len(c.managedOrders))
+	}
+	c.managedOrders = nil
+}
+
+// runManagedOrder starts executing the managed order. The managedMtx must be
+// held.
+func (c *Core) runManagedOrder(ord *db.ManagedOrder) {
+	ctx, cancel := context.WithCancel(c.ctx)
+	c.managedOrders[ord.ID] = &managedOrder{ord: ord, cancel: cancel}
+	c.wg.Add(1)
+	go func() {
+		defer c.wg.Done()
+		ticker := time.NewTicker(managedOrderTick)
+		defer ticker.Stop()
+		for {
+			c.stepManagedOrder(ord.ID)
+			select {
+			case <-ticker.C:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+}
+
+// stopManagedOrder stops executing the managed order. The managedMtx must be
+// held.
+func (c *Core) stopManagedOrder(mo *managedOrder) {
+	mo.cancel()
+	delete(c.managedOrders, mo.ord.ID)
+}
+
+// snapshotManagedOrder copies the state of the running managed order, or
+// returns nil if the order is no longer running.
+func (c *Core) snapshotManagedOrder(id string) (*managedOrder, *db.ManagedOrder) {
+	c.managedMtx.Lock()
+	defer c.managedMtx.Unlock()
+	mo := c.managedOrders[id]
+	if mo == nil {
+		return nil, nil
+	}
+	ord := *mo.ord
+	ord.Children = append([]order.OrderID(nil), mo.ord.Children...)
+	return mo, &ord
+}
+
+// updateManagedOrder applies the update to the managed order and stores it,
+// if the order is still running. The update is made with the managedMtx
+// held. The returned ManagedOrder is the updated state, for notifications.
+func (c *Core) updateManagedOrder(mo *managedOrder, update func(ord *db.ManagedOrder)) (*ManagedOrder, bool) {
+	c.managedMtx.Lock()
+	defer c.managedMtx.Unlock()
+	if c.managedOrders[mo.ord.ID] != mo {
+		return nil, false
+	}
+	update(mo.ord)
+	if err := c.db.StoreManagedOrder(mo.ord); err != nil {
+		c.log.Errorf("Error storing managed order %s: %v", mo.ord.ID, err)
+	}
+	return managedOrderFromDB(mo.ord, 0), true
+}
+
+// stepManagedOrder checks the active child order of the managed order, and
+// places the next child order when it is due. The managed order's state is
+// copied, so that orders are placed and canceled without the managedMtx held.
+// The state is only updated if the managed order was not canceled in the
+// meantime.
+func (c *Core) stepManagedOrder(id string) {
+	mo, ord := c.snapshotManagedOrder(id)
+	if mo == nil {
+		return
+	}
+	dc, _, err := c.dex(ord.Host)
+	if err != nil {
+		c.log.Errorf("Managed order %s: %v", id, err)
+		return
+	}
+	mkt := dc.marketConfig(marketName(ord.Base, ord.Quote))
+	if mkt == nil || mkt.LotSize == 0 {
+		c.log.Errorf("Managed order %s: market %s not known", id, marketName(ord.Base, ord.Quote))
+		return
+	}
+	now := uint64(time.Now().UnixMilli())
+
+	if ord.Active {
+		child := ord.Children[len(ord.Children)-1]
+		corder, err := c.Order(child[:])
+		if err != nil {
+			c.log.Errorf("Managed order %s: error retrieving child order %s: %v", id, child, err)
+			return
+		}
+		if corder.Status.IsActive() {
+			// A booked TWAP slice is canceled when the next slice is due.
+			if ord.Type == ManagedTWAP && ord.Placed < ord.Slices && now >= managedSliceDue(ord) &&
+				corder.Status == order.OrderStatusBooked && !corder.Cancelling {
+				if err := c.Cancel(child[:]); err != nil {
+					c.log.Errorf("Managed order %s: error canceling child order %s: %v", id, child, err)
+				}
+			}
+			return
+		}
+		note, ok := c.updateManagedOrder(mo, func(o *db.ManagedOrder) {
+			o.Filled += corder.Filled
+			o.Active = false
+		})
+		if !ok {
+			return
+		}
+		c.notifyManagedOrderProgress(note)
+		ord.Filled += corder.Filled
+		ord.Active = false
+	}
+
+	qty, slice, done := managedNextChild(ord, mkt.LotSize, now)
+	if done {
+		note, ok := c.updateManagedOrder(mo, func(o *db.ManagedOrder) {
+			o.Status = ManagedStatusComplete
+			c.stopManagedOrder(mo)
+		})
+		if !ok {
+			return
+		}
+		subject, details := c.formatDetails(TopicManagedOrderComplete, ord.Type, id,
+			asset.FormatAtoms(ord.Base, ord.Filled), asset.FormatAtoms(ord.Base, ord.Qty))
+		c.notify(newManagedOrderNote(TopicManagedOrderComplete, subject, details, db.Success, note))
+		return
+	}
+	if qty == 0 {
+		if slice > ord.Placed {
+			c.updateManagedOrder(mo, func(o *db.ManagedOrder) {
+				o.Placed = slice
+			})
+		}
+		return
+	}
+
+	corder, err := c.Trade(nil, &TradeForm{
+		Host:    ord.Host,
+		IsLimit: true,
+		Sell:    ord.Sell,
+		Base:    ord.Base,
+		Quote:   ord.Quote,
+		Qty:     qty,
+		Rate:    ord.Rate,
+	})
+	if err != nil {
+		note, ok := c.updateManagedOrder(mo, func(o *db.ManagedOrder) {
+			o.Status = ManagedStatusFailed
+			o.Error = err.Error()
+			c.stopManagedOrder(mo)
+		})
+		if !ok {
+			return
+		}
+		subject, details := c.formatDetails(TopicManagedOrderFailed, ord.Type, id, err)
+		c.notify(newManagedOrderNote(TopicManagedOrderFailed, subject, details, db.ErrorLevel, note))
+		return
+	}
+	var oid order.OrderID
+	copy(oid[:], corder.ID)
+	note, ok := c.updateManagedOrder(mo, func(o *db.ManagedOrder) {
+		o.Children = append(o.Children, oid)
+		o.Active = true
+		o.Placed = slice
+	})
+	if !ok {
+		// The managed order was canceled while the child was being placed.
+		c.log.Infof("Managed order %s was canceled. Canceling new child order %s", id, oid)
+		c.managedMtx.Lock()
+		mo.ord.Children = append(mo.ord.Children, oid)
+		mo.ord.Active = true
+		if err := c.db.StoreManagedOrder(mo.ord); err != nil {
+			c.log.Errorf("Error storing managed order %s: %v", id, err)
+		}
+		c.managedMtx.Unlock()
+		if err := c.cancelManagedChild(oid); err != nil {
+			c.log.Errorf("Managed order %s: %v", id, err)
+		}
+		return
+	}
+	c.log.Infof("Managed %s order %s placed child order %s for %d", ord.Type, id, oid, qty)
+	c.notifyManagedOrderProgress(note)
+}
+
+// notifyManagedOrderProgress sends a data notification with the managed
+// order's state.
+func (c *Core) notifyManagedOrderProgress(ord *ManagedOrder) {
+	c.notify(newManagedOrderNote(TopicManagedOrderProgress, "", "", db.Data, ord))
+}
@@ -0,0 +1,137 @@
+//go:build !harness && !botlive
+
+package core
+
+import (
+	"testing"
+
+	"decred.org/dcrdex/client/db"
+	"decred.org/dcrdex/dex/order"
+)
+
+func TestManagedNextChild(t *testing.T) {
+	const lot = 10
+	twap := func(placed uint32, filled uint64) *db.ManagedOrder {
+		return &db.ManagedOrder{Type: ManagedTWAP, Qty: 100, Slices: 4, Duration: 400, Stamp: 1000, Placed: placed, Filled: filled}
+	}
+	iceberg := func(filled uint64) *db.ManagedOrder {
+		return &db.ManagedOrder{Type: ManagedIceberg, Qty: 100, Clip: 30, Filled: filled}
+	}
+	tests := []struct {
+		name      string
+		ord       *db.ManagedOrder
+		now       uint64
+		wantQty   uint64
+		wantSlice uint32
+		wantDone  bool
+	}{
+		{"twap first slice", twap(0, 0), 1000, 20, 1, false},
+		{"twap not due", twap(1, 20), 1099, 0, 1, false},
+		{"twap second slice", twap(1, 20), 1100, 30, 2, false},
+		{"twap carries unfilled", twap(1, 0), 1100, 50, 2, false},
+		{"twap combines missed slices", twap(1, 20), 1350, 80, 4, false},
+		{"twap ahead of target", twap(1, 60), 1100, 0, 2, false},
+		{"twap all slices placed", twap(4, 60), 2000, 0, 4, true},
+		{"twap all filled", twap(2, 100), 1200, 0, 2, true},
+		{"iceberg clip", iceberg(0), 0, 30, 0, false},
+		{"iceberg remainder", iceberg(90), 0, 10, 0, false},
+		{"iceberg filled", iceberg(100), 0, 0, 0, true},
+	}
+	for _, tt := range tests {
+		qty, slice, done := managedNextChild(tt.ord, lot, tt.now)
+		if qty != tt.wantQty || slice != tt.wantSlice || done != tt.wantDone {
+			t.Fatalf("%s: wanted (%d, %d, %t), got (%d, %d, %t)", tt.name,
+				tt.wantQty, tt.wantSlice, tt.wantDone, qty, slice, done)
+		}
+	}
+}
+
+func TestManagedOrders(t *testing.T) {
+	rig := newTestRig()
+	defer rig.shutdown()
+	tCore := rig.core
+	dcrWallet, _ := newTWallet(tUTXOAssetA.ID)
+	tCore.wallets[tUTXOAssetA.ID] = dcrWallet
+	btcWallet, _ := newTWallet(tUTXOAssetB.ID)
+	tCore.wallets[tUTXOAssetB.ID] = btcWallet
+
+	form := &ManagedOrderForm{
+		Host:  tDexHost,
+		Base:  tUTXOAssetA.ID,
+		Quote: tUTXOAssetB.ID,
+		Type:  ManagedIceberg,
+		Sell:  true,
+		Qty:   dcrBtcLotSize * 10,
+		Rate:  dcrBtcRateStep * 100,
+		Clip:  dcrBtcLotSize * 2,
+	}
+	for _, bad := range []func(f *ManagedOrderForm){
+		func(f *ManagedOrderForm) { f.Qty = 0 },
+		func(f *ManagedOrderForm) { f.Qty = dcrBtcLotSize*10 + 1 },
+		func(f *ManagedOrderForm) { f.Rate = 0 },
+		func(f *ManagedOrderForm) { f.Clip = dcrBtcLotSize / 2 },
+		func(f *ManagedOrderForm) { f.Clip = dcrBtcLotSize * 20 },
+		func(f *ManagedOrderForm) { f.Type = ManagedTWAP },
+		func(f *ManagedOrderForm) { f.Type, f.Slices, f.Duration = ManagedTWAP, 11, 1000 },
+		func(f *ManagedOrderForm) { f.Type, f.Slices = ManagedTWAP, 5 },
+		func(f *ManagedOrderForm) { f.Type = "vwap" },
+		func(f *ManagedOrderForm) { f.Host = "unknown.dex" },
+		func(f *ManagedOrderForm) { f.Quote = 12345 },
+	} {
+		badForm := *form
+		bad(&badForm)
+		if _, err := tCore.CreateManagedOrder(tPW, &badForm); err == nil {
+			t.Fatalf("no error for bad form %+v", badForm)
+		}
+	}
+
+	// Created while logged out, so not started.
+	ord, err := tCore.CreateManagedOrder(tPW, form)
+	if err != nil {
+		t.Fatalf("CreateManagedOrder error: %v", err)
+	}
+	if ords, _ := tCore.ManagedOrders(); len(ords) != 1 || ords[0].Status != ManagedStatusActive {
+		t.Fatalf("managed order not listed")
+	}
+
+	// The active child order is executed.
+	lo, dbOrder, _, _ := makeLimitOrder(rig.dc, true, form.Clip, form.Rate)
+	lo.FillAmt = dcrBtcLotSize
+	dbOrder.MetaData.Status = order.OrderStatusExecuted
+	rig.db.orderOrders[lo.ID()] = dbOrder
+	dbOrd := rig.db.managedOrders[ord.ID]
+	dbOrd.Children, dbOrd.Active = []order.OrderID{lo.ID()}, true
+	tCore.managedOrders = map[string]*managedOrder{ord.ID: {ord: dbOrd, cancel: func() {}}}
+	if ords, _ := tCore.ManagedOrders(); ords[0].Filled != dcrBtcLotSize || ords[0].ActiveChild == nil {
+		t.Fatalf("active child fill not counted")
+	}
+
+	// The fill is recorded, and the next child can't be placed because the
+	// server rejects it.
+	tCore.stepManagedOrder(ord.ID)
+	dbOrd = rig.db.managedOrders[ord.ID]
+	if dbOrd.Status != ManagedStatusFailed || dbOrd.Error == "" || dbOrd.Filled != dcrBtcLotSize || dbOrd.Active {
+		t.Fatalf("wrong managed order state after failure %+v", dbOrd)
+	}
+	if len(tCore.managedOrders) != 0 {
+		t.Fatalf("failed managed order still active")
+	}
+
+	// Cancel.
+	tCore.managedOrders = nil
+	form.Type, form.Slices, form.Duration = ManagedTWAP, 5, 60_000
+	ord, err = tCore.CreateManagedOrder(tPW, form)
+	if err != nil {
+		t.Fatalf("CreateManagedOrder error: %v", err)
+	}
+	if err := tCore.CancelManagedOrder(ord.ID); err == nil {
+		t.Fatalf("no error canceling inactive managed order")
+	}
+	tCore.managedOrders = map[string]*managedOrder{ord.ID: {ord: rig.db.managedOrders[ord.ID], cancel: func() {}}}
+	if err := tCore.CancelManagedOrder(ord.ID); err != nil {
+		t.Fatalf("CancelManagedOrder error: %v", err)
+	}
+	if rig.db.managedOrders[ord.ID].Status != ManagedStatusCanceled || len(tCore.managedOrders) != 0 {
+		t.Fatalf("managed order not canceled")
+	}
+}
@@ -0,0 +1,1141 @@
+// This code is available on the terms of the project LICENSE.md file,
+// also available online at https://blueoakcouncil.org/license/1.0.0.
+
+package core
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"decred.org/dcrdex/client/asset"
+	"decred.org/dcrdex/client/comms"
+	"decred.org/dcrdex/client/db"
+	"decred.org/dcrdex/client/orderbook"
+	"decred.org/dcrdex/dex"
+	"decred.org/dcrdex/dex/calc"
+	"decred.org/dcrdex/dex/encode"
+	"decred.org/dcrdex/dex/encrypt"
+	"decred.org/dcrdex/dex/keygen"
+	"decred.org/dcrdex/dex/msgjson"
+	"decred.org/dcrdex/dex/order"
+	"decred.org/dcrdex/tatanka/client/mesh"
+	"decred.org/dcrdex/tatanka/client/swap"
+	meshtrade "decred.org/dcrdex/tatanka/client/trade"
+	"decred.org/dcrdex/tatanka/tanka"
+	"github.com/decred/dcrd/crypto/blake256"
+	"github.com/decred/dcrd/dcrec/secp256k1/v4"
+	"github.com/decred/dcrd/dcrec/secp256k1/v4/ecdsa"
+)
+
+const (
+	// MeshHostPrefix is prepended to the address of a Tatanka mesh entry node
+	// to form the host used for the mesh with Core's market and trading
+	// methods, e.g. SyncBook, Trade and Cancel.
+	MeshHostPrefix = "mesh:"
+	// meshMaxFeeExposure is the maximum fee losses we are willing to incur
+	// from a mesh trade, as a ratio of the trade size. Our lot sizes and the
+	// standing orders that we will match are selected to satisfy it.
+	meshMaxFeeExposure = 0.01
+	// meshProposalTimeout is how long we wait for the owner of a standing
+	// order to accept our match proposal. Proposals that are not accepted in
+	// time are not counted toward the order's fill.
+	meshProposalTimeout = time.Minute
+)
+
+// IsMeshHost is true if the host is a Tatanka mesh host.
+func IsMeshHost(host string) bool {
+	return strings.HasPrefix(host, MeshHostPrefix)
+}
+
+// MeshForm is the information necessary to add a Tatanka mesh host.
+type MeshForm struct {
+	// Addr is the address of the mesh entry node.
+	Addr string `json:"addr"`
+	// PeerID is the entry node's peer ID, a compressed secp256k1 pubkey.
+	PeerID dex.Bytes `json:"peerID"`
+	Cert   dex.Bytes `json:"cert"`
+	NoTLS  bool      `json:"noTLS"`
+	// Markets are the [base, quote] asset IDs of the mesh markets to trade.
+	Markets [][2]uint32 `json:"markets"`
+}
+
+// meshClient is the Tatanka mesh client used by a meshConnection.
+// *mesh.Mesh satisfies meshClient.
+type meshClient interface {
+	ID() tanka.PeerID
+	Next() <-chan any
+	SubscribeMarket(baseID, quoteID uint32) error
+	Book(baseID, quoteID uint32) (buys, sells []*tanka.Order, err error)
+	PlaceOrder(ord *tanka.Order) error
+	ProposeMatch(ord *tanka.Order, qty uint64) (*tanka.Match, error)
+	RenewOrder(ord *tanka.Order, qty uint64) error
+}
+
+var _ meshClient = (*mesh.Mesh)(nil)
+
+// meshConnection is a connection to a Tatanka mesh. The mesh markets are
+// traded through Core like the markets of a DEX server.
+type meshConnection struct {
+	meshClient
+	host    string
+	markets [][2]uint32
+	priv    *secp256k1.PrivateKey
+	log     dex.Logger
+	cancel  context.CancelFunc
+	wg      *sync.WaitGroup
+
+	booksMtx sync.Mutex
+	books    map[string]*meshBook
+
+	// orders are our active mesh orders. Orders are removed when they are
+	// no longer booked and have no active matches.
+	ordersMtx sync.RWMutex
+	orders    map[order.OrderID]*meshOrder
+}
+
+// meshOrder is an order placed through a mesh. The order and its matches are
+// stored in the database like DEX orders, with the mesh host.
+type meshOrder struct {
+	*db.MetaOrder
+	// standing is our standing order for the quantity that was not matched
+	// with existing orders. standing is nil if no standing order was placed,
+	// or if the order was loaded from the database.
+	standing *tanka.Order
+	// standingFilled is the quantity of the standing order that was matched.
+	standingFilled uint64
+	// proposed are our match proposals for other peers' standing orders
+	// that have not been accepted yet.
+	proposed map[tanka.ID32]*tanka.Match
+	canceled bool
+	matches  map[order.MatchID]*db.MetaMatch
+}
+
+// limitOrder is the order as an *order.LimitOrder.
+func (mo *meshOrder) limitOrder() *order.LimitOrder {
+	return mo.Order.(*order.LimitOrder)
+}
+
+// coreOrder creates an *Order for the mesh order.
+func (mo *meshOrder) coreOrder() *Order {
+	corder := coreOrderFromTrade(mo.Order, mo.MetaData)
+	corder.ReadyToTick = true
+	corder.Canceled = mo.canceled
+	corder.Matches = make([]*Match, 0, len(mo.matches))
+	for _, mm := range mo.matches {
+		corder.Matches = append(corder.Matches, matchFromMetaMatch(mo.Order, mm))
+	}
+	sort.Slice(corder.Matches, func(i, j int) bool { return corder.Matches[i].Stamp < corder.Matches[j].Stamp })
+	return corder
+}
+
+// updateStatus sets the order status. The order is booked while any of the
+// standing order remains, and is an epoch order while any of our match
+// proposals are awaiting acceptance.
+func (mo *meshOrder) updateStatus() {
+	status := order.OrderStatusExecuted
+	switch {
+	case mo.canceled:
+		status = order.OrderStatusCanceled
+	case mo.standing != nil && mo.standingFilled < mo.standing.Qty:
+		status = order.OrderStatusBooked
+	case len(mo.proposed) > 0:
+		status = order.OrderStatusEpoch
+	}
+	mo.MetaData.Status = status
+}
+
+// active is true if the order is still booked or awaiting acceptance of our
+// match proposals, or if any of its matches are active.
+func (mo *meshOrder) active() bool {
+	if mo.MetaData.Status.IsActive() {
+		return true
+	}
+	for _, mm := range mo.matches {
+		if db.MatchIsActive(mm.UserMatch, &mm.MetaData.Proof) {
+			return true
+		}
+	}
+	return false
+}
+
+// meshMatchStatus converts the swap status to the status of a DEX match.
+// Refunded swaps keep the last status, and are inactive because the match
+// proof has a refund coin.
+func meshMatchStatus(s *swap.Swap, prev order.MatchStatus) order.MatchStatus {
+	switch s.Status {
+	case swap.StatusNegotiating:
+		return order.NewlyMatched
+	case swap.StatusMakerSwapCast:
+		return order.MakerSwapCast
+	case swap.StatusTakerSwapCast:
+		return order.TakerSwapCast
+	case swap.StatusMakerRedeemed:
+		return order.MakerRedeemed
+	case swap.StatusComplete:
+		return order.MatchComplete
+	}
+	return prev
+}
+
+// updateMatch updates the match with the swap's status and coins.
+func updateMeshMatch(mm *db.MetaMatch, s *swap.Swap) {
+	mm.Status = meshMatchStatus(s, mm.Status)
+	// The address is left empty for cancel matches, so use the
+	// counterparty's peer ID until we know their address.
+	mm.Address = s.Counterparty().String()
+	if s.CounterAddress != "" {
+		mm.Address = s.CounterAddress
+	}
+	proof := &mm.MetaData.Proof
+	proof.SecretHash = s.SecretHash
+	proof.Secret = s.Secret
+	proof.ContractData = s.Contract
+	proof.CounterContract = s.CounterContract
+	proof.RefundCoin = order.CoinID(s.RefundCoinID)
+	if s.Maker {
+		proof.MakerSwap, proof.TakerSwap = order.CoinID(s.CoinID), order.CoinID(s.CounterCoinID)
+		proof.MakerRedeem = order.CoinID(s.RedeemCoinID)
+	} else {
+		proof.TakerSwap, proof.MakerSwap = order.CoinID(s.CoinID), order.CoinID(s.CounterCoinID)
+		proof.TakerRedeem = order.CoinID(s.RedeemCoinID)
+	}
+}
+
+// meshBook is a bookie for a mesh market. Changes to the mesh market are
+// translated into the order notes of a DEX server's order book feed.
+type meshBook struct {
+	*bookie
+	seq uint64
+	// qtys are the quantities of the orders on the bookie's book.
+	qtys map[order.OrderID]uint64
+}
+
+// meshOrderID converts the mesh order ID to an order.OrderID.
+func meshOrderID(oid tanka.ID40) order.OrderID {
+	return blake256.Sum256(oid[:])
+}
+
+// update updates the book to match the mesh market's orders, sending the
+// changes to the book feeds.
+func (b *meshBook) update(host string, buys, sells []*tanka.Order) {
+	mktID := marketName(b.base, b.quote)
+	noteFor := func(oid order.OrderID) msgjson.OrderNote {
+		b.seq++
+		return msgjson.OrderNote{
+			Seq:      b.seq,
+			MarketID: mktID,
+			OrderID:  oid[:],
+		}
+	}
+	seen := make(map[order.OrderID]bool, len(buys)+len(sells))
+	for _, ord := range append(buys, sells...) {
+		oid := meshOrderID(ord.ID())
+		seen[oid] = true
+		qty, found := b.qtys[oid]
+		switch {
+		case !found:
+			side := msgjson.BuyOrderNum
+			if ord.Sell {
+				side = msgjson.SellOrderNum
+			}
+			note := &msgjson.BookOrderNote{
+				OrderNote: noteFor(oid),
+				TradeNote: msgjson.TradeNote{
+					Side:     uint8(side),
+					Quantity: ord.Qty,
+					Rate:     ord.Rate,
+					TiF:      msgjson.StandingOrderNum,
+					Time:     uint64(ord.Stamp.UnixMilli()),
+				},
+			}
+			if err := b.Book(note); err != nil {
+				b.log.Errorf("Error booking mesh order %s: %v", ord.ID(), err)
+				continue
+			}
+			b.qtys[oid] = ord.Qty
+			b.send(&BookUpdate{
+				Action:   BookOrderAction,
+				Host:     host,
+				MarketID: mktID,
+				Payload:  b.minifyOrder(note.OrderID, &note.TradeNote, 0),
+			})
+		case qty != ord.Qty:
+			note := &msgjson.UpdateRemainingNote{
+				OrderNote: noteFor(oid),
+				Remaining: ord.Qty,
+			}
+			if err := b.UpdateRemaining(note); err != nil {
+				b.log.Errorf("Error updating mesh order %s: %v", ord.ID(), err)
+				continue
+			}
+			b.qtys[oid] = ord.Qty
+			b.send(&BookUpdate{
+				Action:   UpdateRemainingAction,
+				Host:     host,
+				MarketID: mktID,
+				Payload: &RemainderUpdate{
+					Token:     token(note.OrderID),
+					Qty:       float64(ord.Qty) / float64(b.baseUnits.Conventional.ConversionFactor),
+					QtyAtomic: ord.Qty,
+				},
+			})
+		}
+	}
+	for oid := range b.qtys {
+		if seen[oid] {
+			continue
+		}
+		note := msgjson.UnbookOrderNote(noteFor(oid))
+		if err := b.Unbook(&note); err != nil {
+			b.log.Errorf("Error unbooking mesh order %s: %v", oid, err)
+		}
+		delete(b.qtys, oid)
+		b.send(&BookUpdate{
+			Action:   UnbookOrderAction,
+			Host:     host,
+			MarketID: mktID,
+			Payload:  &MiniOrder{Token: token(note.OrderID)},
+		})
+	}
+}
+
+// hasMarket is true if the market is one of the mesh's configured markets.
+func (mc *meshConnection) hasMarket(base, quote uint32) bool {
+	for _, mkt := range mc.markets {
+		if mkt[0] == base && mkt[1] == quote {
+			return true
+		}
+	}
+	return false
+}
+
+// syncBook creates a bookie for the mesh market if one doesn't exist, and
+// returns the book and a BookFeed to receive order book updates.
+func (mc *meshConnection) syncBook(base, quote uint32) (*orderbook.OrderBook, BookFeed, error) {
+	mktID := marketName(base, quote)
+	if !mc.hasMarket(base, quote) {
+		return nil, nil, fmt.Errorf("unknown market %s", mktID)
+	}
+
+	mc.booksMtx.Lock()
+	defer mc.booksMtx.Unlock()
+
+	book, found := mc.books[mktID]
+	if !found {
+		book = &meshBook{
+			bookie: newMeshBookie(base, quote, func() { mc.stopBook(base, quote) }, mc.log.SubLogger(mktID)),
+			qtys:   make(map[order.OrderID]uint64),
+		}
+		if err := book.Sync(&msgjson.OrderBook{MarketID: mktID}); err != nil {
+			return nil, nil, err
+		}
+		buys, sells, err := mc.Book(base, quote)
+		if err != nil {
+			return nil, nil, err
+		}
+		book.update(mc.host, buys, sells)
+		mc.books[mktID] = book
+	}
+
+	feed := book.newFeed(&BookUpdate{
+		Action:   FreshBookAction,
+		Host:     mc.host,
+		MarketID: mktID,
+		Payload: &MarketOrderBook{
+			Base:  base,
+			Quote: quote,
+			Book:  book.book(),
+		},
+	})
+
+	return book.OrderBook, feed, nil
+}
+
+// meshOrderBook gets the order book for the mesh market.
+func (c *Core) meshOrderBook(host string, base, quote uint32) (*OrderBook, error) {
+	mc, err := c.meshConnection(host)
+	if err != nil {
+		return nil, err
+	}
+	mktID := marketName(base, quote)
+	if !mc.hasMarket(base, quote) {
+		return nil, fmt.Errorf("unknown market %s", mktID)
+	}
+	buys, sells, err := mc.Book(base, quote)
+	if err != nil {
+		return nil, err
+	}
+	book := &meshBook{
+		bookie: newMeshBookie(base, quote, func() {}, mc.log),
+		qtys:   make(map[order.OrderID]uint64),
+	}
+	if err := book.Sync(&msgjson.OrderBook{MarketID: mktID}); err != nil {
+		return nil, err
+	}
+	book.update(mc.host, buys, sells)
+	return book.book(), nil
+}
+
+// stopBook deletes the market's bookie if it has no feeds. We remain
+// subscribed to the mesh market for trading.
+func (mc *meshConnection) stopBook(base, quote uint32) {
+	mktID := marketName(base, quote)
+	mc.booksMtx.Lock()
+	defer mc.booksMtx.Unlock()
+	if book, found := mc.books[mktID]; found {
+		book.feedsMtx.RLock()
+		numFeeds := len(book.feeds)
+		book.feedsMtx.RUnlock()
+		if numFeeds == 0 {
+			delete(mc.books, mktID)
+		}
+	}
+}
+
+// refreshBook updates the market's bookie, if it exists, with the current
+// mesh orders.
+func (mc *meshConnection) refreshBook(base, quote uint32) {
+	mc.booksMtx.Lock()
+	defer mc.booksMtx.Unlock()
+	book, found := mc.books[marketName(base, quote)]
+	if !found {
+		return
+	}
+	buys, sells, err := mc.Book(base, quote)
+	if err != nil {
+		mc.log.Errorf("Error getting mesh book: %v", err)
+		return
+	}
+	book.update(mc.host, buys, sells)
+}
+
+// runMesh processes mesh events until the context is canceled.
+func (c *Core) runMesh(ctx context.Context, mc *meshConnection) {
+	ticker := time.NewTicker(meshProposalTimeout / 2)
+	defer ticker.Stop()
+	for {
+		select {
+		case thing := <-mc.Next():
+			switch u := thing.(type) {
+			case *mesh.MarketUpdate:
+				mc.refreshBook(u.BaseID, u.QuoteID)
+			case *swap.Swap:
+				c.updateMeshOrder(mc, u)
+			}
+		case <-ticker.C:
+			c.expireMeshProposals(mc)
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// saveMeshOrder stores the order and any updated matches in the database,
+// and sends a notification with the updated order. The order is removed from
+// the active orders if it is no longer active. The caller must hold the
+// ordersMtx.
+func (c *Core) saveMeshOrder(mc *meshConnection, mo *meshOrder, matches ...*db.MetaMatch) {
+	oid := mo.Order.ID()
+	if err := c.db.UpdateOrder(mo.MetaOrder); err != nil {
+		c.log.Errorf("Error storing mesh order %s: %v", oid, err)
+	}
+	for _, mm := range matches {
+		if err := c.db.UpdateMatch(mm); err != nil {
+			c.log.Errorf("Error storing mesh match %s: %v", mm.MatchID, err)
+		}
+	}
+	if !mo.active() {
+		delete(mc.orders, oid)
+	}
+	c.notify(newOrderNote(TopicOrderStatusUpdate, "", "", db.Data, mo.coreOrder()))
+}
+
+// updateMeshOrder updates our order with the progress of the swap. A swap is
+// only started for an accepted match, so the match quantity is added to the
+// order's fill when we first see the swap.
+func (c *Core) updateMeshOrder(mc *meshConnection, s *swap.Swap) {
+	tankaMatchID := s.Match.ID()
+	matchID := order.MatchID(tankaMatchID)
+	mc.ordersMtx.Lock()
+	defer mc.ordersMtx.Unlock()
+	for _, mo := range mc.orders {
+		mm, found := mo.matches[matchID]
+		if !found {
+			switch {
+			case s.Maker && mo.standing != nil && mo.standing.ID() == s.Order.ID():
+				mo.standingFilled += s.Match.Qty
+			case !s.Maker && mo.proposed[tankaMatchID] != nil:
+				delete(mo.proposed, tankaMatchID)
+			default:
+				continue
+			}
+			side := order.Taker
+			if s.Maker {
+				side = order.Maker
+			}
+			mm = &db.MetaMatch{
+				UserMatch: &order.UserMatch{
+					OrderID:  mo.Order.ID(),
+					MatchID:  matchID,
+					Quantity: s.Match.Qty,
+					Rate:     s.Order.Rate,
+					Side:     side,
+				},
+				MetaData: &db.MatchMetaData{
+					DEX:   mc.host,
+					Base:  s.Order.BaseID,
+					Quote: s.Order.QuoteID,
+					Stamp: uint64(s.Match.Stamp.UnixMilli()),
+				},
+			}
+			mo.matches[matchID] = mm
+			mo.limitOrder().AddFill(s.Match.Qty)
+		}
+		updateMeshMatch(mm, s)
+		mo.updateStatus()
+		c.saveMeshOrder(mc, mo, mm)
+		return
+	}
+	c.log.Warnf("Swap update for match %s, which is not for any of our mesh orders", tankaMatchID)
+}
+
+// expireMeshProposals drops our match proposals that were not accepted in
+// time.
+func (c *Core) expireMeshProposals(mc *meshConnection) {
+	mc.ordersMtx.Lock()
+	defer mc.ordersMtx.Unlock()
+	for _, mo := range mc.orders {
+		var expired bool
+		for matchID, match := range mo.proposed {
+			if time.Since(match.Stamp) > meshProposalTimeout {
+				mc.log.Infof("Match proposal %s for order %s was not accepted", matchID, match.OrderID)
+				delete(mo.proposed, matchID)
+				expired = true
+			}
+		}
+		if expired {
+			mo.updateStatus()
+			c.saveMeshOrder(mc, mo)
+		}
+	}
+}
+
+// loadMeshOrders loads our active orders for the mesh from the database.
+// Standing orders are not renewed across sessions, and our match proposals
+// are forgotten, so orders that were booked or awaiting acceptance are
+// revoked. Swaps for their accepted matches are resumed by the mesh client.
+func (c *Core) loadMeshOrders(mc *meshConnection) error {
+	dbOrders, err := c.db.ActiveDEXOrders(mc.host)
+	if err != nil {
+		return fmt.Errorf("error loading active orders: %w", err)
+	}
+	activeMatchOIDs, err := c.db.DEXOrdersWithActiveMatches(mc.host)
+	if err != nil {
+		return fmt.Errorf("error loading orders with active matches: %w", err)
+	}
+	for _, oid := range activeMatchOIDs {
+		mOrd, err := c.db.Order(oid)
+		if err != nil {
+			return fmt.Errorf("error loading order %s: %w", oid, err)
+		}
+		dbOrders = append(dbOrders, mOrd)
+	}
+	for _, mOrd := range dbOrders {
+		oid := mOrd.Order.ID()
+		if _, found := mc.orders[oid]; found {
+			continue
+		}
+		if _, ok := mOrd.Order.(*order.LimitOrder); !ok {
+			c.log.Errorf("Mesh order %s is not a limit order", oid)
+			continue
+		}
+		matches, err := c.db.MatchesForOrder(oid, true)
+		if err != nil {
+			return fmt.Errorf("error loading matches for order %s: %w", oid, err)
+		}
+		mo := &meshOrder{
+			MetaOrder: mOrd,
+			proposed:  make(map[tanka.ID32]*tanka.Match),
+			matches:   make(map[order.MatchID]*db.MetaMatch, len(matches)),
+		}
+		for _, mm := range matches {
+			mo.matches[mm.MatchID] = mm
+		}
+		mc.orders[oid] = mo
+		if mOrd.MetaData.Status.IsActive() {
+			mOrd.MetaData.Status = order.OrderStatusRevoked
+			c.saveMeshOrder(mc, mo)
+		}
+	}
+	return nil
+}
+
+// marketOrders returns our orders for the market.
+func (mc *meshConnection) marketOrders(base, quote uint32) []*Order {
+	mc.ordersMtx.RLock()
+	defer mc.ordersMtx.RUnlock()
+	ords := make([]*Order, 0)
+	for _, mo := range mc.orders {
+		if mo.Order.Base() == base && mo.Order.Quote() == quote {
+			ords = append(ords, mo.coreOrder())
+		}
+	}
+	return ords
+}
+
+// exchange creates an *Exchange for the mesh, with the mesh markets and our
+// orders.
+func (mc *meshConnection) exchange() *Exchange {
+	xc := &Exchange{
+		Host:             mc.host,
+		AcctID:           mc.ID().String(),
+		Markets:          make(map[string]*Market, len(mc.markets)),
+		Assets:           make(map[uint32]*dex.Asset),
+		BondAssets:       make(map[string]*BondAsset),
+		ConnectionStatus: comms.Connected,
+		Mesh:             true,
+	}
+	for _, mkt := range mc.markets {
+		base, quote := mkt[0], mkt[1]
+		for _, assetID := range mkt {
+			xc.Assets[assetID] = &dex.Asset{
+				ID:       assetID,
+				Symbol:   unbip(assetID),
+				UnitInfo: meshUnitInfo(assetID),
+			}
+		}
+		name := marketName(base, quote)
+		xc.Markets[name] = &Market{
+			Name:        name,
+			BaseID:      base,
+			BaseSymbol:  unbip(base),
+			QuoteID:     quote,
+			QuoteSymbol: unbip(quote),
+			LotSize:     1,
+			RateStep:    1,
+			AtomToConv:  float64(meshUnitInfo(base).Conventional.ConversionFactor) / float64(meshUnitInfo(quote).Conventional.ConversionFactor),
+			Orders:      mc.marketOrders(base, quote),
+		}
+	}
+	return xc
+}
+
+// meshUnitInfo gets the asset's unit info, with a default for unknown assets.
+func meshUnitInfo(assetID uint32) dex.UnitInfo {
+	if unitInfo, err := asset.UnitInfo(assetID); err == nil {
+		return unitInfo
+	}
+	return defaultUnitInfo(unbip(assetID))
+}
+
+// deriveMeshKey derives our mesh peer private key from the app seed.
+func deriveMeshKey(seed []byte) (*secp256k1.PrivateKey, error) {
+	extKey, err := keygen.GenDeepChild(seed, []uint32{hdKeyPurposeMesh})
+	if err != nil {
+		return nil, fmt.Errorf("GenDeepChild error: %w", err)
+	}
+	privB, err := extKey.SerializedPrivKey()
+	if err != nil {
+		return nil, fmt.Errorf("SerializedPrivKey error: %w", err)
+	}
+	return secp256k1.PrivKeyFromBytes(privB), nil
+}
+
+// meshKey decrypts the app seed and derives our mesh peer private key.
+func (c *Core) meshKey(crypter encrypt.Crypter) (*secp256k1.PrivateKey, error) {
+	creds := c.creds()
+	if creds == nil {
+		return nil, errors.New("primary credentials not retrieved. Is the client initialized?")
+	}
+	seed, err := crypter.Decrypt(creds.EncSeed)
+	if err != nil {
+		return nil, fmt.Errorf("seed decryption error: %w", err)
+	}
+	defer encode.ClearBytes(seed)
+	return deriveMeshKey(seed)
+}
+
+// meshWallet is the wallet source for mesh swaps.
+func (c *Core) meshWallet(assetID uint32) (asset.Wallet, error) {
+	w, err := c.connectedWallet(assetID)
+	if err != nil {
+		return nil, err
+	}
+	return w.Wallet, nil
+}
+
+// meshSwapConfs is the number of confirmations required for a mesh
+// counterparty's swap contract. With no server to set the requirement, we use
+// the largest requirement of our connected DEX servers.
+func (c *Core) meshSwapConfs(assetID uint32) uint32 {
+	confs := uint32(swap.DefaultSwapConfs)
+	for _, dc := range c.dexConnections() {
+		if a := dc.assetConfig(assetID); a != nil && a.SwapConf > confs {
+			confs = a.SwapConf
+		}
+	}
+	return confs
+}
+
+// connectMesh connects to the mesh and subscribes to the mesh markets.
+func (c *Core) connectMesh(h *db.MeshHost, priv *secp256k1.PrivateKey) (*meshConnection, error) {
+	var peerID tanka.PeerID
+	if len(h.PeerID) != len(peerID) {
+		return nil, fmt.Errorf("invalid entry node peer ID length %d", len(h.PeerID))
+	}
+	copy(peerID[:], h.PeerID)
+	host := MeshHostPrefix + h.Addr
+	logger := c.log.SubLogger("MESH")
+	m, err := mesh.New(&mesh.Config{
+		DataDir:    filepath.Join(filepath.Dir(c.cfg.DBPath), "mesh", hex.EncodeToString(h.PeerID)),
+		PrivateKey: priv,
+		Logger:     logger,
+		EntryNode: &mesh.TatankaCredentials{
+			PeerID: peerID,
+			Addr:   h.Addr,
+			Cert:   h.Cert,
+			NoTLS:  h.NoTLS,
+		},
+		Wallets:   c.meshWallet,
+		SwapConfs: c.meshSwapConfs,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error creating mesh client: %w", err)
+	}
+	ctx, cancel := context.WithCancel(c.ctx)
+	wg, err := m.Connect(ctx)
+	if err != nil {
+		cancel()
+		return nil, fmt.Errorf("error connecting to mesh %s: %w", h.Addr, err)
+	}
+	if err := m.Auth(peerID); err != nil {
+		cancel()
+		wg.Wait()
+		return nil, fmt.Errorf("error authenticating with mesh %s: %w", h.Addr, err)
+	}
+	for _, mkt := range h.Markets {
+		if err := m.SubscribeMarket(mkt[0], mkt[1]); err != nil {
+			logger.Errorf("Error subscribing to mesh market %s: %v", marketName(mkt[0], mkt[1]), err)
+		}
+	}
+	mc := &meshConnection{
+		meshClient: m,
+		host:       host,
+		markets:    h.Markets,
+		priv:       priv,
+		log:        logger,
+		cancel:     cancel,
+		wg:         wg,
+		books:      make(map[string]*meshBook),
+		orders:     make(map[order.OrderID]*meshOrder),
+	}
+	if err := c.loadMeshOrders(mc); err != nil {
+		logger.Errorf("Error loading mesh orders: %v", err)
+	}
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		c.runMesh(ctx, mc)
+	}()
+	return mc, nil
+}
+
+// AddMesh connects to a Tatanka mesh and subscribes to the mesh markets. The
+// mesh is saved and reconnected on login. The mesh markets are traded with
+// the host MeshHostPrefix + form.Addr. Our mesh peer ID is derived from the
+// app seed.
+func (c *Core) AddMesh(pw []byte, form *MeshForm) error {
+	if form.Addr == "" {
+		return errors.New("no mesh address provided")
+	}
+	if len(form.Markets) == 0 {
+		return errors.New("no mesh markets provided")
+	}
+	for _, mkt := range form.Markets {
+		if _, err := dex.MarketName(mkt[0], mkt[1]); err != nil {
+			return fmt.Errorf("invalid market: %w", err)
+		}
+		if mkt[0] == mkt[1] {
+			return fmt.Errorf("invalid market %s", marketName(mkt[0], mkt[1]))
+		}
+	}
+	crypter, err := c.encryptionKey(pw)
+	if err != nil {
+		return codedError(passwordErr, err)
+	}
+	defer crypter.Close()
+
+	host := MeshHostPrefix + form.Addr
+	c.meshMtx.Lock()
+	defer c.meshMtx.Unlock()
+	if c.meshes == nil {
+		return errors.New("not logged in")
+	}
+	if _, found := c.meshes[host]; found {
+		return fmt.Errorf("mesh %s already added", form.Addr)
+	}
+	priv, err := c.meshKey(crypter)
+	if err != nil {
+		return err
+	}
+	h := &db.MeshHost{
+		Addr:    form.Addr,
+		PeerID:  form.PeerID,
+		Cert:    form.Cert,
+		NoTLS:   form.NoTLS,
+		Markets: form.Markets,
+	}
+	mc, err := c.connectMesh(h, priv)
+	if err != nil {
+		return err
+	}
+	if err := c.db.StoreMeshHost(h); err != nil {
+		mc.cancel()
+		return fmt.Errorf("error storing mesh host: %w", err)
+	}
+	c.meshes[host] = mc
+	return nil
+}
+
+// startMeshes connects to the saved meshes. startMeshes is called on login.
+func (c *Core) startMeshes(crypter encrypt.Crypter) {
+	c.meshMtx.Lock()
+	defer c.meshMtx.Unlock()
+	c.meshes = make(map[string]*meshConnection)
+	hosts, err := c.db.MeshHosts()
+	if err != nil {
+		c.log.Errorf("Error loading mesh hosts: %v", err)
+		return
+	}
+	if len(hosts) == 0 {
+		return
+	}
+	priv, err := c.meshKey(crypter)
+	if err != nil {
+		c.log.Errorf("Error deriving mesh key: %v", err)
+		return
+	}
+	for _, h := range hosts {
+		mc, err := c.connectMesh(h, priv)
+		if err != nil {
+			c.log.Errorf("Error connecting to mesh %s: %v", h.Addr, err)
+			continue
+		}
+		c.meshes[mc.host] = mc
+	}
+}
+
+// stopMeshes disconnects from the meshes. stopMeshes is called on logout.
+func (c *Core) stopMeshes() {
+	c.meshMtx.Lock()
+	meshes := c.meshes
+	c.meshes = nil
+	c.meshMtx.Unlock()
+	for _, mc := range meshes {
+		mc.cancel()
+		if mc.wg != nil {
+			mc.wg.Wait()
+		}
+	}
+}
+
+// meshConnection gets the connection for the mesh host.
+func (c *Core) meshConnection(host string) (*meshConnection, error) {
+	c.meshMtx.RLock()
+	defer c.meshMtx.RUnlock()
+	mc, found := c.meshes[host]
+	if !found {
+		return nil, fmt.Errorf("unknown mesh %q", strings.TrimPrefix(host, MeshHostPrefix))
+	}
+	return mc, nil
+}
+
+// meshConnections returns the connected meshes.
+func (c *Core) meshConnections() []*meshConnection {
+	c.meshMtx.RLock()
+	defer c.meshMtx.RUnlock()
+	meshes := make([]*meshConnection, 0, len(c.meshes))
+	for _, mc := range c.meshes {
+		meshes = append(meshes, mc)
+	}
+	return meshes
+}
+
+// meshFeeParameters are the fee parameters for selecting compatible mesh
+// orders and our lot size.
+func (c *Core) meshFeeParameters(base, quote uint32, sell bool) (*meshtrade.FeeParameters, error) {
+	fees := func(assetID uint32, send bool) (uint64, error) {
+		w, err := c.connectedWallet(assetID)
+		if err != nil {
+			return 0, err
+		}
+		feeRate := c.feeSuggestionAny(assetID)
+		if send {
+			swapFees, _, err := w.SingleLotSwapRefundFees(asset.VersionNewest, feeRate, false)
+			return swapFees, err
+		}
+		return w.SingleLotRedeemFees(asset.VersionNewest, feeRate)
+	}
+	baseFees, err := fees(base, sell)
+	if err != nil {
+		return nil, fmt.Errorf("error estimating %s fees: %w", unbip(base), err)
+	}
+	quoteFees, err := fees(quote, !sell)
+	if err != nil {
+		return nil, fmt.Errorf("error estimating %s fees: %w", unbip(quote), err)
+	}
+	return &meshtrade.FeeParameters{
+		MaxFeeExposure:    meshMaxFeeExposure,
+		BaseFeesPerMatch:  baseFees,
+		QuoteFeesPerMatch: quoteFees,
+	}, nil
+}
+
+// meshUnlockedWallet connects and unlocks the wallet for a mesh trade.
+func (c *Core) meshUnlockedWallet(crypter encrypt.Crypter, assetID uint32) (*xcWallet, error) {
+	w, found := c.wallet(assetID)
+	if !found {
+		return nil, newError(missingWalletErr, "no configured wallet found for %s (%d)",
+			strings.ToUpper(unbip(assetID)), assetID)
+	}
+	if err := c.connectAndUnlock(crypter, w); err != nil {
+		return nil, codedError(walletErr, fmt.Errorf("%s connectAndUnlock error: %w", unbip(assetID), err))
+	}
+	return w, nil
+}
+
+// checkMeshBalance checks that the from wallet's available balance covers the
+// order quantity and the swap fees, assuming a match for every lot. Token
+// swap fees are checked against the parent asset's wallet.
+func (c *Core) checkMeshBalance(fromWallet *xcWallet, form *TradeForm, p *meshtrade.FeeParameters, lotSize uint64) error {
+	fundQty, feesPerMatch := form.Qty, p.BaseFeesPerMatch
+	if !form.Sell {
+		fundQty, feesPerMatch = calc.BaseToQuote(form.Rate, form.Qty), p.QuoteFeesPerMatch
+	}
+	fees := (form.Qty + lotSize - 1) / lotSize * feesPerMatch
+	check := func(w *xcWallet, req uint64) error {
+		bal, err := w.Balance()
+		if err != nil {
+			return codedError(walletErr, fmt.Errorf("%s balance error: %w", unbip(w.AssetID), err))
+		}
+		if req > bal.Available {
+			return newError(walletBalanceErr, "insufficient %s balance. need %d, have %d",
+				unbip(w.AssetID), req, bal.Available)
+		}
+		return nil
+	}
+	token := asset.TokenInfo(fromWallet.AssetID)
+	if token == nil {
+		return check(fromWallet, fundQty+fees)
+	}
+	if err := check(fromWallet, fundQty); err != nil {
+		return err
+	}
+	feeWallet, found := c.wallet(token.ParentID)
+	if !found {
+		return newError(missingWalletErr, "no %s wallet for %s fees", unbip(token.ParentID), unbip(fromWallet.AssetID))
+	}
+	return check(feeWallet, fees)
+}
+
+// meshTrade matches the limit order with compatible standing orders on the
+// mesh market using meshtrade.MatchBook, proposing matches to their owners, and
+// places a standing order for the remaining quantity, unless the order is
+// an immediate time-in-force order. Matches proposed for our standing order
+// are accepted by the mesh client, and swaps are executed for accepted
+// matches. Only accepted matches count toward the order's fill.
+func (c *Core) meshTrade(pw []byte, mc *meshConnection, form *TradeForm) (*Order, error) {
+	crypter, err := c.encryptionKey(pw)
+	if err != nil {
+		return nil, codedError(passwordErr, err)
+	}
+	defer crypter.Close()
+	if !form.IsLimit {
+		return nil, errors.New("mesh markets only support limit orders")
+	}
+	if form.Qty == 0 {
+		return nil, errors.New("zero quantity not allowed")
+	}
+	if form.Rate == 0 {
+		return nil, errors.New("zero rate not allowed")
+	}
+	mktID := marketName(form.Base, form.Quote)
+	if !mc.hasMarket(form.Base, form.Quote) {
+		return nil, fmt.Errorf("unknown market %s", mktID)
+	}
+	// Unlock both wallets now, so swaps for accepted matches can proceed
+	// without the password.
+	fromID, toID := form.Quote, form.Base
+	if form.Sell {
+		fromID, toID = form.Base, form.Quote
+	}
+	fromWallet, err := c.meshUnlockedWallet(crypter, fromID)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := c.meshUnlockedWallet(crypter, toID); err != nil {
+		return nil, err
+	}
+	p, err := c.meshFeeParameters(form.Base, form.Quote, form.Sell)
+	if err != nil {
+		return nil, err
+	}
+	lotSize := max(meshtrade.MinimumLotSize(form.Rate, p), 1)
+	if err := c.checkMeshBalance(fromWallet, form, p, lotSize); err != nil {
+		return nil, err
+	}
+	buys, sells, err := mc.Book(form.Base, form.Quote)
+	if err != nil {
+		return nil, err
+	}
+	side := sells
+	if form.Sell {
+		side = buys
+	}
+	ourID := mc.ID()
+	theirs := make([]*tanka.Order, 0, len(side))
+	for _, ord := range side {
+		if ord.From != ourID {
+			theirs = append(theirs, ord)
+		}
+	}
+	matches, remain := meshtrade.MatchBook(&meshtrade.DesiredTrade{
+		Qty:  form.Qty,
+		Rate: form.Rate,
+		Sell: form.Sell,
+	}, p, theirs)
+
+	// Hold the orders lock until the order is stored, so that swaps for
+	// accepted matches are not processed before we know about the order.
+	mc.ordersMtx.Lock()
+	defer mc.ordersMtx.Unlock()
+
+	proposed := make(map[tanka.ID32]*tanka.Match, len(matches))
+	for _, m := range matches {
+		match, err := mc.ProposeMatch(m.Order, m.Qty)
+		if err != nil {
+			c.log.Errorf("Error proposing match for mesh order %s: %v", m.Order.ID(), err)
+			continue
+		}
+		proposed[match.ID()] = match
+	}
+
+	now := time.Now()
+	ord := &tanka.Order{
+		From:       ourID,
+		BaseID:     form.Base,
+		QuoteID:    form.Quote,
+		Sell:       form.Sell,
+		Rate:       form.Rate,
+		LotSize:    lotSize,
+		Nonce:      binary.BigEndian.Uint64(encode.RandomBytes(8)),
+		Stamp:      now,
+		Expiration: now.Add(tanka.MaxOrderLifetime),
+	}
+	ord.Qty = remain / ord.LotSize * ord.LotSize
+	var standing *tanka.Order
+	if !form.TifNow && ord.Qty > 0 {
+		if err := mc.PlaceOrder(ord); err != nil {
+			if len(proposed) == 0 {
+				return nil, fmt.Errorf("error placing mesh order: %w", err)
+			}
+			c.log.Errorf("Error placing mesh order for remaining quantity %d: %v", ord.Qty, err)
+		} else {
+			standing = ord
+		}
+	}
+	if len(proposed) == 0 && standing == nil {
+		return nil, errors.New("no compatible orders to match, and no standing order placed")
+	}
+
+	tif := order.StandingTiF
+	if form.TifNow {
+		tif = order.ImmediateTiF
+	}
+	tankaID := ord.ID()
+	lo := &order.LimitOrder{
+		P: order.Prefix{
+			BaseAsset:  form.Base,
+			QuoteAsset: form.Quote,
+			OrderType:  order.LimitOrderType,
+			ClientTime: now,
+			ServerTime: now,
+			// The commitment ties the order to the mesh order.
+			Commit: blake256.Sum256(tankaID[:]),
+		},
+		T: order.Trade{
+			Sell:     form.Sell,
+			Quantity: form.Qty,
+		},
+		Rate:  form.Rate,
+		Force: tif,
+	}
+	oid := lo.ID()
+	mo := &meshOrder{
+		MetaOrder: &db.MetaOrder{
+			MetaData: &db.OrderMetaData{
+				Host: mc.host,
+				// Mesh orders are not signed by a server, so we sign them
+				// with our mesh key.
+				Proof: db.OrderProof{DEXSig: ecdsa.Sign(mc.priv, oid[:]).Serialize()},
+			},
+			Order: lo,
+		},
+		standing: standing,
+		proposed: proposed,
+		matches:  make(map[order.MatchID]*db.MetaMatch),
+	}
+	mo.updateStatus()
+	if err := c.db.UpdateOrder(mo.MetaOrder); err != nil {
+		// The matches were proposed and the standing order placed, so the
+		// order is tracked anyway.
+		c.log.Errorf("Error storing mesh order %s: %v", oid, err)
+	}
+	mc.orders[oid] = mo
+	corder := mo.coreOrder()
+
+	baseUnits, quoteUnits := meshUnitInfo(form.Base), meshUnitInfo(form.Quote)
+	rateString := strconv.FormatFloat(calc.ConventionalRate(form.Rate, baseUnits, quoteUnits), 'f', -1, 64)
+	topic := TopicBuyOrderPlaced
+	if form.Sell {
+		topic = TopicSellOrderPlaced
+	}
+	subject, details := c.formatDetails(topic, baseUnits.ConventionalString(form.Qty), baseUnits.Conventional.Unit, rateString, makeOrderToken(token(corder.ID)))
+	c.notify(newOrderNote(topic, subject, details, db.Poke, corder))
+
+	return corder, nil
+}
+
+// cancelMeshOrder cancels our standing mesh order, if the order was placed
+// through a mesh.
+func (c *Core) cancelMeshOrder(oid order.OrderID) (found bool, err error) {
+	for _, mc := range c.meshConnections() {
+		mc.ordersMtx.Lock()
+		mo, found := mc.orders[oid]
+		if !found {
+			mc.ordersMtx.Unlock()
+			continue
+		}
+		defer mc.ordersMtx.Unlock()
+		if mo.standing == nil || mo.MetaData.Status != order.OrderStatusBooked {
+			return true, fmt.Errorf("order %s is not booked", oid)
+		}
+		if err := mc.RenewOrder(mo.standing, 0); err != nil {
+			return true, fmt.Errorf("error removing mesh order: %w", err)
+		}
+		mo.canceled = true
+		mo.updateStatus()
+		c.saveMeshOrder(mc, mo)
+		return true, nil
+	}
+	return false, nil
+}
@@ -0,0 +1,374 @@
+//go:build !harness && !botlive
+
+package core
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"decred.org/dcrdex/client/asset"
+	"decred.org/dcrdex/client/db"
+	"decred.org/dcrdex/dex/calc"
+	"decred.org/dcrdex/dex/encode"
+	"decred.org/dcrdex/dex/order"
+	"decred.org/dcrdex/tatanka/client/swap"
+	"decred.org/dcrdex/tatanka/tanka"
+	"github.com/decred/dcrd/dcrec/secp256k1/v4"
+)
+
+type tMesh struct {
+	peerID      tanka.PeerID
+	buys, sells []*tanka.Order
+	placed      []*tanka.Order
+	proposed    map[tanka.ID40]uint64
+	matches     []*tanka.Match
+	renewed     map[tanka.ID40]uint64
+	next        chan any
+}
+
+func newTMesh() *tMesh {
+	var peerID tanka.PeerID
+	copy(peerID[:], encode.RandomBytes(33))
+	return &tMesh{
+		peerID:   peerID,
+		proposed: make(map[tanka.ID40]uint64),
+		renewed:  make(map[tanka.ID40]uint64),
+		next:     make(chan any),
+	}
+}
+
+func (m *tMesh) ID() tanka.PeerID                             { return m.peerID }
+func (m *tMesh) Next() <-chan any                             { return m.next }
+func (m *tMesh) SubscribeMarket(baseID, quoteID uint32) error { return nil }
+func (m *tMesh) Book(baseID, quoteID uint32) (buys, sells []*tanka.Order, err error) {
+	return m.buys, m.sells, nil
+}
+func (m *tMesh) PlaceOrder(ord *tanka.Order) error {
+	m.placed = append(m.placed, ord)
+	return nil
+}
+func (m *tMesh) ProposeMatch(ord *tanka.Order, qty uint64) (*tanka.Match, error) {
+	m.proposed[ord.ID()] = qty
+	match := &tanka.Match{From: m.peerID, OrderID: ord.ID(), Qty: qty, Stamp: time.Now()}
+	m.matches = append(m.matches, match)
+	return match, nil
+}
+func (m *tMesh) RenewOrder(ord *tanka.Order, qty uint64) error {
+	m.renewed[ord.ID()] = qty
+	return nil
+}
+
+func tMeshOrder(sell bool, qty, rate, lotSize uint64) *tanka.Order {
+	var from tanka.PeerID
+	copy(from[:], encode.RandomBytes(33))
+	now := time.Now()
+	return &tanka.Order{
+		From:       from,
+		BaseID:     tUTXOAssetA.ID,
+		QuoteID:    tUTXOAssetB.ID,
+		Sell:       sell,
+		Qty:        qty,
+		Rate:       rate,
+		LotSize:    lotSize,
+		Stamp:      now,
+		Expiration: now.Add(tanka.MaxOrderLifetime),
+	}
+}
+
+func newTMeshConnection(m *tMesh) *meshConnection {
+	priv, _ := secp256k1.GeneratePrivateKey()
+	return &meshConnection{
+		meshClient: m,
+		host:       MeshHostPrefix + "tatanka.example.com:7232",
+		markets:    [][2]uint32{{tUTXOAssetA.ID, tUTXOAssetB.ID}},
+		priv:       priv,
+		log:        tLogger,
+		cancel:     func() {},
+		books:      make(map[string]*meshBook),
+		orders:     make(map[order.OrderID]*meshOrder),
+	}
+}
+
+func TestMeshBook(t *testing.T) {
+	rig := newTestRig()
+	defer rig.shutdown()
+	tCore := rig.core
+	m := newTMesh()
+	mc := newTMeshConnection(m)
+	tCore.meshes = map[string]*meshConnection{mc.host: mc}
+
+	buy := tMeshOrder(false, 4e8, 1e6, 1e8)
+	sell := tMeshOrder(true, 2e8, 2e6, 1e8)
+	m.buys, m.sells = []*tanka.Order{buy}, []*tanka.Order{sell}
+
+	if _, _, err := tCore.SyncBook(mc.host, tUTXOAssetB.ID, tUTXOAssetA.ID); err == nil {
+		t.Fatalf("no error for unknown mesh market")
+	}
+	ob, feed, err := tCore.SyncBook(mc.host, tUTXOAssetA.ID, tUTXOAssetB.ID)
+	if err != nil {
+		t.Fatalf("SyncBook error: %v", err)
+	}
+	defer feed.Close()
+	if u := <-feed.Next(); u.Action != FreshBookAction {
+		t.Fatalf("first update was %s, not %s", u.Action, FreshBookAction)
+	}
+	buys, sells, _ := ob.Orders()
+	if len(buys) != 1 || len(sells) != 1 || buys[0].Quantity != 4e8 || sells[0].Rate != 2e6 {
+		t.Fatalf("wrong synced book")
+	}
+
+	// Partially fill the buy order and remove the sell order.
+	buy.Qty = 3e8
+	m.sells = nil
+	mc.refreshBook(tUTXOAssetA.ID, tUTXOAssetB.ID)
+	for _, action := range []string{UpdateRemainingAction, UnbookOrderAction} {
+		if u := <-feed.Next(); u.Action != action {
+			t.Fatalf("expected %s update, got %s", action, u.Action)
+		}
+	}
+	buys, sells, _ = ob.Orders()
+	if len(buys) != 1 || len(sells) != 0 || buys[0].Quantity != 3e8 {
+		t.Fatalf("wrong updated book")
+	}
+
+	book, err := tCore.Book(mc.host, tUTXOAssetA.ID, tUTXOAssetB.ID)
+	if err != nil {
+		t.Fatalf("Book error: %v", err)
+	}
+	if len(book.Buys) != 1 || len(book.Sells) != 0 {
+		t.Fatalf("wrong book")
+	}
+}
+
+func TestMeshTrade(t *testing.T) {
+	rig := newTestRig()
+	defer rig.shutdown()
+	tCore := rig.core
+	dcrWallet, _ := newTWallet(tUTXOAssetA.ID)
+	tCore.wallets[tUTXOAssetA.ID] = dcrWallet
+	btcWallet, tBtcWallet := newTWallet(tUTXOAssetB.ID)
+	tCore.wallets[tUTXOAssetB.ID] = btcWallet
+	m := newTMesh()
+	mc := newTMeshConnection(m)
+	tCore.meshes = map[string]*meshConnection{mc.host: mc}
+
+	good := tMeshOrder(true, 2e8, 1e6, 1e8)
+	tooExpensive := tMeshOrder(true, 2e8, 3e6, 1e8)
+	ours := tMeshOrder(true, 2e8, 5e5, 1e8)
+	ours.From = m.peerID
+	m.sells = []*tanka.Order{ours, good, tooExpensive}
+
+	form := &TradeForm{
+		Host:    mc.host,
+		IsLimit: true,
+		Base:    tUTXOAssetA.ID,
+		Quote:   tUTXOAssetB.ID,
+		Qty:     5e8,
+		Rate:    2e6,
+	}
+	badForm := *form
+	badForm.IsLimit = false
+	if _, err := tCore.Trade(tPW, &badForm); err == nil {
+		t.Fatalf("no error for mesh market order")
+	}
+	badForm = *form
+	badForm.Host = MeshHostPrefix + "unknown"
+	if _, err := tCore.Trade(tPW, &badForm); err == nil {
+		t.Fatalf("no error for unknown mesh")
+	}
+
+	rig.crypter.(*tCrypter).recryptErr = tErr
+	if _, err := tCore.Trade(tPW, form); !errorHasCode(err, passwordErr) {
+		t.Fatalf("wrong error for bad password: %v", err)
+	}
+	rig.crypter.(*tCrypter).recryptErr = nil
+	if len(m.proposed) != 0 || len(m.placed) != 0 {
+		t.Fatalf("order placed with bad password")
+	}
+
+	// The quote wallet can't fund the buy order.
+	tBtcWallet.bal = &asset.Balance{Available: calc.BaseToQuote(form.Rate, form.Qty) - 1}
+	if _, err := tCore.Trade(tPW, form); !errorHasCode(err, walletBalanceErr) {
+		t.Fatalf("wrong error for insufficient balance: %v", err)
+	}
+	if len(m.proposed) != 0 || len(m.placed) != 0 {
+		t.Fatalf("order placed with insufficient balance")
+	}
+	tBtcWallet.bal = &asset.Balance{Available: calc.BaseToQuote(form.Rate, form.Qty)}
+
+	ord, err := tCore.Trade(tPW, form)
+	if err != nil {
+		t.Fatalf("Trade error: %v", err)
+	}
+	if len(m.proposed) != 1 || m.proposed[good.ID()] != 2e8 {
+		t.Fatalf("wrong match proposals %v", m.proposed)
+	}
+	if len(m.placed) != 1 || m.placed[0].Qty != 3e8 || m.placed[0].Sell || m.placed[0].From != m.peerID {
+		t.Fatalf("wrong standing order placed")
+	}
+	if ord.Status != order.OrderStatusBooked || ord.Host != mc.host || ord.Filled != 0 {
+		t.Fatalf("wrong order status %s, filled %d", ord.Status, ord.Filled)
+	}
+	if dbOrd := rig.db.updatedOrder; dbOrd == nil || dbOrd.Order.ID().String() != ord.ID.String() ||
+		dbOrd.MetaData.Host != mc.host || dbOrd.MetaData.Status != order.OrderStatusBooked {
+		t.Fatalf("mesh order not stored")
+	}
+	if xc := tCore.Exchanges()[mc.host]; xc == nil || len(xc.Markets[tDcrBtcMktName].Orders) != 1 {
+		t.Fatalf("mesh order not in exchanges")
+	}
+
+	checkOrder := func(status order.OrderStatus, filled uint64, numMatches int) *Order {
+		t.Helper()
+		mo := mc.orders[order.OrderID(ord.ID)]
+		if mo == nil {
+			t.Fatalf("order not active")
+		}
+		corder := mo.coreOrder()
+		if corder.Status != status || corder.Filled != filled || len(corder.Matches) != numMatches {
+			t.Fatalf("wanted status %s, filled %d with %d matches, got %s, %d, %d", status, filled, numMatches,
+				corder.Status, corder.Filled, len(corder.Matches))
+		}
+		if rig.db.updatedOrder.MetaData.Status != status {
+			t.Fatalf("order status not stored")
+		}
+		return corder
+	}
+
+	// Our proposal is accepted.
+	takerSwap := &swap.Swap{Match: m.matches[0], Order: good}
+	tCore.updateMeshOrder(mc, takerSwap)
+	checkOrder(order.OrderStatusBooked, 2e8, 1)
+	if mm := rig.db.updatedMatch; mm == nil || mm.Status != order.NewlyMatched || mm.Side != order.Taker || mm.Quantity != 2e8 {
+		t.Fatalf("taker match not stored")
+	}
+
+	// A match for our standing order is accepted.
+	var counterparty tanka.PeerID
+	copy(counterparty[:], encode.RandomBytes(33))
+	makerSwap := &swap.Swap{
+		Match: &tanka.Match{From: counterparty, OrderID: m.placed[0].ID(), Qty: m.placed[0].LotSize, Stamp: time.Now()},
+		Order: m.placed[0],
+		Maker: true,
+	}
+	tCore.updateMeshOrder(mc, makerSwap)
+	checkOrder(order.OrderStatusBooked, 2e8+m.placed[0].LotSize, 2)
+
+	// Swap progress is recorded.
+	makerSwap.Status, makerSwap.CoinID = swap.StatusMakerSwapCast, encode.RandomBytes(36)
+	tCore.updateMeshOrder(mc, makerSwap)
+	corder := checkOrder(order.OrderStatusBooked, 2e8+m.placed[0].LotSize, 2)
+	if mm := rig.db.updatedMatch; mm.Status != order.MakerSwapCast || !bytes.Equal(mm.MetaData.Proof.MakerSwap, makerSwap.CoinID) {
+		t.Fatalf("maker swap not stored")
+	}
+	var makerSwapCoin *Coin
+	for _, m := range corder.Matches {
+		if m.Side == order.Maker {
+			makerSwapCoin = m.Swap
+		}
+	}
+	if makerSwapCoin == nil {
+		t.Fatalf("no swap coin for maker match")
+	}
+
+	if err := tCore.Cancel(ord.ID); err != nil {
+		t.Fatalf("Cancel error: %v", err)
+	}
+	if qty, found := m.renewed[m.placed[0].ID()]; !found || qty != 0 {
+		t.Fatalf("standing order not removed")
+	}
+	if err := tCore.Cancel(ord.ID); err == nil {
+		t.Fatalf("no error for canceling canceled order")
+	}
+
+	// The order is retired once the swaps are done.
+	checkOrder(order.OrderStatusCanceled, 2e8+m.placed[0].LotSize, 2)
+	takerSwap.Status = swap.StatusComplete
+	tCore.updateMeshOrder(mc, takerSwap)
+	makerSwap.Status, makerSwap.RefundCoinID = swap.StatusRefunded, encode.RandomBytes(36)
+	tCore.updateMeshOrder(mc, makerSwap)
+	if mc.orders[order.OrderID(ord.ID)] != nil {
+		t.Fatalf("order not retired")
+	}
+
+	// An immediate order doesn't place a standing order, and isn't filled
+	// until a proposal is accepted.
+	m.proposed = make(map[tanka.ID40]uint64)
+	form.TifNow = true
+	if ord, err = tCore.Trade(tPW, form); err != nil {
+		t.Fatalf("immediate Trade error: %v", err)
+	}
+	if len(m.placed) != 1 || ord.Status != order.OrderStatusEpoch || ord.Filled != 0 {
+		t.Fatalf("wrong immediate order. status = %s, filled = %d", ord.Status, ord.Filled)
+	}
+	// The proposal is never accepted.
+	m.matches[1].Stamp = time.Now().Add(-meshProposalTimeout - time.Second)
+	tCore.expireMeshProposals(mc)
+	if mc.orders[order.OrderID(ord.ID)] != nil {
+		t.Fatalf("unfilled immediate order not retired")
+	}
+	if dbOrd := rig.db.updatedOrder; dbOrd.MetaData.Status != order.OrderStatusExecuted || dbOrd.Order.Trade().Filled() != 0 {
+		t.Fatalf("wrong stored immediate order")
+	}
+
+	m.sells = nil
+	if _, err = tCore.Trade(tPW, form); err == nil {
+		t.Fatalf("no error for immediate order with nothing to match")
+	}
+}
+
+func TestLoadMeshOrders(t *testing.T) {
+	rig := newTestRig()
+	defer rig.shutdown()
+	tCore := rig.core
+	m := newTMesh()
+	mc := newTMeshConnection(m)
+
+	newOrder := func(status order.OrderStatus) *db.MetaOrder {
+		return &db.MetaOrder{
+			MetaData: &db.OrderMetaData{Host: mc.host, Status: status},
+			Order: &order.LimitOrder{
+				P: order.Prefix{
+					BaseAsset:  tUTXOAssetA.ID,
+					QuoteAsset: tUTXOAssetB.ID,
+					OrderType:  order.LimitOrderType,
+					ClientTime: time.Now(),
+					ServerTime: time.Now(),
+					Commit:     order.Commitment(encode.RandomBytes(32)),
+				},
+				T:    order.Trade{Quantity: 4e8},
+				Rate: 1e6,
+			},
+		}
+	}
+	booked := newOrder(order.OrderStatusBooked)
+	executed := newOrder(order.OrderStatusExecuted)
+	rig.db.activeDEXOrders = []*db.MetaOrder{booked}
+	rig.db.activeMatchOIDs = []order.OrderID{executed.Order.ID()}
+	rig.db.orderOrders = map[order.OrderID]*db.MetaOrder{executed.Order.ID(): executed}
+	rig.db.matchesForOID = []*db.MetaMatch{{
+		UserMatch: &order.UserMatch{
+			OrderID:  executed.Order.ID(),
+			Quantity: 1e8,
+			Address:  "addr",
+			Status:   order.MakerSwapCast,
+			Side:     order.Maker,
+		},
+		MetaData: &db.MatchMetaData{DEX: mc.host, Base: tUTXOAssetA.ID, Quote: tUTXOAssetB.ID},
+	}}
+
+	if err := tCore.loadMeshOrders(mc); err != nil {
+		t.Fatalf("loadMeshOrders error: %v", err)
+	}
+	// Both orders have an active match, but the booked order's standing
+	// order is no longer renewed, so it is revoked.
+	if len(mc.orders) != 2 {
+		t.Fatalf("wanted 2 loaded orders, got %d", len(mc.orders))
+	}
+	if booked.MetaData.Status != order.OrderStatusRevoked || rig.db.updatedOrder != booked {
+		t.Fatalf("booked order not revoked")
+	}
+	if executed.MetaData.Status != order.OrderStatusExecuted {
+		t.Fatalf("executed order status changed")
+	}
+}
@@ -43,6 +43,8 @@ const (
 	NoteTypeWalletNote     = "walletnote"
 	NoteTypeReputation     = "reputation"
 	NoteTypeActionRequired = "actionrequired"
+	NoteTypeConditional    = "conditional"
+	NoteTypeManagedOrder   = "managedorder"
 )
 
 var noteChanCounter uint64
@@ -353,6 +355,44 @@ func newSendNote(topic Topic, subject, details string, severity db.Severity) *Se
 	}
 }
 
+// ConditionalOrderNote is a notification about a triggered conditional order.
+type ConditionalOrderNote struct {
+	db.Notification
+	ConditionalOrder *ConditionalOrder `json:"conditionalOrder"`
+}
+
+const (
+	TopicConditionalOrderPlaced Topic = "ConditionalOrderPlaced"
+	TopicConditionalOrderFailed Topic = "ConditionalOrderFailed"
+)
+
+func newConditionalOrderNote(topic Topic, subject, details string, severity db.Severity, ord *ConditionalOrder) *ConditionalOrderNote {
+	return &ConditionalOrderNote{
+		Notification:     db.NewNotification(NoteTypeConditional, topic, subject, details, severity),
+		ConditionalOrder: ord,
+	}
+}
+
+// ManagedOrderNote is a notification about the progress of a TWAP or iceberg
+// order.
+type ManagedOrderNote struct {
+	db.Notification
+	ManagedOrder *ManagedOrder `json:"managedOrder"`
+}
+
+const (
+	TopicManagedOrderProgress Topic = "ManagedOrderProgress"
+	TopicManagedOrderComplete Topic = "ManagedOrderComplete"
+	TopicManagedOrderFailed   Topic = "ManagedOrderFailed"
+)
+
+func newManagedOrderNote(topic Topic, subject, details string, severity db.Severity, ord *ManagedOrder) *ManagedOrderNote {
+	return &ManagedOrderNote{
+		Notification: db.NewNotification(NoteTypeManagedOrder, topic, subject, details, severity),
+		ManagedOrder: ord,
+	}
+}
+
 // OrderNote is a notification about an order or a match.
 type OrderNote struct {
 	db.Notification
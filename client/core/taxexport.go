@@ -0,0 +1,575 @@
+// This code is available on the terms of the project LICENSE.md file,
+// also available online at https://blueoakcouncil.org/license/1.0.0.
+
+package core
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"decred.org/dcrdex/client/asset"
+	"decred.org/dcrdex/client/db"
+	"decred.org/dcrdex/dex"
+	"decred.org/dcrdex/dex/calc"
+	"decred.org/dcrdex/dex/order"
+)
+
+// Tax history export formats.
+const (
+	// TaxFormatGeneric is a CSV format with every field of the exported
+	// records.
+	TaxFormatGeneric = "generic"
+	// TaxFormatKoinly is Koinly's universal CSV import format.
+	TaxFormatKoinly = "koinly"
+	// TaxFormatCoinTracking is CoinTracking's CSV import format.
+	TaxFormatCoinTracking = "cointracking"
+)
+
+// Tax record types.
+const (
+	TaxRecordTrade      = "trade"
+	TaxRecordSwapFee    = "swapfee"
+	TaxRecordRedeemFee  = "redeemfee"
+	TaxRecordFundingFee = "fundingfee"
+	TaxRecordRefundFee  = "refundfee"
+	TaxRecordBondPost   = "bondpost"
+	TaxRecordBondRefund = "bondrefund"
+	TaxRecordSend       = "send"
+	TaxRecordReceive    = "receive"
+)
+
+// HistoricalRateSource provides historical fiat exchange rates for tax history
+// exports.
+type HistoricalRateSource interface {
+	// Currency is the fiat currency of the rates, e.g. "USD".
+	Currency() string
+	// HistoricalRate is the fiat value of one conventional unit of the asset
+	// at the time.
+	HistoricalRate(assetID uint32, t time.Time) (float64, error)
+}
+
+// FileRateSource is a HistoricalRateSource that reads daily rates from a CSV
+// file. Each line has a date as YYYY-MM-DD or a unix timestamp in seconds, an
+// asset ticker as used by the BIP-44 registry, e.g. "dcr" or "usdc.eth", and
+// the fiat rate. A header line is skipped. The rate for a time is the most
+// recent rate at or before the time.
+type FileRateSource struct {
+	currency string
+	rates    map[uint32][]fileRate // sorted by stamp
+}
+
+type fileRate struct {
+	stamp int64 // unix seconds
+	rate  float64
+}
+
+var _ HistoricalRateSource = (*FileRateSource)(nil)
+
+// NewFileRateSource loads the rates file. The rates are in the specified
+// fiat currency.
+func NewFileRateSource(path, currency string) (*FileRateSource, error) {
+	f, err := os.Open(dex.CleanAndExpandPath(path))
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	r := csv.NewReader(f)
+	r.FieldsPerRecord = 3
+	r.TrimLeadingSpace = true
+	lines, err := r.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("error reading rates file: %w", err)
+	}
+	src := &FileRateSource{
+		currency: strings.ToUpper(currency),
+		rates:    make(map[uint32][]fileRate),
+	}
+	for i, line := range lines {
+		rate, err := strconv.ParseFloat(line[2], 64)
+		if err != nil {
+			if i == 0 {
+				continue // header
+			}
+			return nil, fmt.Errorf("line %d: invalid rate %q", i+1, line[2])
+		}
+		var stamp int64
+		if t, err := time.Parse(time.DateOnly, line[0]); err == nil {
+			stamp = t.Unix()
+		} else if stamp, err = strconv.ParseInt(line[0], 10, 64); err != nil {
+			return nil, fmt.Errorf("line %d: invalid date %q", i+1, line[0])
+		}
+		assetID, found := dex.BipSymbolID(strings.ToLower(line[1]))
+		if !found {
+			return nil, fmt.Errorf("line %d: unknown asset %q", i+1, line[1])
+		}
+		src.rates[assetID] = append(src.rates[assetID], fileRate{stamp: stamp, rate: rate})
+	}
+	for _, rates := range src.rates {
+		sort.Slice(rates, func(i, j int) bool { return rates[i].stamp < rates[j].stamp })
+	}
+	return src, nil
+}
+
+// Currency is the fiat currency of the rates.
+func (src *FileRateSource) Currency() string {
+	return src.currency
+}
+
+// HistoricalRate is the most recent rate for the asset at or before the time.
+func (src *FileRateSource) HistoricalRate(assetID uint32, t time.Time) (float64, error) {
+	rates := src.rates[assetID]
+	i := sort.Search(len(rates), func(i int) bool { return rates[i].stamp > t.Unix() })
+	if i == 0 {
+		return 0, fmt.Errorf("no %s rate at %s", unbip(assetID), t.UTC().Format(time.DateOnly))
+	}
+	return rates[i-1].rate, nil
+}
+
+// TaxExportForm specifies a tax history export.
+type TaxExportForm struct {
+	// Format is one of TaxFormatGeneric, TaxFormatKoinly, or
+	// TaxFormatCoinTracking. The default is TaxFormatGeneric.
+	Format string `json:"format"`
+	// From and To limit the records to a time range, in milliseconds. Zero
+	// values are unbounded.
+	From uint64 `json:"from"`
+	To   uint64 `json:"to"`
+	// RatesFile is a file of historical fiat rates, read by
+	// NewFileRateSource. If not specified, the rates from the configured
+	// HistoricalRateSource are used, if any.
+	RatesFile string `json:"ratesFile"`
+	// Currency is the fiat currency of the RatesFile. The default is USD.
+	Currency string `json:"currency"`
+}
+
+// taxAmount is an amount of an asset in a tax record. A zero amount is
+// omitted.
+type taxAmount struct {
+	assetID uint32
+	value   uint64
+}
+
+// taxRecord is a taxable event or a fee.
+type taxRecord struct {
+	stamp    uint64 // ms
+	kind     string
+	host     string
+	ref      string // match, order, or transaction ID
+	isTx     bool   // ref is a transaction ID
+	sent     taxAmount
+	received taxAmount
+	fee      taxAmount
+	// fiat is the fiat value of the sent amount, or the received amount
+	// if nothing was sent, or the fee for fee records.
+	fiat    float64
+	hasFiat bool
+}
+
+// feeAssetID is the asset that the fees for transactions of the asset are paid
+// in.
+func feeAssetID(assetID uint32) uint32 {
+	if tkn := asset.TokenInfo(assetID); tkn != nil {
+		return tkn.ParentID
+	}
+	return assetID
+}
+
+// ExportTaxHistory writes the trade fills, DEX fees, bond posts and refunds,
+// and wallet sends and receives as a CSV file in the specified format. The
+// number of records written is returned. Wallet transactions are only included
+// for connected wallets that record transaction history.
+func (c *Core) ExportTaxHistory(w io.Writer, form *TaxExportForm) (int, error) {
+	var writeRecord func(*csv.Writer, *taxRecord, string) error
+	var header []string
+	switch form.Format {
+	case TaxFormatGeneric, "":
+		header, writeRecord = genericTaxHeader, writeGenericTaxRecord
+	case TaxFormatKoinly:
+		header, writeRecord = koinlyTaxHeader, writeKoinlyTaxRecord
+	case TaxFormatCoinTracking:
+		header, writeRecord = coinTrackingTaxHeader, writeCoinTrackingTaxRecord
+	default:
+		return 0, fmt.Errorf("unknown tax export format %q", form.Format)
+	}
+
+	rates := c.cfg.HistoricalRates
+	if form.RatesFile != "" {
+		currency := form.Currency
+		if currency == "" {
+			currency = "USD"
+		}
+		src, err := NewFileRateSource(form.RatesFile, currency)
+		if err != nil {
+			return 0, fmt.Errorf("error loading rates file: %w", err)
+		}
+		rates = src
+	}
+
+	records, err := c.taxRecords(form.From, form.To)
+	if err != nil {
+		return 0, err
+	}
+	var currency string
+	if rates != nil {
+		currency = rates.Currency()
+		c.valueTaxRecords(records, rates)
+	}
+
+	csvWriter := csv.NewWriter(w)
+	csvWriter.UseCRLF = runtime.GOOS == "windows"
+	if err := csvWriter.Write(header); err != nil {
+		return 0, err
+	}
+	for _, r := range records {
+		if err := writeRecord(csvWriter, r, currency); err != nil {
+			return 0, err
+		}
+	}
+	csvWriter.Flush()
+	return len(records), csvWriter.Error()
+}
+
+// taxOrders loads the orders from the app database and, since retention
+// pruning moves old orders there, the archive database. An order that is
+// found in both databases, e.g. after an interrupted prune, is only included
+// once.
+func (c *Core) taxOrders() ([]*Order, error) {
+	ordDBs := []db.DB{c.db}
+	if c.archive != nil {
+		ordDBs = append(ordDBs, c.archive)
+	}
+	seen := make(map[order.OrderID]bool)
+	var corders []*Order
+	for _, ordDB := range ordDBs {
+		ords, err := ordDB.Orders(&db.OrderFilter{})
+		if err != nil {
+			return nil, fmt.Errorf("error retrieving orders: %w", err)
+		}
+		for _, mOrd := range ords {
+			oid := mOrd.Order.ID()
+			if seen[oid] {
+				continue
+			}
+			seen[oid] = true
+			corder, err := coreOrderFromDB(ordDB, mOrd)
+			if err != nil {
+				return nil, err
+			}
+			corders = append(corders, corder)
+		}
+	}
+	return corders, nil
+}
+
+// taxRecords collects the tax records in the time range, oldest first.
+func (c *Core) taxRecords(from, to uint64) ([]*taxRecord, error) {
+	if to == 0 {
+		to = ^uint64(0)
+	}
+	var records []*taxRecord
+	add := func(r *taxRecord) {
+		if r.stamp >= from && r.stamp <= to {
+			records = append(records, r)
+		}
+	}
+
+	corders, err := c.taxOrders()
+	if err != nil {
+		return nil, err
+	}
+	for _, corder := range corders {
+		fromID, toID := corder.QuoteID, corder.BaseID
+		if corder.Sell {
+			fromID, toID = toID, fromID
+		}
+		lastStamp := corder.Stamp
+		for _, m := range corder.Matches {
+			if !settledFilter(m) || m.Refund != nil {
+				continue
+			}
+			lastStamp = max(lastStamp, m.Stamp)
+			baseAmt := taxAmount{corder.BaseID, m.Qty}
+			quoteAmt := taxAmount{corder.QuoteID, calc.BaseToQuote(m.Rate, m.Qty)}
+			r := &taxRecord{
+				stamp:    m.Stamp,
+				kind:     TaxRecordTrade,
+				host:     corder.Host,
+				ref:      m.MatchID.String(),
+				sent:     quoteAmt,
+				received: baseAmt,
+			}
+			if corder.Sell {
+				r.sent, r.received = baseAmt, quoteAmt
+			}
+			add(r)
+		}
+		if corder.FeesPaid == nil {
+			continue
+		}
+		for _, fee := range []struct {
+			kind    string
+			assetID uint32
+			value   uint64
+		}{
+			{TaxRecordSwapFee, feeAssetID(fromID), corder.FeesPaid.Swap},
+			{TaxRecordRedeemFee, feeAssetID(toID), corder.FeesPaid.Redemption},
+			{TaxRecordFundingFee, feeAssetID(fromID), corder.FeesPaid.Funding},
+		} {
+			if fee.value == 0 {
+				continue
+			}
+			add(&taxRecord{
+				stamp: lastStamp,
+				kind:  fee.kind,
+				host:  corder.Host,
+				ref:   corder.ID.String(),
+				fee:   taxAmount{fee.assetID, fee.value},
+			})
+		}
+	}
+
+	for _, w := range c.xcWallets() {
+		txs, err := w.TxHistory(0, nil, false)
+		if err != nil {
+			c.log.Warnf("%s transactions are not included in the tax history: %v", unbip(w.AssetID), err)
+			continue
+		}
+		for _, tx := range txs {
+			if tx.Timestamp == 0 { // unmined
+				continue
+			}
+			r := &taxRecord{
+				stamp: tx.Timestamp * 1000,
+				ref:   tx.ID,
+				isTx:  true,
+				fee:   taxAmount{feeAssetID(w.AssetID), tx.Fees},
+			}
+			amt := taxAmount{w.AssetID, tx.Amount}
+			if tx.TokenID != nil {
+				amt.assetID = *tx.TokenID
+			}
+			if tx.Rejected {
+				amt.value = 0
+			}
+			switch tx.Type {
+			case asset.Send:
+				r.kind, r.sent = TaxRecordSend, amt
+			case asset.Receive:
+				r.kind, r.received, r.fee.value = TaxRecordReceive, amt, 0
+			case asset.Refund:
+				r.kind = TaxRecordRefundFee
+			case asset.CreateBond:
+				r.kind, r.sent = TaxRecordBondPost, amt
+			case asset.RedeemBond:
+				r.kind, r.received = TaxRecordBondRefund, amt
+			default:
+				continue
+			}
+			if r.sent.value == 0 && r.received.value == 0 && r.fee.value == 0 {
+				continue
+			}
+			add(r)
+		}
+	}
+
+	sort.SliceStable(records, func(i, j int) bool { return records[i].stamp < records[j].stamp })
+	return records, nil
+}
+
+// valueTaxRecords sets the fiat values of the tax records. Records that can't
+// be valued are logged and left without a fiat value.
+func (c *Core) valueTaxRecords(records []*taxRecord, rates HistoricalRateSource) {
+	for _, r := range records {
+		amt := r.sent
+		if amt.value == 0 {
+			amt = r.received
+		}
+		if amt.value == 0 {
+			amt = r.fee
+		}
+		ui, err := asset.UnitInfo(amt.assetID)
+		if err != nil {
+			continue
+		}
+		rate, err := rates.HistoricalRate(amt.assetID, time.UnixMilli(int64(r.stamp)))
+		if err != nil {
+			c.log.Warnf("No fiat value for %s record %s: %v", r.kind, r.ref, err)
+			continue
+		}
+		r.fiat = rate * float64(amt.value) / float64(ui.Conventional.ConversionFactor)
+		r.hasFiat = true
+	}
+}
+
+// formatTaxAmount formats the amount in conventional units, and returns the
+// currency code. The strings are empty for a zero amount.
+func formatTaxAmount(amt taxAmount) (value, currency string) {
+	if amt.value == 0 {
+		return "", ""
+	}
+	ui, err := asset.UnitInfo(amt.assetID)
+	if err != nil {
+		return strconv.FormatUint(amt.value, 10), strings.ToUpper(unbip(amt.assetID)) + " (atoms)"
+	}
+	currency = ui.Conventional.Unit
+	if currency == "" {
+		currency = strings.ToUpper(unbip(amt.assetID))
+	}
+	return trimTrailingZeros(ui.ConventionalString(amt.value)), currency
+}
+
+func formatFiat(r *taxRecord) string {
+	if !r.hasFiat {
+		return ""
+	}
+	return strconv.FormatFloat(r.fiat, 'f', 2, 64)
+}
+
+var genericTaxHeader = []string{
+	"Time",
+	"Type",
+	"Host",
+	"Reference",
+	"Sent Amount",
+	"Sent Currency",
+	"Received Amount",
+	"Received Currency",
+	"Fee Amount",
+	"Fee Currency",
+	"Fiat Value",
+	"Fiat Currency",
+}
+
+func writeGenericTaxRecord(w *csv.Writer, r *taxRecord, fiatCurrency string) error {
+	sent, sentCur := formatTaxAmount(r.sent)
+	recv, recvCur := formatTaxAmount(r.received)
+	fee, feeCur := formatTaxAmount(r.fee)
+	return w.Write([]string{
+		time.UnixMilli(int64(r.stamp)).UTC().Format(time.RFC3339),
+		r.kind,
+		r.host,
+		r.ref,
+		sent, sentCur,
+		recv, recvCur,
+		fee, feeCur,
+		formatFiat(r), fiatCurrency,
+	})
+}
+
+var koinlyTaxHeader = []string{
+	"Date",
+	"Sent Amount",
+	"Sent Currency",
+	"Received Amount",
+	"Received Currency",
+	"Fee Amount",
+	"Fee Currency",
+	"Net Worth Amount",
+	"Net Worth Currency",
+	"Label",
+	"Description",
+	"TxHash",
+}
+
+func writeKoinlyTaxRecord(w *csv.Writer, r *taxRecord, fiatCurrency string) error {
+	sent, sentCur := formatTaxAmount(r.sent)
+	recv, recvCur := formatTaxAmount(r.received)
+	fee, feeCur := formatTaxAmount(r.fee)
+	var label string
+	if r.sent.value == 0 && r.received.value == 0 {
+		// A fee on its own is reported as a cost.
+		sent, sentCur, fee, feeCur = fee, feeCur, "", ""
+		label = "cost"
+	}
+	var txHash string
+	if r.isTx {
+		txHash = r.ref
+	}
+	if !r.hasFiat {
+		fiatCurrency = ""
+	}
+	return w.Write([]string{
+		time.UnixMilli(int64(r.stamp)).UTC().Format("2006-01-02 15:04 UTC"),
+		sent, sentCur,
+		recv, recvCur,
+		fee, feeCur,
+		formatFiat(r), fiatCurrency,
+		label,
+		strings.TrimSpace(r.kind + " " + r.host),
+		txHash,
+	})
+}
+
+var coinTrackingTaxHeader = []string{
+	"Type",
+	"Buy Amount",
+	"Buy Currency",
+	"Sell Amount",
+	"Sell Currency",
+	"Fee",
+	"Fee Currency",
+	"Exchange",
+	"Trade-Group",
+	"Comment",
+	"Date",
+}
+
+func writeCoinTrackingTaxRecord(w *csv.Writer, r *taxRecord, fiatCurrency string) error {
+	sent, sentCur := formatTaxAmount(r.sent)
+	recv, recvCur := formatTaxAmount(r.received)
+	fee, feeCur := formatTaxAmount(r.fee)
+	var typ string
+	switch {
+	case r.kind == TaxRecordTrade:
+		typ = "Trade"
+	case r.sent.value > 0:
+		typ = "Withdrawal"
+	case r.received.value > 0:
+		typ = "Deposit"
+	default:
+		typ = "Other Fee"
+		sent, sentCur, fee, feeCur = fee, feeCur, "", ""
+	}
+	comment := r.kind + " " + r.ref
+	if r.hasFiat {
+		comment += fmt.Sprintf(" (%s %s)", formatFiat(r), fiatCurrency)
+	}
+	exchange := r.host
+	if exchange == "" {
+		exchange = "Bison Wallet"
+	}
+	return w.Write([]string{
+		typ,
+		recv, recvCur,
+		sent, sentCur,
+		fee, feeCur,
+		exchange,
+		"",
+		comment,
+		time.UnixMilli(int64(r.stamp)).UTC().Format(time.DateTime),
+	})
+}
+
+// ExportTaxHistoryFile writes the tax history to a new file. See
+// ExportTaxHistory.
+func (c *Core) ExportTaxHistoryFile(path string, form *TaxExportForm) (int, error) {
+	f, err := createFile(path)
+	if err != nil {
+		return 0, err
+	}
+	n, err := c.ExportTaxHistory(f, form)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(f.Name())
+	}
+	return n, err
+}
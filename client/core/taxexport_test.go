@@ -0,0 +1,187 @@
+//go:build !harness && !botlive
+
+package core
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"decred.org/dcrdex/client/asset"
+	"decred.org/dcrdex/client/db"
+	"decred.org/dcrdex/dex/calc"
+	"decred.org/dcrdex/dex/encode"
+	"decred.org/dcrdex/dex/order"
+)
+
+type tHistorianWallet struct {
+	*TXCWallet
+	txs []*asset.WalletTransaction
+}
+
+func (w *tHistorianWallet) TxHistory(n int, refID *string, past bool) ([]*asset.WalletTransaction, error) {
+	return w.txs, nil
+}
+
+func (w *tHistorianWallet) WalletTransaction(ctx context.Context, txID string) (*asset.WalletTransaction, error) {
+	return nil, asset.CoinNotFoundError
+}
+
+func TestExportTaxHistory(t *testing.T) {
+	rig := newTestRig()
+	defer rig.shutdown()
+	tCore := rig.core
+	dcrWallet, tDcrWallet := newTWallet(tUTXOAssetA.ID)
+	tCore.wallets[tUTXOAssetA.ID] = dcrWallet
+
+	day := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	stamp := func(d time.Duration) uint64 { return uint64(day.Add(d).UnixMilli()) }
+
+	// A sell with a settled match, a refunded match, and fees.
+	qty, rate := dcrBtcLotSize*3, dcrBtcRateStep*100
+	lo, dbOrder, _, _ := makeLimitOrder(rig.dc, true, qty, rate)
+	lo.ServerTime = day
+	dbOrder.MetaData.SwapFeesPaid = 1000
+	dbOrder.MetaData.RedemptionFeesPaid = 50000
+	rig.db.filteredOrders = []*db.MetaOrder{dbOrder}
+	match := func(status order.MatchStatus, refund []byte) *db.MetaMatch {
+		var mid order.MatchID
+		copy(mid[:], encode.RandomBytes(32))
+		return &db.MetaMatch{
+			UserMatch: &order.UserMatch{
+				OrderID:  lo.ID(),
+				MatchID:  mid,
+				Quantity: dcrBtcLotSize,
+				Rate:     rate,
+				Address:  "addr",
+				Status:   status,
+				Side:     order.Maker,
+			},
+			MetaData: &db.MatchMetaData{
+				Proof: db.MatchProof{RefundCoin: refund},
+				DEX:   tDexHost,
+				Base:  tUTXOAssetA.ID,
+				Quote: tUTXOAssetB.ID,
+				Stamp: stamp(time.Hour),
+			},
+		}
+	}
+	rig.db.matchesForOID = []*db.MetaMatch{
+		match(order.MakerRedeemed, nil),
+		match(order.MakerSwapCast, encode.RandomBytes(36)),
+		match(order.MakerSwapCast, nil), // not settled
+	}
+
+	// Wallet transactions.
+	dcrWallet.Wallet = &tHistorianWallet{
+		TXCWallet: tDcrWallet,
+		txs: []*asset.WalletTransaction{
+			{Type: asset.Send, ID: "send", Amount: 2e8, Fees: 2000, Timestamp: uint64(day.Add(2 * time.Hour).Unix())},
+			{Type: asset.Receive, ID: "recv", Amount: 5e8, Timestamp: uint64(day.Add(-time.Hour).Unix())},
+			{Type: asset.CreateBond, ID: "bond", Amount: 1e8, Fees: 300, Timestamp: uint64(day.Add(3 * time.Hour).Unix())},
+			{Type: asset.Swap, ID: "swap", Amount: 1e8, Fees: 100, Timestamp: uint64(day.Add(time.Hour).Unix())},
+			{Type: asset.Refund, ID: "refund", Amount: 1e7, Fees: 200, Timestamp: uint64(day.Add(4 * time.Hour).Unix())},
+			{Type: asset.Send, ID: "mempool", Amount: 1e8, Fees: 100},
+		},
+	}
+
+	ratesFile := filepath.Join(t.TempDir(), "rates.csv")
+	if err := os.WriteFile(ratesFile, []byte("date,asset,rate\n2024-03-01,dcr,20\n2024-02-29,btc,60000\n2024-03-02,dcr,25\n"), 0644); err != nil {
+		t.Fatalf("WriteFile error: %v", err)
+	}
+
+	export := func(form *TaxExportForm) [][]string {
+		t.Helper()
+		var buf bytes.Buffer
+		n, err := tCore.ExportTaxHistory(&buf, form)
+		if err != nil {
+			t.Fatalf("ExportTaxHistory error: %v", err)
+		}
+		lines, err := csv.NewReader(&buf).ReadAll()
+		if err != nil {
+			t.Fatalf("error reading CSV: %v", err)
+		}
+		if len(lines) != n+1 {
+			t.Fatalf("wrong number of lines %d for %d records", len(lines), n)
+		}
+		return lines
+	}
+
+	// receive, trade, swap fee, redeem fee, send, bond post, refund fee
+	lines := export(&TaxExportForm{RatesFile: ratesFile})
+	if len(lines) != 8 {
+		t.Fatalf("expected 7 records, got %d", len(lines)-1)
+	}
+	wantTypes := []string{TaxRecordReceive, TaxRecordTrade, TaxRecordSwapFee, TaxRecordRedeemFee,
+		TaxRecordSend, TaxRecordBondPost, TaxRecordRefundFee}
+	for i, typ := range wantTypes {
+		if lines[i+1][1] != typ {
+			t.Fatalf("record %d: wanted type %s, got %s", i, typ, lines[i+1][1])
+		}
+	}
+	trade := lines[2]
+	wantQuote := calc.BaseToQuote(rate, dcrBtcLotSize)
+	if trade[4] != "0.1" || trade[5] != "DCR" || trade[6] != trimTrailingZeros(tWalletInfo.UnitInfo.ConventionalString(wantQuote)) ||
+		trade[7] != "BTC" || trade[10] != "2.00" || trade[11] != "USD" {
+		t.Fatalf("wrong trade record %v", trade)
+	}
+	// The redeem fee is paid in BTC.
+	if redeemFee := lines[4]; redeemFee[8] != "0.0005" || redeemFee[9] != "BTC" || redeemFee[10] != "30.00" {
+		t.Fatalf("wrong redeem fee record %v", redeemFee)
+	}
+	// No rate before the first DCR rate.
+	if receive := lines[1]; receive[10] != "" {
+		t.Fatalf("unexpected fiat value for receive %v", receive)
+	}
+
+	// Time range, without fiat values.
+	lines = export(&TaxExportForm{From: stamp(90 * time.Minute), To: stamp(3 * time.Hour)})
+	if len(lines) != 3 || lines[1][1] != TaxRecordSend || lines[2][1] != TaxRecordBondPost || lines[1][10] != "" {
+		t.Fatalf("wrong records in time range %v", lines)
+	}
+
+	// Other formats.
+	lines = export(&TaxExportForm{Format: TaxFormatKoinly, RatesFile: ratesFile})
+	if len(lines) != 8 || lines[0][0] != "Date" {
+		t.Fatalf("wrong koinly export %v", lines)
+	}
+	if swapFee := lines[3]; swapFee[1] != "0.00001" || swapFee[2] != "DCR" || swapFee[9] != "cost" {
+		t.Fatalf("wrong koinly fee record %v", swapFee)
+	}
+	lines = export(&TaxExportForm{Format: TaxFormatCoinTracking})
+	if len(lines) != 8 || lines[2][0] != "Trade" || lines[5][0] != "Withdrawal" || lines[1][0] != "Deposit" {
+		t.Fatalf("wrong cointracking export %v", lines)
+	}
+
+	// Archived orders are included, but an order in both databases is only
+	// counted once.
+	archivedLo, archivedOrder, _, _ := makeLimitOrder(rig.dc, false, qty, rate)
+	archivedLo.ServerTime = day.Add(-48 * time.Hour)
+	archivedMatch := match(order.MakerRedeemed, nil)
+	archivedMatch.OrderID = archivedLo.ID()
+	archivedMatch.MetaData.Stamp = stamp(-47 * time.Hour)
+	tCore.archive = &TDB{
+		filteredOrders: []*db.MetaOrder{dbOrder, archivedOrder},
+		matchesForOID:  []*db.MetaMatch{archivedMatch},
+	}
+	lines = export(&TaxExportForm{To: stamp(-46 * time.Hour)})
+	if len(lines) != 2 || lines[1][1] != TaxRecordTrade || lines[1][5] != "BTC" {
+		t.Fatalf("wrong archived records %v", lines)
+	}
+	lines = export(&TaxExportForm{})
+	if len(lines) != 9 {
+		t.Fatalf("expected 8 records with the archive, got %d", len(lines)-1)
+	}
+	tCore.archive = nil
+
+	if _, err := tCore.ExportTaxHistory(new(bytes.Buffer), &TaxExportForm{Format: "turbotax"}); err == nil {
+		t.Fatalf("no error for unknown format")
+	}
+	if _, err := tCore.ExportTaxHistory(new(bytes.Buffer), &TaxExportForm{RatesFile: ratesFile + "x"}); err == nil {
+		t.Fatalf("no error for missing rates file")
+	}
+}
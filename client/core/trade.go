@@ -78,6 +78,12 @@ type matchTracker struct {
 	// trying to redeem this match. If suspectRedeem is true, the match will not
 	// be grouped when attempting future redemptions.
 	suspectRedeem bool
+	// swapSigPending and redeemSigPending are set when the wallet returned
+	// asset.ErrSignaturePending for the match's swap or redeem. The wallet is
+	// waiting on an external signer, and the same group of matches must be
+	// retried until the transaction is signed.
+	swapSigPending   bool
+	redeemSigPending bool
 	// refundErr will be set to true if we attempt a refund and get a
 	// CoinNotFoundError, indicating there is nothing to refund and the
 	// counterparty redemption search should be attempted. Prevents retries.
@@ -298,6 +304,9 @@ type trackedTrade struct {
 	redemptionLocked uint64 // remaining locked of redemptionReserves
 	refundLocked     uint64 // remaining locked of refundReserves
 	readyToTick      bool   // this will be false if either of the wallets cannot be connected and unlocked
+	// feeBumpStamps are the times of the last automatic fee bump attempts,
+	// keyed by the redemption coin ID, or swapFeeBumpKey for the swaps.
+	feeBumpStamps map[string]time.Time
 }
 
 // newTrackedTrade is a constructor for a trackedTrade.
@@ -332,6 +341,7 @@ func newTrackedTrade(dbOrder *db.MetaOrder, preImg order.Preimage, dc *dexConnec
 		redemptionReserves: dbOrder.MetaData.RedemptionReserves,
 		refundReserves:     dbOrder.MetaData.RefundReserves,
 		readyToTick:        true,
+		feeBumpStamps:      make(map[string]time.Time),
 	}
 	return t
 }
@@ -2002,6 +2012,15 @@ func (c *Core) tick(t *trackedTrade) (assetMap, error) {
 
 	rmCancel := t.hasStaleCancelOrder()
 
+	// Look for swaps and redemptions that have been unconfirmed for a while.
+	// Only check swaps if we aren't about to send more.
+	var bumpSwaps bool
+	var bumpStart, bumpDeadline time.Time
+	if len(swaps) == 0 {
+		bumpSwaps, bumpStart, bumpDeadline = t.pendingSwapFeeBump()
+	}
+	bumpRedeems := t.pendingRedeemFeeBumps()
+
 	// End checks under read-only lock.
 	t.mtx.RUnlock()
 
@@ -2028,7 +2047,8 @@ func (c *Core) tick(t *trackedTrade) (assetMap, error) {
 
 	if !rmCancel && len(swaps) == 0 && len(refunds) == 0 && len(redeems) == 0 &&
 		len(revokes) == 0 && len(searches) == 0 && len(redemptionConfirms) == 0 &&
-		len(dynamicSwapFeeConfirms) == 0 && len(dynamicRedemptionFeeConfirms) == 0 {
+		len(dynamicSwapFeeConfirms) == 0 && len(dynamicRedemptionFeeConfirms) == 0 &&
+		!bumpSwaps && len(bumpRedeems) == 0 {
 		return assets, nil // nothing to do, don't acquire the write-lock
 	}
 
@@ -2148,6 +2168,16 @@ func (c *Core) tick(t *trackedTrade) (assetMap, error) {
 		t.updateDynamicSwapOrRedemptionFeesPaid(c.ctx, match, false)
 	}
 
+	if bumpSwaps {
+		c.bumpSwapFees(t, bumpStart, bumpDeadline)
+		assets.count(t.wallets.fromWallet.AssetID)
+	}
+
+	if len(bumpRedeems) > 0 {
+		c.bumpRedeemFees(t, bumpRedeems)
+		assets.count(t.wallets.toWallet.AssetID)
+	}
+
 	return assets, errs.ifAny()
 }
 
@@ -2286,6 +2316,12 @@ func (t *trackedTrade) revokeMatch(matchID order.MatchID, fromServer bool) error
 // mutex lock held for writes.
 func (c *Core) swapMatches(t *trackedTrade, matches []*matchTracker) (err error) {
 	errs := newErrorSet("swapMatches order %s - ", t.ID())
+	// If a swap is waiting on an external signer, retry that exact group.
+	// Other matches wait, since they would be funded with the same coins.
+	if pending := t.sigPendingMatches(func(m *matchTracker) bool { return m.swapSigPending }); len(pending) > 0 {
+		c.swapMatchGroup(t, pending, t.bestSwapGroupFeeRate(pending), errs)
+		return errs.ifAny()
+	}
 	groupables := make([]*matchTracker, 0, len(matches)) // Over-allocating if there are suspect matches
 	var suspects []*matchTracker
 	for _, m := range matches {
@@ -2321,6 +2357,19 @@ func (c *Core) swapMatches(t *trackedTrade, matches []*matchTracker) (err error)
 	return errs.ifAny()
 }
 
+// sigPendingMatches returns the matches that are waiting on an external
+// signer. This method MUST be called with the trackedTrade mutex lock held for
+// reads.
+func (t *trackedTrade) sigPendingMatches(pending func(*matchTracker) bool) []*matchTracker {
+	var matches []*matchTracker
+	for _, m := range t.matches {
+		if pending(m) {
+			matches = append(matches, m)
+		}
+	}
+	return matches
+}
+
 // bestSwapGroupRate gets the most appropriate fee rate for a group of swaps.
 func (t *trackedTrade) bestSwapGroupFeeRate(matches []*matchTracker) uint64 {
 	var highestFeeRate uint64
@@ -2445,6 +2494,17 @@ func (c *Core) swapMatchGroup(t *trackedTrade, matches []*matchTracker, highestF
 		Options:      t.options,
 	}
 	receipts, change, fees, err := fromWallet.Swap(swaps)
+	for _, match := range matches {
+		match.swapSigPending = errors.Is(err, asset.ErrSignaturePending)
+	}
+	if errors.Is(err, asset.ErrSignaturePending) {
+		// The wallet will time out the signing request if the signer does not
+		// respond, so there is no broadcast timeout check here. If the swap is
+		// signed after a revocation, it will be refunded.
+		c.log.Infof("Swap transaction for %d matches of order %v is awaiting a signature from the %s external signer",
+			len(matches), t.ID(), fromWallet.Symbol)
+		return
+	}
 	if err != nil {
 		bTimeout, tickInterval := t.broadcastTimeout(), t.dc.ticker.Dur() // bTimeout / tickCheckInterval
 		for _, match := range matches {
@@ -2673,6 +2733,14 @@ func (c *Core) sendInitAsync(t *trackedTrade, match *matchTracker, coinID, contr
 // mutex lock held for writes.
 func (c *Core) redeemMatches(t *trackedTrade, matches []*matchTracker) (err error) {
 	errs := newErrorSet("redeemMatches order %s - ", t.ID())
+	// If a redeem is waiting on an external signer, retry that exact group.
+	if pending := t.sigPendingMatches(func(m *matchTracker) bool { return m.redeemSigPending }); len(pending) > 0 {
+		if !t.wallets.toWallet.connected() {
+			return errWalletNotConnected
+		}
+		c.redeemMatchGroup(t, pending, errs)
+		return errs.ifAny()
+	}
 	groupables := make([]*matchTracker, 0, len(matches)) // Over-allocating if there are suspect matches
 	var suspects []*matchTracker
 	for _, m := range matches {
@@ -2753,6 +2821,14 @@ func (c *Core) redeemMatchGroup(t *trackedTrade, matches []*matchTracker, errs *
 		FeeSuggestion: t.redeemFee(), // fallback - wallet will try to get a rate internally for configured redeem conf target
 		Options:       t.options,
 	})
+	for _, match := range matches {
+		match.redeemSigPending = errors.Is(err, asset.ErrSignaturePending)
+	}
+	if errors.Is(err, asset.ErrSignaturePending) {
+		c.log.Infof("Redeem transaction for %d matches of order %v is awaiting a signature from the %s external signer",
+			len(matches), t.ID(), redeemWallet.Symbol)
+		return
+	}
 	// If an error was encountered, fail all of the matches. A failed match will
 	// not run again on during ticks.
 	if err != nil {
@@ -3295,7 +3371,11 @@ func (c *Core) refundMatches(t *trackedTrade, matches []*matchTracker) (uint64,
 			// CRITICAL - Refund must indicate if the swap is spent (i.e.
 			// redeemed already) so that as taker we will start the
 			// auto-redemption path.
-			if errors.Is(err, asset.CoinNotFoundError) && match.Side == order.Taker {
+			if errors.Is(err, asset.ErrSignaturePending) {
+				// Retry next tick. The wallet times out the signing request.
+				c.log.Infof("Refund of %s contract %s for match %s is awaiting a signature from the external signer",
+					symbol, swapCoinString, match)
+			} else if errors.Is(err, asset.CoinNotFoundError) && match.Side == order.Taker {
 				match.refundErr = err
 				// Could not find the contract coin, which means it has been
 				// spent. Unless the locktime is expired, we would have already
@@ -3341,6 +3421,7 @@ func (c *Core) refundMatches(t *trackedTrade, matches []*matchTracker) (uint64,
 		if err != nil {
 			errs.add("error storing match info in database: %v", err)
 		}
+		c.watchRefund(refundWallet, refundCoin, contractToRefund, t.metaData.MaxFeeRate)
 	}
 
 	return refundedQty, errs.ifAny()
@@ -3789,6 +3870,30 @@ func (t *trackedTrade) requiredForRemainingSwaps() (uint64, error) {
 	return requiredForRemainingSwaps, nil
 }
 
+// accelerate accelerates the swap transactions in this trade to newFeeRate, and
+// records the new change and acceleration coins.
+// MUST be called with the trackedTrade mutex write-locked.
+func (t *trackedTrade) accelerate(newFeeRate uint64) (string, error) {
+	swapCoinIDs, accelerationCoins, changeCoinID, requiredForRemainingSwaps, err := t.orderAccelerationParameters()
+	if err != nil {
+		return "", err
+	}
+
+	newChangeCoin, txID, err :=
+		t.wallets.fromWallet.accelerateOrder(swapCoinIDs, accelerationCoins, changeCoinID, requiredForRemainingSwaps, newFeeRate)
+	if err != nil {
+		return "", err
+	}
+	if newChangeCoin != nil {
+		t.metaData.ChangeCoin = order.CoinID(newChangeCoin.ID())
+		t.coins[newChangeCoin.ID().String()] = newChangeCoin
+	} else {
+		t.metaData.ChangeCoin = nil
+	}
+	t.metaData.AccelerationCoins = append(t.metaData.AccelerationCoins, t.metaData.ChangeCoin)
+	return txID, t.db.UpdateOrderMetaData(t.ID(), t.metaData)
+}
+
 // orderAccelerationParameters returns the parameters needed to accelerate the
 // swap transactions in this trade.
 // MUST be called with the trackedTrade mutex held.
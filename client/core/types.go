@@ -37,6 +37,9 @@ const (
 	// scheme to locate them on-chain:
 	//  m / hdKeyPurposeBonds / assetID' / bondIndex
 	hdKeyPurposeBonds uint32 = hdkeychain.HardenedKeyStart + 0x626f6e64 // ASCII "bond"
+	// hdKeyPurposeMesh is the BIP-43 purpose field for our Tatanka mesh peer
+	// key, m / hdKeyPurposeMesh.
+	hdKeyPurposeMesh uint32 = hdkeychain.HardenedKeyStart + 0x6d657368 // ASCII "mesh"
 )
 
 // errorSet is a slice of orders with a prefix prepended to the Error output.
@@ -715,6 +718,9 @@ type Exchange struct {
 	PenaltyThreshold uint32                 `json:"penaltyThreshold"`
 	MaxScore         uint32                 `json:"maxScore"`
 	Disabled         bool                   `json:"disabled"`
+	// Mesh is true if the exchange is a Tatanka mesh, which has no server
+	// account or bonds.
+	Mesh bool `json:"mesh"`
 }
 
 // newDisplayIDFromSymbols creates a display-friendly market ID for a base/quote
@@ -1046,6 +1052,9 @@ type TradeForm struct {
 	Rate    uint64            `json:"rate"`
 	TifNow  bool              `json:"tifnow"`
 	Options map[string]string `json:"options"`
+	// Coins, if non-empty, restricts order funding to the specified coins.
+	// The wallet must support coin control.
+	Coins []dex.Bytes `json:"coins,omitempty"`
 }
 
 // QtyRate specifies the quantity and rate of an order placement.
@@ -1131,6 +1140,9 @@ type OrderFilter struct {
 		Base  uint32 `json:"baseID"`
 		Quote uint32 `json:"quoteID"`
 	} `json:"market"`
+	// Archived selects orders from the archive database, which holds the
+	// orders moved out of the app database by the retention policy.
+	Archived bool `json:"archived"`
 }
 
 // Account holds data returned from AccountExport.
@@ -535,6 +535,35 @@ func (w *xcWallet) preAccelerate(swapCoins, accelerationCoins []dex.Bytes, chang
 	return accelerator.PreAccelerate(swapCoins, accelerationCoins, changeCoin, requiredForRemainingSwaps, feeSuggestion)
 }
 
+// txFeeRate gets the effective fee rate of an unconfirmed transaction if the
+// wallet is a FeeBumper.
+func (w *xcWallet) txFeeRate(coinID dex.Bytes) (uint64, bool, error) {
+	if !w.connected() {
+		return 0, false, errWalletNotConnected
+	}
+	bumper, ok := w.Wallet.(asset.FeeBumper)
+	if !ok {
+		return 0, false, errors.New("wallet does not support fee bumping")
+	}
+	return bumper.TxFeeRate(coinID)
+}
+
+// bumpFee bumps the fee of an unconfirmed transaction if the wallet is a
+// FeeBumper.
+func (w *xcWallet) bumpFee(coinID, contract dex.Bytes, newFeeRate uint64, replace bool) (dex.Bytes, error) {
+	if w.isDisabled() { // cannot bump fees with disabled wallet.
+		return nil, fmt.Errorf(walletDisabledErrStr, strings.ToUpper(unbip(w.AssetID)))
+	}
+	if !w.connected() {
+		return nil, errWalletNotConnected
+	}
+	bumper, ok := w.Wallet.(asset.FeeBumper)
+	if !ok {
+		return nil, errors.New("wallet does not support fee bumping")
+	}
+	return bumper.BumpFee(coinID, contract, newFeeRate, replace)
+}
+
 // swapConfirmations calls (asset.Wallet).SwapConfirmations with a timeout
 // Context. If the coin cannot be located, an asset.CoinNotFoundError is
 // returned. If the coin is located, but recognized as spent, no error is
@@ -0,0 +1,330 @@
+// This code is available on the terms of the project LICENSE.md file,
+// also available online at https://blueoakcouncil.org/license/1.0.0.
+
+package core
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"decred.org/dcrdex/client/db"
+)
+
+// defaultWithdrawalPolicyDelay is the default time before a new address book
+// entry or a less restrictive withdrawal policy takes effect.
+const defaultWithdrawalPolicyDelay = 24 * time.Hour
+
+// withdrawalLimitPeriod is the period for the daily withdrawal limit.
+const withdrawalLimitPeriod = 24 * time.Hour
+
+// AddressBookEntry is a labeled withdrawal address. An entry can be used as a
+// withdrawal destination once it is active.
+type AddressBookEntry struct {
+	AssetID uint32 `json:"assetID"`
+	Symbol  string `json:"symbol"`
+	Address string `json:"address"`
+	Label   string `json:"label"`
+	// Added is the time the entry was added, in milliseconds.
+	Added uint64 `json:"added"`
+	// ActiveTime is the time, in milliseconds, after which the entry can be
+	// used.
+	ActiveTime uint64 `json:"activeTime"`
+	Active     bool   `json:"active"`
+}
+
+// PendingWithdrawalPolicy is a less restrictive withdrawal policy that is not
+// yet in effect.
+type PendingWithdrawalPolicy struct {
+	AllowlistOnly bool   `json:"allowlistOnly"`
+	DailyLimit    uint64 `json:"dailyLimit"`
+	// ActiveTime is the time, in milliseconds, after which the policy is in
+	// effect.
+	ActiveTime uint64 `json:"activeTime"`
+}
+
+// WithdrawalPolicy is the withdrawal policy in effect for an asset.
+type WithdrawalPolicy struct {
+	AssetID uint32 `json:"assetID"`
+	Symbol  string `json:"symbol"`
+	// AllowlistOnly restricts withdrawals to active address book entries.
+	AllowlistOnly bool `json:"allowlistOnly"`
+	// DailyLimit is the maximum amount, in atoms, that can be withdrawn in any
+	// 24 hour period. Zero means no limit.
+	DailyLimit uint64 `json:"dailyLimit"`
+	// Withdrawn is the amount withdrawn in the last 24 hours.
+	Withdrawn uint64                   `json:"withdrawn"`
+	Pending   *PendingWithdrawalPolicy `json:"pending,omitempty"`
+}
+
+// withdrawalPolicyDelay is the time before a new address book entry or a less
+// restrictive withdrawal policy takes effect.
+func (c *Core) withdrawalPolicyDelay() time.Duration {
+	if c.cfg.WithdrawalPolicyDelay > 0 {
+		return c.cfg.WithdrawalPolicyDelay
+	}
+	return defaultWithdrawalPolicyDelay
+}
+
+// checkWithdrawalPassword checks the app password for changes to the address
+// book and withdrawal policies.
+func (c *Core) checkWithdrawalPassword(pw []byte) error {
+	crypter, err := c.encryptionKey(pw)
+	if err != nil {
+		return codedError(passwordErr, err)
+	}
+	crypter.Close()
+	return nil
+}
+
+func newAddressBookEntry(e *db.AddressBookEntry, now uint64) *AddressBookEntry {
+	return &AddressBookEntry{
+		AssetID:    e.AssetID,
+		Symbol:     unbip(e.AssetID),
+		Address:    e.Address,
+		Label:      e.Label,
+		Added:      e.Stamp,
+		ActiveTime: e.ActiveStamp,
+		Active:     e.ActiveStamp <= now,
+	}
+}
+
+// AddressBook lists the address book entries, sorted by asset and label.
+func (c *Core) AddressBook() ([]*AddressBookEntry, error) {
+	dbEntries, err := c.db.AddressBook()
+	if err != nil {
+		return nil, codedError(dbErr, err)
+	}
+	now := uint64(time.Now().UnixMilli())
+	entries := make([]*AddressBookEntry, 0, len(dbEntries))
+	for _, e := range dbEntries {
+		entries = append(entries, newAddressBookEntry(e, now))
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].AssetID != entries[j].AssetID {
+			return entries[i].AssetID < entries[j].AssetID
+		}
+		return entries[i].Label < entries[j].Label
+	})
+	return entries, nil
+}
+
+// AddAddressBookEntry adds an address to the address book, or updates the
+// label of an existing entry. New entries can't be used as withdrawal
+// destinations until the withdrawal policy delay has passed.
+func (c *Core) AddAddressBookEntry(pw []byte, assetID uint32, addr, label string) (*AddressBookEntry, error) {
+	if err := c.checkWithdrawalPassword(pw); err != nil {
+		return nil, err
+	}
+	if addr == "" {
+		return nil, newError(addressParseErr, "no address")
+	}
+	if w, found := c.wallet(assetID); found && !w.Wallet.ValidateAddress(addr) {
+		return nil, newError(addressParseErr, "invalid %s address %q", unbip(assetID), addr)
+	}
+	now := uint64(time.Now().UnixMilli())
+	entry := &db.AddressBookEntry{
+		AssetID:     assetID,
+		Address:     addr,
+		Label:       label,
+		Stamp:       now,
+		ActiveStamp: now + uint64(c.withdrawalPolicyDelay().Milliseconds()),
+	}
+	c.withdrawMtx.Lock()
+	defer c.withdrawMtx.Unlock()
+	if existing, err := c.addressBookEntry(assetID, addr); err != nil {
+		return nil, err
+	} else if existing != nil {
+		// Relabeling doesn't restart the delay.
+		entry.Stamp, entry.ActiveStamp = existing.Stamp, existing.ActiveStamp
+	}
+	if err := c.db.StoreAddressBookEntry(entry); err != nil {
+		return nil, codedError(dbErr, err)
+	}
+	return newAddressBookEntry(entry, now), nil
+}
+
+// RemoveAddressBookEntry removes an address from the address book. The
+// address can no longer be used as a withdrawal destination if the asset's
+// withdrawal policy is AllowlistOnly.
+func (c *Core) RemoveAddressBookEntry(pw []byte, assetID uint32, addr string) error {
+	if err := c.checkWithdrawalPassword(pw); err != nil {
+		return err
+	}
+	c.withdrawMtx.Lock()
+	defer c.withdrawMtx.Unlock()
+	if existing, err := c.addressBookEntry(assetID, addr); err != nil {
+		return err
+	} else if existing == nil {
+		return fmt.Errorf("%s address %q is not in the address book", unbip(assetID), addr)
+	}
+	if err := c.db.DeleteAddressBookEntry(assetID, addr); err != nil {
+		return codedError(dbErr, err)
+	}
+	return nil
+}
+
+func (c *Core) addressBookEntry(assetID uint32, addr string) (*db.AddressBookEntry, error) {
+	entries, err := c.db.AddressBook()
+	if err != nil {
+		return nil, codedError(dbErr, err)
+	}
+	for _, e := range entries {
+		if e.AssetID == assetID && e.Address == addr {
+			return e, nil
+		}
+	}
+	return nil, nil
+}
+
+// withdrawalPolicy retrieves the stored withdrawal policy for the asset, with
+// any pending policy that has passed its delay applied. A nil policy is
+// returned if no policy has been set.
+func (c *Core) withdrawalPolicy(assetID uint32, now uint64) (*db.WithdrawalPolicy, error) {
+	policies, err := c.db.WithdrawalPolicies()
+	if err != nil {
+		return nil, codedError(dbErr, err)
+	}
+	for _, p := range policies {
+		if p.AssetID == assetID {
+			return effectiveWithdrawalPolicy(p, now), nil
+		}
+	}
+	return nil, nil
+}
+
+func effectiveWithdrawalPolicy(p *db.WithdrawalPolicy, now uint64) *db.WithdrawalPolicy {
+	if p.Pending == nil || p.Pending.ActiveStamp > now {
+		return p
+	}
+	return &db.WithdrawalPolicy{
+		AssetID:       p.AssetID,
+		AllowlistOnly: p.Pending.AllowlistOnly,
+		DailyLimit:    p.Pending.DailyLimit,
+	}
+}
+
+// WithdrawalPolicies lists the withdrawal policies in effect, with any pending
+// changes, and the amounts withdrawn in the last 24 hours.
+func (c *Core) WithdrawalPolicies() ([]*WithdrawalPolicy, error) {
+	policies, err := c.db.WithdrawalPolicies()
+	if err != nil {
+		return nil, codedError(dbErr, err)
+	}
+	now := time.Now()
+	nowMs := uint64(now.UnixMilli())
+	since := uint64(now.Add(-withdrawalLimitPeriod).UnixMilli())
+	res := make([]*WithdrawalPolicy, 0, len(policies))
+	for _, p := range policies {
+		p = effectiveWithdrawalPolicy(p, nowMs)
+		withdrawn, err := c.db.WithdrawnSince(p.AssetID, since)
+		if err != nil {
+			return nil, codedError(dbErr, err)
+		}
+		policy := &WithdrawalPolicy{
+			AssetID:       p.AssetID,
+			Symbol:        unbip(p.AssetID),
+			AllowlistOnly: p.AllowlistOnly,
+			DailyLimit:    p.DailyLimit,
+			Withdrawn:     withdrawn,
+		}
+		if p.Pending != nil {
+			policy.Pending = &PendingWithdrawalPolicy{
+				AllowlistOnly: p.Pending.AllowlistOnly,
+				DailyLimit:    p.Pending.DailyLimit,
+				ActiveTime:    p.Pending.ActiveStamp,
+			}
+		}
+		res = append(res, policy)
+	}
+	return res, nil
+}
+
+// SetWithdrawalPolicy sets the withdrawal policy for an asset. A policy that is
+// at least as restrictive as the policy in effect applies immediately, and
+// cancels any pending policy. A less restrictive policy, e.g. a higher daily
+// limit, applies after the withdrawal policy delay, and the current policy
+// remains in effect until then. A dailyLimit of zero means no limit.
+func (c *Core) SetWithdrawalPolicy(pw []byte, assetID uint32, allowlistOnly bool, dailyLimit uint64) error {
+	if err := c.checkWithdrawalPassword(pw); err != nil {
+		return err
+	}
+	c.withdrawMtx.Lock()
+	defer c.withdrawMtx.Unlock()
+	now := uint64(time.Now().UnixMilli())
+	current, err := c.withdrawalPolicy(assetID, now)
+	if err != nil {
+		return err
+	}
+	if current == nil {
+		current = &db.WithdrawalPolicy{AssetID: assetID}
+	}
+	stricter := (allowlistOnly || !current.AllowlistOnly) &&
+		(current.DailyLimit == 0 || (dailyLimit > 0 && dailyLimit <= current.DailyLimit))
+	policy := &db.WithdrawalPolicy{
+		AssetID:       assetID,
+		AllowlistOnly: allowlistOnly,
+		DailyLimit:    dailyLimit,
+	}
+	if !stricter {
+		activeStamp := now + uint64(c.withdrawalPolicyDelay().Milliseconds())
+		policy = &db.WithdrawalPolicy{
+			AssetID:       assetID,
+			AllowlistOnly: current.AllowlistOnly,
+			DailyLimit:    current.DailyLimit,
+			Pending: &db.PendingWithdrawalPolicy{
+				AllowlistOnly: allowlistOnly,
+				DailyLimit:    dailyLimit,
+				ActiveStamp:   activeStamp,
+			},
+		}
+		c.log.Infof("New %s withdrawal policy takes effect at %s", unbip(assetID),
+			time.UnixMilli(int64(activeStamp)).Format(time.RFC3339))
+	}
+	if err := c.db.StoreWithdrawalPolicy(policy); err != nil {
+		return codedError(dbErr, err)
+	}
+	return nil
+}
+
+// checkWithdrawal checks that a withdrawal is allowed by the asset's
+// withdrawal policy. The withdrawMtx must be held, and must not be released
+// until the withdrawal is recorded.
+func (c *Core) checkWithdrawal(assetID uint32, value uint64, addr string) error {
+	now := time.Now()
+	policy, err := c.withdrawalPolicy(assetID, uint64(now.UnixMilli()))
+	if err != nil || policy == nil {
+		return err
+	}
+	if policy.AllowlistOnly {
+		entry, err := c.addressBookEntry(assetID, addr)
+		if err != nil {
+			return err
+		}
+		if entry == nil {
+			return newError(withdrawalPolicyErr, "%s address %s is not in the address book", unbip(assetID), addr)
+		}
+		if entry.ActiveStamp > uint64(now.UnixMilli()) {
+			return newError(withdrawalPolicyErr, "%s address %s is not active until %s", unbip(assetID), addr,
+				time.UnixMilli(int64(entry.ActiveStamp)).Format(time.RFC3339))
+		}
+	}
+	if policy.DailyLimit > 0 {
+		withdrawn, err := c.db.WithdrawnSince(assetID, uint64(now.Add(-withdrawalLimitPeriod).UnixMilli()))
+		if err != nil {
+			return codedError(dbErr, err)
+		}
+		if withdrawn+value > policy.DailyLimit {
+			return newError(withdrawalPolicyErr, "withdrawal of %d exceeds the %s daily limit of %d. %d withdrawn in the last 24 hours",
+				value, unbip(assetID), policy.DailyLimit, withdrawn)
+		}
+	}
+	return nil
+}
+
+// recordWithdrawal records a withdrawal for the daily withdrawal limit.
+func (c *Core) recordWithdrawal(assetID uint32, value uint64) {
+	if err := c.db.RecordWithdrawal(assetID, uint64(time.Now().UnixMilli()), value); err != nil {
+		c.log.Errorf("Error recording %s withdrawal: %v", unbip(assetID), err)
+	}
+}
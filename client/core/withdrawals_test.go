@@ -0,0 +1,110 @@
+//go:build !harness && !botlive
+
+package core
+
+import (
+	"testing"
+	"time"
+
+	"decred.org/dcrdex/dex/encode"
+)
+
+func TestWithdrawalPolicy(t *testing.T) {
+	rig := newTestRig()
+	defer rig.shutdown()
+	tCore := rig.core
+	tCore.cfg.WithdrawalPolicyDelay = time.Hour
+	wallet, tWallet := newTWallet(tUTXOAssetA.ID)
+	tCore.wallets[tUTXOAssetA.ID] = wallet
+	tWallet.sendCoin = &tCoin{id: encode.RandomBytes(36)}
+	tWallet.validAddr = true
+	assetID := tUTXOAssetA.ID
+
+	ensurePolicyErr := func(tag string, err error) {
+		t.Helper()
+		if !errorHasCode(err, withdrawalPolicyErr) {
+			t.Fatalf("%s: expected a withdrawal policy error, got %v", tag, err)
+		}
+	}
+	send := func(value uint64, addr string) error {
+		_, err := tCore.Send(tPW, assetID, value, addr, false)
+		return err
+	}
+
+	// No policy.
+	if err := send(1e8, "addr1"); err != nil {
+		t.Fatalf("Send error: %v", err)
+	}
+
+	// A stricter policy applies immediately.
+	if err := tCore.SetWithdrawalPolicy(tPW, assetID, true, 3e8); err != nil {
+		t.Fatalf("SetWithdrawalPolicy error: %v", err)
+	}
+	ensurePolicyErr("not in address book", send(1e8, "addr1"))
+
+	// New entries are delayed.
+	entry, err := tCore.AddAddressBookEntry(tPW, assetID, "addr1", "cold")
+	if err != nil {
+		t.Fatalf("AddAddressBookEntry error: %v", err)
+	}
+	if entry.Active {
+		t.Fatalf("new entry is active")
+	}
+	ensurePolicyErr("inactive address", send(1e8, "addr1"))
+	rig.db.addressBook[0].ActiveStamp = uint64(time.Now().UnixMilli())
+	if err := send(1e8, "addr1"); err != nil {
+		t.Fatalf("Send error: %v", err)
+	}
+	// Relabeling doesn't restart the delay.
+	if entry, _ = tCore.AddAddressBookEntry(tPW, assetID, "addr1", "relabeled"); !entry.Active || entry.Label != "relabeled" {
+		t.Fatalf("relabeled entry not active")
+	}
+
+	// Daily limit. 2e8 withdrawn already, including the send before the
+	// policy was set.
+	ensurePolicyErr("over daily limit", send(2e8, "addr1"))
+	if err := send(1e8, "addr1"); err != nil {
+		t.Fatalf("Send error: %v", err)
+	}
+	ensurePolicyErr("at daily limit", send(1, "addr1"))
+
+	// A less restrictive policy is delayed.
+	if err := tCore.SetWithdrawalPolicy(tPW, assetID, false, 0); err != nil {
+		t.Fatalf("SetWithdrawalPolicy error: %v", err)
+	}
+	policies, err := tCore.WithdrawalPolicies()
+	if err != nil {
+		t.Fatalf("WithdrawalPolicies error: %v", err)
+	}
+	if len(policies) != 1 || !policies[0].AllowlistOnly || policies[0].DailyLimit != 3e8 ||
+		policies[0].Withdrawn != 3e8 || policies[0].Pending == nil {
+		t.Fatalf("wrong policies %+v", policies[0])
+	}
+	ensurePolicyErr("pending policy", send(1, "addr2"))
+	rig.db.withdrawPolicies[assetID].Pending.ActiveStamp = uint64(time.Now().UnixMilli())
+	if err := send(1e8, "addr2"); err != nil {
+		t.Fatalf("Send error: %v", err)
+	}
+
+	// Removing an entry applies immediately.
+	if err := tCore.SetWithdrawalPolicy(tPW, assetID, true, 0); err != nil {
+		t.Fatalf("SetWithdrawalPolicy error: %v", err)
+	}
+	if err := tCore.RemoveAddressBookEntry(tPW, assetID, "addr1"); err != nil {
+		t.Fatalf("RemoveAddressBookEntry error: %v", err)
+	}
+	ensurePolicyErr("removed address", send(1, "addr1"))
+	if err := tCore.RemoveAddressBookEntry(tPW, assetID, "addr1"); err == nil {
+		t.Fatalf("no error removing unknown entry")
+	}
+
+	// Bad address and password.
+	tWallet.validAddr = false
+	if _, err := tCore.AddAddressBookEntry(tPW, assetID, "bad", ""); err == nil {
+		t.Fatalf("no error for invalid address")
+	}
+	rig.crypter.(*tCrypter).recryptErr = tErr
+	if err := tCore.SetWithdrawalPolicy(tPW, assetID, false, 0); !errorHasCode(err, passwordErr) {
+		t.Fatalf("no error for wrong password")
+	}
+}
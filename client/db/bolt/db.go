@@ -12,6 +12,7 @@ import (
 	"io/fs"
 	"os"
 	"path/filepath"
+	"slices"
 	"sort"
 	"strings"
 	"time"
@@ -68,6 +69,13 @@ var (
 	notesBucket           = []byte("notes")
 	pokesBucket           = []byte("pokes")
 	credentialsBucket     = []byte("credentials")
+	apiKeysBucket         = []byte("apiKeys")
+	addressBookBucket     = []byte("addressBook")
+	withdrawPolicyBucket  = []byte("withdrawPolicies")
+	withdrawalsBucket     = []byte("withdrawals")
+	condOrdersBucket      = []byte("conditionalOrders")
+	managedOrdersBucket   = []byte("managedOrders")
+	meshHostsBucket       = []byte("meshHosts")
 
 	// value keys
 	versionKey            = []byte("version")
@@ -182,7 +190,9 @@ func NewDB(dbPath string, logger dex.Logger, opts ...Opts) (dexdb.DB, error) {
 		activeOrdersBucket, archivedOrdersBucket,
 		activeMatchesBucket, archivedMatchesBucket,
 		walletsBucket, notesBucket, credentialsBucket,
-		botProgramsBucket, pokesBucket,
+		botProgramsBucket, pokesBucket, apiKeysBucket, addressBookBucket,
+		withdrawPolicyBucket, withdrawalsBucket, condOrdersBucket,
+		managedOrdersBucket, meshHostsBucket,
 	}); err != nil {
 		return nil, err
 	}
@@ -391,6 +401,26 @@ func (db *BoltDB) Recrypt(creds *dexdb.PrimaryCredentials, oldCrypter, newCrypte
 			return fmt.Errorf("accounts update error: %w", err)
 		}
 
+		// API keys.
+		if apiKeys := tx.Bucket(apiKeysBucket); apiKeys != nil {
+			encKeys := make(map[string][]byte)
+			if err := apiKeys.ForEach(func(id, encKey []byte) error {
+				b, err := oldCrypter.Decrypt(encKey)
+				if err != nil {
+					return err
+				}
+				encKeys[string(id)], err = newCrypter.Encrypt(b)
+				return err
+			}); err != nil {
+				return fmt.Errorf("api keys update error: %w", err)
+			}
+			for id, encKey := range encKeys {
+				if err := apiKeys.Put([]byte(id), encKey); err != nil {
+					return err
+				}
+			}
+		}
+
 		// Store the new credentials.
 		return db.setCreds(tx, creds)
 	})
@@ -2405,6 +2435,186 @@ func (db *BoltDB) DeleteInactiveMatches(ctx context.Context, olderThan *time.Tim
 	return nDeletedMatches, nil
 }
 
+// PruneOrders deletes archived orders and their archived matches that are
+// older than olderThan, or that are not among the keepPerMarket most recent
+// archived orders of their market. A nil olderThan or a zero keepPerMarket
+// disables that part of the policy. Orders with active matches are not
+// deleted. A cancel order is deleted with its linked trade. The optional
+// perOrderFn is called for each order and its matches before the deletion is
+// committed.
+func (db *BoltDB) PruneOrders(ctx context.Context, olderThan *time.Time, keepPerMarket int,
+	perOrderFn func(ord *dexdb.MetaOrder, matches []*dexdb.MetaMatch) error) (int, error) {
+	const batchSize = 1000
+	if (olderThan == nil || olderThan.IsZero()) && keepPerMarket <= 0 {
+		return 0, nil
+	}
+
+	type candidate struct {
+		key    []byte
+		stamp  uint64
+		market string
+	}
+	var candidates []*candidate
+	matchKeys := make(map[order.OrderID][][]byte)
+	if err := db.View(func(tx *bbolt.Tx) error {
+		// Orders with active matches are still needed.
+		activeMatchOrders := make(map[order.OrderID]struct{})
+		amb := tx.Bucket(activeMatchesBucket)
+		if err := amb.ForEach(func(k, _ []byte) error {
+			mBkt := amb.Bucket(k)
+			if mBkt == nil {
+				return fmt.Errorf("match %x bucket is not a bucket", k)
+			}
+			var oid order.OrderID
+			copy(oid[:], mBkt.Get(orderIDKey))
+			activeMatchOrders[oid] = struct{}{}
+			return nil
+		}); err != nil {
+			return fmt.Errorf("unable to get active matches: %v", err)
+		}
+
+		archivedMB := tx.Bucket(archivedMatchesBucket)
+		if err := archivedMB.ForEach(func(k, _ []byte) error {
+			mBkt := archivedMB.Bucket(k)
+			if mBkt == nil {
+				return fmt.Errorf("match %x bucket is not a bucket", k)
+			}
+			var oid order.OrderID
+			copy(oid[:], mBkt.Get(orderIDKey))
+			matchKeys[oid] = append(matchKeys[oid], bytes.Clone(k))
+			return nil
+		}); err != nil {
+			return fmt.Errorf("unable to get archived matches: %v", err)
+		}
+
+		archivedOB := tx.Bucket(archivedOrdersBucket)
+		return archivedOB.ForEach(func(k, _ []byte) error {
+			oBkt := archivedOB.Bucket(k)
+			if oBkt == nil {
+				return fmt.Errorf("order %x bucket is not a bucket", k)
+			}
+			var oid order.OrderID
+			copy(oid[:], k)
+			if _, has := activeMatchOrders[oid]; has {
+				return nil
+			}
+			if order.OrderStatus(intCoder.Uint16(oBkt.Get(statusKey))).IsActive() {
+				return nil
+			}
+			if oTypeB := oBkt.Get(typeKey); len(oTypeB) == 1 && order.OrderType(oTypeB[0]) == order.CancelOrderType {
+				return nil
+			}
+			candidates = append(candidates, &candidate{
+				key:    bytes.Clone(k),
+				stamp:  intCoder.Uint64(oBkt.Get(updateTimeKey)),
+				market: string(oBkt.Get(dexKey)) + string(oBkt.Get(baseKey)) + string(oBkt.Get(quoteKey)),
+			})
+			return nil
+		})
+	}); err != nil {
+		return 0, err
+	}
+
+	// Newest first, so that the first keepPerMarket orders of each market
+	// are kept.
+	sort.Slice(candidates, func(i, j int) bool {
+		return candidates[i].stamp > candidates[j].stamp
+	})
+	var olderThanMs uint64
+	if olderThan != nil && !olderThan.IsZero() {
+		olderThanMs = uint64(olderThan.UnixMilli())
+	}
+	var keys [][]byte
+	marketCounts := make(map[string]int)
+	for _, c := range candidates {
+		marketCounts[c.market]++
+		if (olderThanMs > 0 && c.stamp < olderThanMs) || (keepPerMarket > 0 && marketCounts[c.market] > keepPerMarket) {
+			keys = append(keys, c.key)
+		}
+	}
+	// Delete the oldest first, so that perOrderFn sees the orders in the order
+	// they were archived.
+	slices.Reverse(keys)
+
+	nDeletedOrders := 0
+	start := time.Now()
+	for i := 0; i < len(keys); i += batchSize {
+		if err := ctx.Err(); err != nil {
+			return 0, err
+		}
+
+		end := i + batchSize
+		if end > len(keys) {
+			end = len(keys)
+		}
+
+		nDeletedBatch := 0
+		err := db.Update(func(tx *bbolt.Tx) error {
+			archivedOB := tx.Bucket(archivedOrdersBucket)
+			archivedMB := tx.Bucket(archivedMatchesBucket)
+			var deleteOrder func(key []byte) error
+			deleteOrder = func(key []byte) error {
+				oBkt := archivedOB.Bucket(key)
+				if oBkt == nil {
+					return nil
+				}
+				o, err := decodeOrderBucket(key, oBkt)
+				if err != nil {
+					return fmt.Errorf("failed to decode order bucket: %v", err)
+				}
+				var oid order.OrderID
+				copy(oid[:], key)
+				matches := make([]*dexdb.MetaMatch, 0, len(matchKeys[oid]))
+				for _, mk := range matchKeys[oid] {
+					mBkt := archivedMB.Bucket(mk)
+					if mBkt == nil {
+						continue
+					}
+					m, err := loadMatchBucket(mBkt, false)
+					if err != nil {
+						return fmt.Errorf("failed to load match bucket: %v", err)
+					}
+					matches = append(matches, m)
+					if err := archivedMB.DeleteBucket(mk); err != nil {
+						return fmt.Errorf("failed to delete match bucket: %v", err)
+					}
+				}
+				if perOrderFn != nil {
+					if err := perOrderFn(o, matches); err != nil {
+						return fmt.Errorf("problem performing batch function: %v", err)
+					}
+				}
+				if err := archivedOB.DeleteBucket(key); err != nil {
+					return fmt.Errorf("failed to delete order bucket: %v", err)
+				}
+				nDeletedBatch++
+				if linked := o.MetaData.LinkedOrder; !linked.IsZero() && o.Order.Type() != order.CancelOrderType {
+					return deleteOrder(linked[:])
+				}
+				return nil
+			}
+			for _, key := range keys[i:end] {
+				if err := deleteOrder(key); err != nil {
+					return err
+				}
+			}
+			nDeletedOrders += nDeletedBatch
+			return nil
+		})
+		if err != nil {
+			if perOrderFn != nil && nDeletedBatch != 0 {
+				db.log.Warnf("%d orders reported as pruned have been rolled back due to error.", nDeletedBatch)
+			}
+			return 0, fmt.Errorf("unable to prune orders: %v", err)
+		}
+	}
+
+	db.log.Infof("Pruned %d archived orders from the database in %v",
+		nDeletedOrders, time.Since(start))
+
+	return nDeletedOrders, nil
+}
+
 // SaveDisabledRateSources updates disabled fiat rate sources.
 func (db *BoltDB) SaveDisabledRateSources(disabledSources []string) error {
 	return db.Update(func(tx *bbolt.Tx) error {
@@ -2463,6 +2673,280 @@ func (db *BoltDB) Language() (lang string, _ error) {
 	})
 }
 
+// StoreAPIKey stores the encrypted API key, replacing any existing key with
+// the same ID.
+func (db *BoltDB) StoreAPIKey(id string, encKey []byte) error {
+	return db.Update(func(dbTx *bbolt.Tx) error {
+		bkt := dbTx.Bucket(apiKeysBucket)
+		if bkt == nil {
+			return fmt.Errorf("api keys bucket not found")
+		}
+		return bkt.Put([]byte(id), encKey)
+	})
+}
+
+// APIKeys retrieves the encrypted API keys, keyed by ID.
+func (db *BoltDB) APIKeys() (map[string][]byte, error) {
+	keys := make(map[string][]byte)
+	return keys, db.View(func(dbTx *bbolt.Tx) error {
+		bkt := dbTx.Bucket(apiKeysBucket)
+		if bkt == nil {
+			return fmt.Errorf("api keys bucket not found")
+		}
+		return bkt.ForEach(func(id, encKey []byte) error {
+			keys[string(id)] = bytes.Clone(encKey)
+			return nil
+		})
+	})
+}
+
+// DeleteAPIKey deletes the API key. It is not an error if the key does not
+// exist.
+func (db *BoltDB) DeleteAPIKey(id string) error {
+	return db.Update(func(dbTx *bbolt.Tx) error {
+		bkt := dbTx.Bucket(apiKeysBucket)
+		if bkt == nil {
+			return fmt.Errorf("api keys bucket not found")
+		}
+		return bkt.Delete([]byte(id))
+	})
+}
+
+// addressBookKey is the asset ID followed by the address.
+func addressBookKey(assetID uint32, addr string) []byte {
+	return append(uint32Bytes(assetID), addr...)
+}
+
+// StoreAddressBookEntry stores the address book entry, replacing any existing
+// entry for the same asset and address.
+func (db *BoltDB) StoreAddressBookEntry(entry *dexdb.AddressBookEntry) error {
+	return db.Update(func(dbTx *bbolt.Tx) error {
+		bkt := dbTx.Bucket(addressBookBucket)
+		if bkt == nil {
+			return fmt.Errorf("address book bucket not found")
+		}
+		return bkt.Put(addressBookKey(entry.AssetID, entry.Address), entry.Encode())
+	})
+}
+
+// AddressBook retrieves all address book entries.
+func (db *BoltDB) AddressBook() ([]*dexdb.AddressBookEntry, error) {
+	var entries []*dexdb.AddressBookEntry
+	return entries, db.View(func(dbTx *bbolt.Tx) error {
+		bkt := dbTx.Bucket(addressBookBucket)
+		if bkt == nil {
+			return fmt.Errorf("address book bucket not found")
+		}
+		return bkt.ForEach(func(k, v []byte) error {
+			entry, err := dexdb.DecodeAddressBookEntry(v)
+			if err != nil {
+				return fmt.Errorf("error decoding address book entry %x: %w", k, err)
+			}
+			entries = append(entries, entry)
+			return nil
+		})
+	})
+}
+
+// DeleteAddressBookEntry deletes the address book entry.
+func (db *BoltDB) DeleteAddressBookEntry(assetID uint32, addr string) error {
+	return db.Update(func(dbTx *bbolt.Tx) error {
+		bkt := dbTx.Bucket(addressBookBucket)
+		if bkt == nil {
+			return fmt.Errorf("address book bucket not found")
+		}
+		return bkt.Delete(addressBookKey(assetID, addr))
+	})
+}
+
+// StoreWithdrawalPolicy stores the asset's withdrawal policy.
+func (db *BoltDB) StoreWithdrawalPolicy(policy *dexdb.WithdrawalPolicy) error {
+	return db.Update(func(dbTx *bbolt.Tx) error {
+		bkt := dbTx.Bucket(withdrawPolicyBucket)
+		if bkt == nil {
+			return fmt.Errorf("withdrawal policies bucket not found")
+		}
+		return bkt.Put(uint32Bytes(policy.AssetID), policy.Encode())
+	})
+}
+
+// WithdrawalPolicies retrieves the withdrawal policies of all assets.
+func (db *BoltDB) WithdrawalPolicies() ([]*dexdb.WithdrawalPolicy, error) {
+	var policies []*dexdb.WithdrawalPolicy
+	return policies, db.View(func(dbTx *bbolt.Tx) error {
+		bkt := dbTx.Bucket(withdrawPolicyBucket)
+		if bkt == nil {
+			return fmt.Errorf("withdrawal policies bucket not found")
+		}
+		return bkt.ForEach(func(k, v []byte) error {
+			policy, err := dexdb.DecodeWithdrawalPolicy(v)
+			if err != nil {
+				return fmt.Errorf("error decoding withdrawal policy %x: %w", k, err)
+			}
+			policies = append(policies, policy)
+			return nil
+		})
+	})
+}
+
+// withdrawalRecordExpiry is how long withdrawal records are kept.
+const withdrawalRecordExpiry = 7 * 24 * time.Hour
+
+// RecordWithdrawal records an amount withdrawn. Records are keyed by asset ID,
+// time stamp, and a sequence number, and expired records are pruned.
+func (db *BoltDB) RecordWithdrawal(assetID uint32, stamp, value uint64) error {
+	return db.Update(func(dbTx *bbolt.Tx) error {
+		bkt := dbTx.Bucket(withdrawalsBucket)
+		if bkt == nil {
+			return fmt.Errorf("withdrawals bucket not found")
+		}
+		seq, err := bkt.NextSequence()
+		if err != nil {
+			return err
+		}
+		prefix := uint32Bytes(assetID)
+		expiry := uint64Bytes(stamp - uint64(withdrawalRecordExpiry.Milliseconds()))
+		// Deleting with the cursor while iterating skips keys, so collect the
+		// expired keys first.
+		var expired [][]byte
+		c := bkt.Cursor()
+		for k, _ := c.Seek(prefix); k != nil && bytes.HasPrefix(k, prefix); k, _ = c.Next() {
+			if bytes.Compare(k[4:12], expiry) >= 0 {
+				break
+			}
+			expired = append(expired, bytes.Clone(k))
+		}
+		for _, k := range expired {
+			if err := bkt.Delete(k); err != nil {
+				return err
+			}
+		}
+		k := append(append(prefix, uint64Bytes(stamp)...), uint64Bytes(seq)...)
+		return bkt.Put(k, uint64Bytes(value))
+	})
+}
+
+// WithdrawnSince sums the amounts of the asset withdrawn since the time, in
+// milliseconds.
+func (db *BoltDB) WithdrawnSince(assetID uint32, since uint64) (total uint64, _ error) {
+	return total, db.View(func(dbTx *bbolt.Tx) error {
+		bkt := dbTx.Bucket(withdrawalsBucket)
+		if bkt == nil {
+			return fmt.Errorf("withdrawals bucket not found")
+		}
+		prefix := uint32Bytes(assetID)
+		c := bkt.Cursor()
+		for k, v := c.Seek(append(prefix, uint64Bytes(since)...)); k != nil && bytes.HasPrefix(k, prefix); k, v = c.Next() {
+			total += intCoder.Uint64(v)
+		}
+		return nil
+	})
+}
+
+// StoreConditionalOrder stores the conditional order, replacing any existing
+// order with the same ID.
+func (db *BoltDB) StoreConditionalOrder(ord *dexdb.ConditionalOrder) error {
+	return db.Update(func(dbTx *bbolt.Tx) error {
+		bkt := dbTx.Bucket(condOrdersBucket)
+		if bkt == nil {
+			return fmt.Errorf("conditional orders bucket not found")
+		}
+		return bkt.Put([]byte(ord.ID), ord.Encode())
+	})
+}
+
+// ConditionalOrders retrieves all conditional orders.
+func (db *BoltDB) ConditionalOrders() ([]*dexdb.ConditionalOrder, error) {
+	var ords []*dexdb.ConditionalOrder
+	return ords, db.View(func(dbTx *bbolt.Tx) error {
+		bkt := dbTx.Bucket(condOrdersBucket)
+		if bkt == nil {
+			return fmt.Errorf("conditional orders bucket not found")
+		}
+		return bkt.ForEach(func(k, v []byte) error {
+			ord, err := dexdb.DecodeConditionalOrder(v)
+			if err != nil {
+				return fmt.Errorf("error decoding conditional order %s: %w", string(k), err)
+			}
+			ords = append(ords, ord)
+			return nil
+		})
+	})
+}
+
+// DeleteConditionalOrder deletes the conditional order.
+func (db *BoltDB) DeleteConditionalOrder(id string) error {
+	return db.Update(func(dbTx *bbolt.Tx) error {
+		bkt := dbTx.Bucket(condOrdersBucket)
+		if bkt == nil {
+			return fmt.Errorf("conditional orders bucket not found")
+		}
+		return bkt.Delete([]byte(id))
+	})
+}
+
+// StoreManagedOrder stores the managed order, replacing any existing order
+// with the same ID.
+func (db *BoltDB) StoreManagedOrder(ord *dexdb.ManagedOrder) error {
+	return db.Update(func(dbTx *bbolt.Tx) error {
+		bkt := dbTx.Bucket(managedOrdersBucket)
+		if bkt == nil {
+			return fmt.Errorf("managed orders bucket not found")
+		}
+		return bkt.Put([]byte(ord.ID), ord.Encode())
+	})
+}
+
+// ManagedOrders retrieves all managed orders.
+func (db *BoltDB) ManagedOrders() ([]*dexdb.ManagedOrder, error) {
+	var ords []*dexdb.ManagedOrder
+	return ords, db.View(func(dbTx *bbolt.Tx) error {
+		bkt := dbTx.Bucket(managedOrdersBucket)
+		if bkt == nil {
+			return fmt.Errorf("managed orders bucket not found")
+		}
+		return bkt.ForEach(func(k, v []byte) error {
+			ord, err := dexdb.DecodeManagedOrder(v)
+			if err != nil {
+				return fmt.Errorf("error decoding managed order %s: %w", string(k), err)
+			}
+			ords = append(ords, ord)
+			return nil
+		})
+	})
+}
+
+// StoreMeshHost stores the Tatanka mesh host, replacing any existing host with
+// the same address.
+func (db *BoltDB) StoreMeshHost(h *dexdb.MeshHost) error {
+	return db.Update(func(dbTx *bbolt.Tx) error {
+		bkt := dbTx.Bucket(meshHostsBucket)
+		if bkt == nil {
+			return fmt.Errorf("mesh hosts bucket not found")
+		}
+		return bkt.Put([]byte(h.Addr), h.Encode())
+	})
+}
+
+// MeshHosts retrieves all Tatanka mesh hosts.
+func (db *BoltDB) MeshHosts() ([]*dexdb.MeshHost, error) {
+	var hosts []*dexdb.MeshHost
+	return hosts, db.View(func(dbTx *bbolt.Tx) error {
+		bkt := dbTx.Bucket(meshHostsBucket)
+		if bkt == nil {
+			return fmt.Errorf("mesh hosts bucket not found")
+		}
+		return bkt.ForEach(func(k, v []byte) error {
+			h, err := dexdb.DecodeMeshHost(v)
+			if err != nil {
+				return fmt.Errorf("error decoding mesh host %s: %w", string(k), err)
+			}
+			hosts = append(hosts, h)
+			return nil
+		})
+	})
+}
+
 // timeNow is the current unix timestamp in milliseconds.
 func timeNow() uint64 {
 	return uint64(time.Now().UnixMilli())
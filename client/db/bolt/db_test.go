@@ -8,6 +8,7 @@ import (
 	"math/rand"
 	"os"
 	"path/filepath"
+	"reflect"
 	"sync"
 	"testing"
 	"time"
@@ -1339,6 +1340,153 @@ func TestDeleteInactiveMatches(t *testing.T) {
 	}
 }
 
+func TestPruneOrders(t *testing.T) {
+	boltdb, shutdown := newTestDB(t)
+	defer shutdown()
+	ctx := context.Background()
+
+	acct := dbtest.RandomAccountInfo()
+	if err := boltdb.CreateAccount(acct); err != nil {
+		t.Fatalf("CreateAccount error: %v", err)
+	}
+	base1, quote1 := randU32(), randU32()
+	base2, quote2 := randU32(), randU32()
+
+	newOrder := func(base, quote uint32, status order.OrderStatus) *db.MetaOrder {
+		t.Helper()
+		lo, _ := ordertest.RandomLimitOrder()
+		lo.BaseAsset, lo.QuoteAsset = base, quote
+		mo := &db.MetaOrder{
+			MetaData: &db.OrderMetaData{
+				Status: status,
+				Host:   acct.Host,
+				Proof:  db.OrderProof{DEXSig: randBytes(73)},
+			},
+			Order: lo,
+		}
+		if err := boltdb.UpdateOrder(mo); err != nil {
+			t.Fatalf("error inserting order: %v", err)
+		}
+		time.Sleep(2 * time.Millisecond) // distinct update times
+		return mo
+	}
+	newMatch := func(oid order.OrderID, active bool) *db.MetaMatch {
+		t.Helper()
+		m := &db.MetaMatch{
+			MetaData: &db.MatchMetaData{
+				Proof: *dbtest.RandomMatchProof(0.5),
+				DEX:   acct.Host,
+				Base:  base1,
+				Quote: quote1,
+			},
+			UserMatch: ordertest.RandomUserMatch(),
+		}
+		m.OrderID = oid
+		if active {
+			m.Status = order.MakerSwapCast
+			m.MetaData.Proof.RefundCoin = nil
+			m.MetaData.Proof.SelfRevoked = false
+			m.MetaData.Proof.ServerRevoked = false
+		} else {
+			m.Status = order.MatchConfirmed
+		}
+		if err := boltdb.UpdateMatch(m); err != nil {
+			t.Fatalf("error inserting match: %v", err)
+		}
+		return m
+	}
+
+	// Market 1, oldest first. The oldest has an active match.
+	withActiveMatch := newOrder(base1, quote1, order.OrderStatusExecuted)
+	newMatch(withActiveMatch.Order.ID(), true)
+	withMatch := newOrder(base1, quote1, order.OrderStatusExecuted)
+	archivedMatch := newMatch(withMatch.Order.ID(), false)
+	canceled := newOrder(base1, quote1, order.OrderStatusCanceled)
+	co, _ := ordertest.RandomCancelOrder()
+	co.BaseAsset, co.QuoteAsset, co.TargetOrderID = base1, quote1, canceled.Order.ID()
+	cancelOrd := &db.MetaOrder{
+		MetaData: &db.OrderMetaData{
+			Status:      order.OrderStatusExecuted,
+			Host:        acct.Host,
+			Proof:       db.OrderProof{DEXSig: randBytes(73)},
+			LinkedOrder: canceled.Order.ID(),
+		},
+		Order: co,
+	}
+	if err := boltdb.UpdateOrder(cancelOrd); err != nil {
+		t.Fatalf("error inserting cancel order: %v", err)
+	}
+	if err := boltdb.LinkOrder(canceled.Order.ID(), co.ID()); err != nil {
+		t.Fatalf("LinkOrder error: %v", err)
+	}
+	newOrder(base1, quote1, order.OrderStatusRevoked)
+	newOrder(base1, quote1, order.OrderStatusExecuted)
+	active := newOrder(base1, quote1, order.OrderStatusBooked)
+	// Market 2.
+	newOrder(base2, quote2, order.OrderStatusExecuted)
+	newOrder(base2, quote2, order.OrderStatusExecuted)
+
+	pruned := make(map[order.OrderID][]*db.MetaMatch)
+	perOrderFn := func(ord *db.MetaOrder, matches []*db.MetaMatch) error {
+		pruned[ord.Order.ID()] = matches
+		return nil
+	}
+	countOrders := func() int {
+		t.Helper()
+		ords, err := boltdb.Orders(&db.OrderFilter{})
+		if err != nil {
+			t.Fatalf("Orders error: %v", err)
+		}
+		return len(ords)
+	}
+
+	// Nothing to do without a policy.
+	if n, err := boltdb.PruneOrders(ctx, nil, 0, perOrderFn); err != nil || n != 0 {
+		t.Fatalf("pruned %d orders without a policy, err = %v", n, err)
+	}
+
+	// Keep the 2 most recent inactive orders of each market. The order with
+	// an active match is not a candidate, so the canceled order, its cancel
+	// order, and the order with the archived match are pruned.
+	n, err := boltdb.PruneOrders(ctx, nil, 2, perOrderFn)
+	if err != nil {
+		t.Fatalf("PruneOrders error: %v", err)
+	}
+	if n != 3 || len(pruned) != 3 {
+		t.Fatalf("expected 3 orders pruned, got %d (%d)", n, len(pruned))
+	}
+	if _, found := pruned[co.ID()]; !found {
+		t.Fatalf("linked cancel order not pruned")
+	}
+	if ms := pruned[withMatch.Order.ID()]; len(ms) != 1 || ms[0].MatchID != archivedMatch.MatchID {
+		t.Fatalf("archived match not pruned with order")
+	}
+	if ms, err := boltdb.MatchesForOrder(withMatch.Order.ID(), false); err != nil || len(ms) != 0 {
+		t.Fatalf("archived match not deleted. err = %v", err)
+	}
+	if n := countOrders(); n != 6 {
+		t.Fatalf("expected 6 orders remaining, got %d", n)
+	}
+
+	// Prune everything older than now.
+	now := time.Now()
+	if n, err = boltdb.PruneOrders(ctx, &now, 0, nil); err != nil || n != 4 {
+		t.Fatalf("expected 4 orders pruned by age, got %d, err = %v", n, err)
+	}
+	ords, err := boltdb.Orders(&db.OrderFilter{})
+	if err != nil {
+		t.Fatalf("Orders error: %v", err)
+	}
+	if len(ords) != 2 {
+		t.Fatalf("expected 2 orders remaining, got %d", len(ords))
+	}
+	for _, mo := range ords {
+		if oid := mo.Order.ID(); oid != active.Order.ID() && oid != withActiveMatch.Order.ID() {
+			t.Fatalf("unexpected order %s remaining", oid)
+		}
+	}
+}
+
 func TestDeleteInactiveOrders(t *testing.T) {
 	boltdb, shutdown := newTestDB(t)
 	defer shutdown()
@@ -1613,3 +1761,239 @@ func TestPokes(t *testing.T) {
 		t.Fatal("Result from second LoadPokes wasn't empty")
 	}
 }
+
+func TestAPIKeys(t *testing.T) {
+	boltdb, shutdown := newTestDB(t)
+	defer shutdown()
+
+	keys := map[string][]byte{"a": randBytes(10), "b": randBytes(10)}
+	for id, encKey := range keys {
+		if err := boltdb.StoreAPIKey(id, encKey); err != nil {
+			t.Fatalf("StoreAPIKey error: %v", err)
+		}
+	}
+	reKeys, err := boltdb.APIKeys()
+	if err != nil {
+		t.Fatalf("APIKeys error: %v", err)
+	}
+	if len(reKeys) != 2 || !bytes.Equal(reKeys["a"], keys["a"]) || !bytes.Equal(reKeys["b"], keys["b"]) {
+		t.Fatalf("wrong keys loaded")
+	}
+
+	if err := boltdb.DeleteAPIKey("a"); err != nil {
+		t.Fatalf("DeleteAPIKey error: %v", err)
+	}
+	if reKeys, _ = boltdb.APIKeys(); len(reKeys) != 1 || reKeys["a"] != nil {
+		t.Fatalf("key not deleted")
+	}
+}
+
+func TestWithdrawalPolicies(t *testing.T) {
+	boltdb, shutdown := newTestDB(t)
+	defer shutdown()
+
+	entries := []*db.AddressBookEntry{
+		{AssetID: 42, Address: "Dsaddr1", Label: "cold", Stamp: 1, ActiveStamp: 2},
+		{AssetID: 42, Address: "Dsaddr2", Label: "exchange", Stamp: 3, ActiveStamp: 4},
+		{AssetID: 0, Address: "bc1addr", Stamp: 5, ActiveStamp: 6},
+	}
+	for _, e := range entries {
+		if err := boltdb.StoreAddressBookEntry(e); err != nil {
+			t.Fatalf("StoreAddressBookEntry error: %v", err)
+		}
+	}
+	reEntries, err := boltdb.AddressBook()
+	if err != nil {
+		t.Fatalf("AddressBook error: %v", err)
+	}
+	if len(reEntries) != 3 {
+		t.Fatalf("expected 3 address book entries, got %d", len(reEntries))
+	}
+	for _, re := range reEntries {
+		if re.Address == entries[1].Address && !reflect.DeepEqual(re, entries[1]) {
+			t.Fatalf("wrong entry decoded. %+v != %+v", re, entries[1])
+		}
+	}
+	if err := boltdb.DeleteAddressBookEntry(42, "Dsaddr1"); err != nil {
+		t.Fatalf("DeleteAddressBookEntry error: %v", err)
+	}
+	if reEntries, _ = boltdb.AddressBook(); len(reEntries) != 2 {
+		t.Fatalf("entry not deleted")
+	}
+
+	policies := []*db.WithdrawalPolicy{
+		{AssetID: 42, AllowlistOnly: true, DailyLimit: 1e8},
+		{AssetID: 0, DailyLimit: 1e6, Pending: &db.PendingWithdrawalPolicy{DailyLimit: 2e6, ActiveStamp: 10}},
+	}
+	for _, p := range policies {
+		if err := boltdb.StoreWithdrawalPolicy(p); err != nil {
+			t.Fatalf("StoreWithdrawalPolicy error: %v", err)
+		}
+	}
+	rePolicies, err := boltdb.WithdrawalPolicies()
+	if err != nil {
+		t.Fatalf("WithdrawalPolicies error: %v", err)
+	}
+	if len(rePolicies) != 2 {
+		t.Fatalf("expected 2 policies, got %d", len(rePolicies))
+	}
+	// Sorted by asset ID key.
+	if !reflect.DeepEqual(rePolicies[0], policies[1]) || !reflect.DeepEqual(rePolicies[1], policies[0]) {
+		t.Fatalf("wrong policies decoded")
+	}
+
+	now := uint64(time.Now().UnixMilli())
+	old := now - uint64(withdrawalRecordExpiry.Milliseconds()) - 1
+	for _, r := range []struct {
+		assetID      uint32
+		stamp, value uint64
+	}{{42, old - 1, 1}, {42, old, 1}, {42, now - 10, 2}, {42, now - 10, 3}, {42, now, 4}, {0, now, 5}} {
+		if err := boltdb.RecordWithdrawal(r.assetID, r.stamp, r.value); err != nil {
+			t.Fatalf("RecordWithdrawal error: %v", err)
+		}
+	}
+	for _, tt := range []struct {
+		assetID uint32
+		since   uint64
+		want    uint64
+	}{{42, 0, 9}, {42, now - 10, 9}, {42, now - 9, 4}, {0, 0, 5}, {60, 0, 0}} {
+		total, err := boltdb.WithdrawnSince(tt.assetID, tt.since)
+		if err != nil {
+			t.Fatalf("WithdrawnSince error: %v", err)
+		}
+		if total != tt.want {
+			t.Fatalf("asset %d since %d: wanted %d, got %d", tt.assetID, tt.since, tt.want, total)
+		}
+	}
+}
+
+func TestConditionalOrders(t *testing.T) {
+	boltdb, shutdown := newTestDB(t)
+	defer shutdown()
+
+	ords := []*db.ConditionalOrder{{
+		ID:          "a",
+		Host:        "dex.example.com",
+		Base:        42,
+		Quote:       0,
+		Type:        "stoploss",
+		Sell:        true,
+		Qty:         1e8,
+		TriggerRate: 5e5,
+		Stamp:       1,
+		Status:      "active",
+	}, {
+		ID:       "b",
+		Host:     "dex.example.com",
+		Base:     42,
+		Type:     "trailingstop",
+		Qty:      2e8,
+		IsLimit:  true,
+		Rate:     6e5,
+		TrailPPM: 5e4,
+		Extreme:  4e5,
+		Stamp:    2,
+		Status:   "failed",
+		OrderID:  randBytes(32),
+		Error:    "insufficient funds",
+	}}
+	for _, ord := range ords {
+		if err := boltdb.StoreConditionalOrder(ord); err != nil {
+			t.Fatalf("StoreConditionalOrder error: %v", err)
+		}
+	}
+	reOrds, err := boltdb.ConditionalOrders()
+	if err != nil {
+		t.Fatalf("ConditionalOrders error: %v", err)
+	}
+	if len(reOrds) != 2 || !reflect.DeepEqual(reOrds[0], ords[0]) || !reflect.DeepEqual(reOrds[1], ords[1]) {
+		t.Fatalf("wrong conditional orders decoded")
+	}
+	if err := boltdb.DeleteConditionalOrder("a"); err != nil {
+		t.Fatalf("DeleteConditionalOrder error: %v", err)
+	}
+	if reOrds, _ = boltdb.ConditionalOrders(); len(reOrds) != 1 || reOrds[0].ID != "b" {
+		t.Fatalf("conditional order not deleted")
+	}
+}
+
+func TestManagedOrders(t *testing.T) {
+	boltdb, shutdown := newTestDB(t)
+	defer shutdown()
+
+	var oid1, oid2 order.OrderID
+	copy(oid1[:], randBytes(32))
+	copy(oid2[:], randBytes(32))
+	ords := []*db.ManagedOrder{{
+		ID:       "a",
+		Host:     "dex.example.com",
+		Base:     42,
+		Type:     "twap",
+		Sell:     true,
+		Qty:      10e8,
+		Rate:     5e5,
+		Slices:   10,
+		Duration: 3600e3,
+		Stamp:    1,
+		Status:   "active",
+		Filled:   1e8,
+		Placed:   2,
+		Children: []order.OrderID{oid1, oid2},
+		Active:   true,
+	}, {
+		ID:     "b",
+		Host:   "dex.example.com",
+		Base:   42,
+		Type:   "iceberg",
+		Qty:    5e8,
+		Rate:   6e5,
+		Clip:   1e8,
+		Stamp:  2,
+		Status: "failed",
+		Error:  "insufficient funds",
+	}}
+	for _, ord := range ords {
+		if err := boltdb.StoreManagedOrder(ord); err != nil {
+			t.Fatalf("StoreManagedOrder error: %v", err)
+		}
+	}
+	reOrds, err := boltdb.ManagedOrders()
+	if err != nil {
+		t.Fatalf("ManagedOrders error: %v", err)
+	}
+	if len(reOrds) != 2 || !reflect.DeepEqual(reOrds[0], ords[0]) || !reflect.DeepEqual(reOrds[1], ords[1]) {
+		t.Fatalf("wrong managed orders decoded")
+	}
+}
+
+func TestMeshHosts(t *testing.T) {
+	boltdb, shutdown := newTestDB(t)
+	defer shutdown()
+
+	hosts := []*db.MeshHost{{
+		Addr:    "a.tatanka.example.com:7232",
+		PeerID:  randBytes(33),
+		Cert:    randBytes(100),
+		Markets: [][2]uint32{{42, 0}, {60, 0}},
+	}, {
+		Addr:   "b.tatanka.example.com:7232",
+		PeerID: randBytes(33),
+		NoTLS:  true,
+	}}
+	for _, h := range hosts {
+		if err := boltdb.StoreMeshHost(h); err != nil {
+			t.Fatalf("StoreMeshHost error: %v", err)
+		}
+	}
+	hosts[0].Markets = hosts[0].Markets[:1]
+	if err := boltdb.StoreMeshHost(hosts[0]); err != nil {
+		t.Fatalf("StoreMeshHost replacement error: %v", err)
+	}
+	reHosts, err := boltdb.MeshHosts()
+	if err != nil {
+		t.Fatalf("MeshHosts error: %v", err)
+	}
+	if len(reHosts) != 2 || !reflect.DeepEqual(reHosts[0], hosts[0]) || !reflect.DeepEqual(reHosts[1], hosts[1]) {
+		t.Fatalf("wrong mesh hosts decoded")
+	}
+}
@@ -85,7 +85,9 @@ func (db *BoltDB) upgradeDB() error {
 	// Backup the current version's DB file before processing the upgrades to
 	// DBVersion. Note that any intermediate versions are not stored.
 	currentFile := filepath.Base(db.Path())
-	backupPath := fmt.Sprintf("%s.v%d.bak", currentFile, version) // e.g. bisonw.db.v1.bak
+	backupFile := fmt.Sprintf("%s.v%d.bak", currentFile, version) // e.g. bisonw.db.v1.bak
+	// Keep the backup next to the DB file, not in the working directory.
+	backupPath := filepath.Join(filepath.Dir(db.Path()), backupFile)
 	if err = db.backup(backupPath, true); err != nil {
 		return fmt.Errorf("failed to backup DB prior to upgrade: %w", err)
 	}
@@ -141,6 +141,13 @@ type DB interface {
 	// function to perform on deleted matches that includes if it was a sell
 	// order.
 	DeleteInactiveMatches(ctx context.Context, olderThan *time.Time, perMatchFn func(match *MetaMatch, isSell bool) error) (int, error)
+	// PruneOrders deletes inactive orders and their matches that are older
+	// than olderThan, or that are not among the keepPerMarket most recent
+	// inactive orders of their market, and returns the number of orders
+	// deleted. A nil olderThan or zero keepPerMarket disables that part of
+	// the retention policy. Accepts an optional function to perform on each
+	// deleted order and its matches.
+	PruneOrders(ctx context.Context, olderThan *time.Time, keepPerMarket int, perOrderFn func(ord *MetaOrder, matches []*MetaMatch) error) (int, error)
 	// SetSeedGenerationTime stores the time when the app seed was generated.
 	SetSeedGenerationTime(time uint64) error
 	// SeedGenerationTime fetches the time when the app seed was generated.
@@ -155,4 +162,44 @@ type DB interface {
 	SetLanguage(lang string) error
 	// Language gets the language stored with SetLanguage.
 	Language() (string, error)
+	// StoreAPIKey stores an encrypted RPC API key.
+	StoreAPIKey(id string, encKey []byte) error
+	// APIKeys retrieves the encrypted RPC API keys, keyed by ID.
+	APIKeys() (map[string][]byte, error)
+	// DeleteAPIKey deletes the RPC API key.
+	DeleteAPIKey(id string) error
+	// StoreAddressBookEntry stores the address book entry, replacing any
+	// existing entry for the same asset and address.
+	StoreAddressBookEntry(entry *AddressBookEntry) error
+	// AddressBook retrieves all address book entries.
+	AddressBook() ([]*AddressBookEntry, error)
+	// DeleteAddressBookEntry deletes the address book entry.
+	DeleteAddressBookEntry(assetID uint32, addr string) error
+	// StoreWithdrawalPolicy stores the asset's withdrawal policy.
+	StoreWithdrawalPolicy(policy *WithdrawalPolicy) error
+	// WithdrawalPolicies retrieves the withdrawal policies of all assets.
+	WithdrawalPolicies() ([]*WithdrawalPolicy, error)
+	// RecordWithdrawal records an amount withdrawn. The records are used to
+	// enforce withdrawal limits.
+	RecordWithdrawal(assetID uint32, stamp, value uint64) error
+	// WithdrawnSince sums the amounts of the asset withdrawn since the time,
+	// in milliseconds.
+	WithdrawnSince(assetID uint32, since uint64) (uint64, error)
+	// StoreConditionalOrder stores the conditional order, replacing any
+	// existing order with the same ID.
+	StoreConditionalOrder(ord *ConditionalOrder) error
+	// ConditionalOrders retrieves all conditional orders.
+	ConditionalOrders() ([]*ConditionalOrder, error)
+	// DeleteConditionalOrder deletes the conditional order.
+	DeleteConditionalOrder(id string) error
+	// StoreManagedOrder stores the managed order, replacing any existing
+	// order with the same ID.
+	StoreManagedOrder(ord *ManagedOrder) error
+	// ManagedOrders retrieves all managed orders.
+	ManagedOrders() ([]*ManagedOrder, error)
+	// StoreMeshHost stores the Tatanka mesh host, replacing any existing host
+	// with the same address.
+	StoreMeshHost(h *MeshHost) error
+	// MeshHosts retrieves all Tatanka mesh hosts.
+	MeshHosts() ([]*MeshHost, error)
 }
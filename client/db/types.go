@@ -1234,3 +1234,377 @@ func hashKey(b []byte) []byte {
 	h := blake2s.Sum256(b)
 	return h[:]
 }
+
+// AddressBookEntry is a labeled withdrawal address. If the WithdrawalPolicy for
+// the asset has AllowlistOnly set, only active address book entries are valid
+// withdrawal destinations.
+type AddressBookEntry struct {
+	AssetID uint32
+	Address string
+	Label   string
+	// Stamp is the time the entry was added, in milliseconds.
+	Stamp uint64
+	// ActiveStamp is the time, in milliseconds, after which the entry can be
+	// used.
+	ActiveStamp uint64
+}
+
+// Encode encodes the AddressBookEntry to a versioned blob.
+func (e *AddressBookEntry) Encode() []byte {
+	return versionedBytes(0).
+		AddData(uint32Bytes(e.AssetID)).
+		AddData([]byte(e.Address)).
+		AddData([]byte(e.Label)).
+		AddData(uint64Bytes(e.Stamp)).
+		AddData(uint64Bytes(e.ActiveStamp))
+}
+
+// DecodeAddressBookEntry decodes the versioned blob into an
+// *AddressBookEntry.
+func DecodeAddressBookEntry(b []byte) (*AddressBookEntry, error) {
+	ver, pushes, err := encode.DecodeBlob(b)
+	if err != nil {
+		return nil, err
+	}
+	switch ver {
+	case 0:
+		return decodeAddressBookEntry_v0(pushes)
+	}
+	return nil, fmt.Errorf("unknown AddressBookEntry version %d", ver)
+}
+
+func decodeAddressBookEntry_v0(pushes [][]byte) (*AddressBookEntry, error) {
+	if len(pushes) != 5 {
+		return nil, fmt.Errorf("decodeAddressBookEntry_v0: expected 5 pushes, got %d", len(pushes))
+	}
+	return &AddressBookEntry{
+		AssetID:     intCoder.Uint32(pushes[0]),
+		Address:     string(pushes[1]),
+		Label:       string(pushes[2]),
+		Stamp:       intCoder.Uint64(pushes[3]),
+		ActiveStamp: intCoder.Uint64(pushes[4]),
+	}, nil
+}
+
+// WithdrawalPolicy is the withdrawal policy for an asset.
+type WithdrawalPolicy struct {
+	AssetID uint32
+	// AllowlistOnly restricts withdrawals to active address book entries.
+	AllowlistOnly bool
+	// DailyLimit is the maximum amount, in atoms, that can be withdrawn in any
+	// 24 hour period. Zero means no limit.
+	DailyLimit uint64
+	// Pending is a less restrictive policy that replaces the current policy
+	// at Pending.ActiveStamp.
+	Pending *PendingWithdrawalPolicy
+}
+
+// PendingWithdrawalPolicy is a WithdrawalPolicy update that is not yet in
+// effect.
+type PendingWithdrawalPolicy struct {
+	AllowlistOnly bool
+	DailyLimit    uint64
+	// ActiveStamp is the time, in milliseconds, after which the update is in
+	// effect.
+	ActiveStamp uint64
+}
+
+// Encode encodes the WithdrawalPolicy to a versioned blob.
+func (p *WithdrawalPolicy) Encode() []byte {
+	b := versionedBytes(0).
+		AddData(uint32Bytes(p.AssetID)).
+		AddData(boolByte(p.AllowlistOnly)).
+		AddData(uint64Bytes(p.DailyLimit))
+	if p.Pending == nil {
+		return b.AddData(nil).AddData(nil).AddData(nil)
+	}
+	return b.AddData(boolByte(p.Pending.AllowlistOnly)).
+		AddData(uint64Bytes(p.Pending.DailyLimit)).
+		AddData(uint64Bytes(p.Pending.ActiveStamp))
+}
+
+// DecodeWithdrawalPolicy decodes the versioned blob into a *WithdrawalPolicy.
+func DecodeWithdrawalPolicy(b []byte) (*WithdrawalPolicy, error) {
+	ver, pushes, err := encode.DecodeBlob(b)
+	if err != nil {
+		return nil, err
+	}
+	switch ver {
+	case 0:
+		return decodeWithdrawalPolicy_v0(pushes)
+	}
+	return nil, fmt.Errorf("unknown WithdrawalPolicy version %d", ver)
+}
+
+func decodeWithdrawalPolicy_v0(pushes [][]byte) (*WithdrawalPolicy, error) {
+	if len(pushes) != 6 {
+		return nil, fmt.Errorf("decodeWithdrawalPolicy_v0: expected 6 pushes, got %d", len(pushes))
+	}
+	p := &WithdrawalPolicy{
+		AssetID:       intCoder.Uint32(pushes[0]),
+		AllowlistOnly: bytes.Equal(pushes[1], encode.ByteTrue),
+		DailyLimit:    intCoder.Uint64(pushes[2]),
+	}
+	if len(pushes[5]) > 0 {
+		p.Pending = &PendingWithdrawalPolicy{
+			AllowlistOnly: bytes.Equal(pushes[3], encode.ByteTrue),
+			DailyLimit:    intCoder.Uint64(pushes[4]),
+			ActiveStamp:   intCoder.Uint64(pushes[5]),
+		}
+	}
+	return p, nil
+}
+
+// ConditionalOrder is a client-side rule that places an order when the market
+// rate crosses a trigger. See the core package for the Type and Status values.
+type ConditionalOrder struct {
+	ID    string
+	Host  string
+	Base  uint32
+	Quote uint32
+	Type  string
+	// The order placed when the rule is triggered.
+	Sell    bool
+	Qty     uint64
+	IsLimit bool
+	Rate    uint64
+	// TriggerRate is the trigger for stop-loss and take-profit rules.
+	TriggerRate uint64
+	// TrailPPM is the trailing distance of a trailing stop, in parts per
+	// million of the rate.
+	TrailPPM uint64
+	// Extreme is the highest rate seen by a trailing stop-loss sell, or the
+	// lowest rate seen by a trailing stop buy.
+	Extreme uint64
+	// Stamp is the time the rule was created, in milliseconds.
+	Stamp   uint64
+	Status  string
+	OrderID []byte
+	Error   string
+}
+
+// Encode encodes the ConditionalOrder to a versioned blob.
+func (o *ConditionalOrder) Encode() []byte {
+	return versionedBytes(0).
+		AddData([]byte(o.ID)).
+		AddData([]byte(o.Host)).
+		AddData(uint32Bytes(o.Base)).
+		AddData(uint32Bytes(o.Quote)).
+		AddData([]byte(o.Type)).
+		AddData(boolByte(o.Sell)).
+		AddData(uint64Bytes(o.Qty)).
+		AddData(boolByte(o.IsLimit)).
+		AddData(uint64Bytes(o.Rate)).
+		AddData(uint64Bytes(o.TriggerRate)).
+		AddData(uint64Bytes(o.TrailPPM)).
+		AddData(uint64Bytes(o.Extreme)).
+		AddData(uint64Bytes(o.Stamp)).
+		AddData([]byte(o.Status)).
+		AddData(o.OrderID).
+		AddData([]byte(o.Error))
+}
+
+// DecodeConditionalOrder decodes the versioned blob into a *ConditionalOrder.
+func DecodeConditionalOrder(b []byte) (*ConditionalOrder, error) {
+	ver, pushes, err := encode.DecodeBlob(b)
+	if err != nil {
+		return nil, err
+	}
+	switch ver {
+	case 0:
+		return decodeConditionalOrder_v0(pushes)
+	}
+	return nil, fmt.Errorf("unknown ConditionalOrder version %d", ver)
+}
+
+func decodeConditionalOrder_v0(pushes [][]byte) (*ConditionalOrder, error) {
+	if len(pushes) != 16 {
+		return nil, fmt.Errorf("decodeConditionalOrder_v0: expected 16 pushes, got %d", len(pushes))
+	}
+	return &ConditionalOrder{
+		ID:          string(pushes[0]),
+		Host:        string(pushes[1]),
+		Base:        intCoder.Uint32(pushes[2]),
+		Quote:       intCoder.Uint32(pushes[3]),
+		Type:        string(pushes[4]),
+		Sell:        bytes.Equal(pushes[5], encode.ByteTrue),
+		Qty:         intCoder.Uint64(pushes[6]),
+		IsLimit:     bytes.Equal(pushes[7], encode.ByteTrue),
+		Rate:        intCoder.Uint64(pushes[8]),
+		TriggerRate: intCoder.Uint64(pushes[9]),
+		TrailPPM:    intCoder.Uint64(pushes[10]),
+		Extreme:     intCoder.Uint64(pushes[11]),
+		Stamp:       intCoder.Uint64(pushes[12]),
+		Status:      string(pushes[13]),
+		OrderID:     pushes[14],
+		Error:       string(pushes[15]),
+	}, nil
+}
+
+// ManagedOrder is a large limit order that is executed by the client as a
+// series of smaller child orders. See the core package for the Type and Status
+// values.
+type ManagedOrder struct {
+	ID    string
+	Host  string
+	Base  uint32
+	Quote uint32
+	Type  string
+	Sell  bool
+	Qty   uint64
+	Rate  uint64
+	// Slices is the number of child orders a TWAP order is split into, and
+	// Duration is the time over which they are placed, in milliseconds.
+	Slices   uint32
+	Duration uint64
+	// Clip is the visible quantity of an iceberg order.
+	Clip uint64
+	// Stamp is the time the order was created, in milliseconds.
+	Stamp  uint64
+	Status string
+	// Filled is the quantity filled by completed child orders.
+	Filled uint64
+	// Placed is the number of TWAP slices that have been placed.
+	Placed uint32
+	// Children are the IDs of all child orders, oldest first. The last child
+	// is the active one if Active is true.
+	Children []order.OrderID
+	Active   bool
+	Error    string
+}
+
+// Encode encodes the ManagedOrder to a versioned blob.
+func (o *ManagedOrder) Encode() []byte {
+	children := make([]byte, 0, len(o.Children)*order.OrderIDSize)
+	for _, oid := range o.Children {
+		children = append(children, oid[:]...)
+	}
+	return versionedBytes(0).
+		AddData([]byte(o.ID)).
+		AddData([]byte(o.Host)).
+		AddData(uint32Bytes(o.Base)).
+		AddData(uint32Bytes(o.Quote)).
+		AddData([]byte(o.Type)).
+		AddData(boolByte(o.Sell)).
+		AddData(uint64Bytes(o.Qty)).
+		AddData(uint64Bytes(o.Rate)).
+		AddData(uint32Bytes(o.Slices)).
+		AddData(uint64Bytes(o.Duration)).
+		AddData(uint64Bytes(o.Clip)).
+		AddData(uint64Bytes(o.Stamp)).
+		AddData([]byte(o.Status)).
+		AddData(uint64Bytes(o.Filled)).
+		AddData(uint32Bytes(o.Placed)).
+		AddData(children).
+		AddData(boolByte(o.Active)).
+		AddData([]byte(o.Error))
+}
+
+// DecodeManagedOrder decodes the versioned blob into a *ManagedOrder.
+func DecodeManagedOrder(b []byte) (*ManagedOrder, error) {
+	ver, pushes, err := encode.DecodeBlob(b)
+	if err != nil {
+		return nil, err
+	}
+	switch ver {
+	case 0:
+		return decodeManagedOrder_v0(pushes)
+	}
+	return nil, fmt.Errorf("unknown ManagedOrder version %d", ver)
+}
+
+func decodeManagedOrder_v0(pushes [][]byte) (*ManagedOrder, error) {
+	if len(pushes) != 18 {
+		return nil, fmt.Errorf("decodeManagedOrder_v0: expected 18 pushes, got %d", len(pushes))
+	}
+	childB := pushes[15]
+	if len(childB)%order.OrderIDSize != 0 {
+		return nil, fmt.Errorf("decodeManagedOrder_v0: invalid child order IDs length %d", len(childB))
+	}
+	var children []order.OrderID
+	for i := 0; i < len(childB); i += order.OrderIDSize {
+		var oid order.OrderID
+		copy(oid[:], childB[i:])
+		children = append(children, oid)
+	}
+	return &ManagedOrder{
+		ID:       string(pushes[0]),
+		Host:     string(pushes[1]),
+		Base:     intCoder.Uint32(pushes[2]),
+		Quote:    intCoder.Uint32(pushes[3]),
+		Type:     string(pushes[4]),
+		Sell:     bytes.Equal(pushes[5], encode.ByteTrue),
+		Qty:      intCoder.Uint64(pushes[6]),
+		Rate:     intCoder.Uint64(pushes[7]),
+		Slices:   intCoder.Uint32(pushes[8]),
+		Duration: intCoder.Uint64(pushes[9]),
+		Clip:     intCoder.Uint64(pushes[10]),
+		Stamp:    intCoder.Uint64(pushes[11]),
+		Status:   string(pushes[12]),
+		Filled:   intCoder.Uint64(pushes[13]),
+		Placed:   intCoder.Uint32(pushes[14]),
+		Children: children,
+		Active:   bytes.Equal(pushes[16], encode.ByteTrue),
+		Error:    string(pushes[17]),
+	}, nil
+}
+
+// MeshHost is a Tatanka mesh entry node and the markets that the client
+// trades on the mesh.
+type MeshHost struct {
+	Addr   string
+	PeerID []byte
+	Cert   []byte
+	NoTLS  bool
+	// Markets are the [base, quote] asset IDs of the mesh markets.
+	Markets [][2]uint32
+}
+
+// Encode encodes the MeshHost to a versioned blob.
+func (h *MeshHost) Encode() []byte {
+	mkts := make([]byte, 0, len(h.Markets)*8)
+	for _, mkt := range h.Markets {
+		mkts = append(mkts, uint32Bytes(mkt[0])...)
+		mkts = append(mkts, uint32Bytes(mkt[1])...)
+	}
+	return versionedBytes(0).
+		AddData([]byte(h.Addr)).
+		AddData(h.PeerID).
+		AddData(h.Cert).
+		AddData(boolByte(h.NoTLS)).
+		AddData(mkts)
+}
+
+// DecodeMeshHost decodes the versioned blob into a *MeshHost.
+func DecodeMeshHost(b []byte) (*MeshHost, error) {
+	ver, pushes, err := encode.DecodeBlob(b)
+	if err != nil {
+		return nil, err
+	}
+	switch ver {
+	case 0:
+		return decodeMeshHost_v0(pushes)
+	}
+	return nil, fmt.Errorf("unknown MeshHost version %d", ver)
+}
+
+func decodeMeshHost_v0(pushes [][]byte) (*MeshHost, error) {
+	if len(pushes) != 5 {
+		return nil, fmt.Errorf("decodeMeshHost_v0: expected 5 pushes, got %d", len(pushes))
+	}
+	mktsB := pushes[4]
+	if len(mktsB)%8 != 0 {
+		return nil, fmt.Errorf("decodeMeshHost_v0: invalid markets length %d", len(mktsB))
+	}
+	var mkts [][2]uint32
+	for i := 0; i < len(mktsB); i += 8 {
+		mkts = append(mkts, [2]uint32{intCoder.Uint32(mktsB[i:]), intCoder.Uint32(mktsB[i+4:])})
+	}
+	return &MeshHost{
+		Addr:    string(pushes[0]),
+		PeerID:  pushes[1],
+		Cert:    pushes[2],
+		NoTLS:   bytes.Equal(pushes[3], encode.ByteTrue),
+		Markets: mkts,
+	}, nil
+}
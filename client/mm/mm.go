@@ -9,6 +9,7 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"os"
 	"sync"
 	"time"
@@ -19,6 +20,13 @@ import (
 	"decred.org/dcrdex/client/orderbook"
 	"decred.org/dcrdex/dex"
 	"decred.org/dcrdex/dex/order"
+	"go.etcd.io/bbolt"
+)
+
+// Names of the market making files in a full-state backup.
+const (
+	backupCfgName      = "mm_cfg.json"
+	backupEventLogName = "eventlog.db"
 )
 
 // clientCore is satisfied by core.Core.
@@ -165,6 +173,14 @@ type MarketMaker struct {
 
 // NewMarketMaker creates a new MarketMaker.
 func NewMarketMaker(c clientCore, eventLogDBPath, cfgPath string, log dex.Logger) (*MarketMaker, error) {
+	for _, path := range []string{cfgPath, eventLogDBPath} {
+		if restored, err := core.ApplyStagedRestore(path); err != nil {
+			return nil, err
+		} else if restored {
+			log.Infof("Restored %s from backup", path)
+		}
+	}
+
 	var cfg MarketMakingConfig
 	if b, err := os.ReadFile(cfgPath); err != nil && !os.IsNotExist(err) {
 		return nil, fmt.Errorf("error reading config file from %q: %w", cfgPath, err)
@@ -185,6 +201,25 @@ func NewMarketMaker(c clientCore, eventLogDBPath, cfgPath string, log dex.Logger
 	}, nil
 }
 
+// BackupFiles are the market making config file and event log DB, to be
+// included in a full-state backup, or restored from one.
+func (m *MarketMaker) BackupFiles() []*core.BackupFile {
+	eventLog := &core.BackupFile{Name: backupEventLogName, Path: m.eventLogDBPath}
+	// The event log DB is open once the MarketMaker is connected.
+	if db, ok := m.eventLogDB.(*boltEventLogDB); ok {
+		eventLog.WriteTo = func(w io.Writer) error {
+			return db.View(func(tx *bbolt.Tx) error {
+				_, err := tx.WriteTo(w)
+				return err
+			})
+		}
+	}
+	return []*core.BackupFile{
+		{Name: backupCfgName, Path: m.defaultCfgPath},
+		eventLog,
+	}
+}
+
 // runningBotsLookup returns a lookup map for running bots.
 func (m *MarketMaker) runningBotsLookup() map[MarketWithHost]*runningBot {
 	m.runningBotsMtx.RLock()
@@ -0,0 +1,224 @@
+// This code is available on the terms of the project LICENSE.md file,
+// also available online at https://blueoakcouncil.org/license/1.0.0.
+
+package notify
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"reflect"
+
+	"decred.org/dcrdex/client/db"
+)
+
+// Sink types.
+const (
+	SinkWebhook = "webhook"
+	SinkEmail   = "email"
+	SinkCommand = "command"
+)
+
+// Redacted replaces the webhook secrets and SMTP passwords in the Config
+// returned by Notifier.Config. A Redacted value in a Config passed to
+// Notifier.UpdateConfig keeps the current value.
+const Redacted = "********"
+
+// defaultMinSeverity is the minimum severity used when a sink does not specify
+// one.
+const defaultMinSeverity = "warning"
+
+// Config is the notifier configuration.
+type Config struct {
+	Sinks []*SinkConfig `json:"sinks"`
+}
+
+// Copy creates a shallow copy of the Config.
+func (cfg *Config) Copy() *Config {
+	c := &Config{Sinks: make([]*SinkConfig, len(cfg.Sinks))}
+	copy(c.Sinks, cfg.Sinks)
+	return c
+}
+
+// redacted creates a copy of the Config with the webhook secrets and SMTP
+// passwords replaced by Redacted.
+func (cfg *Config) redacted() *Config {
+	c := &Config{Sinks: make([]*SinkConfig, len(cfg.Sinks))}
+	for i, sc := range cfg.Sinks {
+		sc2 := *sc
+		if sc.Webhook != nil && sc.Webhook.Secret != "" {
+			wh := *sc.Webhook
+			wh.Secret = Redacted
+			sc2.Webhook = &wh
+		}
+		if sc.Email != nil && sc.Email.Password != "" {
+			em := *sc.Email
+			em.Password = Redacted
+			sc2.Email = &em
+		}
+		c.Sinks[i] = &sc2
+	}
+	return c
+}
+
+// unredact replaces any Redacted secrets and passwords with the values from
+// the previous configuration of the same sink.
+func (cfg *Config) unredact(prev *Config) error {
+	prevSinks := make(map[string]*SinkConfig, len(prev.Sinks))
+	for _, sc := range prev.Sinks {
+		prevSinks[sc.ID] = sc
+	}
+	for _, sc := range cfg.Sinks {
+		p := prevSinks[sc.ID]
+		if sc.Webhook != nil && sc.Webhook.Secret == Redacted {
+			if p == nil || p.Webhook == nil {
+				return fmt.Errorf("no previous webhook secret for sink %q", sc.ID)
+			}
+			sc.Webhook.Secret = p.Webhook.Secret
+		}
+		if sc.Email != nil && sc.Email.Password == Redacted {
+			if p == nil || p.Email == nil {
+				return fmt.Errorf("no previous smtp password for sink %q", sc.ID)
+			}
+			sc.Email.Password = p.Email.Password
+		}
+	}
+	return nil
+}
+
+// checkCommandSinks checks that the Config does not add or change any command
+// sinks. Command sinks run arbitrary local programs, so they can only be
+// configured in the config file, and not through Notifier.UpdateConfig.
+// Removing a command sink is allowed.
+func (cfg *Config) checkCommandSinks(prev *Config) error {
+	prevSinks := make(map[string]*SinkConfig, len(prev.Sinks))
+	for _, sc := range prev.Sinks {
+		prevSinks[sc.ID] = sc
+	}
+	for _, sc := range cfg.Sinks {
+		p := prevSinks[sc.ID]
+		if sc.Type != SinkCommand && (p == nil || p.Type != SinkCommand) {
+			continue
+		}
+		if p == nil || !reflect.DeepEqual(sc, p) {
+			return fmt.Errorf("command sink %q can only be configured in the config file", sc.ID)
+		}
+	}
+	return nil
+}
+
+// SinkConfig configures a destination for notifications.
+type SinkConfig struct {
+	// ID is a unique name for the sink.
+	ID string `json:"id"`
+	// Type is one of SinkWebhook, SinkEmail, or SinkCommand.
+	Type     string `json:"type"`
+	Disabled bool   `json:"disabled"`
+	// Topics limits the sink to the specified notification topics. If empty,
+	// notifications of any topic are sent.
+	Topics []db.Topic `json:"topics,omitempty"`
+	// MinSeverity is the minimum severity of notifications sent to the sink,
+	// one of "poke", "success", "warning", or "error". The default is
+	// "warning".
+	MinSeverity string `json:"minSeverity,omitempty"`
+
+	Webhook *WebhookConfig `json:"webhook,omitempty"`
+	Email   *EmailConfig   `json:"email,omitempty"`
+	Command *CommandConfig `json:"command,omitempty"`
+}
+
+// WebhookConfig is the configuration for an HTTP webhook sink.
+type WebhookConfig struct {
+	URL string `json:"url"`
+	// Secret is used to sign the request body with HMAC-SHA256. If set, the
+	// signature is sent in the X-Bison-Signature header.
+	Secret  string            `json:"secret,omitempty"`
+	Headers map[string]string `json:"headers,omitempty"`
+}
+
+// EmailConfig is the configuration for an SMTP email sink.
+type EmailConfig struct {
+	// Host is the SMTP server address, host:port.
+	Host     string   `json:"host"`
+	User     string   `json:"user,omitempty"`
+	Password string   `json:"password,omitempty"`
+	From     string   `json:"from"`
+	To       []string `json:"to"`
+}
+
+// CommandConfig is the configuration for a local command hook. The command is
+// run with the JSON-encoded Event on stdin, and the event fields in the
+// environment.
+type CommandConfig struct {
+	Path string   `json:"path"`
+	Args []string `json:"args,omitempty"`
+}
+
+func parseSeverity(s string) (db.Severity, error) {
+	if s == "" {
+		s = defaultMinSeverity
+	}
+	switch s {
+	case "poke":
+		return db.Poke, nil
+	case "success":
+		return db.Success, nil
+	case "warning":
+		return db.WarningLevel, nil
+	case "error":
+		return db.ErrorLevel, nil
+	}
+	return 0, fmt.Errorf("unknown severity %q", s)
+}
+
+// validate checks that the sink is fully specified.
+func (s *SinkConfig) validate() error {
+	if s.ID == "" {
+		return errors.New("no sink ID")
+	}
+	if _, err := parseSeverity(s.MinSeverity); err != nil {
+		return err
+	}
+	switch s.Type {
+	case SinkWebhook:
+		if s.Webhook == nil {
+			return errors.New("no webhook configuration")
+		}
+		u, err := url.Parse(s.Webhook.URL)
+		if err != nil {
+			return fmt.Errorf("invalid webhook URL: %w", err)
+		}
+		if u.Scheme != "http" && u.Scheme != "https" {
+			return fmt.Errorf("unsupported webhook URL scheme %q", u.Scheme)
+		}
+	case SinkEmail:
+		if s.Email == nil {
+			return errors.New("no email configuration")
+		}
+		if s.Email.Host == "" || s.Email.From == "" || len(s.Email.To) == 0 {
+			return errors.New("email host, from, and to addresses are required")
+		}
+	case SinkCommand:
+		if s.Command == nil || s.Command.Path == "" {
+			return errors.New("no command path")
+		}
+	default:
+		return fmt.Errorf("unknown sink type %q", s.Type)
+	}
+	return nil
+}
+
+// validate checks the sink configurations, and that sink IDs are unique.
+func (cfg *Config) validate() error {
+	ids := make(map[string]bool, len(cfg.Sinks))
+	for _, s := range cfg.Sinks {
+		if err := s.validate(); err != nil {
+			return fmt.Errorf("invalid sink %q: %w", s.ID, err)
+		}
+		if ids[s.ID] {
+			return fmt.Errorf("duplicate sink ID %q", s.ID)
+		}
+		ids[s.ID] = true
+	}
+	return nil
+}
@@ -0,0 +1,426 @@
+// This code is available on the terms of the project LICENSE.md file,
+// also available online at https://blueoakcouncil.org/license/1.0.0.
+
+// Package notify forwards selected core notifications to outbound sinks, such
+// as HTTP webhooks, email, and local commands. Deliveries are queued on disk,
+// and failed deliveries are retried with exponential backoff.
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"decred.org/dcrdex/client/core"
+	"decred.org/dcrdex/client/db"
+	"decred.org/dcrdex/dex"
+)
+
+const (
+	// maxAttempts is the number of delivery attempts before a delivery is
+	// abandoned.
+	maxAttempts = 10
+	// minRetryDelay is the delay before the first retry. The delay doubles
+	// with each subsequent attempt, up to maxRetryDelay.
+	minRetryDelay = 5 * time.Second
+	maxRetryDelay = time.Hour
+	// maxQueueSize is the maximum number of pending deliveries. When the queue
+	// is full, the oldest deliveries are dropped.
+	maxQueueSize = 1000
+	// TopicTest is the topic of the Event sent by TestSink.
+	TopicTest db.Topic = "NotifierTest"
+)
+
+// sendTimeout is the time limit for a single delivery. It is a variable so
+// that tests can shorten it.
+var sendTimeout = 30 * time.Second
+
+type clientCore interface {
+	NotificationFeed() *core.NoteFeed
+}
+
+// Event is the notification data delivered to sinks.
+type Event struct {
+	ID       string   `json:"id"`
+	Type     string   `json:"type"`
+	Topic    db.Topic `json:"topic"`
+	Subject  string   `json:"subject"`
+	Details  string   `json:"details"`
+	Severity string   `json:"severity"`
+	// Time is the notification timestamp, in milliseconds.
+	Time uint64 `json:"time"`
+}
+
+func newEvent(note core.Notification) *Event {
+	return &Event{
+		ID:       note.ID().String(),
+		Type:     note.Type(),
+		Topic:    note.Topic(),
+		Subject:  note.Subject(),
+		Details:  note.Details(),
+		Severity: note.Severity().String(),
+		Time:     note.Time(),
+	}
+}
+
+// delivery is a pending delivery of an Event to a sink.
+type delivery struct {
+	SinkID   string `json:"sinkID"`
+	Event    *Event `json:"event"`
+	Attempts int    `json:"attempts"`
+	// NextAttempt is the earliest time of the next attempt, in milliseconds.
+	NextAttempt int64  `json:"nextAttempt"`
+	LastError   string `json:"lastError,omitempty"`
+}
+
+// activeSink is a configured sink with its parsed filters.
+type activeSink struct {
+	cfg         *SinkConfig
+	minSeverity db.Severity
+	topics      map[db.Topic]bool
+	sink
+}
+
+func (s *activeSink) accepts(note core.Notification) bool {
+	if note.Severity() < s.minSeverity {
+		return false
+	}
+	return len(s.topics) == 0 || s.topics[note.Topic()]
+}
+
+// Notifier sends core notifications to the configured sinks.
+type Notifier struct {
+	core      clientCore
+	log       dex.Logger
+	cfgPath   string
+	queuePath string
+	newSink   func(*SinkConfig) (sink, error)
+
+	cfgMtx sync.RWMutex
+	cfg    *Config
+	sinks  map[string]*activeSink
+
+	queueMtx sync.Mutex
+	queue    []*delivery
+
+	wake chan struct{}
+}
+
+// New is the constructor for a Notifier. The sink configuration is read from
+// the JSON file at cfgPath, and any undelivered notifications from a previous
+// session are read from the file at queuePath.
+func New(c clientCore, cfgPath, queuePath string, log dex.Logger) (*Notifier, error) {
+	n := &Notifier{
+		core:      c,
+		log:       log,
+		cfgPath:   cfgPath,
+		queuePath: queuePath,
+		newSink:   newSink,
+		cfg:       new(Config),
+		wake:      make(chan struct{}, 1),
+	}
+
+	if b, err := os.ReadFile(cfgPath); err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("error reading config file from %q: %w", cfgPath, err)
+	} else if len(b) > 0 {
+		if err := json.Unmarshal(b, n.cfg); err != nil {
+			return nil, fmt.Errorf("error unmarshaling config file: %v", err)
+		}
+	}
+	sinks, err := n.loadSinks(n.cfg)
+	if err != nil {
+		return nil, err
+	}
+	n.sinks = sinks
+
+	if b, err := os.ReadFile(queuePath); err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("error reading delivery queue from %q: %w", queuePath, err)
+	} else if len(b) > 0 {
+		if err := json.Unmarshal(b, &n.queue); err != nil {
+			return nil, fmt.Errorf("error unmarshaling delivery queue: %v", err)
+		}
+	}
+	return n, nil
+}
+
+// loadSinks validates the config and constructs the enabled sinks.
+func (n *Notifier) loadSinks(cfg *Config) (map[string]*activeSink, error) {
+	if err := cfg.validate(); err != nil {
+		return nil, err
+	}
+	sinks := make(map[string]*activeSink, len(cfg.Sinks))
+	for _, sc := range cfg.Sinks {
+		if sc.Disabled {
+			continue
+		}
+		s, err := n.newSink(sc)
+		if err != nil {
+			return nil, fmt.Errorf("error creating sink %q: %w", sc.ID, err)
+		}
+		minSeverity, _ := parseSeverity(sc.MinSeverity) // validated
+		topics := make(map[db.Topic]bool, len(sc.Topics))
+		for _, t := range sc.Topics {
+			topics[t] = true
+		}
+		sinks[sc.ID] = &activeSink{
+			cfg:         sc,
+			minSeverity: minSeverity,
+			topics:      topics,
+			sink:        s,
+		}
+	}
+	return sinks, nil
+}
+
+// Connect starts monitoring core notifications and delivering them to the
+// sinks. Connect is part of the dex.Connector interface.
+func (n *Notifier) Connect(ctx context.Context) (*sync.WaitGroup, error) {
+	feed := n.core.NotificationFeed()
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		defer feed.ReturnFeed()
+		for {
+			select {
+			case note := <-feed.C:
+				n.handleNote(note)
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		n.deliveryLoop(ctx)
+	}()
+
+	return &wg, nil
+}
+
+// handleNote queues deliveries of the notification to any sinks that accept
+// it.
+func (n *Notifier) handleNote(note core.Notification) {
+	var e *Event
+	var ds []*delivery
+	n.cfgMtx.RLock()
+	for id, s := range n.sinks {
+		if !s.accepts(note) {
+			continue
+		}
+		if e == nil {
+			e = newEvent(note)
+		}
+		ds = append(ds, &delivery{SinkID: id, Event: e})
+	}
+	n.cfgMtx.RUnlock()
+	if len(ds) == 0 {
+		return
+	}
+
+	n.queueMtx.Lock()
+	n.queue = append(n.queue, ds...)
+	if over := len(n.queue) - maxQueueSize; over > 0 {
+		n.log.Warnf("Notification delivery queue is full. Dropping %d oldest deliveries", over)
+		n.queue = n.queue[over:]
+	}
+	n.storeQueue()
+	n.queueMtx.Unlock()
+
+	select {
+	case n.wake <- struct{}{}:
+	default:
+	}
+}
+
+// storeQueue writes the pending deliveries to file. The queueMtx must be held.
+func (n *Notifier) storeQueue() {
+	b, err := json.Marshal(n.queue)
+	if err != nil {
+		n.log.Errorf("Error marshaling notification delivery queue: %v", err)
+		return
+	}
+	if err := os.WriteFile(n.queuePath, b, 0600); err != nil {
+		n.log.Errorf("Error writing notification delivery queue: %v", err)
+	}
+}
+
+func (n *Notifier) deliveryLoop(ctx context.Context) {
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+	for {
+		n.deliverDue(ctx)
+		select {
+		case <-ticker.C:
+		case <-n.wake:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// retryDelay is the delay before the next attempt after the specified number
+// of failed attempts.
+func retryDelay(attempts int) time.Duration {
+	d := minRetryDelay << (attempts - 1)
+	if d > maxRetryDelay || d <= 0 {
+		return maxRetryDelay
+	}
+	return d
+}
+
+// deliverDue attempts all deliveries that are due. Each sink's deliveries are
+// sent in order in their own goroutine, so that a slow sink does not delay the
+// others. After a failed delivery, the sink's remaining deliveries are skipped
+// until the next pass.
+func (n *Notifier) deliverDue(ctx context.Context) {
+	now := time.Now().UnixMilli()
+	n.queueMtx.Lock()
+	due := make([]*delivery, 0, len(n.queue))
+	for _, d := range n.queue {
+		if d.NextAttempt <= now {
+			due = append(due, d)
+		}
+	}
+	n.queueMtx.Unlock()
+	if len(due) == 0 {
+		return
+	}
+
+	bySink := make(map[string][]*delivery)
+	for _, d := range due {
+		bySink[d.SinkID] = append(bySink[d.SinkID], d)
+	}
+
+	done := make(map[*delivery]bool, len(due))
+	var wg sync.WaitGroup
+	for sinkID, ds := range bySink {
+		n.cfgMtx.RLock()
+		s := n.sinks[sinkID]
+		n.cfgMtx.RUnlock()
+		if s == nil {
+			n.log.Debugf("Dropping %d deliveries to removed or disabled sink %q", len(ds), sinkID)
+			n.queueMtx.Lock()
+			for _, d := range ds {
+				done[d] = true
+			}
+			n.queueMtx.Unlock()
+			continue
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for _, d := range ds {
+				if ctx.Err() != nil || !n.deliver(ctx, s, d, done) {
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	n.queueMtx.Lock()
+	defer n.queueMtx.Unlock()
+	remaining := n.queue[:0]
+	for _, d := range n.queue {
+		if !done[d] {
+			remaining = append(remaining, d)
+		}
+	}
+	n.queue = remaining
+	n.storeQueue()
+}
+
+// deliver attempts the delivery, recording the result. Successful and abandoned
+// deliveries are marked in done. The return value is false if the delivery
+// failed.
+func (n *Notifier) deliver(ctx context.Context, s *activeSink, d *delivery, done map[*delivery]bool) bool {
+	sendCtx, cancel := context.WithTimeout(ctx, sendTimeout)
+	err := s.send(sendCtx, d.Event)
+	cancel()
+	n.queueMtx.Lock()
+	defer n.queueMtx.Unlock()
+	d.Attempts++
+	if err == nil {
+		done[d] = true
+		return true
+	}
+	if d.Attempts >= maxAttempts {
+		n.log.Errorf("Giving up on delivery of notification %s to sink %q after %d attempts: %v",
+			d.Event.Topic, d.SinkID, d.Attempts, err)
+		done[d] = true
+	} else {
+		n.log.Warnf("Error delivering notification %s to sink %q (attempt %d): %v",
+			d.Event.Topic, d.SinkID, d.Attempts, err)
+		d.NextAttempt = time.Now().Add(retryDelay(d.Attempts)).UnixMilli()
+		d.LastError = err.Error()
+	}
+	return false
+}
+
+// Config is the current notifier configuration, with the webhook secrets and
+// SMTP passwords redacted.
+func (n *Notifier) Config() *Config {
+	n.cfgMtx.RLock()
+	defer n.cfgMtx.RUnlock()
+	return n.cfg.redacted()
+}
+
+// UpdateConfig validates and applies the configuration, and writes it to file.
+// Redacted webhook secrets and SMTP passwords keep their current values.
+// Command sinks cannot be added or changed, only removed. They must be
+// configured in the config file.
+func (n *Notifier) UpdateConfig(cfg *Config) error {
+	n.cfgMtx.RLock()
+	prev := n.cfg
+	n.cfgMtx.RUnlock()
+	if err := cfg.checkCommandSinks(prev); err != nil {
+		return err
+	}
+	if err := cfg.unredact(prev); err != nil {
+		return err
+	}
+	sinks, err := n.loadSinks(cfg)
+	if err != nil {
+		return err
+	}
+	b, err := json.MarshalIndent(cfg, "", "    ")
+	if err != nil {
+		return fmt.Errorf("error marshaling notifier config: %w", err)
+	}
+	if err := os.WriteFile(n.cfgPath, b, 0600); err != nil {
+		return fmt.Errorf("error writing notifier config: %w", err)
+	}
+	n.cfgMtx.Lock()
+	n.cfg = cfg.Copy()
+	n.sinks = sinks
+	n.cfgMtx.Unlock()
+	return nil
+}
+
+// TestSink sends a test event to the sink, without retries.
+func (n *Notifier) TestSink(ctx context.Context, sinkID string) error {
+	n.cfgMtx.RLock()
+	s := n.sinks[sinkID]
+	n.cfgMtx.RUnlock()
+	if s == nil {
+		return fmt.Errorf("no enabled sink %q", sinkID)
+	}
+	ctx, cancel := context.WithTimeout(ctx, sendTimeout)
+	defer cancel()
+	return s.send(ctx, &Event{
+		ID:       "test",
+		Type:     "test",
+		Topic:    TopicTest,
+		Subject:  "Test notification",
+		Details:  fmt.Sprintf("This is a test of the %q notification sink.", sinkID),
+		Severity: db.Success.String(),
+		Time:     uint64(time.Now().UnixMilli()),
+	})
+}
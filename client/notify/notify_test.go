@@ -0,0 +1,353 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/smtp"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"decred.org/dcrdex/client/core"
+	"decred.org/dcrdex/client/db"
+	"decred.org/dcrdex/dex"
+)
+
+var tLogger = dex.StdOutLogger("T", dex.LevelCritical)
+
+type tCore struct {
+	feed chan core.Notification
+}
+
+func (c *tCore) NotificationFeed() *core.NoteFeed {
+	return &core.NoteFeed{C: c.feed}
+}
+
+type tSink struct {
+	mtx    sync.Mutex
+	events []*Event
+	err    error
+	hang   bool
+	sends  int
+}
+
+func (s *tSink) send(ctx context.Context, e *Event) error {
+	s.mtx.Lock()
+	s.sends++
+	hang := s.hang
+	s.mtx.Unlock()
+	if hang {
+		<-ctx.Done()
+		return ctx.Err()
+	}
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	if s.err != nil {
+		return s.err
+	}
+	s.events = append(s.events, e)
+	return nil
+}
+
+func tNote(topic db.Topic, severity db.Severity) core.Notification {
+	n := db.NewNotification("test", topic, "subject", "details", severity)
+	return &n
+}
+
+func newTNotifier(t *testing.T, dir string, sinks map[string]*tSink) *Notifier {
+	t.Helper()
+	n, err := New(&tCore{feed: make(chan core.Notification)}, filepath.Join(dir, "notify.json"),
+		filepath.Join(dir, "notify_queue.json"), tLogger)
+	if err != nil {
+		t.Fatalf("New error: %v", err)
+	}
+	n.newSink = func(cfg *SinkConfig) (sink, error) {
+		return sinks[cfg.ID], nil
+	}
+	if n.sinks, err = n.loadSinks(n.cfg); err != nil {
+		t.Fatalf("loadSinks error: %v", err)
+	}
+	return n
+}
+
+func TestConfigValidate(t *testing.T) {
+	webhook := &WebhookConfig{URL: "https://example.com/hook"}
+	tests := []struct {
+		name    string
+		cfg     *Config
+		wantErr bool
+	}{
+		{"ok", &Config{Sinks: []*SinkConfig{
+			{ID: "a", Type: SinkWebhook, Webhook: webhook},
+			{ID: "b", Type: SinkEmail, Email: &EmailConfig{Host: "smtp.example.com:587", From: "a@example.com", To: []string{"b@example.com"}}},
+			{ID: "c", Type: SinkCommand, Command: &CommandConfig{Path: "/bin/true"}, MinSeverity: "error"},
+		}}, false},
+		{"no id", &Config{Sinks: []*SinkConfig{{Type: SinkWebhook, Webhook: webhook}}}, true},
+		{"duplicate id", &Config{Sinks: []*SinkConfig{
+			{ID: "a", Type: SinkWebhook, Webhook: webhook},
+			{ID: "a", Type: SinkWebhook, Webhook: webhook},
+		}}, true},
+		{"bad scheme", &Config{Sinks: []*SinkConfig{{ID: "a", Type: SinkWebhook, Webhook: &WebhookConfig{URL: "ftp://example.com"}}}}, true},
+		{"bad severity", &Config{Sinks: []*SinkConfig{{ID: "a", Type: SinkWebhook, Webhook: webhook, MinSeverity: "data"}}}, true},
+		{"no email recipients", &Config{Sinks: []*SinkConfig{{ID: "a", Type: SinkEmail, Email: &EmailConfig{Host: "h:25", From: "a@example.com"}}}}, true},
+		{"no command", &Config{Sinks: []*SinkConfig{{ID: "a", Type: SinkCommand}}}, true},
+		{"unknown type", &Config{Sinks: []*SinkConfig{{ID: "a", Type: "pager"}}}, true},
+	}
+	for _, tt := range tests {
+		err := tt.cfg.validate()
+		if (err != nil) != tt.wantErr {
+			t.Fatalf("%s: wantErr = %t, got err = %v", tt.name, tt.wantErr, err)
+		}
+	}
+}
+
+func TestNotifier(t *testing.T) {
+	dir := t.TempDir()
+	all, errs := &tSink{}, &tSink{}
+	sinks := map[string]*tSink{"all": all, "errs": errs}
+	n := newTNotifier(t, dir, sinks)
+
+	cfg := &Config{Sinks: []*SinkConfig{
+		{ID: "all", Type: SinkWebhook, Webhook: &WebhookConfig{URL: "https://example.com/all"}, MinSeverity: "success"},
+		{ID: "errs", Type: SinkWebhook, Webhook: &WebhookConfig{URL: "https://example.com/errs"}, MinSeverity: "error",
+			Topics: []db.Topic{core.TopicRefundFailure}},
+	}}
+	if err := n.UpdateConfig(cfg); err != nil {
+		t.Fatalf("UpdateConfig error: %v", err)
+	}
+
+	n.handleNote(tNote(core.TopicMatchComplete, db.Success))
+	n.handleNote(tNote(core.TopicRefundFailure, db.ErrorLevel))
+	n.handleNote(tNote(core.TopicBondPostError, db.ErrorLevel))
+	n.handleNote(tNote(core.TopicMatchComplete, db.Data))
+	if len(n.queue) != 4 {
+		t.Fatalf("expected 4 deliveries, got %d", len(n.queue))
+	}
+
+	ctx := context.Background()
+	errs.err = errors.New("test error")
+	n.deliverDue(ctx)
+	if len(all.events) != 3 {
+		t.Fatalf("expected 3 events for the unfiltered sink, got %d", len(all.events))
+	}
+	if len(n.queue) != 1 {
+		t.Fatalf("expected 1 failed delivery, got %d", len(n.queue))
+	}
+	d := n.queue[0]
+	if d.SinkID != "errs" || d.Attempts != 1 || d.LastError == "" || d.Event.Topic != core.TopicRefundFailure {
+		t.Fatalf("wrong failed delivery %+v", d)
+	}
+
+	// Not due yet.
+	errs.err = nil
+	n.deliverDue(ctx)
+	if len(errs.events) != 0 {
+		t.Fatalf("delivery attempted before retry delay")
+	}
+
+	// The queue is persisted.
+	n2 := newTNotifier(t, dir, sinks)
+	if len(n2.queue) != 1 || len(n2.Config().Sinks) != 2 {
+		t.Fatalf("queue or config not persisted")
+	}
+	n2.queue[0].NextAttempt = 0
+	n2.deliverDue(ctx)
+	if len(errs.events) != 1 || len(n2.queue) != 0 {
+		t.Fatalf("retry not delivered")
+	}
+
+	// Give up after maxAttempts.
+	errs.err = errors.New("test error")
+	n2.handleNote(tNote(core.TopicRefundFailure, db.ErrorLevel))
+	for i := 0; i < maxAttempts; i++ {
+		n2.queue[0].NextAttempt = 0
+		n2.deliverDue(ctx)
+	}
+	if len(n2.queue) != 0 {
+		t.Fatalf("delivery not abandoned after max attempts")
+	}
+
+	// Deliveries to disabled sinks are dropped.
+	cfg.Sinks[0].Disabled = true
+	if err := n2.UpdateConfig(cfg); err != nil {
+		t.Fatalf("UpdateConfig error: %v", err)
+	}
+	n2.queue = []*delivery{{SinkID: "all", Event: &Event{}}}
+	n2.deliverDue(ctx)
+	if len(n2.queue) != 0 || len(all.events) != 4 {
+		t.Fatalf("delivery to disabled sink not dropped")
+	}
+
+	if err := n2.TestSink(ctx, "all"); err == nil {
+		t.Fatalf("no error testing disabled sink")
+	}
+}
+
+func TestHangingSink(t *testing.T) {
+	defer func(d time.Duration) { sendTimeout = d }(sendTimeout)
+	sendTimeout = 50 * time.Millisecond
+
+	hung, ok := &tSink{hang: true}, &tSink{}
+	n := newTNotifier(t, t.TempDir(), map[string]*tSink{"hung": hung, "ok": ok})
+	cfg := &Config{Sinks: []*SinkConfig{
+		{ID: "hung", Type: SinkWebhook, Webhook: &WebhookConfig{URL: "https://example.com/hung"}, MinSeverity: "success"},
+		{ID: "ok", Type: SinkWebhook, Webhook: &WebhookConfig{URL: "https://example.com/ok"}, MinSeverity: "success"},
+	}}
+	if err := n.UpdateConfig(cfg); err != nil {
+		t.Fatalf("UpdateConfig error: %v", err)
+	}
+	const numNotes = 3
+	for i := 0; i < numNotes; i++ {
+		n.handleNote(tNote(core.TopicMatchComplete, db.Success))
+	}
+
+	// The hanging sink is only tried once, and doesn't hold up the other sink.
+	n.deliverDue(context.Background())
+	if len(ok.events) != numNotes {
+		t.Fatalf("expected %d events for the working sink, got %d", numNotes, len(ok.events))
+	}
+	if hung.sends != 1 {
+		t.Fatalf("expected 1 send to the hanging sink, got %d", hung.sends)
+	}
+	if len(n.queue) != numNotes {
+		t.Fatalf("expected %d pending deliveries, got %d", numNotes, len(n.queue))
+	}
+	var attempted int
+	for _, d := range n.queue {
+		if d.SinkID != "hung" {
+			t.Fatalf("unexpected pending delivery to sink %q", d.SinkID)
+		}
+		attempted += d.Attempts
+	}
+	if attempted != 1 {
+		t.Fatalf("expected 1 failed attempt, got %d", attempted)
+	}
+}
+
+func TestUpdateConfigRestrictions(t *testing.T) {
+	dir := t.TempDir()
+	cmdSink := &SinkConfig{ID: "cmd", Type: SinkCommand, Command: &CommandConfig{Path: "/bin/true"}}
+	cfg := &Config{Sinks: []*SinkConfig{
+		{ID: "hook", Type: SinkWebhook, Webhook: &WebhookConfig{URL: "https://example.com/hook", Secret: "shh"}},
+		{ID: "mail", Type: SinkEmail, Email: &EmailConfig{Host: "smtp.example.com:587", User: "u", Password: "pw",
+			From: "a@example.com", To: []string{"b@example.com"}}},
+		cmdSink,
+	}}
+	b, _ := json.Marshal(cfg)
+	if err := os.WriteFile(filepath.Join(dir, "notify.json"), b, 0600); err != nil {
+		t.Fatal(err)
+	}
+	n := newTNotifier(t, dir, map[string]*tSink{"hook": {}, "mail": {}, "cmd": {}})
+
+	// Secrets are redacted.
+	redacted := n.Config()
+	if redacted.Sinks[0].Webhook.Secret != Redacted || redacted.Sinks[1].Email.Password != Redacted {
+		t.Fatalf("secrets not redacted")
+	}
+	if n.cfg.Sinks[0].Webhook.Secret != "shh" {
+		t.Fatalf("stored secret modified")
+	}
+
+	// Redacted values are kept when updating.
+	redacted.Sinks[0].MinSeverity = "error"
+	if err := n.UpdateConfig(redacted); err != nil {
+		t.Fatalf("UpdateConfig error: %v", err)
+	}
+	if n.cfg.Sinks[0].Webhook.Secret != "shh" || n.cfg.Sinks[1].Email.Password != "pw" || n.cfg.Sinks[0].MinSeverity != "error" {
+		t.Fatalf("redacted secrets not restored")
+	}
+
+	// Command sinks cannot be added or changed.
+	changed := n.Config()
+	changed.Sinks[2] = &SinkConfig{ID: "cmd", Type: SinkCommand, Command: &CommandConfig{Path: "/bin/sh"}}
+	if err := n.UpdateConfig(changed); err == nil {
+		t.Fatalf("no error for changed command sink")
+	}
+	added := n.Config()
+	added.Sinks = append(added.Sinks, &SinkConfig{ID: "cmd2", Type: SinkCommand, Command: &CommandConfig{Path: "/bin/true"}})
+	if err := n.UpdateConfig(added); err == nil {
+		t.Fatalf("no error for added command sink")
+	}
+	retyped := n.Config()
+	retyped.Sinks[2] = &SinkConfig{ID: "cmd", Type: SinkWebhook, Webhook: &WebhookConfig{URL: "https://example.com/hook"}}
+	if err := n.UpdateConfig(retyped); err == nil {
+		t.Fatalf("no error for replaced command sink")
+	}
+
+	// But they can be removed.
+	removed := n.Config()
+	removed.Sinks = removed.Sinks[:2]
+	if err := n.UpdateConfig(removed); err != nil {
+		t.Fatalf("error removing command sink: %v", err)
+	}
+}
+
+func TestWebhookSink(t *testing.T) {
+	const secret = "shh"
+	var gotSig, gotTS, gotHeader string
+	var gotEvent Event
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		gotSig, gotTS = r.Header.Get(SignatureHeader), r.Header.Get(TimestampHeader)
+		gotHeader = r.Header.Get("X-Test")
+		if Sign(secret, gotTS, b) != gotSig {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		json.Unmarshal(b, &gotEvent)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	cfg := &WebhookConfig{URL: srv.URL, Secret: secret, Headers: map[string]string{"X-Test": "1"}}
+	s := &webhookSink{cfg: cfg, client: srv.Client()}
+	e := &Event{ID: "abc", Topic: core.TopicMatchRevoked, Subject: "Match revoked"}
+	if err := s.send(context.Background(), e); err != nil {
+		t.Fatalf("send error: %v", err)
+	}
+	if gotEvent.ID != "abc" || gotEvent.Topic != core.TopicMatchRevoked || gotHeader != "1" {
+		t.Fatalf("wrong webhook request: %+v", gotEvent)
+	}
+
+	cfg.Secret = "wrong"
+	if err := s.send(context.Background(), e); err == nil {
+		t.Fatalf("no error for rejected signature")
+	}
+}
+
+func TestEmailSink(t *testing.T) {
+	var gotAddr, gotFrom string
+	var gotMsg []byte
+	var gotAuth smtp.Auth
+	s := &emailSink{
+		cfg: &EmailConfig{Host: "smtp.example.com:587", User: "u", Password: "p", From: "bot@example.com", To: []string{"me@example.com"}},
+		sendMail: func(addr string, a smtp.Auth, from string, to []string, msg []byte) error {
+			gotAddr, gotAuth, gotFrom, gotMsg = addr, a, from, msg
+			return nil
+		},
+	}
+	if err := s.send(context.Background(), &Event{Subject: "Refund failure", Details: "details", Topic: core.TopicRefundFailure}); err != nil {
+		t.Fatalf("send error: %v", err)
+	}
+	if gotAddr != "smtp.example.com:587" || gotAuth == nil || gotFrom != "bot@example.com" {
+		t.Fatalf("wrong smtp parameters")
+	}
+	if !strings.Contains(string(gotMsg), "Subject: [Bison Wallet] Refund failure\r\n") {
+		t.Fatalf("wrong message: %s", gotMsg)
+	}
+
+	// Line breaks in the subject cannot inject headers.
+	if err := s.send(context.Background(), &Event{Subject: "x\r\nBcc: evil@example.com"}); err != nil {
+		t.Fatalf("send error: %v", err)
+	}
+	if strings.Contains(string(gotMsg), "\r\nBcc:") {
+		t.Fatalf("header injected: %s", gotMsg)
+	}
+}
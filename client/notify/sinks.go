@@ -0,0 +1,152 @@
+// This code is available on the terms of the project LICENSE.md file,
+// also available online at https://blueoakcouncil.org/license/1.0.0.
+
+package notify
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/smtp"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// SignatureHeader is the webhook request header with the hex-encoded
+	// HMAC-SHA256 signature of the timestamp and body, prefixed with
+	// "sha256=".
+	SignatureHeader = "X-Bison-Signature"
+	// TimestampHeader is the webhook request header with the UNIX timestamp
+	// of the request, in seconds.
+	TimestampHeader = "X-Bison-Timestamp"
+)
+
+// sink delivers an event to a destination.
+type sink interface {
+	send(ctx context.Context, e *Event) error
+}
+
+func newSink(cfg *SinkConfig) (sink, error) {
+	switch cfg.Type {
+	case SinkWebhook:
+		return &webhookSink{cfg: cfg.Webhook, client: http.DefaultClient}, nil
+	case SinkEmail:
+		return &emailSink{cfg: cfg.Email, sendMail: smtp.SendMail}, nil
+	case SinkCommand:
+		return &commandSink{cfg: cfg.Command}, nil
+	}
+	return nil, fmt.Errorf("unknown sink type %q", cfg.Type)
+}
+
+// Sign computes the webhook signature for the timestamp and request body.
+// Receivers should compute the same signature with the shared secret and
+// compare with the SignatureHeader value.
+func Sign(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte{'.'})
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// webhookSink POSTs events as JSON to a URL.
+type webhookSink struct {
+	cfg    *WebhookConfig
+	client *http.Client
+}
+
+func (s *webhookSink) send(ctx context.Context, e *Event) error {
+	body, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.cfg.URL, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("error constructing request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range s.cfg.Headers {
+		req.Header.Set(k, v)
+	}
+	ts := strconv.FormatInt(time.Now().Unix(), 10)
+	req.Header.Set(TimestampHeader, ts)
+	if s.cfg.Secret != "" {
+		req.Header.Set(SignatureHeader, Sign(s.cfg.Secret, ts, body))
+	}
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("error performing request: %w", err)
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 1<<16))
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("webhook responded with status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// headerReplacer strips line breaks from email header values, which would
+// otherwise allow a notification subject to inject headers.
+var headerReplacer = strings.NewReplacer("\r", " ", "\n", " ")
+
+// emailSink sends events by email through an SMTP server.
+type emailSink struct {
+	cfg      *EmailConfig
+	sendMail func(addr string, a smtp.Auth, from string, to []string, msg []byte) error
+}
+
+func (s *emailSink) send(_ context.Context, e *Event) error {
+	var auth smtp.Auth
+	if s.cfg.User != "" {
+		host, _, err := net.SplitHostPort(s.cfg.Host)
+		if err != nil {
+			return fmt.Errorf("invalid smtp host %q: %w", s.cfg.Host, err)
+		}
+		auth = smtp.PlainAuth("", s.cfg.User, s.cfg.Password, host)
+	}
+	var msg strings.Builder
+	fmt.Fprintf(&msg, "From: %s\r\n", s.cfg.From)
+	fmt.Fprintf(&msg, "To: %s\r\n", strings.Join(s.cfg.To, ", "))
+	fmt.Fprintf(&msg, "Subject: [Bison Wallet] %s\r\n", headerReplacer.Replace(e.Subject))
+	fmt.Fprintf(&msg, "Date: %s\r\n", time.UnixMilli(int64(e.Time)).Format(time.RFC1123Z))
+	msg.WriteString("Content-Type: text/plain; charset=UTF-8\r\n\r\n")
+	fmt.Fprintf(&msg, "%s\r\n\r\nTopic: %s\r\nSeverity: %s\r\n", e.Details, e.Topic, e.Severity)
+	return s.sendMail(s.cfg.Host, auth, s.cfg.From, s.cfg.To, []byte(msg.String()))
+}
+
+// commandSink runs a local command for each event.
+type commandSink struct {
+	cfg *CommandConfig
+}
+
+func (s *commandSink) send(ctx context.Context, e *Event) error {
+	b, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	cmd := exec.CommandContext(ctx, s.cfg.Path, s.cfg.Args...)
+	cmd.Stdin = bytes.NewReader(b)
+	cmd.Env = append(os.Environ(),
+		"BISON_NOTE_ID="+e.ID,
+		"BISON_NOTE_TYPE="+e.Type,
+		"BISON_NOTE_TOPIC="+string(e.Topic),
+		"BISON_NOTE_SEVERITY="+e.Severity,
+		"BISON_NOTE_SUBJECT="+e.Subject,
+		"BISON_NOTE_DETAILS="+e.Details,
+	)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("command error: %w, output: %s", err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
@@ -63,19 +63,51 @@ const (
 	setVotingPreferencesRoute  = "setvotingprefs"
 	txHistoryRoute             = "txhistory"
 	walletTxRoute              = "wallettx"
+	privacyReportRoute         = "privacyreport"
 	withdrawBchSpvRoute        = "withdrawbchspv"
+	listUTXOsRoute             = "listutxos"
+	setUTXOLabelRoute          = "setutxolabel"
+	freezeUTXOsRoute           = "freezeutxos"
+	exportBackupRoute          = "exportbackup"
+	restoreBackupRoute         = "restorebackup"
+	createAPIKeyRoute          = "createapikey"
+	listAPIKeysRoute           = "listapikeys"
+	revokeAPIKeyRoute          = "revokeapikey"
+	addressBookRoute           = "addressbook"
+	addAddressRoute            = "addaddress"
+	removeAddressRoute         = "removeaddress"
+	withdrawalPoliciesRoute    = "withdrawalpolicies"
+	setWithdrawalPolicyRoute   = "setwithdrawalpolicy"
+	createCondOrderRoute       = "createconditionalorder"
+	condOrdersRoute            = "conditionalorders"
+	removeCondOrderRoute       = "removeconditionalorder"
+	createManagedOrderRoute    = "createmanagedorder"
+	managedOrdersRoute         = "managedorders"
+	cancelManagedOrderRoute    = "cancelmanagedorder"
+	exportTaxHistoryRoute      = "exporttaxhistory"
 )
 
 const (
-	initializedStr    = "app initialized"
-	walletCreatedStr  = "%s wallet created and unlocked"
-	walletLockedStr   = "%s wallet locked"
-	walletUnlockedStr = "%s wallet unlocked"
-	canceledOrderStr  = "canceled order %s"
-	logoutStr         = "goodbye"
-	walletStatusStr   = "%s wallet has been %s"
-	setVotePrefsStr   = "vote preferences set"
-	setVSPStr         = "vsp set to %s"
+	initializedStr      = "app initialized"
+	walletCreatedStr    = "%s wallet created and unlocked"
+	walletLockedStr     = "%s wallet locked"
+	walletUnlockedStr   = "%s wallet unlocked"
+	canceledOrderStr    = "canceled order %s"
+	logoutStr           = "goodbye"
+	walletStatusStr     = "%s wallet has been %s"
+	setVotePrefsStr     = "vote preferences set"
+	setVSPStr           = "vsp set to %s"
+	utxoLabelSetStr     = "utxo label set"
+	utxosFrozenStr      = "%d utxos frozen"
+	utxosUnfrozenStr    = "%d utxos unfrozen"
+	backupWrittenStr    = "backup written to %s"
+	backupRestoredStr   = "backup from %s restored. Restart to complete the restoration"
+	apiKeyRevokedStr    = "api key %s revoked"
+	addressRemovedStr   = "address %s removed"
+	policySetStr        = "withdrawal policy set"
+	condOrderRemovedStr = "conditional order %s removed"
+	managedCanceledStr  = "managed order %s canceled"
+	taxExportedStr      = "%d records written to %s"
 )
 
 // createResponse creates a msgjson response payload.
@@ -140,7 +172,238 @@ var routes = map[string]func(s *RPCServer, params *RawParams) *msgjson.ResponseP
 	setVotingPreferencesRoute:  handleSetVotingPreferences,
 	txHistoryRoute:             handleTxHistory,
 	walletTxRoute:              handleWalletTx,
+	privacyReportRoute:         handlePrivacyReport,
 	withdrawBchSpvRoute:        handleWithdrawBchSpv,
+	listUTXOsRoute:             handleListUTXOs,
+	setUTXOLabelRoute:          handleSetUTXOLabel,
+	freezeUTXOsRoute:           handleFreezeUTXOs,
+	exportBackupRoute:          handleExportBackup,
+	restoreBackupRoute:         handleRestoreBackup,
+	createAPIKeyRoute:          handleCreateAPIKey,
+	listAPIKeysRoute:           handleListAPIKeys,
+	revokeAPIKeyRoute:          handleRevokeAPIKey,
+	addressBookRoute:           handleAddressBook,
+	addAddressRoute:            handleAddAddress,
+	removeAddressRoute:         handleRemoveAddress,
+	withdrawalPoliciesRoute:    handleWithdrawalPolicies,
+	setWithdrawalPolicyRoute:   handleSetWithdrawalPolicy,
+	createCondOrderRoute:       handleCreateConditionalOrder,
+	condOrdersRoute:            handleConditionalOrders,
+	removeCondOrderRoute:       handleRemoveConditionalOrder,
+	createManagedOrderRoute:    handleCreateManagedOrder,
+	managedOrdersRoute:         handleManagedOrders,
+	cancelManagedOrderRoute:    handleCancelManagedOrder,
+	exportTaxHistoryRoute:      handleExportTaxHistory,
+}
+
+// routeScopes maps routes to the API key scope required to use them. Routes
+// that are not listed can only be used with the RPC user and password.
+var routeScopes = map[string]string{
+	exchangesRoute:           core.APIScopeRead,
+	helpRoute:                core.APIScopeRead,
+	versionRoute:             core.APIScopeRead,
+	walletsRoute:             core.APIScopeRead,
+	myOrdersRoute:            core.APIScopeRead,
+	orderBookRoute:           core.APIScopeRead,
+	getDEXConfRoute:          core.APIScopeRead,
+	bondAssetsRoute:          core.APIScopeRead,
+	bondOptionsRoute:         core.APIScopeRead,
+	notificationsRoute:       core.APIScopeRead,
+	txHistoryRoute:           core.APIScopeRead,
+	walletTxRoute:            core.APIScopeRead,
+	privacyReportRoute:       core.APIScopeRead,
+	walletPeersRoute:         core.APIScopeRead,
+	stakeStatusRoute:         core.APIScopeRead,
+	listUTXOsRoute:           core.APIScopeRead,
+	mmAvailableBalancesRoute: core.APIScopeRead,
+	mmStatusRoute:            core.APIScopeRead,
+	addressBookRoute:         core.APIScopeRead,
+	withdrawalPoliciesRoute:  core.APIScopeRead,
+	condOrdersRoute:          core.APIScopeRead,
+	managedOrdersRoute:       core.APIScopeRead,
+	tradeRoute:               core.APIScopeTrade,
+	multiTradeRoute:          core.APIScopeTrade,
+	cancelRoute:              core.APIScopeTrade,
+	createCondOrderRoute:     core.APIScopeTrade,
+	removeCondOrderRoute:     core.APIScopeTrade,
+	createManagedOrderRoute:  core.APIScopeTrade,
+	cancelManagedOrderRoute:  core.APIScopeTrade,
+	startBotRoute:            core.APIScopeMM,
+	stopBotRoute:             core.APIScopeMM,
+	updateRunningBotCfgRoute: core.APIScopeMM,
+	updateRunningBotInvRoute: core.APIScopeMM,
+	withdrawRoute:            core.APIScopeWithdraw,
+	sendRoute:                core.APIScopeWithdraw,
+}
+
+// handleAddressBook handles requests for addressbook.
+// *msgjson.ResponsePayload.Error is empty if successful.
+func handleAddressBook(s *RPCServer, _ *RawParams) *msgjson.ResponsePayload {
+	entries, err := s.core.AddressBook()
+	if err != nil {
+		resErr := msgjson.NewError(msgjson.RPCWithdrawalPolicyError, "unable to retrieve address book: %v", err)
+		return createResponse(addressBookRoute, nil, resErr)
+	}
+	return createResponse(addressBookRoute, entries, nil)
+}
+
+// handleAddAddress handles requests for addaddress.
+// *msgjson.ResponsePayload.Error is empty if successful.
+func handleAddAddress(s *RPCServer, params *RawParams) *msgjson.ResponsePayload {
+	form, err := parseAddressBookArgs(params, true)
+	if err != nil {
+		return usage(addAddressRoute, err)
+	}
+	defer form.appPass.Clear()
+
+	entry, err := s.core.AddAddressBookEntry(form.appPass, form.assetID, form.address, form.label)
+	if err != nil {
+		resErr := msgjson.NewError(msgjson.RPCWithdrawalPolicyError, "unable to add address: %v", err)
+		return createResponse(addAddressRoute, nil, resErr)
+	}
+	return createResponse(addAddressRoute, entry, nil)
+}
+
+// handleRemoveAddress handles requests for removeaddress.
+// *msgjson.ResponsePayload.Error is empty if successful.
+func handleRemoveAddress(s *RPCServer, params *RawParams) *msgjson.ResponsePayload {
+	form, err := parseAddressBookArgs(params, false)
+	if err != nil {
+		return usage(removeAddressRoute, err)
+	}
+	defer form.appPass.Clear()
+
+	if err := s.core.RemoveAddressBookEntry(form.appPass, form.assetID, form.address); err != nil {
+		resErr := msgjson.NewError(msgjson.RPCWithdrawalPolicyError, "unable to remove address: %v", err)
+		return createResponse(removeAddressRoute, nil, resErr)
+	}
+	return createResponse(removeAddressRoute, fmt.Sprintf(addressRemovedStr, form.address), nil)
+}
+
+// handleWithdrawalPolicies handles requests for withdrawalpolicies.
+// *msgjson.ResponsePayload.Error is empty if successful.
+func handleWithdrawalPolicies(s *RPCServer, _ *RawParams) *msgjson.ResponsePayload {
+	policies, err := s.core.WithdrawalPolicies()
+	if err != nil {
+		resErr := msgjson.NewError(msgjson.RPCWithdrawalPolicyError, "unable to retrieve withdrawal policies: %v", err)
+		return createResponse(withdrawalPoliciesRoute, nil, resErr)
+	}
+	return createResponse(withdrawalPoliciesRoute, policies, nil)
+}
+
+// handleSetWithdrawalPolicy handles requests for setwithdrawalpolicy.
+// *msgjson.ResponsePayload.Error is empty if successful.
+func handleSetWithdrawalPolicy(s *RPCServer, params *RawParams) *msgjson.ResponsePayload {
+	form, err := parseSetWithdrawalPolicyArgs(params)
+	if err != nil {
+		return usage(setWithdrawalPolicyRoute, err)
+	}
+	defer form.appPass.Clear()
+
+	if err := s.core.SetWithdrawalPolicy(form.appPass, form.assetID, form.allowlistOnly, form.dailyLimit); err != nil {
+		resErr := msgjson.NewError(msgjson.RPCWithdrawalPolicyError, "unable to set withdrawal policy: %v", err)
+		return createResponse(setWithdrawalPolicyRoute, nil, resErr)
+	}
+	return createResponse(setWithdrawalPolicyRoute, policySetStr, nil)
+}
+
+// handleCreateConditionalOrder handles requests for createconditionalorder.
+// *msgjson.ResponsePayload.Error is empty if successful.
+func handleCreateConditionalOrder(s *RPCServer, params *RawParams) *msgjson.ResponsePayload {
+	form, err := parseCreateConditionalOrderArgs(params)
+	if err != nil {
+		return usage(createCondOrderRoute, err)
+	}
+	defer form.appPass.Clear()
+
+	ord, err := s.core.CreateConditionalOrder(form.appPass, form.form)
+	if err != nil {
+		resErr := msgjson.NewError(msgjson.RPCConditionalOrderError, "unable to create conditional order: %v", err)
+		return createResponse(createCondOrderRoute, nil, resErr)
+	}
+	return createResponse(createCondOrderRoute, ord, nil)
+}
+
+// handleConditionalOrders handles requests for conditionalorders.
+// *msgjson.ResponsePayload.Error is empty if successful.
+func handleConditionalOrders(s *RPCServer, _ *RawParams) *msgjson.ResponsePayload {
+	ords, err := s.core.ConditionalOrders()
+	if err != nil {
+		resErr := msgjson.NewError(msgjson.RPCConditionalOrderError, "unable to retrieve conditional orders: %v", err)
+		return createResponse(condOrdersRoute, nil, resErr)
+	}
+	return createResponse(condOrdersRoute, ords, nil)
+}
+
+// handleRemoveConditionalOrder handles requests for removeconditionalorder.
+// *msgjson.ResponsePayload.Error is empty if successful.
+func handleRemoveConditionalOrder(s *RPCServer, params *RawParams) *msgjson.ResponsePayload {
+	if err := checkNArgs(params, []int{0}, []int{1}); err != nil {
+		return usage(removeCondOrderRoute, err)
+	}
+	id := params.Args[0]
+	if err := s.core.RemoveConditionalOrder(id); err != nil {
+		resErr := msgjson.NewError(msgjson.RPCConditionalOrderError, "unable to remove conditional order: %v", err)
+		return createResponse(removeCondOrderRoute, nil, resErr)
+	}
+	return createResponse(removeCondOrderRoute, fmt.Sprintf(condOrderRemovedStr, id), nil)
+}
+
+// handleCreateManagedOrder handles requests for createmanagedorder.
+// *msgjson.ResponsePayload.Error is empty if successful.
+func handleCreateManagedOrder(s *RPCServer, params *RawParams) *msgjson.ResponsePayload {
+	form, err := parseCreateManagedOrderArgs(params)
+	if err != nil {
+		return usage(createManagedOrderRoute, err)
+	}
+	defer form.appPass.Clear()
+
+	ord, err := s.core.CreateManagedOrder(form.appPass, form.form)
+	if err != nil {
+		resErr := msgjson.NewError(msgjson.RPCManagedOrderError, "unable to create managed order: %v", err)
+		return createResponse(createManagedOrderRoute, nil, resErr)
+	}
+	return createResponse(createManagedOrderRoute, ord, nil)
+}
+
+// handleManagedOrders handles requests for managedorders.
+// *msgjson.ResponsePayload.Error is empty if successful.
+func handleManagedOrders(s *RPCServer, _ *RawParams) *msgjson.ResponsePayload {
+	ords, err := s.core.ManagedOrders()
+	if err != nil {
+		resErr := msgjson.NewError(msgjson.RPCManagedOrderError, "unable to retrieve managed orders: %v", err)
+		return createResponse(managedOrdersRoute, nil, resErr)
+	}
+	return createResponse(managedOrdersRoute, ords, nil)
+}
+
+// handleCancelManagedOrder handles requests for cancelmanagedorder.
+// *msgjson.ResponsePayload.Error is empty if successful.
+func handleCancelManagedOrder(s *RPCServer, params *RawParams) *msgjson.ResponsePayload {
+	if err := checkNArgs(params, []int{0}, []int{1}); err != nil {
+		return usage(cancelManagedOrderRoute, err)
+	}
+	id := params.Args[0]
+	if err := s.core.CancelManagedOrder(id); err != nil {
+		resErr := msgjson.NewError(msgjson.RPCManagedOrderError, "unable to cancel managed order: %v", err)
+		return createResponse(cancelManagedOrderRoute, nil, resErr)
+	}
+	return createResponse(cancelManagedOrderRoute, fmt.Sprintf(managedCanceledStr, id), nil)
+}
+
+// handleExportTaxHistory handles requests for exporttaxhistory.
+// *msgjson.ResponsePayload.Error is empty if successful.
+func handleExportTaxHistory(s *RPCServer, params *RawParams) *msgjson.ResponsePayload {
+	form, err := parseExportTaxHistoryArgs(params)
+	if err != nil {
+		return usage(exportTaxHistoryRoute, err)
+	}
+	n, err := s.core.ExportTaxHistoryFile(form.path, form.form)
+	if err != nil {
+		resErr := msgjson.NewError(msgjson.RPCTaxExportError, "unable to export tax history: %v", err)
+		return createResponse(exportTaxHistoryRoute, nil, resErr)
+	}
+	return createResponse(exportTaxHistoryRoute, fmt.Sprintf(taxExportedStr, n, form.path), nil)
 }
 
 // handleHelp handles requests for help. Returns general help for all commands
@@ -584,7 +847,12 @@ func send(s *RPCServer, params *RawParams, route string) *msgjson.ResponsePayloa
 		resErr := msgjson.NewError(msgjson.RPCFundTransferError, "empty pass")
 		return createResponse(route, nil, resErr)
 	}
-	coin, err := s.core.Send(form.appPass, form.assetID, form.value, form.address, subtract)
+	var coin asset.Coin
+	if len(form.coinIDs) > 0 {
+		coin, err = s.core.SendWithCoins(form.appPass, form.assetID, form.value, form.address, subtract, form.coinIDs)
+	} else {
+		coin, err = s.core.Send(form.appPass, form.assetID, form.value, form.address, subtract)
+	}
 	if err != nil {
 		resErr := msgjson.NewError(msgjson.RPCFundTransferError, "unable to %s: %v", route, err)
 		return createResponse(route, nil, resErr)
@@ -739,6 +1007,28 @@ func handleMyOrders(s *RPCServer, params *RawParams) *msgjson.ResponsePayload {
 	}
 	var myOrders myOrdersResponse
 	filterMkts := form.base != nil && form.quote != nil
+	if form.archived {
+		filter := new(core.OrderFilter)
+		filter.Archived = true
+		if form.host != "" {
+			filter.Hosts = []string{form.host}
+		}
+		if filterMkts {
+			filter.Market = &struct {
+				Base  uint32 `json:"baseID"`
+				Quote uint32 `json:"quoteID"`
+			}{*form.base, *form.quote}
+		}
+		ords, err := s.core.Orders(filter)
+		if err != nil {
+			resErr := msgjson.NewError(msgjson.RPCArchivedOrdersError, "unable to retrieve archived orders: %v", err)
+			return createResponse(myOrdersRoute, nil, resErr)
+		}
+		for _, ord := range ords {
+			myOrders = append(myOrders, parseCoreOrder(ord, ord.BaseID, ord.QuoteID))
+		}
+		return createResponse(myOrdersRoute, myOrders, nil)
+	}
 	exchanges := s.core.Exchanges()
 	for host, exchange := range exchanges {
 		if form.host != "" && form.host != host {
@@ -1058,6 +1348,189 @@ func handleWalletTx(s *RPCServer, params *RawParams) *msgjson.ResponsePayload {
 	return createResponse(walletTxRoute, tx, nil)
 }
 
+// handlePrivacyReport handles requests for privacyreport.
+// *msgjson.ResponsePayload.Error is empty if successful.
+func handlePrivacyReport(s *RPCServer, params *RawParams) *msgjson.ResponsePayload {
+	assetID, err := parseListUTXOsArgs(params)
+	if err != nil {
+		return usage(privacyReportRoute, err)
+	}
+
+	report, err := s.core.PrivacyReport(assetID)
+	if err != nil {
+		resErr := msgjson.NewError(msgjson.RPCTxHistoryError, "unable to get privacy report: %v", err)
+		return createResponse(privacyReportRoute, nil, resErr)
+	}
+
+	return createResponse(privacyReportRoute, report, nil)
+}
+
+// handleListUTXOs handles requests for listutxos. *msgjson.ResponsePayload.Error
+// is empty if successful.
+func handleListUTXOs(s *RPCServer, params *RawParams) *msgjson.ResponsePayload {
+	assetID, err := parseListUTXOsArgs(params)
+	if err != nil {
+		return usage(listUTXOsRoute, err)
+	}
+
+	utxos, err := s.core.ListUTXOs(assetID)
+	if err != nil {
+		resErr := msgjson.NewError(msgjson.RPCCoinControlError, "unable to list utxos: %v", err)
+		return createResponse(listUTXOsRoute, nil, resErr)
+	}
+
+	return createResponse(listUTXOsRoute, utxos, nil)
+}
+
+// handleSetUTXOLabel handles requests for setutxolabel.
+// *msgjson.ResponsePayload.Error is empty if successful.
+func handleSetUTXOLabel(s *RPCServer, params *RawParams) *msgjson.ResponsePayload {
+	form, err := parseSetUTXOLabelArgs(params)
+	if err != nil {
+		return usage(setUTXOLabelRoute, err)
+	}
+
+	if err := s.core.SetUTXOLabel(form.assetID, form.coinID, form.label); err != nil {
+		resErr := msgjson.NewError(msgjson.RPCCoinControlError, "unable to set utxo label: %v", err)
+		return createResponse(setUTXOLabelRoute, nil, resErr)
+	}
+
+	return createResponse(setUTXOLabelRoute, utxoLabelSetStr, nil)
+}
+
+// handleFreezeUTXOs handles requests for freezeutxos.
+// *msgjson.ResponsePayload.Error is empty if successful.
+func handleFreezeUTXOs(s *RPCServer, params *RawParams) *msgjson.ResponsePayload {
+	form, err := parseFreezeUTXOsArgs(params)
+	if err != nil {
+		return usage(freezeUTXOsRoute, err)
+	}
+
+	if err := s.core.FreezeUTXOs(form.assetID, form.coinIDs, form.freeze); err != nil {
+		resErr := msgjson.NewError(msgjson.RPCCoinControlError, "unable to freeze utxos: %v", err)
+		return createResponse(freezeUTXOsRoute, nil, resErr)
+	}
+
+	resStr := utxosFrozenStr
+	if !form.freeze {
+		resStr = utxosUnfrozenStr
+	}
+	return createResponse(freezeUTXOsRoute, fmt.Sprintf(resStr, len(form.coinIDs)), nil)
+}
+
+// backupFiles are the files to include in a full-state backup, in addition to
+// the app DB.
+func (s *RPCServer) backupFiles() []*core.BackupFile {
+	if s.mm == nil {
+		return nil
+	}
+	return s.mm.BackupFiles()
+}
+
+// handleExportBackup handles requests for exportbackup.
+// *msgjson.ResponsePayload.Error is empty if successful.
+func handleExportBackup(s *RPCServer, params *RawParams) *msgjson.ResponsePayload {
+	form, err := parseBackupArgs(params)
+	if err != nil {
+		return usage(exportBackupRoute, err)
+	}
+	defer form.appPass.Clear()
+
+	b, err := s.core.ExportBackup(form.appPass, s.backupFiles())
+	if err != nil {
+		resErr := msgjson.NewError(msgjson.RPCExportBackupError, "unable to export backup: %v", err)
+		return createResponse(exportBackupRoute, nil, resErr)
+	}
+	// Don't overwrite an existing file.
+	f, err := os.OpenFile(form.path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0600)
+	if err == nil {
+		_, err = f.Write(b)
+		if closeErr := f.Close(); err == nil {
+			err = closeErr
+		}
+	}
+	if err != nil {
+		resErr := msgjson.NewError(msgjson.RPCExportBackupError, "unable to write backup file: %v", err)
+		return createResponse(exportBackupRoute, nil, resErr)
+	}
+
+	return createResponse(exportBackupRoute, fmt.Sprintf(backupWrittenStr, form.path), nil)
+}
+
+// handleRestoreBackup handles requests for restorebackup.
+// *msgjson.ResponsePayload.Error is empty if successful.
+func handleRestoreBackup(s *RPCServer, params *RawParams) *msgjson.ResponsePayload {
+	form, err := parseBackupArgs(params)
+	if err != nil {
+		return usage(restoreBackupRoute, err)
+	}
+	defer form.appPass.Clear()
+
+	b, err := os.ReadFile(form.path)
+	if err != nil {
+		resErr := msgjson.NewError(msgjson.RPCRestoreBackupError, "unable to read backup file: %v", err)
+		return createResponse(restoreBackupRoute, nil, resErr)
+	}
+	manifest, err := s.core.RestoreBackup(form.appPass, b, s.backupFiles())
+	if err != nil {
+		resErr := msgjson.NewError(msgjson.RPCRestoreBackupError, "unable to restore backup: %v", err)
+		return createResponse(restoreBackupRoute, nil, resErr)
+	}
+
+	created := time.UnixMilli(manifest.Created).Format(time.RFC3339)
+	return createResponse(restoreBackupRoute, fmt.Sprintf(backupRestoredStr, created), nil)
+}
+
+// handleCreateAPIKey handles requests for createapikey.
+// *msgjson.ResponsePayload.Error is empty if successful.
+func handleCreateAPIKey(s *RPCServer, params *RawParams) *msgjson.ResponsePayload {
+	form, err := parseCreateAPIKeyArgs(params)
+	if err != nil {
+		return usage(createAPIKeyRoute, err)
+	}
+	defer form.appPass.Clear()
+
+	key, token, err := s.core.CreateAPIKey(form.appPass, form.form)
+	if err != nil {
+		resErr := msgjson.NewError(msgjson.RPCAPIKeyError, "unable to create api key: %v", err)
+		return createResponse(createAPIKeyRoute, nil, resErr)
+	}
+
+	res := &struct {
+		Key   *core.APIKey `json:"key"`
+		Token string       `json:"token"`
+	}{
+		Key:   key,
+		Token: token,
+	}
+	return createResponse(createAPIKeyRoute, res, nil)
+}
+
+// handleListAPIKeys handles requests for listapikeys.
+// *msgjson.ResponsePayload.Error is empty if successful.
+func handleListAPIKeys(s *RPCServer, _ *RawParams) *msgjson.ResponsePayload {
+	keys, err := s.core.APIKeys()
+	if err != nil {
+		resErr := msgjson.NewError(msgjson.RPCAPIKeyError, "unable to list api keys: %v", err)
+		return createResponse(listAPIKeysRoute, nil, resErr)
+	}
+	return createResponse(listAPIKeysRoute, keys, nil)
+}
+
+// handleRevokeAPIKey handles requests for revokeapikey.
+// *msgjson.ResponsePayload.Error is empty if successful.
+func handleRevokeAPIKey(s *RPCServer, params *RawParams) *msgjson.ResponsePayload {
+	id, err := parseRevokeAPIKeyArgs(params)
+	if err != nil {
+		return usage(revokeAPIKeyRoute, err)
+	}
+	if err := s.core.RevokeAPIKey(id); err != nil {
+		resErr := msgjson.NewError(msgjson.RPCAPIKeyError, "unable to revoke api key: %v", err)
+		return createResponse(revokeAPIKeyRoute, nil, resErr)
+	}
+	return createResponse(revokeAPIKeyRoute, fmt.Sprintf(apiKeyRevokedStr, id), nil)
+}
+
 func handleWithdrawBchSpv(s *RPCServer, params *RawParams) *msgjson.ResponsePayload {
 	appPW, recipient, err := parseBchWithdrawArgs(params)
 	if err != nil {
@@ -1422,7 +1895,7 @@ var helpMsgs = map[string]helpMsg{
 	},
 	tradeRoute: {
 		pwArgsShort: `"appPass"`,
-		argsShort:   `"host" isLimit sell base quote qty rate immediate`,
+		argsShort:   `"host" isLimit sell base quote qty rate immediate options (coins)`,
 		cmdSummary:  `Make an order to buy or sell an asset.`,
 		pwArgsLong: `Password Args:
     appPass (string): The Bison Wallet password.`,
@@ -1437,7 +1910,9 @@ var helpMsgs = map[string]helpMsg{
       156000 satoshi/DCR for the DCR(base)_BTC(quote).
     immediate (bool): Require immediate match. Do not book the order.
     options (string): A JSON-encoded string->string mapping of additional
-       trade options.`,
+       trade options.
+    coins (string): Optional. A JSON-encoded array of hex coin IDs. If set,
+      the order is funded only with these coins. See listutxos.`,
 		returns: `Returns:
     obj: The order details.
     {
@@ -1503,7 +1978,7 @@ needed to complete a swap.`,
 	},
 	withdrawRoute: {
 		pwArgsShort: `"appPass"`,
-		argsShort:   `assetID value "address"`,
+		argsShort:   `assetID value "address" (coins)`,
 		cmdSummary:  `Withdraw value from an exchange wallet to address. Fees are subtracted from the value.`,
 		pwArgsLong: `Password Args:
     appPass (string): The Bison Wallet password.`,
@@ -1513,13 +1988,15 @@ needed to complete a swap.`,
       https://github.com/satoshilabs/slips/blob/master/slip-0044.md
     value (int): The amount to withdraw in units of the asset's smallest
       denomination (e.g. satoshis, atoms, etc.)"
-    address (string): The address to which withdrawn funds are sent.`,
+    address (string): The address to which withdrawn funds are sent.
+    coins (string): Optional. A JSON-encoded array of hex coin IDs. If set,
+      only these coins may be spent. See listutxos.`,
 		returns: `Returns:
     string: "[coin ID]"`,
 	},
 	sendRoute: {
 		pwArgsShort: `"appPass"`,
-		argsShort:   `assetID value "address"`,
+		argsShort:   `assetID value "address" (coins)`,
 		cmdSummary:  `Sends exact value from an exchange wallet to address.`,
 		pwArgsLong: `Password Args:
     appPass (string): The Bison Wallet password.`,
@@ -1529,7 +2006,9 @@ needed to complete a swap.`,
       https://github.com/satoshilabs/slips/blob/master/slip-0044.md
     value (int): The amount to send in units of the asset's smallest
       denomination (e.g. satoshis, atoms, etc.)"
-    address (string): The address to which funds are sent.`,
+    address (string): The address to which funds are sent.
+    coins (string): Optional. A JSON-encoded array of hex coin IDs. If set,
+      only these coins may be spent. See listutxos.`,
 		returns: `Returns:
     string: "[coin ID]"`,
 	},
@@ -1584,13 +2063,16 @@ needed to complete a swap.`,
     }`,
 	},
 	myOrdersRoute: {
-		argsShort: `("host") (base) (quote)`,
+		argsShort: `("host") (base) (quote) (archived)`,
 		cmdSummary: `Fetch all active and recently executed orders
-    belonging to the user.`,
+    belonging to the user, or the orders that were moved to the archive
+    database by the retention policy.`,
 		argsLong: `Args:
     host (string): Optional. The DEX to show orders from.
     base (int): Optional. The BIP-44 coin index for the market's base asset.
-    quote (int): Optional. The BIP-44 coin index for the market's quote asset.`,
+    quote (int): Optional. The BIP-44 coin index for the market's quote asset.
+    archived (bool): Optional. Show archived orders instead. base and quote
+      may be blank to show all markets. Default is false.`,
 		returns: `Returns:
   array: An array of orders.
   [
@@ -1837,6 +2319,376 @@ an spv wallet and enables options to view and set the vsp.
 		  assetID (int): The asset's BIP-44 registered coin index.
 		  txID (string): The transaction ID.`,
 	},
+	privacyReportRoute: {
+		argsShort: `assetID`,
+		cmdSummary: `Report how trades with a shielded wallet exposed funds on the
+    transparent chain.`,
+		argsLong: `Args:
+    assetID (int): The asset's BIP-44 registered coin index.`,
+		returns: `Returns:
+    obj: The privacy report.
+    {
+      "trades" (array): The swap, redeem, refund and order funding
+        transactions with transparent outputs, newest first.
+        [{
+          "txID" (string): The transaction ID.
+          "type" (int): The transaction type.
+          "timestamp" (int): The transaction's block time, if mined.
+          "addresses" ([]string): The transparent addresses paid.
+          "reshielded" ([]string): The addresses whose funds have since
+            been moved back into the shielded pool.
+        },...],
+      "transparentBalance" (int): The value still held at transparent
+        addresses, in the asset's smallest denomination.
+    }`,
+	},
+	listUTXOsRoute: {
+		argsShort:  `assetID`,
+		cmdSummary: `List the unspent outputs of a wallet that supports coin control.`,
+		argsLong: `Args:
+    assetID (int): The asset's BIP-44 registered coin index.`,
+		returns: `Returns:
+    array: The wallet's unspent outputs.
+    [{
+      "coinID" (string): The hex coin ID.
+      "txID" (string): The transaction ID.
+      "vout" (int): The output index.
+      "address" (string): The address that received the output.
+      "amount" (int): The value in the asset's smallest denomination.
+      "confs" (int): The number of confirmations.
+      "label" (string): The user's label for the output, if any.
+      "frozen" (bool): Whether the output is excluded from automatic coin
+        selection.
+      "locked" (bool): Whether the output is reserved, e.g. funding an order.
+    },...]`,
+	},
+	setUTXOLabelRoute: {
+		argsShort:  `assetID "coinID" ("label")`,
+		cmdSummary: `Set the label of an unspent output.`,
+		argsLong: `Args:
+    assetID (int): The asset's BIP-44 registered coin index.
+    coinID (string): The hex coin ID of the output.
+    label (string): Optional. The label. If empty or unset, any existing
+      label is removed.`,
+		returns: `Returns:
+    string: The message "` + utxoLabelSetStr + `"`,
+	},
+	freezeUTXOsRoute: {
+		argsShort:  `assetID coins (freeze)`,
+		cmdSummary: `Freeze or unfreeze unspent outputs. Frozen outputs are not selected automatically for sends, bonds, or order funding.`,
+		argsLong: `Args:
+    assetID (int): The asset's BIP-44 registered coin index.
+    coins (string): A JSON-encoded array of hex coin IDs.
+    freeze (bool): Optional. Whether to freeze or unfreeze the outputs. The
+      default is true.`,
+		returns: `Returns:
+    string: The message "[n] utxos frozen" or "[n] utxos unfrozen".`,
+	},
+	exportBackupRoute: {
+		pwArgsShort: `"appPass"`,
+		argsShort:   `path`,
+		cmdSummary: `Write an encrypted backup of the application state, including the
+  app database with wallet and DEX account settings, and the market making
+  configuration and event log. The backup is encrypted with the app password.`,
+		pwArgsLong: `Password Args:
+    appPass (string): The Bison Wallet password.`,
+		argsLong: `Args:
+    path (string): The path of the backup file to create. An existing file is
+      not overwritten.`,
+		returns: `Returns:
+    string: The message "` + fmt.Sprintf(backupWrittenStr, "[path]") + `"`,
+	},
+	restoreBackupRoute: {
+		pwArgsShort: `"appPass"`,
+		argsShort:   `path`,
+		cmdSummary: `Restore a backup created with exportbackup. The backup can only be
+  restored before the app is initialized. The restoration is completed when
+  Bison Wallet is restarted, after which the app password is the password
+  used to create the backup.`,
+		pwArgsLong: `Password Args:
+    appPass (string): The app password used to create the backup.`,
+		argsLong: `Args:
+    path (string): The path of the backup file.`,
+		returns: `Returns:
+    string: The message "` + fmt.Sprintf(backupRestoredStr, "[time]") + `"`,
+	},
+	createAPIKeyRoute: {
+		pwArgsShort: `"appPass"`,
+		argsShort:   `name scopes (allowlist expiry)`,
+		cmdSummary: `Create a scoped API key for the RPC server. API key clients authenticate
+  with the HTTP header "Authorization: Bearer [token]". API keys are stored
+  encrypted with the app password, and can only be used while the app is
+  logged in. Routes that are not covered by a scope, such as login, appseed,
+  and API key management, require the RPC user and password.`,
+		pwArgsLong: `Password Args:
+    appPass (string): The Bison Wallet password.`,
+		argsLong: `Args:
+    name (string): A name for the key.
+    scopes (string): A comma-separated list of scopes. Valid scopes are
+      "` + core.APIScopeRead + `", for routes that do not change state,
+      "` + core.APIScopeTrade + `", for trade, multitrade, and cancel,
+      "` + core.APIScopeMM + `", for market making bot control, and
+      "` + core.APIScopeWithdraw + `", for send and withdraw.
+    allowlist (string): Optional. A JSON-encoded map of asset IDs to arrays of
+      addresses. If set, send and withdraw are only allowed to the listed
+      addresses. e.g. '{"42": ["Dsaddr"]}'. Use "" for no allowlist.
+    expiry (string): Optional. The duration for which the key is valid,
+      e.g. "720h". If not set, the key does not expire.`,
+		returns: `Returns:
+    obj: The API key and token. The token is not shown again.
+    {
+      "key": {
+        "id" (string): The key ID.
+        "name" (string): The key name.
+        "scopes" ([]string): The key's scopes.
+        "addressAllowlist" (obj): The address allowlist, if set.
+        "expiration" (int): The UNIX time, in seconds, that the key expires,
+          if set.
+        "created" (int): The UNIX time, in seconds, that the key was created.
+      },
+      "token" (string): The token for the Authorization header.
+    }`,
+	},
+	listAPIKeysRoute: {
+		cmdSummary: `List the API keys. The app must be logged in.`,
+		returns: `Returns:
+    array: An array of API keys, as returned by createapikey.`,
+	},
+	revokeAPIKeyRoute: {
+		argsShort:  `id`,
+		cmdSummary: `Revoke an API key.`,
+		argsLong: `Args:
+    id (string): The key ID.`,
+		returns: `Returns:
+    string: The message "` + fmt.Sprintf(apiKeyRevokedStr, "[id]") + `"`,
+	},
+	addressBookRoute: {
+		cmdSummary: `List the withdrawal address book.`,
+		returns: `Returns:
+    array: The address book entries.
+    [{
+      "assetID" (int): The asset's BIP-44 registered coin index.
+      "symbol" (string): The asset's ticker symbol.
+      "address" (string): The address.
+      "label" (string): The address label.
+      "added" (int): The time the address was added, in milliseconds.
+      "activeTime" (int): The time, in milliseconds, after which the address
+        can be used when the asset's withdrawal policy is allowlist only.
+      "active" (bool): Whether the address can be used.
+    },...]`,
+	},
+	addAddressRoute: {
+		pwArgsShort: `"appPass"`,
+		argsShort:   `assetID "address" ("label")`,
+		cmdSummary: `Add an address to the withdrawal address book, or update the label of an
+  existing address. New addresses can't be used as withdrawal destinations
+  until a delay has passed, 24 hours by default.`,
+		pwArgsLong: `Password Args:
+    appPass (string): The Bison Wallet password.`,
+		argsLong: `Args:
+    assetID (int): The asset's BIP-44 registered coin index.
+    address (string): The address.
+    label (string): Optional. A label for the address.`,
+		returns: `Returns:
+    obj: The address book entry, as returned by addressbook.`,
+	},
+	removeAddressRoute: {
+		pwArgsShort: `"appPass"`,
+		argsShort:   `assetID "address"`,
+		cmdSummary:  `Remove an address from the withdrawal address book.`,
+		pwArgsLong: `Password Args:
+    appPass (string): The Bison Wallet password.`,
+		argsLong: `Args:
+    assetID (int): The asset's BIP-44 registered coin index.
+    address (string): The address.`,
+		returns: `Returns:
+    string: The message "` + fmt.Sprintf(addressRemovedStr, "[address]") + `"`,
+	},
+	withdrawalPoliciesRoute: {
+		cmdSummary: `List the withdrawal policies.`,
+		returns: `Returns:
+    array: The withdrawal policies in effect.
+    [{
+      "assetID" (int): The asset's BIP-44 registered coin index.
+      "symbol" (string): The asset's ticker symbol.
+      "allowlistOnly" (bool): Whether withdrawals are restricted to active
+        address book entries.
+      "dailyLimit" (int): The maximum amount, in the asset's smallest
+        denomination, that can be withdrawn in 24 hours. 0 means no limit.
+      "withdrawn" (int): The amount withdrawn in the last 24 hours.
+      "pending" (obj): A less restrictive policy that is not yet in effect, if
+        any, with "allowlistOnly", "dailyLimit", and "activeTime", the time in
+        milliseconds after which it is in effect.
+    },...]`,
+	},
+	setWithdrawalPolicyRoute: {
+		pwArgsShort: `"appPass"`,
+		argsShort:   `assetID allowlistOnly (dailyLimit)`,
+		cmdSummary: `Set the withdrawal policy for an asset. The policy applies to send and
+  withdraw. A policy that is at least as restrictive as the current policy
+  takes effect immediately. A less restrictive policy takes effect after a
+  delay, 24 hours by default.`,
+		pwArgsLong: `Password Args:
+    appPass (string): The Bison Wallet password.`,
+		argsLong: `Args:
+    assetID (int): The asset's BIP-44 registered coin index.
+    allowlistOnly (bool): Whether to restrict withdrawals to active address
+      book entries.
+    dailyLimit (int): Optional. The maximum amount, in the asset's smallest
+      denomination, that can be withdrawn in 24 hours. The default is 0, no
+      limit.`,
+		returns: `Returns:
+    string: The message "` + policySetStr + `"`,
+	},
+	createCondOrderRoute: {
+		pwArgsShort: `"appPass"`,
+		argsShort:   `"host" "type" isLimit sell base quote qty rate "trigger"`,
+		cmdSummary: `Create a conditional order. The order is placed when the market rate, the
+  book's mid-gap or the latest candle's close rate, crosses the trigger.
+  Conditional orders are watched while the app is logged in, and persist
+  across restarts. The order is placed without the app password, so the
+  wallets must remain unlocked.`,
+		pwArgsLong: `Password Args:
+    appPass (string): The Bison Wallet password.`,
+		argsLong: `Args:
+    host (string): The DEX to trade on.
+    type (string): The conditional order type. "` + core.CondStopLoss + `" places the
+      order when the rate falls to the trigger for a sell, or rises to the
+      trigger for a buy. "` + core.CondTakeProfit + `" places the order when the
+      rate rises to the trigger for a sell, or falls to the trigger for a
+      buy. "` + core.CondTrailingStop + `" places the order when the rate falls
+      by the trailing percentage from its high for a sell, or rises by the
+      trailing percentage from its low for a buy.
+    isLimit (bool): Whether to place a limit order. Otherwise, a market order
+      is placed.
+    sell (bool): Whether the order is a sell.
+    base (int): The BIP-44 coin index for the market's base asset.
+    quote (int): The BIP-44 coin index for the market's quote asset.
+    qty (int): The number of units to buy/sell. Must be a multiple of the lot
+      size. For market buys, the quantity is in units of the quote asset.
+    rate (int): The atoms quote asset to pay/accept per unit base asset for a
+      limit order. Ignored for market orders.
+    trigger (string): The trigger rate, in the same units as rate, for
+      stop-loss and take-profit orders, or the trailing percentage, e.g. "5",
+      for trailing stops.`,
+		returns: `Returns:
+    obj: The conditional order.
+    {
+      "id" (string): The conditional order ID.
+      "host" (string): The DEX.
+      "base" (int): The base asset.
+      "quote" (int): The quote asset.
+      "type" (string): The conditional order type.
+      "sell" (bool): Whether the order is a sell.
+      "qty" (int): The order quantity.
+      "isLimit" (bool): Whether the order is a limit order.
+      "rate" (int): The limit order rate.
+      "triggerRate" (int): The trigger rate.
+      "trailPercent" (float): The trailing percentage.
+      "extreme" (int): The high rate for a trailing stop sell, or the low rate
+        for a trailing stop buy.
+      "stamp" (int): The creation time, in milliseconds.
+      "status" (string): "` + core.CondStatusActive + `", "` + core.CondStatusTriggered + `", "` + core.CondStatusPlaced + `", or "` + core.CondStatusFailed + `".
+      "orderID" (string): The ID of the placed order.
+      "error" (string): The error placing the order, if failed.
+    }`,
+	},
+	condOrdersRoute: {
+		cmdSummary: `List the conditional orders, newest first.`,
+		returns: `Returns:
+    array: The conditional orders, as returned by createconditionalorder.`,
+	},
+	removeCondOrderRoute: {
+		argsShort:  `"id"`,
+		cmdSummary: `Cancel an active conditional order, or remove a triggered one from the list.`,
+		argsLong: `Args:
+    id (string): The conditional order ID.`,
+		returns: `Returns:
+    string: The message "` + fmt.Sprintf(condOrderRemovedStr, "[id]") + `"`,
+	},
+	createManagedOrderRoute: {
+		pwArgsShort: `"appPass"`,
+		argsShort:   `"host" "type" sell base quote qty rate slicesOrClip ("duration")`,
+		cmdSummary: `Create a TWAP or iceberg order. The parent limit order is executed as a
+  series of smaller child limit orders, which are placed while the app is
+  logged in. Child orders are placed without the app password, so the wallets
+  must remain unlocked.`,
+		pwArgsLong: `Password Args:
+    appPass (string): The Bison Wallet password.`,
+		argsLong: `Args:
+    host (string): The DEX to trade on.
+    type (string): "` + core.ManagedTWAP + `" splits the order into equal slices placed at
+      regular intervals over the duration. A slice still booked when the next
+      one is due is canceled, and its unfilled quantity is carried into the
+      next slice. "` + core.ManagedIceberg + `" keeps one child order of at most the clip
+      size on the book, and places the next when it is filled.
+    sell (bool): Whether the order is a sell.
+    base (int): The BIP-44 coin index for the market's base asset.
+    quote (int): The BIP-44 coin index for the market's quote asset.
+    qty (int): The number of units to buy/sell. Must be a multiple of the lot
+      size.
+    rate (int): The atoms quote asset to pay/accept per unit base asset.
+    slicesOrClip (int): The number of slices for a TWAP order, or the visible
+      quantity of an iceberg order, which must be a multiple of the lot size.
+    duration (string): The TWAP duration, e.g. "2h". TWAP orders only.`,
+		returns: `Returns:
+    obj: The managed order.
+    {
+      "id" (string): The managed order ID.
+      "host" (string): The DEX.
+      "base" (int): The base asset.
+      "quote" (int): The quote asset.
+      "type" (string): The managed order type.
+      "sell" (bool): Whether the order is a sell.
+      "qty" (int): The order quantity.
+      "rate" (int): The order rate.
+      "slices" (int): The number of TWAP slices.
+      "duration" (int): The TWAP duration, in milliseconds.
+      "clip" (int): The iceberg clip size.
+      "stamp" (int): The creation time, in milliseconds.
+      "status" (string): "` + core.ManagedStatusActive + `", "` + core.ManagedStatusComplete + `", "` + core.ManagedStatusCanceled + `", or "` + core.ManagedStatusFailed + `".
+      "filled" (int): The quantity filled by the child orders.
+      "children" (array): The child order IDs, oldest first.
+      "activeChild" (string): The ID of the child order that is not complete.
+      "error" (string): The error placing a child order, if failed.
+    }`,
+	},
+	managedOrdersRoute: {
+		cmdSummary: `List the managed orders, newest first.`,
+		returns: `Returns:
+    array: The managed orders, as returned by createmanagedorder.`,
+	},
+	cancelManagedOrderRoute: {
+		argsShort:  `"id"`,
+		cmdSummary: `Stop an active managed order and cancel its active child order.`,
+		argsLong: `Args:
+    id (string): The managed order ID.`,
+		returns: `Returns:
+    string: The message "` + fmt.Sprintf(managedCanceledStr, "[id]") + `"`,
+	},
+	exportTaxHistoryRoute: {
+		argsShort: `"path" ("format") (from) (to) ("ratesFile") ("currency")`,
+		cmdSummary: `Export trade fills, swap, redeem, and refund fees, bond posts and refunds,
+  and wallet sends and receives as a CSV file for tax reporting. Wallet
+  transactions are only included for connected wallets that record their
+  transaction history. Fiat values are included if a historical rates file is
+  specified.`,
+		argsLong: `Args:
+    path (string): The path of the CSV file to create. The file must not exist.
+    format (string): Optional. "` + core.TaxFormatGeneric + `" (default), "` + core.TaxFormatKoinly + `", or "` + core.TaxFormatCoinTracking + `".
+    from (int): Optional. The start of the time range, in unix milliseconds.
+      0 for no limit.
+    to (int): Optional. The end of the time range, in unix milliseconds. 0 for
+      no limit.
+    ratesFile (string): Optional. A CSV file of historical fiat rates. Each
+      line has a date as YYYY-MM-DD or unix seconds, an asset ticker, e.g.
+      "dcr" or "usdc.eth", and the rate. The rate for a record is the most
+      recent rate at or before the record's time.
+    currency (string): Optional. The fiat currency of the rates file. Default
+      is USD.`,
+		returns: `Returns:
+    string: The message "` + fmt.Sprintf(taxExportedStr, 0, "[path]") + `"`,
+	},
 	withdrawBchSpvRoute: {
 		pwArgsShort: `"appPass"`,
 		argsShort:   `recipient`,
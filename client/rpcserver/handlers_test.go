@@ -4,10 +4,13 @@
 package rpcserver
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"os"
+	"path/filepath"
 	"reflect"
 	"strings"
 	"testing"
@@ -376,7 +379,7 @@ func TestHandleNewWallet(t *testing.T) {
 			createWalletErr: test.createWalletErr,
 			openWalletErr:   test.openWalletErr,
 		}
-		r := &RPCServer{core: tc, wsServer: wsServer}
+		r := &RPCServer{core: tc, wsServers: map[string]*websocket.Server{"": wsServer}}
 		payload := handleNewWallet(r, test.params)
 
 		res := ""
@@ -427,7 +430,7 @@ func TestHandleOpenWallet(t *testing.T) {
 	}}
 	for _, test := range tests {
 		tc := &TCore{openWalletErr: test.openWalletErr}
-		r := &RPCServer{core: tc, wsServer: wsServer}
+		r := &RPCServer{core: tc, wsServers: map[string]*websocket.Server{"": wsServer}}
 		payload := handleOpenWallet(r, test.params)
 		res := ""
 		if err := verifyResponse(payload, &res, test.wantErrCode); err != nil {
@@ -458,7 +461,7 @@ func TestHandleCloseWallet(t *testing.T) {
 	}}
 	for _, test := range tests {
 		tc := &TCore{closeWalletErr: test.closeWalletErr}
-		r := &RPCServer{core: tc, wsServer: wsServer}
+		r := &RPCServer{core: tc, wsServers: map[string]*websocket.Server{"": wsServer}}
 		payload := handleCloseWallet(r, test.params)
 		res := ""
 		if err := verifyResponse(payload, &res, test.wantErrCode); err != nil {
@@ -493,7 +496,7 @@ func TestHandleToggleWalletStatus(t *testing.T) {
 	}}
 	for _, test := range tests {
 		tc := &TCore{walletStatusErr: test.walletStatusErr, walletState: &core.WalletState{}}
-		r := &RPCServer{core: tc, wsServer: wsServer}
+		r := &RPCServer{core: tc, wsServers: map[string]*websocket.Server{"": wsServer}}
 		payload := handleToggleWalletStatus(r, test.params)
 		res := ""
 		if err := verifyResponse(payload, &res, test.wantErrCode); err != nil {
@@ -847,6 +850,95 @@ func TestHandleSendAndWithdraw(t *testing.T) {
 			t.Fatal(err)
 		}
 	}
+
+	// Send with coin control.
+	tc := &TCore{coin: tCoin{}}
+	r := &RPCServer{core: tc}
+	coinParams := &RawParams{
+		PWArgs: []encode.PassBytes{pw},
+		Args:   []string{"0", "1000", "abc", `["0a0b", "0c0d"]`},
+	}
+	res := ""
+	if err := verifyResponse(handleSend(r, coinParams), &res, -1); err != nil {
+		t.Fatal(err)
+	}
+	if len(tc.sentCoinIDs) != 2 || tc.sentCoinIDs[1].String() != "0c0d" {
+		t.Fatalf("wrong coins sent: %v", tc.sentCoinIDs)
+	}
+	coinParams.Args[3] = "0a0b"
+	if err := verifyResponse(handleSend(r, coinParams), &res, msgjson.RPCArgumentsError); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestHandleCoinControl(t *testing.T) {
+	utxos := []*asset.WalletUTXO{{CoinID: dex.Bytes{0x0a}, Amount: 1e8, Label: "cold", Frozen: true}}
+	tc := &TCore{utxos: utxos}
+	r := &RPCServer{core: tc}
+
+	var gotUTXOs []*asset.WalletUTXO
+	if err := verifyResponse(handleListUTXOs(r, &RawParams{Args: []string{"0"}}), &gotUTXOs, -1); err != nil {
+		t.Fatal(err)
+	}
+	if len(gotUTXOs) != 1 || gotUTXOs[0].Label != "cold" || !gotUTXOs[0].Frozen {
+		t.Fatalf("wrong utxos returned")
+	}
+	if err := verifyResponse(handleListUTXOs(r, &RawParams{}), &gotUTXOs, msgjson.RPCArgumentsError); err != nil {
+		t.Fatal(err)
+	}
+
+	var res string
+	if err := verifyResponse(handleSetUTXOLabel(r, &RawParams{Args: []string{"0", "0a", "hot"}}), &res, -1); err != nil {
+		t.Fatal(err)
+	}
+	if err := verifyResponse(handleSetUTXOLabel(r, &RawParams{Args: []string{"0", "zz", "hot"}}), &res, msgjson.RPCArgumentsError); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := verifyResponse(handleFreezeUTXOs(r, &RawParams{Args: []string{"0", `["0a"]`}}), &res, -1); err != nil {
+		t.Fatal(err)
+	}
+	if res != "1 utxos frozen" || !tc.frozen["0a"] {
+		t.Fatalf("utxo not frozen: %q", res)
+	}
+	if err := verifyResponse(handleFreezeUTXOs(r, &RawParams{Args: []string{"0", `["0a"]`, "false"}}), &res, -1); err != nil {
+		t.Fatal(err)
+	}
+	if res != "1 utxos unfrozen" || tc.frozen["0a"] {
+		t.Fatalf("utxo not unfrozen: %q", res)
+	}
+
+	tc.coinCtlErr = errors.New("not a coin controller")
+	if err := verifyResponse(handleFreezeUTXOs(r, &RawParams{Args: []string{"0", `["0a"]`}}), &res, msgjson.RPCCoinControlError); err != nil {
+		t.Fatal(err)
+	}
+	if err := verifyResponse(handleListUTXOs(r, &RawParams{Args: []string{"0"}}), &gotUTXOs, msgjson.RPCCoinControlError); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestHandlePrivacyReport(t *testing.T) {
+	report := &asset.PrivacyReport{
+		Trades:             []*asset.TransparentExposure{{TxID: "01", Type: asset.Swap, Addresses: []string{"t1"}}},
+		TransparentBalance: 1e8,
+	}
+	tc := &TCore{privacyReport: report}
+	r := &RPCServer{core: tc}
+
+	var res asset.PrivacyReport
+	if err := verifyResponse(handlePrivacyReport(r, &RawParams{Args: []string{"133"}}), &res, -1); err != nil {
+		t.Fatal(err)
+	}
+	if res.TransparentBalance != 1e8 || len(res.Trades) != 1 || res.Trades[0].TxID != "01" {
+		t.Fatalf("wrong privacy report returned")
+	}
+	if err := verifyResponse(handlePrivacyReport(r, &RawParams{}), &res, msgjson.RPCArgumentsError); err != nil {
+		t.Fatal(err)
+	}
+	tc.privacyReportErr = errors.New("not a privacy reporter")
+	if err := verifyResponse(handlePrivacyReport(r, &RawParams{Args: []string{"133"}}), &res, msgjson.RPCTxHistoryError); err != nil {
+		t.Fatal(err)
+	}
 }
 
 func TestHandleLogout(t *testing.T) {
@@ -1008,6 +1100,24 @@ func TestHandleMyOrders(t *testing.T) {
 			t.Fatal(err)
 		}
 	}
+
+	// Archived orders.
+	tc := &TCore{archivedOrders: []*core.Order{{Host: "127.0.0.1:7232", BaseID: 42, QuoteID: 0, MarketID: "dcr_btc"}}}
+	r := &RPCServer{core: tc}
+	payload := handleMyOrders(r, paramsWithArgs("127.0.0.1:7232", "42", "0", "true"))
+	res := new(myOrdersResponse)
+	if err := verifyResponse(payload, res, -1); err != nil {
+		t.Fatal(err)
+	}
+	if len(*res) != 1 || !tc.ordersFilter.Archived || tc.ordersFilter.Hosts[0] != "127.0.0.1:7232" ||
+		tc.ordersFilter.Market == nil || tc.ordersFilter.Market.Base != 42 {
+		t.Fatalf("wrong archived orders request")
+	}
+	tc.ordersErr = errors.New("")
+	payload = handleMyOrders(r, paramsWithArgs("", "", "", "true"))
+	if err := verifyResponse(payload, res, msgjson.RPCArchivedOrdersError); err != nil {
+		t.Fatal(err)
+	}
 }
 
 func TestParseCoreOrder(t *testing.T) {
@@ -1382,3 +1492,374 @@ func TestHandleSetVotingPreferences(t *testing.T) {
 		}
 	}
 }
+
+func TestHandleBackup(t *testing.T) {
+	tc := &TCore{backup: []byte{0x01, 0x02}}
+	r := &RPCServer{core: tc}
+	path := filepath.Join(t.TempDir(), "bw.backup")
+	params := &RawParams{PWArgs: []encode.PassBytes{encode.PassBytes("abc")}, Args: []string{path}}
+
+	var res string
+	if err := verifyResponse(handleExportBackup(r, params), &res, -1); err != nil {
+		t.Fatal(err)
+	}
+	if b, err := os.ReadFile(path); err != nil || !bytes.Equal(b, tc.backup) {
+		t.Fatalf("backup not written: %v", err)
+	}
+	// Existing files are not overwritten.
+	if err := verifyResponse(handleExportBackup(r, params), &res, msgjson.RPCExportBackupError); err != nil {
+		t.Fatal(err)
+	}
+	if err := verifyResponse(handleExportBackup(r, &RawParams{Args: []string{path}}), &res, msgjson.RPCArgumentsError); err != nil {
+		t.Fatal(err)
+	}
+
+	tc.backup = nil
+	if err := verifyResponse(handleRestoreBackup(r, params), &res, -1); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(tc.backup, []byte{0x01, 0x02}) {
+		t.Fatalf("backup file not restored")
+	}
+	tc.backupErr = errors.New("already initialized")
+	if err := verifyResponse(handleRestoreBackup(r, params), &res, msgjson.RPCRestoreBackupError); err != nil {
+		t.Fatal(err)
+	}
+	params.Args[0] = filepath.Join(t.TempDir(), "missing")
+	tc.backupErr = nil
+	if err := verifyResponse(handleRestoreBackup(r, params), &res, msgjson.RPCRestoreBackupError); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestHandleAPIKeys(t *testing.T) {
+	tc := &TCore{apiKey: &core.APIKey{ID: "id"}}
+	r := &RPCServer{core: tc}
+	pw := []encode.PassBytes{encode.PassBytes("abc")}
+
+	var res json.RawMessage
+	params := &RawParams{PWArgs: pw, Args: []string{"bot", "read,trade", `{"42":["Dsaddr"]}`, "24h"}}
+	if err := verifyResponse(handleCreateAPIKey(r, params), &res, -1); err != nil {
+		t.Fatal(err)
+	}
+	form := tc.apiKeyForm
+	if form.Name != "bot" || len(form.Scopes) != 2 || form.AddressAllowlist[42][0] != "Dsaddr" || form.Expiration == 0 {
+		t.Fatalf("wrong form: %+v", form)
+	}
+	for _, args := range [][]string{
+		{"bot"},
+		{"bot", "read", "notjson"},
+		{"bot", "read", "", "never"},
+	} {
+		params = &RawParams{PWArgs: pw, Args: args}
+		if err := verifyResponse(handleCreateAPIKey(r, params), &res, msgjson.RPCArgumentsError); err != nil {
+			t.Fatalf("%v: %v", args, err)
+		}
+	}
+
+	if err := verifyResponse(handleListAPIKeys(r, nil), &res, -1); err != nil {
+		t.Fatal(err)
+	}
+	if err := verifyResponse(handleRevokeAPIKey(r, &RawParams{Args: []string{"id"}}), &res, -1); err != nil {
+		t.Fatal(err)
+	}
+	if err := verifyResponse(handleRevokeAPIKey(r, &RawParams{}), &res, msgjson.RPCArgumentsError); err != nil {
+		t.Fatal(err)
+	}
+
+	tc.apiKeyErr = errors.New("not logged in")
+	if err := verifyResponse(handleListAPIKeys(r, nil), &res, msgjson.RPCAPIKeyError); err != nil {
+		t.Fatal(err)
+	}
+	if err := verifyResponse(handleRevokeAPIKey(r, &RawParams{Args: []string{"id"}}), &res, msgjson.RPCAPIKeyError); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestHandleWithdrawalPolicies(t *testing.T) {
+	tc := new(TCore)
+	r := &RPCServer{core: tc}
+	pw := []encode.PassBytes{encode.PassBytes("abc")}
+
+	tests := []struct {
+		name    string
+		handler func(*RPCServer, *RawParams) *msgjson.ResponsePayload
+		params  *RawParams
+		coreErr error
+		wantErr int
+	}{{
+		name:    "add ok",
+		handler: handleAddAddress,
+		params:  &RawParams{PWArgs: pw, Args: []string{"42", "Dsaddr", "cold"}},
+		wantErr: -1,
+	}, {
+		name:    "add no address",
+		handler: handleAddAddress,
+		params:  &RawParams{PWArgs: pw, Args: []string{"42"}},
+		wantErr: msgjson.RPCArgumentsError,
+	}, {
+		name:    "add core error",
+		handler: handleAddAddress,
+		params:  &RawParams{PWArgs: pw, Args: []string{"42", "Dsaddr"}},
+		coreErr: errors.New(""),
+		wantErr: msgjson.RPCWithdrawalPolicyError,
+	}, {
+		name:    "remove ok",
+		handler: handleRemoveAddress,
+		params:  &RawParams{PWArgs: pw, Args: []string{"42", "Dsaddr"}},
+		wantErr: -1,
+	}, {
+		name:    "remove with label",
+		handler: handleRemoveAddress,
+		params:  &RawParams{PWArgs: pw, Args: []string{"42", "Dsaddr", "cold"}},
+		wantErr: msgjson.RPCArgumentsError,
+	}, {
+		name:    "address book ok",
+		handler: handleAddressBook,
+		wantErr: -1,
+	}, {
+		name:    "set policy ok",
+		handler: handleSetWithdrawalPolicy,
+		params:  &RawParams{PWArgs: pw, Args: []string{"42", "true", "100000000"}},
+		wantErr: -1,
+	}, {
+		name:    "set policy bad bool",
+		handler: handleSetWithdrawalPolicy,
+		params:  &RawParams{PWArgs: pw, Args: []string{"42", "maybe"}},
+		wantErr: msgjson.RPCArgumentsError,
+	}, {
+		name:    "set policy no password",
+		handler: handleSetWithdrawalPolicy,
+		params:  &RawParams{Args: []string{"42", "true"}},
+		wantErr: msgjson.RPCArgumentsError,
+	}, {
+		name:    "policies core error",
+		handler: handleWithdrawalPolicies,
+		coreErr: errors.New(""),
+		wantErr: msgjson.RPCWithdrawalPolicyError,
+	}}
+	for _, test := range tests {
+		tc.withdrawalPolicyErr = test.coreErr
+		payload := test.handler(r, test.params)
+		if err := verifyResponse(payload, new(json.RawMessage), test.wantErr); err != nil {
+			t.Fatalf("%s: %v", test.name, err)
+		}
+	}
+}
+
+func TestHandleConditionalOrders(t *testing.T) {
+	tc := new(TCore)
+	r := &RPCServer{core: tc}
+	pw := []encode.PassBytes{encode.PassBytes("abc")}
+	args := func(typ, trigger string) []string {
+		return []string{"dex:1234", typ, "true", "true", "42", "0", "100000000", "500000", trigger}
+	}
+
+	tests := []struct {
+		name        string
+		handler     func(*RPCServer, *RawParams) *msgjson.ResponsePayload
+		params      *RawParams
+		coreErr     error
+		wantErr     int
+		wantTrigger uint64
+		wantTrail   float64
+	}{{
+		name:        "stop-loss ok",
+		handler:     handleCreateConditionalOrder,
+		params:      &RawParams{PWArgs: pw, Args: args("stoploss", "400000")},
+		wantErr:     -1,
+		wantTrigger: 400000,
+	}, {
+		name:      "trailing stop ok",
+		handler:   handleCreateConditionalOrder,
+		params:    &RawParams{PWArgs: pw, Args: args("trailingstop", "2.5")},
+		wantErr:   -1,
+		wantTrail: 2.5,
+	}, {
+		name:    "bad trigger",
+		handler: handleCreateConditionalOrder,
+		params:  &RawParams{PWArgs: pw, Args: args("stoploss", "2.5")},
+		wantErr: msgjson.RPCArgumentsError,
+	}, {
+		name:    "bad trail",
+		handler: handleCreateConditionalOrder,
+		params:  &RawParams{PWArgs: pw, Args: args("trailingstop", "abc")},
+		wantErr: msgjson.RPCArgumentsError,
+	}, {
+		name:    "no password",
+		handler: handleCreateConditionalOrder,
+		params:  &RawParams{Args: args("stoploss", "400000")},
+		wantErr: msgjson.RPCArgumentsError,
+	}, {
+		name:    "create core error",
+		handler: handleCreateConditionalOrder,
+		params:  &RawParams{PWArgs: pw, Args: args("stoploss", "400000")},
+		coreErr: errors.New(""),
+		wantErr: msgjson.RPCConditionalOrderError,
+	}, {
+		name:    "list ok",
+		handler: handleConditionalOrders,
+		wantErr: -1,
+	}, {
+		name:    "remove ok",
+		handler: handleRemoveConditionalOrder,
+		params:  &RawParams{Args: []string{"abcd"}},
+		wantErr: -1,
+	}, {
+		name:    "remove no id",
+		handler: handleRemoveConditionalOrder,
+		params:  &RawParams{},
+		wantErr: msgjson.RPCArgumentsError,
+	}, {
+		name:    "remove core error",
+		handler: handleRemoveConditionalOrder,
+		params:  &RawParams{Args: []string{"abcd"}},
+		coreErr: errors.New(""),
+		wantErr: msgjson.RPCConditionalOrderError,
+	}}
+	for _, test := range tests {
+		tc.condOrderErr = test.coreErr
+		tc.condOrderForm = nil
+		payload := test.handler(r, test.params)
+		if err := verifyResponse(payload, new(json.RawMessage), test.wantErr); err != nil {
+			t.Fatalf("%s: %v", test.name, err)
+		}
+		if test.wantErr == -1 && tc.condOrderForm != nil {
+			if tc.condOrderForm.TriggerRate != test.wantTrigger || tc.condOrderForm.TrailPercent != test.wantTrail {
+				t.Fatalf("%s: wrong form %+v", test.name, tc.condOrderForm)
+			}
+		}
+	}
+}
+
+func TestHandleManagedOrders(t *testing.T) {
+	tc := new(TCore)
+	r := &RPCServer{core: tc}
+	pw := []encode.PassBytes{encode.PassBytes("abc")}
+	args := func(extra ...string) []string {
+		return append([]string{"dex:1234", "", "true", "42", "0", "1000000000", "500000"}, extra...)
+	}
+	withType := func(typ string, args []string) []string {
+		args[1] = typ
+		return args
+	}
+
+	tests := []struct {
+		name    string
+		handler func(*RPCServer, *RawParams) *msgjson.ResponsePayload
+		params  *RawParams
+		coreErr error
+		wantErr int
+		want    *core.ManagedOrderForm
+	}{{
+		name:    "twap ok",
+		handler: handleCreateManagedOrder,
+		params:  &RawParams{PWArgs: pw, Args: withType("twap", args("5", "1h"))},
+		wantErr: -1,
+		want:    &core.ManagedOrderForm{Slices: 5, Duration: 3600e3},
+	}, {
+		name:    "iceberg ok",
+		handler: handleCreateManagedOrder,
+		params:  &RawParams{PWArgs: pw, Args: withType("iceberg", args("100000000"))},
+		wantErr: -1,
+		want:    &core.ManagedOrderForm{Clip: 1e8},
+	}, {
+		name:    "twap no duration",
+		handler: handleCreateManagedOrder,
+		params:  &RawParams{PWArgs: pw, Args: withType("twap", args("5"))},
+		wantErr: msgjson.RPCArgumentsError,
+	}, {
+		name:    "twap bad duration",
+		handler: handleCreateManagedOrder,
+		params:  &RawParams{PWArgs: pw, Args: withType("twap", args("5", "soon"))},
+		wantErr: msgjson.RPCArgumentsError,
+	}, {
+		name:    "iceberg with duration",
+		handler: handleCreateManagedOrder,
+		params:  &RawParams{PWArgs: pw, Args: withType("iceberg", args("100000000", "1h"))},
+		wantErr: msgjson.RPCArgumentsError,
+	}, {
+		name:    "unknown type",
+		handler: handleCreateManagedOrder,
+		params:  &RawParams{PWArgs: pw, Args: withType("vwap", args("5"))},
+		wantErr: msgjson.RPCArgumentsError,
+	}, {
+		name:    "create core error",
+		handler: handleCreateManagedOrder,
+		params:  &RawParams{PWArgs: pw, Args: withType("iceberg", args("100000000"))},
+		coreErr: errors.New(""),
+		wantErr: msgjson.RPCManagedOrderError,
+	}, {
+		name:    "list ok",
+		handler: handleManagedOrders,
+		wantErr: -1,
+	}, {
+		name:    "cancel ok",
+		handler: handleCancelManagedOrder,
+		params:  &RawParams{Args: []string{"abcd"}},
+		wantErr: -1,
+	}, {
+		name:    "cancel core error",
+		handler: handleCancelManagedOrder,
+		params:  &RawParams{Args: []string{"abcd"}},
+		coreErr: errors.New(""),
+		wantErr: msgjson.RPCManagedOrderError,
+	}}
+	for _, test := range tests {
+		tc.managedOrderErr = test.coreErr
+		tc.managedOrderForm = nil
+		payload := test.handler(r, test.params)
+		if err := verifyResponse(payload, new(json.RawMessage), test.wantErr); err != nil {
+			t.Fatalf("%s: %v", test.name, err)
+		}
+		if test.want != nil {
+			f := tc.managedOrderForm
+			if f.Slices != test.want.Slices || f.Duration != test.want.Duration || f.Clip != test.want.Clip {
+				t.Fatalf("%s: wrong form %+v", test.name, f)
+			}
+		}
+	}
+}
+
+func TestHandleExportTaxHistory(t *testing.T) {
+	tests := []struct {
+		name    string
+		params  *RawParams
+		coreErr error
+		wantErr int
+		want    core.TaxExportForm
+	}{{
+		name:    "path only",
+		params:  &RawParams{Args: []string{"tax.csv"}},
+		wantErr: -1,
+	}, {
+		name:    "all args",
+		params:  &RawParams{Args: []string{"tax.csv", "koinly", "1000", "2000", "rates.csv", "EUR"}},
+		wantErr: -1,
+		want:    core.TaxExportForm{Format: "koinly", From: 1000, To: 2000, RatesFile: "rates.csv", Currency: "EUR"},
+	}, {
+		name:    "no path",
+		params:  &RawParams{},
+		wantErr: msgjson.RPCArgumentsError,
+	}, {
+		name:    "bad from",
+		params:  &RawParams{Args: []string{"tax.csv", "koinly", "yesterday"}},
+		wantErr: msgjson.RPCArgumentsError,
+	}, {
+		name:    "core error",
+		params:  &RawParams{Args: []string{"tax.csv"}},
+		coreErr: errors.New(""),
+		wantErr: msgjson.RPCTaxExportError,
+	}}
+	for _, test := range tests {
+		tc := &TCore{taxExportErr: test.coreErr}
+		r := &RPCServer{core: tc}
+		payload := handleExportTaxHistory(r, test.params)
+		if err := verifyResponse(payload, new(string), test.wantErr); err != nil {
+			t.Fatalf("%s: %v", test.name, err)
+		}
+		if test.wantErr == -1 && *tc.taxExportForm != test.want {
+			t.Fatalf("%s: wrong form %+v", test.name, tc.taxExportForm)
+		}
+	}
+}
@@ -17,6 +17,7 @@ import (
 	"io"
 	"net/http"
 	"os"
+	"strings"
 	"sync"
 	"time"
 
@@ -64,6 +65,7 @@ type clientCore interface {
 	CreateWallet(appPass, walletPass []byte, form *core.WalletForm) error
 	DiscoverAccount(dexAddr string, pass []byte, certI any) (*core.Exchange, bool, error)
 	Exchanges() (exchanges map[string]*core.Exchange)
+	Orders(filter *core.OrderFilter) ([]*core.Order, error)
 	InitializeClient(appPass []byte, seed *string) (string, error)
 	Login(appPass []byte) error
 	Logout() error
@@ -77,6 +79,30 @@ type clientCore interface {
 	WalletState(assetID uint32) *core.WalletState
 	RescanWallet(assetID uint32, force bool) error
 	Send(appPass []byte, assetID uint32, value uint64, addr string, subtract bool) (asset.Coin, error)
+	SendWithCoins(appPass []byte, assetID uint32, value uint64, addr string, subtract bool, coinIDs []dex.Bytes) (asset.Coin, error)
+	ListUTXOs(assetID uint32) ([]*asset.WalletUTXO, error)
+	PrivacyReport(assetID uint32) (*asset.PrivacyReport, error)
+	SetUTXOLabel(assetID uint32, coinID dex.Bytes, label string) error
+	FreezeUTXOs(assetID uint32, coinIDs []dex.Bytes, freeze bool) error
+	ExportBackup(pw []byte, files []*core.BackupFile) ([]byte, error)
+	NotificationFeed() *core.NoteFeed
+	CreateAPIKey(pw []byte, form *core.APIKeyForm) (*core.APIKey, string, error)
+	APIKeys() ([]*core.APIKey, error)
+	RevokeAPIKey(id string) error
+	AuthorizeAPIKey(token string) (*core.APIKey, error)
+	AddressBook() ([]*core.AddressBookEntry, error)
+	AddAddressBookEntry(pw []byte, assetID uint32, addr, label string) (*core.AddressBookEntry, error)
+	RemoveAddressBookEntry(pw []byte, assetID uint32, addr string) error
+	WithdrawalPolicies() ([]*core.WithdrawalPolicy, error)
+	SetWithdrawalPolicy(pw []byte, assetID uint32, allowlistOnly bool, dailyLimit uint64) error
+	CreateConditionalOrder(pw []byte, form *core.ConditionalOrderForm) (*core.ConditionalOrder, error)
+	ConditionalOrders() ([]*core.ConditionalOrder, error)
+	RemoveConditionalOrder(id string) error
+	CreateManagedOrder(pw []byte, form *core.ManagedOrderForm) (*core.ManagedOrder, error)
+	ManagedOrders() ([]*core.ManagedOrder, error)
+	CancelManagedOrder(id string) error
+	ExportTaxHistoryFile(path string, form *core.TaxExportForm) (int, error)
+	RestoreBackup(pw, backup []byte, files []*core.BackupFile) (*core.BackupManifest, error)
 	ExportSeed(pw []byte) (string, error)
 	DeleteArchivedRecords(olderThan *time.Time, matchesFileStr, ordersFileStr string) (int, error)
 	WalletPeers(assetID uint32) ([]*asset.WalletPeer, error)
@@ -101,7 +127,6 @@ type RPCServer struct {
 	core      clientCore
 	mm        *mm.MarketMaker
 	mux       *chi.Mux
-	wsServer  *websocket.Server
 	addr      string
 	tlsConfig *tls.Config
 	srv       *http.Server
@@ -109,6 +134,11 @@ type RPCServer struct {
 	wg        sync.WaitGroup
 	bwVersion *SemVersion
 	ctx       context.Context
+	profiles  map[string]*Profile
+	// wsServers are keyed by profile name, with the default profile keyed by
+	// "". Each profile has its own websocket server, so that websocket clients
+	// only receive the notifications of their profile.
+	wsServers map[string]*websocket.Server
 }
 
 // genCertPair generates a key/cert pair to the paths provided.
@@ -184,7 +214,9 @@ func (s *RPCServer) handleJSON(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Responses not accepted", http.StatusMethodNotAllowed)
 		return
 	}
-	s.parseHTTPRequest(w, req)
+	key, _ := r.Context().Value(apiKeyCtxKey).(*core.APIKey)
+	keyProfile, _ := r.Context().Value(apiKeyProfileCtxKey).(string)
+	s.parseHTTPRequest(w, req, key, keyProfile)
 }
 
 // Config holds variables needed to create a new RPC Server.
@@ -194,8 +226,24 @@ type Config struct {
 	Addr, User, Pass, Cert, Key string
 	BWVersion                   *SemVersion
 	CertHosts                   []string
+	// Profiles are additional isolated profiles, keyed by name, that requests
+	// can select with RawParams.Profile. Core and MarketMaker above are the
+	// default profile.
+	Profiles map[string]*Profile
 }
 
+// Profile is a Bison Wallet profile with its own database, app password and
+// seed.
+type Profile struct {
+	Core        clientCore
+	MarketMaker *mm.MarketMaker
+}
+
+// DefaultProfile is the name of the profile created from Config.Core and
+// Config.MarketMaker. Requests that do not specify a profile are for the
+// default profile.
+const DefaultProfile = "default"
+
 // SetLogger sets the logger for the RPCServer package.
 func SetLogger(logger dex.Logger) {
 	log = logger
@@ -248,7 +296,14 @@ func New(cfg *Config) (*RPCServer, error) {
 		addr:      cfg.Addr,
 		tlsConfig: tlsConfig,
 		bwVersion: cfg.BWVersion,
-		wsServer:  websocket.New(cfg.Core, log.SubLogger("WS")),
+		profiles:  cfg.Profiles,
+		wsServers: map[string]*websocket.Server{"": websocket.New(cfg.Core, log.SubLogger("WS"))},
+	}
+	if _, found := s.profiles[DefaultProfile]; found {
+		return nil, fmt.Errorf("profile name %q is reserved", DefaultProfile)
+	}
+	for name, p := range s.profiles {
+		s.wsServers[name] = websocket.New(p.Core, log.SubLogger("WS").SubLogger(name))
 	}
 
 	// Create authSHA to verify requests against.
@@ -294,9 +349,30 @@ func (s *RPCServer) Connect(ctx context.Context) (*sync.WaitGroup, error) {
 		}
 	}()
 
-	// Configure the websocket handler before starting the server.
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		s.relayNotifications(ctx)
+	}()
+
+	// Configure the websocket handler before starting the server. The
+	// connection is handled by the websocket server of the requested profile.
 	s.mux.Get("/ws", func(w http.ResponseWriter, r *http.Request) {
-		s.wsServer.HandleConnect(ctx, w, r)
+		if key, _ := r.Context().Value(apiKeyCtxKey).(*core.APIKey); key != nil && !key.HasScope(core.APIScopeRead) {
+			http.Error(w, http.StatusText(http.StatusForbidden), http.StatusForbidden)
+			return
+		}
+		wsServer, msgErr := s.wsServerForRequest(r)
+		if msgErr != nil {
+			log.Debugf("websocket connection rejected: %s", msgErr.Message)
+			code := http.StatusForbidden
+			if msgErr.Code == msgjson.RPCUnknownProfileError {
+				code = http.StatusNotFound
+			}
+			http.Error(w, msgErr.Message, code)
+			return
+		}
+		wsServer.HandleConnect(ctx, w, r)
 	})
 
 	s.wg.Add(1)
@@ -307,15 +383,117 @@ func (s *RPCServer) Connect(ctx context.Context) (*sync.WaitGroup, error) {
 		}
 		// Disconnect the websocket clients since http.(*Server).Shutdown does
 		// not deal with hijacked websocket connections.
-		s.wsServer.Shutdown()
+		for _, wsServer := range s.wsServers {
+			wsServer.Shutdown()
+		}
 		log.Infof("RPC server off")
 	}()
 	log.Infof("RPC server listening on %s", s.addr)
 	return &s.wg, nil
 }
 
-// handleRequest sends the request to the correct handler function if able.
-func (s *RPCServer) handleRequest(req *msgjson.Message) *msgjson.ResponsePayload {
+// relayNotifications relays the core notifications of each profile to the
+// profile's websocket clients with matching subscriptions.
+func (s *RPCServer) relayNotifications(ctx context.Context) {
+	var wg sync.WaitGroup
+	relay := func(c clientCore, wsServer *websocket.Server) {
+		defer wg.Done()
+		feed := c.NotificationFeed()
+		defer feed.ReturnFeed()
+		for {
+			select {
+			case n := <-feed.C:
+				for _, subType := range noteSubscriptions(n) {
+					wsServer.NotifySubscribers(subType, n)
+				}
+			case <-ctx.Done():
+				return
+			}
+		}
+	}
+	wg.Add(1)
+	go relay(s.core, s.wsServers[""])
+	for name, p := range s.profiles {
+		wg.Add(1)
+		go relay(p.Core, s.wsServers[name])
+	}
+	wg.Wait()
+}
+
+// noteSubscriptions are the websocket subscription types that receive the
+// notification. Data-severity notifications are state updates that are not
+// shown to the user, and are not sent to notifications subscribers.
+func noteSubscriptions(n core.Notification) []string {
+	var subs []string
+	switch n.Type() {
+	case core.NoteTypeOrder, core.NoteTypeMatch:
+		subs = append(subs, websocket.SubOrders)
+	case core.NoteTypeBalance:
+		subs = append(subs, websocket.SubBalances)
+	case mm.NoteTypeRunStats, mm.NoteTypeRunEvent:
+		subs = append(subs, websocket.SubMMStats)
+	}
+	if n.Severity() > db.Data {
+		subs = append(subs, websocket.SubNotifications)
+	}
+	return subs
+}
+
+// profileName resolves the requested profile name, with the default profile
+// named "". If the request was authenticated with an API key, the key must
+// belong to the profile. API key requests that do not specify a profile are for
+// the key's profile.
+func (s *RPCServer) profileName(name string, key *core.APIKey, keyProfile string) (string, *msgjson.Error) {
+	if key != nil && name == "" {
+		name = keyProfile
+	}
+	if name == DefaultProfile {
+		name = ""
+	}
+	if key != nil && name != keyProfile {
+		return "", msgjson.NewError(msgjson.RPCUnauthorizedRouteError, "api key not authorized for profile %q", name)
+	}
+	if _, found := s.profiles[name]; name != "" && !found {
+		return "", msgjson.NewError(msgjson.RPCUnknownProfileError, "unknown profile %q", name)
+	}
+	return name, nil
+}
+
+// wsServerForRequest returns the websocket server of the profile requested
+// with the "profile" URL query parameter. See profileName.
+func (s *RPCServer) wsServerForRequest(r *http.Request) (*websocket.Server, *msgjson.Error) {
+	key, _ := r.Context().Value(apiKeyCtxKey).(*core.APIKey)
+	keyProfile, _ := r.Context().Value(apiKeyProfileCtxKey).(string)
+	name, msgErr := s.profileName(r.URL.Query().Get("profile"), key, keyProfile)
+	if msgErr != nil {
+		return nil, msgErr
+	}
+	return s.wsServers[name], nil
+}
+
+// forProfile returns an RPCServer for handling requests for the named profile.
+// See profileName.
+func (s *RPCServer) forProfile(name string, key *core.APIKey, keyProfile string) (*RPCServer, *msgjson.Error) {
+	name, msgErr := s.profileName(name, key, keyProfile)
+	if msgErr != nil {
+		return nil, msgErr
+	}
+	if name == "" {
+		return s, nil
+	}
+	p := s.profiles[name]
+	return &RPCServer{
+		core:      p.Core,
+		mm:        p.MarketMaker,
+		bwVersion: s.bwVersion,
+		ctx:       s.ctx,
+	}, nil
+}
+
+// handleRequest sends the request to the correct handler function if able. If
+// the request was authenticated with an API key, the key must be authorized for
+// the route and the requested profile.
+func (s *RPCServer) handleRequest(req *msgjson.Message, key *core.APIKey, keyProfile string) *msgjson.ResponsePayload {
 	payload := new(msgjson.ResponsePayload)
 	if req.Route == "" {
 		log.Debugf("route not specified")
@@ -339,13 +517,28 @@ func (s *RPCServer) handleRequest(req *msgjson.Message) *msgjson.ResponsePayload
 		return payload
 	}
 
-	return h(s, params)
+	srv, msgErr := s.forProfile(params.Profile, key, keyProfile)
+	if msgErr != nil {
+		log.Debugf("profile %q rejected for route %s: %s", params.Profile, req.Route, msgErr.Message)
+		payload.Error = msgErr
+		return payload
+	}
+
+	if key != nil {
+		if msgErr := authorizeRoute(key, req.Route, params); msgErr != nil {
+			log.Warnf("API key %s (%s) denied for route %s: %s", key.ID, key.Name, req.Route, msgErr.Message)
+			payload.Error = msgErr
+			return payload
+		}
+	}
+
+	return h(srv, params)
 }
 
 // parseHTTPRequest parses the msgjson message in the request body, creates a
 // response message, and writes it to the http.ResponseWriter.
-func (s *RPCServer) parseHTTPRequest(w http.ResponseWriter, req *msgjson.Message) {
-	payload := s.handleRequest(req)
+func (s *RPCServer) parseHTTPRequest(w http.ResponseWriter, req *msgjson.Message, key *core.APIKey, keyProfile string) {
+	payload := s.handleRequest(req, key, keyProfile)
 	resp, err := msgjson.NewResponse(req.ID, payload.Result, payload.Error)
 	if err != nil {
 		msg := fmt.Sprintf("error encoding response: %v", err)
@@ -356,7 +549,53 @@ func (s *RPCServer) parseHTTPRequest(w http.ResponseWriter, req *msgjson.Message
 	writeJSON(w, resp)
 }
 
-// authMiddleware checks incoming requests for authentication.
+// authorizeRoute checks that the API key has the scope required for the
+// route, and that sends and withdrawals are to allowed addresses.
+func authorizeRoute(key *core.APIKey, route string, params *RawParams) *msgjson.Error {
+	scope, found := routeScopes[route]
+	if !found || !key.HasScope(scope) {
+		return msgjson.NewError(msgjson.RPCUnauthorizedRouteError, "api key not authorized for %s", route)
+	}
+	if scope == core.APIScopeWithdraw {
+		form, err := parseSendOrWithdrawArgs(params)
+		if err != nil {
+			return nil // the handler will respond with usage
+		}
+		if !key.AllowsAddress(form.assetID, form.address) {
+			return msgjson.NewError(msgjson.RPCUnauthorizedRouteError, "address %s is not in the api key allowlist", form.address)
+		}
+	}
+	return nil
+}
+
+type ctxKey int
+
+const (
+	// apiKeyCtxKey is the request context key for the *core.APIKey of
+	// requests authenticated with an API key.
+	apiKeyCtxKey ctxKey = iota
+	// apiKeyProfileCtxKey is the request context key for the name of the
+	// profile that the API key belongs to. The default profile is "".
+	apiKeyProfileCtxKey
+)
+
+// authorizeAPIKey finds the profile that the API key token belongs to and
+// authorizes the token with that profile's Core.
+func (s *RPCServer) authorizeAPIKey(token string) (*core.APIKey, string, error) {
+	key, err := s.core.AuthorizeAPIKey(token)
+	if err == nil {
+		return key, "", nil
+	}
+	for name, p := range s.profiles {
+		if key, pErr := p.Core.AuthorizeAPIKey(token); pErr == nil {
+			return key, name, nil
+		}
+	}
+	return nil, "", err
+}
+
+// authMiddleware checks incoming requests for authentication. Requests are
+// authenticated with the RPC user and password, or with an API key token.
 func (s *RPCServer) authMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		fail := func() {
@@ -369,6 +608,18 @@ func (s *RPCServer) authMiddleware(next http.Handler) http.Handler {
 			fail()
 			return
 		}
+		if token, found := strings.CutPrefix(auth[0], "Bearer "); found {
+			key, profile, err := s.authorizeAPIKey(token)
+			if err != nil {
+				log.Debugf("api key authentication error: %v", err)
+				fail()
+				return
+			}
+			log.Debugf("authenticated api key %s with ip: %s", key.ID, r.RemoteAddr)
+			ctx := context.WithValue(r.Context(), apiKeyCtxKey, key)
+			next.ServeHTTP(w, r.WithContext(context.WithValue(ctx, apiKeyProfileCtxKey, profile)))
+			return
+		}
 		authSHA := sha256.Sum256([]byte(auth[0]))
 		if subtle.ConstantTimeCompare(s.authSHA[:], authSHA[:]) != 1 {
 			fail()
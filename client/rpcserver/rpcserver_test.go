@@ -11,18 +11,23 @@ import (
 	"crypto/sha256"
 	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"os"
+	"reflect"
 	"testing"
 	"time"
 
 	"decred.org/dcrdex/client/asset"
 	"decred.org/dcrdex/client/core"
 	"decred.org/dcrdex/client/db"
+	"decred.org/dcrdex/client/mm"
 	"decred.org/dcrdex/client/mnemonic"
 	"decred.org/dcrdex/client/orderbook"
+	"decred.org/dcrdex/client/websocket"
 	"decred.org/dcrdex/dex"
+	"decred.org/dcrdex/dex/encode"
 	"decred.org/dcrdex/dex/msgjson"
 )
 
@@ -71,6 +76,27 @@ type TCore struct {
 	stakeStatus              *asset.TicketStakingStatus
 	stakeStatusErr           error
 	setVotingPrefErr         error
+	sentCoinIDs              []dex.Bytes
+	utxos                    []*asset.WalletUTXO
+	coinCtlErr               error
+	privacyReport            *asset.PrivacyReport
+	privacyReportErr         error
+	frozen                   map[string]bool
+	ordersFilter             *core.OrderFilter
+	archivedOrders           []*core.Order
+	ordersErr                error
+	backup                   []byte
+	backupErr                error
+	apiKey                   *core.APIKey
+	apiKeyErr                error
+	apiKeyForm               *core.APIKeyForm
+	withdrawalPolicyErr      error
+	condOrderForm            *core.ConditionalOrderForm
+	condOrderErr             error
+	managedOrderForm         *core.ManagedOrderForm
+	managedOrderErr          error
+	taxExportForm            *core.TaxExportForm
+	taxExportErr             error
 }
 
 func (c *TCore) Balance(uint32) (uint64, error) {
@@ -94,6 +120,10 @@ func (c *TCore) CloseWallet(assetID uint32) error {
 	return c.closeWalletErr
 }
 func (c *TCore) Exchanges() (exchanges map[string]*core.Exchange) { return c.exchanges }
+func (c *TCore) Orders(filter *core.OrderFilter) ([]*core.Order, error) {
+	c.ordersFilter = filter
+	return c.archivedOrders, c.ordersErr
+}
 func (c *TCore) Exchange(host string) (*core.Exchange, error) {
 	exchange, ok := c.exchanges[host]
 	if !ok {
@@ -153,6 +183,99 @@ func (c *TCore) WalletState(assetID uint32) *core.WalletState {
 func (c *TCore) Send(pw []byte, assetID uint32, value uint64, addr string, subtract bool) (asset.Coin, error) {
 	return c.coin, c.sendErr
 }
+func (c *TCore) SendWithCoins(pw []byte, assetID uint32, value uint64, addr string, subtract bool, coinIDs []dex.Bytes) (asset.Coin, error) {
+	c.sentCoinIDs = coinIDs
+	return c.coin, c.sendErr
+}
+func (c *TCore) ListUTXOs(assetID uint32) ([]*asset.WalletUTXO, error) {
+	return c.utxos, c.coinCtlErr
+}
+func (c *TCore) PrivacyReport(assetID uint32) (*asset.PrivacyReport, error) {
+	return c.privacyReport, c.privacyReportErr
+}
+func (c *TCore) SetUTXOLabel(assetID uint32, coinID dex.Bytes, label string) error {
+	return c.coinCtlErr
+}
+func (c *TCore) CreateAPIKey(pw []byte, form *core.APIKeyForm) (*core.APIKey, string, error) {
+	c.apiKeyForm = form
+	return c.apiKey, "token", c.apiKeyErr
+}
+func (c *TCore) APIKeys() ([]*core.APIKey, error) {
+	return []*core.APIKey{c.apiKey}, c.apiKeyErr
+}
+func (c *TCore) RevokeAPIKey(id string) error {
+	return c.apiKeyErr
+}
+func (c *TCore) AuthorizeAPIKey(token string) (*core.APIKey, error) {
+	if token != "token" {
+		return nil, errors.New("unknown API key")
+	}
+	return c.apiKey, c.apiKeyErr
+}
+func (c *TCore) AddressBook() ([]*core.AddressBookEntry, error) {
+	return nil, c.withdrawalPolicyErr
+}
+func (c *TCore) AddAddressBookEntry(pw []byte, assetID uint32, addr, label string) (*core.AddressBookEntry, error) {
+	return &core.AddressBookEntry{AssetID: assetID, Address: addr, Label: label}, c.withdrawalPolicyErr
+}
+func (c *TCore) RemoveAddressBookEntry(pw []byte, assetID uint32, addr string) error {
+	return c.withdrawalPolicyErr
+}
+func (c *TCore) WithdrawalPolicies() ([]*core.WithdrawalPolicy, error) {
+	return nil, c.withdrawalPolicyErr
+}
+func (c *TCore) SetWithdrawalPolicy(pw []byte, assetID uint32, allowlistOnly bool, dailyLimit uint64) error {
+	return c.withdrawalPolicyErr
+}
+func (c *TCore) CreateConditionalOrder(pw []byte, form *core.ConditionalOrderForm) (*core.ConditionalOrder, error) {
+	c.condOrderForm = form
+	return &core.ConditionalOrder{ID: "id", ConditionalOrderForm: *form}, c.condOrderErr
+}
+func (c *TCore) ConditionalOrders() ([]*core.ConditionalOrder, error) {
+	return nil, c.condOrderErr
+}
+func (c *TCore) RemoveConditionalOrder(id string) error {
+	return c.condOrderErr
+}
+func (c *TCore) CreateManagedOrder(pw []byte, form *core.ManagedOrderForm) (*core.ManagedOrder, error) {
+	c.managedOrderForm = form
+	return &core.ManagedOrder{ID: "id", ManagedOrderForm: *form}, c.managedOrderErr
+}
+func (c *TCore) ManagedOrders() ([]*core.ManagedOrder, error) {
+	return nil, c.managedOrderErr
+}
+func (c *TCore) CancelManagedOrder(id string) error {
+	return c.managedOrderErr
+}
+func (c *TCore) ExportTaxHistoryFile(path string, form *core.TaxExportForm) (int, error) {
+	c.taxExportForm = form
+	return 1, c.taxExportErr
+}
+func (c *TCore) NotificationFeed() *core.NoteFeed {
+	return &core.NoteFeed{
+		C: make(chan core.Notification, 1),
+	}
+}
+func (c *TCore) ExportBackup(pw []byte, files []*core.BackupFile) ([]byte, error) {
+	return c.backup, c.backupErr
+}
+func (c *TCore) RestoreBackup(pw, backup []byte, files []*core.BackupFile) (*core.BackupManifest, error) {
+	if c.backupErr != nil {
+		return nil, c.backupErr
+	}
+	c.backup = backup
+	return &core.BackupManifest{Version: 1}, nil
+}
+func (c *TCore) FreezeUTXOs(assetID uint32, coinIDs []dex.Bytes, freeze bool) error {
+	if c.coinCtlErr != nil {
+		return c.coinCtlErr
+	}
+	c.frozen = make(map[string]bool)
+	for _, coinID := range coinIDs {
+		c.frozen[coinID.String()] = freeze
+	}
+	return nil
+}
 func (c *TCore) ExportSeed(pw []byte) (string, error) {
 	return c.exportSeed, c.exportSeedErr
 }
@@ -523,3 +646,175 @@ func TestAuthMiddleware(t *testing.T) {
 		wantAuthError(test.name, test.wantErr)
 	}
 }
+
+func TestAPIKeyAuth(t *testing.T) {
+	s, shutdown := newTServer(t, false, "", "abc")
+	defer shutdown()
+	tc := s.core.(*TCore)
+	tc.apiKey = &core.APIKey{ID: "id", Scopes: []string{core.APIScopeRead}}
+
+	var gotKey *core.APIKey
+	am := s.authMiddleware(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			gotKey, _ = r.Context().Value(apiKeyCtxKey).(*core.APIKey)
+			w.WriteHeader(http.StatusOK)
+		}))
+	for _, test := range []struct {
+		token    string
+		wantCode int
+	}{
+		{"token", http.StatusOK},
+		{"wrong", http.StatusUnauthorized},
+	} {
+		r, _ := http.NewRequest("GET", "", nil)
+		r.Header.Set("Authorization", "Bearer "+test.token)
+		w := &tResponseWriter{}
+		gotKey = nil
+		am.ServeHTTP(w, r)
+		if w.code != test.wantCode {
+			t.Fatalf("%s: wanted code %d, got %d", test.token, test.wantCode, w.code)
+		}
+		if (gotKey != nil) != (test.wantCode == http.StatusOK) {
+			t.Fatalf("%s: api key not set in context", test.token)
+		}
+	}
+
+	req := func(route string, args ...string) *msgjson.Message {
+		msg, _ := msgjson.NewRequest(1, route, &RawParams{PWArgs: []encode.PassBytes{encode.PassBytes("abc")}, Args: args})
+		return msg
+	}
+	wantUnauthorized := func(msg *msgjson.Message, want bool) {
+		t.Helper()
+		payload := s.handleRequest(msg, tc.apiKey, "")
+		unauthorized := payload.Error != nil && payload.Error.Code == msgjson.RPCUnauthorizedRouteError
+		if unauthorized != want {
+			t.Fatalf("%s: wanted unauthorized = %t, got error %v", msg.Route, want, payload.Error)
+		}
+	}
+	wantUnauthorized(req(versionRoute), false)
+	wantUnauthorized(req(appSeedRoute), true)
+	wantUnauthorized(req(sendRoute, "42", "100", "Dsaddr"), true)
+
+	tc.apiKey.Scopes = append(tc.apiKey.Scopes, core.APIScopeWithdraw)
+	tc.apiKey.AddressAllowlist = map[uint32][]string{42: {"Dsaddr"}}
+	tc.coin = &tCoin{}
+	wantUnauthorized(req(sendRoute, "42", "100", "Dsaddr"), false)
+	wantUnauthorized(req(withdrawRoute, "42", "100", "Dsother"), true)
+	wantUnauthorized(req(sendRoute, "0", "100", "Dsaddr"), true)
+	// Admin credentials are not restricted.
+	if payload := s.handleRequest(req(withdrawRoute, "42", "100", "Dsother"), nil, ""); payload.Error != nil {
+		t.Fatalf("admin withdraw error: %v", payload.Error)
+	}
+}
+
+func TestProfiles(t *testing.T) {
+	s, shutdown := newTServer(t, false, "", "abc")
+	defer shutdown()
+	tc := s.core.(*TCore)
+	tc.wallets = []*core.WalletState{{Symbol: "dcr"}}
+	tc.apiKeyErr = errors.New("unknown API key")
+	altCore := &TCore{
+		wallets: []*core.WalletState{{Symbol: "btc"}},
+		apiKey:  &core.APIKey{ID: "alt", Scopes: []string{core.APIScopeRead}},
+	}
+	s.profiles = map[string]*Profile{"alt": {Core: altCore}}
+
+	req := func(profile string) *msgjson.Message {
+		msg, _ := msgjson.NewRequest(1, walletsRoute, &RawParams{Profile: profile})
+		return msg
+	}
+	wantWallet := func(payload *msgjson.ResponsePayload, symbol string) {
+		t.Helper()
+		if payload.Error != nil {
+			t.Fatalf("unexpected error: %v", payload.Error)
+		}
+		var wallets []*core.WalletState
+		if err := json.Unmarshal(payload.Result, &wallets); err != nil {
+			t.Fatalf("error decoding wallets: %v", err)
+		}
+		if len(wallets) != 1 || wallets[0].Symbol != symbol {
+			t.Fatalf("wanted %s wallet, got %v", symbol, wallets)
+		}
+	}
+	wantErrCode := func(payload *msgjson.ResponsePayload, code int) {
+		t.Helper()
+		if payload.Error == nil || payload.Error.Code != code {
+			t.Fatalf("wanted error code %d, got %v", code, payload.Error)
+		}
+	}
+
+	wantWallet(s.handleRequest(req(""), nil, ""), "dcr")
+	wantWallet(s.handleRequest(req(DefaultProfile), nil, ""), "dcr")
+	wantWallet(s.handleRequest(req("alt"), nil, ""), "btc")
+	wantErrCode(s.handleRequest(req("other"), nil, ""), msgjson.RPCUnknownProfileError)
+
+	// API keys are only valid for their own profile.
+	key, profile, err := s.authorizeAPIKey("token")
+	if err != nil {
+		t.Fatalf("authorizeAPIKey error: %v", err)
+	}
+	if profile != "alt" || key != altCore.apiKey {
+		t.Fatalf("wrong api key profile %q", profile)
+	}
+	wantWallet(s.handleRequest(req(""), key, profile), "btc")
+	wantWallet(s.handleRequest(req("alt"), key, profile), "btc")
+	wantErrCode(s.handleRequest(req(DefaultProfile), key, profile), msgjson.RPCUnauthorizedRouteError)
+	if _, _, err := s.authorizeAPIKey("wrong"); err == nil {
+		t.Fatalf("no error for unknown api key")
+	}
+
+	// Websocket connections are served by the websocket server of the
+	// requested profile.
+	altWS := websocket.New(altCore, log)
+	s.wsServers["alt"] = altWS
+	wsReq := func(profile string, key *core.APIKey, keyProfile string) *http.Request {
+		r, _ := http.NewRequest("GET", "/ws?profile="+profile, nil)
+		if key != nil {
+			ctx := context.WithValue(r.Context(), apiKeyCtxKey, key)
+			r = r.WithContext(context.WithValue(ctx, apiKeyProfileCtxKey, keyProfile))
+		}
+		return r
+	}
+	wantWSServer := func(r *http.Request, want *websocket.Server, wantCode int) {
+		t.Helper()
+		wsServer, msgErr := s.wsServerForRequest(r)
+		if wantCode != 0 {
+			if msgErr == nil || msgErr.Code != wantCode {
+				t.Fatalf("wanted error code %d, got %v", wantCode, msgErr)
+			}
+			return
+		}
+		if msgErr != nil {
+			t.Fatalf("unexpected error: %v", msgErr)
+		}
+		if wsServer != want {
+			t.Fatalf("wrong websocket server")
+		}
+	}
+	wantWSServer(wsReq("", nil, ""), s.wsServers[""], 0)
+	wantWSServer(wsReq("alt", nil, ""), altWS, 0)
+	wantWSServer(wsReq("other", nil, ""), nil, msgjson.RPCUnknownProfileError)
+	wantWSServer(wsReq("", key, profile), altWS, 0)
+	wantWSServer(wsReq(DefaultProfile, key, profile), nil, msgjson.RPCUnauthorizedRouteError)
+}
+
+func TestNoteSubscriptions(t *testing.T) {
+	tests := []struct {
+		noteType string
+		severity db.Severity
+		want     []string
+	}{
+		{core.NoteTypeOrder, db.Data, []string{websocket.SubOrders}},
+		{core.NoteTypeMatch, db.Success, []string{websocket.SubOrders, websocket.SubNotifications}},
+		{core.NoteTypeBalance, db.Data, []string{websocket.SubBalances}},
+		{mm.NoteTypeRunStats, db.Data, []string{websocket.SubMMStats}},
+		{core.NoteTypeSecurity, db.WarningLevel, []string{websocket.SubNotifications}},
+		{core.NoteTypeSpots, db.Data, nil},
+	}
+	for _, tt := range tests {
+		n := db.NewNotification(tt.noteType, "", "", "", tt.severity)
+		if subs := noteSubscriptions(&n); !reflect.DeepEqual(subs, tt.want) {
+			t.Fatalf("%s: wanted subscriptions %v, got %v", tt.noteType, tt.want, subs)
+		}
+	}
+}
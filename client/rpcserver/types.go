@@ -10,6 +10,7 @@ import (
 	"fmt"
 	"math"
 	"strconv"
+	"strings"
 	"time"
 
 	"decred.org/dcrdex/client/core"
@@ -32,6 +33,9 @@ var (
 type RawParams struct {
 	PWArgs []encode.PassBytes `json:"PWArgs"`
 	Args   []string           `json:"args"`
+	// Profile is the name of the profile the request is for. The default
+	// profile is used if Profile is empty.
+	Profile string `json:"profile,omitempty"`
 }
 
 // VersionResponse holds bisonw and bisonw rpc server version.
@@ -167,6 +171,7 @@ type sendOrWithdrawForm struct {
 	assetID uint32
 	value   uint64
 	address string
+	coinIDs []dex.Bytes
 }
 
 // orderBookForm is information necessary to fetch an order book.
@@ -179,9 +184,10 @@ type orderBookForm struct {
 
 // myOrdersForm is information necessary to fetch the user's orders.
 type myOrdersForm struct {
-	host  string
-	base  *uint32
-	quote *uint32
+	host     string
+	base     *uint32
+	quote    *uint32
+	archived bool
 }
 
 type deleteRecordsForm struct {
@@ -189,6 +195,12 @@ type deleteRecordsForm struct {
 	ordersFileStr, matchesFileStr string
 }
 
+// taxExportForm is the information necessary to export the tax history.
+type taxExportForm struct {
+	path string
+	form *core.TaxExportForm
+}
+
 // addRemovePeerForm is the information necessary to add or remove a wallet peer.
 type addRemovePeerForm struct {
 	assetID uint32
@@ -233,6 +245,62 @@ type setVotingPreferencesForm struct {
 	voteChoices, tSpendPolicy, treasuryPolicy map[string]string
 }
 
+// setUTXOLabelForm is information necessary to label a UTXO.
+type setUTXOLabelForm struct {
+	assetID uint32
+	coinID  dex.Bytes
+	label   string
+}
+
+// freezeUTXOsForm is information necessary to freeze or unfreeze UTXOs.
+type freezeUTXOsForm struct {
+	assetID uint32
+	coinIDs []dex.Bytes
+	freeze  bool
+}
+
+// backupForm is information necessary to export or restore a backup.
+type backupForm struct {
+	appPass encode.PassBytes
+	path    string
+}
+
+// createAPIKeyForm is information necessary to create an API key.
+type createAPIKeyForm struct {
+	appPass encode.PassBytes
+	form    *core.APIKeyForm
+}
+
+// addressBookForm is information necessary to add or remove an address book
+// entry.
+type addressBookForm struct {
+	appPass encode.PassBytes
+	assetID uint32
+	address string
+	label   string
+}
+
+// withdrawalPolicyForm is information necessary to set a withdrawal policy.
+type withdrawalPolicyForm struct {
+	appPass       encode.PassBytes
+	assetID       uint32
+	allowlistOnly bool
+	dailyLimit    uint64
+}
+
+// conditionalOrderForm is information necessary to create a conditional
+// order.
+type conditionalOrderForm struct {
+	appPass encode.PassBytes
+	form    *core.ConditionalOrderForm
+}
+
+// managedOrderForm is information necessary to create a managed order.
+type managedOrderForm struct {
+	appPass encode.PassBytes
+	form    *core.ManagedOrderForm
+}
+
 type txHistoryForm struct {
 	assetID uint32
 	num     int
@@ -297,6 +365,15 @@ func checkMapArg(arg, name string) (map[string]string, error) {
 	return m, nil
 }
 
+func checkCoinIDsArg(arg, name string) ([]dex.Bytes, error) {
+	var coinIDs []dex.Bytes
+	err := json.Unmarshal([]byte(arg), &coinIDs)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s must be a JSON-encoded array of hex coin IDs: %v", errArgs, name, err)
+	}
+	return coinIDs, nil
+}
+
 func parseDiscoverAcctArgs(params *RawParams) (*discoverAcctForm, error) {
 	if err := checkNArgs(params, []int{1}, []int{1, 2}); err != nil {
 		return nil, err
@@ -558,7 +635,7 @@ func parsePostBondArgs(params *RawParams) (*core.PostBondForm, error) {
 }
 
 func parseTradeArgs(params *RawParams) (*tradeForm, error) {
-	if err := checkNArgs(params, []int{1}, []int{9}); err != nil {
+	if err := checkNArgs(params, []int{1}, []int{9, 10}); err != nil {
 		return nil, err
 	}
 	isLimit, err := checkBoolArg(params.Args[1], "isLimit")
@@ -607,6 +684,12 @@ func parseTradeArgs(params *RawParams) (*tradeForm, error) {
 			Options: options,
 		},
 	}
+	if len(params.Args) > 9 {
+		req.srvForm.Coins, err = checkCoinIDsArg(params.Args[9], "coins")
+		if err != nil {
+			return nil, err
+		}
+	}
 	return req, nil
 }
 
@@ -685,7 +768,7 @@ func parseCancelArgs(params *RawParams) (*cancelForm, error) {
 }
 
 func parseSendOrWithdrawArgs(params *RawParams) (*sendOrWithdrawForm, error) {
-	if err := checkNArgs(params, []int{1}, []int{3}); err != nil {
+	if err := checkNArgs(params, []int{1}, []int{3, 4}); err != nil {
 		return nil, err
 	}
 	assetID, err := checkUIntArg(params.Args[0], "assetID", 32)
@@ -702,6 +785,12 @@ func parseSendOrWithdrawArgs(params *RawParams) (*sendOrWithdrawForm, error) {
 		value:   value,
 		address: params.Args[2],
 	}
+	if len(params.Args) > 3 {
+		req.coinIDs, err = checkCoinIDsArg(params.Args[3], "coins")
+		if err != nil {
+			return nil, err
+		}
+	}
 	return req, nil
 }
 
@@ -759,11 +848,23 @@ func parseOrderBookArgs(params *RawParams) (*orderBookForm, error) {
 }
 
 func parseMyOrdersArgs(params *RawParams) (*myOrdersForm, error) {
-	if err := checkNArgs(params, []int{0}, []int{0, 3}); err != nil {
+	if err := checkNArgs(params, []int{0}, []int{0, 4}); err != nil {
 		return nil, err
 	}
 	req := new(myOrdersForm)
 	switch len(params.Args) {
+	case 4:
+		archived, err := checkBoolArg(params.Args[3], "archived")
+		if err != nil {
+			return nil, err
+		}
+		req.archived = archived
+		// Base and quote may both be blank to show all markets.
+		if params.Args[1] == "" && params.Args[2] == "" {
+			req.host = params.Args[0]
+			break
+		}
+		fallthrough
 	case 3:
 		// Args 1 and 2 should be base ID and quote ID. If present,
 		// they are a pair.
@@ -797,6 +898,38 @@ func parseAppSeedArgs(params *RawParams) (encode.PassBytes, error) {
 	return params.PWArgs[0], nil
 }
 
+func parseExportTaxHistoryArgs(params *RawParams) (*taxExportForm, error) {
+	if err := checkNArgs(params, []int{0}, []int{1, 6}); err != nil {
+		return nil, err
+	}
+	form := &taxExportForm{path: params.Args[0], form: new(core.TaxExportForm)}
+	switch len(params.Args) {
+	case 6:
+		form.form.Currency = params.Args[5]
+		fallthrough
+	case 5:
+		form.form.RatesFile = params.Args[4]
+		fallthrough
+	case 4:
+		to, err := checkUIntArg(params.Args[3], "to", 64)
+		if err != nil {
+			return nil, err
+		}
+		form.form.To = to
+		fallthrough
+	case 3:
+		from, err := checkUIntArg(params.Args[2], "from", 64)
+		if err != nil {
+			return nil, err
+		}
+		form.form.From = from
+		fallthrough
+	case 2:
+		form.form.Format = params.Args[1]
+	}
+	return form, nil
+}
+
 func parseDeleteArchivedRecordsArgs(params *RawParams) (form *deleteRecordsForm, err error) {
 	if err = checkNArgs(params, []int{0}, []int{0, 3}); err != nil {
 		return nil, err
@@ -1147,6 +1280,267 @@ func parseTxHistoryArgs(params *RawParams) (*txHistoryForm, error) {
 	}, nil
 }
 
+func parseListUTXOsArgs(params *RawParams) (uint32, error) {
+	if err := checkNArgs(params, []int{0}, []int{1}); err != nil {
+		return 0, err
+	}
+	assetID, err := checkUIntArg(params.Args[0], "assetID", 32)
+	if err != nil {
+		return 0, err
+	}
+	return uint32(assetID), nil
+}
+
+func parseSetUTXOLabelArgs(params *RawParams) (*setUTXOLabelForm, error) {
+	if err := checkNArgs(params, []int{0}, []int{2, 3}); err != nil {
+		return nil, err
+	}
+	assetID, err := checkUIntArg(params.Args[0], "assetID", 32)
+	if err != nil {
+		return nil, err
+	}
+	coinID, err := hex.DecodeString(params.Args[1])
+	if err != nil {
+		return nil, fmt.Errorf("%w: invalid coinID: %v", errArgs, err)
+	}
+	form := &setUTXOLabelForm{
+		assetID: uint32(assetID),
+		coinID:  coinID,
+	}
+	if len(params.Args) > 2 {
+		form.label = params.Args[2]
+	}
+	return form, nil
+}
+
+func parseFreezeUTXOsArgs(params *RawParams) (*freezeUTXOsForm, error) {
+	if err := checkNArgs(params, []int{0}, []int{2, 3}); err != nil {
+		return nil, err
+	}
+	assetID, err := checkUIntArg(params.Args[0], "assetID", 32)
+	if err != nil {
+		return nil, err
+	}
+	coinIDs, err := checkCoinIDsArg(params.Args[1], "coins")
+	if err != nil {
+		return nil, err
+	}
+	freeze := true
+	if len(params.Args) > 2 {
+		freeze, err = checkBoolArg(params.Args[2], "freeze")
+		if err != nil {
+			return nil, err
+		}
+	}
+	return &freezeUTXOsForm{
+		assetID: uint32(assetID),
+		coinIDs: coinIDs,
+		freeze:  freeze,
+	}, nil
+}
+
+func parseBackupArgs(params *RawParams) (*backupForm, error) {
+	if err := checkNArgs(params, []int{1}, []int{1}); err != nil {
+		return nil, err
+	}
+	if params.Args[0] == "" {
+		return nil, fmt.Errorf("%w: no backup file path", errArgs)
+	}
+	return &backupForm{
+		appPass: params.PWArgs[0],
+		path:    params.Args[0],
+	}, nil
+}
+
+func parseCreateAPIKeyArgs(params *RawParams) (*createAPIKeyForm, error) {
+	if err := checkNArgs(params, []int{1}, []int{2, 4}); err != nil {
+		return nil, err
+	}
+	form := &core.APIKeyForm{
+		Name:   params.Args[0],
+		Scopes: strings.Split(params.Args[1], ","),
+	}
+	if len(params.Args) > 2 && params.Args[2] != "" {
+		if err := json.Unmarshal([]byte(params.Args[2]), &form.AddressAllowlist); err != nil {
+			return nil, fmt.Errorf("%w: allowlist must be a JSON-encoded map of asset IDs to address arrays: %v", errArgs, err)
+		}
+	}
+	if len(params.Args) > 3 {
+		expiry, err := time.ParseDuration(params.Args[3])
+		if err != nil || expiry <= 0 {
+			return nil, fmt.Errorf("%w: invalid expiry %q", errArgs, params.Args[3])
+		}
+		form.Expiration = time.Now().Add(expiry).Unix()
+	}
+	return &createAPIKeyForm{
+		appPass: params.PWArgs[0],
+		form:    form,
+	}, nil
+}
+
+func parseRevokeAPIKeyArgs(params *RawParams) (string, error) {
+	if err := checkNArgs(params, []int{0}, []int{1}); err != nil {
+		return "", err
+	}
+	return params.Args[0], nil
+}
+
+func parseAddressBookArgs(params *RawParams, withLabel bool) (*addressBookForm, error) {
+	nArgs := []int{2}
+	if withLabel {
+		nArgs = []int{2, 3}
+	}
+	if err := checkNArgs(params, []int{1}, nArgs); err != nil {
+		return nil, err
+	}
+	assetID, err := checkUIntArg(params.Args[0], "assetID", 32)
+	if err != nil {
+		return nil, err
+	}
+	form := &addressBookForm{
+		appPass: params.PWArgs[0],
+		assetID: uint32(assetID),
+		address: params.Args[1],
+	}
+	if len(params.Args) > 2 {
+		form.label = params.Args[2]
+	}
+	return form, nil
+}
+
+func parseSetWithdrawalPolicyArgs(params *RawParams) (*withdrawalPolicyForm, error) {
+	if err := checkNArgs(params, []int{1}, []int{2, 3}); err != nil {
+		return nil, err
+	}
+	assetID, err := checkUIntArg(params.Args[0], "assetID", 32)
+	if err != nil {
+		return nil, err
+	}
+	allowlistOnly, err := checkBoolArg(params.Args[1], "allowlistOnly")
+	if err != nil {
+		return nil, err
+	}
+	form := &withdrawalPolicyForm{
+		appPass:       params.PWArgs[0],
+		assetID:       uint32(assetID),
+		allowlistOnly: allowlistOnly,
+	}
+	if len(params.Args) > 2 {
+		if form.dailyLimit, err = checkUIntArg(params.Args[2], "dailyLimit", 64); err != nil {
+			return nil, err
+		}
+	}
+	return form, nil
+}
+
+func parseCreateConditionalOrderArgs(params *RawParams) (*conditionalOrderForm, error) {
+	if err := checkNArgs(params, []int{1}, []int{9}); err != nil {
+		return nil, err
+	}
+	isLimit, err := checkBoolArg(params.Args[2], "isLimit")
+	if err != nil {
+		return nil, err
+	}
+	sell, err := checkBoolArg(params.Args[3], "sell")
+	if err != nil {
+		return nil, err
+	}
+	base, err := checkUIntArg(params.Args[4], "base", 32)
+	if err != nil {
+		return nil, err
+	}
+	quote, err := checkUIntArg(params.Args[5], "quote", 32)
+	if err != nil {
+		return nil, err
+	}
+	qty, err := checkUIntArg(params.Args[6], "qty", 64)
+	if err != nil {
+		return nil, err
+	}
+	rate, err := checkUIntArg(params.Args[7], "rate", 64)
+	if err != nil {
+		return nil, err
+	}
+	form := &core.ConditionalOrderForm{
+		Host:    params.Args[0],
+		Type:    params.Args[1],
+		IsLimit: isLimit,
+		Sell:    sell,
+		Base:    uint32(base),
+		Quote:   uint32(quote),
+		Qty:     qty,
+		Rate:    rate,
+	}
+	if form.Type == core.CondTrailingStop {
+		if form.TrailPercent, err = strconv.ParseFloat(params.Args[8], 64); err != nil {
+			return nil, fmt.Errorf("%w: cannot parse trail percentage: %v", errArgs, err)
+		}
+	} else if form.TriggerRate, err = checkUIntArg(params.Args[8], "trigger", 64); err != nil {
+		return nil, err
+	}
+	return &conditionalOrderForm{appPass: params.PWArgs[0], form: form}, nil
+}
+
+func parseCreateManagedOrderArgs(params *RawParams) (*managedOrderForm, error) {
+	if err := checkNArgs(params, []int{1}, []int{8, 9}); err != nil {
+		return nil, err
+	}
+	sell, err := checkBoolArg(params.Args[2], "sell")
+	if err != nil {
+		return nil, err
+	}
+	base, err := checkUIntArg(params.Args[3], "base", 32)
+	if err != nil {
+		return nil, err
+	}
+	quote, err := checkUIntArg(params.Args[4], "quote", 32)
+	if err != nil {
+		return nil, err
+	}
+	qty, err := checkUIntArg(params.Args[5], "qty", 64)
+	if err != nil {
+		return nil, err
+	}
+	rate, err := checkUIntArg(params.Args[6], "rate", 64)
+	if err != nil {
+		return nil, err
+	}
+	form := &core.ManagedOrderForm{
+		Host:  params.Args[0],
+		Type:  params.Args[1],
+		Sell:  sell,
+		Base:  uint32(base),
+		Quote: uint32(quote),
+		Qty:   qty,
+		Rate:  rate,
+	}
+	switch form.Type {
+	case core.ManagedTWAP:
+		if len(params.Args) != 9 {
+			return nil, fmt.Errorf("%w: TWAP orders require a duration", errArgs)
+		}
+		slices, err := checkUIntArg(params.Args[7], "slices", 32)
+		if err != nil {
+			return nil, err
+		}
+		dur, err := time.ParseDuration(params.Args[8])
+		if err != nil || dur <= 0 {
+			return nil, fmt.Errorf("%w: invalid duration %q", errArgs, params.Args[8])
+		}
+		form.Slices, form.Duration = uint32(slices), uint64(dur.Milliseconds())
+	case core.ManagedIceberg:
+		if len(params.Args) != 8 {
+			return nil, fmt.Errorf("%w: iceberg orders do not take a duration", errArgs)
+		}
+		if form.Clip, err = checkUIntArg(params.Args[7], "clip", 64); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("%w: unknown managed order type %q", errArgs, form.Type)
+	}
+	return &managedOrderForm{appPass: params.PWArgs[0], form: form}, nil
+}
+
 type walletTxForm struct {
 	assetID uint32
 	txID    string
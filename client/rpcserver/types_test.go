@@ -614,6 +614,16 @@ func TestMyOrdersArgs(t *testing.T) {
 	}, {
 		name:   "ok with blank host, base, and quote",
 		params: paramsWithArgs("", "0", "42"),
+	}, {
+		name:   "ok archived",
+		params: paramsWithArgs("host", "0", "42", "true"),
+	}, {
+		name:   "ok archived all markets",
+		params: paramsWithArgs("", "", "", "1"),
+	}, {
+		name:    "archived not bool",
+		params:  paramsWithArgs("host", "0", "42", "yes"),
+		wantErr: errArgs,
 	}, {
 		name:    "base but no quote",
 		params:  paramsWithArgs("host", "0"),
@@ -641,12 +651,15 @@ func TestMyOrdersArgs(t *testing.T) {
 		if len(test.params.Args) > 0 && res.host != test.params.Args[0] {
 			t.Fatalf("host doesn't match")
 		}
-		if len(test.params.Args) > 1 && fmt.Sprint(*res.base) != test.params.Args[1] {
+		if len(test.params.Args) > 1 && test.params.Args[1] != "" && fmt.Sprint(*res.base) != test.params.Args[1] {
 			t.Fatalf("base doesn't match")
 		}
-		if len(test.params.Args) > 2 && fmt.Sprint(*res.quote) != test.params.Args[2] {
+		if len(test.params.Args) > 2 && test.params.Args[2] != "" && fmt.Sprint(*res.quote) != test.params.Args[2] {
 			t.Fatalf("quote doesn't match")
 		}
+		if res.archived != (len(test.params.Args) > 3) {
+			t.Fatalf("archived doesn't match")
+		}
 	}
 }
 
@@ -5,6 +5,7 @@ package webserver
 
 import (
 	"archive/zip"
+	"bytes"
 	"encoding/hex"
 	"encoding/json"
 	"errors"
@@ -12,6 +13,7 @@ import (
 	"io"
 	"net/http"
 	"os"
+	"strconv"
 	"time"
 
 	"decred.org/dcrdex/client/asset"
@@ -19,6 +21,7 @@ import (
 	"decred.org/dcrdex/client/db"
 	"decred.org/dcrdex/client/mm"
 	"decred.org/dcrdex/client/mm/libxc"
+	"decred.org/dcrdex/client/notify"
 	"decred.org/dcrdex/dex"
 	"decred.org/dcrdex/dex/config"
 	"decred.org/dcrdex/dex/encode"
@@ -40,7 +43,7 @@ func (s *WebServer) apiAddDEX(w http.ResponseWriter, r *http.Request) {
 	}
 	cert := []byte(form.Cert)
 
-	if err = s.core.AddDEX(appPW, form.Addr, cert); err != nil {
+	if err = s.core(r).AddDEX(appPW, form.Addr, cert); err != nil {
 		s.writeAPIError(w, err)
 		return
 	}
@@ -61,7 +64,7 @@ func (s *WebServer) apiDiscoverAccount(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	defer zero(pass)
-	exchangeInfo, paid, err := s.core.DiscoverAccount(form.Addr, pass, cert) // TODO: update when paid return removed
+	exchangeInfo, paid, err := s.core(r).DiscoverAccount(form.Addr, pass, cert) // TODO: update when paid return removed
 	if err != nil {
 		s.writeAPIError(w, err)
 		return
@@ -91,7 +94,7 @@ func (s *WebServer) apiValidateAddress(w http.ResponseWriter, r *http.Request) {
 		s.writeAPIError(w, errors.New("missing asset ID"))
 		return
 	}
-	valid, err := s.core.ValidateAddress(form.Addr, *form.AssetID)
+	valid, err := s.core(r).ValidateAddress(form.Addr, *form.AssetID)
 	if err != nil {
 		s.writeAPIError(w, err)
 		return
@@ -114,7 +117,7 @@ func (s *WebServer) apiEstimateSendTxFee(w http.ResponseWriter, r *http.Request)
 		s.writeAPIError(w, errors.New("missing asset ID"))
 		return
 	}
-	txFee, validAddress, err := s.core.EstimateSendTxFee(form.Addr, *form.AssetID, form.Value, form.Subtract, form.MaxWithdraw)
+	txFee, validAddress, err := s.core(r).EstimateSendTxFee(form.Addr, *form.AssetID, form.Value, form.Subtract, form.MaxWithdraw)
 	if err != nil {
 		s.writeAPIError(w, err)
 		return
@@ -139,7 +142,7 @@ func (s *WebServer) apiGetWalletPeers(w http.ResponseWriter, r *http.Request) {
 	if !readPost(w, r, &form) {
 		return
 	}
-	peers, err := s.core.WalletPeers(form.AssetID)
+	peers, err := s.core(r).WalletPeers(form.AssetID)
 	if err != nil {
 		s.writeAPIError(w, err)
 		return
@@ -163,7 +166,7 @@ func (s *WebServer) apiAddWalletPeer(w http.ResponseWriter, r *http.Request) {
 	if !readPost(w, r, &form) {
 		return
 	}
-	err := s.core.AddWalletPeer(form.AssetID, form.Address)
+	err := s.core(r).AddWalletPeer(form.AssetID, form.Address)
 	if err != nil {
 		s.writeAPIError(w, err)
 		return
@@ -180,7 +183,7 @@ func (s *WebServer) apiRemoveWalletPeer(w http.ResponseWriter, r *http.Request)
 	if !readPost(w, r, &form) {
 		return
 	}
-	err := s.core.RemoveWalletPeer(form.AssetID, form.Address)
+	err := s.core(r).RemoveWalletPeer(form.AssetID, form.Address)
 	if err != nil {
 		s.writeAPIError(w, err)
 		return
@@ -198,7 +201,7 @@ func (s *WebServer) apiApproveTokenFee(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	txFee, err := s.core.ApproveTokenFee(form.AssetID, form.Version, form.Approval)
+	txFee, err := s.core(r).ApproveTokenFee(form.AssetID, form.Version, form.Approval)
 	if err != nil {
 		s.writeAPIError(w, err)
 		return
@@ -230,7 +233,7 @@ func (s *WebServer) apiApproveToken(w http.ResponseWriter, r *http.Request) {
 	}
 	defer zero(pass)
 
-	txID, err := s.core.ApproveToken(pass, form.AssetID, form.DexAddr, func() {})
+	txID, err := s.core(r).ApproveToken(pass, form.AssetID, form.DexAddr, func() {})
 	if err != nil {
 		s.writeAPIError(w, err)
 		return
@@ -261,7 +264,7 @@ func (s *WebServer) apiUnapproveToken(w http.ResponseWriter, r *http.Request) {
 	}
 	defer zero(pass)
 
-	txID, err := s.core.UnapproveToken(pass, form.AssetID, form.Version)
+	txID, err := s.core(r).UnapproveToken(pass, form.AssetID, form.Version)
 	if err != nil {
 		s.writeAPIError(w, err)
 		return
@@ -283,7 +286,7 @@ func (s *WebServer) apiGetDEXInfo(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	cert := []byte(form.Cert)
-	exchangeInfo, err := s.core.GetDEXConfig(form.Addr, cert)
+	exchangeInfo, err := s.core(r).GetDEXConfig(form.Addr, cert)
 	if err != nil {
 		s.writeAPIError(w, err)
 		return
@@ -302,7 +305,7 @@ func (s *WebServer) apiGetDEXInfo(w http.ResponseWriter, r *http.Request) {
 // given asset are cached for 45 minutes. These values are meant to provide a
 // sensible but well-padded fee buffer for bond transactions now and well into
 // the future, so a long expiry is appropriate.
-func (s *WebServer) bondsFeeBuffer(assetID uint32) (feeBuffer uint64, err error) {
+func (s *WebServer) bondsFeeBuffer(c clientCore, assetID uint32) (feeBuffer uint64, err error) {
 	// (*Core).BondsFeeBuffer returns a fresh fee buffer based on a current (but
 	// padded) fee rate estimate. We assist the frontend by stabilizing this
 	// value for up to 45 minutes from the last request for a given asset. A web
@@ -317,7 +320,7 @@ func (s *WebServer) bondsFeeBuffer(assetID uint32) (feeBuffer uint64, err error)
 		log.Tracef("Using cached bond fee buffer (%v old): %d",
 			time.Since(buf.stamp), feeBuffer)
 	} else {
-		feeBuffer, err = s.core.BondsFeeBuffer(assetID)
+		feeBuffer, err = c.BondsFeeBuffer(assetID)
 		if err != nil {
 			return
 		}
@@ -333,7 +336,7 @@ func (s *WebServer) apiBondsFeeBuffer(w http.ResponseWriter, r *http.Request) {
 	if !readPost(w, r, form) {
 		return
 	}
-	feeBuffer, err := s.bondsFeeBuffer(form.AssetID)
+	feeBuffer, err := s.bondsFeeBuffer(s.core(r), form.AssetID)
 	if err != nil {
 		s.writeAPIError(w, err)
 		return
@@ -359,7 +362,7 @@ func (s *WebServer) apiPostBond(w http.ResponseWriter, r *http.Request) {
 	if post.AssetID != nil {
 		assetID = *post.AssetID
 	}
-	wallet := s.core.WalletState(assetID)
+	wallet := s.core(r).WalletState(assetID)
 	if wallet == nil {
 		s.writeAPIError(w, errors.New("no wallet"))
 		return
@@ -387,7 +390,7 @@ func (s *WebServer) apiPostBond(w http.ResponseWriter, r *http.Request) {
 		bondForm.FeeBuffer = *post.FeeBuffer
 	}
 
-	_, err = s.core.PostBond(bondForm)
+	_, err = s.core(r).PostBond(bondForm)
 	if err != nil {
 		s.writeAPIError(w, fmt.Errorf("add bond error: %w", err))
 		return
@@ -405,7 +408,7 @@ func (s *WebServer) apiUpdateBondOptions(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	err := s.core.UpdateBondOptions(form)
+	err := s.core(r).UpdateBondOptions(form)
 	if err != nil {
 		s.writeAPIError(w, fmt.Errorf("update bond options error: %w", err))
 		return
@@ -430,7 +433,7 @@ func (s *WebServer) apiRedeemPrepaidBond(w http.ResponseWriter, r *http.Request)
 		s.writeAPIError(w, fmt.Errorf("password error: %w", err))
 		return
 	}
-	tier, err := s.core.RedeemPrepaidBond(appPW, req.Code, req.Host, req.Cert)
+	tier, err := s.core(r).RedeemPrepaidBond(appPW, req.Code, req.Host, req.Cert)
 	if err != nil {
 		s.writeAPIError(w, err)
 		return
@@ -453,7 +456,7 @@ func (s *WebServer) apiNewWallet(w http.ResponseWriter, r *http.Request) {
 	if !readPost(w, r, form) {
 		return
 	}
-	has := s.core.WalletState(form.AssetID) != nil
+	has := s.core(r).WalletState(form.AssetID) != nil
 	if has {
 		s.writeAPIError(w, fmt.Errorf("already have a wallet for %s", unbip(form.AssetID)))
 		return
@@ -473,7 +476,7 @@ func (s *WebServer) apiNewWallet(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 	// Wallet does not exist yet. Try to create it.
-	err = s.core.CreateWallet(pass, form.Pass, &core.WalletForm{
+	err = s.core(r).CreateWallet(pass, form.Pass, &core.WalletForm{
 		AssetID:    form.AssetID,
 		Type:       form.WalletType,
 		Config:     form.Config,
@@ -503,12 +506,12 @@ func (s *WebServer) apiRecoverWallet(w http.ResponseWriter, r *http.Request) {
 		s.writeAPIError(w, fmt.Errorf("password error: %w", err))
 		return
 	}
-	status := s.core.WalletState(form.AssetID)
+	status := s.core(r).WalletState(form.AssetID)
 	if status == nil {
 		s.writeAPIError(w, fmt.Errorf("no wallet for %d -> %s", form.AssetID, unbip(form.AssetID)))
 		return
 	}
-	err = s.core.RecoverWallet(form.AssetID, appPW, form.Force)
+	err = s.core(r).RecoverWallet(form.AssetID, appPW, form.Force)
 	if err != nil {
 		// NOTE: client may check for code activeOrdersErr to prompt for
 		// override the active orders safety check.
@@ -529,12 +532,12 @@ func (s *WebServer) apiRescanWallet(w http.ResponseWriter, r *http.Request) {
 	if !readPost(w, r, &form) {
 		return
 	}
-	status := s.core.WalletState(form.AssetID)
+	status := s.core(r).WalletState(form.AssetID)
 	if status == nil {
 		s.writeAPIError(w, fmt.Errorf("No wallet for %d -> %s", form.AssetID, unbip(form.AssetID)))
 		return
 	}
-	err := s.core.RescanWallet(form.AssetID, form.Force)
+	err := s.core(r).RescanWallet(form.AssetID, form.Force)
 	if err != nil {
 		s.writeAPIError(w, fmt.Errorf("error rescanning %s wallet: %w", unbip(form.AssetID), err))
 		return
@@ -551,7 +554,7 @@ func (s *WebServer) apiOpenWallet(w http.ResponseWriter, r *http.Request) {
 	if !readPost(w, r, form) {
 		return
 	}
-	status := s.core.WalletState(form.AssetID)
+	status := s.core(r).WalletState(form.AssetID)
 	if status == nil {
 		s.writeAPIError(w, fmt.Errorf("No wallet for %d -> %s", form.AssetID, unbip(form.AssetID)))
 		return
@@ -562,7 +565,7 @@ func (s *WebServer) apiOpenWallet(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	defer zero(pass)
-	err = s.core.OpenWallet(form.AssetID, pass)
+	err = s.core(r).OpenWallet(form.AssetID, pass)
 	if err != nil {
 		s.writeAPIError(w, fmt.Errorf("error unlocking %s wallet: %w", unbip(form.AssetID), err))
 		return
@@ -585,7 +588,7 @@ func (s *WebServer) apiNewDepositAddress(w http.ResponseWriter, r *http.Request)
 	}
 	assetID := *form.AssetID
 
-	addr, err := s.core.NewDepositAddress(assetID)
+	addr, err := s.core(r).NewDepositAddress(assetID)
 	if err != nil {
 		s.writeAPIError(w, fmt.Errorf("error connecting to %s wallet: %w", unbip(assetID), err))
 		return
@@ -615,7 +618,7 @@ func (s *WebServer) apiAddressUsed(w http.ResponseWriter, r *http.Request) {
 	}
 	assetID := *form.AssetID
 
-	used, err := s.core.AddressUsed(assetID, form.Addr)
+	used, err := s.core(r).AddressUsed(assetID, form.Addr)
 	if err != nil {
 		s.writeAPIError(w, err)
 		return
@@ -639,7 +642,7 @@ func (s *WebServer) apiConnectWallet(w http.ResponseWriter, r *http.Request) {
 	if !readPost(w, r, form) {
 		return
 	}
-	err := s.core.ConnectWallet(form.AssetID)
+	err := s.core(r).ConnectWallet(form.AssetID)
 	if err != nil {
 		s.writeAPIError(w, fmt.Errorf("error connecting to %s wallet: %w", unbip(form.AssetID), err))
 		return
@@ -666,7 +669,7 @@ func (s *WebServer) apiTrade(w http.ResponseWriter, r *http.Request) {
 		s.writeAPIError(w, errors.New("order missing"))
 		return
 	}
-	ord, err := s.core.Trade(pass, form.Order)
+	ord, err := s.core(r).Trade(pass, form.Order)
 	if err != nil {
 		s.writeAPIError(w, fmt.Errorf("error placing order: %w", err))
 		return
@@ -696,7 +699,7 @@ func (s *WebServer) apiTradeAsync(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	defer zero(pass)
-	ord, err := s.core.TradeAsync(pass, form.Order)
+	ord, err := s.core(r).TradeAsync(pass, form.Order)
 	if err != nil {
 		s.writeAPIError(w, fmt.Errorf("error placing order: %w", err))
 		return
@@ -726,7 +729,7 @@ func (s *WebServer) apiAccountExport(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	defer zero(pass)
-	account, bonds, err := s.core.AccountExport(pass, form.Host)
+	account, bonds, err := s.core(r).AccountExport(pass, form.Host)
 	if err != nil {
 		s.writeAPIError(w, fmt.Errorf("error exporting account: %w", err))
 		return
@@ -757,7 +760,7 @@ func (s *WebServer) apiExportSeed(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	r.Close = true
-	seed, err := s.core.ExportSeed(form.Pass)
+	seed, err := s.core(r).ExportSeed(form.Pass)
 	if err != nil {
 		s.writeAPIError(w, fmt.Errorf("error exporting seed: %w", err))
 		return
@@ -771,6 +774,66 @@ func (s *WebServer) apiExportSeed(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
+// backupFiles are the files to include in a full-state backup, in addition to
+// the app DB, for the request's profile.
+func (s *WebServer) backupFiles(r *http.Request) []*core.BackupFile {
+	mmCore := s.mm(r)
+	if mmCore == nil {
+		return nil
+	}
+	return mmCore.BackupFiles()
+}
+
+// apiExportBackup is the handler for the '/exportbackup' API request.
+func (s *WebServer) apiExportBackup(w http.ResponseWriter, r *http.Request) {
+	form := &struct {
+		Pass encode.PassBytes `json:"pass"`
+	}{}
+	defer form.Pass.Clear()
+	if !readPost(w, r, form) {
+		return
+	}
+	r.Close = true
+	backup, err := s.core(r).ExportBackup(form.Pass, s.backupFiles(r))
+	if err != nil {
+		s.writeAPIError(w, fmt.Errorf("error exporting backup: %w", err))
+		return
+	}
+	writeJSON(w, &struct {
+		OK     bool   `json:"ok"`
+		Backup []byte `json:"backup"`
+	}{
+		OK:     true,
+		Backup: backup,
+	})
+}
+
+// apiRestoreBackup is the handler for the '/restorebackup' API request. The
+// backup can only be restored before the app is initialized.
+func (s *WebServer) apiRestoreBackup(w http.ResponseWriter, r *http.Request) {
+	form := &struct {
+		Pass   encode.PassBytes `json:"pass"`
+		Backup []byte           `json:"backup"`
+	}{}
+	defer form.Pass.Clear()
+	if !readPost(w, r, form) {
+		return
+	}
+	r.Close = true
+	manifest, err := s.core(r).RestoreBackup(form.Pass, form.Backup, s.backupFiles(r))
+	if err != nil {
+		s.writeAPIError(w, fmt.Errorf("error restoring backup: %w", err))
+		return
+	}
+	writeJSON(w, &struct {
+		OK       bool                 `json:"ok"`
+		Manifest *core.BackupManifest `json:"manifest"`
+	}{
+		OK:       true,
+		Manifest: manifest,
+	})
+}
+
 // apiAccountImport is the handler for the '/importaccount' API request.
 func (s *WebServer) apiAccountImport(w http.ResponseWriter, r *http.Request) {
 	form := new(accountImportForm)
@@ -789,7 +852,7 @@ func (s *WebServer) apiAccountImport(w http.ResponseWriter, r *http.Request) {
 		s.writeAPIError(w, errors.New("account missing"))
 		return
 	}
-	err = s.core.AccountImport(pass, form.Account, form.Bonds)
+	err = s.core(r).AccountImport(pass, form.Account, form.Bonds)
 	if err != nil {
 		s.writeAPIError(w, fmt.Errorf("error importing account: %w", err))
 		return
@@ -807,7 +870,7 @@ func (s *WebServer) apiUpdateCert(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	err := s.core.UpdateCert(form.Host, []byte(form.Cert))
+	err := s.core(r).UpdateCert(form.Host, []byte(form.Cert))
 	if err != nil {
 		s.writeAPIError(w, fmt.Errorf("error updating cert: %w", err))
 		return
@@ -834,7 +897,7 @@ func (s *WebServer) apiUpdateDEXHost(w http.ResponseWriter, r *http.Request) {
 	}
 	defer zero(pass)
 	cert := []byte(form.Cert)
-	exchange, err := s.core.UpdateDEXHost(form.OldHost, form.NewHost, pass, cert)
+	exchange, err := s.core(r).UpdateDEXHost(form.OldHost, form.NewHost, pass, cert)
 	if err != nil {
 		s.writeAPIError(w, fmt.Errorf("error updating host: %w", err))
 		return
@@ -863,7 +926,7 @@ func (s *WebServer) apiRestoreWalletInfo(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	info, err := s.core.WalletRestorationInfo(form.Pass, form.AssetID)
+	info, err := s.core(r).WalletRestorationInfo(form.Pass, form.AssetID)
 	if err != nil {
 		s.writeAPIError(w, fmt.Errorf("error updating cert: %w", err))
 		return
@@ -893,7 +956,7 @@ func (s *WebServer) apiToggleAccountStatus(w http.ResponseWriter, r *http.Reques
 		return
 	}
 	// Disable account.
-	err = s.core.ToggleAccountStatus(appPW, form.Host, form.Disable)
+	err = s.core(r).ToggleAccountStatus(appPW, form.Host, form.Disable)
 	if err != nil {
 		s.writeAPIError(w, fmt.Errorf("error updating account status: %w", err))
 		return
@@ -910,7 +973,7 @@ func (s *WebServer) apiCancel(w http.ResponseWriter, r *http.Request) {
 	if !readPost(w, r, form) {
 		return
 	}
-	err := s.core.Cancel(form.OrderID)
+	err := s.core(r).Cancel(form.OrderID)
 	if err != nil {
 		s.writeAPIError(w, fmt.Errorf("error cancelling order %s: %w", form.OrderID, err))
 		return
@@ -926,7 +989,7 @@ func (s *WebServer) apiCloseWallet(w http.ResponseWriter, r *http.Request) {
 	if !readPost(w, r, form) {
 		return
 	}
-	err := s.core.CloseWallet(form.AssetID)
+	err := s.core(r).CloseWallet(form.AssetID)
 	if err != nil {
 		s.writeAPIError(w, fmt.Errorf("error locking %s wallet: %w", unbip(form.AssetID), err))
 		return
@@ -940,21 +1003,33 @@ func (s *WebServer) apiInit(w http.ResponseWriter, r *http.Request) {
 	var init struct {
 		Pass encode.PassBytes `json:"pass"`
 		Seed string           `json:"seed,omitempty"`
+		// Profile is the name of the profile to initialize. If empty, the
+		// session's current profile is initialized.
+		Profile string `json:"profile,omitempty"`
 	}
 	defer init.Pass.Clear()
 	if !readPost(w, r, &init) {
 		return
 	}
+	profile := init.Profile
+	if profile == "" {
+		profile = s.sessionProfile(r)
+	}
+	p, found := s.profiles[profile]
+	if !found {
+		s.writeAPIError(w, fmt.Errorf("unknown profile %q", profile))
+		return
+	}
 	var seed *string
 	if len(init.Seed) > 0 {
 		seed = &init.Seed
 	}
-	mnemonicSeed, err := s.core.InitializeClient(init.Pass, seed)
+	mnemonicSeed, err := p.Core.InitializeClient(init.Pass, seed)
 	if err != nil {
 		s.writeAPIError(w, fmt.Errorf("initialization error: %w", err))
 		return
 	}
-	err = s.actuallyLogin(w, r, &loginForm{Pass: init.Pass})
+	_, err = s.actuallyLogin(w, r, &loginForm{Pass: init.Pass, Profile: profile})
 	if err != nil {
 		s.writeAPIError(w, err)
 		return
@@ -966,7 +1041,7 @@ func (s *WebServer) apiInit(w http.ResponseWriter, r *http.Request) {
 		MnemonicSeed string   `json:"mnemonic"`
 	}{
 		OK:           true,
-		Hosts:        s.knownUnregisteredExchanges(map[string]*core.Exchange{}),
+		Hosts:        s.knownUnregisteredExchanges(r, map[string]*core.Exchange{}),
 		MnemonicSeed: mnemonicSeed,
 	})
 }
@@ -978,7 +1053,7 @@ func (s *WebServer) apiIsInitialized(w http.ResponseWriter, r *http.Request) {
 		Initialized bool `json:"initialized"`
 	}{
 		OK:          true,
-		Initialized: s.core.IsInitialized(),
+		Initialized: s.core(r).IsInitialized(),
 	})
 }
 
@@ -1009,7 +1084,7 @@ func (s *WebServer) apiSetLocale(w http.ResponseWriter, r *http.Request) {
 	if !readPost(w, r, &lang) {
 		return
 	}
-	if err := s.core.SetLanguage(lang); err != nil {
+	if err := s.core(r).SetLanguage(lang); err != nil {
 		s.writeAPIError(w, err)
 		return
 	}
@@ -1031,13 +1106,13 @@ func (s *WebServer) apiLogin(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	err := s.actuallyLogin(w, r, login)
+	profile, err := s.actuallyLogin(w, r, login)
 	if err != nil {
 		s.writeAPIError(w, err)
 		return
 	}
 
-	notes, pokes, err := s.core.Notifications(100)
+	notes, pokes, err := s.profiles[profile].Core.Notifications(100)
 	if err != nil {
 		log.Errorf("failed to get notifications: %v", err)
 	}
@@ -1054,7 +1129,7 @@ func (s *WebServer) apiLogin(w http.ResponseWriter, r *http.Request) {
 }
 
 func (s *WebServer) apiNotes(w http.ResponseWriter, r *http.Request) {
-	notes, pokes, err := s.core.Notifications(100)
+	notes, pokes, err := s.core(r).Notifications(100)
 	if err != nil {
 		s.writeAPIError(w, fmt.Errorf("failed to get notifications: %w", err))
 		return
@@ -1073,15 +1148,16 @@ func (s *WebServer) apiNotes(w http.ResponseWriter, r *http.Request) {
 
 // apiLogout handles the 'logout' API request.
 func (s *WebServer) apiLogout(w http.ResponseWriter, r *http.Request) {
-	err := s.core.Logout()
+	profile := s.sessionProfile(r)
+	err := s.profiles[profile].Core.Logout()
 	if err != nil {
 		s.writeAPIError(w, fmt.Errorf("logout error: %w", err))
 		return
 	}
 
 	// With Core locked up, invalidate all known auth tokens and cached passwords
-	// to force any other sessions to login again.
-	s.deauth()
+	// of the profile to force any other sessions to login again.
+	s.deauth(profile)
 
 	clearCookie(authCK, w)
 	clearCookie(pwKeyCK, w)
@@ -1102,7 +1178,7 @@ func (s *WebServer) apiGetBalance(w http.ResponseWriter, r *http.Request) {
 	if !readPost(w, r, form) {
 		return
 	}
-	bal, err := s.core.AssetBalance(form.AssetID)
+	bal, err := s.core(r).AssetBalance(form.AssetID)
 	if err != nil {
 		s.writeAPIError(w, fmt.Errorf("balance error: %w", err))
 		return
@@ -1149,7 +1225,7 @@ func (s *WebServer) apiWalletSettings(w http.ResponseWriter, r *http.Request) {
 	if !readPost(w, r, form) {
 		return
 	}
-	settings, err := s.core.WalletSettings(form.AssetID)
+	settings, err := s.core(r).WalletSettings(form.AssetID)
 	if err != nil {
 		s.writeAPIError(w, fmt.Errorf("error setting wallet settings: %w", err))
 		return
@@ -1169,7 +1245,7 @@ func (s *WebServer) apiToggleWalletStatus(w http.ResponseWriter, r *http.Request
 	if !readPost(w, r, form) {
 		return
 	}
-	err := s.core.ToggleWalletStatus(form.AssetID, form.Disable)
+	err := s.core(r).ToggleWalletStatus(form.AssetID, form.Disable)
 	if err != nil {
 		s.writeAPIError(w, fmt.Errorf("error setting wallet settings: %w", err))
 		return
@@ -1192,7 +1268,7 @@ func (s *WebServer) apiDefaultWalletCfg(w http.ResponseWriter, r *http.Request)
 	if !readPost(w, r, form) {
 		return
 	}
-	cfg, err := s.core.AutoWalletConfig(form.AssetID, form.Type)
+	cfg, err := s.core(r).AutoWalletConfig(form.AssetID, form.Type)
 	if err != nil {
 		s.writeAPIError(w, fmt.Errorf("error getting wallet config: %w", err))
 		return
@@ -1213,7 +1289,7 @@ func (s *WebServer) apiOrders(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	ords, err := s.core.Orders(filter)
+	ords, err := s.core(r).Orders(filter)
 	if err != nil {
 		s.writeAPIError(w, fmt.Errorf("Orders error: %w", err))
 		return
@@ -1244,7 +1320,7 @@ func (s *WebServer) apiAccelerateOrder(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	txID, err := s.core.AccelerateOrder(pass, form.OrderID, form.NewRate)
+	txID, err := s.core(r).AccelerateOrder(pass, form.OrderID, form.NewRate)
 	if err != nil {
 		s.writeAPIError(w, fmt.Errorf("Accelerate Order error: %w", err))
 		return
@@ -1267,7 +1343,7 @@ func (s *WebServer) apiPreAccelerate(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	preAccelerate, err := s.core.PreAccelerateOrder(oid)
+	preAccelerate, err := s.core(r).PreAccelerateOrder(oid)
 	if err != nil {
 		s.writeAPIError(w, fmt.Errorf("Pre accelerate error: %w", err))
 		return
@@ -1294,7 +1370,7 @@ func (s *WebServer) apiAccelerationEstimate(w http.ResponseWriter, r *http.Reque
 		return
 	}
 
-	fee, err := s.core.AccelerationEstimate(form.OrderID, form.NewRate)
+	fee, err := s.core(r).AccelerationEstimate(form.OrderID, form.NewRate)
 	if err != nil {
 		s.writeAPIError(w, fmt.Errorf("Accelerate Order error: %w", err))
 		return
@@ -1316,7 +1392,7 @@ func (s *WebServer) apiOrder(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	ord, err := s.core.Order(oid)
+	ord, err := s.core(r).Order(oid)
 	if err != nil {
 		s.writeAPIError(w, fmt.Errorf("Order error: %w", err))
 		return
@@ -1343,7 +1419,8 @@ func (s *WebServer) apiChangeAppPass(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Update application password.
-	err := s.core.ChangeAppPass(form.AppPW, form.NewAppPW)
+	profile := s.sessionProfile(r)
+	err := s.profiles[profile].Core.ChangeAppPass(form.AppPW, form.NewAppPW)
 	if err != nil {
 		s.writeAPIError(w, fmt.Errorf("change app pass error: %w", err))
 		return
@@ -1353,8 +1430,8 @@ func (s *WebServer) apiChangeAppPass(w http.ResponseWriter, r *http.Request) {
 	// Since the user changed the password, we clear all of the auth tokens
 	// and cached passwords. However, we assign a new auth token and cache
 	// the new password (if it was previously cached) for this session.
-	s.deauth()
-	authToken := s.authorize()
+	s.deauth(profile)
+	authToken := s.authorize(profile)
 	setCookie(authCK, authToken, w)
 	if passwordIsCached {
 		key, err := s.cacheAppPassword(form.NewAppPW, authToken)
@@ -1381,7 +1458,7 @@ func (s *WebServer) apiResetAppPassword(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	err := s.core.ResetAppPass(form.NewPass, form.Seed)
+	err := s.core(r).ResetAppPass(form.NewPass, form.Seed)
 	if err != nil {
 		s.writeAPIError(w, err)
 		return
@@ -1414,7 +1491,7 @@ func (s *WebServer) apiReconfig(w http.ResponseWriter, r *http.Request) {
 	}
 	defer zero(pass)
 	// Update wallet settings.
-	err = s.core.ReconfigureWallet(pass, form.NewWalletPW, &core.WalletForm{
+	err = s.core(r).ReconfigureWallet(pass, form.NewWalletPW, &core.WalletForm{
 		AssetID: form.AssetID,
 		Config:  form.Config,
 		Type:    form.WalletType,
@@ -1434,7 +1511,7 @@ func (s *WebServer) apiSend(w http.ResponseWriter, r *http.Request) {
 	if !readPost(w, r, form) {
 		return
 	}
-	state := s.core.WalletState(form.AssetID)
+	state := s.core(r).WalletState(form.AssetID)
 	if state == nil {
 		s.writeAPIError(w, fmt.Errorf("no wallet found for %s", unbip(form.AssetID)))
 		return
@@ -1443,7 +1520,13 @@ func (s *WebServer) apiSend(w http.ResponseWriter, r *http.Request) {
 		s.writeAPIError(w, fmt.Errorf("empty password"))
 		return
 	}
-	coin, err := s.core.Send(form.Pass, form.AssetID, form.Value, form.Address, form.Subtract)
+	var coin asset.Coin
+	var err error
+	if len(form.Coins) > 0 {
+		coin, err = s.core(r).SendWithCoins(form.Pass, form.AssetID, form.Value, form.Address, form.Subtract, form.Coins)
+	} else {
+		coin, err = s.core(r).Send(form.Pass, form.AssetID, form.Value, form.Address, form.Subtract)
+	}
 	if err != nil {
 		s.writeAPIError(w, fmt.Errorf("send/withdraw error: %w", err))
 		return
@@ -1469,7 +1552,7 @@ func (s *WebServer) apiMaxBuy(w http.ResponseWriter, r *http.Request) {
 	if !readPost(w, r, form) {
 		return
 	}
-	maxBuy, err := s.core.MaxBuy(form.Host, form.Base, form.Quote, form.Rate)
+	maxBuy, err := s.core(r).MaxBuy(form.Host, form.Base, form.Quote, form.Rate)
 	if err != nil {
 		s.writeAPIError(w, fmt.Errorf("max order estimation error: %w", err))
 		return
@@ -1494,7 +1577,7 @@ func (s *WebServer) apiMaxSell(w http.ResponseWriter, r *http.Request) {
 	if !readPost(w, r, form) {
 		return
 	}
-	maxSell, err := s.core.MaxSell(form.Host, form.Base, form.Quote)
+	maxSell, err := s.core(r).MaxSell(form.Host, form.Base, form.Quote)
 	if err != nil {
 		s.writeAPIError(w, fmt.Errorf("max order estimation error: %w", err))
 		return
@@ -1516,7 +1599,7 @@ func (s *WebServer) apiPreOrder(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	est, err := s.core.PreOrder(form)
+	est, err := s.core(r).PreOrder(form)
 	if err != nil {
 		s.writeAPIError(w, err)
 		return
@@ -1535,42 +1618,61 @@ func (s *WebServer) apiPreOrder(w http.ResponseWriter, r *http.Request) {
 
 // apiActuallyLogin logs the user in. login form private data is expected to be
 // cleared by the caller.
-func (s *WebServer) actuallyLogin(w http.ResponseWriter, r *http.Request, login *loginForm) error {
+func (s *WebServer) actuallyLogin(w http.ResponseWriter, r *http.Request, login *loginForm) (string, error) {
+	sessionProfile := s.sessionProfile(r)
+	profile := login.Profile
+	if profile == "" {
+		profile = sessionProfile
+	}
+	p, found := s.profiles[profile]
+	if !found {
+		return "", fmt.Errorf("unknown profile %q", profile)
+	}
+	// The cached password is for the session's profile, so logging in to
+	// another profile requires its password.
+	if profile != sessionProfile && len(login.Pass) == 0 {
+		return "", fmt.Errorf("password error: %w", errNoPassword)
+	}
 	pass, err := s.resolvePass(login.Pass, r)
 	defer zero(pass)
 	if err != nil {
-		return fmt.Errorf("password error: %w", err)
+		return "", fmt.Errorf("password error: %w", err)
 	}
-	err = s.core.Login(pass)
+	err = p.Core.Login(pass)
 	if err != nil {
-		return fmt.Errorf("login error: %w", err)
+		return "", fmt.Errorf("login error: %w", err)
 	}
 
-	if !s.isAuthed(r) {
-		authToken := s.authorize()
+	authed := s.isAuthed(r)
+	if !authed || profile != sessionProfile {
+		if authed {
+			// The session is moving to another profile.
+			s.endSession(getAuthToken(r))
+		}
+		authToken := s.authorize(profile)
 		setCookie(authCK, authToken, w)
 		key, err := s.cacheAppPassword(pass, authToken)
 		if err != nil {
-			return fmt.Errorf("login error: %w", err)
+			return "", fmt.Errorf("login error: %w", err)
 
 		}
 		setCookie(pwKeyCK, hex.EncodeToString(key), w)
 		zero(key)
 	}
 
-	return nil
+	return profile, nil
 }
 
 // apiUser handles the 'user' API request.
 func (s *WebServer) apiUser(w http.ResponseWriter, r *http.Request) {
 	var u *core.User
 	if s.isAuthed(r) {
-		u = s.core.User()
+		u = s.core(r).User()
 	}
 
 	var mmStatus *mm.Status
-	if s.mm != nil {
-		mmStatus = s.mm.Status()
+	if mmCore := s.mm(r); mmCore != nil {
+		mmStatus = mmCore.Status()
 	}
 
 	response := struct {
@@ -1581,18 +1683,62 @@ func (s *WebServer) apiUser(w http.ResponseWriter, r *http.Request) {
 		OK       bool       `json:"ok"`
 		OnionUrl string     `json:"onionUrl"`
 		MMStatus *mm.Status `json:"mmStatus"`
+		Profile  string     `json:"profile"`
 	}{
 		User:     u,
 		Lang:     s.lang.Load().(string),
 		Langs:    s.langs,
-		Inited:   s.core.IsInitialized(),
+		Inited:   s.core(r).IsInitialized(),
 		OK:       true,
 		OnionUrl: s.onion,
 		MMStatus: mmStatus,
+		Profile:  s.sessionProfile(r),
 	}
 	writeJSON(w, response)
 }
 
+// apiProfiles handles the 'profiles' API request.
+func (s *WebServer) apiProfiles(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, &struct {
+		OK       bool     `json:"ok"`
+		Profiles []string `json:"profiles"`
+		Active   string   `json:"active"`
+	}{
+		OK:       true,
+		Profiles: s.profileNames(),
+		Active:   s.sessionProfile(r),
+	})
+}
+
+// apiSwitchProfile handles the 'switchprofile' API request. The session is
+// logged in to the new profile with its own app password. Other sessions, and
+// the websocket connections of this session, are not affected, so the client
+// should reconnect its websocket to receive the new profile's notifications.
+func (s *WebServer) apiSwitchProfile(w http.ResponseWriter, r *http.Request) {
+	form := &struct {
+		Profile string           `json:"profile"`
+		Pass    encode.PassBytes `json:"pass"`
+	}{}
+	defer form.Pass.Clear()
+	if !readPost(w, r, form) {
+		return
+	}
+	if form.Profile == s.sessionProfile(r) {
+		writeJSON(w, simpleAck())
+		return
+	}
+	if len(form.Pass) == 0 {
+		s.writeAPIError(w, fmt.Errorf("password error: %w", errNoPassword))
+		return
+	}
+	if _, err := s.actuallyLogin(w, r, &loginForm{Pass: form.Pass, Profile: form.Profile}); err != nil {
+		s.writeAPIError(w, err)
+		return
+	}
+	log.Infof("Session switched to profile %q", form.Profile)
+	writeJSON(w, simpleAck())
+}
+
 // apiToggleRateSource handles the /toggleratesource API request.
 func (s *WebServer) apiToggleRateSource(w http.ResponseWriter, r *http.Request) {
 	form := &struct {
@@ -1602,7 +1748,7 @@ func (s *WebServer) apiToggleRateSource(w http.ResponseWriter, r *http.Request)
 	if !readPost(w, r, form) {
 		return
 	}
-	err := s.core.ToggleRateSourceStatus(form.Source, form.Disable)
+	err := s.core(r).ToggleRateSourceStatus(form.Source, form.Disable)
 	if err != nil {
 		s.writeAPIError(w, fmt.Errorf("error disabling/enabling rate source: %w", err))
 		return
@@ -1623,7 +1769,7 @@ func (s *WebServer) apiDeleteArchivedRecords(w http.ResponseWriter, r *http.Requ
 		olderThan = &ot
 	}
 
-	archivedRecordsPath, nRecordsDeleted, err := s.core.DeleteArchivedRecordsWithBackup(olderThan, form.SaveMatchesToFile, form.SaveOrdersToFile)
+	archivedRecordsPath, nRecordsDeleted, err := s.core(r).DeleteArchivedRecordsWithBackup(olderThan, form.SaveMatchesToFile, form.SaveOrdersToFile)
 	if err != nil {
 		s.writeAPIError(w, fmt.Errorf("error deleting archived records: %w", err))
 		return
@@ -1649,7 +1795,7 @@ func (s *WebServer) apiMarketReport(w http.ResponseWriter, r *http.Request) {
 	if !readPost(w, r, form) {
 		return
 	}
-	report, err := s.mm.MarketReport(form.Host, form.BaseID, form.QuoteID)
+	report, err := s.mm(r).MarketReport(form.Host, form.BaseID, form.QuoteID)
 	if err != nil {
 		s.writeAPIError(w, fmt.Errorf("error getting market report: %w", err))
 		return
@@ -1671,7 +1817,7 @@ func (s *WebServer) apiCEXBalance(w http.ResponseWriter, r *http.Request) {
 	if !readPost(w, r, &req) {
 		return
 	}
-	bal, err := s.mm.CEXBalance(req.CEXName, req.AssetID)
+	bal, err := s.mm(r).CEXBalance(req.CEXName, req.AssetID)
 	if err != nil {
 		s.writeAPIError(w, fmt.Errorf("error getting cex balance: %w", err))
 		return
@@ -1686,7 +1832,7 @@ func (s *WebServer) apiCEXBalance(w http.ResponseWriter, r *http.Request) {
 }
 
 func (s *WebServer) apiArchivedRuns(w http.ResponseWriter, r *http.Request) {
-	runs, err := s.mm.ArchivedRuns()
+	runs, err := s.mm(r).ArchivedRuns()
 	if err != nil {
 		s.writeAPIError(w, fmt.Errorf("error getting archived runs: %w", err))
 		return
@@ -1718,7 +1864,7 @@ func (s *WebServer) apiRunLogs(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	logs, updatedLogs, overview, err := s.mm.RunLogs(req.StartTime, req.Market, req.N, req.RefID, req.Filters)
+	logs, updatedLogs, overview, err := s.mm(r).RunLogs(req.StartTime, req.Market, req.N, req.RefID, req.Filters)
 	if err != nil {
 		s.writeAPIError(w, fmt.Errorf("error getting run logs: %w", err))
 		return
@@ -1746,7 +1892,7 @@ func (s *WebServer) apiCEXBook(w http.ResponseWriter, r *http.Request) {
 	if !readPost(w, r, &req) {
 		return
 	}
-	buys, sells, err := s.mm.CEXBook(req.Host, req.BaseID, req.QuoteID)
+	buys, sells, err := s.mm(r).CEXBook(req.Host, req.BaseID, req.QuoteID)
 	if err != nil {
 		s.writeAPIError(w, fmt.Errorf("error CEX Book: %w", err))
 		return
@@ -1770,7 +1916,7 @@ func (s *WebServer) apiStakeStatus(w http.ResponseWriter, r *http.Request) {
 	if !readPost(w, r, &assetID) {
 		return
 	}
-	status, err := s.core.StakeStatus(assetID)
+	status, err := s.core(r).StakeStatus(assetID)
 	if err != nil {
 		s.writeAPIError(w, fmt.Errorf("error fetching stake status for asset ID %d: %w", assetID, err))
 		return
@@ -1792,7 +1938,7 @@ func (s *WebServer) apiSetVSP(w http.ResponseWriter, r *http.Request) {
 	if !readPost(w, r, &req) {
 		return
 	}
-	if err := s.core.SetVSP(req.AssetID, req.URL); err != nil {
+	if err := s.core(r).SetVSP(req.AssetID, req.URL); err != nil {
 		s.writeAPIError(w, fmt.Errorf("error settings vsp to %q for asset ID %d: %w", req.URL, req.AssetID, err))
 		return
 	}
@@ -1814,7 +1960,7 @@ func (s *WebServer) apiPurchaseTickets(w http.ResponseWriter, r *http.Request) {
 		s.writeAPIError(w, fmt.Errorf("password error: %w", err))
 		return
 	}
-	if err = s.core.PurchaseTickets(req.AssetID, appPW, req.N); err != nil {
+	if err = s.core(r).PurchaseTickets(req.AssetID, appPW, req.N); err != nil {
 		s.writeAPIError(w, fmt.Errorf("error purchasing tickets for asset ID %d: %w", req.AssetID, err))
 		return
 	}
@@ -1831,7 +1977,7 @@ func (s *WebServer) apiSetVotingPreferences(w http.ResponseWriter, r *http.Reque
 	if !readPost(w, r, &req) {
 		return
 	}
-	if err := s.core.SetVotingPreferences(req.AssetID, req.Choices, req.TSpendPolicy, req.TreasuryPolicy); err != nil {
+	if err := s.core(r).SetVotingPreferences(req.AssetID, req.Choices, req.TSpendPolicy, req.TreasuryPolicy); err != nil {
 		s.writeAPIError(w, fmt.Errorf("error setting voting preferences for asset ID %d: %w", req.AssetID, err))
 		return
 	}
@@ -1843,7 +1989,7 @@ func (s *WebServer) apiListVSPs(w http.ResponseWriter, r *http.Request) {
 	if !readPost(w, r, &assetID) {
 		return
 	}
-	vsps, err := s.core.ListVSPs(assetID)
+	vsps, err := s.core(r).ListVSPs(assetID)
 	if err != nil {
 		s.writeAPIError(w, fmt.Errorf("error listing VSPs for asset ID %d: %w", assetID, err))
 		return
@@ -1867,7 +2013,7 @@ func (s *WebServer) apiTicketPage(w http.ResponseWriter, r *http.Request) {
 	if !readPost(w, r, &req) {
 		return
 	}
-	tickets, err := s.core.TicketPage(req.AssetID, req.ScanStart, req.N, req.SkipN)
+	tickets, err := s.core(r).TicketPage(req.AssetID, req.ScanStart, req.N, req.SkipN)
 	if err != nil {
 		s.writeAPIError(w, fmt.Errorf("error retrieving ticket page for %d: %w", req.AssetID, err))
 		return
@@ -1888,7 +2034,7 @@ func (s *WebServer) apiMixingStats(w http.ResponseWriter, r *http.Request) {
 	if !readPost(w, r, &req) {
 		return
 	}
-	stats, err := s.core.FundsMixingStats(req.AssetID)
+	stats, err := s.core(r).FundsMixingStats(req.AssetID)
 	if err != nil {
 		s.writeAPIError(w, fmt.Errorf("error reteiving mixing stats for %d: %w", req.AssetID, err))
 		return
@@ -1916,7 +2062,7 @@ func (s *WebServer) apiConfigureMixer(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	defer zero(pass)
-	if err := s.core.ConfigureFundsMixer(pass, req.AssetID, req.Enabled); err != nil {
+	if err := s.core(r).ConfigureFundsMixer(pass, req.AssetID, req.Enabled); err != nil {
 		s.writeAPIError(w, fmt.Errorf("error configuring mixing for %d: %w", req.AssetID, err))
 		return
 	}
@@ -1943,7 +2089,7 @@ func (s *WebServer) apiStartMarketMakingBot(w http.ResponseWriter, r *http.Reque
 		s.writeAPIError(w, errors.New("config missing"))
 		return
 	}
-	if err = s.mm.StartBot(form.Config, nil, appPW, true); err != nil {
+	if err = s.mm(r).StartBot(form.Config, nil, appPW, true); err != nil {
 		s.writeAPIError(w, fmt.Errorf("error starting market making: %v", err))
 		return
 	}
@@ -1963,7 +2109,7 @@ func (s *WebServer) apiStopMarketMakingBot(w http.ResponseWriter, r *http.Reques
 		s.writeAPIError(w, errors.New("market missing"))
 		return
 	}
-	if err := s.mm.StopBot(form.Market); err != nil {
+	if err := s.mm(r).StopBot(form.Market); err != nil {
 		s.writeAPIError(w, fmt.Errorf("error stopping mm bot %q: %v", form.Market, err))
 		return
 	}
@@ -1977,7 +2123,7 @@ func (s *WebServer) apiUpdateCEXConfig(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := s.mm.UpdateCEXConfig(updatedCfg); err != nil {
+	if err := s.mm(r).UpdateCEXConfig(updatedCfg); err != nil {
 		s.writeAPIError(w, err)
 		return
 	}
@@ -1992,7 +2138,7 @@ func (s *WebServer) apiUpdateBotConfig(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := s.mm.UpdateBotConfig(updatedCfg); err != nil {
+	if err := s.mm(r).UpdateBotConfig(updatedCfg); err != nil {
 		s.writeAPIError(w, err)
 		return
 	}
@@ -2011,7 +2157,7 @@ func (s *WebServer) apiRemoveBotConfig(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := s.mm.RemoveBotConfig(form.Host, form.BaseID, form.QuoteID); err != nil {
+	if err := s.mm(r).RemoveBotConfig(form.Host, form.BaseID, form.QuoteID); err != nil {
 		s.writeAPIError(w, err)
 		return
 	}
@@ -2025,7 +2171,7 @@ func (s *WebServer) apiMarketMakingStatus(w http.ResponseWriter, r *http.Request
 		Status *mm.Status `json:"status"`
 	}{
 		OK:     true,
-		Status: s.mm.Status(),
+		Status: s.mm(r).Status(),
 	})
 }
 
@@ -2045,7 +2191,7 @@ func (s *WebServer) apiTxHistory(w http.ResponseWriter, r *http.Request) {
 		refID = &form.RefID
 	}
 
-	txs, err := s.core.TxHistory(form.AssetID, form.N, refID, form.Past)
+	txs, err := s.core(r).TxHistory(form.AssetID, form.N, refID, form.Past)
 	if err != nil {
 		s.writeAPIError(w, fmt.Errorf("error getting transaction history: %w", err))
 		return
@@ -2059,6 +2205,296 @@ func (s *WebServer) apiTxHistory(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
+// apiListUTXOs handles the 'listutxos' API request.
+func (s *WebServer) apiListUTXOs(w http.ResponseWriter, r *http.Request) {
+	var form struct {
+		AssetID uint32 `json:"assetID"`
+	}
+	if !readPost(w, r, &form) {
+		return
+	}
+	utxos, err := s.core(r).ListUTXOs(form.AssetID)
+	if err != nil {
+		s.writeAPIError(w, fmt.Errorf("error listing utxos: %w", err))
+		return
+	}
+	writeJSON(w, &struct {
+		OK    bool                `json:"ok"`
+		UTXOs []*asset.WalletUTXO `json:"utxos"`
+	}{
+		OK:    true,
+		UTXOs: utxos,
+	})
+}
+
+// apiSetUTXOLabel handles the 'setutxolabel' API request.
+func (s *WebServer) apiSetUTXOLabel(w http.ResponseWriter, r *http.Request) {
+	var form struct {
+		AssetID uint32    `json:"assetID"`
+		CoinID  dex.Bytes `json:"coinID"`
+		Label   string    `json:"label"`
+	}
+	if !readPost(w, r, &form) {
+		return
+	}
+	if err := s.core(r).SetUTXOLabel(form.AssetID, form.CoinID, form.Label); err != nil {
+		s.writeAPIError(w, fmt.Errorf("error setting utxo label: %w", err))
+		return
+	}
+	writeJSON(w, simpleAck())
+}
+
+// apiFreezeUTXOs handles the 'freezeutxos' API request.
+func (s *WebServer) apiFreezeUTXOs(w http.ResponseWriter, r *http.Request) {
+	var form struct {
+		AssetID uint32      `json:"assetID"`
+		CoinIDs []dex.Bytes `json:"coinIDs"`
+		Freeze  bool        `json:"freeze"`
+	}
+	if !readPost(w, r, &form) {
+		return
+	}
+	if err := s.core(r).FreezeUTXOs(form.AssetID, form.CoinIDs, form.Freeze); err != nil {
+		s.writeAPIError(w, fmt.Errorf("error freezing utxos: %w", err))
+		return
+	}
+	writeJSON(w, simpleAck())
+}
+
+// apiAddressBook handles the 'addressbook' API request.
+func (s *WebServer) apiAddressBook(w http.ResponseWriter, r *http.Request) {
+	entries, err := s.core(r).AddressBook()
+	if err != nil {
+		s.writeAPIError(w, fmt.Errorf("error retrieving address book: %w", err))
+		return
+	}
+	writeJSON(w, &struct {
+		OK      bool                     `json:"ok"`
+		Entries []*core.AddressBookEntry `json:"entries"`
+	}{
+		OK:      true,
+		Entries: entries,
+	})
+}
+
+// apiAddAddress handles the 'addaddress' API request.
+func (s *WebServer) apiAddAddress(w http.ResponseWriter, r *http.Request) {
+	form := &struct {
+		Pass    encode.PassBytes `json:"pw"`
+		AssetID uint32           `json:"assetID"`
+		Address string           `json:"address"`
+		Label   string           `json:"label"`
+	}{}
+	defer form.Pass.Clear()
+	if !readPost(w, r, form) {
+		return
+	}
+	entry, err := s.core(r).AddAddressBookEntry(form.Pass, form.AssetID, form.Address, form.Label)
+	if err != nil {
+		s.writeAPIError(w, fmt.Errorf("error adding address: %w", err))
+		return
+	}
+	writeJSON(w, &struct {
+		OK    bool                   `json:"ok"`
+		Entry *core.AddressBookEntry `json:"entry"`
+	}{
+		OK:    true,
+		Entry: entry,
+	})
+}
+
+// apiRemoveAddress handles the 'removeaddress' API request.
+func (s *WebServer) apiRemoveAddress(w http.ResponseWriter, r *http.Request) {
+	form := &struct {
+		Pass    encode.PassBytes `json:"pw"`
+		AssetID uint32           `json:"assetID"`
+		Address string           `json:"address"`
+	}{}
+	defer form.Pass.Clear()
+	if !readPost(w, r, form) {
+		return
+	}
+	if err := s.core(r).RemoveAddressBookEntry(form.Pass, form.AssetID, form.Address); err != nil {
+		s.writeAPIError(w, fmt.Errorf("error removing address: %w", err))
+		return
+	}
+	writeJSON(w, simpleAck())
+}
+
+// apiWithdrawalPolicies handles the 'withdrawalpolicies' API request.
+func (s *WebServer) apiWithdrawalPolicies(w http.ResponseWriter, r *http.Request) {
+	policies, err := s.core(r).WithdrawalPolicies()
+	if err != nil {
+		s.writeAPIError(w, fmt.Errorf("error retrieving withdrawal policies: %w", err))
+		return
+	}
+	writeJSON(w, &struct {
+		OK       bool                     `json:"ok"`
+		Policies []*core.WithdrawalPolicy `json:"policies"`
+	}{
+		OK:       true,
+		Policies: policies,
+	})
+}
+
+// apiSetWithdrawalPolicy handles the 'setwithdrawalpolicy' API request.
+func (s *WebServer) apiSetWithdrawalPolicy(w http.ResponseWriter, r *http.Request) {
+	form := &struct {
+		Pass          encode.PassBytes `json:"pw"`
+		AssetID       uint32           `json:"assetID"`
+		AllowlistOnly bool             `json:"allowlistOnly"`
+		DailyLimit    uint64           `json:"dailyLimit"`
+	}{}
+	defer form.Pass.Clear()
+	if !readPost(w, r, form) {
+		return
+	}
+	if err := s.core(r).SetWithdrawalPolicy(form.Pass, form.AssetID, form.AllowlistOnly, form.DailyLimit); err != nil {
+		s.writeAPIError(w, fmt.Errorf("error setting withdrawal policy: %w", err))
+		return
+	}
+	writeJSON(w, simpleAck())
+}
+
+// apiCreateConditionalOrder handles the 'createconditionalorder' API request.
+func (s *WebServer) apiCreateConditionalOrder(w http.ResponseWriter, r *http.Request) {
+	form := &struct {
+		Pass  encode.PassBytes           `json:"pw"`
+		Order *core.ConditionalOrderForm `json:"order"`
+	}{}
+	defer form.Pass.Clear()
+	if !readPost(w, r, form) {
+		return
+	}
+	if form.Order == nil {
+		s.writeAPIError(w, errors.New("no order specified"))
+		return
+	}
+	ord, err := s.core(r).CreateConditionalOrder(form.Pass, form.Order)
+	if err != nil {
+		s.writeAPIError(w, fmt.Errorf("error creating conditional order: %w", err))
+		return
+	}
+	writeJSON(w, &struct {
+		OK    bool                   `json:"ok"`
+		Order *core.ConditionalOrder `json:"order"`
+	}{
+		OK:    true,
+		Order: ord,
+	})
+}
+
+// apiConditionalOrders handles the 'conditionalorders' API request.
+func (s *WebServer) apiConditionalOrders(w http.ResponseWriter, r *http.Request) {
+	ords, err := s.core(r).ConditionalOrders()
+	if err != nil {
+		s.writeAPIError(w, fmt.Errorf("error retrieving conditional orders: %w", err))
+		return
+	}
+	writeJSON(w, &struct {
+		OK     bool                     `json:"ok"`
+		Orders []*core.ConditionalOrder `json:"orders"`
+	}{
+		OK:     true,
+		Orders: ords,
+	})
+}
+
+// apiRemoveConditionalOrder handles the 'removeconditionalorder' API request.
+func (s *WebServer) apiRemoveConditionalOrder(w http.ResponseWriter, r *http.Request) {
+	form := &struct {
+		ID string `json:"id"`
+	}{}
+	if !readPost(w, r, form) {
+		return
+	}
+	if err := s.core(r).RemoveConditionalOrder(form.ID); err != nil {
+		s.writeAPIError(w, fmt.Errorf("error removing conditional order: %w", err))
+		return
+	}
+	writeJSON(w, simpleAck())
+}
+
+// apiCreateManagedOrder handles the 'createmanagedorder' API request.
+func (s *WebServer) apiCreateManagedOrder(w http.ResponseWriter, r *http.Request) {
+	form := &struct {
+		Pass  encode.PassBytes       `json:"pw"`
+		Order *core.ManagedOrderForm `json:"order"`
+	}{}
+	defer form.Pass.Clear()
+	if !readPost(w, r, form) {
+		return
+	}
+	if form.Order == nil {
+		s.writeAPIError(w, errors.New("no order specified"))
+		return
+	}
+	ord, err := s.core(r).CreateManagedOrder(form.Pass, form.Order)
+	if err != nil {
+		s.writeAPIError(w, fmt.Errorf("error creating managed order: %w", err))
+		return
+	}
+	writeJSON(w, &struct {
+		OK    bool               `json:"ok"`
+		Order *core.ManagedOrder `json:"order"`
+	}{
+		OK:    true,
+		Order: ord,
+	})
+}
+
+// apiManagedOrders handles the 'managedorders' API request.
+func (s *WebServer) apiManagedOrders(w http.ResponseWriter, r *http.Request) {
+	ords, err := s.core(r).ManagedOrders()
+	if err != nil {
+		s.writeAPIError(w, fmt.Errorf("error retrieving managed orders: %w", err))
+		return
+	}
+	writeJSON(w, &struct {
+		OK     bool                 `json:"ok"`
+		Orders []*core.ManagedOrder `json:"orders"`
+	}{
+		OK:     true,
+		Orders: ords,
+	})
+}
+
+// apiCancelManagedOrder handles the 'cancelmanagedorder' API request.
+func (s *WebServer) apiCancelManagedOrder(w http.ResponseWriter, r *http.Request) {
+	form := &struct {
+		ID string `json:"id"`
+	}{}
+	if !readPost(w, r, form) {
+		return
+	}
+	if err := s.core(r).CancelManagedOrder(form.ID); err != nil {
+		s.writeAPIError(w, fmt.Errorf("error canceling managed order: %w", err))
+		return
+	}
+	writeJSON(w, simpleAck())
+}
+
+// apiAddMesh handles the 'addmesh' API request.
+func (s *WebServer) apiAddMesh(w http.ResponseWriter, r *http.Request) {
+	form := &struct {
+		Pass encode.PassBytes `json:"pw"`
+		Mesh *core.MeshForm   `json:"mesh"`
+	}{}
+	defer form.Pass.Clear()
+	if !readPost(w, r, form) {
+		return
+	}
+	if form.Mesh == nil {
+		s.writeAPIError(w, errors.New("no mesh specified"))
+		return
+	}
+	if err := s.core(r).AddMesh(form.Pass, form.Mesh); err != nil {
+		s.writeAPIError(w, fmt.Errorf("error adding mesh: %w", err))
+		return
+	}
+	writeJSON(w, simpleAck())
+}
+
 func (s *WebServer) apiTakeAction(w http.ResponseWriter, r *http.Request) {
 	var req struct {
 		AssetID  uint32          `json:"assetID"`
@@ -2068,7 +2504,7 @@ func (s *WebServer) apiTakeAction(w http.ResponseWriter, r *http.Request) {
 	if !readPost(w, r, &req) {
 		return
 	}
-	if err := s.core.TakeAction(req.AssetID, req.ActionID, req.Action); err != nil {
+	if err := s.core(r).TakeAction(req.AssetID, req.ActionID, req.Action); err != nil {
 		s.writeAPIError(w, fmt.Errorf("error taking action: %w", err))
 		return
 	}
@@ -2108,6 +2544,48 @@ func (s *WebServer) apiExportAppLogs(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// apiExportTaxHistory handles the 'exporttaxhistory' API request. The export
+// is specified with the query parameters format, from, to, ratesFile, and
+// currency. See core.TaxExportForm.
+func (s *WebServer) apiExportTaxHistory(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	form := &core.TaxExportForm{
+		Format:    q.Get("format"),
+		RatesFile: q.Get("ratesFile"),
+		Currency:  q.Get("currency"),
+	}
+	for _, p := range []struct {
+		name string
+		v    *uint64
+	}{{"from", &form.From}, {"to", &form.To}} {
+		if str := q.Get(p.name); str != "" {
+			v, err := strconv.ParseUint(str, 10, 64)
+			if err != nil {
+				s.writeAPIError(w, fmt.Errorf("invalid %s time %q", p.name, str))
+				return
+			}
+			*p.v = v
+		}
+	}
+
+	// Buffer the export so that errors can still be reported.
+	var buf bytes.Buffer
+	if _, err := s.core(r).ExportTaxHistory(&buf, form); err != nil {
+		s.writeAPIError(w, fmt.Errorf("error exporting tax history: %w", err))
+		return
+	}
+	format := form.Format
+	if format == "" {
+		format = core.TaxFormatGeneric
+	}
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=taxhistory_%s.csv", format))
+	w.Header().Set("Content-Type", "text/csv")
+	w.WriteHeader(http.StatusOK)
+	if _, err := buf.WriteTo(w); err != nil {
+		log.Errorf("error writing tax history: %v", err)
+	}
+}
+
 func (s *WebServer) redeemGameCode(w http.ResponseWriter, r *http.Request) {
 	var form struct {
 		Code  dex.Bytes        `json:"code"`
@@ -2123,7 +2601,7 @@ func (s *WebServer) redeemGameCode(w http.ResponseWriter, r *http.Request) {
 		s.writeAPIError(w, fmt.Errorf("password error: %w", err))
 		return
 	}
-	coinID, win, err := s.core.RedeemGeocode(appPW, form.Code, form.Msg)
+	coinID, win, err := s.core(r).RedeemGeocode(appPW, form.Code, form.Msg)
 	if err != nil {
 		s.writeAPIError(w, fmt.Errorf("redemption error: %w", err))
 		return
@@ -2198,3 +2676,66 @@ func (s *WebServer) resolvePass(appPW []byte, r *http.Request) ([]byte, error) {
 	}
 	return cachedPass, nil
 }
+
+// errNoNotifier is returned by the notifier API when outbound notifications
+// are not enabled.
+var errNoNotifier = errors.New("outbound notifications are not enabled")
+
+// apiNotifyConfig is the handler for the '/notifyconfig' API request.
+func (s *WebServer) apiNotifyConfig(w http.ResponseWriter, r *http.Request) {
+	notifier := s.notifier(r)
+	if notifier == nil {
+		s.writeAPIError(w, errNoNotifier)
+		return
+	}
+	writeJSON(w, &struct {
+		OK     bool           `json:"ok"`
+		Config *notify.Config `json:"config"`
+	}{
+		OK:     true,
+		Config: notifier.Config(),
+	})
+}
+
+// apiUpdateNotifyConfig is the handler for the '/updatenotifyconfig' API
+// request. Command sinks can only be configured in the notifier config file.
+func (s *WebServer) apiUpdateNotifyConfig(w http.ResponseWriter, r *http.Request) {
+	notifier := s.notifier(r)
+	if notifier == nil {
+		s.writeAPIError(w, errNoNotifier)
+		return
+	}
+	var cfg *notify.Config
+	if !readPost(w, r, &cfg) {
+		return
+	}
+	if cfg == nil {
+		s.writeAPIError(w, errors.New("no config"))
+		return
+	}
+	if err := notifier.UpdateConfig(cfg); err != nil {
+		s.writeAPIError(w, err)
+		return
+	}
+	writeJSON(w, simpleAck())
+}
+
+// apiTestNotifySink is the handler for the '/testnotifysink' API request.
+func (s *WebServer) apiTestNotifySink(w http.ResponseWriter, r *http.Request) {
+	notifier := s.notifier(r)
+	if notifier == nil {
+		s.writeAPIError(w, errNoNotifier)
+		return
+	}
+	var form struct {
+		SinkID string `json:"sinkID"`
+	}
+	if !readPost(w, r, &form) {
+		return
+	}
+	if err := notifier.TestSink(r.Context(), form.SinkID); err != nil {
+		s.writeAPIError(w, fmt.Errorf("test notification failed: %w", err))
+		return
+	}
+	writeJSON(w, simpleAck())
+}
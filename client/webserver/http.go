@@ -104,15 +104,15 @@ func (s *WebServer) handleRegister(w http.ResponseWriter, r *http.Request) {
 	s.sendTemplate(w, "register", &registerTmplData{
 		CommonArguments: *common,
 		Host:            host,
-		KnownExchanges:  s.knownUnregisteredExchanges(s.core.Exchanges()),
-		Initialized:     s.core.IsInitialized(),
+		KnownExchanges:  s.knownUnregisteredExchanges(r, s.core(r).Exchanges()),
+		Initialized:     s.core(r).IsInitialized(),
 	})
 }
 
 // knownUnregisteredExchanges returns all the known exchanges that
 // the user has not registered for.
-func (s *WebServer) knownUnregisteredExchanges(registeredExchanges map[string]*core.Exchange) []string {
-	certs := core.CertStore[s.core.Network()]
+func (s *WebServer) knownUnregisteredExchanges(r *http.Request, registeredExchanges map[string]*core.Exchange) []string {
+	certs := core.CertStore[s.core(r).Network()]
 	exchanges := make([]string, 0, len(certs))
 	for host := range certs {
 		xc := registeredExchanges[host]
@@ -141,7 +141,7 @@ func (s *WebServer) handleMarketMaking(w http.ResponseWriter, r *http.Request) {
 
 // handleWallets is the handler for the '/wallets' page request.
 func (s *WebServer) handleWallets(w http.ResponseWriter, r *http.Request) {
-	assetMap := s.core.SupportedAssets()
+	assetMap := s.core(r).SupportedAssets()
 	// Sort assets by 1. wallet vs no wallet, and 2) alphabetically.
 	assets := make([]*core.SupportedAsset, 0, len(assetMap))
 	// over-allocating, but assuming user will not have set up most wallets.
@@ -186,7 +186,7 @@ func (s *WebServer) handleWalletLogFile(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	logFilePath, err := s.core.WalletLogFilePath(uint32(assetID))
+	logFilePath, err := s.core(r).WalletLogFilePath(uint32(assetID))
 	if err != nil {
 		log.Errorf("failed to get log file path %v", err)
 		http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
@@ -258,7 +258,7 @@ func (s *WebServer) handleGenerateCompanionAppQRCode(w http.ResponseWriter, r *h
 	}
 	// Create auth token and append it to the URL for authTokenMiddleware to pick up.
 	// TODO save this token in the DB to make it permanent?
-	authToken := s.authorize()
+	authToken := s.authorize(s.sessionProfile(r))
 	url = fmt.Sprintf("%s?%s=%s", url, authCK, authToken)
 
 	png, err := qrcode.Encode(url, qrcode.Medium, 200)
@@ -286,7 +286,7 @@ func (s *WebServer) handleInit(w http.ResponseWriter, r *http.Request) {
 // handleSettings is the handler for the '/settings' page request.
 func (s *WebServer) handleSettings(w http.ResponseWriter, r *http.Request) {
 	common := s.commonArgs(r, "Settings | Bison Wallet")
-	xcs := s.core.Exchanges()
+	xcs := s.core(r).Exchanges()
 	data := &struct {
 		CommonArguments
 		KnownExchanges  []string
@@ -296,11 +296,11 @@ func (s *WebServer) handleSettings(w http.ResponseWriter, r *http.Request) {
 		IsInitialized   bool
 	}{
 		CommonArguments: *common,
-		KnownExchanges:  s.knownUnregisteredExchanges(xcs),
+		KnownExchanges:  s.knownUnregisteredExchanges(r, xcs),
 		FiatCurrency:    core.DefaultFiatCurrency,
-		FiatRateSources: s.core.FiatRateSources(),
+		FiatRateSources: s.core(r).FiatRateSources(),
 		Exchanges:       xcs,
-		IsInitialized:   s.core.IsInitialized(),
+		IsInitialized:   s.core(r).IsInitialized(),
 	}
 	s.sendTemplate(w, "settings", data)
 }
@@ -314,7 +314,7 @@ func (s *WebServer) handleDexSettings(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	exchange, err := s.core.Exchange(host)
+	exchange, err := s.core(r).Exchange(host)
 	if err != nil {
 		log.Errorf("error getting exchange: %v", err)
 		http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
@@ -329,7 +329,7 @@ func (s *WebServer) handleDexSettings(w http.ResponseWriter, r *http.Request) {
 	}{
 		CommonArguments: common,
 		Exchange:        exchange,
-		KnownExchanges:  s.knownUnregisteredExchanges(s.core.Exchanges()),
+		KnownExchanges:  s.knownUnregisteredExchanges(r, s.core(r).Exchanges()),
 	}
 
 	s.sendTemplate(w, "dexsettings", data)
@@ -370,7 +370,7 @@ var allStatuses = map[uint8]string{
 
 // handleOrders is the handler for the /orders page request.
 func (s *WebServer) handleOrders(w http.ResponseWriter, r *http.Request) {
-	xcs := s.core.Exchanges()
+	xcs := s.core(r).Exchanges()
 	hosts := make([]string, 0, len(xcs))
 	for _, xc := range xcs {
 		hosts = append(hosts, xc.Host)
@@ -378,7 +378,7 @@ func (s *WebServer) handleOrders(w http.ResponseWriter, r *http.Request) {
 
 	s.sendTemplate(w, "orders", &ordersTmplData{
 		CommonArguments: *s.commonArgs(r, "Orders | Bison Wallet"),
-		Assets:          s.core.SupportedAssets(),
+		Assets:          s.core(r).SupportedAssets(),
 		Hosts:           hosts,
 		Statuses:        allStatuses,
 	})
@@ -418,7 +418,7 @@ func (s *WebServer) handleExportOrders(w http.ResponseWriter, r *http.Request) {
 		filter.Statuses[k] = order.OrderStatus(statusNumID)
 	}
 
-	ords, err := s.core.Orders(filter)
+	ords, err := s.core(r).Orders(filter)
 	if err != nil {
 		log.Errorf("error retrieving order: %v", err)
 		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
@@ -462,7 +462,7 @@ func (s *WebServer) handleExportOrders(w http.ResponseWriter, r *http.Request) {
 	}
 
 	for _, ord := range ords {
-		ordReader := s.orderReader(ord)
+		ordReader := s.orderReader(r, ord)
 
 		timestamp := time.UnixMilli(int64(ord.Stamp)).Local().Format(time.RFC3339Nano)
 		err = csvWriter.Write([]string{
@@ -510,7 +510,7 @@ func (s *WebServer) handleOrder(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
 		return
 	}
-	ord, err := s.core.Order(oid)
+	ord, err := s.core(r).Order(oid)
 	if err != nil {
 		log.Errorf("error retrieving order: %v", err)
 		http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
@@ -518,7 +518,7 @@ func (s *WebServer) handleOrder(w http.ResponseWriter, r *http.Request) {
 	}
 	s.sendTemplate(w, "order", &orderTmplData{
 		CommonArguments: *s.commonArgs(r, "Order | Bison Wallet"),
-		Order:           s.orderReader(ord),
+		Order:           s.orderReader(r, ord),
 	})
 }
 
@@ -532,13 +532,13 @@ func defaultUnitInfo(symbol string) dex.UnitInfo {
 	}
 }
 
-func (s *WebServer) orderReader(ord *core.Order) *core.OrderReader {
+func (s *WebServer) orderReader(r *http.Request, ord *core.Order) *core.OrderReader {
 	unitInfo := func(assetID uint32, symbol string) dex.UnitInfo {
 		unitInfo, err := asset.UnitInfo(assetID)
 		if err == nil {
 			return unitInfo
 		}
-		xc := s.core.Exchanges()[ord.Host]
+		xc := s.core(r).Exchanges()[ord.Host]
 		a, found := xc.Assets[assetID]
 		if !found || a.UnitInfo.Conventional.ConversionFactor == 0 {
 			return defaultUnitInfo(symbol)
@@ -12,6 +12,7 @@ import (
 	"encoding/hex"
 	"encoding/json"
 	"fmt"
+	"io"
 	"math"
 	mrand "math/rand"
 	"sort"
@@ -1717,6 +1718,33 @@ func (c *TCore) SupportedAssets() map[uint32]*core.SupportedAsset {
 func (c *TCore) Send(pw []byte, assetID uint32, value uint64, address string, subtract bool) (asset.Coin, error) {
 	return &tCoin{id: []byte{0xde, 0xc7, 0xed}}, nil
 }
+func (c *TCore) SendWithCoins(pw []byte, assetID uint32, value uint64, address string, subtract bool, coinIDs []dex.Bytes) (asset.Coin, error) {
+	return &tCoin{id: []byte{0xde, 0xc7, 0xed}}, nil
+}
+func (c *TCore) ListUTXOs(assetID uint32) ([]*asset.WalletUTXO, error)              { return nil, nil }
+func (c *TCore) SetUTXOLabel(assetID uint32, coinID dex.Bytes, label string) error  { return nil }
+func (c *TCore) FreezeUTXOs(assetID uint32, coinIDs []dex.Bytes, freeze bool) error { return nil }
+func (c *TCore) AddressBook() ([]*core.AddressBookEntry, error)                     { return nil, nil }
+func (c *TCore) AddAddressBookEntry(pw []byte, assetID uint32, addr, label string) (*core.AddressBookEntry, error) {
+	return &core.AddressBookEntry{AssetID: assetID, Address: addr, Label: label}, nil
+}
+func (c *TCore) RemoveAddressBookEntry(pw []byte, assetID uint32, addr string) error { return nil }
+func (c *TCore) WithdrawalPolicies() ([]*core.WithdrawalPolicy, error)               { return nil, nil }
+func (c *TCore) SetWithdrawalPolicy(pw []byte, assetID uint32, allowlistOnly bool, dailyLimit uint64) error {
+	return nil
+}
+func (c *TCore) CreateConditionalOrder(pw []byte, form *core.ConditionalOrderForm) (*core.ConditionalOrder, error) {
+	return &core.ConditionalOrder{ConditionalOrderForm: *form}, nil
+}
+func (c *TCore) ConditionalOrders() ([]*core.ConditionalOrder, error) { return nil, nil }
+func (c *TCore) RemoveConditionalOrder(id string) error               { return nil }
+func (c *TCore) CreateManagedOrder(pw []byte, form *core.ManagedOrderForm) (*core.ManagedOrder, error) {
+	return &core.ManagedOrder{ManagedOrderForm: *form}, nil
+}
+func (c *TCore) ManagedOrders() ([]*core.ManagedOrder, error)                        { return nil, nil }
+func (c *TCore) CancelManagedOrder(id string) error                                  { return nil }
+func (c *TCore) AddMesh(pw []byte, form *core.MeshForm) error                        { return nil }
+func (c *TCore) ExportTaxHistory(w io.Writer, form *core.TaxExportForm) (int, error) { return 0, nil }
 func (c *TCore) Trade(pw []byte, form *core.TradeForm) (*core.Order, error) {
 	return c.trade(form), nil
 }
@@ -1904,6 +1932,12 @@ func (c *TCore) runRandomNotes() {
 	}
 }
 
+func (c *TCore) ExportBackup(pw []byte, files []*core.BackupFile) ([]byte, error) {
+	return nil, nil
+}
+func (c *TCore) RestoreBackup(pw, backup []byte, files []*core.BackupFile) (*core.BackupManifest, error) {
+	return &core.BackupManifest{}, nil
+}
 func (c *TCore) ExportSeed(pw []byte) (string, error) {
 	return "copper life simple hello fit manage dune curve argue gadget erosion fork theme chase broccoli", nil
 }
@@ -2601,6 +2635,10 @@ func (m *TMarketMaker) RunLogs(startTime int64, mkt *mm.MarketWithHost, n uint64
 	return events, nil, overview, nil
 }
 
+func (m *TMarketMaker) BackupFiles() []*core.BackupFile {
+	return nil
+}
+
 func (m *TMarketMaker) CEXBook(host string, baseID, quoteID uint32) (buys, sells []*core.MiniOrder, _ error) {
 	mktID := dex.BipIDSymbol(baseID) + "_" + dex.BipIDSymbol(quoteID)
 	book := m.core.book(host, mktID)
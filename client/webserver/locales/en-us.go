@@ -342,6 +342,8 @@ var EnUS = map[string]*intl.Translation{
 	"delete_all_archived_records": {T: "Leave unchecked to delete all archived records."},
 	"show_archived_date_msg":      {T: "Specify date of latest records to keep"},
 	"archived_date_tooltip":       {T: "Archived orders and matches created before your specified and date and time will be deleted from the database."},
+	"show_pruned_orders":          {T: "Show pruned orders"},
+	"pruned_orders_tooltip":       {T: "Show orders that were moved to the archive database by the order retention policy."},
 	"save_matches_to_file":        {T: "Save matches to CSV file"},
 	"save_orders_to_file":         {T: "Save orders to CSV file"},
 	"save_orders_to_file_msg":     {T: "Optional: Whether to save deleted orders to CSV file on bisonw data directory. Default is false."},
@@ -689,4 +691,9 @@ var EnUS = map[string]*intl.Translation{
 	"delete_bot":                  {T: "Delete Bot"},
 	"export_logs":                 {T: "Export Logs"},
 	"address has been used":       {T: "address has been used"},
+	"Add a Mesh":                  {T: "Add a Tatanka Mesh"},
+	"mesh_entry_node":             {T: "Entry node address"},
+	"mesh_peer_id":                {T: "Entry node peer ID"},
+	"mesh_markets":                {T: "Markets"},
+	"mesh_no_tls":                 {T: "Connect without TLS"},
 }
@@ -83,7 +83,7 @@ func extractBooleanCookie(r *http.Request, k string, defaultVal bool) bool {
 // not initialized.
 func (s *WebServer) requireInit(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		if !s.core.IsInitialized() {
+		if !s.core(r).IsInitialized() {
 			http.Redirect(w, r, initRoute, http.StatusSeeOther)
 			return
 		}
@@ -97,7 +97,7 @@ func (s *WebServer) requireInit(next http.Handler) http.Handler {
 // wallets page.
 func (s *WebServer) requireNotInit(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		if s.core.IsInitialized() {
+		if s.core(r).IsInitialized() {
 			route := loginRoute
 			if extractUserInfo(r).Authed {
 				route = walletsRoute
@@ -113,7 +113,7 @@ func (s *WebServer) requireNotInit(next http.Handler) http.Handler {
 // instead of redirecting to the register path.
 func (s *WebServer) rejectUninited(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		if !s.core.IsInitialized() {
+		if !s.core(r).IsInitialized() {
 			http.Error(w, http.StatusText(http.StatusPreconditionRequired), http.StatusPreconditionRequired)
 			return
 		}
@@ -152,7 +152,7 @@ func (s *WebServer) rejectUnauthed(next http.Handler) http.Handler {
 // register page if the user has not connected any DEX.
 func (s *WebServer) requireDEXConnection(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		if len(s.core.Exchanges()) == 0 {
+		if len(s.core(r).Exchanges()) == 0 {
 			http.Redirect(w, r, registerRoute, http.StatusSeeOther)
 			return
 		}
@@ -219,6 +219,7 @@ export const ID_CEX_BALANCES = 'CEX_BALANCES'
 export const ID_CAUSES_SELF_MATCH = 'CAUSES_SELF_MATCH'
 export const ID_CEX_NOT_CONNECTED = 'CEX_NOT_CONNECTED'
 export const ID_DELETE_BOT = 'DELETE_BOT'
+export const ID_INVALID_MESH_MARKET = 'INVALID_MESH_MARKET'
 
 let locale: Locale
 
@@ -793,7 +793,9 @@ export default class MarketsPage extends BasePage {
     const showOrderForm = async () : Promise<boolean> => {
       if (!this.assetsAreSupported().isSupported) return false // assets not supported
 
-      if (!this.market || this.market.dex.auth.effectiveTier < 1) return false// acct suspended or not registered
+      if (!this.market) return false
+      // Mesh markets have no server account to register.
+      if (!this.market.dex.mesh && this.market.dex.auth.effectiveTier < 1) return false// acct suspended or not registered
 
       const { baseAssetApprovalStatus, quoteAssetApprovalStatus } = this.tokenAssetApprovalStatuses()
       if (baseAssetApprovalStatus !== ApprovalStatus.Approved || quoteAssetApprovalStatus !== ApprovalStatus.Approved) return false
@@ -808,8 +810,8 @@ export default class MarketsPage extends BasePage {
     Doc.setVis(await showOrderForm(), page.orderForm, page.orderTypeBttns)
 
     if (this.market) {
-      const { auth: { effectiveTier, pendingStrength } } = this.market.dex
-      Doc.setVis(effectiveTier > 0 || pendingStrength > 0, page.reputationAndTradingTierBox)
+      const { auth: { effectiveTier, pendingStrength }, mesh } = this.market.dex
+      Doc.setVis(!mesh && (effectiveTier > 0 || pendingStrength > 0), page.reputationAndTradingTierBox)
     }
 
     const mmStatus = app().mmStatus
@@ -985,7 +987,10 @@ export default class MarketsPage extends BasePage {
       }
     }
 
-    if (market.dex.auth.effectiveTier >= 1) {
+    if (market.dex.mesh) {
+      showSection(undefined)
+      this.resolveOrderFormVisibility()
+    } else if (market.dex.auth.effectiveTier >= 1) {
       const toggle = async () => {
         showSection(undefined)
         this.resolveOrderFormVisibility()
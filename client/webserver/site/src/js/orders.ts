@@ -56,6 +56,7 @@ export default class OrdersPage extends BasePage {
     readFilter(page.hostFilter, 'hosts')
     readFilter(page.assetFilter, 'assets')
     readFilter(page.statusFilter, 'statuses')
+    filterState.archived = page.archivedFilter.checked = search.get('archived') === 'true'
 
     const applyButtons: HTMLElement[] = []
     const monitorFilter = (form: HTMLElement, filterKey: string) => {
@@ -82,6 +83,9 @@ export default class OrdersPage extends BasePage {
     monitorFilter(page.assetFilter, 'assets')
     monitorFilter(page.statusFilter, 'statuses')
 
+    // The archive is a separate database, so toggling it reloads immediately.
+    Doc.bind(page.archivedFilter, 'change', () => { this.submitFilter() })
+
     Doc.bind(this.main, 'scroll', () => {
       if (this.loading) return
       const belowBottom = page.ordersTable.offsetHeight - this.main.offsetHeight - this.main.scrollTop
@@ -228,6 +232,7 @@ export default class OrdersPage extends BasePage {
     filterState.hosts = parseSubFilter(page.hostFilter)
     filterState.assets = parseSubFilter(page.assetFilter).map((s: string) => parseInt(s))
     filterState.statuses = parseSubFilter(page.statusFilter).map((s: string) => parseInt(s))
+    filterState.archived = page.archivedFilter.checked
     this.setOrders(await this.fetchOrders())
   }
 
@@ -303,6 +308,7 @@ export default class OrdersPage extends BasePage {
       hosts: filterState.hosts,
       assets: filterState.assets?.map((s: any) => parseInt(s)),
       statuses: filterState.statuses?.map((s: any) => parseInt(s)),
+      archived: filterState.archived,
       n: orderBatchSize,
       offset: this.offset
     }
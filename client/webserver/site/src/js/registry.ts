@@ -65,6 +65,7 @@ export interface Exchange {
   maxScore: number
   penaltyThreshold: number
   disabled:boolean
+  mesh: boolean
 }
 
 export interface Candle {
@@ -750,6 +751,7 @@ export interface OrderFilter {
   assets?: number[]
   market?: OrderFilterMarket
   statuses?: number[]
+  archived?: boolean
 }
 
 export interface OrderPlacement {
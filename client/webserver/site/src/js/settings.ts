@@ -67,6 +67,12 @@ export default class SettingsPage extends BasePage {
       this.showForm(page.dexAddrForm)
     })
 
+    Doc.bind(page.addMesh, 'click', () => {
+      Doc.hide(page.addMeshErr)
+      this.showForm(page.addMeshForm)
+    })
+    forms.bind(page.addMeshForm, page.addMeshSubmit, () => this.submitAddMesh())
+
     this.fiatRateSources.forEach(src => {
       Doc.bind(src, 'change', async () => {
         const res = await postJSON('/api/toggleratesource', {
@@ -181,6 +187,7 @@ export default class SettingsPage extends BasePage {
     const closePopups = () => {
       Doc.hide(page.forms)
       page.exportSeedPW.value = ''
+      page.meshPW.value = ''
       page.legacySeed.textContent = ''
       page.mnemonic.textContent = ''
     }
@@ -383,6 +390,50 @@ export default class SettingsPage extends BasePage {
     this.showForm(page.authorizeSeedDisplay)
   }
 
+  /* submitAddMesh connects to a mesh through the configured entry node. */
+  async submitAddMesh () {
+    const page = this.page
+    Doc.hide(page.addMeshErr)
+    const markets: [number, number][] = []
+    for (const mkt of page.meshMarkets.value.split(',')) {
+      const name = mkt.trim().toLowerCase()
+      if (!name) continue
+      const [baseSymbol, quoteSymbol] = name.split('_')
+      const base = Object.values(app().assets).find(a => a.symbol === baseSymbol)
+      const quote = Object.values(app().assets).find(a => a.symbol === quoteSymbol)
+      if (!base || !quote) {
+        Doc.showFormError(page.addMeshErr, intl.prep(intl.ID_INVALID_MESH_MARKET, { market: name }))
+        return
+      }
+      markets.push([base.id, quote.id])
+    }
+    let cert = ''
+    const files = page.meshCertFile.files
+    if (files && files.length) {
+      const b = new TextEncoder().encode(await files[0].text())
+      cert = Array.from(b, v => v.toString(16).padStart(2, '0')).join('')
+    }
+    const req = {
+      pw: page.meshPW.value,
+      mesh: {
+        addr: page.meshAddr.value.trim(),
+        peerID: page.meshPeerID.value.trim(),
+        cert: cert,
+        noTLS: page.meshNoTLS.checked,
+        markets: markets
+      }
+    }
+    page.meshPW.value = ''
+    const loaded = app().loading(page.addMeshForm)
+    const res = await postJSON('/api/addmesh', req)
+    loaded()
+    if (!app().checkResponse(res)) {
+      Doc.showFormError(page.addMeshErr, res.msg)
+      return
+    }
+    window.location.reload()
+  }
+
   /* showForm shows a modal form with a little animation. */
   async showForm (form: HTMLElement) {
     const page = this.page
@@ -27,6 +27,9 @@ func simpleAck() *standardResponse {
 // The loginForm is sent by the client to log in to a DEX.
 type loginForm struct {
 	Pass encode.PassBytes `json:"pass"`
+	// Profile is the name of the profile to log in to. If empty, the session's
+	// current profile is used.
+	Profile string `json:"profile,omitempty"`
 }
 
 // addDexForm is used to connect a DEX without creating an account.
@@ -120,6 +123,8 @@ type sendForm struct {
 	Address  string           `json:"address"`
 	Subtract bool             `json:"subtract"`
 	Pass     encode.PassBytes `json:"pw"`
+	// Coins, if non-empty, are the only coins that may be spent.
+	Coins []dex.Bytes `json:"coins"`
 }
 
 type accountExportForm struct {
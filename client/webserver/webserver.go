@@ -22,6 +22,7 @@ import (
 	"path"
 	"path/filepath"
 	"runtime"
+	"sort"
 	"strconv"
 	"strings"
 	"sync"
@@ -33,6 +34,8 @@ import (
 	"decred.org/dcrdex/client/db"
 	"decred.org/dcrdex/client/mm"
 	"decred.org/dcrdex/client/mm/libxc"
+	"decred.org/dcrdex/client/notify"
+	"decred.org/dcrdex/client/orderbook"
 	"decred.org/dcrdex/client/tor"
 	"decred.org/dcrdex/client/webserver/locales"
 	"decred.org/dcrdex/client/websocket"
@@ -76,6 +79,9 @@ var (
 	// errNoCachedPW is returned when attempting to retrieve a cached password, but the
 	// cookie that should contain the cached password is not populated.
 	errNoCachedPW = errors.New("no cached password")
+	// errNoPassword is returned when the app password is required, and a
+	// cached password cannot be used.
+	errNoPassword = errors.New("app password required")
 )
 
 var (
@@ -124,6 +130,23 @@ type clientCore interface {
 	DiscoverAccount(dexAddr string, pass []byte, certI any) (*core.Exchange, bool, error)
 	SupportedAssets() map[uint32]*core.SupportedAsset
 	Send(pw []byte, assetID uint32, value uint64, address string, subtract bool) (asset.Coin, error)
+	SendWithCoins(pw []byte, assetID uint32, value uint64, address string, subtract bool, coinIDs []dex.Bytes) (asset.Coin, error)
+	ListUTXOs(assetID uint32) ([]*asset.WalletUTXO, error)
+	SetUTXOLabel(assetID uint32, coinID dex.Bytes, label string) error
+	FreezeUTXOs(assetID uint32, coinIDs []dex.Bytes, freeze bool) error
+	AddressBook() ([]*core.AddressBookEntry, error)
+	AddAddressBookEntry(pw []byte, assetID uint32, addr, label string) (*core.AddressBookEntry, error)
+	RemoveAddressBookEntry(pw []byte, assetID uint32, addr string) error
+	WithdrawalPolicies() ([]*core.WithdrawalPolicy, error)
+	SetWithdrawalPolicy(pw []byte, assetID uint32, allowlistOnly bool, dailyLimit uint64) error
+	CreateConditionalOrder(pw []byte, form *core.ConditionalOrderForm) (*core.ConditionalOrder, error)
+	ConditionalOrders() ([]*core.ConditionalOrder, error)
+	RemoveConditionalOrder(id string) error
+	CreateManagedOrder(pw []byte, form *core.ManagedOrderForm) (*core.ManagedOrder, error)
+	ManagedOrders() ([]*core.ManagedOrder, error)
+	CancelManagedOrder(id string) error
+	AddMesh(pw []byte, form *core.MeshForm) error
+	ExportTaxHistory(w io.Writer, form *core.TaxExportForm) (int, error)
 	Trade(pw []byte, form *core.TradeForm) (*core.Order, error)
 	TradeAsync(pw []byte, form *core.TradeForm) (*core.InFlightOrder, error)
 	Cancel(oid dex.Bytes) error
@@ -138,6 +161,8 @@ type clientCore interface {
 	ToggleAccountStatus(pw []byte, host string, disable bool) error
 	IsInitialized() bool
 	ExportSeed(pw []byte) (string, error)
+	ExportBackup(pw []byte, files []*core.BackupFile) ([]byte, error)
+	RestoreBackup(pw, backup []byte, files []*core.BackupFile) (*core.BackupManifest, error)
 	PreOrder(*core.TradeForm) (*core.OrderEstimate, error)
 	WalletLogFilePath(assetID uint32) (string, error)
 	BondsFeeBuffer(assetID uint32) (uint64, error)
@@ -188,6 +213,14 @@ type MMCore interface {
 	RunOverview(startTime int64, mkt *mm.MarketWithHost) (*mm.MarketMakingRunOverview, error)
 	RunLogs(startTime int64, mkt *mm.MarketWithHost, n uint64, refID *uint64, filter *mm.RunLogFilters) (events, updatedEvents []*mm.MarketMakingEvent, overview *mm.MarketMakingRunOverview, err error)
 	CEXBook(host string, baseID, quoteID uint32) (buys, sells []*core.MiniOrder, _ error)
+	BackupFiles() []*core.BackupFile
+}
+
+// NotifierCore is the outbound notification sink manager.
+type NotifierCore interface {
+	Config() *notify.Config
+	UpdateConfig(cfg *notify.Config) error
+	TestSink(ctx context.Context, sinkID string) error
 }
 
 // genCertPair generates a key/cert pair to the paths provided.
@@ -226,8 +259,9 @@ type cachedPassword struct {
 
 type Config struct {
 	DataDir       string
-	Core          clientCore // *core.Core
-	MarketMaker   MMCore     // *mm.MarketMaker
+	Core          clientCore   // *core.Core
+	MarketMaker   MMCore       // *mm.MarketMaker
+	Notifier      NotifierCore // *notify.Notifier
 	Addr          string
 	CustomSiteDir string
 	Language      string
@@ -246,8 +280,24 @@ type Config struct {
 	HttpProf        bool
 	Tor             bool
 	MainLogFilePath string
+	// Profiles are additional isolated profiles, keyed by name, that the user
+	// can switch to. The Core, MarketMaker and Notifier fields above are
+	// the default profile.
+	Profiles map[string]*Profile
+}
+
+// Profile is a Bison Wallet profile with its own database, app password,
+// seed and notification sinks.
+type Profile struct {
+	Core        clientCore   // *core.Core
+	MarketMaker MMCore       // *mm.MarketMaker
+	Notifier    NotifierCore // *notify.Notifier
 }
 
+// DefaultProfile is the name of the profile created from Config.Core and
+// Config.MarketMaker.
+const DefaultProfile = "default"
+
 type valStamp struct {
 	val   uint64
 	stamp time.Time
@@ -256,24 +306,22 @@ type valStamp struct {
 // WebServer is a single-client http and websocket server enabling a browser
 // interface to Bison Wallet.
 type WebServer struct {
-	ctx      context.Context
-	dataDir  string
-	wsServer *websocket.Server
-	mux      *chi.Mux
-	siteDir  string
-	lang     atomic.Value // string
-	langs    []string
-	core     clientCore
-	mm       MMCore
-	addr     string
-	csp      string
-	srv      *http.Server
-	html     atomic.Value // *templates
-	tor      bool
-	onion    string
-
-	authMtx         sync.RWMutex
-	authTokens      map[string]bool
+	ctx     context.Context
+	dataDir string
+	mux     *chi.Mux
+	siteDir string
+	lang    atomic.Value // string
+	langs   []string
+	addr    string
+	csp     string
+	srv     *http.Server
+	html    atomic.Value // *templates
+	tor     bool
+	onion   string
+
+	authMtx sync.RWMutex
+	// authTokens maps each auth token to the name of the session's profile.
+	authTokens      map[string]string
 	cachedPasswords map[string]*cachedPassword // cached passwords keyed by auth token
 
 	bondBufMtx sync.Mutex
@@ -283,6 +331,13 @@ type WebServer struct {
 
 	useDEXBranding  bool
 	mainLogFilePath string
+
+	// profiles and wsServers are keyed by profile name, and are not modified
+	// after construction. Each profile has its own websocket server, so that
+	// websocket clients only receive the notifications of their session's
+	// profile.
+	profiles  map[string]*Profile
+	wsServers map[string]*websocket.Server
 }
 
 // New is the constructor for a new WebServer. CustomSiteDir in the Config can
@@ -396,21 +451,35 @@ func New(cfg *Config) (*WebServer, error) {
 	// Make the server here so its methods can be registered.
 	s := &WebServer{
 		langs:           langs,
-		core:            cfg.Core,
-		mm:              cfg.MarketMaker,
 		siteDir:         siteDir,
 		mux:             mux,
 		srv:             httpServer,
 		addr:            cfg.Addr,
 		dataDir:         cfg.DataDir,
-		wsServer:        websocket.New(cfg.Core, log.SubLogger("WS")),
-		authTokens:      make(map[string]bool),
+		authTokens:      make(map[string]string),
 		cachedPasswords: make(map[string]*cachedPassword),
 		tor:             cfg.Tor,
 		bondBuf:         map[uint32]valStamp{},
 		appVersion:      cfg.AppVersion,
 		useDEXBranding:  useDEXBranding,
 		mainLogFilePath: cfg.MainLogFilePath,
+		profiles: map[string]*Profile{
+			DefaultProfile: {Core: cfg.Core, MarketMaker: cfg.MarketMaker, Notifier: cfg.Notifier},
+		},
+		wsServers: make(map[string]*websocket.Server, len(cfg.Profiles)+1),
+	}
+	for name, p := range cfg.Profiles {
+		if _, found := s.profiles[name]; found {
+			return nil, fmt.Errorf("duplicate profile %q", name)
+		}
+		s.profiles[name] = p
+	}
+	for name, p := range s.profiles {
+		wsLog := log.SubLogger("WS")
+		if name != DefaultProfile {
+			wsLog = wsLog.SubLogger(name)
+		}
+		s.wsServers[name] = websocket.New(&wsCore{p.Core}, wsLog)
 	}
 	s.lang.Store(lang)
 
@@ -516,9 +585,11 @@ func New(cfg *Config) (*WebServer, error) {
 		r.Post("/init", s.apiInit)
 		r.Get("/isinitialized", s.apiIsInitialized)
 		r.Post("/resetapppassword", s.apiResetAppPassword)
+		r.Post("/restorebackup", s.apiRestoreBackup)
 		r.Get("/user", s.apiUser)
 		r.Post("/locale", s.apiLocale)
 		r.Post("/setlocale", s.apiSetLocale)
+		r.Get("/profiles", s.apiProfiles)
 
 		r.Group(func(apiInit chi.Router) {
 			apiInit.Use(s.rejectUninited)
@@ -532,6 +603,7 @@ func New(cfg *Config) (*WebServer, error) {
 		r.Group(func(apiAuth chi.Router) {
 			apiAuth.Use(s.rejectUnauthed)
 			apiAuth.Get("/notes", s.apiNotes)
+			apiAuth.Post("/switchprofile", s.apiSwitchProfile)
 			apiAuth.Post("/defaultwalletcfg", s.apiDefaultWalletCfg)
 			apiAuth.Post("/postbond", s.apiPostBond)
 			apiAuth.Post("/updatebondoptions", s.apiUpdateBondOptions)
@@ -562,6 +634,7 @@ func New(cfg *Config) (*WebServer, error) {
 			apiAuth.Post("/preorder", s.apiPreOrder)
 			apiAuth.Post("/exportaccount", s.apiAccountExport)
 			apiAuth.Post("/exportseed", s.apiExportSeed)
+			apiAuth.Post("/exportbackup", s.apiExportBackup)
 			apiAuth.Post("/importaccount", s.apiAccountImport)
 			apiAuth.Post("/toggleaccountstatus", s.apiToggleAccountStatus)
 			apiAuth.Post("/accelerateorder", s.apiAccelerateOrder)
@@ -581,9 +654,25 @@ func New(cfg *Config) (*WebServer, error) {
 			apiAuth.Post("/unapprovetoken", s.apiUnapproveToken)
 			apiAuth.Post("/approvetokenfee", s.apiApproveTokenFee)
 			apiAuth.Post("/txhistory", s.apiTxHistory)
+			apiAuth.Post("/listutxos", s.apiListUTXOs)
+			apiAuth.Post("/setutxolabel", s.apiSetUTXOLabel)
+			apiAuth.Post("/freezeutxos", s.apiFreezeUTXOs)
+			apiAuth.Post("/addressbook", s.apiAddressBook)
+			apiAuth.Post("/addaddress", s.apiAddAddress)
+			apiAuth.Post("/removeaddress", s.apiRemoveAddress)
+			apiAuth.Post("/withdrawalpolicies", s.apiWithdrawalPolicies)
+			apiAuth.Post("/setwithdrawalpolicy", s.apiSetWithdrawalPolicy)
+			apiAuth.Post("/createconditionalorder", s.apiCreateConditionalOrder)
+			apiAuth.Post("/conditionalorders", s.apiConditionalOrders)
+			apiAuth.Post("/removeconditionalorder", s.apiRemoveConditionalOrder)
+			apiAuth.Post("/createmanagedorder", s.apiCreateManagedOrder)
+			apiAuth.Post("/managedorders", s.apiManagedOrders)
+			apiAuth.Post("/cancelmanagedorder", s.apiCancelManagedOrder)
+			apiAuth.Post("/addmesh", s.apiAddMesh)
 			apiAuth.Post("/takeaction", s.apiTakeAction)
 			apiAuth.Post("/redeemgamecode", s.redeemGameCode)
 			apiAuth.Get("/exportapplog", s.apiExportAppLogs)
+			apiAuth.Get("/exporttaxhistory", s.apiExportTaxHistory)
 
 			apiAuth.Post("/stakestatus", s.apiStakeStatus)
 			apiAuth.Post("/setvsp", s.apiSetVSP)
@@ -606,6 +695,10 @@ func New(cfg *Config) (*WebServer, error) {
 			apiAuth.Get("/archivedmmruns", s.apiArchivedRuns)
 			apiAuth.Post("/mmrunlogs", s.apiRunLogs)
 			apiAuth.Post("/cexbook", s.apiCEXBook)
+
+			apiAuth.Get("/notifyconfig", s.apiNotifyConfig)
+			apiAuth.Post("/updatenotifyconfig", s.apiUpdateNotifyConfig)
+			apiAuth.Post("/testnotifysink", s.apiTestNotifySink)
 		})
 	})
 
@@ -757,13 +850,16 @@ func (s *WebServer) Connect(ctx context.Context) (*sync.WaitGroup, error) {
 		if err != nil {
 			log.Errorf("Problem shutting down rpc: %v", err)
 		}
-		s.wsServer.Shutdown()
+		for _, wsServer := range s.wsServers {
+			wsServer.Shutdown()
+		}
 		log.Infof("Web server off")
 	}()
 
-	// Configure the websocket handler before starting the server.
+	// Configure the websocket handler before starting the server. The
+	// connection is handled by the websocket server of the session's profile.
 	s.mux.Get("/ws", func(w http.ResponseWriter, r *http.Request) {
-		s.wsServer.HandleConnect(ctx, w, r)
+		s.wsServers[s.sessionProfile(r)].HandleConnect(ctx, w, r)
 	})
 
 	for _, listener := range listeners {
@@ -812,25 +908,39 @@ func prepareAddr(addr net.Addr) (string, bool) {
 	return addr.String(), false
 }
 
-// authorize creates, stores, and returns a new auth token to identify the user.
-// deauth should be used to invalidate tokens on logout.
-func (s *WebServer) authorize() string {
+// authorize creates, stores, and returns a new auth token to identify the user
+// of the named profile. deauth should be used to invalidate tokens on logout.
+func (s *WebServer) authorize(profile string) string {
 	b := make([]byte, 32)
 	crand.Read(b)
 	token := hex.EncodeToString(b)
 	zero(b)
 	s.authMtx.Lock()
-	s.authTokens[token] = true
+	s.authTokens[token] = profile
 	s.authMtx.Unlock()
 	return token
 }
 
-// deauth invalidates all current auth tokens. All existing sessions will need
-// to login again.
-func (s *WebServer) deauth() {
+// deauth invalidates the auth tokens and cached passwords of all sessions of
+// the named profile. Those sessions will need to login again. Sessions of
+// other profiles are not affected.
+func (s *WebServer) deauth(profile string) {
 	s.authMtx.Lock()
-	s.authTokens = make(map[string]bool)
-	s.cachedPasswords = make(map[string]*cachedPassword)
+	for token, p := range s.authTokens {
+		if p == profile {
+			delete(s.authTokens, token)
+			delete(s.cachedPasswords, token)
+		}
+	}
+	s.authMtx.Unlock()
+}
+
+// endSession invalidates the auth token and cached password of a single
+// session.
+func (s *WebServer) endSession(authToken string) {
+	s.authMtx.Lock()
+	delete(s.authTokens, authToken)
+	delete(s.cachedPasswords, authToken)
 	s.authMtx.Unlock()
 }
 
@@ -878,7 +988,8 @@ func (s *WebServer) isAuthed(r *http.Request) bool {
 	}
 	s.authMtx.RLock()
 	defer s.authMtx.RUnlock()
-	return s.authTokens[authToken]
+	_, found := s.authTokens[authToken]
+	return found
 }
 
 // getCachedPassword retrieves the cached password for the user identified by authToken and
@@ -949,20 +1060,82 @@ func (s *WebServer) isPasswordCached(r *http.Request) bool {
 	return err == nil
 }
 
-// readNotifications reads from the Core notification channel and relays to
-// websocket clients.
+// readNotifications reads from the Core notification channel of each profile
+// and relays the notifications to the websocket clients of that profile.
 func (s *WebServer) readNotifications(ctx context.Context) {
-	ch := s.core.NotificationFeed()
-	defer ch.ReturnFeed()
-
-	for {
-		select {
-		case n := <-ch.C:
-			s.wsServer.Notify(notifyRoute, n)
-		case <-ctx.Done():
-			return
-		}
+	var wg sync.WaitGroup
+	for name, p := range s.profiles {
+		wg.Add(1)
+		go func(c clientCore, wsServer *websocket.Server) {
+			defer wg.Done()
+			ch := c.NotificationFeed()
+			defer ch.ReturnFeed()
+			for {
+				select {
+				case n := <-ch.C:
+					wsServer.Notify(notifyRoute, n)
+				case <-ctx.Done():
+					return
+				}
+			}
+		}(p.Core, s.wsServers[name])
+	}
+	wg.Wait()
+}
+
+// sessionProfile is the name of the profile of the request's session. Requests
+// without a valid auth token are for the default profile.
+func (s *WebServer) sessionProfile(r *http.Request) string {
+	authToken := getAuthToken(r)
+	if authToken == "" {
+		return DefaultProfile
+	}
+	s.authMtx.RLock()
+	defer s.authMtx.RUnlock()
+	if name, found := s.authTokens[authToken]; found {
+		return name
+	}
+	return DefaultProfile
+}
+
+// core is the Core of the request's profile.
+func (s *WebServer) core(r *http.Request) clientCore {
+	return s.profiles[s.sessionProfile(r)].Core
+}
+
+// mm is the MarketMaker of the request's profile. mm is nil if the profile
+// does not have a MarketMaker.
+func (s *WebServer) mm(r *http.Request) MMCore {
+	return s.profiles[s.sessionProfile(r)].MarketMaker
+}
+
+// notifier is the Notifier of the request's profile. notifier is nil if
+// outbound notifications are not enabled for the profile.
+func (s *WebServer) notifier(r *http.Request) NotifierCore {
+	return s.profiles[s.sessionProfile(r)].Notifier
+}
+
+// profileNames is a sorted list of the profile names.
+func (s *WebServer) profileNames() []string {
+	names := make([]string, 0, len(s.profiles))
+	for name := range s.profiles {
+		names = append(names, name)
 	}
+	sort.Strings(names)
+	return names
+}
+
+// wsCore satisfies websocket.Core with the Core of a profile.
+type wsCore struct {
+	c clientCore
+}
+
+func (c *wsCore) SyncBook(host string, base, quote uint32) (*orderbook.OrderBook, core.BookFeed, error) {
+	return c.c.SyncBook(host, base, quote)
+}
+
+func (c *wsCore) AckNotes(ids []dex.Bytes) {
+	c.c.AckNotes(ids)
 }
 
 // readPost unmarshals the request body into the provided interface.
@@ -184,6 +184,33 @@ func (c *TCore) SupportedAssets() map[uint32]*core.SupportedAsset {
 func (c *TCore) Send(pw []byte, assetID uint32, value uint64, address string, subtract bool) (asset.Coin, error) {
 	return &tCoin{id: []byte{0xde, 0xc7, 0xed}}, c.sendErr
 }
+func (c *TCore) SendWithCoins(pw []byte, assetID uint32, value uint64, address string, subtract bool, coinIDs []dex.Bytes) (asset.Coin, error) {
+	return &tCoin{id: []byte{0xde, 0xc7, 0xed}}, c.sendErr
+}
+func (c *TCore) ListUTXOs(assetID uint32) ([]*asset.WalletUTXO, error)              { return nil, nil }
+func (c *TCore) SetUTXOLabel(assetID uint32, coinID dex.Bytes, label string) error  { return nil }
+func (c *TCore) FreezeUTXOs(assetID uint32, coinIDs []dex.Bytes, freeze bool) error { return nil }
+func (c *TCore) AddressBook() ([]*core.AddressBookEntry, error)                     { return nil, nil }
+func (c *TCore) AddAddressBookEntry(pw []byte, assetID uint32, addr, label string) (*core.AddressBookEntry, error) {
+	return &core.AddressBookEntry{AssetID: assetID, Address: addr, Label: label}, nil
+}
+func (c *TCore) RemoveAddressBookEntry(pw []byte, assetID uint32, addr string) error { return nil }
+func (c *TCore) WithdrawalPolicies() ([]*core.WithdrawalPolicy, error)               { return nil, nil }
+func (c *TCore) SetWithdrawalPolicy(pw []byte, assetID uint32, allowlistOnly bool, dailyLimit uint64) error {
+	return nil
+}
+func (c *TCore) CreateConditionalOrder(pw []byte, form *core.ConditionalOrderForm) (*core.ConditionalOrder, error) {
+	return &core.ConditionalOrder{ConditionalOrderForm: *form}, nil
+}
+func (c *TCore) ConditionalOrders() ([]*core.ConditionalOrder, error) { return nil, nil }
+func (c *TCore) RemoveConditionalOrder(id string) error               { return nil }
+func (c *TCore) CreateManagedOrder(pw []byte, form *core.ManagedOrderForm) (*core.ManagedOrder, error) {
+	return &core.ManagedOrder{ManagedOrderForm: *form}, nil
+}
+func (c *TCore) ManagedOrders() ([]*core.ManagedOrder, error)                        { return nil, nil }
+func (c *TCore) CancelManagedOrder(id string) error                                  { return nil }
+func (c *TCore) AddMesh(pw []byte, form *core.MeshForm) error                        { return nil }
+func (c *TCore) ExportTaxHistory(w io.Writer, form *core.TaxExportForm) (int, error) { return 0, nil }
 func (c *TCore) ValidateAddress(address string, assetID uint32) (bool, error) {
 	return c.validAddr, nil
 }
@@ -249,6 +276,12 @@ func (c *TCore) AccountImport(pw []byte, account *core.Account, bonds []*db.Bond
 }
 func (c *TCore) ToggleAccountStatus(pw []byte, host string, disable bool) error { return nil }
 
+func (c *TCore) ExportBackup(pw []byte, files []*core.BackupFile) ([]byte, error) {
+	return nil, nil
+}
+func (c *TCore) RestoreBackup(pw, backup []byte, files []*core.BackupFile) (*core.BackupManifest, error) {
+	return &core.BackupManifest{}, nil
+}
 func (c *TCore) ExportSeed(pw []byte) (string, error) {
 	return "seed words here", nil
 }
@@ -675,6 +708,102 @@ func TestAPILogout(t *testing.T) {
 	tCore.logoutErr = nil
 }
 
+func TestProfiles(t *testing.T) {
+	writer := new(TWriter)
+	reader := new(TReader)
+	tCore, altCore := &TCore{}, &TCore{loginErr: tErr}
+	s, err := New(&Config{
+		Core:     tCore,
+		Addr:     "127.0.0.1:0",
+		Logger:   tLogger,
+		Profiles: map[string]*Profile{"alt": {Core: altCore}},
+	})
+	if err != nil {
+		t.Fatalf("error creating server: %v", err)
+	}
+
+	ensure := func(f func(w http.ResponseWriter, r *http.Request), want string, body any, authToken string) {
+		t.Helper()
+		var cookies map[string]string
+		if authToken != "" {
+			cookies = map[string]string{authCK: authToken}
+		}
+		ensureResponse(t, f, want, reader, writer, body, cookies)
+	}
+	sessionsOf := func(profile string) (tokens []string) {
+		s.authMtx.RLock()
+		defer s.authMtx.RUnlock()
+		for token, p := range s.authTokens {
+			if p == profile {
+				tokens = append(tokens, token)
+			}
+		}
+		return tokens
+	}
+
+	ensure(s.apiProfiles, `{"ok":true,"profiles":["alt","default"],"active":"default"}`, nil, "")
+	token, otherToken := s.authorize(DefaultProfile), s.authorize(DefaultProfile)
+	req, _ := http.NewRequest("GET", "/", nil)
+	req.AddCookie(&http.Cookie{Name: authCK, Value: token})
+	if s.core(req) != tCore {
+		t.Fatalf("default profile not used")
+	}
+
+	// The password of the new profile is required.
+	ensure(s.apiSwitchProfile, `{"ok":false,"msg":"app password required"}`,
+		map[string]string{"profile": "alt"}, token)
+	ensure(s.apiSwitchProfile, fmt.Sprintf(`{"ok":false,"msg":"%s"}`, tErr),
+		map[string]any{"profile": "alt", "pass": encode.PassBytes("abc")}, token)
+	ensure(s.apiSwitchProfile, `{"ok":false,"msg":"unknown profile \"other\""}`,
+		map[string]any{"profile": "other", "pass": encode.PassBytes("abc")}, token)
+	if len(sessionsOf(DefaultProfile)) != 2 || len(sessionsOf("alt")) != 0 {
+		t.Fatalf("sessions changed after failed switches")
+	}
+
+	// Only the switching session moves to the new profile.
+	altCore.loginErr = nil
+	ensure(s.apiSwitchProfile, `{"ok":true}`, map[string]any{"profile": "alt", "pass": encode.PassBytes("abc")}, token)
+	if defTokens := sessionsOf(DefaultProfile); len(defTokens) != 1 || defTokens[0] != otherToken {
+		t.Fatalf("other session not kept")
+	}
+	altTokens := sessionsOf("alt")
+	if len(altTokens) != 1 {
+		t.Fatalf("no alt session")
+	}
+	altToken := altTokens[0]
+	ensure(s.apiProfiles, `{"ok":true,"profiles":["alt","default"],"active":"alt"}`, nil, altToken)
+	ensure(s.apiProfiles, `{"ok":true,"profiles":["alt","default"],"active":"default"}`, nil, otherToken)
+	req, _ = http.NewRequest("GET", "/", nil)
+	req.AddCookie(&http.Cookie{Name: authCK, Value: altToken})
+	if s.core(req) != altCore {
+		t.Fatalf("alt profile not used")
+	}
+
+	// Logging out of a profile only ends the sessions of that profile.
+	ensure(s.apiLogout, `{"ok":true}`, nil, altToken)
+	if len(sessionsOf("alt")) != 0 || len(sessionsOf(DefaultProfile)) != 1 {
+		t.Fatalf("wrong sessions after logout")
+	}
+
+	// A new session can log in to a profile directly.
+	ensure(s.apiLogin, `{"ok":true,"notes":null,"pokes":[]}`,
+		&loginForm{Pass: encode.PassBytes("abc"), Profile: "alt"}, "")
+	if len(sessionsOf("alt")) != 1 {
+		t.Fatalf("login to alt profile failed")
+	}
+
+	// The default profile name is reserved.
+	_, err = New(&Config{
+		Core:     tCore,
+		Addr:     "127.0.0.1:0",
+		Logger:   tLogger,
+		Profiles: map[string]*Profile{DefaultProfile: {Core: altCore}},
+	})
+	if err == nil {
+		t.Fatalf("no error for duplicate default profile")
+	}
+}
+
 func TestApiGetBalance(t *testing.T) {
 	writer := new(TWriter)
 	reader := new(TReader)
@@ -765,8 +894,8 @@ func TestPasswordCache(t *testing.T) {
 	defer shutdown()
 
 	password := encode.PassBytes("def")
-	authToken1 := s.authorize()
-	authToken2 := s.authorize()
+	authToken1 := s.authorize(DefaultProfile)
+	authToken2 := s.authorize(DefaultProfile)
 
 	key1, err := s.cacheAppPassword(password, authToken1)
 	if err != nil {
@@ -810,7 +939,9 @@ func TestPasswordCache(t *testing.T) {
 		pwKeyCK: hex.EncodeToString(key1),
 	})
 
-	s.apiLogout(writer, nil)
+	req, _ := http.NewRequest("GET", "/", nil)
+	req.AddCookie(&http.Cookie{Name: authCK, Value: authToken1})
+	s.apiLogout(writer, req)
 
 	if len(s.cachedPasswords) != 0 {
 		t.Fatal("logout should clear all cached passwords")
@@ -0,0 +1,159 @@
+// This code is available on the terms of the project LICENSE.md file,
+// also available online at https://blueoakcouncil.org/license/1.0.0.
+
+package websocket
+
+import (
+	"sort"
+
+	"decred.org/dcrdex/dex/msgjson"
+)
+
+// Subscription types. Clients that subscribe receive notifications only for
+// their subscribed types, with the subscription type as the notification
+// route. See NotifySubscribers.
+const (
+	SubOrders        = "orders"
+	SubBalances      = "balances"
+	SubNotifications = "notifications"
+	SubMMStats       = "mmstats"
+	// SubBook subscribes to the order book of a single market. Book updates
+	// are sent with the routes used by the 'loadmarket' route, and the book
+	// subscription replaces any market loaded with 'loadmarket'.
+	SubBook = "book"
+)
+
+var subTypes = map[string]bool{
+	SubOrders:        true,
+	SubBalances:      true,
+	SubNotifications: true,
+	SubMMStats:       true,
+	SubBook:          true,
+}
+
+// subscription is a typed subscription requested with the 'subscribe' and
+// 'unsubscribe' routes. Market is required for SubBook subscriptions.
+type subscription struct {
+	Type   string      `json:"type"`
+	Market *marketLoad `json:"market,omitempty"`
+}
+
+// subscriptionsResult is the result of 'subscribe' and 'unsubscribe' requests,
+// with the client's active subscription types.
+type subscriptionsResult struct {
+	Subscriptions []string `json:"subscriptions"`
+}
+
+func (cl *wsClient) subscribed(subType string) bool {
+	cl.subsMtx.RLock()
+	defer cl.subsMtx.RUnlock()
+	return cl.subs[subType]
+}
+
+// respondSubscriptions sends the client's active subscriptions in response to
+// the request.
+func (cl *wsClient) respondSubscriptions(id uint64) *msgjson.Error {
+	cl.subsMtx.RLock()
+	res := &subscriptionsResult{Subscriptions: make([]string, 0, len(cl.subs))}
+	for subType := range cl.subs {
+		res.Subscriptions = append(res.Subscriptions, subType)
+	}
+	cl.subsMtx.RUnlock()
+	sort.Strings(res.Subscriptions)
+	resp, err := msgjson.NewResponse(id, res, nil)
+	if err != nil {
+		return msgjson.NewError(msgjson.RPCInternal, "error encoding response: %v", err)
+	}
+	if err := cl.Send(resp); err != nil {
+		cl.Disconnect()
+	}
+	return nil
+}
+
+func parseSubscriptions(msg *msgjson.Message) ([]*subscription, *msgjson.Error) {
+	var subs []*subscription
+	if err := msg.Unmarshal(&subs); err != nil {
+		return nil, msgjson.NewError(msgjson.RPCParseError, "error unmarshalling subscriptions: %v", err)
+	}
+	for _, sub := range subs {
+		if !subTypes[sub.Type] {
+			return nil, msgjson.NewError(msgjson.RPCArgumentsError, "unknown subscription type %q", sub.Type)
+		}
+	}
+	return subs, nil
+}
+
+// wsSubscribe is the handler for the 'subscribe' websocket route. The payload
+// is a list of subscriptions.
+func wsSubscribe(s *Server, cl *wsClient, msg *msgjson.Message) *msgjson.Error {
+	subs, msgErr := parseSubscriptions(msg)
+	if msgErr != nil {
+		return msgErr
+	}
+	for _, sub := range subs {
+		if sub.Type != SubBook {
+			continue
+		}
+		if sub.Market == nil {
+			return msgjson.NewError(msgjson.RPCArgumentsError, "no market for book subscription")
+		}
+		if _, msgErr := loadMarket(s, cl, sub.Market); msgErr != nil {
+			return msgErr
+		}
+	}
+	cl.subsMtx.Lock()
+	for _, sub := range subs {
+		cl.subs[sub.Type] = true
+	}
+	cl.subsMtx.Unlock()
+	return cl.respondSubscriptions(msg.ID)
+}
+
+// wsUnsubscribe is the handler for the 'unsubscribe' websocket route. The
+// payload is a list of subscriptions. The market is not required for
+// SubBook.
+func wsUnsubscribe(_ *Server, cl *wsClient, msg *msgjson.Message) *msgjson.Error {
+	subs, msgErr := parseSubscriptions(msg)
+	if msgErr != nil {
+		return msgErr
+	}
+	cl.subsMtx.Lock()
+	for _, sub := range subs {
+		delete(cl.subs, sub.Type)
+	}
+	cl.subsMtx.Unlock()
+	for _, sub := range subs {
+		if sub.Type == SubBook {
+			cl.feedMtx.Lock()
+			cl.shutDownFeed()
+			cl.feedMtx.Unlock()
+		}
+	}
+	return cl.respondSubscriptions(msg.ID)
+}
+
+// NotifySubscribers sends a notification to the websocket clients with a
+// subscription of the specified type. The subscription type is used as the
+// notification route.
+func (s *Server) NotifySubscribers(subType string, payload any) {
+	if !subTypes[subType] {
+		s.log.Errorf("unknown subscription type %q", subType)
+		return
+	}
+	msg, err := msgjson.NewNotification(subType, payload)
+	if err != nil {
+		s.log.Errorf("%q notification encoding error: %v", subType, err)
+		return
+	}
+	s.clientsMtx.RLock()
+	defer s.clientsMtx.RUnlock()
+	for _, cl := range s.clients {
+		if !cl.subscribed(subType) {
+			continue
+		}
+		if err = cl.Send(msg); err != nil {
+			s.log.Warnf("Failed to send %v notification to client %v at %v: %v",
+				msg.Route, cl.cid, cl.Addr(), err)
+		}
+	}
+}
@@ -45,12 +45,16 @@ type wsClient struct {
 
 	feedMtx sync.RWMutex
 	feed    *bookFeed
+
+	subsMtx sync.RWMutex
+	subs    map[string]bool
 }
 
 func newWSClient(addr string, conn ws.Connection, hndlr func(msg *msgjson.Message) *msgjson.Error, logger dex.Logger) *wsClient {
 	return &wsClient{
 		WSLink: ws.NewWSLink(addr, conn, pingPeriod, hndlr, logger),
 		cid:    atomic.AddInt32(&cidCounter, 1),
+		subs:   make(map[string]bool),
 	}
 }
 
@@ -215,6 +219,8 @@ var wsHandlers = map[string]wsHandler{
 	"loadcandles": wsLoadCandles,
 	"unmarket":    wsUnmarket,
 	"acknotes":    wsAckNotes,
+	"subscribe":   wsSubscribe,
+	"unsubscribe": wsUnsubscribe,
 }
 
 // marketLoad is sent by websocket clients to subscribe to a market and request
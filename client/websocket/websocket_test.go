@@ -302,3 +302,81 @@ func TestClientMap(t *testing.T) {
 		t.Fatal("connection not closed on server shutdown")
 	}
 }
+
+func TestSubscriptions(t *testing.T) {
+	srv, tCore := newTServer()
+	link := newLink()
+	linkWg, err := link.cl.Connect(tCtx)
+	if err != nil {
+		t.Fatalf("WSLink Start: %v", err)
+	}
+	defer func() {
+		link.cl.shutDownFeed()
+		link.cl.Disconnect()
+		linkWg.Wait()
+	}()
+	srv.clients[link.cl.cid] = link.cl
+
+	readMsg := func() *msgjson.Message {
+		t.Helper()
+		select {
+		case b := <-link.conn.respReady:
+			msg, err := msgjson.DecodeMessage(b)
+			if err != nil {
+				t.Fatalf("error decoding message: %v", err)
+			}
+			return msg
+		case <-time.After(time.Second):
+			t.Fatalf("no message sent")
+		}
+		return nil
+	}
+	checkSubs := func(want ...string) {
+		t.Helper()
+		var res subscriptionsResult
+		if err := readMsg().UnmarshalResult(&res); err != nil {
+			t.Fatalf("error unmarshaling result: %v", err)
+		}
+		if len(res.Subscriptions) != len(want) {
+			t.Fatalf("wanted subscriptions %v, got %v", want, res.Subscriptions)
+		}
+		for i := range want {
+			if res.Subscriptions[i] != want[i] {
+				t.Fatalf("wanted subscriptions %v, got %v", want, res.Subscriptions)
+			}
+		}
+	}
+
+	tCore.syncFeed = &tBookFeed{}
+	subs := []*subscription{{Type: SubOrders}, {Type: SubBook, Market: &marketLoad{Host: "abc", Base: 1, Quote: 2}}}
+	req, _ := msgjson.NewRequest(1, "subscribe", subs)
+	if msgErr := srv.handleMessage(link.cl, req); msgErr != nil {
+		t.Fatalf("'subscribe' error: %d: %s", msgErr.Code, msgErr.Message)
+	}
+	checkSubs(SubBook, SubOrders)
+	if link.cl.feed == nil {
+		t.Fatalf("book not loaded for book subscription")
+	}
+
+	srv.NotifySubscribers(SubBalances, "balance")
+	srv.NotifySubscribers(SubOrders, "order")
+	if msg := readMsg(); msg.Route != SubOrders {
+		t.Fatalf("wrong notification route %q", msg.Route)
+	}
+
+	req, _ = msgjson.NewRequest(2, "unsubscribe", []*subscription{{Type: SubBook}})
+	if msgErr := srv.handleMessage(link.cl, req); msgErr != nil {
+		t.Fatalf("'unsubscribe' error: %d: %s", msgErr.Code, msgErr.Message)
+	}
+	checkSubs(SubOrders)
+	if link.cl.feed != nil {
+		t.Fatalf("book feed not stopped")
+	}
+
+	for _, subs := range [][]*subscription{{{Type: "trades"}}, {{Type: SubBook}}} {
+		req, _ = msgjson.NewRequest(3, "subscribe", subs)
+		if msgErr := srv.handleMessage(link.cl, req); msgErr == nil || msgErr.Code != msgjson.RPCArgumentsError {
+			t.Fatalf("wrong error for bad subscription %s: %v", subs[0].Type, msgErr)
+		}
+	}
+}
@@ -640,6 +640,9 @@ var bipIDs = map[uint32]string{
 	91927009: "kusd",
 	99999998: "fluid",
 	99999999: "qkc",
+	// Lightning Network reserved range 1000000000-1000000999
+	1000000000: "lnbtc",
+	// END Lightning Network reserved range
 	// math.MaxInt32 is the highest ID we should gol for v1 non-mesh dcrdex cuz
 	// of db type used for asset ID.
 	// Reserved for pre-paid bonds.
@@ -98,6 +98,17 @@ const (
 	RPCUpdateRunningBotCfgError          // 80
 	RPCUpdateRunningBotInvError          // 81
 	RPCMMStatusError                     // 82
+	RPCCoinControlError                  // 83
+	RPCExportBackupError                 // 84
+	RPCRestoreBackupError                // 85
+	RPCAPIKeyError                       // 86
+	RPCUnauthorizedRouteError            // 87
+	RPCWithdrawalPolicyError             // 88
+	RPCConditionalOrderError             // 89
+	RPCManagedOrderError                 // 90
+	RPCTaxExportError                    // 91
+	RPCUnknownProfileError               // 92
+	RPCArchivedOrdersError               // 93
 )
 
 // Routes are destinations for a "payload" of data. The type of data being
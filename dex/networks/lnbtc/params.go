@@ -0,0 +1,118 @@
+// This code is available on the terms of the project LICENSE.md file,
+// also available online at https://blueoakcouncil.org/license/1.0.0.
+
+package lnbtc
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"time"
+
+	"decred.org/dcrdex/dex"
+)
+
+const (
+	// BipID is the asset ID for Bitcoin over the Lightning Network. It is
+	// in the Lightning Network reserved range of dex.bipIDs.
+	BipID = 1000000000
+	// ContractVersion is the version of the hold invoice contract encoding.
+	ContractVersion = 0
+	// SecretHashSize is the size of the payment hash.
+	SecretHashSize = 32
+	// PubKeySize is the size of a compressed node public key.
+	PubKeySize = 33
+	// ContractDataSize is the size of an encoded v0 Contract.
+	ContractDataSize = 4 + SecretHashSize + 8 + 8 + PubKeySize
+	// BlockInterval is the target time between Bitcoin blocks, used to convert
+	// between contract lock times and HTLC CLTV deltas.
+	BlockInterval = 10 * time.Minute
+	// MinCLTVDelta is the smallest final CLTV delta that will be requested
+	// for a swap HTLC, regardless of the contract lock time. LND rejects
+	// final CLTV deltas less than 18 blocks.
+	MinCLTVDelta = 18
+)
+
+var UnitInfo = dex.UnitInfo{
+	AtomicUnit: "Sats",
+	Conventional: dex.Denomination{
+		Unit:             "BTC",
+		ConversionFactor: 1e8,
+	},
+	Alternatives: []dex.Denomination{
+		{
+			Unit:             "mBTC",
+			ConversionFactor: 1e5,
+		},
+		{
+			Unit:             "µBTC",
+			ConversionFactor: 1e2,
+		},
+	},
+	FeeRateDenom: "payment",
+}
+
+// Contract is the data that uniquely identifies a Lightning swap. The swap is
+// a payment to the Payee node for Value sats, locked by an HTLC with payment
+// hash SecretHash. The Payee accepts the HTLC into a hold invoice, and settles
+// the invoice with the secret to redeem. If the Payee never settles, the HTLC
+// times out at approximately the LockTime and the funds return to the payer.
+type Contract struct {
+	SecretHash [SecretHashSize]byte
+	LockTime   uint64 // UNIX seconds
+	Value      uint64 // sats
+	Payee      [PubKeySize]byte
+}
+
+// Encode serializes the Contract, prefixed with the contract version.
+func (c *Contract) Encode() []byte {
+	b := make([]byte, ContractDataSize)
+	binary.BigEndian.PutUint32(b[:4], ContractVersion)
+	copy(b[4:36], c.SecretHash[:])
+	binary.BigEndian.PutUint64(b[36:44], c.LockTime)
+	binary.BigEndian.PutUint64(b[44:52], c.Value)
+	copy(b[52:], c.Payee[:])
+	return b
+}
+
+// Expiration is the LockTime as a time.Time.
+func (c *Contract) Expiration() time.Time {
+	return time.Unix(int64(c.LockTime), 0)
+}
+
+// DecodeContract decodes the versioned contract data.
+func DecodeContract(b []byte) (*Contract, error) {
+	if len(b) < 4 {
+		return nil, errors.New("invalid short encoding")
+	}
+	if ver := binary.BigEndian.Uint32(b[:4]); ver != ContractVersion {
+		return nil, fmt.Errorf("unknown contract version %d", ver)
+	}
+	if len(b) != ContractDataSize {
+		return nil, fmt.Errorf("wrong contract data length. expected %d, got %d", ContractDataSize, len(b))
+	}
+	c := &Contract{
+		LockTime: binary.BigEndian.Uint64(b[36:44]),
+		Value:    binary.BigEndian.Uint64(b[44:52]),
+	}
+	copy(c.SecretHash[:], b[4:36])
+	copy(c.Payee[:], b[52:])
+	return c, nil
+}
+
+// ValidateSecret checks that the secret is the preimage of the payment hash.
+func ValidateSecret(secret, secretHash []byte) bool {
+	h := sha256.Sum256(secret)
+	return len(secretHash) == SecretHashSize && [SecretHashSize]byte(secretHash) == h
+}
+
+// CLTVDelta is the number of blocks until the lockTime, assuming the
+// BlockInterval, and no less than MinCLTVDelta.
+func CLTVDelta(lockTime, now time.Time) uint32 {
+	blocks := int64(lockTime.Sub(now) / BlockInterval)
+	if blocks < MinCLTVDelta {
+		return MinCLTVDelta
+	}
+	return uint32(blocks)
+}
@@ -0,0 +1,89 @@
+package lnbtc
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"testing"
+	"time"
+
+	"github.com/btcsuite/btcd/btcec/v2"
+	"github.com/btcsuite/btcd/btcec/v2/ecdsa"
+)
+
+func TestContractEncoding(t *testing.T) {
+	c := &Contract{
+		LockTime: uint64(time.Now().Unix()),
+		Value:    123_456,
+	}
+	copy(c.SecretHash[:], bytes.Repeat([]byte{0x01}, SecretHashSize))
+	copy(c.Payee[:], bytes.Repeat([]byte{0x02}, PubKeySize))
+
+	b := c.Encode()
+	if len(b) != ContractDataSize {
+		t.Fatalf("wrong encoded length %d", len(b))
+	}
+	reC, err := DecodeContract(b)
+	if err != nil {
+		t.Fatalf("DecodeContract error: %v", err)
+	}
+	if *reC != *c {
+		t.Fatalf("decoded contract doesn't match. %+v != %+v", reC, c)
+	}
+
+	if _, err := DecodeContract(b[:len(b)-1]); err == nil {
+		t.Fatalf("no error for short contract")
+	}
+	b[3] = 1
+	if _, err := DecodeContract(b); err == nil {
+		t.Fatalf("no error for unknown version")
+	}
+}
+
+func TestValidateSecret(t *testing.T) {
+	secret := bytes.Repeat([]byte{0x0a}, 32)
+	h := sha256.Sum256(secret)
+	if !ValidateSecret(secret, h[:]) {
+		t.Fatalf("valid secret not validated")
+	}
+	if ValidateSecret(secret[1:], h[:]) {
+		t.Fatalf("invalid secret validated")
+	}
+	if ValidateSecret(secret, h[1:]) {
+		t.Fatalf("short secret hash validated")
+	}
+}
+
+func TestCLTVDelta(t *testing.T) {
+	now := time.Now()
+	if d := CLTVDelta(now.Add(-time.Hour), now); d != MinCLTVDelta {
+		t.Fatalf("expected minimum delta for past lock time, got %d", d)
+	}
+	if d := CLTVDelta(now.Add(20*time.Hour), now); d != 120 {
+		t.Fatalf("expected 120 blocks, got %d", d)
+	}
+}
+
+func TestNodeSignature(t *testing.T) {
+	priv, _ := btcec.NewPrivateKey()
+	pubKey := priv.PubKey().SerializeCompressed()
+	msg := []byte("order")
+	sig := ecdsa.SignCompact(priv, SignedMessageHash(msg), true)
+
+	reSig, err := DecodeZBase32(EncodeZBase32(sig))
+	if err != nil {
+		t.Fatalf("DecodeZBase32 error: %v", err)
+	}
+	if !bytes.Equal(reSig, sig) {
+		t.Fatalf("zbase32 round trip failed")
+	}
+
+	if err := VerifyNodeSignature(pubKey, msg, reSig); err != nil {
+		t.Fatalf("VerifyNodeSignature error: %v", err)
+	}
+	if err := VerifyNodeSignature(pubKey, []byte("other"), reSig); err == nil {
+		t.Fatalf("no error for wrong message")
+	}
+	if _, err := DecodeZBase32("0"); err == nil {
+		t.Fatalf("no error for invalid zbase32 character")
+	}
+}
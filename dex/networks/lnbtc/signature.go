@@ -0,0 +1,84 @@
+// This code is available on the terms of the project LICENSE.md file,
+// also available online at https://blueoakcouncil.org/license/1.0.0.
+
+package lnbtc
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/btcsuite/btcd/btcec/v2/ecdsa"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+)
+
+// signedMsgPrefix is prepended to messages signed by a Lightning node with the
+// signmessage RPC.
+var signedMsgPrefix = []byte("Lightning Signed Message:")
+
+// zbase32Alphabet is the human-oriented base-32 alphabet used to encode node
+// message signatures.
+const zbase32Alphabet = "ybndrfg8ejkmcpqxot1uwisza345h769"
+
+// SignedMessageHash is the hash that is signed by a Lightning node's
+// signmessage RPC.
+func SignedMessageHash(msg []byte) []byte {
+	return chainhash.DoubleHashB(append(append([]byte{}, signedMsgPrefix...), msg...))
+}
+
+// DecodeZBase32 decodes a zbase32-encoded string, as returned by the
+// signmessage RPC.
+func DecodeZBase32(s string) ([]byte, error) {
+	b := make([]byte, 0, len(s)*5/8)
+	var acc uint32
+	var bits uint
+	for _, r := range s {
+		v := strings.IndexRune(zbase32Alphabet, r)
+		if v < 0 {
+			return nil, fmt.Errorf("invalid zbase32 character %q", r)
+		}
+		acc = acc<<5 | uint32(v)
+		bits += 5
+		if bits >= 8 {
+			bits -= 8
+			b = append(b, byte(acc>>bits))
+		}
+	}
+	return b, nil
+}
+
+// EncodeZBase32 encodes the bytes with the zbase32 alphabet.
+func EncodeZBase32(b []byte) string {
+	var sb strings.Builder
+	var acc uint32
+	var bits uint
+	for _, c := range b {
+		acc = acc<<8 | uint32(c)
+		bits += 8
+		for bits >= 5 {
+			bits -= 5
+			sb.WriteByte(zbase32Alphabet[(acc>>bits)&0x1f])
+		}
+	}
+	if bits > 0 {
+		sb.WriteByte(zbase32Alphabet[(acc<<(5-bits))&0x1f])
+	}
+	return sb.String()
+}
+
+// VerifyNodeSignature checks that the compact signature over msg, as produced
+// by the signmessage RPC, was created by the node with the given public key.
+func VerifyNodeSignature(pubKey, msg, sig []byte) error {
+	if len(sig) != 65 {
+		return fmt.Errorf("wrong signature length %d", len(sig))
+	}
+	recovered, _, err := ecdsa.RecoverCompact(sig, SignedMessageHash(msg))
+	if err != nil {
+		return fmt.Errorf("error recovering public key: %w", err)
+	}
+	if !bytes.Equal(recovered.SerializeCompressed(), pubKey) {
+		return errors.New("signature is not from the node")
+	}
+	return nil
+}
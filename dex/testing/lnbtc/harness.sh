@@ -0,0 +1,116 @@
+#!/usr/bin/env bash
+# Lightning harness. Starts two LND nodes, alpha and beta, using neutrino
+# against the BTC harness alpha node, funds them, and opens a channel from alpha
+# to beta with half of the capacity pushed to beta. The BTC harness must be
+# running.
+SYMBOL="lnbtc"
+BTC_LISTEN_PORT="20575"
+ALPHA_LISTEN_PORT="21735"
+BETA_LISTEN_PORT="21736"
+ALPHA_RPC_PORT="21737"
+BETA_RPC_PORT="21738"
+ALPHA_REST_PORT="21739"
+BETA_REST_PORT="21740"
+CHANNEL_SIZE="10000000"
+PUSH_AMT="5000000"
+
+set -ex
+NODES_ROOT=~/dextest/${SYMBOL}
+BTC_HARNESS_CTL=~/dextest/btc/harness-ctl
+rm -rf "${NODES_ROOT}"
+ALPHA_DIR="${NODES_ROOT}/alpha"
+BETA_DIR="${NODES_ROOT}/beta"
+HARNESS_DIR="${NODES_ROOT}/harness-ctl"
+mkdir -p "${ALPHA_DIR}" "${BETA_DIR}" "${HARNESS_DIR}"
+
+if [ ! -d "${BTC_HARNESS_CTL}" ]; then
+  echo "BTC harness is not running"
+  exit 1
+fi
+
+write_conf() {
+cat > "$1/lnd.conf" <<EOF
+[Application Options]
+lnddir=$1
+listen=127.0.0.1:$2
+rpclisten=127.0.0.1:$3
+restlisten=127.0.0.1:$4
+noseedbackup=true
+debuglevel=info
+alias=$5
+accept-keysend=true
+
+[Bitcoin]
+bitcoin.regtest=true
+bitcoin.node=neutrino
+
+[neutrino]
+neutrino.connect=127.0.0.1:${BTC_LISTEN_PORT}
+EOF
+}
+
+write_conf "${ALPHA_DIR}" "${ALPHA_LISTEN_PORT}" "${ALPHA_RPC_PORT}" "${ALPHA_REST_PORT}" alpha
+write_conf "${BETA_DIR}" "${BETA_LISTEN_PORT}" "${BETA_RPC_PORT}" "${BETA_REST_PORT}" beta
+
+SESSION="${SYMBOL}-harness"
+export SHELL=$(which bash)
+cd "${NODES_ROOT}" && tmux new-session -d -s $SESSION $SHELL
+
+tmux rename-window -t $SESSION:0 'alpha'
+tmux send-keys -t $SESSION:0 "lnd --configfile=${ALPHA_DIR}/lnd.conf; tmux wait-for -S alpha${SYMBOL}" C-m
+tmux new-window -t $SESSION:1 -n 'beta' $SHELL
+tmux send-keys -t $SESSION:1 "lnd --configfile=${BETA_DIR}/lnd.conf; tmux wait-for -S beta${SYMBOL}" C-m
+
+cat > "${HARNESS_DIR}/alpha" <<EOF
+#!/usr/bin/env bash
+lncli --network=regtest --lnddir=${ALPHA_DIR} --rpcserver=127.0.0.1:${ALPHA_RPC_PORT} "\$@"
+EOF
+chmod +x "${HARNESS_DIR}/alpha"
+
+cat > "${HARNESS_DIR}/beta" <<EOF
+#!/usr/bin/env bash
+lncli --network=regtest --lnddir=${BETA_DIR} --rpcserver=127.0.0.1:${BETA_RPC_PORT} "\$@"
+EOF
+chmod +x "${HARNESS_DIR}/beta"
+
+cat > "${HARNESS_DIR}/quit" <<EOF
+#!/usr/bin/env bash
+tmux send-keys -t $SESSION:0 C-c
+tmux send-keys -t $SESSION:1 C-c
+tmux wait-for alpha${SYMBOL}
+tmux wait-for beta${SYMBOL}
+tmux kill-session
+EOF
+chmod +x "${HARNESS_DIR}/quit"
+
+cd "${HARNESS_DIR}"
+
+echo "Waiting for the nodes to start"
+until ./alpha getinfo > /dev/null 2>&1 && ./beta getinfo > /dev/null 2>&1; do
+  sleep 1
+done
+
+echo "Funding the alpha node"
+ALPHA_ADDR=$(./alpha newaddress p2wkh | jq -r .address)
+${BTC_HARNESS_CTL}/alpha sendtoaddress "${ALPHA_ADDR}" 1
+${BTC_HARNESS_CTL}/mine-alpha 6
+sleep 5
+
+echo "Opening a channel from alpha to beta"
+BETA_PUBKEY=$(./beta getinfo | jq -r .identity_pubkey)
+./alpha connect "${BETA_PUBKEY}@127.0.0.1:${BETA_LISTEN_PORT}"
+./alpha openchannel --node_key "${BETA_PUBKEY}" --local_amt ${CHANNEL_SIZE} --push_amt ${PUSH_AMT}
+${BTC_HARNESS_CTL}/mine-alpha 6
+
+set +x
+echo "alpha REST: https://127.0.0.1:${ALPHA_REST_PORT}"
+echo "  macaroon: ${ALPHA_DIR}/data/chain/bitcoin/regtest/admin.macaroon"
+echo "  tls cert: ${ALPHA_DIR}/tls.cert"
+echo "beta REST: https://127.0.0.1:${BETA_REST_PORT}"
+echo "  macaroon: ${BETA_DIR}/data/chain/bitcoin/regtest/admin.macaroon"
+echo "  tls cert: ${BETA_DIR}/tls.cert"
+
+tmux new-window -t $SESSION:2 -n 'harness-ctl' $SHELL
+tmux send-keys -t $SESSION:2 "cd ${HARNESS_DIR}" C-m
+tmux select-window -t $SESSION:2
+tmux attach-session -t $SESSION
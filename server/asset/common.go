@@ -104,6 +104,16 @@ type AccountBalancer interface {
 	InitTxSize() uint64
 }
 
+// AuditAcknowledger is implemented by backends that cannot observe swap
+// contracts, and instead rely on the counterparty's signed audit
+// acknowledgement as proof that a swap has been funded. A swap Coin from such
+// a backend reports zero confirmations until its audit is acknowledged.
+type AuditAcknowledger interface {
+	// AuditAcknowledged records that the counterparty has acknowledged their
+	// audit of the swap contract with the coin ID.
+	AuditAcknowledged(coinID []byte)
+}
+
 // TokenBacker is implemented by Backends that support degenerate tokens.
 type TokenBacker interface {
 	TokenBackend(assetID uint32, configPath string) (Backend, error)
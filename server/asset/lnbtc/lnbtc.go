@@ -0,0 +1,355 @@
+// This code is available on the terms of the project LICENSE.md file,
+// also available online at https://blueoakcouncil.org/license/1.0.0.
+
+// Package lnbtc is the server backend for Bitcoin on the Lightning Network.
+//
+// Lightning swaps are HTLC payments between the traders' nodes, and are not
+// observable by the server. Instead of locating a contract on-chain, the
+// backend validates the contract data, which commits to the payment hash,
+// value, lock time and payee node. The acceptance of the HTLC is confirmed by
+// the payee's wallet, which will not acknowledge an audit until its hold
+// invoice for the payment hash has accepted an HTLC of at least the contract
+// value. The swapper reports the signed audit acknowledgement to the backend
+// via AuditAcknowledged, and a swap has zero confirmations until then. A payer
+// that never dispatches the HTLC will fail to get an audit acknowledgement and
+// be penalized for a failed swap as with any other asset.
+package lnbtc
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	"decred.org/dcrdex/dex"
+	dexlnbtc "decred.org/dcrdex/dex/networks/lnbtc"
+	"decred.org/dcrdex/server/asset"
+	"github.com/btcsuite/btcd/btcec/v2"
+)
+
+const (
+	version = 0
+	BipID   = dexlnbtc.BipID
+	// routingFeeRate is the reported "fee rate", which is interpreted by
+	// Lightning wallets as the maximum routing fee in sats for a single swap
+	// payment.
+	routingFeeRate = 10
+	// tickInterval is how often a BlockUpdate is sent. There are no blocks,
+	// but the swapper relies on block updates to re-check swap status.
+	tickInterval = 30 * time.Second
+	// acceptedRetention is how long an accepted swap is remembered. It is
+	// longer than any swap lock time.
+	acceptedRetention = 48 * time.Hour
+)
+
+// Driver implements asset.Driver.
+type Driver struct{}
+
+var _ asset.Driver = (*Driver)(nil)
+
+func init() {
+	asset.Register(BipID, &Driver{})
+}
+
+// Setup creates the Lightning backend.
+func (d *Driver) Setup(cfg *asset.BackendConfig) (asset.Backend, error) {
+	return NewBackend(cfg)
+}
+
+// DecodeCoinID creates a human-readable representation of a coin ID, which is
+// a payment hash.
+func (d *Driver) DecodeCoinID(coinID []byte) (string, error) {
+	if len(coinID) != dexlnbtc.SecretHashSize {
+		return "", fmt.Errorf("invalid coin ID length %d", len(coinID))
+	}
+	return hex.EncodeToString(coinID), nil
+}
+
+// Version returns the Backend implementation's version number.
+func (d *Driver) Version() uint32 {
+	return version
+}
+
+// UnitInfo returns the dex.UnitInfo for the asset.
+func (d *Driver) UnitInfo() dex.UnitInfo {
+	return dexlnbtc.UnitInfo
+}
+
+// Name is the asset's name.
+func (d *Driver) Name() string {
+	return "Bitcoin (Lightning)"
+}
+
+// Backend is an asset.Backend for Lightning swaps.
+type Backend struct {
+	log dex.Logger
+
+	blockChansMtx sync.RWMutex
+	blockChans    map[chan *asset.BlockUpdate]struct{}
+
+	// accepted is the payment hashes of swaps for which the payee has
+	// acknowledged their audit, and therefore accepted the HTLC, mapped to
+	// the time of the acknowledgement.
+	acceptedMtx sync.RWMutex
+	accepted    map[[32]byte]time.Time
+}
+
+var _ asset.Backend = (*Backend)(nil)
+var _ asset.AccountBalancer = (*Backend)(nil)
+var _ asset.AuditAcknowledger = (*Backend)(nil)
+
+// NewBackend is the constructor for a Lightning Backend.
+func NewBackend(cfg *asset.BackendConfig) (*Backend, error) {
+	return &Backend{
+		log:        cfg.Logger,
+		blockChans: make(map[chan *asset.BlockUpdate]struct{}),
+		accepted:   make(map[[32]byte]time.Time),
+	}, nil
+}
+
+// Connect starts the block update ticker.
+func (be *Backend) Connect(ctx context.Context) (*sync.WaitGroup, error) {
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		ticker := time.NewTicker(tickInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				be.pruneAccepted()
+				be.signalUpdate()
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return &wg, nil
+}
+
+func (be *Backend) signalUpdate() {
+	be.blockChansMtx.RLock()
+	defer be.blockChansMtx.RUnlock()
+	for c := range be.blockChans {
+		select {
+		case c <- &asset.BlockUpdate{}:
+		default:
+			be.log.Errorf("failed to send block update on blocking channel")
+		}
+	}
+}
+
+// AuditAcknowledged records that the payee has acknowledged their audit of
+// the swap, and so has accepted the HTLC. Satisfies asset.AuditAcknowledger.
+func (be *Backend) AuditAcknowledged(coinID []byte) {
+	if len(coinID) != dexlnbtc.SecretHashSize {
+		be.log.Errorf("AuditAcknowledged: invalid coin ID %x", coinID)
+		return
+	}
+	be.acceptedMtx.Lock()
+	defer be.acceptedMtx.Unlock()
+	if _, found := be.accepted[[32]byte(coinID)]; !found {
+		be.accepted[[32]byte(coinID)] = time.Now()
+	}
+}
+
+func (be *Backend) isAccepted(hash [32]byte) bool {
+	be.acceptedMtx.RLock()
+	defer be.acceptedMtx.RUnlock()
+	_, found := be.accepted[hash]
+	return found
+}
+
+// pruneAccepted forgets accepted swaps whose contracts have long expired.
+func (be *Backend) pruneAccepted() {
+	be.acceptedMtx.Lock()
+	defer be.acceptedMtx.Unlock()
+	for h, stamp := range be.accepted {
+		if time.Since(stamp) > acceptedRetention {
+			delete(be.accepted, h)
+		}
+	}
+}
+
+// Contract validates the contract data and returns the Contract. The coin ID
+// must be the contract's payment hash.
+func (be *Backend) Contract(coinID []byte, contractData []byte) (*asset.Contract, error) {
+	c, err := dexlnbtc.DecodeContract(contractData)
+	if err != nil {
+		return nil, err
+	}
+	if !bytes.Equal(coinID, c.SecretHash[:]) {
+		return nil, fmt.Errorf("coin ID %x does not match payment hash %x", coinID, c.SecretHash)
+	}
+	return &asset.Contract{
+		Coin:         &paymentCoin{hash: c.SecretHash, value: c.Value, be: be},
+		SwapAddress:  hex.EncodeToString(c.Payee[:]),
+		ContractData: contractData,
+		SecretHash:   c.SecretHash[:],
+		LockTime:     c.Expiration(),
+	}, nil
+}
+
+// TxData returns nil. There are no transactions.
+func (be *Backend) TxData([]byte) ([]byte, error) {
+	return nil, nil
+}
+
+// ValidateSecret checks that the secret is the contract's payment preimage.
+func (be *Backend) ValidateSecret(secret, contractData []byte) bool {
+	c, err := dexlnbtc.DecodeContract(contractData)
+	if err != nil {
+		be.log.Errorf("ValidateSecret: invalid contract data: %v", err)
+		return false
+	}
+	return dexlnbtc.ValidateSecret(secret, c.SecretHash[:])
+}
+
+// Redemption returns a Coin for the settled payment. A settlement is
+// identified by the payment hash, so the redemption ID must match the
+// contract ID.
+func (be *Backend) Redemption(redemptionID, contractID, contractData []byte) (asset.Coin, error) {
+	if !bytes.Equal(redemptionID, contractID) {
+		return nil, errors.New("redemption ID does not match the contract ID")
+	}
+	c, err := dexlnbtc.DecodeContract(contractData)
+	if err != nil {
+		return nil, err
+	}
+	if !bytes.Equal(contractID, c.SecretHash[:]) {
+		return nil, fmt.Errorf("contract ID %x does not match payment hash %x", contractID, c.SecretHash)
+	}
+	// A redemption is only reported once the payee has settled the invoice.
+	return &paymentCoin{hash: c.SecretHash, value: c.Value}, nil
+}
+
+// BlockChannel creates and returns a new channel on which to receive updates.
+func (be *Backend) BlockChannel(size int) <-chan *asset.BlockUpdate {
+	c := make(chan *asset.BlockUpdate, size)
+	be.blockChansMtx.Lock()
+	defer be.blockChansMtx.Unlock()
+	be.blockChans[c] = struct{}{}
+	return c
+}
+
+// CheckSwapAddress checks that the address is a node public key.
+func (be *Backend) CheckSwapAddress(addr string) bool {
+	b, err := hex.DecodeString(addr)
+	if err != nil {
+		return false
+	}
+	_, err = btcec.ParsePubKey(b)
+	return err == nil
+}
+
+// ValidateCoinID checks that the coin ID is a payment hash.
+func (be *Backend) ValidateCoinID(coinID []byte) (string, error) {
+	return (&Driver{}).DecodeCoinID(coinID)
+}
+
+// ValidateContract checks that the contract data can be decoded and that the
+// payee is a valid node public key.
+func (be *Backend) ValidateContract(contractData []byte) error {
+	c, err := dexlnbtc.DecodeContract(contractData)
+	if err != nil {
+		return err
+	}
+	if _, err := btcec.ParsePubKey(c.Payee[:]); err != nil {
+		return fmt.Errorf("invalid payee: %w", err)
+	}
+	return nil
+}
+
+// FeeRate returns the max routing fee per swap payment.
+func (be *Backend) FeeRate(context.Context) (uint64, error) {
+	return routingFeeRate, nil
+}
+
+// Synced is always true. There is no chain to sync.
+func (be *Backend) Synced() (bool, error) {
+	return true, nil
+}
+
+// Info provides auxiliary information about the backend.
+func (be *Backend) Info() *asset.BackendInfo {
+	return &asset.BackendInfo{}
+}
+
+// ValidateFeeRate always returns true. Routing fees are paid by the payer
+// and do not affect the counterparty.
+func (be *Backend) ValidateFeeRate(asset.Coin, uint64) bool {
+	return true
+}
+
+// AccountBalance returns the maximum balance. Channel liquidity is not
+// observable by the server. A payer without sufficient outbound liquidity
+// will fail to dispatch the swap HTLC, so the swap will never be acknowledged
+// as accepted, and the payer will be penalized for it.
+func (be *Backend) AccountBalance(string) (uint64, error) {
+	return math.MaxInt64, nil
+}
+
+// ValidateSignature checks that the address is the hex-encoded pubkey and
+// that the signature was created by the node's signmessage RPC.
+func (be *Backend) ValidateSignature(addr string, pubkey, msg, sig []byte) error {
+	if addr != hex.EncodeToString(pubkey) {
+		return fmt.Errorf("pubkey does not match address %s", addr)
+	}
+	return dexlnbtc.VerifyNodeSignature(pubkey, msg, sig)
+}
+
+// RedeemSize is zero. Settling a hold invoice is free.
+func (be *Backend) RedeemSize() uint64 {
+	return 0
+}
+
+// InitTxSize is 1, so that swap fees are one fee rate, i.e. one max routing
+// fee, per swap.
+func (be *Backend) InitTxSize() uint64 {
+	return 1
+}
+
+// paymentCoin is a Lightning payment, identified by the payment hash.
+type paymentCoin struct {
+	hash  [32]byte
+	value uint64
+	// be is set for swap contracts, which are not confirmed until the payee
+	// acknowledges their audit.
+	be *Backend
+}
+
+var _ asset.Coin = (*paymentCoin)(nil)
+
+// Confirmations is 1 once the HTLC has been accepted by the payee, and 0
+// before. See the package documentation.
+func (c *paymentCoin) Confirmations(context.Context) (int64, error) {
+	if c.be != nil && !c.be.isAccepted(c.hash) {
+		return 0, nil
+	}
+	return 1, nil
+}
+
+func (c *paymentCoin) ID() []byte {
+	return c.hash[:]
+}
+
+func (c *paymentCoin) TxID() string {
+	return c.String()
+}
+
+func (c *paymentCoin) String() string {
+	return hex.EncodeToString(c.hash[:])
+}
+
+func (c *paymentCoin) Value() uint64 {
+	return c.value
+}
+
+func (c *paymentCoin) FeeRate() uint64 {
+	return 0
+}
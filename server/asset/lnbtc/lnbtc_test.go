@@ -0,0 +1,79 @@
+package lnbtc
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"testing"
+	"time"
+
+	"decred.org/dcrdex/dex"
+	dexlnbtc "decred.org/dcrdex/dex/networks/lnbtc"
+	"decred.org/dcrdex/server/asset"
+	"github.com/btcsuite/btcd/btcec/v2"
+	"github.com/btcsuite/btcd/btcec/v2/ecdsa"
+)
+
+func TestContract(t *testing.T) {
+	be, _ := NewBackend(&asset.BackendConfig{Logger: dex.StdOutLogger("T", dex.LevelTrace)})
+	priv, _ := btcec.NewPrivateKey()
+	secret := []byte("abcdefghijklmnopqrstuvwxyz012345")
+	c := &dexlnbtc.Contract{
+		SecretHash: sha256.Sum256(secret),
+		LockTime:   uint64(time.Now().Unix()),
+		Value:      5e4,
+		Payee:      [33]byte(priv.PubKey().SerializeCompressed()),
+	}
+	contractData := c.Encode()
+	if err := be.ValidateContract(contractData); err != nil {
+		t.Fatalf("ValidateContract error: %v", err)
+	}
+	ctr, err := be.Contract(c.SecretHash[:], contractData)
+	if err != nil {
+		t.Fatalf("Contract error: %v", err)
+	}
+	if ctr.Value() != c.Value || ctr.SwapAddress != hex.EncodeToString(c.Payee[:]) || !ctr.LockTime.Equal(c.Expiration()) {
+		t.Fatalf("wrong contract")
+	}
+	if !be.CheckSwapAddress(ctr.SwapAddress) {
+		t.Fatalf("swap address not valid")
+	}
+	if _, err := be.Contract(secret, contractData); err == nil {
+		t.Fatalf("no error for wrong coin ID")
+	}
+	if !be.ValidateSecret(secret, contractData) {
+		t.Fatalf("secret not validated")
+	}
+	if _, err := be.Redemption(c.SecretHash[:], c.SecretHash[:], contractData); err != nil {
+		t.Fatalf("Redemption error: %v", err)
+	}
+
+	// Not confirmed until the payee acknowledges the audit.
+	if confs, _ := ctr.Confirmations(context.Background()); confs != 0 {
+		t.Fatalf("unacknowledged swap has %d confirmations", confs)
+	}
+	be.AuditAcknowledged(c.SecretHash[:])
+	if confs, _ := ctr.Confirmations(context.Background()); confs != 1 {
+		t.Fatalf("acknowledged swap has %d confirmations", confs)
+	}
+	be.accepted[c.SecretHash] = time.Now().Add(-acceptedRetention - time.Second)
+	be.pruneAccepted()
+	if confs, _ := ctr.Confirmations(context.Background()); confs != 0 {
+		t.Fatalf("pruned swap has %d confirmations", confs)
+	}
+}
+
+func TestValidateSignature(t *testing.T) {
+	be, _ := NewBackend(&asset.BackendConfig{Logger: dex.StdOutLogger("T", dex.LevelTrace)})
+	priv, _ := btcec.NewPrivateKey()
+	pubKey := priv.PubKey().SerializeCompressed()
+	addr := hex.EncodeToString(pubKey)
+	msg := []byte("order")
+	sig := ecdsa.SignCompact(priv, dexlnbtc.SignedMessageHash(msg), true)
+	if err := be.ValidateSignature(addr, pubKey, msg, sig); err != nil {
+		t.Fatalf("ValidateSignature error: %v", err)
+	}
+	if err := be.ValidateSignature(addr[2:], pubKey, msg, sig); err == nil {
+		t.Fatalf("no error for wrong address")
+	}
+}
@@ -584,6 +584,7 @@ func (s *Swapper) restoreActiveSwaps(allowPartial bool) error {
 		ContractScript  []byte // {a,b}Contract
 		RedeemTime      int64  // {a,b}RedeemTime
 		RedeemCoinIn    []byte // {a,b}aRedeemCoinID
+		AuditAckSig     []byte // counterparty's audit ack of the contract
 		// SwapConfirmTime is not stored in the DB, so use time.Now() if the
 		// contract has reached SwapConf.
 	}
@@ -602,6 +603,10 @@ func (s *Swapper) restoreActiveSwaps(allowPartial bool) error {
 			ss.swap = swap
 			ss.swapTime = time.UnixMilli(ssd.SwapTime)
 
+			if acker, is := swapAsset.Backend.(asset.AuditAcknowledger); is && len(ssd.AuditAckSig) > 0 {
+				acker.AuditAcknowledged(swapCoin)
+			}
+
 			swapConfs, err := swap.Confirmations(context.Background())
 			if err != nil {
 				log.Warnf("No swap confirmed time for %v: %v", swap, err)
@@ -719,6 +724,7 @@ func (s *Swapper) restoreActiveSwaps(allowPartial bool) error {
 			ContractScript:  sd.SwapData.ContractA,
 			RedeemTime:      sd.SwapData.RedeemATime,
 			RedeemCoinIn:    sd.SwapData.RedeemACoinID,
+			AuditAckSig:     match.Sigs.TakerAudit,
 		}
 		takerStatus := &swapStatusData{
 			SwapAsset:       makerRedeemAsset,
@@ -728,6 +734,7 @@ func (s *Swapper) restoreActiveSwaps(allowPartial bool) error {
 			ContractScript:  sd.SwapData.ContractB,
 			RedeemTime:      sd.SwapData.RedeemBTime,
 			RedeemCoinIn:    sd.SwapData.RedeemBCoinID,
+			AuditAckSig:     match.Sigs.MakerAudit,
 		}
 
 		if err := translateSwapStatus(mt.makerStatus, makerStatus, takerStatus.ContractCoinOut); err != nil {
@@ -1482,13 +1489,20 @@ func (s *Swapper) processAck(msg *msgjson.Message, acker *messageAcker) {
 		log.Debugf("Received contract 'audit' acknowledgement from user %v (%s) for match %v (%v)",
 			acker.user, makerTaker(acker.isMaker), acker.match.Match.ID(), acker.match.Status)
 		// It's a contract audit ack.
+		auditedStatus := acker.match.takerStatus
 		if acker.isMaker {
 			acker.match.Sigs.MakerAudit = ack.Sig         // i.e. audited taker's contract
 			s.storage.SaveAuditAckSigA(mktMatch, ack.Sig) // sql error makes backend go fatal
 		} else {
+			auditedStatus = acker.match.makerStatus
 			acker.match.Sigs.TakerAudit = ack.Sig
 			s.storage.SaveAuditAckSigB(mktMatch, ack.Sig)
 		}
+		if a, found := s.coins[auditedStatus.swapAsset]; found {
+			if auditAcker, is := a.Backend.(asset.AuditAcknowledger); is {
+				auditAcker.AuditAcknowledged(acker.params.(*msgjson.Audit).CoinID)
+			}
+		}
 		return
 	}
 
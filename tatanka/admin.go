@@ -0,0 +1,337 @@
+// This code is available on the terms of the project LICENSE.md file,
+// also available online at https://blueoakcouncil.org/license/1.0.0.
+
+package tatanka
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"decred.org/dcrdex/dex"
+	"decred.org/dcrdex/tatanka/db"
+	"decred.org/dcrdex/tatanka/mj"
+	"decred.org/dcrdex/tatanka/tanka"
+	"decred.org/dcrdex/tatanka/tcp/noise"
+)
+
+// ClientInfo describes a locally connected client.
+type ClientInfo struct {
+	PeerID     dex.Bytes         `json:"peerID"`
+	BondTier   uint64            `json:"bondTier"`
+	Tier       int64             `json:"tier"`
+	Reputation *tanka.Reputation `json:"reputation"`
+	Topics     []tanka.Topic     `json:"topics"`
+}
+
+// TatankaInfo describes a connected remote tatanka node.
+type TatankaInfo struct {
+	PeerID dex.Bytes `json:"peerID"`
+	Chains []uint32  `json:"chains"`
+	// Clients is the number of clients we think are connected to the node.
+	Clients int `json:"clients"`
+}
+
+// TopicInfo is the subscription counts for a topic.
+type TopicInfo struct {
+	Topic       tanka.Topic           `json:"topic"`
+	Subscribers int                   `json:"subscribers"`
+	Subjects    map[tanka.Subject]int `json:"subjects"`
+}
+
+// PeerInfo is the bonds, reputation, and ban status of a peer.
+type PeerInfo struct {
+	PeerID     dex.Bytes         `json:"peerID"`
+	Bonds      []*tanka.Bond     `json:"bonds"`
+	Standing   *tanka.Standing   `json:"standing"`
+	Reputation *tanka.Reputation `json:"reputation"`
+	Tier       int64             `json:"tier"`
+	// Connected is true if the peer is a locally connected client or a
+	// connected remote tatanka node.
+	Connected bool    `json:"connected"`
+	Ban       *db.Ban `json:"ban,omitempty"`
+}
+
+// BanInfo is a banned peer.
+type BanInfo struct {
+	PeerID dex.Bytes `json:"peerID"`
+	*db.Ban
+}
+
+// whitelisted checks whether the peer is a whitelisted tatanka node.
+func (t *Tatanka) whitelisted(peerID tanka.PeerID) bool {
+	t.whitelistMtx.RLock()
+	defer t.whitelistMtx.RUnlock()
+	_, found := t.whitelist[peerID]
+	return found
+}
+
+func (t *Tatanka) whitelistNodes() []*parsedBootNode {
+	t.whitelistMtx.RLock()
+	defer t.whitelistMtx.RUnlock()
+	nodes := make([]*parsedBootNode, 0, len(t.whitelist))
+	for _, n := range t.whitelist {
+		nodes = append(nodes, n)
+	}
+	return nodes
+}
+
+// banned returns the peer's ban, or nil if the peer is not banned.
+func (t *Tatanka) banned(peerID tanka.PeerID) *db.Ban {
+	t.banMtx.RLock()
+	defer t.banMtx.RUnlock()
+	return t.bans[peerID]
+}
+
+// Clients lists the locally connected clients.
+func (t *Tatanka) Clients() []*ClientInfo {
+	t.clientMtx.RLock()
+	defer t.clientMtx.RUnlock()
+	clients := make([]*ClientInfo, 0, len(t.clients))
+	for peerID, c := range t.clients {
+		c.mtx.RLock()
+		ci := &ClientInfo{
+			PeerID:     peerID[:],
+			BondTier:   c.BondTier(),
+			Tier:       calcTier(c.Reputation, c.BondTier()),
+			Reputation: c.Reputation,
+			Topics:     make([]tanka.Topic, 0),
+		}
+		c.mtx.RUnlock()
+		for topicID, topic := range t.topics {
+			if _, found := topic.subscribers[peerID]; found {
+				ci.Topics = append(ci.Topics, topicID)
+			}
+		}
+		clients = append(clients, ci)
+	}
+	return clients
+}
+
+// Tatankas lists the connected remote tatanka nodes.
+func (t *Tatanka) Tatankas() []*TatankaInfo {
+	job := &clientJob{
+		task: &clientJobCountRemotes{},
+		res:  make(chan interface{}, 1),
+	}
+	var counts map[tanka.PeerID]int
+	select {
+	case t.clientJobs <- job:
+		counts = (<-job.res).(map[tanka.PeerID]int)
+	case <-t.ctx.Done():
+	}
+
+	nodes := t.tatankaNodes()
+	tatankas := make([]*TatankaInfo, 0, len(nodes))
+	for _, tt := range nodes {
+		ti := &TatankaInfo{
+			PeerID:  tt.ID[:],
+			Clients: counts[tt.ID],
+		}
+		if cfg, is := tt.cfg.Load().(*mj.TatankaConfig); is {
+			ti.Chains = cfg.Chains
+		}
+		tatankas = append(tatankas, ti)
+	}
+	return tatankas
+}
+
+// Topics lists the subscription counts for every topic and subject.
+func (t *Tatanka) Topics() []*TopicInfo {
+	t.clientMtx.RLock()
+	defer t.clientMtx.RUnlock()
+	topics := make([]*TopicInfo, 0, len(t.topics))
+	for topicID, topic := range t.topics {
+		ti := &TopicInfo{
+			Topic:       topicID,
+			Subscribers: len(topic.subscribers),
+			Subjects:    make(map[tanka.Subject]int, len(topic.subjects)),
+		}
+		for subject, subs := range topic.subjects {
+			ti.Subjects[subject] = len(subs)
+		}
+		topics = append(topics, ti)
+	}
+	sort.Slice(topics, func(i, j int) bool { return topics[i].Topic < topics[j].Topic })
+	return topics
+}
+
+// PeerInfo retrieves the bonds, reputation, and ban status of any peer.
+func (t *Tatanka) PeerInfo(peerID tanka.PeerID) (*PeerInfo, error) {
+	p, err := t.db.Peer(peerID)
+	if err != nil {
+		return nil, err
+	}
+	standing, err := t.db.Standing(peerID)
+	if err != nil {
+		return nil, fmt.Errorf("error getting standing: %w", err)
+	}
+	var bondTier uint64
+	if time.Now().Before(standing.BondExpiration) {
+		bondTier = standing.BondTier
+	}
+	return &PeerInfo{
+		PeerID:     peerID[:],
+		Bonds:      p.Bonds,
+		Standing:   standing,
+		Reputation: p.Reputation,
+		Tier:       calcTier(p.Reputation, bondTier),
+		Connected:  t.clientNode(peerID) != nil || t.tatankaNode(peerID) != nil,
+		Ban:        t.banned(peerID),
+	}, nil
+}
+
+// Whitelist lists the whitelisted tatanka nodes.
+func (t *Tatanka) Whitelist() []*BootNode {
+	nodes := t.whitelistNodes()
+	whitelist := make([]*BootNode, 0, len(nodes))
+	for _, n := range nodes {
+		whitelist = append(whitelist, &BootNode{
+			Protocol: n.protocol,
+			PeerID:   n.peerID[:],
+			Config:   n.cfg,
+		})
+	}
+	return whitelist
+}
+
+// AddWhitelistNode adds a tatanka node to the whitelist, replacing any
+// existing entry for the node, and attempts to connect. The addition is
+// stored, and takes precedence over the configured whitelist after a restart.
+func (t *Tatanka) AddWhitelistNode(n *BootNode) error {
+	if len(n.PeerID) != tanka.PeerIDLength {
+		return fmt.Errorf("invalid peer ID length %d", len(n.PeerID))
+	}
+	switch n.Protocol {
+	case "ws", "wss", noise.Protocol:
+	default:
+		return fmt.Errorf("unknown boot node network protocol %q", n.Protocol)
+	}
+	if len(n.Config) == 0 {
+		return errors.New("no boot node config")
+	}
+	var peerID tanka.PeerID
+	copy(peerID[:], n.PeerID)
+	if peerID == t.id {
+		return errors.New("cannot whitelist ourselves")
+	}
+
+	edit := &db.WhitelistEdit{
+		Protocol: n.Protocol,
+		Config:   n.Config,
+		Stamp:    time.Now().Truncate(time.Second),
+	}
+	if err := t.db.EditWhitelist(peerID, edit); err != nil {
+		return fmt.Errorf("error storing whitelist node: %w", err)
+	}
+
+	t.whitelistMtx.Lock()
+	t.whitelist[peerID] = &parsedBootNode{
+		peerID:   peerID,
+		cfg:      n.Config,
+		protocol: n.Protocol,
+	}
+	t.whitelistMtx.Unlock()
+	t.db.TrustScorers(peerID)
+
+	t.log.Infof("Added %s node %s to the whitelist", n.Protocol, peerID)
+
+	select {
+	case t.whitelistCh <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+// RemoveWhitelistNode removes the tatanka node from the whitelist and
+// disconnects it. The node's scores no longer have full weight. The removal is
+// stored, and takes precedence over the configured whitelist after a restart.
+func (t *Tatanka) RemoveWhitelistNode(peerID tanka.PeerID) error {
+	if !t.whitelisted(peerID) {
+		return fmt.Errorf("%s is not whitelisted", peerID)
+	}
+	edit := &db.WhitelistEdit{
+		Removed: true,
+		Stamp:   time.Now().Truncate(time.Second),
+	}
+	if err := t.db.EditWhitelist(peerID, edit); err != nil {
+		return fmt.Errorf("error storing whitelist removal: %w", err)
+	}
+	t.whitelistMtx.Lock()
+	delete(t.whitelist, peerID)
+	t.whitelistMtx.Unlock()
+	t.db.UntrustScorers(peerID)
+	t.log.Infof("Removed node %s from the whitelist", peerID)
+	t.disconnectTatanka(peerID)
+	return nil
+}
+
+// disconnectTatanka disconnects the remote tatanka node, if connected.
+func (t *Tatanka) disconnectTatanka(peerID tanka.PeerID) {
+	t.tatankasMtx.Lock()
+	tt, found := t.tatankas[peerID]
+	delete(t.tatankas, peerID)
+	t.tatankasMtx.Unlock()
+	if found {
+		tt.Disconnect()
+	}
+}
+
+// Bans lists the banned peers.
+func (t *Tatanka) Bans() []*BanInfo {
+	t.banMtx.RLock()
+	defer t.banMtx.RUnlock()
+	bans := make([]*BanInfo, 0, len(t.bans))
+	for peerID, ban := range t.bans {
+		bans = append(bans, &BanInfo{
+			PeerID: peerID[:],
+			Ban:    ban,
+		})
+	}
+	sort.Slice(bans, func(i, j int) bool { return bans[i].Stamp.Before(bans[j].Stamp) })
+	return bans
+}
+
+// Ban bans the peer, disconnecting them if they are connected. A banned client
+// cannot connect, and broadcasts from a banned client are not accepted from
+// remote tatankas. A banned tatanka node cannot connect, even if whitelisted.
+func (t *Tatanka) Ban(peerID tanka.PeerID, reason string) error {
+	if peerID == t.id {
+		return errors.New("cannot ban ourselves")
+	}
+	ban := &db.Ban{
+		Reason: reason,
+		Stamp:  time.Now().Truncate(time.Second),
+	}
+	if err := t.db.Ban(peerID, ban); err != nil {
+		return fmt.Errorf("error storing ban: %w", err)
+	}
+	t.banMtx.Lock()
+	t.bans[peerID] = ban
+	t.banMtx.Unlock()
+
+	t.log.Infof("Banned peer %s: %s", peerID, reason)
+
+	if c := t.clientNode(peerID); c != nil {
+		c.Disconnect()
+		t.clientDisconnected(peerID)
+	}
+	t.disconnectTatanka(peerID)
+	return nil
+}
+
+// Unban lifts the peer's ban.
+func (t *Tatanka) Unban(peerID tanka.PeerID) error {
+	if t.banned(peerID) == nil {
+		return fmt.Errorf("%s is not banned", peerID)
+	}
+	if err := t.db.Unban(peerID); err != nil {
+		return fmt.Errorf("error deleting ban: %w", err)
+	}
+	t.banMtx.Lock()
+	delete(t.bans, peerID)
+	t.banMtx.Unlock()
+	t.log.Infof("Unbanned peer %s", peerID)
+	return nil
+}
@@ -0,0 +1,173 @@
+// This code is available on the terms of the project LICENSE.md file,
+// also available online at https://blueoakcouncil.org/license/1.0.0.
+
+package admin
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+
+	"decred.org/dcrdex/tatanka"
+	"decred.org/dcrdex/tatanka/tanka"
+	"github.com/go-chi/chi/v5"
+)
+
+const (
+	pongStr = "pong"
+	// maxBootNodeSize is the largest accepted whitelist node POST body.
+	maxBootNodeSize = 1 << 14
+	// maxBanReasonLen is the longest accepted ban reason.
+	maxBanReasonLen = 256
+)
+
+// writeJSON marshals the provided interface and writes the bytes to the
+// ResponseWriter. The response code is assumed to be StatusOK.
+func (s *Server) writeJSON(w http.ResponseWriter, thing any) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	b, err := json.MarshalIndent(thing, "", "    ")
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		s.log.Errorf("JSON encode error: %v", err)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	_, err = w.Write(append(b, byte('\n')))
+	if err != nil {
+		s.log.Errorf("Write error: %v", err)
+	}
+}
+
+// decodePeerID decodes the hex-encoded peer ID.
+func decodePeerID(peerIDStr string) (peerID tanka.PeerID, err error) {
+	if len(peerIDStr) != tanka.PeerIDLength*2 {
+		return peerID, fmt.Errorf("peer ID has incorrect length %d", len(peerIDStr))
+	}
+	if _, err = hex.Decode(peerID[:], []byte(peerIDStr)); err != nil {
+		return peerID, fmt.Errorf("could not decode peer ID: %w", err)
+	}
+	return peerID, nil
+}
+
+// apiPing is the handler for the '/ping' API request.
+func (s *Server) apiPing(w http.ResponseWriter, _ *http.Request) {
+	s.writeJSON(w, pongStr)
+}
+
+// apiClients is the handler for the '/clients' API request.
+func (s *Server) apiClients(w http.ResponseWriter, _ *http.Request) {
+	s.writeJSON(w, s.core.Clients())
+}
+
+// apiTatankas is the handler for the '/tatankas' API request.
+func (s *Server) apiTatankas(w http.ResponseWriter, _ *http.Request) {
+	s.writeJSON(w, s.core.Tatankas())
+}
+
+// apiTopics is the handler for the '/topics' API request.
+func (s *Server) apiTopics(w http.ResponseWriter, _ *http.Request) {
+	s.writeJSON(w, s.core.Topics())
+}
+
+// apiPeer is the handler for the '/peer/{peerIDKey}' API request.
+func (s *Server) apiPeer(w http.ResponseWriter, r *http.Request) {
+	peerID, err := decodePeerID(chi.URLParam(r, peerIDKey))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	peerInfo, err := s.core.PeerInfo(peerID)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("failed to get peer info: %v", err), http.StatusInternalServerError)
+		return
+	}
+	s.writeJSON(w, peerInfo)
+}
+
+// apiBan is the handler for the '/peer/{peerIDKey}/ban?reason=REASON' API
+// request.
+func (s *Server) apiBan(w http.ResponseWriter, r *http.Request) {
+	peerID, err := decodePeerID(chi.URLParam(r, peerIDKey))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	reason := r.URL.Query().Get(reasonKey)
+	if len(reason) > maxBanReasonLen {
+		http.Error(w, fmt.Sprintf("ban reason longer than %d characters", maxBanReasonLen), http.StatusBadRequest)
+		return
+	}
+	if err := s.core.Ban(peerID, reason); err != nil {
+		http.Error(w, fmt.Sprintf("failed to ban peer: %v", err), http.StatusBadRequest)
+		return
+	}
+	s.writeJSON(w, true)
+}
+
+// apiUnban is the handler for the '/peer/{peerIDKey}/unban' API request.
+func (s *Server) apiUnban(w http.ResponseWriter, r *http.Request) {
+	peerID, err := decodePeerID(chi.URLParam(r, peerIDKey))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err := s.core.Unban(peerID); err != nil {
+		http.Error(w, fmt.Sprintf("failed to unban peer: %v", err), http.StatusBadRequest)
+		return
+	}
+	s.writeJSON(w, true)
+}
+
+// apiBans is the handler for the '/bans' API request.
+func (s *Server) apiBans(w http.ResponseWriter, _ *http.Request) {
+	s.writeJSON(w, s.core.Bans())
+}
+
+// apiWhitelist is the handler for the GET '/whitelist' API request.
+func (s *Server) apiWhitelist(w http.ResponseWriter, _ *http.Request) {
+	s.writeJSON(w, s.core.Whitelist())
+}
+
+// apiAddWhitelistNode is the handler for the POST '/whitelist' API request.
+// The body is a JSON-encoded tatanka.BootNode. The node stays whitelisted
+// across restarts, even if it is not in the configured whitelist.
+func (s *Server) apiAddWhitelistNode(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxBootNodeSize+1))
+	r.Body.Close()
+	if err != nil {
+		http.Error(w, fmt.Sprintf("unable to read request body: %v", err), http.StatusInternalServerError)
+		return
+	}
+	if len(body) > maxBootNodeSize {
+		http.Error(w, "boot node too large", http.StatusBadRequest)
+		return
+	}
+	var n tatanka.BootNode
+	if err := json.Unmarshal(body, &n); err != nil {
+		http.Error(w, fmt.Sprintf("unable to parse boot node: %v", err), http.StatusBadRequest)
+		return
+	}
+	if err := s.core.AddWhitelistNode(&n); err != nil {
+		http.Error(w, fmt.Sprintf("failed to whitelist node: %v", err), http.StatusBadRequest)
+		return
+	}
+	s.writeJSON(w, true)
+}
+
+// apiRemoveWhitelistNode is the handler for the DELETE
+// '/whitelist/{peerIDKey}' API request. The node stays removed across
+// restarts, even if it is in the configured whitelist.
+func (s *Server) apiRemoveWhitelistNode(w http.ResponseWriter, r *http.Request) {
+	peerID, err := decodePeerID(chi.URLParam(r, peerIDKey))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err := s.core.RemoveWhitelistNode(peerID); err != nil {
+		http.Error(w, fmt.Sprintf("failed to remove node: %v", err), http.StatusBadRequest)
+		return
+	}
+	s.writeJSON(w, true)
+}
@@ -0,0 +1,199 @@
+// This code is available on the terms of the project LICENSE.md file,
+// also available online at https://blueoakcouncil.org/license/1.0.0.
+
+// Package admin provides a password protected https server to administer a
+// running tatanka node.
+package admin
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"decred.org/dcrdex/dex"
+	"decred.org/dcrdex/tatanka"
+	"decred.org/dcrdex/tatanka/tanka"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+)
+
+const (
+	// rpcTimeoutSeconds is the number of seconds a connection to the
+	// server is allowed to stay open without authenticating before it
+	// is closed.
+	rpcTimeoutSeconds = 10
+
+	peerIDKey = "peer"
+	reasonKey = "reason"
+)
+
+// TatankaCore is satisfied by *tatanka.Tatanka.
+type TatankaCore interface {
+	Clients() []*tatanka.ClientInfo
+	Tatankas() []*tatanka.TatankaInfo
+	Topics() []*tatanka.TopicInfo
+	PeerInfo(peerID tanka.PeerID) (*tatanka.PeerInfo, error)
+	Whitelist() []*tatanka.BootNode
+	AddWhitelistNode(n *tatanka.BootNode) error
+	RemoveWhitelistNode(peerID tanka.PeerID) error
+	Bans() []*tatanka.BanInfo
+	Ban(peerID tanka.PeerID, reason string) error
+	Unban(peerID tanka.PeerID) error
+}
+
+// Server is a multi-client https server.
+type Server struct {
+	core      TatankaCore
+	log       dex.Logger
+	addr      string
+	tlsConfig *tls.Config
+	srv       *http.Server
+	authSHA   [32]byte
+}
+
+// SrvConfig holds variables needed to create a new Server.
+type SrvConfig struct {
+	Core            TatankaCore
+	Logger          dex.Logger
+	Addr, Cert, Key string
+	AuthSHA         [32]byte
+	NoTLS           bool
+}
+
+// NewServer is the constructor for a new Server.
+func NewServer(cfg *SrvConfig) (*Server, error) {
+	var tlsConfig *tls.Config
+	if !cfg.NoTLS {
+		// Find the key pair.
+		if !dex.FileExists(cfg.Key) || !dex.FileExists(cfg.Cert) {
+			return nil, fmt.Errorf("missing certificates")
+		}
+		keypair, err := tls.LoadX509KeyPair(cfg.Cert, cfg.Key)
+		if err != nil {
+			return nil, err
+		}
+
+		// Prepare the TLS configuration.
+		tlsConfig = &tls.Config{
+			Certificates: []tls.Certificate{keypair},
+			MinVersion:   tls.VersionTLS12,
+		}
+	}
+
+	// Create an HTTP router.
+	mux := chi.NewRouter()
+	httpServer := &http.Server{
+		Handler:      mux,
+		ReadTimeout:  rpcTimeoutSeconds * time.Second, // slow requests should not hold connections opened
+		WriteTimeout: rpcTimeoutSeconds * time.Second, // hung responses must die
+	}
+
+	// Make the server.
+	s := &Server{
+		core:      cfg.Core,
+		log:       cfg.Logger,
+		srv:       httpServer,
+		addr:      cfg.Addr,
+		tlsConfig: tlsConfig,
+		authSHA:   cfg.AuthSHA,
+	}
+
+	// Middleware
+	mux.Use(middleware.Recoverer)
+	mux.Use(middleware.RealIP)
+	mux.Use(oneTimeConnection)
+	mux.Use(s.authMiddleware)
+
+	// api endpoints
+	mux.Route("/api", func(r chi.Router) {
+		r.Use(middleware.AllowContentType("text/plain", "application/json"))
+		r.Get("/ping", s.apiPing)
+		r.Get("/clients", s.apiClients)
+		r.Get("/tatankas", s.apiTatankas)
+		r.Get("/topics", s.apiTopics)
+		r.Route("/peer/{"+peerIDKey+"}", func(rm chi.Router) {
+			rm.Get("/", s.apiPeer)
+			rm.Post("/ban", s.apiBan)
+			rm.Post("/unban", s.apiUnban)
+		})
+		r.Get("/bans", s.apiBans)
+		r.Route("/whitelist", func(rm chi.Router) {
+			rm.Get("/", s.apiWhitelist)
+			rm.Post("/", s.apiAddWhitelistNode)
+			rm.Delete("/{"+peerIDKey+"}", s.apiRemoveWhitelistNode)
+		})
+	})
+
+	return s, nil
+}
+
+// Run starts the server.
+func (s *Server) Run(ctx context.Context) {
+	// Create listener.
+	var listener net.Listener
+	var err error
+	if s.tlsConfig != nil {
+		listener, err = tls.Listen("tcp", s.addr, s.tlsConfig)
+	} else {
+		listener, err = net.Listen("tcp", s.addr)
+	}
+	if err != nil {
+		s.log.Errorf("can't listen on %s. admin server quitting: %v", s.addr, err)
+		return
+	}
+
+	// Close the listener on context cancellation.
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		<-ctx.Done()
+
+		if err := s.srv.Shutdown(context.Background()); err != nil {
+			// Error from closing listeners:
+			s.log.Errorf("HTTP server Shutdown: %v", err)
+		}
+	}()
+	s.log.Infof("admin server listening on %s", s.addr)
+	if err := s.srv.Serve(listener); !errors.Is(err, http.ErrServerClosed) {
+		s.log.Warnf("unexpected (http.Server).Serve error: %v", err)
+	}
+
+	// Wait for Shutdown.
+	wg.Wait()
+	s.log.Infof("admin server off")
+}
+
+// oneTimeConnection sets fields in the header and request that indicate this
+// connection should not be reused.
+func oneTimeConnection(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Connection", "close")
+		r.Close = true
+		next.ServeHTTP(w, r)
+	})
+}
+
+// authMiddleware checks incoming requests for authentication.
+func (s *Server) authMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// User is ignored.
+		_, pass, ok := r.BasicAuth()
+		authSHA := sha256.Sum256([]byte(pass))
+		if !ok || subtle.ConstantTimeCompare(s.authSHA[:], authSHA[:]) != 1 {
+			s.log.Warnf("server authentication failure from ip: %s", r.RemoteAddr)
+			w.Header().Add("WWW-Authenticate", `Basic realm="tatanka admin"`)
+			http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+			return
+		}
+		s.log.Debugf("server authenticated ip: %s", r.RemoteAddr)
+		next.ServeHTTP(w, r)
+	})
+}
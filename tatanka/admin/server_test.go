@@ -0,0 +1,192 @@
+// This code is available on the terms of the project LICENSE.md file,
+// also available online at https://blueoakcouncil.org/license/1.0.0.
+
+package admin
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"decred.org/dcrdex/dex"
+	"decred.org/dcrdex/tatanka"
+	"decred.org/dcrdex/tatanka/db"
+	"decred.org/dcrdex/tatanka/tanka"
+)
+
+var _ TatankaCore = (*tatanka.Tatanka)(nil)
+
+const tPass = "pass"
+
+type TCore struct {
+	clients   []*tatanka.ClientInfo
+	peerInfo  *tatanka.PeerInfo
+	whitelist []*tatanka.BootNode
+	addErr    error
+	removeErr error
+	bans      map[tanka.PeerID]string
+	unbanErr  error
+}
+
+func (c *TCore) Clients() []*tatanka.ClientInfo   { return c.clients }
+func (c *TCore) Tatankas() []*tatanka.TatankaInfo { return nil }
+func (c *TCore) Topics() []*tatanka.TopicInfo     { return nil }
+func (c *TCore) PeerInfo(peerID tanka.PeerID) (*tatanka.PeerInfo, error) {
+	return c.peerInfo, nil
+}
+func (c *TCore) Whitelist() []*tatanka.BootNode { return c.whitelist }
+func (c *TCore) AddWhitelistNode(n *tatanka.BootNode) error {
+	if c.addErr != nil {
+		return c.addErr
+	}
+	c.whitelist = append(c.whitelist, n)
+	return nil
+}
+func (c *TCore) RemoveWhitelistNode(peerID tanka.PeerID) error { return c.removeErr }
+func (c *TCore) Bans() []*tatanka.BanInfo {
+	bans := make([]*tatanka.BanInfo, 0, len(c.bans))
+	for peerID, reason := range c.bans {
+		bans = append(bans, &tatanka.BanInfo{PeerID: peerID[:], Ban: &db.Ban{Reason: reason}})
+	}
+	return bans
+}
+func (c *TCore) Ban(peerID tanka.PeerID, reason string) error {
+	c.bans[peerID] = reason
+	return nil
+}
+func (c *TCore) Unban(peerID tanka.PeerID) error { return c.unbanErr }
+
+func tNewServer(t *testing.T) (*Server, *TCore) {
+	t.Helper()
+	core := &TCore{bans: make(map[tanka.PeerID]string)}
+	s, err := NewServer(&SrvConfig{
+		Core:    core,
+		Logger:  dex.StdOutLogger("T", dex.LevelTrace),
+		Addr:    "127.0.0.1:0",
+		AuthSHA: sha256.Sum256([]byte(tPass)),
+		NoTLS:   true,
+	})
+	if err != nil {
+		t.Fatalf("NewServer error: %v", err)
+	}
+	return s, core
+}
+
+func (s *Server) tRequest(t *testing.T, method, path, body string, expCode int) *httptest.ResponseRecorder {
+	t.Helper()
+	r, _ := http.NewRequest(method, "http://localhost/api"+path, strings.NewReader(body))
+	r.RemoteAddr = "localhost"
+	r.SetBasicAuth("", tPass)
+	if body != "" {
+		r.Header.Set("Content-Type", "application/json")
+	}
+	w := httptest.NewRecorder()
+	s.srv.Handler.ServeHTTP(w, r)
+	if w.Code != expCode {
+		t.Fatalf("%s %s returned code %d, expected %d. body = %q", method, path, w.Code, expCode, w.Body.String())
+	}
+	return w
+}
+
+func TestAuth(t *testing.T) {
+	s, _ := tNewServer(t)
+	for _, pass := range []string{"", "wrong"} {
+		r, _ := http.NewRequest(http.MethodGet, "http://localhost/api/ping", nil)
+		r.RemoteAddr = "localhost"
+		if pass != "" {
+			r.SetBasicAuth("", pass)
+		}
+		w := httptest.NewRecorder()
+		s.srv.Handler.ServeHTTP(w, r)
+		if w.Code != http.StatusUnauthorized {
+			t.Fatalf("expected unauthorized for password %q, got code %d", pass, w.Code)
+		}
+	}
+	if w := s.tRequest(t, http.MethodGet, "/ping", "", http.StatusOK); w.Body.String() != "\"pong\"\n" {
+		t.Fatalf("wrong ping response %q", w.Body.String())
+	}
+}
+
+func TestClients(t *testing.T) {
+	s, core := tNewServer(t)
+	core.clients = []*tatanka.ClientInfo{{PeerID: []byte{0x01}, BondTier: 2, Tier: 1, Topics: []tanka.Topic{"market"}}}
+	w := s.tRequest(t, http.MethodGet, "/clients", "", http.StatusOK)
+	var clients []*tatanka.ClientInfo
+	if err := json.Unmarshal(w.Body.Bytes(), &clients); err != nil {
+		t.Fatalf("error unmarshaling clients: %v", err)
+	}
+	if len(clients) != 1 || clients[0].BondTier != 2 || clients[0].Topics[0] != "market" {
+		t.Fatalf("wrong clients")
+	}
+}
+
+func TestPeer(t *testing.T) {
+	s, core := tNewServer(t)
+	var peerID tanka.PeerID
+	peerID[0] = 0x02
+	peerIDStr := hex.EncodeToString(peerID[:])
+	core.peerInfo = &tatanka.PeerInfo{PeerID: peerID[:], Tier: 3}
+
+	w := s.tRequest(t, http.MethodGet, "/peer/"+peerIDStr, "", http.StatusOK)
+	var peerInfo tatanka.PeerInfo
+	if err := json.Unmarshal(w.Body.Bytes(), &peerInfo); err != nil {
+		t.Fatalf("error unmarshaling peer info: %v", err)
+	}
+	if peerInfo.Tier != 3 {
+		t.Fatalf("wrong tier %d", peerInfo.Tier)
+	}
+
+	// Bad peer IDs.
+	s.tRequest(t, http.MethodGet, "/peer/abcd", "", http.StatusBadRequest)
+	s.tRequest(t, http.MethodGet, "/peer/"+strings.Repeat("z", tanka.PeerIDLength*2), "", http.StatusBadRequest)
+
+	// Ban and unban.
+	s.tRequest(t, http.MethodPost, "/peer/"+peerIDStr+"/ban?reason=spam", "", http.StatusOK)
+	if core.bans[peerID] != "spam" {
+		t.Fatalf("peer not banned")
+	}
+	s.tRequest(t, http.MethodPost, "/peer/"+peerIDStr+"/ban?reason="+strings.Repeat("a", maxBanReasonLen+1), "", http.StatusBadRequest)
+	w = s.tRequest(t, http.MethodGet, "/bans", "", http.StatusOK)
+	var bans []*tatanka.BanInfo
+	if err := json.Unmarshal(w.Body.Bytes(), &bans); err != nil {
+		t.Fatalf("error unmarshaling bans: %v", err)
+	}
+	if len(bans) != 1 || bans[0].Reason != "spam" {
+		t.Fatalf("wrong bans")
+	}
+	s.tRequest(t, http.MethodPost, "/peer/"+peerIDStr+"/unban", "", http.StatusOK)
+	core.unbanErr = errors.New("test error")
+	s.tRequest(t, http.MethodPost, "/peer/"+peerIDStr+"/unban", "", http.StatusBadRequest)
+}
+
+func TestWhitelist(t *testing.T) {
+	s, core := tNewServer(t)
+	var peerID tanka.PeerID
+	peerID[0] = 0x03
+	peerIDStr := hex.EncodeToString(peerID[:])
+
+	body := `{"protocol":"wss","peerID":"` + peerIDStr + `","config":{"url":"wss://127.0.0.1:7232"}}`
+	s.tRequest(t, http.MethodPost, "/whitelist", body, http.StatusOK)
+	w := s.tRequest(t, http.MethodGet, "/whitelist", "", http.StatusOK)
+	var whitelist []*tatanka.BootNode
+	if err := json.Unmarshal(w.Body.Bytes(), &whitelist); err != nil {
+		t.Fatalf("error unmarshaling whitelist: %v", err)
+	}
+	if len(whitelist) != 1 || whitelist[0].Protocol != "wss" || hex.EncodeToString(whitelist[0].PeerID) != peerIDStr {
+		t.Fatalf("wrong whitelist")
+	}
+
+	s.tRequest(t, http.MethodPost, "/whitelist", "{", http.StatusBadRequest)
+	s.tRequest(t, http.MethodPost, "/whitelist", `"`+strings.Repeat("a", maxBootNodeSize)+`"`, http.StatusBadRequest)
+	core.addErr = errors.New("test error")
+	s.tRequest(t, http.MethodPost, "/whitelist", body, http.StatusBadRequest)
+
+	s.tRequest(t, http.MethodDelete, "/whitelist/"+peerIDStr, "", http.StatusOK)
+	core.removeErr = errors.New("test error")
+	s.tRequest(t, http.MethodDelete, "/whitelist/"+peerIDStr, "", http.StatusBadRequest)
+}
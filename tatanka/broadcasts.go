@@ -0,0 +1,198 @@
+// This code is available on the terms of the project LICENSE.md file,
+// also available online at https://blueoakcouncil.org/license/1.0.0.
+
+package tatanka
+
+import (
+	"sync"
+	"time"
+
+	"decred.org/dcrdex/tatanka/mj"
+	"decred.org/dcrdex/tatanka/tanka"
+)
+
+const (
+	// broadcastLogSize is the maximum number of broadcasts kept per subject.
+	broadcastLogSize = 512
+	// maxLoggedSubjects is the maximum number of subjects logged. When a new
+	// subject would exceed the limit, the subject with the oldest last
+	// broadcast is forgotten.
+	maxLoggedSubjects = 1024
+	// broadcastLogAge is how long broadcasts are kept. Orders older than
+	// MaxOrderLifetime are expired, so there's no point in replaying them.
+	broadcastLogAge = tanka.MaxOrderLifetime
+)
+
+type subjectKey struct {
+	topic   tanka.Topic
+	subject tanka.Subject
+}
+
+// subjectLog is the log of recent broadcasts for a subject.
+type subjectLog struct {
+	bcasts []*mj.Broadcast
+	// pruned and prunedStamp are the sequence number and stamp of the last
+	// broadcast removed from the log.
+	pruned      uint64
+	prunedStamp time.Time
+}
+
+func (sl *subjectLog) dropFirst(n int) {
+	last := sl.bcasts[n-1]
+	sl.pruned, sl.prunedStamp = last.Seq, last.Stamp
+	sl.bcasts = append(sl.bcasts[:0:0], sl.bcasts[n:]...)
+}
+
+// broadcastLog is a bounded log of recent broadcasts, kept so that
+// subscribers can catch up on broadcasts they missed while offline.
+type broadcastLog struct {
+	mtx sync.Mutex
+	// seq is the last assigned sequence number. Sequence numbers are shared
+	// by all subjects, so they increase across restarts of the log, and the
+	// gaps for a subject mean nothing.
+	seq      uint64
+	subjects map[subjectKey]*subjectLog
+	// forgotten and forgottenStamp are the latest pruned sequence number and
+	// stamp across all subjects that have been removed from the log. Before
+	// anything is logged, they mark the start of the log.
+	forgotten      uint64
+	forgottenStamp time.Time
+}
+
+func newBroadcastLog() *broadcastLog {
+	// Start the sequence at the current time so that the sequence numbers
+	// after a restart are greater than any assigned before.
+	now := time.Now()
+	seq := uint64(now.UnixMicro())
+	return &broadcastLog{
+		seq:            seq,
+		subjects:       make(map[subjectKey]*subjectLog),
+		forgotten:      seq,
+		forgottenStamp: now,
+	}
+}
+
+// isLoggable is true for broadcasts that are replayed to new subscribers.
+// Subscription management broadcasts are not logged.
+func isLoggable(bcast *mj.Broadcast) bool {
+	switch bcast.MessageType {
+	case mj.MessageTypeNewSubscriber, mj.MessageTypeUnsubTopic, mj.MessageTypeUnsubSubject:
+		return false
+	}
+	return true
+}
+
+// add logs a copy of the broadcast with the next sequence number, returning
+// the copy.
+func (l *broadcastLog) add(bcast *mj.Broadcast) *mj.Broadcast {
+	b := *bcast
+	k := subjectKey{bcast.Topic, bcast.Subject}
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+	sl, found := l.subjects[k]
+	if !found {
+		if len(l.subjects) >= maxLoggedSubjects {
+			l.forgetOldest()
+		}
+		sl = &subjectLog{pruned: l.forgotten, prunedStamp: l.forgottenStamp}
+		l.subjects[k] = sl
+	}
+	l.seq++
+	b.Seq = l.seq
+	sl.bcasts = append(sl.bcasts, &b)
+	if len(sl.bcasts) > broadcastLogSize {
+		sl.dropFirst(len(sl.bcasts) - broadcastLogSize)
+	}
+	return &b
+}
+
+// replay returns the logged broadcasts for the subject requested by the
+// ReplayRequest.
+func (l *broadcastLog) replay(topic tanka.Topic, subject tanka.Subject, req *mj.ReplayRequest) *mj.SubscriptionReplay {
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+	r := &mj.SubscriptionReplay{
+		Seq:        l.seq,
+		Broadcasts: make([]*mj.Broadcast, 0),
+	}
+	sl, found := l.subjects[subjectKey{topic, subject}]
+	if !found {
+		sl = &subjectLog{pruned: l.forgotten, prunedStamp: l.forgottenStamp}
+	}
+	switch {
+	case req.Seq > l.seq:
+		// Not one of ours. Send everything.
+		r.Broadcasts = append(r.Broadcasts, sl.bcasts...)
+	case req.Seq > 0:
+		r.Complete = req.Seq >= sl.pruned
+		for _, b := range sl.bcasts {
+			if b.Seq > req.Seq {
+				r.Broadcasts = append(r.Broadcasts, b)
+			}
+		}
+	default:
+		r.Complete = !sl.prunedStamp.After(req.Since)
+		for _, b := range sl.bcasts {
+			if b.Stamp.After(req.Since) {
+				r.Broadcasts = append(r.Broadcasts, b)
+			}
+		}
+	}
+	return r
+}
+
+// prune removes broadcasts older than broadcastLogAge. Subjects with no
+// remaining broadcasts are removed.
+func (l *broadcastLog) prune() {
+	cutoff := time.Now().Add(-broadcastLogAge)
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+	for k, sl := range l.subjects {
+		var n int
+		for n < len(sl.bcasts) && sl.bcasts[n].Stamp.Before(cutoff) {
+			n++
+		}
+		if n > 0 {
+			sl.dropFirst(n)
+		}
+		if len(sl.bcasts) == 0 {
+			l.forget(k, sl)
+		}
+	}
+}
+
+// forget removes the subject from the log. Replays for the subject will be
+// incomplete for anything before the subject's last broadcast. forget must be
+// called with the mtx locked.
+func (l *broadcastLog) forget(k subjectKey, sl *subjectLog) {
+	if n := len(sl.bcasts); n > 0 {
+		sl.dropFirst(n)
+	}
+	delete(l.subjects, k)
+	if sl.pruned > l.forgotten {
+		l.forgotten = sl.pruned
+	}
+	if sl.prunedStamp.After(l.forgottenStamp) {
+		l.forgottenStamp = sl.prunedStamp
+	}
+}
+
+// forgetOldest forgets the subject with the oldest last broadcast. forgetOldest
+// must be called with the mtx locked.
+func (l *broadcastLog) forgetOldest() {
+	var oldestKey subjectKey
+	var oldest *subjectLog
+	var oldestSeq uint64
+	for k, sl := range l.subjects {
+		seq := sl.pruned
+		if n := len(sl.bcasts); n > 0 {
+			seq = sl.bcasts[n-1].Seq
+		}
+		if oldest == nil || seq < oldestSeq {
+			oldestKey, oldest, oldestSeq = k, sl, seq
+		}
+	}
+	if oldest != nil {
+		l.forget(oldestKey, oldest)
+	}
+}
@@ -35,8 +35,15 @@ type Chain interface {
 	// Query(context.Context, Query) (Result, error)
 	Connected() bool
 	CheckBond(*tanka.Bond) error
-	// AuditHTLC will be needed if Tatanka nodes are the auditors.
-	// AuditHTLC(*tanka.HTLCAudit) (bool, error)
+}
+
+// HTLCAuditor is an optional interface that should be implemented by backends
+// that can verify swap contracts on-chain. Tatanka nodes use the audits to
+// resolve swap disputes between peers. AuditHTLC returns false with a nil error
+// if the contract does not satisfy the audit, and an error if the audit could
+// not be performed.
+type HTLCAuditor interface {
+	AuditHTLC(*tanka.HTLCAudit) (bool, error)
 }
 
 // FeeRater is an optional interface that should be implemented by backends for
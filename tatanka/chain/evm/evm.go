@@ -0,0 +1,400 @@
+// This code is available on the terms of the project LICENSE.md file,
+// also available online at https://blueoakcouncil.org/license/1.0.0.
+
+package evm
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"decred.org/dcrdex/dex"
+	dexeth "decred.org/dcrdex/dex/networks/eth"
+	swapv0 "decred.org/dcrdex/dex/networks/eth/contracts/v0"
+	"decred.org/dcrdex/tatanka/chain"
+	"decred.org/dcrdex/tatanka/tanka"
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethclient"
+)
+
+/*
+	The evm package is the Tatanka node backend for Ethereum and ERC20 tokens.
+
+	Bonds are posted to the version 0 swap contract, so no additional contract
+	needs to be deployed. A bond is a swap initiation whose participant is the
+	peer's bond address, as returned by BondAddress. The bond address is
+	derived from the peer ID and no private key is known for it, so the swap
+	can't be redeemed, and the peer can only get the bonded funds back with a
+	refund after the swap's lock time. The bond's CoinID is the swap's 32-byte
+	secret hash, which the peer can choose freely.
+
+	Swap contracts are audited against the version 0 swap contract too. The
+	HTLCAudit Contract is the version 0 contract data, as encoded by
+	dexeth.EncodeContractData.
+*/
+
+var feeMonitorTick = time.Second * 10
+
+// BondAddress is the swap participant address for the peer's bonds. It is the
+// last 20 bytes of the keccak256 hash of the peer ID.
+func BondAddress(peerID tanka.PeerID) common.Address {
+	return common.BytesToAddress(crypto.Keccak256(peerID[:]))
+}
+
+func init() {
+	chain.RegisterChainConstructor(dexeth.EthBipID, NewEthereum)
+	for tokenID, token := range dexeth.Tokens {
+		if token.ParentID != dexeth.EthBipID {
+			continue
+		}
+		chain.RegisterChainConstructor(tokenID, tokenConstructor(tokenID))
+	}
+}
+
+// ConfigFile is the configuration for an EVM chain.
+type ConfigFile struct {
+	// RPCURL is the http, websocket or IPC endpoint of the node.
+	RPCURL string `json:"rpcurl"`
+	// BondAmount is the bond value, in atomic units, required per unit of
+	// bond strength. If zero, any positive bond value is accepted.
+	BondAmount uint64 `json:"bondAmount"`
+	// TokenAddress overrides the token contract address for ERC20 chains,
+	// e.g. for simnet, where the token is deployed by the harness.
+	TokenAddress string `json:"tokenAddress"`
+	// SwapContract overrides the version 0 swap contract address used for
+	// bonds and HTLC audits.
+	SwapContract string `json:"swapContract"`
+}
+
+// ethClient is the subset of *ethclient.Client methods used by the backend.
+type ethClient interface {
+	ChainID(ctx context.Context) (*big.Int, error)
+	HeaderByNumber(ctx context.Context, number *big.Int) (*types.Header, error)
+	SuggestGasTipCap(ctx context.Context) (*big.Int, error)
+	CallContract(ctx context.Context, call ethereum.CallMsg, blockNumber *big.Int) ([]byte, error)
+	Close()
+}
+
+type evmChain struct {
+	cfg     *ConfigFile
+	net     dex.Network
+	log     dex.Logger
+	fees    chan uint64
+	assetID uint32
+	// tokenAddr is the zero address for ETH.
+	tokenAddr common.Address
+	// swapContract is the version 0 swap contract address, or the zero
+	// address if unknown.
+	swapContract common.Address
+	// toAtomic converts a contract value to atomic units.
+	toAtomic func(*big.Int) uint64
+
+	// clMtx guards ctx and cl, which are set by Connect.
+	clMtx     sync.RWMutex
+	ctx       context.Context
+	cl        ethClient
+	connected atomic.Bool
+}
+
+// NewEthereum is the ChainConstructor for Ethereum.
+func NewEthereum(rawConfig json.RawMessage, log dex.Logger, net dex.Network) (chain.Chain, error) {
+	return newEVMChain(dexeth.EthBipID, rawConfig, log, net)
+}
+
+// tokenConstructor creates a ChainConstructor for the ERC20 token.
+func tokenConstructor(tokenID uint32) chain.ChainConstructor {
+	return func(rawConfig json.RawMessage, log dex.Logger, net dex.Network) (chain.Chain, error) {
+		return newEVMChain(tokenID, rawConfig, log, net)
+	}
+}
+
+func newEVMChain(assetID uint32, rawConfig json.RawMessage, log dex.Logger, net dex.Network) (*evmChain, error) {
+	var cfg ConfigFile
+	if err := json.Unmarshal(rawConfig, &cfg); err != nil {
+		return nil, fmt.Errorf("error parsing configuration: %w", err)
+	}
+	if cfg.RPCURL == "" {
+		return nil, errors.New("no rpcurl provided")
+	}
+	c := &evmChain{
+		cfg:      &cfg,
+		net:      net,
+		log:      log,
+		fees:     make(chan uint64, 1),
+		assetID:  assetID,
+		toAtomic: dexeth.WeiToGwei,
+	}
+	if cfg.SwapContract != "" {
+		if !common.IsHexAddress(cfg.SwapContract) {
+			return nil, fmt.Errorf("invalid swap contract address %q", cfg.SwapContract)
+		}
+		c.swapContract = common.HexToAddress(cfg.SwapContract)
+	}
+	if assetID == dexeth.EthBipID {
+		if c.swapContract == (common.Address{}) {
+			c.swapContract = dexeth.ContractAddresses[0][net]
+		}
+		return c, nil
+	}
+	token, found := dexeth.Tokens[assetID]
+	if !found {
+		return nil, fmt.Errorf("unknown token %d", assetID)
+	}
+	switch {
+	case cfg.TokenAddress != "":
+		if !common.IsHexAddress(cfg.TokenAddress) {
+			return nil, fmt.Errorf("invalid token address %q", cfg.TokenAddress)
+		}
+		c.tokenAddr = common.HexToAddress(cfg.TokenAddress)
+	case token.NetTokens[net] != nil:
+		c.tokenAddr = token.NetTokens[net].Address
+	}
+	if netToken := token.NetTokens[net]; c.swapContract == (common.Address{}) && netToken != nil {
+		if sc := netToken.SwapContracts[0]; sc != nil {
+			c.swapContract = sc.Address
+		}
+	}
+	if c.tokenAddr == (common.Address{}) {
+		return nil, fmt.Errorf("no %s token address for %s", token.Name, net)
+	}
+	c.toAtomic = token.EVMToAtomic
+	return c, nil
+}
+
+func (c *evmChain) Connect(ctx context.Context) (*sync.WaitGroup, error) {
+	cl, err := ethclient.DialContext(ctx, c.cfg.RPCURL)
+	if err != nil {
+		return nil, fmt.Errorf("error connecting to %s: %w", c.cfg.RPCURL, err)
+	}
+	c.clMtx.Lock()
+	c.cl = cl
+	c.ctx = ctx
+	c.clMtx.Unlock()
+
+	if err := c.initialize(ctx); err != nil {
+		cl.Close()
+		return nil, err
+	}
+
+	c.connected.Store(true)
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		defer cl.Close()
+		defer c.connected.Store(false)
+		c.monitorFees(ctx)
+	}()
+
+	return &wg, nil
+}
+
+func (c *evmChain) initialize(ctx context.Context) error {
+	chainID, err := c.cl.ChainID(ctx)
+	if err != nil {
+		return fmt.Errorf("error getting chain ID: %w", err)
+	}
+	if wantChainID := dexeth.ChainIDs[c.net]; chainID.Cmp(big.NewInt(wantChainID)) != 0 {
+		return fmt.Errorf("wrong chain ID %s, expected %d", chainID, wantChainID)
+	}
+	if _, err := c.cl.HeaderByNumber(ctx, nil); err != nil {
+		return fmt.Errorf("error getting best header: %w", err)
+	}
+	return nil
+}
+
+// client returns the RPC client and the Connect context. It is an error to
+// use the client before Connect.
+func (c *evmChain) client() (ethClient, context.Context, error) {
+	c.clMtx.RLock()
+	defer c.clMtx.RUnlock()
+	if c.cl == nil {
+		return nil, nil, errors.New("not connected")
+	}
+	return c.cl, c.ctx, nil
+}
+
+func (c *evmChain) Connected() bool {
+	return c.connected.Load()
+}
+
+func (c *evmChain) FeeChannel() <-chan uint64 {
+	return c.fees
+}
+
+// feeRate is the max fee rate in gwei/gas, with the base fee doubled to
+// accommodate base fee increases in the next few blocks.
+func (c *evmChain) feeRate(ctx context.Context, hdr *types.Header) (uint64, error) {
+	if hdr.BaseFee == nil {
+		return 0, errors.New("no base fee in header")
+	}
+	tip, err := c.cl.SuggestGasTipCap(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("error getting tip cap: %w", err)
+	}
+	feeRate := new(big.Int).Add(tip, new(big.Int).Mul(hdr.BaseFee, big.NewInt(2)))
+	gweiFeeRate := dexeth.WeiToGweiCeil(feeRate)
+	if gweiFeeRate == 0 {
+		gweiFeeRate = 1
+	}
+	return gweiFeeRate, nil
+}
+
+func (c *evmChain) monitorFees(ctx context.Context) {
+	tick := time.NewTicker(feeMonitorTick)
+	defer tick.Stop()
+	var tip *big.Int
+	for {
+		select {
+		case <-tick.C:
+		case <-ctx.Done():
+			return
+		}
+
+		hdr, err := c.cl.HeaderByNumber(ctx, nil)
+		if err != nil {
+			c.connected.Store(false)
+			c.log.Errorf("Error getting best header: %v", err)
+			continue
+		}
+		c.connected.Store(true)
+		if tip != nil && tip.Cmp(hdr.Number) == 0 {
+			continue
+		}
+		tip = hdr.Number
+
+		feeRate, err := c.feeRate(ctx, hdr)
+		if err != nil {
+			c.log.Errorf("Error getting fee rate: %v", err)
+			continue
+		}
+		select {
+		case c.fees <- feeRate:
+		case <-time.After(time.Second * 5):
+			c.log.Errorf("fee channel is blocking")
+		}
+	}
+}
+
+// swap gets the state of the version 0 swap with the secret hash.
+func (c *evmChain) swap(secretHash [32]byte) (*dexeth.SwapState, error) {
+	if c.swapContract == (common.Address{}) {
+		return nil, errors.New("no swap contract address")
+	}
+	cl, ctx, err := c.client()
+	if err != nil {
+		return nil, err
+	}
+	swapABI := dexeth.ABIs[0]
+	data, err := swapABI.Pack("swap", secretHash)
+	if err != nil {
+		return nil, fmt.Errorf("error packing swap call: %w", err)
+	}
+	ctx, cancel := context.WithTimeout(ctx, time.Second*10)
+	defer cancel()
+	res, err := cl.CallContract(ctx, ethereum.CallMsg{To: &c.swapContract, Data: data}, nil)
+	if err != nil {
+		return nil, fmt.Errorf("error calling swap contract: %w", err)
+	}
+	outs, err := swapABI.Unpack("swap", res)
+	if err != nil {
+		return nil, fmt.Errorf("error unpacking swap: %w", err)
+	}
+	if len(outs) != 1 {
+		return nil, fmt.Errorf("expected 1 swap output, got %d", len(outs))
+	}
+	swap, ok := abi.ConvertType(outs[0], new(swapv0.ETHSwapSwap)).(*swapv0.ETHSwapSwap)
+	if !ok {
+		return nil, errors.New("unexpected swap output type")
+	}
+	return dexeth.SwapStateFromV0(swap), nil
+}
+
+// CheckBond checks that the bond is an unspent swap in this asset's swap
+// contract that pays the bonding peer's bond address, is large enough for the
+// bond strength, and is locked until at least the bond expiration.
+func (c *evmChain) CheckBond(b *tanka.Bond) error {
+	if b.AssetID != c.assetID {
+		return fmt.Errorf("wrong asset ID %d, expected %d", b.AssetID, c.assetID)
+	}
+	if len(b.CoinID) != 32 {
+		return fmt.Errorf("invalid bond ID length %d", len(b.CoinID))
+	}
+	var secretHash [32]byte
+	copy(secretHash[:], b.CoinID)
+	swap, err := c.swap(secretHash)
+	if err != nil {
+		return err
+	}
+	switch swap.State {
+	case dexeth.SSInitiated:
+	case dexeth.SSNone:
+		return fmt.Errorf("bond %s not found", b.CoinID)
+	default:
+		return fmt.Errorf("bond %s is spent", b.CoinID)
+	}
+	if swap.Participant != BondAddress(b.PeerID) {
+		return errors.New("bond is for a different peer")
+	}
+	if atoms, required := c.toAtomic(swap.Value), b.Strength*c.cfg.BondAmount; atoms == 0 || atoms < required {
+		return fmt.Errorf("bond value %d is less than the %d required for strength %d", atoms, required, b.Strength)
+	}
+	if swap.LockTime.Unix() < b.Expiration.Unix() {
+		return fmt.Errorf("bond lock time %s is before the bond expiration %s", swap.LockTime, b.Expiration)
+	}
+	return nil
+}
+
+// AuditHTLC checks that the swap contract has a swap for the secret hash that
+// pays at least the audited value to the recipient and cannot be refunded
+// before the audited lock time. Swaps that have since been redeemed or refunded
+// pass the audit.
+func (c *evmChain) AuditHTLC(a *tanka.HTLCAudit) (bool, error) {
+	if a.AssetID != c.assetID {
+		return false, fmt.Errorf("wrong asset ID %d, expected %d", a.AssetID, c.assetID)
+	}
+	secretHash, err := dexeth.DecodeContractDataV0(a.Contract)
+	if err != nil {
+		c.log.Debugf("HTLC audit failed: %v", err)
+		return false, nil
+	}
+	swap, err := c.swap(secretHash)
+	if err != nil {
+		return false, err
+	}
+	if err := c.checkSwap(a, secretHash, swap); err != nil {
+		c.log.Debugf("HTLC audit failed for %x: %v", secretHash, err)
+		return false, nil
+	}
+	return true, nil
+}
+
+func (c *evmChain) checkSwap(a *tanka.HTLCAudit, secretHash [32]byte, swap *dexeth.SwapState) error {
+	if swap.State == dexeth.SSNone {
+		return errors.New("swap not found")
+	}
+	if !bytes.Equal(secretHash[:], a.SecretHash) {
+		return fmt.Errorf("contract secret hash %x, expected %x", secretHash, a.SecretHash)
+	}
+	if !common.IsHexAddress(a.Recipient) || swap.Participant != common.HexToAddress(a.Recipient) {
+		return fmt.Errorf("swap pays %s, expected %s", swap.Participant, a.Recipient)
+	}
+	if atoms := c.toAtomic(swap.Value); atoms < a.Value {
+		return fmt.Errorf("swap value %d is less than the %d expected", atoms, a.Value)
+	}
+	if !a.LockTime.IsZero() && swap.LockTime.Before(a.LockTime) {
+		return fmt.Errorf("swap lock time %s is before %s", swap.LockTime, a.LockTime)
+	}
+	return nil
+}
@@ -0,0 +1,57 @@
+//go:build harness
+
+package evm
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"decred.org/dcrdex/dex"
+	"decred.org/dcrdex/dex/encode"
+	"decred.org/dcrdex/tatanka/tanka"
+)
+
+// TestSimnetETH requires the geth dev chain from dex/testing/eth/harness.sh.
+func TestSimnetETH(t *testing.T) {
+	feeMonitorTick = time.Second
+
+	rawCfg, _ := json.Marshal(&ConfigFile{RPCURL: "ws://127.0.0.1:38557"})
+	c, err := NewEthereum(rawCfg, dex.StdOutLogger("T", dex.LevelTrace), dex.Simnet)
+	if err != nil {
+		t.Fatalf("NewEthereum error: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	cm := dex.NewConnectionMaster(c)
+	if err := cm.ConnectOnce(ctx); err != nil {
+		t.Fatalf("Connect error: %v", err)
+	}
+
+	fmt.Println("Waiting for fee update")
+	select {
+	case fees := <-c.(*evmChain).FeeChannel():
+		fmt.Println("Fees received over fee channel =", fees)
+	case <-time.After(time.Second * 30):
+		t.Fatalf("No fee update seen. Is the miner running?")
+	}
+
+	// The harness deploys the version 0 swap contract, but there is no swap
+	// for a random secret hash.
+	var peerID tanka.PeerID
+	copy(peerID[:], encode.RandomBytes(tanka.PeerIDLength))
+	err = c.CheckBond(&tanka.Bond{
+		PeerID:     peerID,
+		AssetID:    60,
+		CoinID:     encode.RandomBytes(32),
+		Strength:   1,
+		Expiration: time.Now().Add(time.Hour),
+	})
+	if err == nil || !strings.Contains(err.Error(), "not found") {
+		t.Fatalf("expected not found error for unknown bond, got %v", err)
+	}
+}
@@ -0,0 +1,394 @@
+package evm
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"testing"
+	"time"
+
+	"decred.org/dcrdex/dex"
+	"decred.org/dcrdex/dex/encode"
+	dexeth "decred.org/dcrdex/dex/networks/eth"
+	swapv0 "decred.org/dcrdex/dex/networks/eth/contracts/v0"
+	"decred.org/dcrdex/tatanka/tanka"
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+const usdcID = 60001
+
+var tLogger = dex.StdOutLogger("T", dex.LevelTrace)
+
+type tClient struct {
+	chainID  int64
+	hdr      *types.Header
+	tip      *big.Int
+	tipErr   error
+	callRes  []byte
+	callErr  error
+	lastCall ethereum.CallMsg
+}
+
+func (c *tClient) ChainID(context.Context) (*big.Int, error) {
+	return big.NewInt(c.chainID), nil
+}
+
+func (c *tClient) HeaderByNumber(context.Context, *big.Int) (*types.Header, error) {
+	return c.hdr, nil
+}
+
+func (c *tClient) SuggestGasTipCap(context.Context) (*big.Int, error) {
+	return c.tip, c.tipErr
+}
+
+func (c *tClient) CallContract(_ context.Context, call ethereum.CallMsg, _ *big.Int) ([]byte, error) {
+	c.lastCall = call
+	return c.callRes, c.callErr
+}
+
+func (c *tClient) Close() {}
+
+func newTestChain(t *testing.T, assetID uint32, cfg *ConfigFile) (*evmChain, *tClient) {
+	t.Helper()
+	rawCfg, _ := json.Marshal(cfg)
+	c, err := newEVMChain(assetID, rawCfg, tLogger, dex.Simnet)
+	if err != nil {
+		t.Fatalf("newEVMChain error: %v", err)
+	}
+	cl := &tClient{
+		chainID: dexeth.SimnetChainID,
+		hdr:     &types.Header{Number: big.NewInt(1), BaseFee: dexeth.GweiToWei(10)},
+		tip:     dexeth.GweiToWei(2),
+	}
+	c.cl = cl
+	c.ctx = context.Background()
+	return c, cl
+}
+
+func packSwap(t *testing.T, swap *swapv0.ETHSwapSwap) []byte {
+	t.Helper()
+	b, err := dexeth.ABIs[0].Methods["swap"].Outputs.Pack(swap)
+	if err != nil {
+		t.Fatalf("error packing swap: %v", err)
+	}
+	return b
+}
+
+func TestNewEVMChain(t *testing.T) {
+	tokenAddr := common.BytesToAddress(encode.RandomBytes(20))
+	tests := []struct {
+		name    string
+		assetID uint32
+		cfg     *ConfigFile
+		wantErr bool
+	}{{
+		name:    "eth",
+		assetID: dexeth.EthBipID,
+		cfg:     &ConfigFile{RPCURL: "ws://127.0.0.1:38557"},
+	}, {
+		name:    "token with address",
+		assetID: usdcID,
+		cfg:     &ConfigFile{RPCURL: "ws://127.0.0.1:38557", TokenAddress: tokenAddr.String()},
+	}, {
+		name:    "no rpc url",
+		assetID: dexeth.EthBipID,
+		cfg:     &ConfigFile{},
+		wantErr: true,
+	}, {
+		name:    "bad swap contract",
+		assetID: dexeth.EthBipID,
+		cfg:     &ConfigFile{RPCURL: "ws://127.0.0.1:38557", SwapContract: "0x1234"},
+		wantErr: true,
+	}, {
+		name:    "bad token address",
+		assetID: usdcID,
+		cfg:     &ConfigFile{RPCURL: "ws://127.0.0.1:38557", TokenAddress: "abc"},
+		wantErr: true,
+	}}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			rawCfg, _ := json.Marshal(test.cfg)
+			c, err := newEVMChain(test.assetID, rawCfg, tLogger, dex.Simnet)
+			if test.wantErr {
+				if err == nil {
+					t.Fatal("expected error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if test.assetID != dexeth.EthBipID && c.tokenAddr != tokenAddr {
+				t.Fatalf("wrong token address %s", c.tokenAddr)
+			}
+		})
+	}
+}
+
+func TestCheckBond(t *testing.T) {
+	const bondAmount = 1e6
+	swapContract := common.BytesToAddress(encode.RandomBytes(20))
+	tokenAddr := common.BytesToAddress(encode.RandomBytes(20))
+	var peerID tanka.PeerID
+	copy(peerID[:], encode.RandomBytes(tanka.PeerIDLength))
+	expiration := time.Now().Add(time.Hour)
+
+	newBond := func(assetID uint32) *tanka.Bond {
+		return &tanka.Bond{
+			PeerID:     peerID,
+			AssetID:    assetID,
+			CoinID:     encode.RandomBytes(32),
+			Strength:   2,
+			Expiration: expiration,
+		}
+	}
+	newSwap := func() *swapv0.ETHSwapSwap {
+		return &swapv0.ETHSwapSwap{
+			Value:                dexeth.GweiToWei(2 * bondAmount),
+			InitBlockNumber:      big.NewInt(1),
+			RefundBlockTimestamp: big.NewInt(expiration.Unix()),
+			Initiator:            common.BytesToAddress(encode.RandomBytes(20)),
+			Participant:          BondAddress(peerID),
+			State:                uint8(dexeth.SSInitiated),
+		}
+	}
+
+	tests := []struct {
+		name         string
+		assetID      uint32
+		bond         *tanka.Bond
+		swap         func(*swapv0.ETHSwapSwap)
+		callErr      error
+		disconnected bool
+		wantErr      bool
+	}{{
+		name:    "ok eth",
+		assetID: dexeth.EthBipID,
+	}, {
+		// USDC has 6 decimals and an EVMFactor of 0.
+		name:    "ok token",
+		assetID: usdcID,
+		swap:    func(s *swapv0.ETHSwapSwap) { s.Value = big.NewInt(2 * bondAmount) },
+	}, {
+		name:    "wrong asset",
+		assetID: dexeth.EthBipID,
+		bond:    newBond(usdcID),
+		wantErr: true,
+	}, {
+		name:    "bad coin ID",
+		assetID: dexeth.EthBipID,
+		bond:    &tanka.Bond{PeerID: peerID, AssetID: dexeth.EthBipID, CoinID: encode.RandomBytes(31)},
+		wantErr: true,
+	}, {
+		name:         "not connected",
+		assetID:      dexeth.EthBipID,
+		disconnected: true,
+		wantErr:      true,
+	}, {
+		name:    "call error",
+		assetID: dexeth.EthBipID,
+		callErr: errors.New("test error"),
+		wantErr: true,
+	}, {
+		name:    "not found",
+		assetID: dexeth.EthBipID,
+		swap:    func(s *swapv0.ETHSwapSwap) { s.State = uint8(dexeth.SSNone) },
+		wantErr: true,
+	}, {
+		name:    "refunded",
+		assetID: dexeth.EthBipID,
+		swap:    func(s *swapv0.ETHSwapSwap) { s.State = uint8(dexeth.SSRefunded) },
+		wantErr: true,
+	}, {
+		name:    "wrong peer",
+		assetID: dexeth.EthBipID,
+		swap:    func(s *swapv0.ETHSwapSwap) { s.Participant = common.Address{0x01} },
+		wantErr: true,
+	}, {
+		name:    "value too low",
+		assetID: dexeth.EthBipID,
+		swap:    func(s *swapv0.ETHSwapSwap) { s.Value = dexeth.GweiToWei(2*bondAmount - 1) },
+		wantErr: true,
+	}, {
+		name:    "lock time too early",
+		assetID: dexeth.EthBipID,
+		swap:    func(s *swapv0.ETHSwapSwap) { s.RefundBlockTimestamp = big.NewInt(expiration.Unix() - 1) },
+		wantErr: true,
+	}}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c, cl := newTestChain(t, test.assetID, &ConfigFile{
+				RPCURL:       "ws://127.0.0.1:38557",
+				BondAmount:   bondAmount,
+				TokenAddress: tokenAddr.String(),
+				SwapContract: swapContract.String(),
+			})
+			if test.disconnected {
+				c.cl = nil
+			}
+			swap := newSwap()
+			if test.swap != nil {
+				test.swap(swap)
+			}
+			cl.callRes = packSwap(t, swap)
+			cl.callErr = test.callErr
+			bond := test.bond
+			if bond == nil {
+				bond = newBond(test.assetID)
+			}
+			err := c.CheckBond(bond)
+			if test.wantErr {
+				if err == nil {
+					t.Fatal("expected error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if *cl.lastCall.To != swapContract {
+				t.Fatalf("called wrong contract %s", cl.lastCall.To)
+			}
+		})
+	}
+}
+
+func TestAuditHTLC(t *testing.T) {
+	swapContract := common.BytesToAddress(encode.RandomBytes(20))
+	participant := common.BytesToAddress(encode.RandomBytes(20))
+	secretHash := encode.RandomBytes(32)
+	lockTime := time.Now().Add(time.Hour).Truncate(time.Second)
+	const value = 1e6 // gwei
+
+	newAudit := func() *tanka.HTLCAudit {
+		return &tanka.HTLCAudit{
+			AssetID:    dexeth.EthBipID,
+			Contract:   dexeth.EncodeContractData(0, secretHash),
+			Recipient:  participant.String(),
+			Value:      value,
+			SecretHash: secretHash,
+			LockTime:   lockTime,
+		}
+	}
+	newSwap := func() *swapv0.ETHSwapSwap {
+		return &swapv0.ETHSwapSwap{
+			Value:                dexeth.GweiToWei(value),
+			InitBlockNumber:      big.NewInt(1),
+			RefundBlockTimestamp: big.NewInt(lockTime.Unix()),
+			Initiator:            common.BytesToAddress(encode.RandomBytes(20)),
+			Participant:          participant,
+			State:                uint8(dexeth.SSInitiated),
+		}
+	}
+
+	tests := []struct {
+		name      string
+		audit     func(*tanka.HTLCAudit)
+		swap      func(*swapv0.ETHSwapSwap)
+		callErr   error
+		wantValid bool
+		wantErr   bool
+	}{{
+		name:      "ok",
+		wantValid: true,
+	}, {
+		name:      "ok redeemed",
+		swap:      func(s *swapv0.ETHSwapSwap) { s.State = uint8(dexeth.SSRedeemed) },
+		wantValid: true,
+	}, {
+		name:    "wrong asset",
+		audit:   func(a *tanka.HTLCAudit) { a.AssetID = usdcID },
+		wantErr: true,
+	}, {
+		name:    "call error",
+		callErr: errors.New("test error"),
+		wantErr: true,
+	}, {
+		name:  "wrong contract version",
+		audit: func(a *tanka.HTLCAudit) { a.Contract = dexeth.EncodeContractData(1, secretHash) },
+	}, {
+		name: "not found",
+		swap: func(s *swapv0.ETHSwapSwap) { s.State = uint8(dexeth.SSNone) },
+	}, {
+		name:  "wrong secret hash",
+		audit: func(a *tanka.HTLCAudit) { a.SecretHash = encode.RandomBytes(32) },
+	}, {
+		name: "wrong participant",
+		swap: func(s *swapv0.ETHSwapSwap) { s.Participant = common.Address{0x01} },
+	}, {
+		name: "value too low",
+		swap: func(s *swapv0.ETHSwapSwap) { s.Value = dexeth.GweiToWei(value - 1) },
+	}, {
+		name: "lock time too early",
+		swap: func(s *swapv0.ETHSwapSwap) { s.RefundBlockTimestamp = big.NewInt(lockTime.Unix() - 1) },
+	}}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c, cl := newTestChain(t, dexeth.EthBipID, &ConfigFile{
+				RPCURL:       "ws://127.0.0.1:38557",
+				SwapContract: swapContract.String(),
+			})
+			swap := newSwap()
+			if test.swap != nil {
+				test.swap(swap)
+			}
+			cl.callRes = packSwap(t, swap)
+			cl.callErr = test.callErr
+			audit := newAudit()
+			if test.audit != nil {
+				test.audit(audit)
+			}
+			valid, err := c.AuditHTLC(audit)
+			if test.wantErr {
+				if err == nil {
+					t.Fatal("expected error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if valid != test.wantValid {
+				t.Fatalf("wanted valid = %t, got %t", test.wantValid, valid)
+			}
+			if valid && *cl.lastCall.To != swapContract {
+				t.Fatalf("called wrong contract %s", cl.lastCall.To)
+			}
+		})
+	}
+}
+
+func TestFeeRate(t *testing.T) {
+	c, cl := newTestChain(t, dexeth.EthBipID, &ConfigFile{RPCURL: "ws://127.0.0.1:38557"})
+	ctx := context.Background()
+
+	feeRate, err := c.feeRate(ctx, cl.hdr)
+	if err != nil {
+		t.Fatalf("feeRate error: %v", err)
+	}
+	// 2 * base fee + tip
+	if feeRate != 22 {
+		t.Fatalf("wanted fee rate 22, got %d", feeRate)
+	}
+
+	cl.tipErr = errors.New("test error")
+	if _, err := c.feeRate(ctx, cl.hdr); err == nil {
+		t.Fatalf("no error for tip cap error")
+	}
+	cl.tipErr = nil
+
+	if _, err := c.feeRate(ctx, &types.Header{Number: big.NewInt(1)}); err == nil {
+		t.Fatalf("no error for header without base fee")
+	}
+
+	if err := c.initialize(ctx); err != nil {
+		t.Fatalf("initialize error: %v", err)
+	}
+	cl.chainID = dexeth.MainnetChainID
+	if err := c.initialize(ctx); err == nil {
+		t.Fatalf("no error for wrong chain ID")
+	}
+}
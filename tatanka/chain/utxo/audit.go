@@ -0,0 +1,58 @@
+// This code is available on the terms of the project LICENSE.md file,
+// also available online at https://blueoakcouncil.org/license/1.0.0.
+
+package utxo
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"decred.org/dcrdex/tatanka/tanka"
+	"github.com/decred/dcrd/dcrjson/v4"
+)
+
+// auditTimeout is the timeout for RPC requests made during an audit.
+const auditTimeout = time.Second * 10
+
+// decodeCoinID decodes the coin ID into a tx hash and a vout.
+func decodeCoinID(coinID []byte) (txHash [32]byte, vout uint32, err error) {
+	if len(coinID) != 36 {
+		return txHash, 0, fmt.Errorf("coin ID wrong length. expected 36, got %d", len(coinID))
+	}
+	copy(txHash[:], coinID[:32])
+	return txHash, binary.BigEndian.Uint32(coinID[32:]), nil
+}
+
+// toAtoms converts the float coin value to atoms.
+func toAtoms(v float64) uint64 {
+	return uint64(math.Round(v * 1e8))
+}
+
+// isTxNotFoundErr will return true if the error indicates that the requested
+// transaction is not known to the node.
+func isTxNotFoundErr(err error) bool {
+	var rpcErr *dcrjson.RPCError
+	return errors.As(err, &rpcErr) && rpcErr.Code == dcrjson.ErrRPCNoTxInfo
+}
+
+// checkSwapDetails checks the details extracted from an on-chain swap contract
+// against the audit. A non-nil error describes the audit failure.
+func checkSwapDetails(a *tanka.HTLCAudit, receiver string, lockTime uint64, secretHash []byte, value uint64) error {
+	if receiver != a.Recipient {
+		return fmt.Errorf("contract pays %s, expected %s", receiver, a.Recipient)
+	}
+	if !bytes.Equal(secretHash, a.SecretHash) {
+		return fmt.Errorf("contract secret hash %x, expected %x", secretHash, a.SecretHash)
+	}
+	if value < a.Value {
+		return fmt.Errorf("contract value %d is less than the %d expected", value, a.Value)
+	}
+	if !a.LockTime.IsZero() && lockTime < uint64(a.LockTime.Unix()) {
+		return fmt.Errorf("contract lock time %d is before %s", lockTime, a.LockTime)
+	}
+	return nil
+}
@@ -0,0 +1,168 @@
+package utxo
+
+import (
+	"encoding/hex"
+	"testing"
+	"time"
+
+	"decred.org/dcrdex/dex/encode"
+	dexbtc "decred.org/dcrdex/dex/networks/btc"
+	dexdcr "decred.org/dcrdex/dex/networks/dcr"
+	"decred.org/dcrdex/tatanka/tanka"
+	"github.com/btcsuite/btcd/btcjson"
+	"github.com/btcsuite/btcd/btcutil"
+	btcchaincfg "github.com/btcsuite/btcd/chaincfg"
+	"github.com/decred/dcrd/chaincfg/v3"
+	chainjson "github.com/decred/dcrd/rpc/jsonrpc/types/v4"
+	"github.com/decred/dcrd/txscript/v4/stdaddr"
+)
+
+type auditTest struct {
+	name      string
+	audit     func(*tanka.HTLCAudit)
+	confs     int64
+	vout      uint32
+	value     float64
+	wrongPay  bool
+	wantValid bool
+}
+
+func auditTests() []*auditTest {
+	return []*auditTest{{
+		name:      "ok",
+		wantValid: true,
+	}, {
+		name:  "not mined",
+		confs: -1,
+	}, {
+		name: "vout out of range",
+		vout: 1,
+	}, {
+		name:     "wrong output script",
+		wrongPay: true,
+	}, {
+		name:  "wrong recipient",
+		audit: func(a *tanka.HTLCAudit) { a.Recipient = "abc" },
+	}, {
+		name:  "wrong secret hash",
+		audit: func(a *tanka.HTLCAudit) { a.SecretHash = encode.RandomBytes(32) },
+	}, {
+		name:  "value too low",
+		value: 0.009,
+	}, {
+		name:  "lock time too early",
+		audit: func(a *tanka.HTLCAudit) { a.LockTime = a.LockTime.Add(time.Second) },
+	}, {
+		name:      "zero lock time not checked",
+		audit:     func(a *tanka.HTLCAudit) { a.LockTime = time.Time{} },
+		wantValid: true,
+	}}
+}
+
+func (test *auditTest) prepare(a *tanka.HTLCAudit) (confs int64, value float64) {
+	if test.audit != nil {
+		test.audit(a)
+	}
+	confs, value = 1, 0.01
+	if test.confs < 0 {
+		confs = 0
+	}
+	if test.value != 0 {
+		value = test.value
+	}
+	return
+}
+
+func TestAuditBitcoinContract(t *testing.T) {
+	params := &btcchaincfg.RegressionNetParams
+	secretHash := encode.RandomBytes(32)
+	lockTime := time.Now().Add(time.Hour).Truncate(time.Second)
+	rAddr, _ := btcutil.NewAddressWitnessPubKeyHash(encode.RandomBytes(20), params)
+	sAddr, _ := btcutil.NewAddressWitnessPubKeyHash(encode.RandomBytes(20), params)
+	contract, err := dexbtc.MakeContract(rAddr, sAddr, secretHash, lockTime.Unix(), true, params)
+	if err != nil {
+		t.Fatalf("MakeContract error: %v", err)
+	}
+
+	c := &bitcoinChain{}
+	for _, test := range auditTests() {
+		a := &tanka.HTLCAudit{
+			Contract:   contract,
+			Recipient:  rAddr.String(),
+			Value:      1e6,
+			SecretHash: secretHash,
+			LockTime:   lockTime,
+		}
+		confs, value := test.prepare(a)
+		pkScript := p2wshScript(contract, params)
+		if test.wrongPay {
+			pkScript = p2shScript(encode.RandomBytes(20), params)
+		}
+		tx := &btcjson.TxRawResult{
+			Confirmations: uint64(confs),
+			Vout: []btcjson.Vout{{
+				Value:        value,
+				ScriptPubKey: btcjson.ScriptPubKeyResult{Hex: hex.EncodeToString(pkScript)},
+			}},
+		}
+		err := c.auditContractOutput(a, tx, test.vout, params)
+		if valid := err == nil; valid != test.wantValid {
+			t.Fatalf("%s: wanted valid = %t, got error %v", test.name, test.wantValid, err)
+		}
+	}
+}
+
+func TestAuditDecredContract(t *testing.T) {
+	params := chaincfg.SimNetParams()
+	secretHash := encode.RandomBytes(32)
+	lockTime := time.Now().Add(time.Hour).Truncate(time.Second)
+	rAddr, _ := stdaddr.NewAddressPubKeyHashEcdsaSecp256k1V0(encode.RandomBytes(20), params)
+	sAddr, _ := stdaddr.NewAddressPubKeyHashEcdsaSecp256k1V0(encode.RandomBytes(20), params)
+	contract, err := dexdcr.MakeContract(rAddr.String(), sAddr.String(), secretHash, lockTime.Unix(), params)
+	if err != nil {
+		t.Fatalf("MakeContract error: %v", err)
+	}
+	contractAddr, _ := stdaddr.NewAddressScriptHashV0(contract, params)
+	_, contractScript := contractAddr.PaymentScript()
+
+	for _, test := range auditTests() {
+		a := &tanka.HTLCAudit{
+			Contract:   contract,
+			Recipient:  rAddr.String(),
+			Value:      1e6,
+			SecretHash: secretHash,
+			LockTime:   lockTime,
+		}
+		confs, value := test.prepare(a)
+		pkScript := contractScript
+		if test.wrongPay {
+			otherAddr, _ := stdaddr.NewAddressScriptHashV0(encode.RandomBytes(20), params)
+			_, pkScript = otherAddr.PaymentScript()
+		}
+		tx := &chainjson.TxRawResult{
+			Confirmations: confs,
+			Vout: []chainjson.Vout{{
+				Value:        value,
+				ScriptPubKey: chainjson.ScriptPubKeyResult{Hex: hex.EncodeToString(pkScript)},
+			}},
+		}
+		err := auditDecredContractOutput(a, tx, test.vout, params)
+		if valid := err == nil; valid != test.wantValid {
+			t.Fatalf("%s: wanted valid = %t, got error %v", test.name, test.wantValid, err)
+		}
+	}
+}
+
+func TestDecodeCoinID(t *testing.T) {
+	coinID := append(encode.RandomBytes(32), 0, 0, 0, 5)
+	txHash, vout, err := decodeCoinID(coinID)
+	if err != nil {
+		t.Fatalf("decodeCoinID error: %v", err)
+	}
+	if vout != 5 || string(txHash[:]) != string(coinID[:32]) {
+		t.Fatalf("wrong decoded coin ID %x:%d", txHash, vout)
+	}
+	if _, _, err := decodeCoinID(coinID[1:]); err == nil {
+		t.Fatal("no error for short coin ID")
+	}
+}
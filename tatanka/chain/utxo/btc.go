@@ -4,7 +4,10 @@
 package utxo
 
 import (
+	"bytes"
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -20,7 +23,9 @@ import (
 	"decred.org/dcrdex/tatanka/tanka"
 	"github.com/btcsuite/btcd/btcjson"
 	"github.com/btcsuite/btcd/btcutil"
+	"github.com/btcsuite/btcd/chaincfg"
 	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/txscript"
 	chainjson "github.com/decred/dcrd/rpc/jsonrpc/types/v4"
 	"github.com/decred/dcrd/rpcclient/v8"
 )
@@ -46,6 +51,7 @@ type bitcoinChain struct {
 	fees chan uint64
 	name string
 
+	ctx       context.Context
 	cl        *rpcclient.Client
 	connected atomic.Bool
 }
@@ -82,6 +88,7 @@ func (c *bitcoinChain) Connect(ctx context.Context) (_ *sync.WaitGroup, err erro
 	if err != nil {
 		return nil, fmt.Errorf("error connecting RPC client: %w", err)
 	}
+	c.ctx = ctx
 
 	if err = c.initialize(ctx); err != nil {
 		return nil, err
@@ -316,11 +323,92 @@ func (c *bitcoinChain) CheckBond(b *tanka.Bond) error {
 	return nil
 }
 
-func (c *bitcoinChain) AuditHTLC(*tanka.HTLCAudit) (bool, error) {
+// AuditHTLC checks that the contract output exists in a mined transaction and
+// pays to the P2SH or P2WSH script of a swap contract that satisfies the
+// audit.
+func (c *bitcoinChain) AuditHTLC(a *tanka.HTLCAudit) (bool, error) {
+	txHash, vout, err := decodeCoinID(a.CoinID)
+	if err != nil {
+		c.log.Debugf("HTLC audit failed: %v", err)
+		return false, nil
+	}
+	params, err := netParams(c.net)
+	if err != nil {
+		return false, err
+	}
+	ctx, cancel := context.WithTimeout(c.ctx, auditTimeout)
+	defer cancel()
+	var tx btcjson.TxRawResult
+	if err := c.call(ctx, "getrawtransaction", []any{chainhash.Hash(txHash).String(), true}, &tx); err != nil {
+		if isTxNotFoundErr(err) {
+			c.log.Debugf("HTLC audit failed: transaction %s not found", chainhash.Hash(txHash))
+			return false, nil
+		}
+		return false, fmt.Errorf("error getting transaction: %w", err)
+	}
+	if err := c.auditContractOutput(a, &tx, vout, params); err != nil {
+		c.log.Debugf("HTLC audit failed for %s:%d: %v", tx.Txid, vout, err)
+		return false, nil
+	}
+	return true, nil
+}
+
+func (c *bitcoinChain) auditContractOutput(a *tanka.HTLCAudit, tx *btcjson.TxRawResult, vout uint32, params *chaincfg.Params) error {
+	if tx.Confirmations == 0 {
+		return errors.New("transaction is not mined")
+	}
+	if int(vout) >= len(tx.Vout) {
+		return fmt.Errorf("vout %d out of range", vout)
+	}
+	out := tx.Vout[vout]
+	pkScript, err := hex.DecodeString(out.ScriptPubKey.Hex)
+	if err != nil {
+		return fmt.Errorf("error decoding pubkey script: %w", err)
+	}
+	var segwit bool
+	switch {
+	case bytes.Equal(pkScript, p2shScript(a.Contract, params)):
+	case bytes.Equal(pkScript, p2wshScript(a.Contract, params)):
+		segwit = true
+	default:
+		return errors.New("output does not pay to the contract")
+	}
+	_, receiver, lockTime, secretHash, err := dexbtc.ExtractSwapDetails(a.Contract, segwit, params)
+	if err != nil {
+		return fmt.Errorf("error parsing contract: %w", err)
+	}
+	return checkSwapDetails(a, receiver.String(), lockTime, secretHash, toAtoms(out.Value))
+}
+
+func p2shScript(contract []byte, params *chaincfg.Params) []byte {
+	addr, err := btcutil.NewAddressScriptHash(contract, params)
+	if err != nil {
+		return nil
+	}
+	pkScript, _ := txscript.PayToAddrScript(addr)
+	return pkScript
+}
 
-	// TODO: Perform the audit
+func p2wshScript(contract []byte, params *chaincfg.Params) []byte {
+	scriptHash := sha256.Sum256(contract)
+	addr, err := btcutil.NewAddressWitnessScriptHash(scriptHash[:], params)
+	if err != nil {
+		return nil
+	}
+	pkScript, _ := txscript.PayToAddrScript(addr)
+	return pkScript
+}
 
-	return true, nil
+func netParams(net dex.Network) (*chaincfg.Params, error) {
+	switch net {
+	case dex.Mainnet:
+		return &chaincfg.MainNetParams, nil
+	case dex.Testnet:
+		return &chaincfg.TestNet3Params, nil
+	case dex.Regtest:
+		return &chaincfg.RegressionNetParams, nil
+	}
+	return nil, fmt.Errorf("unknown network %s", net)
 }
 
 // isMethodNotFoundErr will return true if the error indicates that the RPC
@@ -4,7 +4,9 @@
 package utxo
 
 import (
+	"bytes"
 	"context"
+	"encoding/hex"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -15,12 +17,15 @@ import (
 	"time"
 
 	"decred.org/dcrdex/dex"
+	dexdcr "decred.org/dcrdex/dex/networks/dcr"
 	"decred.org/dcrdex/tatanka/chain"
 	"decred.org/dcrdex/tatanka/tanka"
 	"github.com/decred/dcrd/chaincfg/chainhash"
+	"github.com/decred/dcrd/chaincfg/v3"
 	"github.com/decred/dcrd/dcrutil/v4"
 	chainjson "github.com/decred/dcrd/rpc/jsonrpc/types/v4"
 	"github.com/decred/dcrd/rpcclient/v8"
+	"github.com/decred/dcrd/txscript/v4/stdaddr"
 	"github.com/decred/dcrd/wire"
 )
 
@@ -53,6 +58,7 @@ type decredChain struct {
 	log  dex.Logger
 	fees chan uint64
 
+	ctx       context.Context
 	cl        *rpcclient.Client
 	connected atomic.Bool
 }
@@ -80,6 +86,7 @@ func (c *decredChain) Connect(ctx context.Context) (_ *sync.WaitGroup, err error
 	if err != nil {
 		return nil, fmt.Errorf("error connecting RPC client: %w", err)
 	}
+	c.ctx = ctx
 
 	if err = c.initialize(ctx); err != nil {
 		return nil, err
@@ -251,11 +258,73 @@ func (c *decredChain) CheckBond(b *tanka.Bond) error {
 	return nil
 }
 
-func (c *decredChain) AuditHTLC(*tanka.HTLCAudit) (bool, error) {
+// AuditHTLC checks that the contract output exists in a mined transaction and
+// pays to the P2SH script of a swap contract that satisfies the audit.
+func (c *decredChain) AuditHTLC(a *tanka.HTLCAudit) (bool, error) {
+	txHash, vout, err := decodeCoinID(a.CoinID)
+	if err != nil {
+		c.log.Debugf("HTLC audit failed: %v", err)
+		return false, nil
+	}
+	params, err := dcrNetParams(c.net)
+	if err != nil {
+		return false, err
+	}
+	ctx, cancel := context.WithTimeout(c.ctx, auditTimeout)
+	defer cancel()
+	h := chainhash.Hash(txHash)
+	tx, err := c.cl.GetRawTransactionVerbose(ctx, &h)
+	if err != nil {
+		if isTxNotFoundErr(err) {
+			c.log.Debugf("HTLC audit failed: transaction %s not found", h)
+			return false, nil
+		}
+		return false, fmt.Errorf("error getting transaction: %w", err)
+	}
+	if err := auditDecredContractOutput(a, tx, vout, params); err != nil {
+		c.log.Debugf("HTLC audit failed for %s:%d: %v", h, vout, err)
+		return false, nil
+	}
+	return true, nil
+}
 
-	// TODO: Perform the audit
+func auditDecredContractOutput(a *tanka.HTLCAudit, tx *chainjson.TxRawResult, vout uint32, params *chaincfg.Params) error {
+	if tx.Confirmations == 0 {
+		return errors.New("transaction is not mined")
+	}
+	if int(vout) >= len(tx.Vout) {
+		return fmt.Errorf("vout %d out of range", vout)
+	}
+	out := tx.Vout[vout]
+	pkScript, err := hex.DecodeString(out.ScriptPubKey.Hex)
+	if err != nil {
+		return fmt.Errorf("error decoding pubkey script: %w", err)
+	}
+	addr, err := stdaddr.NewAddressScriptHashV0(a.Contract, params)
+	if err != nil {
+		return fmt.Errorf("error creating contract address: %w", err)
+	}
+	scriptVer, contractScript := addr.PaymentScript()
+	if out.Version != scriptVer || !bytes.Equal(pkScript, contractScript) {
+		return errors.New("output does not pay to the contract")
+	}
+	_, receiver, lockTime, secretHash, err := dexdcr.ExtractSwapDetails(a.Contract, params)
+	if err != nil {
+		return fmt.Errorf("error parsing contract: %w", err)
+	}
+	return checkSwapDetails(a, receiver.String(), lockTime, secretHash, toAtoms(out.Value))
+}
 
-	return true, nil
+func dcrNetParams(net dex.Network) (*chaincfg.Params, error) {
+	switch net {
+	case dex.Mainnet:
+		return chaincfg.MainNetParams(), nil
+	case dex.Testnet:
+		return chaincfg.TestNet3Params(), nil
+	case dex.Simnet:
+		return chaincfg.SimNetParams(), nil
+	}
+	return nil, fmt.Errorf("unknown network %s", net)
 }
 
 // connectNodeRPC attempts to create a new websocket connection to a dcrd node
@@ -28,6 +28,7 @@ const (
 	ErrPeerNeedsReconnect = dex.ErrorKind("peer needs reconnect")
 	ErrTankaError         = dex.ErrorKind("tanka error")
 	ErrBadPeerResponse    = dex.ErrorKind("bad peer response")
+	ErrPeerNotConnected   = dex.ErrorKind("peer not connected")
 )
 
 // NetworkBackend represents a peer's communication protocol.
@@ -165,6 +166,10 @@ type MessageHandlers struct {
 	HandleTatankaRequest      func(tanka.PeerID, *msgjson.Message) *msgjson.Error
 	HandleTatankaNotification func(tanka.PeerID, *msgjson.Message)
 	HandlePeerMessage         func(tanka.PeerID, *IncomingTankagram) *msgjson.Error
+	// HandleTatankaReconnect is optional, and is called after the connection
+	// to a tatanka node is re-established. The tatanka node will have
+	// forgotten us, so we need to Auth again.
+	HandleTatankaReconnect func(tanka.PeerID)
 }
 
 // Config is the configuration for the MeshConn.
@@ -416,6 +421,12 @@ func (c *MeshConn) addTatankaNode(ctx context.Context, creds *TatankaCredentials
 		HandleMessage: func(msg *msgjson.Message) {
 			c.handleTatankaMessage(peerID, msg)
 		},
+		HandleReconnect: func() {
+			if c.handlers.HandleTatankaReconnect != nil {
+				// Don't block the connection's reconnect loop.
+				go c.handlers.HandleTatankaReconnect(peerID)
+			}
+		},
 	})
 	if err != nil {
 		return fmt.Errorf("error creating connection: %w", err)
@@ -758,7 +769,7 @@ func (c *MeshConn) RequestPeer(peerID tanka.PeerID, msg *msgjson.Message, thing
 	p, known := c.peers[peerID]
 	c.peersMtx.RUnlock()
 	if !known {
-		return fmt.Errorf("not connected to peer %s", peerID)
+		return fmt.Errorf("%w: %s", ErrPeerNotConnected, peerID)
 	}
 
 	payload, err := json.Marshal(msg)
@@ -12,11 +12,13 @@ import (
 	"sync"
 	"time"
 
+	"decred.org/dcrdex/client/asset"
 	"decred.org/dcrdex/dex"
 	"decred.org/dcrdex/dex/fiatrates"
 	"decred.org/dcrdex/dex/lexi"
 	"decred.org/dcrdex/dex/msgjson"
 	"decred.org/dcrdex/tatanka/client/conn"
+	"decred.org/dcrdex/tatanka/client/swap"
 	"decred.org/dcrdex/tatanka/mj"
 	"decred.org/dcrdex/tatanka/tanka"
 	"github.com/decred/dcrd/dcrec/secp256k1/v4"
@@ -28,6 +30,12 @@ type Config struct {
 	PrivateKey *secp256k1.PrivateKey
 	Logger     dex.Logger
 	EntryNode  *TatankaCredentials
+	// Wallets is optional. If provided, swaps are executed for accepted
+	// matches of our orders and our match proposals.
+	Wallets func(assetID uint32) (asset.Wallet, error)
+	// SwapConfs is optional, and returns the number of confirmations required
+	// for a counterparty's swap contract. See swap.Config.
+	SwapConfs func(assetID uint32) uint32
 }
 
 // Mesh is a manager for operations on the Tatanka Mesh Network.
@@ -44,12 +52,24 @@ type Mesh struct {
 	db        *lexi.DB
 	dbCM      *dex.ConnectionMaster
 	bondTable *lexi.Table
+	swaps     *swap.Engine
 
 	marketsMtx sync.RWMutex
 	markets    map[string]*market
 
 	fiatRatesMtx sync.RWMutex
 	fiatRates    map[string]*fiatrates.FiatRateInfo
+
+	reputationsMtx sync.Mutex
+	reputations    map[tanka.PeerID]*cachedReputation
+}
+
+// reputationCacheExpiry is how long a peer's reputation is cached.
+const reputationCacheExpiry = time.Minute
+
+type cachedReputation struct {
+	*tanka.Reputation
+	stamp time.Time
 }
 
 // New is the constructor for a new Mesh.
@@ -62,20 +82,39 @@ func New(cfg *Config) (*Mesh, error) {
 	}
 
 	mesh := &Mesh{
-		priv:      cfg.PrivateKey,
-		peerID:    peerID,
-		log:       cfg.Logger,
-		dataDir:   cfg.DataDir,
-		entryNode: cfg.EntryNode,
-		payloads:  make(chan interface{}, 128),
-		markets:   make(map[string]*market),
-		fiatRates: make(map[string]*fiatrates.FiatRateInfo),
+		priv:        cfg.PrivateKey,
+		peerID:      peerID,
+		log:         cfg.Logger,
+		dataDir:     cfg.DataDir,
+		entryNode:   cfg.EntryNode,
+		payloads:    make(chan interface{}, 128),
+		markets:     make(map[string]*market),
+		fiatRates:   make(map[string]*fiatrates.FiatRateInfo),
+		reputations: make(map[tanka.PeerID]*cachedReputation),
 	}
 
 	if err := mesh.initializeDB(); err != nil {
 		return nil, fmt.Errorf("failed to initialize database: %w", err)
 	}
 
+	if cfg.Wallets != nil {
+		var err error
+		mesh.swaps, err = swap.New(&swap.Config{
+			PrivateKey: cfg.PrivateKey,
+			DB:         mesh.db,
+			Wallets:    cfg.Wallets,
+			Peers:      (*swapMessenger)(mesh),
+			Logger:     cfg.Logger.SubLogger("SWAP"),
+			SwapConfs:  cfg.SwapConfs,
+			Notify: func(s *swap.Swap) {
+				mesh.emit(s)
+			},
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to initialize swap engine: %w", err)
+		}
+	}
+
 	return mesh, nil
 }
 
@@ -102,6 +141,9 @@ func (m *Mesh) Connect(ctx context.Context) (*sync.WaitGroup, error) {
 			HandlePeerMessage: func(peerID tanka.PeerID, msg *conn.IncomingTankagram) *msgjson.Error {
 				return m.handlePeerRequest(peerID, msg)
 			},
+			HandleTatankaReconnect: func(tatankaID tanka.PeerID) {
+				m.handleTatankaReconnect(tatankaID)
+			},
 		},
 		PrivateKey: m.priv,
 	})
@@ -113,6 +155,20 @@ func (m *Mesh) Connect(ctx context.Context) (*sync.WaitGroup, error) {
 
 	m.conn = &meshConn{mesh, meshCM}
 
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		m.pruneOrders(ctx)
+	}()
+
+	if m.swaps != nil {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			m.swaps.Run(ctx)
+		}()
+	}
+
 	wg.Add(1)
 	go func() {
 		<-dbCM.Done()
@@ -175,13 +231,94 @@ func (m *Mesh) handleTatankaNotification(peerID tanka.PeerID, msg *msgjson.Messa
 }
 
 func (m *Mesh) handlePeerRequest(peerID tanka.PeerID, msgI any) *msgjson.Error {
-	switch msgI.(type) {
+	switch msg := msgI.(type) {
 	case *conn.IncomingTankagram:
+		if m.swaps != nil && swap.IsSwapRoute(msg.Msg.Route) {
+			m.handleSwapTankagram(peerID, msg)
+			return nil
+		}
 	}
 	m.emit(msgI)
 	return nil
 }
 
+func (m *Mesh) handleSwapTankagram(peerID tanka.PeerID, gram *conn.IncomingTankagram) {
+	res, err := m.swaps.HandlePeerMessage(peerID, gram.Msg)
+	if err != nil {
+		m.log.Errorf("Error handling %s tankagram from %s: %v", gram.Msg.Route, peerID, err)
+		if err := gram.RespondErr(mj.TEEBadRequest); err != nil {
+			m.log.Errorf("Error sending %s error response to %s: %v", gram.Msg.Route, peerID, err)
+		}
+		return
+	}
+	if err := gram.Respond(res); err != nil {
+		m.log.Errorf("Error sending %s response to %s: %v", gram.Msg.Route, peerID, err)
+	}
+}
+
+// Swaps returns the active swaps. Swaps are only executed if Config.Wallets
+// was provided.
+func (m *Mesh) Swaps() []*swap.Swap {
+	if m.swaps == nil {
+		return nil
+	}
+	return m.swaps.Swaps()
+}
+
+// Reputations retrieves the mesh's view of the peers' reputations, e.g. to
+// filter counterparties with trade.DesiredTrade.Reputation. Reputations are
+// cached for a short time.
+func (m *Mesh) Reputations(peerIDs []tanka.PeerID) (map[tanka.PeerID]*tanka.Reputation, error) {
+	reps := make(map[tanka.PeerID]*tanka.Reputation, len(peerIDs))
+	var missing []tanka.PeerID
+	m.reputationsMtx.Lock()
+	for _, peerID := range peerIDs {
+		if cr, found := m.reputations[peerID]; found && time.Since(cr.stamp) < reputationCacheExpiry {
+			reps[peerID] = cr.Reputation
+		} else if _, dupe := reps[peerID]; !dupe {
+			reps[peerID] = nil
+			missing = append(missing, peerID)
+		}
+	}
+	m.reputationsMtx.Unlock()
+
+	for len(missing) > 0 {
+		n := min(len(missing), mj.MaxReputationRequest)
+		req := mj.MustRequest(mj.RouteReputation, &mj.ReputationRequest{PeerIDs: missing[:n]})
+		var res []*tanka.Reputation
+		if err := m.conn.RequestMesh(req, &res); err != nil {
+			return nil, fmt.Errorf("error requesting reputations: %w", err)
+		}
+		if len(res) != n {
+			return nil, fmt.Errorf("requested %d reputations, got %d", n, len(res))
+		}
+		now := time.Now()
+		m.reputationsMtx.Lock()
+		for i, peerID := range missing[:n] {
+			reps[peerID] = res[i]
+			m.reputations[peerID] = &cachedReputation{Reputation: res[i], stamp: now}
+		}
+		m.reputationsMtx.Unlock()
+		missing = missing[n:]
+	}
+	return reps, nil
+}
+
+// DisputeSwap asks the mesh to audit the swap's contracts on-chain. If the
+// counterparty did not fund the swap as agreed, the tatanka node records a
+// penalty against their reputation.
+func (m *Mesh) DisputeSwap(s *swap.Swap) (*mj.SwapDisputeResult, error) {
+	dispute, err := s.Dispute()
+	if err != nil {
+		return nil, err
+	}
+	var res mj.SwapDisputeResult
+	if err := m.conn.RequestMesh(mj.MustRequest(mj.RouteAuditSwap, dispute), &res); err != nil {
+		return nil, err
+	}
+	return &res, nil
+}
+
 func (m *Mesh) Broadcast(topic tanka.Topic, subject tanka.Subject, msgType mj.BroadcastMessageType, thing interface{}) error {
 	payload, err := json.Marshal(thing)
 	if err != nil {
@@ -236,34 +373,103 @@ func (m *Mesh) ActiveBonds() ([]*tanka.Bond, error) {
 	})
 }
 
+// SubscribeMarket subscribes to the market. The tatanka node's recent
+// broadcasts for the market are replayed, so the market's order book is
+// populated without waiting for orders to be re-announced.
 func (m *Mesh) SubscribeMarket(baseID, quoteID uint32) error {
 	mktName, err := dex.MarketName(baseID, quoteID)
 	if err != nil {
 		return fmt.Errorf("error constructing market name: %w", err)
 	}
 
-	req := mj.MustRequest(mj.RouteSubscribe, &mj.Subscription{
-		Topic:   mj.TopicMarket,
-		Subject: tanka.Subject(mktName),
-	})
-	mj.SignMessage(m.priv, req)
-
 	m.marketsMtx.Lock()
 	defer m.marketsMtx.Unlock()
 
-	// Only possible non-error response is `true`.
-	var ok bool
-	if err := m.conn.RequestMesh(req, &ok); err != nil {
+	if _, found := m.markets[mktName]; found {
+		return nil
+	}
+
+	replay, err := m.subscribeMarket(mktName, &mj.ReplayRequest{})
+	if err != nil {
 		return err
 	}
 
-	m.markets[mktName] = &market{
-		log:  m.log.SubLogger(mktName),
-		ords: make(map[tanka.ID40]*order),
+	mkt := &market{
+		log:     m.log.SubLogger(mktName),
+		baseID:  baseID,
+		quoteID: quoteID,
+		ords:    make(map[tanka.ID40]*order),
 	}
+	m.markets[mktName] = mkt
+	m.applyReplay(mkt, replay)
 	return nil
 }
 
+// ResyncMarket resubscribes to a subscribed market, replaying any broadcasts
+// missed since the last broadcast received. ResyncMarket is called for every
+// subscribed market after reconnecting to the mesh. If the tatanka node no longer has all of
+// the missed broadcasts, the market's orders from other peers are rebuilt
+// from the node's log.
+func (m *Mesh) ResyncMarket(baseID, quoteID uint32) error {
+	mktName, err := dex.MarketName(baseID, quoteID)
+	if err != nil {
+		return fmt.Errorf("error constructing market name: %w", err)
+	}
+
+	m.marketsMtx.Lock()
+	defer m.marketsMtx.Unlock()
+
+	mkt, found := m.markets[mktName]
+	if !found {
+		return fmt.Errorf("not subscribed to market %s", mktName)
+	}
+
+	replay, err := m.subscribeMarket(mktName, &mj.ReplayRequest{Seq: mkt.lastSeq()})
+	if err != nil {
+		return err
+	}
+	if !replay.Complete {
+		m.log.Infof("Rebuilding order book for market %s from incomplete replay", mktName)
+	}
+	m.applyReplay(mkt, replay)
+	return nil
+}
+
+// handleTatankaReconnect authenticates with the tatanka node again after a
+// reconnect, and resyncs the subscribed markets.
+func (m *Mesh) handleTatankaReconnect(tatankaID tanka.PeerID) {
+	if err := m.Auth(tatankaID); err != nil {
+		m.log.Errorf("Error authenticating with tatanka node %s after reconnect: %v", tatankaID, err)
+		return
+	}
+	m.marketsMtx.RLock()
+	mkts := make([][2]uint32, 0, len(m.markets))
+	for _, mkt := range m.markets {
+		mkts = append(mkts, [2]uint32{mkt.baseID, mkt.quoteID})
+	}
+	m.marketsMtx.RUnlock()
+	for _, mkt := range mkts {
+		if err := m.ResyncMarket(mkt[0], mkt[1]); err != nil {
+			m.log.Errorf("Error resyncing market %d-%d after reconnect: %v", mkt[0], mkt[1], err)
+		}
+	}
+}
+
+// subscribeMarket sends the market subscription with the replay request.
+func (m *Mesh) subscribeMarket(mktName string, replayReq *mj.ReplayRequest) (*mj.SubscriptionReplay, error) {
+	req := mj.MustRequest(mj.RouteSubscribe, &mj.Subscription{
+		Topic:   mj.TopicMarket,
+		Subject: tanka.Subject(mktName),
+		Replay:  replayReq,
+	})
+	mj.SignMessage(m.priv, req)
+	var replay mj.SubscriptionReplay
+	if err := m.conn.RequestMesh(req, &replay); err != nil {
+		return nil, err
+	}
+	return &replay, nil
+}
+
 func (m *Mesh) handleBroadcast(msg *msgjson.Message) {
 	var bcast mj.Broadcast
 	if err := msg.Unmarshal(&bcast); err != nil {
@@ -311,6 +517,22 @@ func (m *Mesh) RequestPeer(peerID tanka.PeerID, msg *msgjson.Message, thing inte
 
 type TatankaCredentials = conn.TatankaCredentials
 
+// swapMessenger is a swap.PeerMessenger that connects to the peer if we
+// are not already connected.
+type swapMessenger Mesh
+
+func (sm *swapMessenger) RequestPeer(peerID tanka.PeerID, msg *msgjson.Message, thing any) error {
+	m := (*Mesh)(sm)
+	err := m.RequestPeer(peerID, msg, thing)
+	if !errors.Is(err, conn.ErrPeerNotConnected) && !errors.Is(err, conn.ErrPeerNeedsReconnect) {
+		return err
+	}
+	if err := m.ConnectPeer(peerID); err != nil {
+		return fmt.Errorf("error connecting to peer %s: %w", peerID, err)
+	}
+	return m.RequestPeer(peerID, msg, thing)
+}
+
 // meshConn is our representation of the connection to the mesh network.
 type meshConn struct {
 	*conn.MeshConn
@@ -1,17 +1,35 @@
 package mesh
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"sort"
 	"sync"
 	"time"
 
 	"decred.org/dcrdex/dex"
 	"decred.org/dcrdex/dex/fiatrates"
+	"decred.org/dcrdex/tatanka/client/orderbook"
 	"decred.org/dcrdex/tatanka/mj"
 	"decred.org/dcrdex/tatanka/tanka"
 )
 
+// orderPruneInterval is how often expired orders are pruned from the markets.
+const orderPruneInterval = time.Minute
+
+// orderRenewBuffer is how long before expiration our standing orders are
+// renewed.
+const orderRenewBuffer = orderPruneInterval * 3
+
+// MarketUpdate is emitted by Next when the orders on a subscribed market
+// change. Use Book to get the current orders.
+type MarketUpdate struct {
+	BaseID  uint32
+	QuoteID uint32
+}
+
 type order struct {
 	*tanka.Order
 	oid      tanka.ID40
@@ -20,19 +38,86 @@ type order struct {
 }
 
 type market struct {
-	log dex.Logger
+	log             dex.Logger
+	baseID, quoteID uint32
 
 	ordsMtx sync.RWMutex
 	ords    map[tanka.ID40]*order
+	// seq is the sequence number of the last broadcast received from our
+	// tatanka node.
+	seq uint64
+	// books are the attached order books, which are kept in sync with ords.
+	books []orderbook.OrderBooker
+}
+
+// checkSeq records the broadcast's sequence number, returning false if the
+// broadcast has already been seen. Broadcasts without a sequence number are
+// always new.
+func (m *market) checkSeq(seq uint64) bool {
+	if seq == 0 {
+		return true
+	}
+	m.ordsMtx.Lock()
+	defer m.ordsMtx.Unlock()
+	if seq <= m.seq {
+		return false
+	}
+	m.seq = seq
+	return true
+}
+
+// lastSeq is the sequence number of the last broadcast received.
+func (m *market) lastSeq() uint64 {
+	m.ordsMtx.RLock()
+	defer m.ordsMtx.RUnlock()
+	return m.seq
+}
+
+// applyReplay applies the replayed broadcasts. If the replay is incomplete,
+// orders from other peers are discarded first, since we may have missed
+// their updates.
+func (m *Mesh) applyReplay(mkt *market, r *mj.SubscriptionReplay) {
+	mkt.ordsMtx.Lock()
+	if !r.Complete {
+		for oid, ord := range mkt.ords {
+			if ord.From != m.peerID {
+				delete(mkt.ords, oid)
+				for _, ob := range mkt.books {
+					ob.Delete(oid)
+				}
+			}
+		}
+	}
+	mkt.seq = 0
+	mkt.ordsMtx.Unlock()
+	for _, bcast := range r.Broadcasts {
+		if mkt.checkSeq(bcast.Seq) {
+			m.applyMarketBroadcast(mkt, bcast, true)
+		}
+	}
+	mkt.ordsMtx.Lock()
+	mkt.seq = max(mkt.seq, r.Seq)
+	mkt.ordsMtx.Unlock()
+	m.emitMarketUpdate(mkt)
 }
 
-func (m *market) addOrder(ord *tanka.Order) {
+// addOrder adds the order to the market, returning false if the order was
+// invalid or already known.
+func (m *market) addOrder(ord *tanka.Order) bool {
+	if err := ord.Valid(); err != nil {
+		m.log.Debugf("ignoring invalid order %s: %v", ord.ID(), err)
+		return false
+	}
+	if ord.Expired() {
+		m.log.Debugf("ignoring expired order %s", ord.ID())
+		return false
+	}
 	m.ordsMtx.Lock()
 	defer m.ordsMtx.Unlock()
 	oid := ord.ID()
 	if _, exists := m.ords[oid]; exists {
 		// ignore it then
-		return
+		return false
 	}
 	m.ords[oid] = &order{
 		Order:    ord,
@@ -40,34 +125,167 @@ func (m *market) addOrder(ord *tanka.Order) {
 		proposed: make(map[tanka.ID32]*tanka.Match),
 		accepted: make(map[tanka.ID32]*tanka.Match),
 	}
+	for _, ob := range m.books {
+		o := *ord
+		ob.Add(&o)
+	}
+	return true
+}
+
+// updateOrder updates the order's quantity and renews its expiration. An
+// update with zero quantity removes the order from the market.
+func (m *market) updateOrder(ou *tanka.OrderUpdate) error {
+	if err := ou.Valid(); err != nil {
+		return err
+	}
+	if ou.Expired() {
+		return errors.New("order update is expired")
+	}
+	m.ordsMtx.Lock()
+	defer m.ordsMtx.Unlock()
+	ord, found := m.ords[ou.ID()]
+	if !found {
+		return fmt.Errorf("unknown order %s", ou.ID())
+	}
+	if ou.Stamp.Before(ord.Stamp) {
+		return fmt.Errorf("order update stamp %s is before the current stamp %s", ou.Stamp, ord.Stamp)
+	}
+	if ou.Qty == 0 {
+		delete(m.ords, ord.oid)
+		for _, ob := range m.books {
+			ob.Delete(ord.oid)
+		}
+		return nil
+	}
+	ord.Qty = ou.Qty
+	ord.Stamp = ou.Stamp
+	ord.Expiration = ou.Expiration
+	for _, ob := range m.books {
+		if err := ob.Update(ou); err != nil {
+			m.log.Debugf("error updating attached order book: %v", err)
+		}
+	}
+	return nil
 }
 
-func (m *market) addMatchProposal(match *tanka.Match) {
+// pruneExpired deletes expired orders, along with their unaccepted match
+// proposals. The number of pruned orders is returned.
+func (m *market) pruneExpired() (n int) {
+	m.ordsMtx.Lock()
+	defer m.ordsMtx.Unlock()
+	for oid, ord := range m.ords {
+		if ord.Expired() {
+			m.log.Debugf("pruning expired order %s", oid)
+			delete(m.ords, oid)
+			for _, ob := range m.books {
+				ob.Delete(oid)
+			}
+			n++
+		}
+	}
+	return n
+}
+
+// book returns copies of the market's orders, with buys sorted high-to-low and
+// sells sorted low-to-high by rate.
+func (m *market) book() (buys, sells []*tanka.Order) {
+	m.ordsMtx.RLock()
+	for _, ord := range m.ords {
+		if ord.Qty == 0 {
+			// Fully matched, awaiting our update.
+			continue
+		}
+		o := *ord.Order
+		if o.Sell {
+			sells = append(sells, &o)
+		} else {
+			buys = append(buys, &o)
+		}
+	}
+	m.ordsMtx.RUnlock()
+	sort.Slice(buys, func(i, j int) bool { return buys[i].Rate > buys[j].Rate })
+	sort.Slice(sells, func(i, j int) bool { return sells[i].Rate < sells[j].Rate })
+	return buys, sells
+}
+
+// expiringOrders returns copies of the peer's orders that expire before the
+// deadline.
+func (m *market) expiringOrders(peerID tanka.PeerID, deadline time.Time) (ords []*tanka.Order) {
+	m.ordsMtx.RLock()
+	defer m.ordsMtx.RUnlock()
+	for _, ord := range m.ords {
+		if ord.From == peerID && ord.Expiration.Before(deadline) {
+			o := *ord.Order
+			ords = append(ords, &o)
+		}
+	}
+	return ords
+}
+
+// addMatchProposal records the match proposal, returning the proposed order.
+// A nil order is returned if the order is unknown or the proposal is already
+// known.
+func (m *market) addMatchProposal(match *tanka.Match) *tanka.Order {
 	m.ordsMtx.Lock()
 	defer m.ordsMtx.Unlock()
 	ord, found := m.ords[match.OrderID]
 	if !found {
 		m.log.Debugf("ignoring match proposal for unknown order %s", match.OrderID)
+		return nil
 	}
 	// Make sure it's not already known or accepted
 	mid := match.ID()
 	if ord.proposed[mid] != nil {
 		// Already known
-		return
+		return nil
 	}
 	if ord.accepted[mid] != nil {
 		// Already accepted
-		return
+		return nil
 	}
 	ord.proposed[mid] = match
+	return ord.Order
 }
 
-func (m *market) addMatchAcceptance(match *tanka.Match) {
+// acceptProposal accepts the proposed match for our order, deducting the
+// matched quantity from the order. A copy of the order from before the
+// deduction is returned, along with the remaining quantity.
+func (m *market) acceptProposal(match *tanka.Match) (_ *tanka.Order, remain uint64, err error) {
 	m.ordsMtx.Lock()
 	defer m.ordsMtx.Unlock()
 	ord, found := m.ords[match.OrderID]
 	if !found {
-		m.log.Debugf("ignoring match proposal for unknown order %s", match.OrderID)
+		return nil, 0, fmt.Errorf("unknown order %s", match.OrderID)
+	}
+	mid := match.ID()
+	if ord.proposed[mid] == nil {
+		return nil, 0, fmt.Errorf("unknown match proposal %s", mid)
+	}
+	if match.From == ord.From {
+		return nil, 0, errors.New("match proposed by order owner")
+	}
+	if err := match.CheckStamp(); err != nil {
+		return nil, 0, err
+	}
+	if match.Qty == 0 || match.Qty > ord.Qty || match.Qty%ord.LotSize != 0 {
+		return nil, 0, fmt.Errorf("invalid match quantity %d for order quantity %d with lot size %d", match.Qty, ord.Qty, ord.LotSize)
+	}
+	o := *ord.Order
+	delete(ord.proposed, mid)
+	ord.accepted[mid] = match
+	ord.Qty -= match.Qty
+	return &o, ord.Qty, nil
+}
+
+// addMatchAcceptance records the match acceptance, returning the matched
+// order. A nil order is returned if the order is unknown.
+func (m *market) addMatchAcceptance(match *tanka.Match) *tanka.Order {
+	m.ordsMtx.Lock()
+	defer m.ordsMtx.Unlock()
+	ord, found := m.ords[match.OrderID]
+	if !found {
+		m.log.Debugf("ignoring match acceptance for unknown order %s", match.OrderID)
+		return nil
 	}
 	// Make sure it's not already known or accepted
 	mid := match.ID()
@@ -76,9 +294,10 @@ func (m *market) addMatchAcceptance(match *tanka.Match) {
 	}
 	if ord.accepted[mid] != nil {
 		// Already accepted
-		return
+		return ord.Order
 	}
 	ord.accepted[mid] = match
+	return ord.Order
 }
 
 func (m *Mesh) handleMarketBroadcast(bcast *mj.Broadcast) {
@@ -90,6 +309,17 @@ func (m *Mesh) handleMarketBroadcast(bcast *mj.Broadcast) {
 		m.log.Debugf("received order notification for unknown market %q", mktName)
 		return
 	}
+	if !mkt.checkSeq(bcast.Seq) {
+		m.log.Tracef("ignoring already seen broadcast %d for market %s", bcast.Seq, mktName)
+		return
+	}
+	m.applyMarketBroadcast(mkt, bcast, false)
+}
+
+// applyMarketBroadcast applies the broadcast to the market. Matches are not
+// accepted or swapped for replayed broadcasts, which may be stale.
+func (m *Mesh) applyMarketBroadcast(mkt *market, bcast *mj.Broadcast, replayed bool) {
+	mktName := string(bcast.Subject)
 	switch bcast.MessageType {
 	case mj.MessageTypeTrollBox:
 		var troll mj.Troll
@@ -104,21 +334,59 @@ func (m *Mesh) handleMarketBroadcast(bcast *mj.Broadcast) {
 			m.log.Errorf("error unmarshaling new order: %v", err)
 			return
 		}
-		mkt.addOrder(&ord)
+		if ord.From != bcast.PeerID {
+			m.log.Errorf("ignoring order from %s broadcast by %s", ord.From, bcast.PeerID)
+			return
+		}
+		if mkt.addOrder(&ord) && !replayed {
+			m.emitMarketUpdate(mkt)
+		}
+	case mj.MessageTypeUpdateOrder:
+		var ou tanka.OrderUpdate
+		if err := json.Unmarshal(bcast.Payload, &ou); err != nil {
+			m.log.Errorf("error unmarshaling order update: %v", err)
+			return
+		}
+		if ou.From != bcast.PeerID {
+			m.log.Errorf("ignoring order update from %s broadcast by %s", ou.From, bcast.PeerID)
+			return
+		}
+		if err := mkt.updateOrder(&ou); err != nil {
+			m.log.Debugf("ignoring order update for %s: %v", ou.ID(), err)
+			return
+		}
+		if !replayed {
+			m.emitMarketUpdate(mkt)
+		}
 	case mj.MessageTypeProposeMatch:
 		var match tanka.Match
 		if err := json.Unmarshal(bcast.Payload, &match); err != nil {
 			m.log.Errorf("error unmarshaling match proposal: %v", err)
 			return
 		}
-		mkt.addMatchProposal(&match)
+		if match.From != bcast.PeerID {
+			m.log.Errorf("ignoring match proposal from %s broadcast by %s", match.From, bcast.PeerID)
+			return
+		}
+		ord := mkt.addMatchProposal(&match)
+		if !replayed && ord != nil && ord.From == m.peerID && m.swaps != nil {
+			m.acceptMatch(mkt, &match)
+		}
 	case mj.MessageTypeAcceptMatch:
 		var match tanka.Match
 		if err := json.Unmarshal(bcast.Payload, &match); err != nil {
 			m.log.Errorf("error unmarshaling match proposal: %v", err)
 			return
 		}
-		mkt.addMatchAcceptance(&match)
+		ord := mkt.addMatchAcceptance(&match)
+		// Only the order owner can accept a match. If we own the order, the
+		// swap was started when we accepted.
+		if replayed || ord == nil || m.swaps == nil || ord.From != bcast.PeerID || ord.From == m.peerID {
+			return
+		}
+		if err := m.swaps.Trade(ord, &match); err != nil {
+			m.log.Errorf("error starting swap for match %s: %v", match.ID(), err)
+		}
 	case mj.MessageTypeNewSubscriber:
 		var ns mj.NewSubscriber
 		if err := json.Unmarshal(bcast.Payload, &ns); err != nil {
@@ -133,6 +401,183 @@ func (m *Mesh) handleMarketBroadcast(bcast *mj.Broadcast) {
 	}
 }
 
+// acceptMatch accepts a match proposal for our order, broadcasting the
+// acceptance and the order's remaining quantity, and starts the swap.
+func (m *Mesh) acceptMatch(mkt *market, match *tanka.Match) {
+	ord, remain, err := mkt.acceptProposal(match)
+	if err != nil {
+		m.log.Errorf("not accepting match proposal %s: %v", match.ID(), err)
+		return
+	}
+	mktName, _ := dex.MarketName(ord.BaseID, ord.QuoteID)
+	if err := m.Broadcast(mj.TopicMarket, tanka.Subject(mktName), mj.MessageTypeAcceptMatch, match); err != nil {
+		m.log.Errorf("error broadcasting acceptance of match %s: %v", match.ID(), err)
+		return
+	}
+	if err := m.RenewOrder(ord, remain); err != nil {
+		m.log.Errorf("error updating order %s after match: %v", ord.ID(), err)
+	}
+	if err := m.swaps.Trade(ord, match); err != nil {
+		m.log.Errorf("error starting swap for match %s: %v", match.ID(), err)
+	}
+	m.emitMarketUpdate(mkt)
+}
+
+func (m *Mesh) emitMarketUpdate(mkt *market) {
+	m.emit(&MarketUpdate{BaseID: mkt.baseID, QuoteID: mkt.quoteID})
+}
+
+// market gets the subscribed market.
+func (m *Mesh) market(baseID, quoteID uint32) (*market, error) {
+	mktName, err := dex.MarketName(baseID, quoteID)
+	if err != nil {
+		return nil, fmt.Errorf("error constructing market name: %w", err)
+	}
+	m.marketsMtx.RLock()
+	defer m.marketsMtx.RUnlock()
+	mkt, found := m.markets[mktName]
+	if !found {
+		return nil, fmt.Errorf("not subscribed to market %s", mktName)
+	}
+	return mkt, nil
+}
+
+// AttachOrderBook keeps the order book in sync with the subscribed market.
+// The book is populated with the market's current orders, and then receives
+// the market's new orders and order updates, including those replayed by
+// ResyncMarket after a reconnect.
+func (m *Mesh) AttachOrderBook(baseID, quoteID uint32, ob orderbook.OrderBooker) error {
+	mkt, err := m.market(baseID, quoteID)
+	if err != nil {
+		return err
+	}
+	mkt.ordsMtx.Lock()
+	defer mkt.ordsMtx.Unlock()
+	for _, ord := range mkt.ords {
+		if ord.Qty == 0 {
+			continue
+		}
+		o := *ord.Order
+		ob.Add(&o)
+	}
+	mkt.books = append(mkt.books, ob)
+	return nil
+}
+
+// Book returns the current orders for the subscribed market, with buys
+// sorted high-to-low and sells sorted low-to-high by rate, as expected by
+// trade.MatchBook.
+func (m *Mesh) Book(baseID, quoteID uint32) (buys, sells []*tanka.Order, err error) {
+	mkt, err := m.market(baseID, quoteID)
+	if err != nil {
+		return nil, nil, err
+	}
+	buys, sells = mkt.book()
+	return buys, sells, nil
+}
+
+// PlaceOrder broadcasts our new standing order to the subscribed market.
+// Matches proposed for the order are accepted automatically, and the order
+// is renewed until it is filled or canceled with a zero quantity RenewOrder.
+func (m *Mesh) PlaceOrder(ord *tanka.Order) error {
+	if ord.From != m.peerID {
+		return errors.New("not our order")
+	}
+	if err := ord.Valid(); err != nil {
+		return fmt.Errorf("invalid order: %w", err)
+	}
+	mkt, err := m.market(ord.BaseID, ord.QuoteID)
+	if err != nil {
+		return err
+	}
+	mktName, _ := dex.MarketName(ord.BaseID, ord.QuoteID)
+	if err := m.Broadcast(mj.TopicMarket, tanka.Subject(mktName), mj.MessageTypeNewOrder, ord); err != nil {
+		return err
+	}
+	o := *ord
+	if mkt.addOrder(&o) {
+		m.emitMarketUpdate(mkt)
+	}
+	return nil
+}
+
+// ProposeMatch broadcasts a match proposal for qty of the standing order. If
+// the order owner accepts, the swap is started.
+func (m *Mesh) ProposeMatch(ord *tanka.Order, qty uint64) (*tanka.Match, error) {
+	if ord.From == m.peerID {
+		return nil, errors.New("cannot match our own order")
+	}
+	if qty == 0 || qty > ord.Qty || qty%ord.LotSize != 0 {
+		return nil, fmt.Errorf("invalid match quantity %d for order quantity %d with lot size %d", qty, ord.Qty, ord.LotSize)
+	}
+	mktName, err := dex.MarketName(ord.BaseID, ord.QuoteID)
+	if err != nil {
+		return nil, fmt.Errorf("error constructing market name: %w", err)
+	}
+	match := &tanka.Match{
+		From:    m.peerID,
+		OrderID: ord.ID(),
+		Qty:     qty,
+		Stamp:   time.Now(),
+	}
+	if err := m.Broadcast(mj.TopicMarket, tanka.Subject(mktName), mj.MessageTypeProposeMatch, match); err != nil {
+		return nil, err
+	}
+	return match, nil
+}
+
+// RenewOrder broadcasts an update for our order with the remaining quantity,
+// renewing the order's expiration to MaxOrderLifetime from now. Standing
+// orders placed with PlaceOrder are renewed automatically. A zero quantity
+// removes the order from the order books.
+func (m *Mesh) RenewOrder(ord *tanka.Order, qty uint64) error {
+	if ord.From != m.peerID {
+		return errors.New("not our order")
+	}
+	mktName, err := dex.MarketName(ord.BaseID, ord.QuoteID)
+	if err != nil {
+		return fmt.Errorf("error constructing market name: %w", err)
+	}
+	stamp := time.Now()
+	return m.Broadcast(mj.TopicMarket, tanka.Subject(mktName), mj.MessageTypeUpdateOrder, &tanka.OrderUpdate{
+		From:       ord.From,
+		Nonce:      ord.Nonce,
+		Qty:        qty,
+		Stamp:      stamp,
+		Expiration: stamp.Add(tanka.MaxOrderLifetime),
+	})
+}
+
+// pruneOrders prunes expired orders from the markets and renews our expiring
+// orders until the context is canceled.
+func (m *Mesh) pruneOrders(ctx context.Context) {
+	ticker := time.NewTicker(orderPruneInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			m.marketsMtx.RLock()
+			mkts := make([]*market, 0, len(m.markets))
+			for _, mkt := range m.markets {
+				mkts = append(mkts, mkt)
+			}
+			m.marketsMtx.RUnlock()
+			for _, mkt := range mkts {
+				if mkt.pruneExpired() > 0 {
+					m.emitMarketUpdate(mkt)
+				}
+				for _, ord := range mkt.expiringOrders(m.peerID, time.Now().Add(orderRenewBuffer)) {
+					if err := m.RenewOrder(ord, ord.Qty); err != nil {
+						m.log.Errorf("error renewing order %s: %v", ord.ID(), err)
+					}
+				}
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
 func (m *Mesh) FiatRate(assetID uint32) float64 {
 	m.fiatRatesMtx.RLock()
 	defer m.fiatRatesMtx.RUnlock()
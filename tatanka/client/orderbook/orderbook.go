@@ -4,9 +4,12 @@
 package orderbook
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"sort"
 	"sync"
+	"time"
 
 	"decred.org/dcrdex/tatanka/tanka"
 )
@@ -19,8 +22,12 @@ type OrderBooker interface {
 	Add(*tanka.Order)
 	Update(ou *tanka.OrderUpdate) error
 	Delete(id tanka.ID40)
+	PruneExpired() []tanka.ID40
 }
 
+// pruneInterval is how often Run prunes expired orders.
+const pruneInterval = time.Minute
+
 // OrderFilter is used when searching for orders.
 type OrderFilter struct {
 	IsSell *bool
@@ -118,17 +125,20 @@ func (ob *OrderBook) deleteSortedOrder(o *tanka.Order) {
 	if o.Sell {
 		ords = &ob.sells
 	}
-	for i, o := range *ords {
+	for i, ord := range *ords {
 		// Comparing pointers.
-		if o == o {
+		if ord == o {
 			*ords = append((*ords)[:i], (*ords)[i+1:]...)
 			break
 		}
 	}
 }
 
-// Add adds an order.
+// Add adds an order. Invalid and expired orders are ignored.
 func (ob *OrderBook) Add(o *tanka.Order) {
+	if o.Valid() != nil || o.Expired() {
+		return
+	}
 	id := o.ID()
 	ob.mtx.Lock()
 	defer ob.mtx.Unlock()
@@ -140,8 +150,14 @@ func (ob *OrderBook) Add(o *tanka.Order) {
 	ob.addOrderAndSort(o)
 }
 
-// Update updates an order.
+// Update updates an order's quantity and renews its expiration.
 func (ob *OrderBook) Update(ou *tanka.OrderUpdate) error {
+	if err := ou.Valid(); err != nil {
+		return fmt.Errorf("invalid order update: %w", err)
+	}
+	if ou.Expired() {
+		return errors.New("order update is expired")
+	}
 	ob.mtx.Lock()
 	defer ob.mtx.Unlock()
 	id := ou.ID()
@@ -149,8 +165,12 @@ func (ob *OrderBook) Update(ou *tanka.OrderUpdate) error {
 	if !has {
 		return fmt.Errorf("order %x not found", id)
 	}
+	if ou.Stamp.Before(o.Stamp) {
+		return fmt.Errorf("order update stamp %s is before the current stamp %s", ou.Stamp, o.Stamp)
+	}
 	o.Qty = ou.Qty
 	o.Stamp = ou.Stamp
+	o.Expiration = ou.Expiration
 	return nil
 }
 
@@ -164,3 +184,32 @@ func (ob *OrderBook) Delete(id tanka.ID40) {
 		ob.deleteSortedOrder(o)
 	}
 }
+
+// PruneExpired deletes expired orders from the books, returning the IDs of the
+// deleted orders.
+func (ob *OrderBook) PruneExpired() (pruned []tanka.ID40) {
+	ob.mtx.Lock()
+	defer ob.mtx.Unlock()
+	for id, o := range ob.book {
+		if o.Expired() {
+			delete(ob.book, id)
+			ob.deleteSortedOrder(o)
+			pruned = append(pruned, id)
+		}
+	}
+	return pruned
+}
+
+// Run prunes expired orders periodically until the context is canceled.
+func (ob *OrderBook) Run(ctx context.Context) {
+	ticker := time.NewTicker(pruneInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			ob.PruneExpired()
+		case <-ctx.Done():
+			return
+		}
+	}
+}
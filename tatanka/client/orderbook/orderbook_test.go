@@ -8,61 +8,59 @@ import (
 	"decred.org/dcrdex/tatanka/tanka"
 )
 
-func mustParseTime(s string) time.Time {
-	t, err := time.Parse(time.RFC1123, s)
-	if err != nil {
-		panic(err)
-	}
-	return t
-}
-
 func testOrders() []*tanka.Order {
 	var peer tanka.PeerID
 	copy(peer[:], encode.RandomBytes(32))
 	baseID, quoteID := uint32(0), uint32(42)
+	stamp := time.Now().Truncate(time.Millisecond)
+	expiration := stamp.Add(time.Minute)
 	lowbuy := &tanka.Order{
-		From:    peer,
-		BaseID:  baseID,
-		QuoteID: quoteID,
-		Sell:    false,
-		Qty:     1000,
-		Rate:    123,
-		LotSize: 3,
-		Nonce:   0,
-		Stamp:   mustParseTime("Sun, 12 Dec 2024 12:23:00 UTC"),
+		From:       peer,
+		BaseID:     baseID,
+		QuoteID:    quoteID,
+		Sell:       false,
+		Qty:        1000,
+		Rate:       123,
+		LotSize:    8,
+		Nonce:      0,
+		Stamp:      stamp,
+		Expiration: expiration,
 	}
 	highbuy := &tanka.Order{
-		From:    peer,
-		BaseID:  baseID,
-		QuoteID: quoteID,
-		Sell:    false,
-		Qty:     1000,
-		Rate:    1234,
-		LotSize: 2,
-		Nonce:   1,
-		Stamp:   mustParseTime("Sun, 12 Dec 2024 12:23:00 UTC"),
+		From:       peer,
+		BaseID:     baseID,
+		QuoteID:    quoteID,
+		Sell:       false,
+		Qty:        1000,
+		Rate:       1234,
+		LotSize:    2,
+		Nonce:      1,
+		Stamp:      stamp,
+		Expiration: expiration,
 	}
 	lowsell := &tanka.Order{
-		From:    peer,
-		BaseID:  baseID,
-		QuoteID: quoteID,
-		Sell:    true,
-		Qty:     1000,
-		Rate:    12345,
-		LotSize: 2,
-		Nonce:   2,
-		Stamp:   mustParseTime("Sun, 12 Dec 2024 12:23:00 UTC"),
+		From:       peer,
+		BaseID:     baseID,
+		QuoteID:    quoteID,
+		Sell:       true,
+		Qty:        1000,
+		Rate:       12345,
+		LotSize:    2,
+		Nonce:      2,
+		Stamp:      stamp,
+		Expiration: expiration,
 	}
 	highsell := &tanka.Order{
-		From:    peer,
-		BaseID:  baseID,
-		QuoteID: quoteID,
-		Sell:    true,
-		Qty:     1000,
-		Rate:    123456,
-		LotSize: 4,
-		Nonce:   3,
-		Stamp:   mustParseTime("Sun, 12 Dec 2024 12:23:00 UTC"),
+		From:       peer,
+		BaseID:     baseID,
+		QuoteID:    quoteID,
+		Sell:       true,
+		Qty:        1000,
+		Rate:       123456,
+		LotSize:    4,
+		Nonce:      3,
+		Stamp:      stamp,
+		Expiration: expiration,
 	}
 	return []*tanka.Order{lowbuy, highbuy, lowsell, highsell}
 }
@@ -170,7 +168,8 @@ func TestUpdate(t *testing.T) {
 		ob.Add(o)
 	}
 	o := ords[0]
-	updateTime := mustParseTime("Sun, 28 Dec 2025 12:00:00 UTC")
+	updateTime := time.Now().Truncate(time.Millisecond)
+	expiration := updateTime.Add(tanka.MaxOrderLifetime)
 	tests := []struct {
 		name    string
 		update  *tanka.OrderUpdate
@@ -178,18 +177,50 @@ func TestUpdate(t *testing.T) {
 	}{{
 		name: "ok",
 		update: &tanka.OrderUpdate{
-			From:  o.From,
-			Nonce: o.Nonce,
-			Qty:   7,
-			Stamp: updateTime,
+			From:       o.From,
+			Nonce:      o.Nonce,
+			Qty:        7,
+			Stamp:      updateTime,
+			Expiration: expiration,
 		},
 	}, {
 		name: "order does not exist",
 		update: &tanka.OrderUpdate{
-			From:  o.From,
-			Nonce: 100,
-			Qty:   7,
-			Stamp: updateTime,
+			From:       o.From,
+			Nonce:      100,
+			Qty:        7,
+			Stamp:      updateTime,
+			Expiration: expiration,
+		},
+		wantErr: true,
+	}, {
+		name: "expiration too far in the future",
+		update: &tanka.OrderUpdate{
+			From:       o.From,
+			Nonce:      o.Nonce,
+			Qty:        7,
+			Stamp:      updateTime,
+			Expiration: expiration.Add(time.Second),
+		},
+		wantErr: true,
+	}, {
+		name: "expired",
+		update: &tanka.OrderUpdate{
+			From:       o.From,
+			Nonce:      o.Nonce,
+			Qty:        7,
+			Stamp:      updateTime.Add(-tanka.MaxOrderLifetime),
+			Expiration: updateTime.Add(-time.Second),
+		},
+		wantErr: true,
+	}, {
+		name: "stamp before order stamp",
+		update: &tanka.OrderUpdate{
+			From:       o.From,
+			Nonce:      o.Nonce,
+			Qty:        7,
+			Stamp:      o.Stamp.Add(-time.Second),
+			Expiration: updateTime.Add(time.Second),
 		},
 		wantErr: true,
 	}}
@@ -212,6 +243,9 @@ func TestUpdate(t *testing.T) {
 			if ord.Stamp != test.update.Stamp {
 				t.Fatalf("expected stamp %s but got %s", test.update.Stamp, ord.Stamp)
 			}
+			if ord.Expiration != test.update.Expiration {
+				t.Fatalf("expected expiration %s but got %s", test.update.Expiration, ord.Expiration)
+			}
 		})
 	}
 }
@@ -236,3 +270,62 @@ func TestDeleteOrder(t *testing.T) {
 		t.Fatalf("wanted 1 but got %d orders", len(oids))
 	}
 }
+
+func TestPruneExpired(t *testing.T) {
+	ob := NewOrderBook()
+	ords := testOrders()
+	for _, o := range ords {
+		ob.Add(o)
+	}
+
+	// Expired orders are not added.
+	expiredOrd := *ords[0]
+	expiredOrd.Nonce = 4
+	expiredOrd.Expiration = time.Now().Add(-time.Second)
+	ob.Add(&expiredOrd)
+	if ob.Order(expiredOrd.ID()) != nil {
+		t.Fatalf("expired order was added")
+	}
+
+	// Neither are invalid orders.
+	futureOrd := *ords[0]
+	futureOrd.Nonce = 5
+	futureOrd.Stamp = time.Now().Add(time.Hour)
+	futureOrd.Expiration = futureOrd.Stamp.Add(time.Minute)
+	ob.Add(&futureOrd)
+	if ob.Order(futureOrd.ID()) != nil {
+		t.Fatalf("order stamped in the future was added")
+	}
+	badLotOrd := *ords[0]
+	badLotOrd.Nonce = 6
+	badLotOrd.LotSize = 3
+	ob.Add(&badLotOrd)
+	if ob.Order(badLotOrd.ID()) != nil {
+		t.Fatalf("order with invalid lot size was added")
+	}
+
+	if pruned := ob.PruneExpired(); len(pruned) != 0 {
+		t.Fatalf("pruned %d orders before expiration", len(pruned))
+	}
+
+	ords[1].Expiration = time.Now().Add(-time.Second)
+	ords[2].Expiration = time.Now().Add(-time.Second)
+	pruned := ob.PruneExpired()
+	if len(pruned) != 2 {
+		t.Fatalf("wanted 2 pruned orders but got %d", len(pruned))
+	}
+	for _, id := range pruned {
+		if id != ords[1].ID() && id != ords[2].ID() {
+			t.Fatalf("wrong order pruned")
+		}
+	}
+	if len(ob.book) != 2 {
+		t.Fatalf("wanted 2 but got %d orders", len(ob.book))
+	}
+	if len(ob.buys) != 1 || ob.buys[0] != ords[0] {
+		t.Fatalf("wrong buys after pruning")
+	}
+	if len(ob.sells) != 1 || ob.sells[0] != ords[3] {
+		t.Fatalf("wrong sells after pruning")
+	}
+}
@@ -0,0 +1,974 @@
+// This code is available on the terms of the project LICENSE.md file,
+// also available online at https://blueoakcouncil.org/license/1.0.0.
+
+package swap
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"decred.org/dcrdex/client/asset"
+	"decred.org/dcrdex/dex"
+	"decred.org/dcrdex/dex/calc"
+	"decred.org/dcrdex/dex/encode"
+	"decred.org/dcrdex/dex/lexi"
+	"decred.org/dcrdex/dex/msgjson"
+	"decred.org/dcrdex/tatanka/mj"
+	"decred.org/dcrdex/tatanka/tanka"
+	"github.com/decred/dcrd/dcrec/secp256k1/v4"
+)
+
+/*
+	The swap package executes atomic swaps for matches accepted on the
+	Tatanka Mesh. The owner of the standing order is the maker, and the peer
+	that proposed the match is the taker. There is no server to coordinate the
+	swap, so the peers talk directly with encrypted tankagrams.
+
+	1. The maker sends their receiving address in a swap_address tankagram.
+	   The taker responds with their own receiving address and their
+	   signature of the tanka.SignedMatch.
+	2. The maker generates the secret and broadcasts their swap contract,
+	   then sends the swap_init tankagram with their own signature.
+	3. The taker audits the maker's contract, broadcasts their own contract
+	   with the same secret hash and a shorter lock time, then sends their own
+	   swap_init tankagram.
+	4. The maker audits the taker's contract and redeems it, revealing the
+	   secret, then sends the swap_redeem tankagram.
+	5. The taker redeems the maker's contract with the secret. If the maker
+	   never sends the swap_redeem tankagram, the taker finds the secret
+	   on-chain.
+
+	Either party refunds their contract if the lock time expires before the
+	counterparty redeems it.
+
+	Each party signs the match and both addresses before the counterparty
+	funds their contract, so that either party can dispute a failed swap with
+	a tatanka node.
+*/
+
+const (
+	// MakerLockTime and TakerLockTime are the lock times of the maker's and
+	// the taker's swap contracts, measured from the match stamp.
+	MakerLockTime = tanka.MakerLockTime
+	TakerLockTime = tanka.TakerLockTime
+
+	// DefaultSwapConfs is the number of confirmations required for the
+	// counterparty's contract when Config.SwapConfs is not set.
+	DefaultSwapConfs = 1
+
+	secretSize      = 32
+	tickInterval    = 30 * time.Second
+	findRedeemLimit = 10 * time.Second
+)
+
+// Status is the status of a swap.
+type Status uint8
+
+const (
+	// StatusNegotiating means that the peers are exchanging addresses, or
+	// that the taker is waiting for the maker's contract.
+	StatusNegotiating Status = iota
+	// StatusMakerSwapCast means that the maker's contract has been broadcast.
+	StatusMakerSwapCast
+	// StatusTakerSwapCast means that the taker's contract has been broadcast.
+	StatusTakerSwapCast
+	// StatusMakerRedeemed means that the maker has redeemed the taker's
+	// contract.
+	StatusMakerRedeemed
+	// StatusComplete means that we have redeemed, and for the maker, that the
+	// secret has been delivered to the taker.
+	StatusComplete
+	// StatusRefunded means that our contract was refunded.
+	StatusRefunded
+)
+
+// String satisfies fmt.Stringer.
+func (s Status) String() string {
+	switch s {
+	case StatusNegotiating:
+		return "negotiating"
+	case StatusMakerSwapCast:
+		return "maker swap cast"
+	case StatusTakerSwapCast:
+		return "taker swap cast"
+	case StatusMakerRedeemed:
+		return "maker redeemed"
+	case StatusComplete:
+		return "complete"
+	case StatusRefunded:
+		return "refunded"
+	}
+	return "unknown"
+}
+
+// Swap is the state of the swap for an accepted match. Swaps are persisted in
+// the database and resumed on startup.
+type Swap struct {
+	mtx sync.Mutex
+
+	Match  *tanka.Match `json:"match"`
+	Order  *tanka.Order `json:"order"`
+	Status Status       `json:"status"`
+	// Maker is true if we are the owner of the standing order.
+	Maker bool `json:"maker"`
+	// Address is our receiving address for the counterparty's asset.
+	Address string `json:"address"`
+	// CounterAddress is the counterparty's receiving address for our asset.
+	CounterAddress string `json:"counterAddress"`
+	// CounterSig is the counterparty's tanka.SignedMatch signature.
+	CounterSig dex.Bytes `json:"counterSig,omitempty"`
+	// Secret is generated by the maker, and learned by the taker when the
+	// maker redeems.
+	Secret     dex.Bytes `json:"secret,omitempty"`
+	SecretHash dex.Bytes `json:"secretHash,omitempty"`
+	// CoinID and Contract describe our swap contract.
+	CoinID   dex.Bytes `json:"coinID,omitempty"`
+	Contract dex.Bytes `json:"contract,omitempty"`
+	InitSent bool      `json:"initSent"`
+	// CounterCoinID and CounterContract describe the counterparty's contract.
+	CounterCoinID   dex.Bytes `json:"counterCoinID,omitempty"`
+	CounterContract dex.Bytes `json:"counterContract,omitempty"`
+	// RedeemCoinID is our redemption of the counterparty's contract.
+	RedeemCoinID dex.Bytes `json:"redeemCoinID,omitempty"`
+	RedeemSent   bool      `json:"redeemSent"`
+	// RefundCoinID is our refund of our own contract.
+	RefundCoinID dex.Bytes `json:"refundCoinID,omitempty"`
+}
+
+// MatchID is the ID of the match.
+func (s *Swap) MatchID() tanka.ID32 {
+	return s.Match.ID()
+}
+
+// Counterparty is the peer on the other side of the match.
+func (s *Swap) Counterparty() tanka.PeerID {
+	if s.Maker {
+		return s.Match.From
+	}
+	return s.Order.From
+}
+
+// Active is true if the swap is still in progress.
+func (s *Swap) Active() bool {
+	return s.Status < StatusComplete
+}
+
+// sendsBase is true if we send the base asset.
+func (s *Swap) sendsBase() bool {
+	return s.Maker == s.Order.Sell
+}
+
+// FromAsset is the asset ID and quantity that we send.
+func (s *Swap) FromAsset() (assetID uint32, qty uint64) {
+	if s.sendsBase() {
+		return s.Order.BaseID, s.Match.Qty
+	}
+	return s.Order.QuoteID, calc.BaseToQuote(s.Order.Rate, s.Match.Qty)
+}
+
+// ToAsset is the asset ID and quantity that we receive.
+func (s *Swap) ToAsset() (assetID uint32, qty uint64) {
+	if s.sendsBase() {
+		return s.Order.QuoteID, calc.BaseToQuote(s.Order.Rate, s.Match.Qty)
+	}
+	return s.Order.BaseID, s.Match.Qty
+}
+
+// signedMatch is the match with both addresses. The caller must hold the swap
+// mutex.
+func (s *Swap) signedMatch() *tanka.SignedMatch {
+	sm := &tanka.SignedMatch{
+		Order:        s.Order,
+		Match:        s.Match,
+		MakerAddress: s.CounterAddress,
+		TakerAddress: s.Address,
+	}
+	if s.Maker {
+		sm.MakerAddress, sm.TakerAddress = s.Address, s.CounterAddress
+	}
+	return sm
+}
+
+// checkCounterSig checks the counterparty's signature of the match, and saves
+// it for disputes. The caller must hold the swap mutex.
+func (s *Swap) checkCounterSig(sig dex.Bytes) error {
+	sm := s.signedMatch()
+	sm.Sig = sig
+	if err := sm.Verify(s.Counterparty()); err != nil {
+		return fmt.Errorf("invalid counterparty match signature: %w", err)
+	}
+	s.CounterSig = sig
+	return nil
+}
+
+// Dispute builds a request for a tatanka node to audit the swap contracts and
+// penalize the counterparty if they did not fund the swap as agreed. Our own
+// contract must have been broadcast, and we must know the counterparty's
+// contract.
+func (s *Swap) Dispute() (*mj.SwapDispute, error) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	if len(s.CoinID) == 0 {
+		return nil, errors.New("our contract was never broadcast")
+	}
+	if len(s.CounterSig) == 0 {
+		return nil, errors.New("no counterparty match signature")
+	}
+	if len(s.CounterCoinID) == 0 {
+		return nil, errors.New("counterparty contract unknown")
+	}
+	sm := s.signedMatch()
+	sm.Sig = s.CounterSig
+	ours, theirs := sm.Contracts()
+	if !s.Maker {
+		ours, theirs = theirs, ours
+	}
+	ours.CoinID, ours.Contract, ours.SecretHash = s.CoinID, s.Contract, s.SecretHash
+	theirs.CoinID, theirs.Contract, theirs.SecretHash = s.CounterCoinID, s.CounterContract, s.SecretHash
+	return &mj.SwapDispute{
+		Match:  sm,
+		Ours:   ours,
+		Theirs: theirs,
+	}, nil
+}
+
+// copy makes a copy of the swap. The caller must hold the swap mutex.
+func (s *Swap) copy() *Swap {
+	return &Swap{
+		Match:           s.Match,
+		Order:           s.Order,
+		Status:          s.Status,
+		Maker:           s.Maker,
+		Address:         s.Address,
+		CounterAddress:  s.CounterAddress,
+		CounterSig:      s.CounterSig,
+		Secret:          s.Secret,
+		SecretHash:      s.SecretHash,
+		CoinID:          s.CoinID,
+		Contract:        s.Contract,
+		InitSent:        s.InitSent,
+		CounterCoinID:   s.CounterCoinID,
+		CounterContract: s.CounterContract,
+		RedeemCoinID:    s.RedeemCoinID,
+		RedeemSent:      s.RedeemSent,
+		RefundCoinID:    s.RefundCoinID,
+	}
+}
+
+// PeerMessenger sends tankagrams to peers.
+type PeerMessenger interface {
+	RequestPeer(peerID tanka.PeerID, msg *msgjson.Message, thing any) error
+}
+
+// Config is the configuration for the Engine.
+type Config struct {
+	// PrivateKey is our peer key, used to sign matches.
+	PrivateKey *secp256k1.PrivateKey
+	// DB is the mesh database. The swaps are stored in the "swap" table.
+	DB *lexi.DB
+	// Wallets retrieves the wallet for an asset.
+	Wallets func(assetID uint32) (asset.Wallet, error)
+	Peers   PeerMessenger
+	Logger  dex.Logger
+	// Notify is called with a copy of the swap when the status changes.
+	Notify func(*Swap)
+	// SwapConfs is optional, and returns the number of confirmations required
+	// for the counterparty's contract before we initiate or redeem. If
+	// SwapConfs is nil, DefaultSwapConfs is required for every asset.
+	SwapConfs func(assetID uint32) uint32
+}
+
+// Engine drives accepted mesh matches through the atomic swap sequence.
+type Engine struct {
+	priv      *secp256k1.PrivateKey
+	peerID    tanka.PeerID
+	wallets   func(assetID uint32) (asset.Wallet, error)
+	peers     PeerMessenger
+	log       dex.Logger
+	notify    func(*Swap)
+	swapConfs func(assetID uint32) uint32
+	table     *lexi.Table
+	kick      chan tanka.ID32
+
+	swapsMtx sync.RWMutex
+	swaps    map[tanka.ID32]*Swap
+}
+
+// New is the constructor for an Engine. Active swaps are loaded from the
+// database, and will be resumed when Run is called.
+func New(cfg *Config) (*Engine, error) {
+	table, err := cfg.DB.Table("swap")
+	if err != nil {
+		return nil, fmt.Errorf("error creating swap table: %w", err)
+	}
+	// Swaps are updated in place as they progress.
+	table.UseDefaultSetOptions(lexi.WithReplace())
+	notify := cfg.Notify
+	if notify == nil {
+		notify = func(*Swap) {}
+	}
+	swapConfs := cfg.SwapConfs
+	if swapConfs == nil {
+		swapConfs = func(uint32) uint32 { return DefaultSwapConfs }
+	}
+	var peerID tanka.PeerID
+	copy(peerID[:], cfg.PrivateKey.PubKey().SerializeCompressed())
+	e := &Engine{
+		priv:      cfg.PrivateKey,
+		peerID:    peerID,
+		wallets:   cfg.Wallets,
+		peers:     cfg.Peers,
+		log:       cfg.Logger,
+		notify:    notify,
+		swapConfs: swapConfs,
+		table:     table,
+		kick:      make(chan tanka.ID32, 128),
+		swaps:     make(map[tanka.ID32]*Swap),
+	}
+	if err := table.Iterate(nil, func(it *lexi.Iter) error {
+		var s Swap
+		if err := it.V(func(vB []byte) error {
+			return json.Unmarshal(vB, &s)
+		}); err != nil {
+			return err
+		}
+		if s.Active() {
+			e.swaps[s.MatchID()] = &s
+		}
+		return nil
+	}); err != nil {
+		return nil, fmt.Errorf("error loading swaps: %w", err)
+	}
+	return e, nil
+}
+
+// Run processes the swaps until the context is canceled.
+func (e *Engine) Run(ctx context.Context) {
+	e.processAll(ctx)
+	ticker := time.NewTicker(tickInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			e.processAll(ctx)
+		case matchID := <-e.kick:
+			if s := e.swap(matchID); s != nil {
+				e.process(ctx, s)
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// Swaps returns copies of the active swaps.
+func (e *Engine) Swaps() []*Swap {
+	e.swapsMtx.RLock()
+	defer e.swapsMtx.RUnlock()
+	swaps := make([]*Swap, 0, len(e.swaps))
+	for _, s := range e.swaps {
+		s.mtx.Lock()
+		swaps = append(swaps, s.copy())
+		s.mtx.Unlock()
+	}
+	return swaps
+}
+
+// Trade starts the swap for an accepted match of the order. Matches that
+// don't involve us are ignored, as are matches that are already known.
+func (e *Engine) Trade(ord *tanka.Order, match *tanka.Match) error {
+	if match.OrderID != ord.ID() {
+		return fmt.Errorf("match %s is not for order %s", match.ID(), ord.ID())
+	}
+	if ord.From != e.peerID && match.From != e.peerID {
+		return nil
+	}
+	if ord.From == match.From {
+		return errors.New("cannot match our own order")
+	}
+	if match.Qty == 0 || match.Qty > ord.Qty || match.Qty%ord.LotSize != 0 {
+		return fmt.Errorf("invalid match quantity %d for order quantity %d with lot size %d", match.Qty, ord.Qty, ord.LotSize)
+	}
+	matchID := match.ID()
+	if e.swap(matchID) != nil {
+		return nil
+	}
+	// The lock times are measured from the taker's stamp. The taker knows
+	// their own stamp, so only the maker checks it.
+	if ord.From == e.peerID {
+		if err := match.CheckStamp(); err != nil {
+			return err
+		}
+	}
+	s := &Swap{
+		Match: match,
+		Order: ord,
+		Maker: ord.From == e.peerID,
+	}
+	fromID, _ := s.FromAsset()
+	if _, err := e.wallets(fromID); err != nil {
+		return fmt.Errorf("no wallet for %s: %w", dex.BipIDSymbol(fromID), err)
+	}
+	toID, _ := s.ToAsset()
+	toWallet, err := e.wallets(toID)
+	if err != nil {
+		return fmt.Errorf("no wallet for %s: %w", dex.BipIDSymbol(toID), err)
+	}
+	if s.Address, err = toWallet.RedemptionAddress(); err != nil {
+		return fmt.Errorf("error getting %s redemption address: %w", dex.BipIDSymbol(toID), err)
+	}
+	if s.Maker {
+		s.Secret = encode.RandomBytes(secretSize)
+		secretHash := sha256.Sum256(s.Secret)
+		s.SecretHash = secretHash[:]
+	}
+	if err := e.store(s); err != nil {
+		return err
+	}
+	e.swapsMtx.Lock()
+	e.swaps[matchID] = s
+	e.swapsMtx.Unlock()
+	e.notify(s.copy())
+	e.kickSwap(matchID)
+	return nil
+}
+
+// IsSwapRoute is true if the route is one of the swap tankagram routes that
+// should be handled with HandlePeerMessage.
+func IsSwapRoute(route string) bool {
+	switch route {
+	case mj.RouteSwapAddress, mj.RouteSwapInit, mj.RouteSwapRedeem:
+		return true
+	}
+	return false
+}
+
+// HandlePeerMessage handles a swap tankagram from a peer. The returned result
+// should be sent back to the peer.
+func (e *Engine) HandlePeerMessage(peerID tanka.PeerID, msg *msgjson.Message) (any, error) {
+	switch msg.Route {
+	case mj.RouteSwapAddress:
+		var addr mj.SwapAddress
+		if err := msg.Unmarshal(&addr); err != nil {
+			return nil, fmt.Errorf("error unmarshaling swap address: %w", err)
+		}
+		return e.handleSwapAddress(peerID, &addr)
+	case mj.RouteSwapInit:
+		var init mj.SwapInit
+		if err := msg.Unmarshal(&init); err != nil {
+			return nil, fmt.Errorf("error unmarshaling swap init: %w", err)
+		}
+		return true, e.handleSwapInit(peerID, &init)
+	case mj.RouteSwapRedeem:
+		var redeem mj.SwapRedeem
+		if err := msg.Unmarshal(&redeem); err != nil {
+			return nil, fmt.Errorf("error unmarshaling swap redeem: %w", err)
+		}
+		return true, e.handleSwapRedeem(peerID, &redeem)
+	}
+	return nil, fmt.Errorf("unknown swap route %q", msg.Route)
+}
+
+// counterpartySwap retrieves the swap for the match ID, checking that the peer
+// is the counterparty.
+func (e *Engine) counterpartySwap(peerID tanka.PeerID, matchID tanka.ID32) (*Swap, error) {
+	s := e.swap(matchID)
+	if s == nil {
+		return nil, fmt.Errorf("unknown match %s", matchID)
+	}
+	if s.Counterparty() != peerID {
+		return nil, fmt.Errorf("peer %s is not the counterparty for match %s", peerID, matchID)
+	}
+	return s, nil
+}
+
+func (e *Engine) handleSwapAddress(peerID tanka.PeerID, addr *mj.SwapAddress) (*mj.SwapAddress, error) {
+	s, err := e.counterpartySwap(peerID, addr.MatchID)
+	if err != nil {
+		return nil, err
+	}
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	if s.Maker {
+		return nil, errors.New("the maker does not receive addresses")
+	}
+	if addr.Address == "" {
+		return nil, errors.New("no address")
+	}
+	if s.CounterAddress != "" && s.CounterAddress != addr.Address {
+		return nil, errors.New("counterparty address already set")
+	}
+	if s.CounterAddress == "" {
+		s.CounterAddress = addr.Address
+		if err := e.store(s); err != nil {
+			return nil, err
+		}
+	}
+	e.kickSwap(addr.MatchID)
+	sm := s.signedMatch()
+	sm.Sign(e.priv)
+	return &mj.SwapAddress{MatchID: addr.MatchID, Address: s.Address, Sig: sm.Sig}, nil
+}
+
+func (e *Engine) handleSwapInit(peerID tanka.PeerID, init *mj.SwapInit) error {
+	s, err := e.counterpartySwap(peerID, init.MatchID)
+	if err != nil {
+		return err
+	}
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	if len(init.CoinID) == 0 || len(init.Contract) == 0 {
+		return errors.New("missing coin ID or contract")
+	}
+	if len(s.CounterCoinID) > 0 {
+		if !s.CounterCoinID.Equal(init.CoinID) || !s.CounterContract.Equal(init.Contract) {
+			return errors.New("counterparty contract already set")
+		}
+		return nil
+	}
+	if s.Maker && s.Status < StatusMakerSwapCast {
+		return errors.New("taker contract received before maker contract")
+	}
+	if !s.Maker {
+		if err := s.checkCounterSig(init.Sig); err != nil {
+			return err
+		}
+	}
+	s.CounterCoinID = init.CoinID
+	s.CounterContract = init.Contract
+	if err := e.store(s); err != nil {
+		return err
+	}
+	e.kickSwap(init.MatchID)
+	return nil
+}
+
+func (e *Engine) handleSwapRedeem(peerID tanka.PeerID, redeem *mj.SwapRedeem) error {
+	s, err := e.counterpartySwap(peerID, redeem.MatchID)
+	if err != nil {
+		return err
+	}
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	if s.Maker {
+		return errors.New("the maker does not receive redemptions")
+	}
+	if len(s.Secret) > 0 {
+		return nil
+	}
+	if len(s.SecretHash) == 0 {
+		return errors.New("redemption received before maker contract was audited")
+	}
+	secretHash := sha256.Sum256(redeem.Secret)
+	if !s.SecretHash.Equal(secretHash[:]) {
+		return errors.New("secret does not match secret hash")
+	}
+	s.Secret = redeem.Secret
+	if err := e.store(s); err != nil {
+		return err
+	}
+	e.kickSwap(redeem.MatchID)
+	return nil
+}
+
+func (e *Engine) swap(matchID tanka.ID32) *Swap {
+	e.swapsMtx.RLock()
+	defer e.swapsMtx.RUnlock()
+	return e.swaps[matchID]
+}
+
+func (e *Engine) kickSwap(matchID tanka.ID32) {
+	select {
+	case e.kick <- matchID:
+	default:
+		// The swap will be processed on the next tick.
+	}
+}
+
+// store saves the swap to the database. The caller must hold the swap mutex.
+func (e *Engine) store(s *Swap) error {
+	matchID := s.MatchID()
+	if err := e.table.Set(matchID[:], lexi.JSON(s)); err != nil {
+		return fmt.Errorf("error storing swap %s: %w", matchID, err)
+	}
+	return nil
+}
+
+func (e *Engine) processAll(ctx context.Context) {
+	e.swapsMtx.RLock()
+	swaps := make([]*Swap, 0, len(e.swaps))
+	for _, s := range e.swaps {
+		swaps = append(swaps, s)
+	}
+	e.swapsMtx.RUnlock()
+	for _, s := range swaps {
+		e.process(ctx, s)
+	}
+}
+
+// process takes the next steps for the swap.
+func (e *Engine) process(ctx context.Context, s *Swap) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	status := s.Status
+	var err error
+	if s.Maker {
+		err = e.processMaker(ctx, s)
+	} else {
+		err = e.processTaker(ctx, s)
+	}
+	matchID := s.MatchID()
+	if err != nil {
+		e.log.Errorf("Error processing swap for match %s: %v", matchID, err)
+	}
+	if s.Status == status {
+		return
+	}
+	e.log.Infof("Swap for match %s is now %s", matchID, s.Status)
+	if err := e.store(s); err != nil {
+		e.log.Errorf("Error storing swap: %v", err)
+	}
+	e.notify(s.copy())
+	if !s.Active() {
+		e.swapsMtx.Lock()
+		delete(e.swaps, matchID)
+		e.swapsMtx.Unlock()
+	}
+}
+
+func (e *Engine) processMaker(ctx context.Context, s *Swap) error {
+	if s.Status == StatusNegotiating {
+		if s.CounterAddress == "" {
+			var addr mj.SwapAddress
+			req := mj.MustRequest(mj.RouteSwapAddress, &mj.SwapAddress{MatchID: s.MatchID(), Address: s.Address})
+			if err := e.peers.RequestPeer(s.Counterparty(), req, &addr); err != nil {
+				return fmt.Errorf("error requesting counterparty address: %w", err)
+			}
+			if addr.Address == "" {
+				return errors.New("counterparty sent an empty address")
+			}
+			s.CounterAddress = addr.Address
+			if err := s.checkCounterSig(addr.Sig); err != nil {
+				s.CounterAddress = ""
+				return err
+			}
+			if err := e.store(s); err != nil {
+				return err
+			}
+		}
+		if err := e.initiate(s, MakerLockTime); err != nil {
+			return err
+		}
+		s.Status = StatusMakerSwapCast
+		if err := e.store(s); err != nil {
+			return err
+		}
+	}
+	if !s.InitSent {
+		if err := e.sendInit(s); err != nil {
+			return err
+		}
+	}
+	if s.Status == StatusMakerSwapCast {
+		if len(s.CounterCoinID) == 0 {
+			return e.maybeRefund(ctx, s)
+		}
+		if _, err := e.auditCounterContract(s, TakerLockTime, TakerLockTime/2); err != nil {
+			// The contract may not be visible to our wallet yet. Try again
+			// next time, and refund if it never shows up.
+			e.log.Warnf("Audit of taker contract for match %s failed: %v", s.MatchID(), err)
+			return e.maybeRefund(ctx, s)
+		}
+		// Don't reveal the secret until the taker's contract can't be
+		// double-spent.
+		if confirmed, err := e.counterConfirmed(ctx, s); err != nil || !confirmed {
+			if err != nil {
+				e.log.Warnf("Error checking taker contract confirmations for match %s: %v", s.MatchID(), err)
+			}
+			return e.maybeRefund(ctx, s)
+		}
+		s.Status = StatusTakerSwapCast
+	}
+	if s.Status == StatusTakerSwapCast {
+		if err := e.redeem(s); err != nil {
+			return err
+		}
+		s.Status = StatusMakerRedeemed
+	}
+	if s.Status == StatusMakerRedeemed {
+		req := mj.MustRequest(mj.RouteSwapRedeem, &mj.SwapRedeem{
+			MatchID: s.MatchID(),
+			CoinID:  s.RedeemCoinID,
+			Secret:  s.Secret,
+		})
+		var ok bool
+		if err := e.peers.RequestPeer(s.Counterparty(), req, &ok); err != nil {
+			// The taker can still find the secret on-chain.
+			e.log.Warnf("Error sending redemption for match %s: %v", s.MatchID(), err)
+			return nil
+		}
+		s.RedeemSent = true
+		s.Status = StatusComplete
+	}
+	return nil
+}
+
+func (e *Engine) processTaker(ctx context.Context, s *Swap) error {
+	if s.Status == StatusNegotiating {
+		if s.CounterAddress == "" || len(s.CounterCoinID) == 0 {
+			// Waiting for the maker.
+			return nil
+		}
+		// The maker's contract must stay locked well past ours, or the maker
+		// could redeem ours and then refund theirs before we find the secret.
+		ai, err := e.auditCounterContract(s, MakerLockTime, 0)
+		if err != nil {
+			return fmt.Errorf("audit of maker contract failed: %w", err)
+		}
+		if len(ai.SecretHash) != sha256.Size {
+			return fmt.Errorf("invalid secret hash length %d", len(ai.SecretHash))
+		}
+		// Don't fund our contract until the maker's can't be double-spent.
+		if confirmed, err := e.counterConfirmed(ctx, s); err != nil || !confirmed {
+			return err
+		}
+		s.SecretHash = ai.SecretHash
+		if err := e.initiate(s, TakerLockTime); err != nil {
+			return err
+		}
+		s.Status = StatusTakerSwapCast
+		if err := e.store(s); err != nil {
+			return err
+		}
+	}
+	if !s.InitSent {
+		if err := e.sendInit(s); err != nil {
+			return err
+		}
+	}
+	if len(s.Secret) == 0 {
+		if err := e.findSecret(ctx, s); err != nil {
+			return err
+		}
+		if len(s.Secret) == 0 {
+			return e.maybeRefund(ctx, s)
+		}
+	}
+	if err := e.redeem(s); err != nil {
+		return err
+	}
+	s.Status = StatusComplete
+	return nil
+}
+
+// initiate funds and broadcasts our swap contract, locked until lockTime after
+// the match stamp. The caller must store the swap before sending the
+// swap_init, so that a restart doesn't initiate a second contract.
+func (e *Engine) initiate(s *Swap, lockTime time.Duration) error {
+	assetID, qty := s.FromAsset()
+	w, err := e.wallets(assetID)
+	if err != nil {
+		return err
+	}
+	feeRate := feeRate(w)
+	coins, _, _, err := w.FundOrder(&asset.Order{
+		AssetVersion:  assetVersion(w),
+		Value:         qty,
+		MaxSwapCount:  1,
+		MaxFeeRate:    feeRate,
+		Immediate:     true,
+		FeeSuggestion: feeRate,
+	})
+	if err != nil {
+		return fmt.Errorf("error funding %s swap: %w", dex.BipIDSymbol(assetID), err)
+	}
+	receipts, _, _, err := w.Swap(&asset.Swaps{
+		AssetVersion: assetVersion(w),
+		Inputs:       coins,
+		Contracts: []*asset.Contract{{
+			Address:    s.CounterAddress,
+			Value:      qty,
+			SecretHash: s.SecretHash,
+			LockTime:   uint64(s.Match.Stamp.Add(lockTime).Unix()),
+		}},
+		FeeRate: feeRate,
+	})
+	if err != nil {
+		if err := w.ReturnCoins(coins); err != nil {
+			e.log.Errorf("Error returning coins: %v", err)
+		}
+		return fmt.Errorf("error broadcasting %s swap: %w", dex.BipIDSymbol(assetID), err)
+	}
+	if len(receipts) != 1 {
+		return fmt.Errorf("expected 1 swap receipt, got %d", len(receipts))
+	}
+	s.CoinID = receipts[0].Coin().ID()
+	s.Contract = receipts[0].Contract()
+	return nil
+}
+
+func (e *Engine) sendInit(s *Swap) error {
+	init := &mj.SwapInit{
+		MatchID:  s.MatchID(),
+		CoinID:   s.CoinID,
+		Contract: s.Contract,
+	}
+	if s.Maker {
+		sm := s.signedMatch()
+		sm.Sign(e.priv)
+		init.Sig = sm.Sig
+	}
+	req := mj.MustRequest(mj.RouteSwapInit, init)
+	var ok bool
+	if err := e.peers.RequestPeer(s.Counterparty(), req, &ok); err != nil {
+		return fmt.Errorf("error sending swap init: %w", err)
+	}
+	s.InitSent = true
+	return e.store(s)
+}
+
+// auditCounterContract audits the counterparty's contract, checking that it
+// pays the expected amount to our address with our secret hash, that it is
+// locked until at least lockTime after the match stamp, and that it doesn't
+// expire within minRemaining.
+func (e *Engine) auditCounterContract(s *Swap, lockTime, minRemaining time.Duration) (*asset.AuditInfo, error) {
+	assetID, qty := s.ToAsset()
+	w, err := e.wallets(assetID)
+	if err != nil {
+		return nil, err
+	}
+	ai, err := w.AuditContract(s.CounterCoinID, s.CounterContract, nil, false)
+	if err != nil {
+		return nil, err
+	}
+	if ai.Recipient != s.Address {
+		return nil, fmt.Errorf("contract recipient %s is not our address %s", ai.Recipient, s.Address)
+	}
+	if v := ai.Coin.Value(); v < qty {
+		return nil, fmt.Errorf("contract value %d is less than the matched quantity %d", v, qty)
+	}
+	if len(s.SecretHash) > 0 && !s.SecretHash.Equal(ai.SecretHash) {
+		return nil, errors.New("contract secret hash does not match")
+	}
+	// Contract lock times have second precision.
+	if reqLockTime := s.Match.Stamp.Add(lockTime).Truncate(time.Second); ai.Expiration.Before(reqLockTime) {
+		return nil, fmt.Errorf("contract lock time %s is before the required %s", ai.Expiration, reqLockTime)
+	}
+	if time.Until(ai.Expiration) < minRemaining {
+		return nil, fmt.Errorf("contract expires too soon at %s", ai.Expiration)
+	}
+	return ai, nil
+}
+
+// counterConfirmed checks whether the counterparty's contract has the
+// confirmations required for its asset. An error is returned if the contract
+// has already been spent.
+func (e *Engine) counterConfirmed(ctx context.Context, s *Swap) (bool, error) {
+	assetID, _ := s.ToAsset()
+	w, err := e.wallets(assetID)
+	if err != nil {
+		return false, err
+	}
+	confs, spent, err := w.SwapConfirmations(ctx, s.CounterCoinID, s.CounterContract, s.Match.Stamp)
+	if err != nil {
+		return false, fmt.Errorf("error getting counterparty contract confirmations: %w", err)
+	}
+	if spent {
+		return false, errors.New("counterparty contract already spent")
+	}
+	if req := e.swapConfs(assetID); confs < req {
+		e.log.Debugf("Counterparty contract for match %s has %d of %d required confirmations", s.MatchID(), confs, req)
+		return false, nil
+	}
+	return true, nil
+}
+
+// redeem redeems the counterparty's contract.
+func (e *Engine) redeem(s *Swap) error {
+	ai, err := e.auditCounterContract(s, 0, 0)
+	if err != nil {
+		return fmt.Errorf("error auditing contract for redemption: %w", err)
+	}
+	assetID, _ := s.ToAsset()
+	w, err := e.wallets(assetID)
+	if err != nil {
+		return err
+	}
+	_, out, _, err := w.Redeem(&asset.RedeemForm{
+		Redemptions:   []*asset.Redemption{{Spends: ai, Secret: s.Secret}},
+		FeeSuggestion: feeRate(w),
+	})
+	if err != nil {
+		return fmt.Errorf("error redeeming %s contract: %w", dex.BipIDSymbol(assetID), err)
+	}
+	s.RedeemCoinID = out.ID()
+	return nil
+}
+
+// findSecret checks whether the maker has redeemed our contract, and if so,
+// finds the secret on-chain.
+func (e *Engine) findSecret(ctx context.Context, s *Swap) error {
+	assetID, _ := s.FromAsset()
+	w, err := e.wallets(assetID)
+	if err != nil {
+		return err
+	}
+	_, spent, err := w.SwapConfirmations(ctx, s.CoinID, s.Contract, s.Match.Stamp)
+	if err != nil || !spent {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(ctx, findRedeemLimit)
+	defer cancel()
+	_, secret, err := w.FindRedemption(ctx, s.CoinID, s.Contract)
+	if err != nil {
+		return fmt.Errorf("error finding redemption of our contract: %w", err)
+	}
+	if !w.ValidateSecret(secret, s.SecretHash) {
+		return errors.New("found secret does not match secret hash")
+	}
+	s.Secret = secret
+	return e.store(s)
+}
+
+// maybeRefund refunds our contract if its lock time has expired.
+func (e *Engine) maybeRefund(ctx context.Context, s *Swap) error {
+	assetID, _ := s.FromAsset()
+	w, err := e.wallets(assetID)
+	if err != nil {
+		return err
+	}
+	expired, _, err := w.ContractLockTimeExpired(ctx, s.Contract)
+	if err != nil || !expired {
+		return err
+	}
+	refundCoin, err := w.Refund(s.CoinID, s.Contract, feeRate(w))
+	if err != nil {
+		return fmt.Errorf("error refunding %s contract: %w", dex.BipIDSymbol(assetID), err)
+	}
+	s.RefundCoinID = refundCoin
+	s.Status = StatusRefunded
+	return nil
+}
+
+// assetVersion is the latest asset version supported by the wallet.
+func assetVersion(w asset.Wallet) (ver uint32) {
+	for _, v := range w.Info().SupportedVersions {
+		if v > ver {
+			ver = v
+		}
+	}
+	return ver
+}
+
+// feeRate is the wallet's fee rate if it is a FeeRater.
+func feeRate(w asset.Wallet) uint64 {
+	if fr, is := w.(asset.FeeRater); is {
+		return fr.FeeRate()
+	}
+	return 0
+}
@@ -0,0 +1,647 @@
+package swap
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"decred.org/dcrdex/client/asset"
+	"decred.org/dcrdex/dex"
+	"decred.org/dcrdex/dex/calc"
+	"decred.org/dcrdex/dex/encode"
+	"decred.org/dcrdex/dex/lexi"
+	"decred.org/dcrdex/dex/msgjson"
+	"decred.org/dcrdex/tatanka/tanka"
+	"github.com/decred/dcrd/dcrec/secp256k1/v4"
+)
+
+const (
+	tBaseID  = 42
+	tQuoteID = 0
+)
+
+var tLogger = dex.StdOutLogger("T", dex.LevelInfo)
+
+type tContract struct {
+	recipient  string
+	value      uint64
+	secretHash []byte
+	expiration time.Time
+	secret     []byte
+	refunded   bool
+}
+
+// tChain is shared by the wallets of both peers.
+type tChain struct {
+	contracts   map[string]*tContract
+	expired     bool
+	unconfirmed bool
+}
+
+type tCoin struct {
+	id    dex.Bytes
+	value uint64
+}
+
+func (c *tCoin) ID() dex.Bytes  { return c.id }
+func (c *tCoin) String() string { return c.id.String() }
+func (c *tCoin) Value() uint64  { return c.value }
+func (c *tCoin) TxID() string   { return c.id.String() }
+
+type tReceipt struct {
+	coin     *tCoin
+	contract dex.Bytes
+}
+
+func (r *tReceipt) Expiration() time.Time   { return time.Time{} }
+func (r *tReceipt) Coin() asset.Coin        { return r.coin }
+func (r *tReceipt) Contract() dex.Bytes     { return r.contract }
+func (r *tReceipt) String() string          { return r.coin.String() }
+func (r *tReceipt) SignedRefund() dex.Bytes { return nil }
+
+type tWallet struct {
+	asset.Wallet
+	chain    *tChain
+	addr     string
+	fundErr  error
+	returned bool
+}
+
+func (w *tWallet) Info() *asset.WalletInfo {
+	return &asset.WalletInfo{SupportedVersions: []uint32{0}}
+}
+
+func (w *tWallet) RedemptionAddress() (string, error) {
+	return w.addr, nil
+}
+
+func (w *tWallet) FundOrder(ord *asset.Order) (asset.Coins, []dex.Bytes, uint64, error) {
+	if w.fundErr != nil {
+		return nil, nil, 0, w.fundErr
+	}
+	return asset.Coins{&tCoin{id: encode.RandomBytes(36), value: ord.Value}}, nil, 0, nil
+}
+
+func (w *tWallet) ReturnCoins(asset.Coins) error {
+	w.returned = true
+	return nil
+}
+
+func (w *tWallet) Swap(swaps *asset.Swaps) ([]asset.Receipt, asset.Coin, uint64, error) {
+	receipts := make([]asset.Receipt, 0, len(swaps.Contracts))
+	for _, c := range swaps.Contracts {
+		coin := &tCoin{id: encode.RandomBytes(36), value: c.Value}
+		w.chain.contracts[coin.id.String()] = &tContract{
+			recipient:  c.Address,
+			value:      c.Value,
+			secretHash: c.SecretHash,
+			expiration: time.Unix(int64(c.LockTime), 0),
+		}
+		receipts = append(receipts, &tReceipt{coin: coin, contract: coin.id})
+	}
+	return receipts, nil, 0, nil
+}
+
+func (w *tWallet) contract(coinID dex.Bytes) (*tContract, error) {
+	c := w.chain.contracts[coinID.String()]
+	if c == nil {
+		return nil, errors.New("contract not found")
+	}
+	return c, nil
+}
+
+func (w *tWallet) AuditContract(coinID, contract, txData dex.Bytes, rebroadcast bool) (*asset.AuditInfo, error) {
+	c, err := w.contract(coinID)
+	if err != nil {
+		return nil, err
+	}
+	return &asset.AuditInfo{
+		Recipient:  c.recipient,
+		Expiration: c.expiration,
+		Coin:       &tCoin{id: coinID, value: c.value},
+		Contract:   contract,
+		SecretHash: c.secretHash,
+	}, nil
+}
+
+func (w *tWallet) Redeem(form *asset.RedeemForm) ([]dex.Bytes, asset.Coin, uint64, error) {
+	for _, r := range form.Redemptions {
+		c, err := w.contract(r.Spends.Coin.ID())
+		if err != nil {
+			return nil, nil, 0, err
+		}
+		if !w.ValidateSecret(r.Secret, c.secretHash) {
+			return nil, nil, 0, errors.New("wrong secret")
+		}
+		c.secret = r.Secret
+	}
+	return nil, &tCoin{id: encode.RandomBytes(36)}, 0, nil
+}
+
+func (w *tWallet) SwapConfirmations(ctx context.Context, coinID dex.Bytes, contract dex.Bytes, matchTime time.Time) (uint32, bool, error) {
+	c, err := w.contract(coinID)
+	if err != nil {
+		return 0, false, err
+	}
+	if w.chain.unconfirmed {
+		return 0, c.secret != nil, nil
+	}
+	return 1, c.secret != nil, nil
+}
+
+func (w *tWallet) FindRedemption(ctx context.Context, coinID, contract dex.Bytes) (dex.Bytes, dex.Bytes, error) {
+	c, err := w.contract(coinID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if c.secret == nil {
+		return nil, nil, errors.New("not redeemed")
+	}
+	return encode.RandomBytes(36), c.secret, nil
+}
+
+func (w *tWallet) ValidateSecret(secret, secretHash []byte) bool {
+	h := sha256.Sum256(secret)
+	return dex.Bytes(secretHash).Equal(h[:])
+}
+
+func (w *tWallet) ContractLockTimeExpired(ctx context.Context, contract dex.Bytes) (bool, time.Time, error) {
+	return w.chain.expired, time.Time{}, nil
+}
+
+func (w *tWallet) Refund(coinID, contract dex.Bytes, feeRate uint64) (dex.Bytes, error) {
+	c, err := w.contract(coinID)
+	if err != nil {
+		return nil, err
+	}
+	c.refunded = true
+	return encode.RandomBytes(36), nil
+}
+
+// tPeers routes tankagrams directly to the counterparty's Engine.
+type tPeers struct {
+	from    tanka.PeerID
+	engines map[tanka.PeerID]*Engine
+	err     error
+}
+
+func (p *tPeers) RequestPeer(peerID tanka.PeerID, msg *msgjson.Message, thing any) error {
+	if p.err != nil {
+		return p.err
+	}
+	e := p.engines[peerID]
+	if e == nil {
+		return fmt.Errorf("unknown peer %s", peerID)
+	}
+	res, err := e.HandlePeerMessage(p.from, msg)
+	if err != nil {
+		return err
+	}
+	b, _ := json.Marshal(res)
+	return json.Unmarshal(b, thing)
+}
+
+type tPeer struct {
+	id      tanka.PeerID
+	priv    *secp256k1.PrivateKey
+	db      *lexi.DB
+	wallets map[uint32]*tWallet
+	peers   *tPeers
+	engine  *Engine
+}
+
+func newTestPeer(t *testing.T, name string, chains map[uint32]*tChain, engines map[tanka.PeerID]*Engine) *tPeer {
+	t.Helper()
+	db, err := lexi.New(&lexi.Config{
+		Path: filepath.Join(t.TempDir(), name+".db"),
+		Log:  tLogger,
+	})
+	if err != nil {
+		t.Fatalf("error constructing db: %v", err)
+	}
+	priv, err := secp256k1.GeneratePrivateKey()
+	if err != nil {
+		t.Fatalf("error generating key: %v", err)
+	}
+	var id tanka.PeerID
+	copy(id[:], priv.PubKey().SerializeCompressed())
+	p := &tPeer{
+		id:      id,
+		priv:    priv,
+		db:      db,
+		wallets: make(map[uint32]*tWallet),
+		peers:   &tPeers{from: id, engines: engines},
+	}
+	for assetID, chain := range chains {
+		p.wallets[assetID] = &tWallet{chain: chain, addr: fmt.Sprintf("%s-%s", name, dex.BipIDSymbol(assetID))}
+	}
+	p.newEngine(t)
+	return p
+}
+
+func (p *tPeer) newEngine(t *testing.T) {
+	t.Helper()
+	e, err := New(&Config{
+		PrivateKey: p.priv,
+		DB:         p.db,
+		Wallets: func(assetID uint32) (asset.Wallet, error) {
+			w, found := p.wallets[assetID]
+			if !found {
+				return nil, errors.New("no wallet")
+			}
+			return w, nil
+		},
+		Peers:  p.peers,
+		Logger: tLogger,
+	})
+	if err != nil {
+		t.Fatalf("error constructing engine: %v", err)
+	}
+	p.engine = e
+	p.peers.engines[p.id] = e
+}
+
+func (p *tPeer) swap(t *testing.T, matchID tanka.ID32) *Swap {
+	t.Helper()
+	var s Swap
+	if err := p.engine.table.Get(matchID[:], lexi.JSON(&s)); err != nil {
+		t.Fatalf("error retrieving swap: %v", err)
+	}
+	return &s
+}
+
+func newTestMatch(maker, taker tanka.PeerID, sell bool) (*tanka.Order, *tanka.Match) {
+	ord := &tanka.Order{
+		From:    maker,
+		BaseID:  tBaseID,
+		QuoteID: tQuoteID,
+		Sell:    sell,
+		Qty:     4e8,
+		Rate:    calc.MessageRateAlt(0.5, 1e8, 1e8),
+		LotSize: 1 << 26,
+		Nonce:   1,
+		Stamp:   time.Now(),
+	}
+	match := &tanka.Match{
+		From:    taker,
+		OrderID: ord.ID(),
+		Qty:     1 << 27,
+		Stamp:   time.Now(),
+	}
+	return ord, match
+}
+
+func newTestPeers(t *testing.T) (maker, taker *tPeer, chains map[uint32]*tChain) {
+	chains = map[uint32]*tChain{
+		tBaseID:  {contracts: make(map[string]*tContract)},
+		tQuoteID: {contracts: make(map[string]*tContract)},
+	}
+	engines := make(map[tanka.PeerID]*Engine)
+	return newTestPeer(t, "maker", chains, engines), newTestPeer(t, "taker", chains, engines), chains
+}
+
+func TestSwap(t *testing.T) {
+	for _, sell := range []bool{true, false} {
+		t.Run(fmt.Sprintf("sell=%t", sell), func(t *testing.T) {
+			testSwap(t, sell)
+		})
+	}
+}
+
+func testSwap(t *testing.T, sell bool) {
+	ctx := context.Background()
+	maker, taker, chains := newTestPeers(t)
+	ord, match := newTestMatch(maker.id, taker.id, sell)
+	matchID := match.ID()
+
+	// The maker can't get the taker's address until the taker knows about the
+	// match, so the maker waits.
+	if err := maker.engine.Trade(ord, match); err != nil {
+		t.Fatalf("maker Trade error: %v", err)
+	}
+	maker.engine.processAll(ctx)
+	if s := maker.swap(t, matchID); s.Status != StatusNegotiating || !s.Maker || len(s.SecretHash) != 32 {
+		t.Fatalf("wrong maker swap state after failed address request: %+v", s)
+	}
+
+	if err := taker.engine.Trade(ord, match); err != nil {
+		t.Fatalf("taker Trade error: %v", err)
+	}
+	if s := taker.swap(t, matchID); s.Maker || len(s.Secret) != 0 {
+		t.Fatalf("taker swap should not have a secret")
+	}
+
+	// Maker exchanges addresses, initiates, and sends the swap_init.
+	maker.engine.processAll(ctx)
+	makerSwap := maker.swap(t, matchID)
+	if makerSwap.Status != StatusMakerSwapCast || !makerSwap.InitSent {
+		t.Fatalf("wrong maker status %s after init. sent = %t", makerSwap.Status, makerSwap.InitSent)
+	}
+	fromID, fromQty := makerSwap.FromAsset()
+	wantFromID, wantFromQty := uint32(tBaseID), match.Qty
+	if !sell {
+		wantFromID, wantFromQty = tQuoteID, calc.BaseToQuote(ord.Rate, match.Qty)
+	}
+	if fromID != wantFromID || fromQty != wantFromQty {
+		t.Fatalf("wrong maker from asset. wanted %d %d, got %d %d", wantFromID, wantFromQty, fromID, fromQty)
+	}
+	makerContract := chains[fromID].contracts[makerSwap.CoinID.String()]
+	if makerContract == nil {
+		t.Fatalf("maker contract not found")
+	}
+	if makerContract.recipient != taker.wallets[fromID].addr || makerContract.value != fromQty {
+		t.Fatalf("wrong maker contract %+v", makerContract)
+	}
+
+	// Restart the maker. The swap should be resumed from the DB.
+	maker.newEngine(t)
+	if swaps := maker.engine.Swaps(); len(swaps) != 1 || swaps[0].Status != StatusMakerSwapCast {
+		t.Fatalf("swap not loaded from DB")
+	}
+
+	// Taker audits and initiates.
+	taker.engine.processAll(ctx)
+	takerSwap := taker.swap(t, matchID)
+	if takerSwap.Status != StatusTakerSwapCast || !takerSwap.InitSent {
+		t.Fatalf("wrong taker status %s after init. sent = %t", takerSwap.Status, takerSwap.InitSent)
+	}
+	if !takerSwap.SecretHash.Equal(makerSwap.SecretHash) {
+		t.Fatalf("taker has the wrong secret hash")
+	}
+	toID, _ := makerSwap.ToAsset()
+	takerContract := chains[toID].contracts[takerSwap.CoinID.String()]
+	if takerContract == nil || takerContract.recipient != maker.wallets[toID].addr {
+		t.Fatalf("wrong taker contract %+v", takerContract)
+	}
+	if !takerContract.expiration.Before(makerContract.expiration) {
+		t.Fatalf("taker contract expires after maker contract")
+	}
+
+	// Maker audits, redeems, and sends the secret.
+	maker.engine.processAll(ctx)
+	if s := maker.swap(t, matchID); s.Status != StatusComplete || !s.RedeemSent {
+		t.Fatalf("wrong maker status %s after redeem", s.Status)
+	}
+	if takerContract.secret == nil {
+		t.Fatalf("taker contract not redeemed")
+	}
+	if len(maker.engine.Swaps()) != 0 {
+		t.Fatalf("completed swap still active")
+	}
+
+	// Taker redeems.
+	taker.engine.processAll(ctx)
+	if s := taker.swap(t, matchID); s.Status != StatusComplete {
+		t.Fatalf("wrong taker status %s after redeem", s.Status)
+	}
+	if makerContract.secret == nil {
+		t.Fatalf("maker contract not redeemed")
+	}
+}
+
+func TestSwapFindSecret(t *testing.T) {
+	ctx := context.Background()
+	maker, taker, _ := newTestPeers(t)
+	ord, match := newTestMatch(maker.id, taker.id, true)
+	matchID := match.ID()
+	if err := maker.engine.Trade(ord, match); err != nil {
+		t.Fatalf("maker Trade error: %v", err)
+	}
+	if err := taker.engine.Trade(ord, match); err != nil {
+		t.Fatalf("taker Trade error: %v", err)
+	}
+	maker.engine.processAll(ctx)
+	taker.engine.processAll(ctx)
+
+	// The maker redeems, but the swap_redeem tankagram doesn't make it.
+	maker.peers.err = errors.New("test error")
+	maker.engine.processAll(ctx)
+	if s := maker.swap(t, matchID); s.Status != StatusMakerRedeemed || s.RedeemSent {
+		t.Fatalf("wrong maker status %s", s.Status)
+	}
+
+	// Taker finds the secret on-chain.
+	taker.engine.processAll(ctx)
+	if s := taker.swap(t, matchID); s.Status != StatusComplete || len(s.Secret) == 0 {
+		t.Fatalf("wrong taker status %s", s.Status)
+	}
+}
+
+func TestSwapConfirmations(t *testing.T) {
+	ctx := context.Background()
+	maker, taker, chains := newTestPeers(t)
+	ord, match := newTestMatch(maker.id, taker.id, true)
+	matchID := match.ID()
+	if err := maker.engine.Trade(ord, match); err != nil {
+		t.Fatalf("maker Trade error: %v", err)
+	}
+	if err := taker.engine.Trade(ord, match); err != nil {
+		t.Fatalf("taker Trade error: %v", err)
+	}
+	maker.engine.processAll(ctx)
+
+	// The taker won't initiate until the maker's contract is confirmed.
+	chains[tBaseID].unconfirmed = true
+	taker.engine.processAll(ctx)
+	if s := taker.swap(t, matchID); s.Status != StatusNegotiating || len(s.CoinID) != 0 {
+		t.Fatalf("taker initiated against an unconfirmed contract. status = %s", s.Status)
+	}
+	chains[tBaseID].unconfirmed = false
+	taker.engine.processAll(ctx)
+	if s := taker.swap(t, matchID); s.Status != StatusTakerSwapCast {
+		t.Fatalf("wrong taker status %s after maker contract confirmed", s.Status)
+	}
+
+	// The maker won't redeem until the taker's contract is confirmed.
+	chains[tQuoteID].unconfirmed = true
+	maker.engine.processAll(ctx)
+	if s := maker.swap(t, matchID); s.Status != StatusMakerSwapCast || len(s.RedeemCoinID) != 0 {
+		t.Fatalf("maker redeemed an unconfirmed contract. status = %s", s.Status)
+	}
+	chains[tQuoteID].unconfirmed = false
+	maker.engine.processAll(ctx)
+	if s := maker.swap(t, matchID); s.Status != StatusComplete {
+		t.Fatalf("wrong maker status %s after taker contract confirmed", s.Status)
+	}
+}
+
+func TestSwapRefund(t *testing.T) {
+	ctx := context.Background()
+	maker, taker, chains := newTestPeers(t)
+	ord, match := newTestMatch(maker.id, taker.id, true)
+	matchID := match.ID()
+	if err := maker.engine.Trade(ord, match); err != nil {
+		t.Fatalf("maker Trade error: %v", err)
+	}
+	if err := taker.engine.Trade(ord, match); err != nil {
+		t.Fatalf("taker Trade error: %v", err)
+	}
+	maker.engine.processAll(ctx)
+
+	// The taker disappears.
+	delete(maker.peers.engines, taker.id)
+	maker.engine.processAll(ctx)
+	if s := maker.swap(t, matchID); s.Status != StatusMakerSwapCast {
+		t.Fatalf("maker refunded before lock time expired")
+	}
+
+	chains[tBaseID].expired = true
+	maker.engine.processAll(ctx)
+	s := maker.swap(t, matchID)
+	if s.Status != StatusRefunded || len(s.RefundCoinID) == 0 {
+		t.Fatalf("maker not refunded. status = %s", s.Status)
+	}
+	if !chains[tBaseID].contracts[s.CoinID.String()].refunded {
+		t.Fatalf("contract not refunded")
+	}
+
+	// Without the taker's contract, there's nothing to dispute.
+	if _, err := s.Dispute(); err == nil {
+		t.Fatalf("no error for dispute without the counterparty's contract")
+	}
+	s.CounterCoinID, s.CounterContract = []byte("theirs"), []byte("contract")
+	d, err := s.Dispute()
+	if err != nil {
+		t.Fatalf("Dispute error: %v", err)
+	}
+	if d.Match == nil || d.Match.Match.ID() != matchID || d.Match.Verify(taker.id) != nil {
+		t.Fatalf("wrong dispute %+v", d)
+	}
+	if d.Ours.AssetID != tBaseID || !bytes.Equal(d.Ours.CoinID, s.CoinID) || d.Ours.Recipient != s.CounterAddress {
+		t.Fatalf("wrong audit %+v", d.Ours)
+	}
+	if d.Theirs.AssetID != tQuoteID || !bytes.Equal(d.Theirs.CoinID, s.CounterCoinID) || d.Theirs.Recipient != s.Address {
+		t.Fatalf("wrong counterparty audit %+v", d.Theirs)
+	}
+}
+
+func TestSwapValidation(t *testing.T) {
+	maker, taker, _ := newTestPeers(t)
+	ord, match := newTestMatch(maker.id, taker.id, true)
+	matchID := match.ID()
+
+	// Not our match.
+	var stranger tanka.PeerID
+	copy(stranger[:], encode.RandomBytes(len(stranger)))
+	strangerOrd, strangerMatch := newTestMatch(stranger, taker.id, true)
+	if err := maker.engine.Trade(strangerOrd, strangerMatch); err != nil || len(maker.engine.Swaps()) != 0 {
+		t.Fatalf("swap started for match that isn't ours. err = %v", err)
+	}
+
+	// Bad quantity.
+	badMatch := *match
+	badMatch.Qty = ord.LotSize + 1
+	if err := maker.engine.Trade(ord, &badMatch); err == nil {
+		t.Fatalf("no error for bad match quantity")
+	}
+
+	// The maker won't accept a stale or future match stamp.
+	for _, d := range []time.Duration{-2 * tanka.MaxMatchStampSkew, 2 * tanka.MaxMatchStampSkew} {
+		staleMatch := *match
+		staleMatch.Stamp = time.Now().Add(d)
+		if err := maker.engine.Trade(ord, &staleMatch); err == nil {
+			t.Fatalf("no error for match stamp offset %s", d)
+		}
+	}
+
+	// Missing wallet.
+	w := maker.wallets[tQuoteID]
+	delete(maker.wallets, tQuoteID)
+	if err := maker.engine.Trade(ord, match); err == nil {
+		t.Fatalf("no error for missing wallet")
+	}
+	maker.wallets[tQuoteID] = w
+
+	if err := maker.engine.Trade(ord, match); err != nil {
+		t.Fatalf("maker Trade error: %v", err)
+	}
+	if err := taker.engine.Trade(ord, match); err != nil {
+		t.Fatalf("taker Trade error: %v", err)
+	}
+
+	// Messages from a peer that isn't the counterparty are rejected.
+	msg, _ := msgjson.NewRequest(1, "swap_init", map[string]any{"matchID": matchID, "coinID": "01", "contract": "01"})
+	if _, err := taker.engine.HandlePeerMessage(stranger, msg); err == nil {
+		t.Fatalf("no error for swap_init from stranger")
+	}
+
+	// The maker doesn't accept a taker contract before its own.
+	if _, err := maker.engine.HandlePeerMessage(taker.id, msg); err == nil {
+		t.Fatalf("no error for early taker contract")
+	}
+
+	// The taker doesn't accept a maker contract without the maker's signature
+	// of the match.
+	if _, err := taker.engine.HandlePeerMessage(maker.id, msg); err == nil {
+		t.Fatalf("no error for unsigned maker contract")
+	}
+	if s := taker.swap(t, matchID); len(s.CounterCoinID) != 0 {
+		t.Fatalf("unsigned maker contract saved")
+	}
+
+	// A funding error is retried.
+	maker.wallets[tBaseID].fundErr = errors.New("test error")
+	maker.engine.processAll(context.Background())
+	if s := maker.swap(t, matchID); s.Status != StatusNegotiating || s.CounterAddress == "" {
+		t.Fatalf("wrong maker state after funding error. status = %s", s.Status)
+	}
+	maker.wallets[tBaseID].fundErr = nil
+	maker.engine.processAll(context.Background())
+	if s := maker.swap(t, matchID); s.Status != StatusMakerSwapCast {
+		t.Fatalf("wrong maker state after funding error resolved. status = %s", s.Status)
+	}
+
+	// A redemption with the wrong secret is rejected.
+	taker.engine.processAll(context.Background())
+	msg, _ = msgjson.NewRequest(1, "swap_redeem", map[string]any{"matchID": matchID, "coinID": "01", "secret": dex.Bytes(encode.RandomBytes(32))})
+	if _, err := taker.engine.HandlePeerMessage(maker.id, msg); err == nil {
+		t.Fatalf("no error for wrong secret")
+	}
+}
+
+func TestSwapLockTimes(t *testing.T) {
+	ctx := context.Background()
+	maker, taker, chains := newTestPeers(t)
+	ord, match := newTestMatch(maker.id, taker.id, true)
+	matchID := match.ID()
+	if err := maker.engine.Trade(ord, match); err != nil {
+		t.Fatalf("maker Trade error: %v", err)
+	}
+	if err := taker.engine.Trade(ord, match); err != nil {
+		t.Fatalf("taker Trade error: %v", err)
+	}
+	maker.engine.processAll(ctx)
+	makerSwap := maker.swap(t, matchID)
+	makerContract := chains[tBaseID].contracts[makerSwap.CoinID.String()]
+	if want := match.Stamp.Add(MakerLockTime).Truncate(time.Second); !makerContract.expiration.Equal(want) {
+		t.Fatalf("wrong maker lock time %s, wanted %s", makerContract.expiration, want)
+	}
+
+	// The taker won't initiate if the maker's contract doesn't leave a safety
+	// margin past the taker's lock time.
+	makerContract.expiration = match.Stamp.Add(TakerLockTime + time.Hour)
+	taker.engine.processAll(ctx)
+	if s := taker.swap(t, matchID); s.Status != StatusNegotiating || len(s.CoinID) != 0 {
+		t.Fatalf("taker initiated with short maker lock time. status = %s", s.Status)
+	}
+	makerContract.expiration = match.Stamp.Add(MakerLockTime).Truncate(time.Second)
+	taker.engine.processAll(ctx)
+	takerSwap := taker.swap(t, matchID)
+	if takerSwap.Status != StatusTakerSwapCast {
+		t.Fatalf("taker didn't initiate. status = %s", takerSwap.Status)
+	}
+
+	// The maker won't redeem a taker contract that expires too early.
+	takerContract := chains[tQuoteID].contracts[takerSwap.CoinID.String()]
+	takerContract.expiration = match.Stamp.Add(TakerLockTime - time.Hour)
+	maker.engine.processAll(ctx)
+	if s := maker.swap(t, matchID); s.Status != StatusMakerSwapCast || takerContract.secret != nil {
+		t.Fatalf("maker redeemed with short taker lock time. status = %s", s.Status)
+	}
+}
@@ -12,6 +12,20 @@ type DesiredTrade struct {
 	Qty  uint64
 	Rate uint64
 	Sell bool
+	// Reputation, if provided, looks up the reputation of a counterparty, e.g.
+	// from Mesh.Reputations. Orders from counterparties with a reputation
+	// score below MinReputation, or with an unknown reputation, are skipped.
+	Reputation    func(tanka.PeerID) *tanka.Reputation
+	MinReputation int64
+}
+
+// counterpartyOK checks the reputation of the order's owner.
+func (desire *DesiredTrade) counterpartyOK(ord *tanka.Order) bool {
+	if desire.Reputation == nil {
+		return true
+	}
+	rep := desire.Reputation(ord.From)
+	return rep != nil && rep.Score >= desire.MinReputation
 }
 
 // MatchProposal is a potential match based on our desired trade and the
@@ -36,6 +50,10 @@ func MatchBook(desire *DesiredTrade, p *FeeParameters, ords []*tanka.Order) (mat
 		} else if ord.Rate > desire.Rate {
 			break
 		}
+		// Check the counterparty's reputation.
+		if !desire.counterpartyOK(ord) {
+			continue
+		}
 		// Check lot size compatibility.
 		if compat, _ := OrderIsMatchable(desire.Qty, ord, p); !compat {
 			continue
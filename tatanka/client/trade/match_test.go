@@ -121,4 +121,28 @@ func TestMatchBook(t *testing.T) {
 	// Sanity check
 	reset()
 	testMatches(0, []uint64{baseQty})
+
+	// Filter counterparties by reputation.
+	goodPeer, badPeer := tanka.PeerID{0x01}, tanka.PeerID{0x02}
+	reps := map[tanka.PeerID]*tanka.Reputation{
+		goodPeer: {Score: 20, Depth: 1},
+		badPeer:  {Score: -100, Depth: 1},
+	}
+	desire.Reputation = func(peerID tanka.PeerID) *tanka.Reputation { return reps[peerID] }
+	ords[0].From = badPeer
+	testMatches(baseQty, nil)
+	ords = append(ords, &tanka.Order{
+		From:    goodPeer,
+		Rate:    msgRate,
+		Qty:     baseQty,
+		LotSize: lotSize,
+	})
+	testMatches(0, []uint64{baseQty})
+	// Raise the bar above the good peer's score.
+	desire.MinReputation = 21
+	testMatches(baseQty, nil)
+	// Unknown reputations are skipped.
+	desire.MinReputation = 0
+	ords[1].From = tanka.PeerID{0x03}
+	testMatches(baseQty, nil)
 }
@@ -7,11 +7,14 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"time"
 
 	"decred.org/dcrdex/dex"
 	"decred.org/dcrdex/dex/msgjson"
 	"decred.org/dcrdex/dex/utils"
+	"decred.org/dcrdex/tatanka/chain"
+	"decred.org/dcrdex/tatanka/db"
 	"decred.org/dcrdex/tatanka/mj"
 	"decred.org/dcrdex/tatanka/tanka"
 )
@@ -33,6 +36,10 @@ type clientJobNewRemote struct {
 // from the remoteClients map.
 type clientJobRemoteDisconnect clientJobNewRemote
 
+// clientJobCountRemotes is a clientJob that produces the number of remote
+// clients connected to each remote tatanka.
+type clientJobCountRemotes struct{}
+
 // clientJobFindRemotes is a clientJob that produces a list of remote tatanka
 // nodes to which the client is thought to be connected.
 type clientJobFindRemotes struct {
@@ -71,6 +78,14 @@ func (t *Tatanka) runRemoteClientsLoop(ctx context.Context) {
 				job.res <- true
 			case *clientJobFindRemotes:
 				job.res <- utils.CopyMap(t.remoteClients[task.clientID])
+			case *clientJobCountRemotes:
+				counts := make(map[tanka.PeerID]int)
+				for _, srvs := range t.remoteClients {
+					for tankaID := range srvs {
+						counts[tankaID]++
+					}
+				}
+				job.res <- counts
 			}
 		case <-ctx.Done():
 			return
@@ -113,6 +128,10 @@ func (t *Tatanka) handleClientConnect(cl tanka.Sender, msg *msgjson.Message) *ms
 		return msgjson.NewError(mj.ErrAuth, "signature error: %v", err)
 	}
 
+	if ban := t.banned(conn.ID); ban != nil {
+		return msgjson.NewError(mj.ErrBannned, "banned: %s", ban.Reason)
+	}
+
 	cl.SetPeerID(p.ID)
 
 	pp := &peer{Peer: p, Sender: cl, rrs: make(map[tanka.PeerID]*tanka.Reputation)}
@@ -350,7 +369,13 @@ func (t *Tatanka) handleSubscription(c *client, msg *msgjson.Message) *msgjson.E
 		}
 	}
 
-	t.sendResult(c, msg.ID, true)
+	if sub.Replay != nil {
+		// Still holding the clientMtx, so no broadcasts for the subject can be
+		// distributed before the replay is sent.
+		t.sendResult(c, msg.ID, t.bcastLog.replay(sub.Topic, sub.Subject, sub.Replay))
+	} else {
+		t.sendResult(c, msg.ID, true)
+	}
 	t.replySubscription(c, sub.Topic)
 	return nil
 }
@@ -431,52 +456,102 @@ func (t *Tatanka) skipRelay(msg *msgjson.Message) bool {
 	return exists
 }
 
+// checkMarketBroadcast checks that the orders and order updates in market
+// broadcasts are valid and not expired, so that stale orders aren't relayed.
+func checkMarketBroadcast(bcast *mj.Broadcast) error {
+	if bcast.Topic != mj.TopicMarket {
+		return nil
+	}
+	switch bcast.MessageType {
+	case mj.MessageTypeNewOrder:
+		var ord tanka.Order
+		if err := json.Unmarshal(bcast.Payload, &ord); err != nil {
+			return fmt.Errorf("error unmarshaling order: %w", err)
+		}
+		if ord.From != bcast.PeerID {
+			return errors.New("order is not from the broadcaster")
+		}
+		if err := ord.Valid(); err != nil {
+			return err
+		}
+		if ord.Expired() {
+			return errors.New("order is expired")
+		}
+	case mj.MessageTypeUpdateOrder:
+		var ou tanka.OrderUpdate
+		if err := json.Unmarshal(bcast.Payload, &ou); err != nil {
+			return fmt.Errorf("error unmarshaling order update: %w", err)
+		}
+		if ou.From != bcast.PeerID {
+			return errors.New("order update is not from the broadcaster")
+		}
+		if err := ou.Valid(); err != nil {
+			return err
+		}
+		if ou.Expired() {
+			return errors.New("order update is expired")
+		}
+	}
+	return nil
+}
+
 // distributeBroadcastedMessage distributes the broadcast to any
-// locally-connected subscribers.
+// locally-connected subscribers. Broadcasts to a subject with local
+// subscribers are logged with a sequence number for replay to later
+// subscribers.
 func (t *Tatanka) distributeBroadcastedMessage(bcast *mj.Broadcast, mustExist bool) *msgjson.Error {
-	relayedMsg := mj.MustNotification(mj.RouteBroadcast, bcast)
-	mj.SignMessage(t.priv, relayedMsg)
-	relayedMsgB, _ := json.Marshal(relayedMsg)
-
+	// Hold the clientMtx while logging, so that a concurrent subscription
+	// sees the broadcast either in its replay or as a live broadcast, but not
+	// both.
 	t.clientMtx.RLock()
 	defer t.clientMtx.RUnlock()
 	topic, found := t.topics[bcast.Topic]
 	if !found {
-		if mustExist {
-			t.log.Errorf("client %q broadcasted to an unknown topic %q", bcast.PeerID, bcast.Topic)
-			return msgjson.NewError(mj.ErrBadRequest, "unknown topic")
-		}
-		return nil
-	}
-
-	relay := func(subs map[tanka.PeerID]struct{}) {
-		for peerID := range subs {
-			subscriber, found := t.clients[peerID]
-			if !found {
-				t.log.Errorf("client not found for subscriber %s on topic %q, subject %q", peerID, bcast.Topic, bcast.Subject)
-				continue
-			}
-
-			if err := subscriber.Sender.SendRaw(relayedMsgB); err != nil {
-				// DRAFT TODO: Remove subscriber and client and disconnect?
-				// Or do that in (*Tatanka).send?
-				t.log.Errorf("Error relaying broadcast: %v", err)
-				continue
-			}
+		if !mustExist {
+			return nil
 		}
+		t.log.Errorf("client %q broadcasted to an unknown topic %q", bcast.PeerID, bcast.Topic)
+		return msgjson.NewError(mj.ErrBadRequest, "unknown topic")
 	}
 
-	if bcast.Subject == "" {
-		relay(topic.subscribers)
-	} else {
-		subs, found := topic.subjects[bcast.Subject]
+	subs := topic.subscribers
+	if bcast.Subject != "" {
+		subs, found = topic.subjects[bcast.Subject]
 		if !found {
 			if mustExist {
 				t.log.Errorf("client %s broadcasted to an unknown subject %q on topic %s", bcast.PeerID, bcast.Subject, bcast.Topic)
 			}
 			return msgjson.NewError(mj.ErrBadRequest, "unknown subject")
 		}
-		relay(subs)
+	}
+
+	// Only log once the subject is known, so that the log can't be filled
+	// with made-up subjects.
+	if isLoggable(bcast) {
+		bcast = t.bcastLog.add(bcast)
+	} else if bcast.Seq != 0 {
+		b := *bcast
+		b.Seq = 0
+		bcast = &b
+	}
+
+	relayedMsg := mj.MustNotification(mj.RouteBroadcast, bcast)
+	mj.SignMessage(t.priv, relayedMsg)
+	relayedMsgB, _ := json.Marshal(relayedMsg)
+
+	for peerID := range subs {
+		subscriber, found := t.clients[peerID]
+		if !found {
+			t.log.Errorf("client not found for subscriber %s on topic %q, subject %q", peerID, bcast.Topic, bcast.Subject)
+			continue
+		}
+
+		if err := subscriber.Sender.SendRaw(relayedMsgB); err != nil {
+			// DRAFT TODO: Remove subscriber and client and disconnect?
+			// Or do that in (*Tatanka).send?
+			t.log.Errorf("Error relaying broadcast: %v", err)
+			continue
+		}
 	}
 	return nil
 }
@@ -505,6 +580,28 @@ func (t *Tatanka) handleBroadcast(c *client, msg *msgjson.Message) *msgjson.Erro
 		return msgjson.NewError(mj.ErrBadRequest, "too old")
 	}
 
+	if err := checkMarketBroadcast(bcast); err != nil {
+		t.log.Debugf("Refusing market broadcast from %s: %v", p.ID, err)
+		return msgjson.NewError(mj.ErrBadRequest, "bad market broadcast: %v", err)
+	}
+
+	if err := checkBroadcastSize(bcast, msg.Payload); err != nil {
+		t.log.Debugf("Refusing broadcast from %s: %v", p.ID, err)
+		// A client could misjudge the size of a broadcast, so only repeat
+		// offenders are penalized.
+		if t.bcastLimiter.strike(p.ID) {
+			t.penalizeBroadcaster(p.ID, "repeated oversized broadcasts")
+		}
+		return msgjson.NewError(mj.ErrBadRequest, "%v", err)
+	}
+
+	if ok, penalize := t.bcastLimiter.allow(bcast, p.tier); !ok {
+		if penalize {
+			t.penalizeBroadcaster(p.ID, "rate limit exceeded")
+		}
+		return msgjson.NewError(mj.ErrRateLimited, "broadcast rate limit exceeded for topic %s", bcast.Topic)
+	}
+
 	// Relay to remote tatankas first.
 	t.relayBroadcast(bcast, p.ID)
 
@@ -647,16 +744,24 @@ func (t *Tatanka) handleSetScore(c *client, msg *msgjson.Message) {
 		t.log.Errorf("error unmarshaling set_score from %s: %v", scorer, err)
 		return
 	}
-	if err := t.db.SetScore(score.PeerID, scorer, score.Score, time.Now()); err != nil {
-		t.log.Errorf("error adding score from %s for %s to db: %v", scorer, score.PeerID, err)
+	if _, err := t.setScore(scorer, score.PeerID, score.Score); err != nil {
+		t.log.Errorf("error setting score from %s for %s: %v", scorer, score.PeerID, err)
+	}
+}
+
+// setScore records the scorer's score of the scored peer, updates the scored
+// peer's reputation if they are a local client, and shares the score with the
+// other tatanka nodes.
+func (t *Tatanka) setScore(scorer, scored tanka.PeerID, score int8) (*tanka.Reputation, error) {
+	if err := t.db.SetScore(scored, scorer, score, time.Now()); err != nil {
+		return nil, fmt.Errorf("error adding score from %s for %s to db: %w", scorer, scored, err)
 	}
-	rep, err := t.db.Reputation(score.PeerID)
+	rep, err := t.db.Reputation(scored)
 	if err != nil {
-		t.log.Errorf("error getting reputation after score update from %s for %s: %v", score.PeerID, scorer, err)
-		return
+		return nil, fmt.Errorf("error getting reputation after score update: %w", err)
 	}
 	t.clientMtx.RLock()
-	c, found := t.clients[score.PeerID]
+	c, found := t.clients[scored]
 	t.clientMtx.RUnlock()
 	if found {
 		c.mtx.Lock()
@@ -664,17 +769,201 @@ func (t *Tatanka) handleSetScore(c *client, msg *msgjson.Message) {
 		c.mtx.Unlock()
 	}
 
+	standing, err := t.db.Standing(scorer)
+	if err != nil {
+		t.log.Errorf("error getting standing for scorer %s: %v", scorer, err)
+	}
 	note := mj.MustNotification(mj.RouteShareScore, &mj.SharedScore{
 		Scorer:     scorer,
-		Scored:     score.PeerID,
-		Score:      score.Score,
+		Scored:     scored,
+		Score:      score,
 		Reputation: rep,
+		Standing:   standing,
 	})
 	for _, tt := range t.tatankaNodes() {
 		if err := t.send(tt, note); err != nil {
 			t.log.Errorf("error notifying %s of new score: %v", tt.ID, err)
 		}
 	}
+	return rep, nil
+}
+
+// handleAuditSwap resolves a swap dispute. The dispute must include the match
+// signed by the counterparty, and can only be made after the swap deadline,
+// when both contracts should have been broadcast. The disputing client's own
+// contract must pass an on-chain audit. If the counterparty's contract is
+// missing or fails the audit, the counterparty is scored with
+// tanka.ScoreSwapFault on behalf of the disputing client. At most one fault is
+// recorded per match.
+func (t *Tatanka) handleAuditSwap(c *client, msg *msgjson.Message) *msgjson.Error {
+	disputer := c.peer.ID
+	var dispute mj.SwapDispute
+	if err := msg.Unmarshal(&dispute); err != nil {
+		t.log.Errorf("error unmarshaling audit_swap from %s: %v", disputer, err)
+		return msgjson.NewError(mj.ErrBadRequest, "bad request")
+	}
+	sm := dispute.Match
+	if sm == nil {
+		return msgjson.NewError(mj.ErrBadRequest, "no signed match provided")
+	}
+	if err := sm.Valid(); err != nil {
+		return msgjson.NewError(mj.ErrBadRequest, "invalid match: %v", err)
+	}
+	if dispute.Ours == nil {
+		return msgjson.NewError(mj.ErrBadRequest, "no contract provided")
+	}
+	// A missing counterparty contract is not proof of fault. Anyone could
+	// leave it out to fault an honest counterparty.
+	if dispute.Theirs == nil {
+		return msgjson.NewError(mj.ErrBadRequest, "no counterparty contract provided")
+	}
+	maker, taker := sm.Order.From, sm.Match.From
+	makerContract, takerContract := sm.Contracts()
+	var counterparty tanka.PeerID
+	var ours, theirs *tanka.HTLCAudit
+	switch disputer {
+	case maker:
+		counterparty, ours, theirs = taker, makerContract, takerContract
+	case taker:
+		counterparty, ours, theirs = maker, takerContract, makerContract
+	default:
+		return msgjson.NewError(mj.ErrBadRequest, "not a party to the match")
+	}
+	if err := sm.Verify(counterparty); err != nil {
+		t.log.Errorf("Client %s disputed a match with a bad counterparty signature: %v", disputer, err)
+		return msgjson.NewError(mj.ErrSig, "counterparty signature doesn't check")
+	}
+	matchID := sm.Match.ID()
+	if deadline := sm.Match.Stamp.Add(tanka.TakerLockTime); time.Now().Before(deadline) {
+		return msgjson.NewError(mj.ErrBadRequest, "swap deadline %s has not passed", deadline)
+	}
+	if msgErr := matchContract(dispute.Ours, ours); msgErr != nil {
+		return msgErr
+	}
+	if msgErr := matchContract(dispute.Theirs, theirs); msgErr != nil {
+		return msgErr
+	}
+	if !theirs.SecretHash.Equal(ours.SecretHash) {
+		return msgjson.NewError(mj.ErrBadRequest, "contracts have different secret hashes")
+	}
+
+	ok, msgErr := t.auditHTLC(ours)
+	if msgErr != nil {
+		return msgErr
+	}
+	if !ok {
+		t.log.Infof("Client %s disputed match %s, but their own contract failed the audit", disputer, matchID)
+		return msgjson.NewError(mj.ErrBadRequest, "contract failed audit")
+	}
+
+	if ok, msgErr = t.auditHTLC(theirs); msgErr != nil {
+		return msgErr
+	}
+	fault := !ok
+
+	var rep *tanka.Reputation
+	var err error
+	if fault {
+		rep, err = t.recordSwapFault(disputer, counterparty, matchID)
+	} else {
+		rep, err = t.db.Reputation(counterparty)
+	}
+	if err != nil {
+		t.log.Errorf("error resolving dispute from %s for %s: %v", disputer, counterparty, err)
+		return msgjson.NewError(mj.ErrInternal, "internal error")
+	}
+
+	t.sendResult(c, msg.ID, &mj.SwapDisputeResult{
+		Fault:      fault,
+		Reputation: rep,
+	})
+	return nil
+}
+
+// matchContract checks that the disputed contract has the asset, recipient
+// and value required by the match, and fills in the required lock time.
+func matchContract(disputed, required *tanka.HTLCAudit) *msgjson.Error {
+	if disputed.AssetID != required.AssetID {
+		return msgjson.NewError(mj.ErrBadRequest, "contract is for asset %d, match requires %d", disputed.AssetID, required.AssetID)
+	}
+	if disputed.Recipient != required.Recipient {
+		return msgjson.NewError(mj.ErrBadRequest, "contract recipient %q is not the matched address %q", disputed.Recipient, required.Recipient)
+	}
+	if disputed.Value != required.Value {
+		return msgjson.NewError(mj.ErrBadRequest, "contract value %d is not the matched value %d", disputed.Value, required.Value)
+	}
+	if len(disputed.SecretHash) != 32 {
+		return msgjson.NewError(mj.ErrBadRequest, "invalid secret hash length %d", len(disputed.SecretHash))
+	}
+	required.CoinID = disputed.CoinID
+	required.Contract = disputed.Contract
+	required.SecretHash = disputed.SecretHash
+	return nil
+}
+
+// recordSwapFault scores the counterparty with tanka.ScoreSwapFault on behalf
+// of the disputer, unless a fault was already recorded for the match.
+func (t *Tatanka) recordSwapFault(disputer, counterparty tanka.PeerID, matchID tanka.ID32) (*tanka.Reputation, error) {
+	prevFault, err := t.db.SwapFault(matchID)
+	if err != nil {
+		return nil, fmt.Errorf("error checking for previous fault: %w", err)
+	}
+	if prevFault != nil {
+		t.log.Debugf("Fault for match %s was already recorded", matchID)
+		return t.db.Reputation(counterparty)
+	}
+	t.log.Infof("Client %s's counterparty %s is at fault in match %s", disputer, counterparty, matchID)
+	rep, err := t.setScore(disputer, counterparty, tanka.ScoreSwapFault)
+	if err != nil {
+		return nil, err
+	}
+	if err := t.db.RecordSwapFault(matchID, &db.SwapFault{PeerID: counterparty, Stamp: time.Now()}); err != nil {
+		return nil, fmt.Errorf("error recording fault: %w", err)
+	}
+	return rep, nil
+}
+
+// handleReputation sends the requested peers' reputations.
+func (t *Tatanka) handleReputation(c *client, msg *msgjson.Message) *msgjson.Error {
+	var req mj.ReputationRequest
+	if err := msg.Unmarshal(&req); err != nil {
+		t.log.Errorf("error unmarshaling reputation request from %s: %v", c.ID, err)
+		return msgjson.NewError(mj.ErrBadRequest, "bad request")
+	}
+	if len(req.PeerIDs) == 0 || len(req.PeerIDs) > mj.MaxReputationRequest {
+		return msgjson.NewError(mj.ErrBadRequest, "request between 1 and %d peers", mj.MaxReputationRequest)
+	}
+	reps := make([]*tanka.Reputation, len(req.PeerIDs))
+	for i, peerID := range req.PeerIDs {
+		rep, err := t.db.Reputation(peerID)
+		if err != nil {
+			t.log.Errorf("error getting reputation for %s: %v", peerID, err)
+			return msgjson.NewError(mj.ErrInternal, "internal error")
+		}
+		reps[i] = rep
+	}
+	t.sendResult(c, msg.ID, reps)
+	return nil
+}
+
+// auditHTLC audits the contract with the asset's chain backend.
+func (t *Tatanka) auditHTLC(a *tanka.HTLCAudit) (bool, *msgjson.Error) {
+	t.chainMtx.RLock()
+	ch := t.chains[a.AssetID]
+	t.chainMtx.RUnlock()
+	if ch == nil {
+		return false, msgjson.NewError(mj.ErrBadRequest, "unsupported asset")
+	}
+	auditor, is := ch.(chain.HTLCAuditor)
+	if !is {
+		return false, msgjson.NewError(mj.ErrBadRequest, "audits not supported for asset %d", a.AssetID)
+	}
+	ok, err := auditor.AuditHTLC(a)
+	if err != nil {
+		t.log.Errorf("error auditing contract %s for asset %d: %v", a.CoinID, a.AssetID, err)
+		return false, msgjson.NewError(mj.ErrInternal, "audit error")
+	}
+	return ok, nil
 }
 
 const ErrNoPath = dex.ErrorKind("no path")
@@ -5,6 +5,7 @@ package main
 
 import (
 	"context"
+	"crypto/sha256"
 	"errors"
 	"fmt"
 	"net"
@@ -13,13 +14,18 @@ import (
 	"path/filepath"
 	"runtime"
 	"strings"
+	"sync"
 
 	"decred.org/dcrdex/dex"
+	"decred.org/dcrdex/dex/encode"
 	"decred.org/dcrdex/dex/fiatrates"
 	"decred.org/dcrdex/server/comms"
 	"decred.org/dcrdex/tatanka"
+	"decred.org/dcrdex/tatanka/admin"
+	_ "decred.org/dcrdex/tatanka/chain/evm" // register Ethereum and ERC20 backends
 	"github.com/jessevdk/go-flags"
 	"github.com/jrick/logrotate/rotator"
+	"golang.org/x/term"
 )
 
 const (
@@ -30,6 +36,7 @@ const (
 	missingPort           = "missing port in address"
 	defaultHSHost         = defaultHost // should be a loopback address
 	defaultHSPort         = "7252"
+	defaultAdminSrvAddr   = "127.0.0.1:7242"
 )
 
 var (
@@ -61,6 +68,20 @@ func mainErr() (err error) {
 		cancel()
 	}()
 
+	// Request the admin server password if the admin server is enabled and the
+	// password is not set in the config.
+	var adminSrvAuthSHA [32]byte
+	if cfg.AdminSrvOn {
+		pass := []byte(cfg.AdminSrvPassword)
+		if len(pass) == 0 {
+			if pass, err = passwordPrompt("Admin interface password: "); err != nil {
+				return fmt.Errorf("cannot use password: %v", err)
+			}
+		}
+		adminSrvAuthSHA = sha256.Sum256(pass)
+		encode.ClearBytes(pass)
+	}
+
 	net := dex.Mainnet
 	switch {
 	case cfg.Simnet:
@@ -82,6 +103,7 @@ func mainErr() (err error) {
 			NoTLS:             cfg.NoTLS,
 			AltDNSNames:       cfg.AltDNSNames,
 		},
+		NoiseListeners: cfg.NoiseListeners,
 	})
 	if err != nil {
 		return fmt.Errorf("tatanka.New error: %w", err)
@@ -92,10 +114,49 @@ func mainErr() (err error) {
 		return fmt.Errorf("ConnectOnce error: %w", err)
 	}
 
+	var wg sync.WaitGroup
+	if cfg.AdminSrvOn {
+		adminServer, err := admin.NewServer(&admin.SrvConfig{
+			Core:    t,
+			Logger:  logMaker.Logger("ADMN"),
+			Addr:    cfg.AdminSrvAddr,
+			AuthSHA: adminSrvAuthSHA,
+			Cert:    cfg.CertPath,
+			Key:     cfg.KeyPath,
+			NoTLS:   cfg.AdminSrvNoTLS,
+		})
+		if err != nil {
+			cancel()
+			tc.Wait()
+			return fmt.Errorf("cannot set up admin server: %v", err)
+		}
+		wg.Add(1)
+		go func() {
+			adminServer.Run(ctx)
+			wg.Done()
+		}()
+	}
+
 	tc.Wait()
+	wg.Wait()
 	return nil
 }
 
+// passwordPrompt prompts the user to enter a password. Password must not be an
+// empty string.
+func passwordPrompt(prompt string) ([]byte, error) {
+	fmt.Print(prompt)
+	pass, err := term.ReadPassword(int(os.Stdin.Fd()))
+	fmt.Println()
+	if err != nil {
+		return nil, err
+	}
+	if len(pass) == 0 {
+		return nil, errors.New("empty password")
+	}
+	return pass, nil
+}
+
 type Config struct {
 	AppDataDir  string `short:"A" long:"appdata" description:"Path to application home directory."`
 	ConfigFile  string `short:"C" long:"configfile" description:"Path to configuration file."`
@@ -113,8 +174,15 @@ type Config struct {
 	AltDNSNames   []string `long:"altdnsnames" description:"A list of hostnames to include in the RPC certificate (X509v3 Subject Alternative Name)."`
 	HiddenService string   `long:"hiddenservice" description:"A host:port on which the RPC server should listen for incoming hidden service connections. No TLS is used for these connections."`
 
+	NoiseListeners []string `long:"noiselisten" description:"IP addresses on which to listen for noise-encrypted TCP connections from whitelisted tatanka nodes."`
+
 	WebAddr string `long:"webaddr" description:"The public facing address by which peers should connect."`
 
+	AdminSrvOn       bool   `long:"adminsrvon" description:"Turn on the admin server."`
+	AdminSrvAddr     string `long:"adminsrvaddr" description:"Administration HTTPS server address (default: 127.0.0.1:7242)."`
+	AdminSrvPassword string `long:"adminsrvpass" description:"Admin server password. INSECURE. Do not set unless absolutely necessary."`
+	AdminSrvNoTLS    bool   `long:"adminsrvnotls" description:"Run admin server without TLS. Only use this option if you are using a securely configured reverse proxy."`
+
 	FiatOracleConfig fiatrates.Config `group:"Fiat Oracle Config"`
 }
 
@@ -216,6 +284,13 @@ func config() (*dex.LoggerMaker, *Config) {
 		}
 	}
 
+	if cfg.AdminSrvAddr == "" {
+		cfg.AdminSrvAddr = defaultAdminSrvAddr
+	}
+	if _, _, err := net.SplitHostPort(cfg.AdminSrvAddr); err != nil {
+		emitConfigError("invalid admin server address %q: %v", cfg.AdminSrvAddr, err)
+	}
+
 	// Create the loggers: Parse and validate the debug level string, create the
 	// subsystem loggers, and set package level loggers. The generated
 	// LoggerMaker is used by other subsystems to create new loggers with the
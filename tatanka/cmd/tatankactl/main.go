@@ -0,0 +1,262 @@
+// This code is available on the terms of the project LICENSE.md file,
+// also available online at https://blueoakcouncil.org/license/1.0.0.
+
+// tatankactl is a command-line client for the tatanka node admin server.
+package main
+
+import (
+	"bytes"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"decred.org/dcrdex/dex"
+	"github.com/jessevdk/go-flags"
+	"golang.org/x/term"
+)
+
+const defaultAdminSrvURL = "https://127.0.0.1:7242"
+
+// command is an admin server request.
+type command struct {
+	args    string // for usage
+	nArgs   int    // required args
+	maxArgs int
+	desc    string
+	req     func(args []string) (method, path string, body []byte, err error)
+}
+
+// get creates a command request for a GET request with no arguments.
+func get(path string) func([]string) (string, string, []byte, error) {
+	return func([]string) (string, string, []byte, error) {
+		return http.MethodGet, path, nil, nil
+	}
+}
+
+var commands = map[string]*command{
+	"ping": {
+		desc: "Check that the admin server is reachable.",
+		req:  get("/ping"),
+	},
+	"clients": {
+		desc: "List locally connected clients.",
+		req:  get("/clients"),
+	},
+	"tatankas": {
+		desc: "List connected remote tatanka nodes.",
+		req:  get("/tatankas"),
+	},
+	"topics": {
+		desc: "Show topic and subject subscription counts.",
+		req:  get("/topics"),
+	},
+	"peer": {
+		args:  "peerID",
+		nArgs: 1,
+		desc:  "Show a peer's bonds, reputation, and ban status.",
+		req: func(args []string) (string, string, []byte, error) {
+			return http.MethodGet, "/peer/" + args[0], nil, nil
+		},
+	},
+	"whitelist": {
+		desc: "List whitelisted tatanka nodes.",
+		req:  get("/whitelist"),
+	},
+	"addnode": {
+		args:  "peerID protocol config",
+		nArgs: 3,
+		desc:  "Whitelist a tatanka node. config is the JSON node configuration for the protocol. The node stays whitelisted after a restart, even if it is not in the configured whitelist.",
+		req: func(args []string) (string, string, []byte, error) {
+			peerID, err := hex.DecodeString(args[0])
+			if err != nil {
+				return "", "", nil, fmt.Errorf("invalid peer ID: %w", err)
+			}
+			if !json.Valid([]byte(args[2])) {
+				return "", "", nil, errors.New("config is not valid JSON")
+			}
+			body, err := json.Marshal(map[string]any{
+				"peerID":   dex.Bytes(peerID),
+				"protocol": args[1],
+				"config":   json.RawMessage(args[2]),
+			})
+			return http.MethodPost, "/whitelist", body, err
+		},
+	},
+	"removenode": {
+		args:  "peerID",
+		nArgs: 1,
+		desc:  "Remove a tatanka node from the whitelist and disconnect it. The node stays removed after a restart, even if it is in the configured whitelist.",
+		req: func(args []string) (string, string, []byte, error) {
+			return http.MethodDelete, "/whitelist/" + args[0], nil, nil
+		},
+	},
+	"bans": {
+		desc: "List banned peers.",
+		req:  get("/bans"),
+	},
+	"ban": {
+		args:    "peerID [reason]",
+		nArgs:   1,
+		maxArgs: 2,
+		desc:    "Ban a peer, disconnecting them if connected.",
+		req: func(args []string) (string, string, []byte, error) {
+			path := "/peer/" + args[0] + "/ban"
+			if len(args) > 1 {
+				path += "?reason=" + url.QueryEscape(args[1])
+			}
+			return http.MethodPost, path, nil, nil
+		},
+	},
+	"unban": {
+		args:  "peerID",
+		nArgs: 1,
+		desc:  "Lift a peer's ban.",
+		req: func(args []string) (string, string, []byte, error) {
+			return http.MethodPost, "/peer/" + args[0] + "/unban", nil, nil
+		},
+	},
+}
+
+type Config struct {
+	AdminSrvURL      string `long:"adminsrvurl" description:"Tatanka admin server URL (default: https://127.0.0.1:7242)."`
+	AdminSrvPassword string `long:"adminsrvpass" description:"Admin server password. INSECURE. Do not set unless absolutely necessary."`
+	AdminSrvCertPath string `long:"adminsrvcert" description:"TLS certificate for connecting to the admin server."`
+}
+
+func main() {
+	if err := mainErr(); err != nil {
+		fmt.Fprint(os.Stderr, err, "\n")
+		os.Exit(1)
+	}
+	os.Exit(0)
+}
+
+func mainErr() error {
+	var cfg Config
+	parser := flags.NewParser(&cfg, flags.Default)
+	parser.Usage = "[OPTIONS] command [args...]\n\n" + commandUsage()
+	args, err := parser.Parse()
+	if err != nil {
+		if e, ok := err.(*flags.Error); ok && e.Type == flags.ErrHelp {
+			return nil
+		}
+		return err
+	}
+	if len(args) == 0 {
+		parser.WriteHelp(os.Stderr)
+		return errors.New("no command specified")
+	}
+
+	cmdName, args := args[0], args[1:]
+	cmd, found := commands[cmdName]
+	if !found {
+		return fmt.Errorf("unknown command %q", cmdName)
+	}
+	if len(args) < cmd.nArgs || len(args) > max(cmd.nArgs, cmd.maxArgs) {
+		return fmt.Errorf("usage: %s [OPTIONS] %s", parser.Name, cmdSignature(cmdName, cmd))
+	}
+	method, path, body, err := cmd.req(args)
+	if err != nil {
+		return err
+	}
+
+	if cfg.AdminSrvURL == "" {
+		cfg.AdminSrvURL = defaultAdminSrvURL
+	}
+	uri, err := url.Parse(cfg.AdminSrvURL)
+	if err != nil {
+		return fmt.Errorf("error parsing adminsrvurl: %w", err)
+	}
+
+	cl := &http.Client{Timeout: time.Second * 30}
+	if cfg.AdminSrvCertPath != "" {
+		certB, err := os.ReadFile(dex.CleanAndExpandPath(cfg.AdminSrvCertPath))
+		if err != nil {
+			return fmt.Errorf("error reading certificate file: %w", err)
+		}
+		rootCAs, _ := x509.SystemCertPool()
+		if rootCAs == nil {
+			rootCAs = x509.NewCertPool()
+		}
+		if ok := rootCAs.AppendCertsFromPEM(certB); !ok {
+			return errors.New("error appending certificate")
+		}
+		cl.Transport = &http.Transport{
+			TLSClientConfig: &tls.Config{
+				RootCAs:    rootCAs,
+				MinVersion: tls.VersionTLS12,
+				ServerName: uri.Hostname(),
+			},
+		}
+	}
+
+	pass := cfg.AdminSrvPassword
+	if pass == "" {
+		fmt.Fprint(os.Stderr, "Admin interface password: ")
+		passB, err := term.ReadPassword(int(os.Stdin.Fd()))
+		fmt.Fprintln(os.Stderr)
+		if err != nil {
+			return fmt.Errorf("error reading password: %w", err)
+		}
+		pass = string(passB)
+	}
+
+	var bodyReader io.Reader
+	if body != nil {
+		bodyReader = bytes.NewReader(body)
+	}
+	req, err := http.NewRequest(method, strings.TrimSuffix(cfg.AdminSrvURL, "/")+"/api"+path, bodyReader)
+	if err != nil {
+		return fmt.Errorf("error constructing request: %w", err)
+	}
+	req.SetBasicAuth("", pass)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := cl.Do(req)
+	if err != nil {
+		return fmt.Errorf("request error: %w", err)
+	}
+	defer resp.Body.Close()
+	b, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("error reading response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s: %s", resp.Status, strings.TrimSpace(string(b)))
+	}
+	fmt.Print(string(b))
+	return nil
+}
+
+// cmdSignature is the command name followed by its arguments.
+func cmdSignature(name string, cmd *command) string {
+	return strings.TrimSpace(name + " " + cmd.args)
+}
+
+// commandUsage lists the available commands.
+func commandUsage() string {
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var sb strings.Builder
+	sb.WriteString("Commands:\n")
+	for _, name := range names {
+		cmd := commands[name]
+		fmt.Fprintf(&sb, "  %-32s %s\n", cmdSignature(name, cmd), cmd.desc)
+	}
+	return sb.String()
+}
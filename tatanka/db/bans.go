@@ -0,0 +1,58 @@
+// This code is available on the terms of the project LICENSE.md file,
+// also available online at https://blueoakcouncil.org/license/1.0.0.
+
+package db
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"decred.org/dcrdex/dex/lexi"
+	"decred.org/dcrdex/tatanka/tanka"
+)
+
+// Ban is an operator-imposed ban of a peer. Unlike a low tier, a ban is not
+// lifted by posting bonds.
+type Ban struct {
+	Reason string    `json:"reason"`
+	Stamp  time.Time `json:"stamp"`
+}
+
+// Ban stores a ban for the peer, replacing any existing ban.
+func (d *DB) Ban(peerID tanka.PeerID, ban *Ban) error {
+	return d.bans.Set(peerID[:], lexi.JSON(ban), lexi.WithReplace())
+}
+
+// Unban deletes the peer's ban. It is not an error to unban a peer that is not
+// banned.
+func (d *DB) Unban(peerID tanka.PeerID) error {
+	if err := d.bans.Delete(peerID[:]); err != nil && !errors.Is(err, lexi.ErrKeyNotFound) {
+		return err
+	}
+	return nil
+}
+
+// Bans retrieves all banned peers.
+func (d *DB) Bans() (map[tanka.PeerID]*Ban, error) {
+	bans := make(map[tanka.PeerID]*Ban)
+	return bans, d.bans.Iterate(nil, func(it *lexi.Iter) error {
+		k, err := it.K()
+		if err != nil {
+			return fmt.Errorf("error getting ban key: %w", err)
+		}
+		if len(k) != tanka.PeerIDLength {
+			return fmt.Errorf("invalid ban key length %d", len(k))
+		}
+		var peerID tanka.PeerID
+		copy(peerID[:], k)
+		return it.V(func(vB []byte) error {
+			ban := new(Ban)
+			if err := lexi.JSON(ban).UnmarshalBinary(vB); err != nil {
+				return fmt.Errorf("error unmarshaling ban: %w", err)
+			}
+			bans[peerID] = ban
+			return nil
+		})
+	})
+}
@@ -4,6 +4,7 @@
 package db
 
 import (
+	"errors"
 	"fmt"
 	"time"
 
@@ -49,8 +50,20 @@ func (bond *dbBond) UnmarshalBinary(b []byte) error {
 	return nil
 }
 
+// StoreBond stores the bond, and records the time of the peer's first bond if
+// this is their first.
 func (d *DB) StoreBond(newBond *tanka.Bond) error {
-	return d.bonds.Set(newBond.CoinID[:], &dbBond{newBond}, lexi.WithReplace())
+	if err := d.bonds.Set(newBond.CoinID[:], &dbBond{newBond}, lexi.WithReplace()); err != nil {
+		return err
+	}
+	return d.recordFirstBond(newBond.PeerID, time.Now())
+}
+
+func (d *DB) recordFirstBond(peerID tanka.PeerID, stamp time.Time) error {
+	if _, err := d.firstBonds.GetRaw(peerID[:]); !errors.Is(err, lexi.ErrKeyNotFound) {
+		return err
+	}
+	return d.firstBonds.Set(peerID[:], encode.Uint64Bytes(uint64(stamp.Unix())))
 }
 
 func (d *DB) GetBonds(peerID tanka.PeerID) ([]*tanka.Bond, error) {
@@ -16,6 +16,7 @@ import (
 	"decred.org/dcrdex/dex"
 	"decred.org/dcrdex/dex/encode"
 	"decred.org/dcrdex/dex/lexi"
+	"decred.org/dcrdex/tatanka/tanka"
 )
 
 const DBVersion = 0
@@ -32,6 +33,24 @@ type DB struct {
 	bonds        *lexi.Table
 	bonderIdx    *lexi.Index
 	bondStampIdx *lexi.Index
+	// firstBonds is the time of each peer's first bond. Keyed on peer ID.
+	firstBonds *lexi.Table
+	// standings is the Standing of peers as shared by other tatanka nodes.
+	// Keyed on peer ID.
+	standings *lexi.Table
+	// bans are the operator-imposed bans. Keyed on peer ID.
+	bans *lexi.Table
+	// whitelist are the operator edits of the tatanka node whitelist. Keyed
+	// on peer ID.
+	whitelist *lexi.Table
+	// swapFaults are the resolved swap disputes in which a peer was at fault.
+	// Keyed on match ID.
+	swapFaults *lexi.Table
+
+	trustedMtx sync.RWMutex
+	// trusted are the scorers whose scores have full weight regardless of
+	// their Standing, i.e. tatanka nodes.
+	trusted map[tanka.PeerID]struct{}
 }
 
 func New(dir string, log dex.Logger) (*DB, error) {
@@ -119,6 +138,26 @@ func New(dir string, log dex.Logger) (*DB, error) {
 	if err != nil {
 		return nil, fmt.Errorf("error initializing bond stamp index: %w", err)
 	}
+	firstBondsTable, err := db.Table("first-bonds")
+	if err != nil {
+		return nil, fmt.Errorf("error initializing first bonds table: %w", err)
+	}
+	standingsTable, err := db.Table("standings")
+	if err != nil {
+		return nil, fmt.Errorf("error initializing standings table: %w", err)
+	}
+	bansTable, err := db.Table("bans")
+	if err != nil {
+		return nil, fmt.Errorf("error initializing bans table: %w", err)
+	}
+	whitelistTable, err := db.Table("whitelist")
+	if err != nil {
+		return nil, fmt.Errorf("error initializing whitelist table: %w", err)
+	}
+	swapFaultsTable, err := db.Table("swap-faults")
+	if err != nil {
+		return nil, fmt.Errorf("error initializing swap faults table: %w", err)
+	}
 	return &DB{
 		DB:           db,
 		scores:       scoreTable,
@@ -127,6 +166,12 @@ func New(dir string, log dex.Logger) (*DB, error) {
 		bonds:        bondsTable,
 		bonderIdx:    bonderIdx,
 		bondStampIdx: bondStampIdx,
+		firstBonds:   firstBondsTable,
+		standings:    standingsTable,
+		bans:         bansTable,
+		whitelist:    whitelistTable,
+		swapFaults:   swapFaultsTable,
+		trusted:      make(map[tanka.PeerID]struct{}),
 	}, nil
 }
 
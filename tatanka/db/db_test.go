@@ -116,6 +116,22 @@ func TestPruneBonds(t *testing.T) {
 	}
 }
 
+// tBondScorer gives the scorer a full-weight Standing.
+func tBondScorer(t *testing.T, db *DB, scorer tanka.PeerID, tier uint64) {
+	t.Helper()
+	if err := db.recordFirstBond(scorer, time.Now().Add(-tanka.ScorerMaturity)); err != nil {
+		t.Fatalf("recordFirstBond error: %v", err)
+	}
+	if err := db.StoreBond(&tanka.Bond{
+		PeerID:     scorer,
+		CoinID:     scorer[:],
+		Strength:   tier,
+		Expiration: time.Now().Add(time.Hour),
+	}); err != nil {
+		t.Fatalf("StoreBond error: %v", err)
+	}
+}
+
 func TestReputation(t *testing.T) {
 	db, shutdown := tNewDB()
 	defer shutdown()
@@ -125,7 +141,9 @@ func TestReputation(t *testing.T) {
 	// Note: If MaxReputationEntries is increased to > 122, this won't work
 	// any more.
 	for i := 0; i < tanka.MaxReputationEntries+outdatedN; i++ {
-		if err := db.SetScore(scored, tanka.PeerID{byte(i + 1)}, int8(i), time.Now().Add(-time.Duration(i)*time.Second)); err != nil {
+		scorer := tanka.PeerID{byte(i + 1)}
+		tBondScorer(t, db, scorer, tanka.FullWeightTier)
+		if err := db.SetScore(scored, scorer, int8(i), time.Now().Add(-time.Duration(i)*time.Second)); err != nil {
 			t.Fatalf("SetScore(%d) error: %v", i, err)
 		}
 	}
@@ -151,4 +169,210 @@ func TestReputation(t *testing.T) {
 	if n != tanka.MaxReputationEntries {
 		t.Fatalf("Wrong number of remaining entries. Expected %d, got %d", tanka.MaxReputationEntries, n)
 	}
+
+	// Negative scores reduce the aggregate score.
+	var negScored tanka.PeerID
+	negScored[0] = 0xff
+	for i, score := range []int8{-100, 20} {
+		// These scorers were bonded above.
+		if err := db.SetScore(negScored, tanka.PeerID{byte(i + 1)}, score, time.Now()); err != nil {
+			t.Fatalf("SetScore(%d) error: %v", score, err)
+		}
+	}
+	if rep, err = db.Reputation(negScored); err != nil {
+		t.Fatalf("Reputation error: %v", err)
+	}
+	if rep.Score != -80 {
+		t.Fatalf("Wrong aggregate score with negative score. Expected -80, got %d", rep.Score)
+	}
+}
+
+func TestReputationWeighting(t *testing.T) {
+	db, shutdown := tNewDB()
+	defer shutdown()
+
+	now := time.Now()
+	var scorerN byte
+	newScorer := func() tanka.PeerID {
+		scorerN++
+		return tanka.PeerID{0x01, scorerN}
+	}
+
+	tests := []struct {
+		name     string
+		standing func(tanka.PeerID)
+		stamp    time.Time
+		expScore int64
+	}{{
+		name:     "full weight",
+		standing: func(scorer tanka.PeerID) { tBondScorer(t, db, scorer, tanka.FullWeightTier) },
+		stamp:    now,
+		expScore: 100,
+	}, {
+		name:     "higher tier is capped",
+		standing: func(scorer tanka.PeerID) { tBondScorer(t, db, scorer, tanka.FullWeightTier*2) },
+		stamp:    now,
+		expScore: 100,
+	}, {
+		name:     "unbonded",
+		standing: func(tanka.PeerID) {},
+		stamp:    now,
+		expScore: 0,
+	}, {
+		name:     "low tier",
+		standing: func(scorer tanka.PeerID) { tBondScorer(t, db, scorer, 1) },
+		stamp:    now,
+		expScore: 100 / tanka.FullWeightTier,
+	}, {
+		name: "new identity",
+		standing: func(scorer tanka.PeerID) {
+			if err := db.recordFirstBond(scorer, now.Add(-tanka.ScorerMaturity/4)); err != nil {
+				t.Fatalf("recordFirstBond error: %v", err)
+			}
+			tBondScorer(t, db, scorer, tanka.FullWeightTier)
+		},
+		stamp:    now,
+		expScore: 25,
+	}, {
+		name:     "decayed",
+		standing: func(scorer tanka.PeerID) { tBondScorer(t, db, scorer, tanka.FullWeightTier) },
+		stamp:    now.Add(-tanka.ScoreHalfLife),
+		expScore: 50,
+	}, {
+		name: "shared standing",
+		standing: func(scorer tanka.PeerID) {
+			if err := db.StoreSharedStanding(scorer, &tanka.Standing{
+				BondTier:       tanka.FullWeightTier,
+				BondExpiration: now.Add(time.Hour),
+				FirstBond:      now.Add(-tanka.ScorerMaturity),
+			}); err != nil {
+				t.Fatalf("StoreSharedStanding error: %v", err)
+			}
+		},
+		stamp:    now,
+		expScore: 100,
+	}, {
+		name: "expired shared standing",
+		standing: func(scorer tanka.PeerID) {
+			if err := db.StoreSharedStanding(scorer, &tanka.Standing{
+				BondTier:       tanka.FullWeightTier,
+				BondExpiration: now.Add(-time.Second),
+				FirstBond:      now.Add(-tanka.ScorerMaturity),
+			}); err != nil {
+				t.Fatalf("StoreSharedStanding error: %v", err)
+			}
+		},
+		stamp:    now,
+		expScore: 0,
+	}, {
+		name:     "trusted scorer",
+		standing: func(scorer tanka.PeerID) { db.TrustScorers(scorer) },
+		stamp:    now,
+		expScore: 100,
+	}, {
+		name:     "decayed trusted scorer",
+		standing: func(scorer tanka.PeerID) { db.TrustScorers(scorer) },
+		stamp:    now.Add(-tanka.ScoreHalfLife),
+		expScore: 50,
+	}}
+	for i, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			scored := tanka.PeerID{0x02, byte(i)}
+			scorer := newScorer()
+			test.standing(scorer)
+			if err := db.SetScore(scored, scorer, 100, test.stamp); err != nil {
+				t.Fatalf("SetScore error: %v", err)
+			}
+			rep, err := db.Reputation(scored)
+			if err != nil {
+				t.Fatalf("Reputation error: %v", err)
+			}
+			if rep.Score != test.expScore {
+				t.Fatalf("wrong score. expected %d, got %d", test.expScore, rep.Score)
+			}
+			if rep.Depth != 1 {
+				t.Fatalf("wrong depth %d", rep.Depth)
+			}
+		})
+	}
+}
+
+func TestBans(t *testing.T) {
+	db, shutdown := tNewDB()
+	defer shutdown()
+
+	peer1, peer2 := tanka.PeerID{0x01}, tanka.PeerID{0x02}
+	if err := db.Ban(peer1, &Ban{Reason: "spam", Stamp: time.Now()}); err != nil {
+		t.Fatalf("Ban error: %v", err)
+	}
+	if err := db.Ban(peer2, &Ban{Reason: "fraud", Stamp: time.Now()}); err != nil {
+		t.Fatalf("Ban error: %v", err)
+	}
+	// Banning again replaces the reason.
+	if err := db.Ban(peer2, &Ban{Reason: "more fraud", Stamp: time.Now()}); err != nil {
+		t.Fatalf("Ban error: %v", err)
+	}
+	bans, err := db.Bans()
+	if err != nil {
+		t.Fatalf("Bans error: %v", err)
+	}
+	if len(bans) != 2 {
+		t.Fatalf("expected 2 bans, got %d", len(bans))
+	}
+	if bans[peer1].Reason != "spam" || bans[peer2].Reason != "more fraud" {
+		t.Fatalf("wrong ban reasons %q, %q", bans[peer1].Reason, bans[peer2].Reason)
+	}
+
+	if err := db.Unban(peer1); err != nil {
+		t.Fatalf("Unban error: %v", err)
+	}
+	// Unbanning a peer that isn't banned is not an error.
+	if err := db.Unban(peer1); err != nil {
+		t.Fatalf("Unban error for unbanned peer: %v", err)
+	}
+	if bans, _ = db.Bans(); len(bans) != 1 || bans[peer2] == nil {
+		t.Fatalf("wrong bans after unban")
+	}
+}
+
+func TestWhitelistEdits(t *testing.T) {
+	db, shutdown := tNewDB()
+	defer shutdown()
+
+	peer1, peer2 := tanka.PeerID{0x01}, tanka.PeerID{0x02}
+	if err := db.EditWhitelist(peer1, &WhitelistEdit{Protocol: "wss", Config: []byte(`{}`), Stamp: time.Now()}); err != nil {
+		t.Fatalf("EditWhitelist error: %v", err)
+	}
+	if err := db.EditWhitelist(peer2, &WhitelistEdit{Protocol: "tcp", Config: []byte(`{}`), Stamp: time.Now()}); err != nil {
+		t.Fatalf("EditWhitelist error: %v", err)
+	}
+	// A removal replaces the addition.
+	if err := db.EditWhitelist(peer2, &WhitelistEdit{Removed: true, Stamp: time.Now()}); err != nil {
+		t.Fatalf("EditWhitelist error: %v", err)
+	}
+	edits, err := db.WhitelistEdits()
+	if err != nil {
+		t.Fatalf("WhitelistEdits error: %v", err)
+	}
+	if len(edits) != 2 {
+		t.Fatalf("expected 2 edits, got %d", len(edits))
+	}
+	if edits[peer1].Removed || edits[peer1].Protocol != "wss" || string(edits[peer1].Config) != "{}" {
+		t.Fatalf("wrong addition %+v", edits[peer1])
+	}
+	if !edits[peer2].Removed || edits[peer2].Protocol != "" {
+		t.Fatalf("wrong removal %+v", edits[peer2])
+	}
+}
+
+func TestTrustScorers(t *testing.T) {
+	db, shutdown := tNewDB()
+	defer shutdown()
+
+	peer1, peer2 := tanka.PeerID{0x01}, tanka.PeerID{0x02}
+	db.TrustScorers(peer1, peer2)
+	db.UntrustScorers(peer1)
+	if db.isTrusted(peer1) || !db.isTrusted(peer2) {
+		t.Fatalf("wrong trusted scorers")
+	}
 }
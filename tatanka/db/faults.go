@@ -0,0 +1,37 @@
+// This code is available on the terms of the project LICENSE.md file,
+// also available online at https://blueoakcouncil.org/license/1.0.0.
+
+package db
+
+import (
+	"errors"
+	"time"
+
+	"decred.org/dcrdex/dex/lexi"
+	"decred.org/dcrdex/tatanka/tanka"
+)
+
+// SwapFault is a peer's fault in a swap dispute.
+type SwapFault struct {
+	PeerID tanka.PeerID `json:"peerID"`
+	Stamp  time.Time    `json:"stamp"`
+}
+
+// RecordSwapFault stores the fault for the match, replacing any existing
+// fault.
+func (d *DB) RecordSwapFault(matchID tanka.ID32, fault *SwapFault) error {
+	return d.swapFaults.Set(matchID[:], lexi.JSON(fault), lexi.WithReplace())
+}
+
+// SwapFault retrieves the fault recorded for the match, or nil if there is
+// none.
+func (d *DB) SwapFault(matchID tanka.ID32) (*SwapFault, error) {
+	fault := new(SwapFault)
+	if err := d.swapFaults.Get(matchID[:], lexi.JSON(fault)); err != nil {
+		if errors.Is(err, lexi.ErrKeyNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return fault, nil
+}
@@ -4,7 +4,10 @@
 package db
 
 import (
+	"encoding/binary"
+	"errors"
 	"fmt"
+	"math"
 	"time"
 
 	"decred.org/dcrdex/dex/lexi"
@@ -33,24 +36,137 @@ func (d *DB) SetScore(scored, scorer tanka.PeerID, score int8, stamp time.Time)
 	return d.scores.Set(k, s, lexi.WithReplace())
 }
 
+// TrustScorers sets the scorers whose scores have full weight, subject only to
+// decay, regardless of their Standing. Tatanka nodes are trusted scorers.
+func (d *DB) TrustScorers(peerIDs ...tanka.PeerID) {
+	d.trustedMtx.Lock()
+	defer d.trustedMtx.Unlock()
+	for _, peerID := range peerIDs {
+		d.trusted[peerID] = struct{}{}
+	}
+}
+
+// UntrustScorers removes the scorers from the trusted scorers. Their scores
+// are weighted by their Standing like any other scorer's.
+func (d *DB) UntrustScorers(peerIDs ...tanka.PeerID) {
+	d.trustedMtx.Lock()
+	defer d.trustedMtx.Unlock()
+	for _, peerID := range peerIDs {
+		delete(d.trusted, peerID)
+	}
+}
+
+func (d *DB) isTrusted(peerID tanka.PeerID) bool {
+	d.trustedMtx.RLock()
+	defer d.trustedMtx.RUnlock()
+	_, found := d.trusted[peerID]
+	return found
+}
+
+// Reputation aggregates the most recent scores for the peer, weighting each
+// score by the scorer's Standing. Scores from trusted scorers have full
+// weight.
 func (d *DB) Reputation(scored tanka.PeerID) (*tanka.Reputation, error) {
-	agg := new(tanka.Reputation)
-	var i int
-	return agg, d.scoredIdx.Iterate(scored[:], func(it *lexi.Iter) error {
-		if i >= tanka.MaxReputationEntries {
+	type entry struct {
+		scorer tanka.PeerID
+		score  int8
+		stamp  time.Time
+	}
+	entries := make([]*entry, 0, tanka.MaxReputationEntries)
+	if err := d.scoredIdx.Iterate(scored[:], func(it *lexi.Iter) error {
+		if len(entries) >= tanka.MaxReputationEntries {
 			return it.Delete()
 		}
+		var e entry
+		k, err := it.K()
+		if err != nil {
+			return fmt.Errorf("error getting score key: %w", err)
+		}
+		if len(k) != tanka.PeerIDLength*2 {
+			return fmt.Errorf("wrong score key length %d", len(k))
+		}
+		copy(e.scorer[:], k[tanka.PeerIDLength:])
+		if err := it.Entry(func(idxB []byte) error {
+			if len(idxB) != tanka.PeerIDLength+8 {
+				return fmt.Errorf("wrong score index entry length %d", len(idxB))
+			}
+			e.stamp = time.UnixMilli(int64(binary.BigEndian.Uint64(idxB[tanka.PeerIDLength:])))
+			return nil
+		}); err != nil {
+			return err
+		}
 		if err := it.V(func(vB []byte) error {
 			if len(vB) != 1 {
 				return fmt.Errorf("score not a single byte. length = %d", len(vB))
 			}
-			agg.Score += int64(vB[0])
+			e.score = int8(vB[0])
 			return nil
 		}); err != nil {
 			return err
 		}
-		agg.Depth++
-		i++
+		entries = append(entries, &e)
 		return nil
-	}, lexi.WithUpdate())
+	}, lexi.WithUpdate()); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	standings := make(map[tanka.PeerID]*tanka.Standing)
+	var score float64
+	for _, e := range entries {
+		if d.isTrusted(e.scorer) {
+			score += float64(e.score) * tanka.ScoreDecay(e.stamp, now)
+			continue
+		}
+		standing, found := standings[e.scorer]
+		if !found {
+			var err error
+			if standing, err = d.Standing(e.scorer); err != nil {
+				return nil, fmt.Errorf("error getting standing for scorer %s: %w", e.scorer, err)
+			}
+			standings[e.scorer] = standing
+		}
+		score += float64(e.score) * standing.ScoreWeight(e.stamp, now)
+	}
+	return &tanka.Reputation{
+		Score: int64(math.Round(score)),
+		Depth: uint64(len(entries)),
+	}, nil
+}
+
+// Standing is the peer's bond history, as determined by the bonds posted to
+// this node. If the peer has no bonds with this node, the Standing shared by
+// other tatanka nodes is used.
+func (d *DB) Standing(peerID tanka.PeerID) (*tanka.Standing, error) {
+	bonds, err := d.GetBonds(peerID)
+	if err != nil {
+		return nil, fmt.Errorf("error getting bonds: %w", err)
+	}
+	standing := new(tanka.Standing)
+	if len(bonds) == 0 {
+		if err := d.standings.Get(peerID[:], lexi.JSON(standing)); err != nil && !errors.Is(err, lexi.ErrKeyNotFound) {
+			return nil, fmt.Errorf("error getting shared standing: %w", err)
+		}
+		return standing, nil
+	}
+	for _, b := range bonds {
+		standing.BondTier += b.Strength
+		if b.Expiration.After(standing.BondExpiration) {
+			standing.BondExpiration = b.Expiration
+		}
+	}
+	stampB, err := d.firstBonds.GetRaw(peerID[:])
+	if err != nil && !errors.Is(err, lexi.ErrKeyNotFound) {
+		return nil, fmt.Errorf("error getting first bond time: %w", err)
+	}
+	if len(stampB) == 8 {
+		standing.FirstBond = time.Unix(int64(binary.BigEndian.Uint64(stampB)), 0)
+	}
+	return standing, nil
+}
+
+// StoreSharedStanding stores the peer's Standing as shared by another tatanka
+// node.
+func (d *DB) StoreSharedStanding(peerID tanka.PeerID, standing *tanka.Standing) error {
+	return d.standings.Set(peerID[:], lexi.JSON(standing), lexi.WithReplace())
 }
@@ -0,0 +1,55 @@
+// This code is available on the terms of the project LICENSE.md file,
+// also available online at https://blueoakcouncil.org/license/1.0.0.
+
+package db
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"decred.org/dcrdex/dex/lexi"
+	"decred.org/dcrdex/tatanka/tanka"
+)
+
+// WhitelistEdit is an operator edit of the whitelist. Edits are applied over
+// the configured whitelist on startup, so that nodes added or removed at
+// runtime stay added or removed.
+type WhitelistEdit struct {
+	// Removed is true if the node was removed from the whitelist. Protocol
+	// and Config are empty for a removal.
+	Removed  bool            `json:"removed,omitempty"`
+	Protocol string          `json:"protocol,omitempty"`
+	Config   json.RawMessage `json:"config,omitempty"`
+	Stamp    time.Time       `json:"stamp"`
+}
+
+// EditWhitelist stores the whitelist edit for the node, replacing any
+// existing edit.
+func (d *DB) EditWhitelist(peerID tanka.PeerID, edit *WhitelistEdit) error {
+	return d.whitelist.Set(peerID[:], lexi.JSON(edit), lexi.WithReplace())
+}
+
+// WhitelistEdits retrieves all whitelist edits.
+func (d *DB) WhitelistEdits() (map[tanka.PeerID]*WhitelistEdit, error) {
+	edits := make(map[tanka.PeerID]*WhitelistEdit)
+	return edits, d.whitelist.Iterate(nil, func(it *lexi.Iter) error {
+		k, err := it.K()
+		if err != nil {
+			return fmt.Errorf("error getting whitelist key: %w", err)
+		}
+		if len(k) != tanka.PeerIDLength {
+			return fmt.Errorf("invalid whitelist key length %d", len(k))
+		}
+		var peerID tanka.PeerID
+		copy(peerID[:], k)
+		return it.V(func(vB []byte) error {
+			edit := new(WhitelistEdit)
+			if err := lexi.JSON(edit).UnmarshalBinary(vB); err != nil {
+				return fmt.Errorf("error unmarshaling whitelist edit: %w", err)
+			}
+			edits[peerID] = edit
+			return nil
+		})
+	})
+}
@@ -25,6 +25,7 @@ const (
 	ErrBannned
 	ErrFailedRelay
 	ErrUnknownSender
+	ErrRateLimited
 )
 
 const (
@@ -46,12 +47,19 @@ const (
 	RouteUnsubscribe = "unsubscribe"
 	RouteRates       = "rates"
 	RouteSetScore    = "set_score"
+	RouteAuditSwap   = "audit_swap"
+	RouteReputation  = "reputation"
 
 	// client1 <=> tatankanode <=> client2
 	RouteTankagram     = "tankagram"
 	RouteEncryptionKey = "encryption_key"
 	RouteBroadcast     = "broadcast"
 	RouteNewSubscriber = "new_subscriber"
+
+	// client1 <=> client2 swap negotiation, carried in tankagrams
+	RouteSwapAddress = "swap_address"
+	RouteSwapInit    = "swap_init"
+	RouteSwapRedeem  = "swap_redeem"
 )
 
 const (
@@ -64,6 +72,7 @@ type BroadcastMessageType string
 const (
 	MessageTypeTrollBox      BroadcastMessageType = "troll_box"
 	MessageTypeNewOrder      BroadcastMessageType = "new_order"
+	MessageTypeUpdateOrder   BroadcastMessageType = "update_order"
 	MessageTypeProposeMatch  BroadcastMessageType = "propose_match"
 	MessageTypeAcceptMatch   BroadcastMessageType = "accept_match"
 	MessageTypeNewSubscriber BroadcastMessageType = "new_subscriber"
@@ -141,6 +150,15 @@ type TankagramResult struct {
 	EncryptedPayload dex.Bytes           `json:"encryptedPayload"`
 }
 
+const (
+	// MaxBroadcastSize is the largest serialized Broadcast that tatanka nodes
+	// will distribute.
+	MaxBroadcastSize = 1 << 14 // 16 KiB
+	// MaxTrollBoxSize is the largest serialized troll box Broadcast that
+	// tatanka nodes will distribute.
+	MaxTrollBoxSize = 1 << 11 // 2 KiB
+)
+
 type Broadcast struct {
 	PeerID      tanka.PeerID         `json:"peerID"`
 	Topic       tanka.Topic          `json:"topic"`
@@ -148,14 +166,45 @@ type Broadcast struct {
 	MessageType BroadcastMessageType `json:"messageType"`
 	Payload     dex.Bytes            `json:"payload,omitempty"`
 	Stamp       time.Time            `json:"stamp"`
+	// Seq is the broadcast's sequence number in the distributing tatanka
+	// node's log for the subject. Sequence numbers are assigned by each node
+	// independently, so are only meaningful to the node that assigned them.
+	Seq uint64 `json:"seq,omitempty"`
 }
 
 type Subscription struct {
 	Topic   tanka.Topic   `json:"topic"`
 	Subject tanka.Subject `json:"subject"`
+	// Replay requests a replay of the tatanka node's recent broadcasts for
+	// the subject. If Replay is set, the subscription result is a
+	// SubscriptionReplay instead of true.
+	Replay *ReplayRequest `json:"replay,omitempty"`
 	// ChannelParameters json.RawMessage `json:"channelParameters,omitempty"`
 }
 
+// ReplayRequest specifies the broadcasts to replay. Broadcasts with sequence
+// numbers after Seq are replayed. If Seq is zero, broadcasts stamped after
+// Since are replayed. If both are zero, all logged broadcasts are replayed.
+type ReplayRequest struct {
+	Seq   uint64    `json:"seq,omitempty"`
+	Since time.Time `json:"since,omitempty"`
+}
+
+// SubscriptionReplay is the result of a subscription with a ReplayRequest.
+// The replayed broadcasts are ordered by sequence number, and any broadcasts
+// distributed after the subscription will have sequence numbers after Seq.
+type SubscriptionReplay struct {
+	// Seq is the sequence number of the node's last logged broadcast for the
+	// subject.
+	Seq uint64 `json:"seq"`
+	// Complete is false if some broadcasts after the requested point have
+	// already been pruned from the node's log, or if the requested sequence
+	// number is unknown to the node, in which case the subscriber should
+	// discard its state for the subject and rebuild it from the replay.
+	Complete   bool         `json:"complete"`
+	Broadcasts []*Broadcast `json:"broadcasts"`
+}
+
 type Unsubscription struct {
 	Topic  tanka.Topic  `json:"topic"`
 	PeerID tanka.PeerID `json:"peerID"`
@@ -204,12 +253,76 @@ type ScoreReport struct {
 }
 
 // SharedScore is a scorer-scored tuple shared between tatanka nodes, along
-// with the sending tatanka's new view of the scored peer's reputation.
+// with the sending tatanka's new view of the scored peer's reputation and the
+// scorer's standing, which the receiving tatanka uses to weight the score if
+// the scorer hasn't bonded with them.
 type SharedScore struct {
 	Scorer     tanka.PeerID      `json:"scorer"`
 	Scored     tanka.PeerID      `json:"scored"`
 	Score      int8              `json:"score"`
 	Reputation *tanka.Reputation `json:"rep"`
+	Standing   *tanka.Standing   `json:"standing,omitempty"`
+}
+
+// MaxReputationRequest is the maximum number of peers in a ReputationRequest.
+const MaxReputationRequest = 100
+
+// ReputationRequest is a client's request for the reputations of peers, e.g.
+// potential counterparties. The response is a []*tanka.Reputation in the same
+// order as PeerIDs.
+type ReputationRequest struct {
+	PeerIDs []tanka.PeerID `json:"peerIDs"`
+}
+
+// SwapDispute is a client's request for a tatanka node to audit the contracts
+// of a failed swap. Match is the match, signed by the counterparty. Ours is
+// the submitting client's own contract, which must pass the audit for the
+// dispute to be considered. Theirs is the counterparty's contract, which is
+// required, since a missing contract cannot be told apart from one left out
+// by the disputer. The counterparty is at fault if their contract fails the
+// audit. The contracts' asset, recipient and value must be those required by
+// the match.
+type SwapDispute struct {
+	Match  *tanka.SignedMatch `json:"match"`
+	Ours   *tanka.HTLCAudit   `json:"ours"`
+	Theirs *tanka.HTLCAudit   `json:"theirs"`
+}
+
+// SwapDisputeResult is the tatanka node's resolution of a SwapDispute.
+type SwapDisputeResult struct {
+	// Fault is true if the counterparty's contract was missing or failed the
+	// audit, in which case the counterparty is scored with
+	// tanka.ScoreSwapFault.
+	Fault bool `json:"fault"`
+	// Reputation is the counterparty's reputation after resolution.
+	Reputation *tanka.Reputation `json:"rep"`
+}
+
+// SwapAddress is the payload of a swap_address tankagram. The maker sends
+// their receiving address, and the taker responds with theirs and their
+// tanka.SignedMatch signature.
+type SwapAddress struct {
+	MatchID tanka.ID32 `json:"matchID"`
+	Address string     `json:"address"`
+	Sig     dex.Bytes  `json:"sig,omitempty"`
+}
+
+// SwapInit is the payload of a swap_init tankagram, sent after a peer
+// broadcasts their swap contract. The maker's swap_init includes their
+// tanka.SignedMatch signature.
+type SwapInit struct {
+	MatchID  tanka.ID32 `json:"matchID"`
+	CoinID   dex.Bytes  `json:"coinID"`
+	Contract dex.Bytes  `json:"contract"`
+	Sig      dex.Bytes  `json:"sig,omitempty"`
+}
+
+// SwapRedeem is the payload of a swap_redeem tankagram, sent by the maker
+// after redeeming the taker's contract.
+type SwapRedeem struct {
+	MatchID tanka.ID32 `json:"matchID"`
+	CoinID  dex.Bytes  `json:"coinID"`
+	Secret  dex.Bytes  `json:"secret"`
 }
 
 func MustRequest(route string, payload any) *msgjson.Message {
@@ -20,11 +20,15 @@ type peer struct {
 
 // banned checks whether the peer is banned.
 func (p *peer) banned() bool {
+	// NEED TO CONSIDER *RemoteReputations....
+	return p.tier() <= 0
+}
+
+// tier is the peer's bonded tier adjusted for reputation.
+func (p *peer) tier() int64 {
 	p.mtx.RLock()
 	defer p.mtx.RUnlock()
-	// NEED TO CONSIDER *RemoteReputations....
-	tier := calcTier(p.Reputation, p.BondTier())
-	return tier <= 0
+	return calcTier(p.Reputation, p.BondTier())
 }
 
 // bondTier is the peer's current bonded tier.
@@ -0,0 +1,238 @@
+// This code is available on the terms of the project LICENSE.md file,
+// also available online at https://blueoakcouncil.org/license/1.0.0.
+
+package tatanka
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"decred.org/dcrdex/tatanka/mj"
+	"decred.org/dcrdex/tatanka/tanka"
+	"golang.org/x/time/rate"
+)
+
+const (
+	// bcastMaxRateTier is the highest tier considered when scaling broadcast
+	// rate limits. Higher tiers get no additional allowance.
+	bcastMaxRateTier = 10
+	// bcastStrikeLimit is the number of rate-limited or oversized broadcasts
+	// after which a client is penalized with tanka.ScoreBroadcastSpam.
+	bcastStrikeLimit = 20
+	// bcastTierRefresh is how often a broadcaster's tier is reevaluated.
+	bcastTierRefresh = time.Minute
+	// bcastLimiterExpiry is how long an idle limiter is kept.
+	bcastLimiterExpiry = time.Minute * 10
+	// relayLimitSlack multiplies the limits for relayed broadcasts, to allow
+	// for network jitter and for differences between our view of the
+	// broadcaster's tier and the view of the tatanka node they're connected
+	// to.
+	relayLimitSlack = 2
+)
+
+// bcastLimit is the broadcast rate limit for a single tier. Limits scale
+// linearly with the broadcaster's tier, up to bcastMaxRateTier.
+type bcastLimit struct {
+	rate  rate.Limit // per second
+	burst int
+}
+
+// scaled is the limit for a peer of the specified tier, multiplied by slack.
+func (bl *bcastLimit) scaled(tier int64, slack float64) (rate.Limit, int) {
+	tier = max(min(tier, bcastMaxRateTier), 0)
+	scale := float64(tier) * slack
+	return bl.rate * rate.Limit(scale), int(float64(bl.burst) * scale)
+}
+
+var (
+	// bcastTopicLimits are the limits for broadcasts to specific topics.
+	bcastTopicLimits = map[tanka.Topic]*bcastLimit{
+		// Orders, order updates, and match negotiation.
+		mj.TopicMarket: {rate: 1, burst: 20},
+	}
+	// defaultBcastLimit is the limit for topics not in bcastTopicLimits.
+	defaultBcastLimit = &bcastLimit{rate: 0.2, burst: 5}
+	// trollBoxLimit is the limit for troll box messages, which are counted
+	// separately from other broadcasts to the same topic.
+	trollBoxLimit = &bcastLimit{rate: 0.1, burst: 3}
+)
+
+// checkBroadcastSize checks that the serialized broadcast is not too large.
+func checkBroadcastSize(bcast *mj.Broadcast, rawBcast []byte) error {
+	maxSize := mj.MaxBroadcastSize
+	if bcast.MessageType == mj.MessageTypeTrollBox {
+		maxSize = mj.MaxTrollBoxSize
+	}
+	if len(rawBcast) > maxSize {
+		return fmt.Errorf("broadcast size %d exceeds limit %d", len(rawBcast), maxSize)
+	}
+	return nil
+}
+
+type bcastLimiterKey struct {
+	peerID   tanka.PeerID
+	topic    tanka.Topic
+	trollBox bool
+}
+
+// tieredLimiter is a rate.Limiter scaled by the broadcaster's tier.
+type tieredLimiter struct {
+	*rate.Limiter
+	limit     *bcastLimit
+	tier      int64
+	tierStamp time.Time
+	lastHit   time.Time
+}
+
+// strikes counts a peer's refused broadcasts since their last penalty.
+type strikes struct {
+	n       int
+	lastHit time.Time
+}
+
+// broadcastLimiter enforces per-peer, per-topic broadcast rate limits, scaled
+// by the broadcaster's tier.
+type broadcastLimiter struct {
+	slack float64
+
+	mtx      sync.Mutex
+	limiters map[bcastLimiterKey]*tieredLimiter
+	strikes  map[tanka.PeerID]*strikes
+}
+
+// newBroadcastLimiter is a constructor for a broadcastLimiter. The limits are
+// multiplied by slack.
+func newBroadcastLimiter(slack float64) *broadcastLimiter {
+	return &broadcastLimiter{
+		slack:    slack,
+		limiters: make(map[bcastLimiterKey]*tieredLimiter),
+		strikes:  make(map[tanka.PeerID]*strikes),
+	}
+}
+
+// allow checks whether the broadcast is within the broadcaster's rate limit.
+// tier is called for the broadcaster's tier when the limiter is created and
+// then every bcastTierRefresh. Broadcasts from peers with a tier <= 0 are
+// never allowed. If the broadcast is not allowed and the broadcaster has now
+// been rate-limited bcastStrikeLimit times since their last penalty, penalize
+// will be true.
+func (l *broadcastLimiter) allow(bcast *mj.Broadcast, tier func() int64) (ok, penalize bool) {
+	k := bcastLimiterKey{
+		peerID:   bcast.PeerID,
+		topic:    bcast.Topic,
+		trollBox: bcast.MessageType == mj.MessageTypeTrollBox,
+	}
+	now := time.Now()
+
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+	lim, found := l.limiters[k]
+	if !found {
+		limit := defaultBcastLimit
+		if k.trollBox {
+			limit = trollBoxLimit
+		} else if topicLimit, found := bcastTopicLimits[k.topic]; found {
+			limit = topicLimit
+		}
+		t := tier()
+		lim = &tieredLimiter{
+			Limiter:   rate.NewLimiter(limit.scaled(t, l.slack)),
+			limit:     limit,
+			tier:      t,
+			tierStamp: now,
+		}
+		l.limiters[k] = lim
+	} else if now.Sub(lim.tierStamp) > bcastTierRefresh {
+		lim.tier, lim.tierStamp = tier(), now
+		r, b := lim.limit.scaled(lim.tier, l.slack)
+		lim.SetLimitAt(now, r)
+		lim.SetBurstAt(now, b)
+	}
+	lim.lastHit = now
+	if lim.tier <= 0 {
+		return false, false
+	}
+	if lim.AllowN(now, 1) {
+		return true, false
+	}
+	return false, l.addStrike(k.peerID, now)
+}
+
+// strike records a strike for a broadcast refused for a reason other than the
+// rate limit, such as its size. If the peer has now reached bcastStrikeLimit
+// strikes since their last penalty, strike returns true, and the peer should
+// be penalized.
+func (l *broadcastLimiter) strike(peerID tanka.PeerID) (penalize bool) {
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+	return l.addStrike(peerID, time.Now())
+}
+
+// addStrike is strike with the mtx locked.
+func (l *broadcastLimiter) addStrike(peerID tanka.PeerID, now time.Time) (penalize bool) {
+	s, found := l.strikes[peerID]
+	if !found {
+		s = new(strikes)
+		l.strikes[peerID] = s
+	}
+	s.n++
+	s.lastHit = now
+	if s.n < bcastStrikeLimit {
+		return false
+	}
+	delete(l.strikes, peerID)
+	// Pick up the penalty on the next broadcast.
+	for k, lim := range l.limiters {
+		if k.peerID == peerID {
+			lim.tierStamp = time.Time{}
+		}
+	}
+	return true
+}
+
+// prune removes idle limiters and strikes.
+func (l *broadcastLimiter) prune() {
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+	for k, lim := range l.limiters {
+		if time.Since(lim.lastHit) > bcastLimiterExpiry {
+			delete(l.limiters, k)
+		}
+	}
+	for peerID, s := range l.strikes {
+		if time.Since(s.lastHit) > bcastLimiterExpiry {
+			delete(l.strikes, peerID)
+		}
+	}
+}
+
+// penalizeBroadcaster records a tanka.ScoreBroadcastSpam score for the locally
+// connected client. Because scores are keyed on the scorer-scored pair,
+// repeated penalties do not compound.
+func (t *Tatanka) penalizeBroadcaster(peerID tanka.PeerID, reason string) {
+	t.log.Infof("Penalizing client %s for broadcast spam: %s", peerID, reason)
+	if _, err := t.setScore(t.id, peerID, tanka.ScoreBroadcastSpam); err != nil {
+		t.log.Errorf("Error recording broadcast spam penalty for %s: %v", peerID, err)
+	}
+}
+
+// remoteTier is our best estimate of the tier of a client connected to
+// another tatanka node. The remote node only accepts broadcasts from clients
+// with a positive tier, so a client whose bonds we don't know about is
+// assumed to be tier 1.
+func (t *Tatanka) remoteTier(peerID tanka.PeerID) int64 {
+	bondTier := uint64(1)
+	standing, err := t.db.Standing(peerID)
+	if err != nil {
+		t.log.Errorf("Error getting standing for %s: %v", peerID, err)
+	} else if standing.BondTier > bondTier && time.Now().Before(standing.BondExpiration) {
+		bondTier = standing.BondTier
+	}
+	rep, err := t.db.Reputation(peerID)
+	if err != nil {
+		t.log.Errorf("Error getting reputation for %s: %v", peerID, err)
+		return int64(bondTier)
+	}
+	return calcTier(rep, bondTier)
+}
@@ -4,6 +4,7 @@
 package tanka
 
 import (
+	"math"
 	"time"
 
 	"decred.org/dcrdex/dex"
@@ -17,13 +18,68 @@ const (
 	TierIncrement        = 20
 	MaxAggregateScore    = MaxReputationEntries * MaxSubScore
 	EpochLength          = time.Second * 15
+	// ScoreSwapFault is the score recorded for a counterparty that is found
+	// by a Tatanka node's audit to have not funded a swap as agreed.
+	ScoreSwapFault int8 = -100
+	// ScoreBroadcastSpam is the score recorded by a Tatanka node for a client
+	// that repeatedly exceeds the broadcast rate limits or sends oversized
+	// broadcasts. A full-weight penalty costs the client one tier.
+	ScoreBroadcastSpam int8 = -TierIncrement
+	// ScoreHalfLife is the age at which a score's weight is halved.
+	ScoreHalfLife = time.Hour * 24 * 30
+	// ScorerMaturity is the bond history required for a scorer's scores to
+	// have full weight. Scores from newer identities are weighted in
+	// proportion to the time since their first bond.
+	ScorerMaturity = time.Hour * 24 * 7
+	// FullWeightTier is the bond tier at which a scorer's scores have full
+	// weight. Scores from lower-tier scorers are weighted in proportion to
+	// their tier.
+	FullWeightTier = 5
 )
 
+// Reputation is the aggregate of the scores submitted for a peer. Each score
+// is weighted by the scorer's Standing before aggregation.
 type Reputation struct {
 	Score int64
 	Depth uint64
 }
 
+// Standing is a scorer's bond history, used to weight their scores.
+type Standing struct {
+	// BondTier is the scorer's current bonded tier.
+	BondTier uint64 `json:"tier"`
+	// BondExpiration is the latest expiration of the scorer's current bonds.
+	BondExpiration time.Time `json:"bondExpiration"`
+	// FirstBond is when the scorer was first seen with a bond.
+	FirstBond time.Time `json:"firstBond"`
+}
+
+// ScoreWeight is the weight, in the range [0, 1], of a score submitted at
+// stamp by a scorer with the Standing. Scorers without a live bond have no
+// weight, which prevents unbonded identities from influencing reputation.
+// Otherwise, the weight is scaled by the scorer's tier up to FullWeightTier
+// and by their bond history up to ScorerMaturity, and decays with the age of
+// the score with a half-life of ScoreHalfLife.
+func (s *Standing) ScoreWeight(stamp, now time.Time) float64 {
+	if s == nil || s.BondTier == 0 || s.FirstBond.IsZero() || now.After(s.BondExpiration) {
+		return 0
+	}
+	w := math.Min(float64(s.BondTier)/FullWeightTier, 1)
+	if history := now.Sub(s.FirstBond); history < ScorerMaturity {
+		w *= math.Max(float64(history), 0) / float64(ScorerMaturity)
+	}
+	return w * ScoreDecay(stamp, now)
+}
+
+// ScoreDecay is the weight, in the range [0, 1], of a score submitted at stamp
+// due to its age alone. Scores decay with a half-life of ScoreHalfLife.
+func ScoreDecay(stamp, now time.Time) float64 {
+	if age := now.Sub(stamp); age > 0 {
+		return math.Pow(0.5, float64(age)/float64(ScoreHalfLife))
+	}
+	return 1
+}
+
 type Bond struct {
 	PeerID     PeerID    `json:"peerID"`
 	AssetID    uint32    `json:"assetID"`
@@ -43,4 +99,22 @@ func (bond *Bond) ID() ID32 {
 
 }
 
-type HTLCAudit struct{}
+// HTLCAudit describes a swap contract that a Tatanka node should verify
+// on-chain. The node checks that the contract exists and pays at least Value
+// to Recipient, is locked to SecretHash, and cannot be refunded before
+// LockTime.
+type HTLCAudit struct {
+	AssetID uint32 `json:"assetID"`
+	// CoinID is the contract output for UTXO-based assets, or the transaction
+	// hash for account-based assets.
+	CoinID dex.Bytes `json:"coinID"`
+	// Contract is the swap contract script for UTXO-based assets, or the
+	// contract locator for account-based assets.
+	Contract   dex.Bytes `json:"contract"`
+	Recipient  string    `json:"recipient"`
+	Value      uint64    `json:"value"`
+	SecretHash dex.Bytes `json:"secretHash"`
+	// LockTime is the earliest acceptable refund time. A zero LockTime is not
+	// checked.
+	LockTime time.Time `json:"lockTime"`
+}
@@ -4,17 +4,49 @@
 package tanka
 
 import (
+	"crypto/sha256"
 	"encoding/binary"
 	"encoding/hex"
 	"errors"
 	"fmt"
 	"time"
 
+	"decred.org/dcrdex/dex"
+	"decred.org/dcrdex/dex/calc"
 	"github.com/decred/dcrd/crypto/blake256"
+	"github.com/decred/dcrd/dcrec/secp256k1/v4"
+	"github.com/decred/dcrd/dcrec/secp256k1/v4/ecdsa"
 )
 
 const orderIDLen = 40
 
+// MaxOrderLifetime is the longest that an order or order update can remain
+// valid after it is stamped. Clients must periodically renew their standing
+// orders with an OrderUpdate to keep them on the order book.
+const MaxOrderLifetime = 15 * time.Minute
+
+const (
+	// MakerLockTime is the lock time of the maker's swap contract, measured
+	// from the match stamp.
+	MakerLockTime = 20 * time.Hour
+	// TakerLockTime is the lock time of the taker's swap contract, measured
+	// from the match stamp. It must be shorter than MakerLockTime so that the
+	// maker cannot wait out the taker's contract after redeeming, and the
+	// difference is the taker's safety margin to find the secret and redeem.
+	// A party whose contract is missing or invalid after TakerLockTime is at
+	// fault.
+	TakerLockTime = 8 * time.Hour
+)
+
+// MaxClockSkew is how far in the future an order or order update can be
+// stamped, to allow for differences between the peers' clocks.
+const MaxClockSkew = 30 * time.Second
+
+// MaxMatchStampSkew is how far a match proposal's stamp can be from the order
+// owner's clock when the match is accepted. Both contract lock times are
+// measured from the stamp, which is set by the match proposer.
+const MaxMatchStampSkew = time.Minute
+
 type Order struct {
 	From    PeerID `json:"from"`
 	BaseID  uint32 `json:"baseID"`
@@ -30,6 +62,10 @@ type Order struct {
 	LotSize uint64    `json:"lotSize"`
 	Nonce   uint64    `json:"nonce"`
 	Stamp   time.Time `json:"stamp"`
+	// Expiration is when the order will be pruned from order books, unless it
+	// is renewed. Expiration can be no more than MaxOrderLifetime after
+	// Stamp.
+	Expiration time.Time `json:"expiration"`
 }
 
 func (ord *Order) ID() ID40 {
@@ -56,6 +92,27 @@ func (ord *Order) Valid() error {
 	if ord.Rate == 0 {
 		return errors.New("order rate is zero")
 	}
+	return checkExpiration(ord.Stamp, ord.Expiration)
+}
+
+// Expired is true if the order's expiration has passed.
+func (ord *Order) Expired() bool {
+	return !time.Now().Before(ord.Expiration)
+}
+
+// checkExpiration checks that the stamp is not more than MaxClockSkew in the
+// future, and that the expiration is after the stamp, but not more than
+// MaxOrderLifetime after the stamp.
+func checkExpiration(stamp, expiration time.Time) error {
+	if stamp.After(time.Now().Add(MaxClockSkew)) {
+		return fmt.Errorf("stamp %s is in the future", stamp)
+	}
+	if !expiration.After(stamp) {
+		return fmt.Errorf("expiration %s is not after stamp %s", expiration, stamp)
+	}
+	if expiration.Sub(stamp) > MaxOrderLifetime {
+		return fmt.Errorf("expiration %s is more than %s after stamp %s", expiration, MaxOrderLifetime, stamp)
+	}
 	return nil
 }
 
@@ -88,6 +145,123 @@ func (m *Match) ID() ID32 {
 	return blake256.Sum256(b)
 }
 
+// CheckStamp checks that the match stamp is within MaxMatchStampSkew of the
+// current time.
+func (m *Match) CheckStamp() error {
+	if d := time.Since(m.Stamp); d > MaxMatchStampSkew || d < -MaxMatchStampSkew {
+		return fmt.Errorf("match stamp %s is more than %s from the current time", m.Stamp, MaxMatchStampSkew)
+	}
+	return nil
+}
+
+// SignedMatch is a match and the swap terms that a party to the match agreed
+// to, signed with the party's peer key. Each party sends their signature to the
+// counterparty before the counterparty funds their contract, so that either
+// party can prove the terms to a tatanka node in a swap dispute.
+type SignedMatch struct {
+	Order *Order `json:"order"`
+	Match *Match `json:"match"`
+	// MakerAddress and TakerAddress are the receiving addresses of the order
+	// owner and the match proposer.
+	MakerAddress string    `json:"makerAddress"`
+	TakerAddress string    `json:"takerAddress"`
+	Sig          dex.Bytes `json:"sig"`
+}
+
+// Valid checks that the match is for the order and that both addresses are
+// set.
+func (sm *SignedMatch) Valid() error {
+	if sm.Order == nil || sm.Match == nil {
+		return errors.New("missing order or match")
+	}
+	if sm.Match.OrderID != sm.Order.ID() {
+		return fmt.Errorf("match %s is not for order %s", sm.Match.ID(), sm.Order.ID())
+	}
+	if sm.Match.From == sm.Order.From {
+		return errors.New("order owner matched their own order")
+	}
+	if sm.Order.BaseID == sm.Order.QuoteID {
+		return fmt.Errorf("base and quote assets are identical. %d = %d", sm.Order.BaseID, sm.Order.QuoteID)
+	}
+	if sm.Match.Qty == 0 || sm.Order.LotSize == 0 || sm.Match.Qty%sm.Order.LotSize != 0 {
+		return fmt.Errorf("invalid match quantity %d for lot size %d", sm.Match.Qty, sm.Order.LotSize)
+	}
+	if sm.MakerAddress == "" || sm.TakerAddress == "" {
+		return errors.New("missing address")
+	}
+	return nil
+}
+
+// digest is the hash that is signed. The order's quantity, stamp and
+// expiration change as the order is matched and renewed, so only the order
+// terms that apply to the match are included.
+func (sm *SignedMatch) digest() [32]byte {
+	matchID := sm.Match.ID()
+	b := make([]byte, 0, 32+4+4+1+8+8+4+len(sm.MakerAddress)+len(sm.TakerAddress))
+	b = append(b, matchID[:]...)
+	b = binary.BigEndian.AppendUint32(b, sm.Order.BaseID)
+	b = binary.BigEndian.AppendUint32(b, sm.Order.QuoteID)
+	var sell byte
+	if sm.Order.Sell {
+		sell = 1
+	}
+	b = append(b, sell)
+	b = binary.BigEndian.AppendUint64(b, sm.Order.Rate)
+	b = binary.BigEndian.AppendUint64(b, sm.Order.LotSize)
+	b = binary.BigEndian.AppendUint16(b, uint16(len(sm.MakerAddress)))
+	b = append(b, sm.MakerAddress...)
+	b = binary.BigEndian.AppendUint16(b, uint16(len(sm.TakerAddress)))
+	b = append(b, sm.TakerAddress...)
+	return sha256.Sum256(b)
+}
+
+// Sign signs the match and swap terms.
+func (sm *SignedMatch) Sign(priv *secp256k1.PrivateKey) {
+	h := sm.digest()
+	sm.Sig = ecdsa.Sign(priv, h[:]).Serialize()
+}
+
+// Verify checks that the match and swap terms were signed by the peer.
+func (sm *SignedMatch) Verify(signer PeerID) error {
+	pubKey, err := signer.PublicKey()
+	if err != nil {
+		return fmt.Errorf("error parsing public key: %w", err)
+	}
+	sig, err := ecdsa.ParseDERSignature(sm.Sig)
+	if err != nil {
+		return fmt.Errorf("error decoding signature: %w", err)
+	}
+	h := sm.digest()
+	if !sig.Verify(h[:], pubKey) {
+		return errors.New("signature verification failed")
+	}
+	return nil
+}
+
+// Contracts are the maker's and the taker's swap contracts required by the
+// match, without the contract-specific CoinID, Contract and SecretHash.
+func (sm *SignedMatch) Contracts() (maker, taker *HTLCAudit) {
+	baseQty := sm.Match.Qty
+	quoteQty := calc.BaseToQuote(sm.Order.Rate, baseQty)
+	maker = &HTLCAudit{
+		AssetID:   sm.Order.QuoteID,
+		Recipient: sm.TakerAddress,
+		Value:     quoteQty,
+		LockTime:  sm.Match.Stamp.Add(MakerLockTime).Truncate(time.Second),
+	}
+	taker = &HTLCAudit{
+		AssetID:   sm.Order.BaseID,
+		Recipient: sm.MakerAddress,
+		Value:     baseQty,
+		LockTime:  sm.Match.Stamp.Add(TakerLockTime).Truncate(time.Second),
+	}
+	if sm.Order.Sell {
+		maker.AssetID, taker.AssetID = taker.AssetID, maker.AssetID
+		maker.Value, taker.Value = taker.Value, maker.Value
+	}
+	return maker, taker
+}
+
 type MatchAcceptance struct {
 	OrderID ID40 `json:"orderID"`
 	MatchID ID32 `json:"matchID"`
@@ -98,11 +272,14 @@ type MarketParameters struct {
 	QuoteID uint32 `json:"quoteID"`
 }
 
+// OrderUpdate updates the remaining quantity of an order, and renews the
+// order with a new expiration.
 type OrderUpdate struct {
-	From  PeerID    `json:"from"`
-	Nonce uint64    `json:"nonce"`
-	Qty   uint64    `json:"qty"`
-	Stamp time.Time `json:"stamp"`
+	From       PeerID    `json:"from"`
+	Nonce      uint64    `json:"nonce"`
+	Qty        uint64    `json:"qty"`
+	Stamp      time.Time `json:"stamp"`
+	Expiration time.Time `json:"expiration"`
 }
 
 func (ou *OrderUpdate) ID() ID40 {
@@ -111,3 +288,14 @@ func (ou *OrderUpdate) ID() ID40 {
 	binary.BigEndian.PutUint64(b[32:], ou.Nonce)
 	return b
 }
+
+// Valid checks that the update's stamp is not in the future and that its
+// expiration is within MaxOrderLifetime of its stamp.
+func (ou *OrderUpdate) Valid() error {
+	return checkExpiration(ou.Stamp, ou.Expiration)
+}
+
+// Expired is true if the update's expiration has passed.
+func (ou *OrderUpdate) Expired() bool {
+	return !time.Now().Before(ou.Expiration)
+}
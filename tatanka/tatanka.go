@@ -23,6 +23,7 @@ import (
 	"decred.org/dcrdex/tatanka/mj"
 	"decred.org/dcrdex/tatanka/tanka"
 	"decred.org/dcrdex/tatanka/tcp"
+	"decred.org/dcrdex/tatanka/tcp/noise"
 	"github.com/decred/dcrd/dcrec/secp256k1/v4"
 	"golang.org/x/text/cases"
 	"golang.org/x/text/language"
@@ -64,13 +65,15 @@ func (topic *Topic) unsubUser(peerID tanka.PeerID) {
 // BootNode represents a configured boot node. Tatanka is whitelist only, and
 // node operators are responsible for keeping their whitelist up to date.
 type BootNode struct {
-	// Protocol is one of ("ws", "wss"), though other tatanka comms protocols
-	// may be implemented later. Or we may end up using e.g. go-libp2p.
-	Protocol string
-	PeerID   dex.Bytes
-	// Config can take different forms depending on the comms protocol, but is
-	// probably a tcp.RemoteNodeConfig.
-	Config json.RawMessage
+	// Protocol is one of ("ws", "wss", "tcp"). "tcp" is the noise-encrypted
+	// plain TCP transport, which is authenticated with the PeerID and does not
+	// need TLS.
+	Protocol string    `json:"protocol"`
+	PeerID   dex.Bytes `json:"peerID"`
+	// Config can take different forms depending on the comms protocol. It is a
+	// tcp.RemoteNodeConfig for "ws" and "wss", and a noise.RemoteNodeConfig for
+	// "tcp".
+	Config json.RawMessage `json:"config"`
 }
 
 // parsedBootNode is the unexported version of BootNode, but with a PeerID
@@ -91,10 +94,10 @@ type Tatanka struct {
 	net             dex.Network
 	log             dex.Logger
 	tcpSrv          *tcp.Server
+	noiseSrv        *noise.Server
 	dataDir         string
 	ctx             context.Context
 	wg              *sync.WaitGroup
-	whitelist       map[tanka.PeerID]*parsedBootNode
 	db              *db.DB
 	nets            atomic.Value // []uint32
 	specialHandlers map[string]func(tanka.Sender, *msgjson.Message) *msgjson.Error
@@ -106,12 +109,27 @@ type Tatanka struct {
 	priv *secp256k1.PrivateKey
 	id   tanka.PeerID
 
+	whitelistMtx sync.RWMutex
+	whitelist    map[tanka.PeerID]*parsedBootNode
+	// whitelistCh signals the whitelist loop to connect to newly
+	// whitelisted nodes.
+	whitelistCh chan struct{}
+
+	banMtx sync.RWMutex
+	bans   map[tanka.PeerID]*db.Ban
+
 	chainMtx sync.RWMutex
 	chains   map[uint32]chain.Chain
 
 	relayMtx     sync.Mutex
 	recentRelays map[[32]byte]time.Time
 
+	bcastLog *broadcastLog
+	// bcastLimiter limits broadcasts from locally connected clients, and
+	// relayLimiter limits broadcasts relayed by remote tatankas.
+	bcastLimiter *broadcastLimiter
+	relayLimiter *broadcastLimiter
+
 	clientMtx sync.RWMutex
 	clients   map[tanka.PeerID]*client
 	topics    map[tanka.Topic]*Topic
@@ -134,6 +152,10 @@ type Config struct {
 	RPC        comms.RPCConfig
 	ConfigPath string
 
+	// NoiseListeners are the addresses on which to accept noise-encrypted TCP
+	// connections from whitelisted tatanka nodes.
+	NoiseListeners []string
+
 	// TODO: Change to whitelist
 	WhiteList []BootNode
 
@@ -198,21 +220,52 @@ func New(cfg *Config) (*Tatanka, error) {
 			protocol: n.Protocol,
 		}
 	}
+	// Apply the whitelist edits made through the admin server.
+	edits, err := db.WhitelistEdits()
+	if err != nil {
+		return nil, fmt.Errorf("error loading whitelist edits: %w", err)
+	}
+	for peerID, edit := range edits {
+		if edit.Removed {
+			delete(whitelist, peerID)
+			continue
+		}
+		whitelist[peerID] = &parsedBootNode{
+			peerID:   peerID,
+			cfg:      edit.Config,
+			protocol: edit.Protocol,
+		}
+	}
+	for peerID := range whitelist {
+		db.TrustScorers(peerID)
+	}
+	// Our own scores, e.g. broadcast spam penalties, have full weight.
+	db.TrustScorers(peerID)
+
+	bans, err := db.Bans()
+	if err != nil {
+		return nil, fmt.Errorf("error loading bans: %w", err)
+	}
 
 	t := &Tatanka{
 		net:             cfg.Net,
 		dataDir:         cfg.DataDir,
 		log:             cfg.Logger,
-		whitelist:       whitelist,
 		db:              db,
 		priv:            priv,
 		id:              peerID,
+		whitelist:       whitelist,
+		whitelistCh:     make(chan struct{}, 1),
+		bans:            bans,
 		chains:          chains,
 		tatankas:        make(map[tanka.PeerID]*remoteTatanka),
 		clients:         make(map[tanka.PeerID]*client),
 		remoteClients:   make(map[tanka.PeerID]map[tanka.PeerID]struct{}),
 		topics:          make(map[tanka.Topic]*Topic),
 		recentRelays:    make(map[[32]byte]time.Time),
+		bcastLog:        newBroadcastLog(),
+		bcastLimiter:    newBroadcastLimiter(1),
+		relayLimiter:    newBroadcastLimiter(relayLimitSlack),
 		clientJobs:      make(chan *clientJob, 128),
 		clientHandlers:  make(map[string]interface{}),
 		tatankaHandlers: make(map[string]interface{}),
@@ -242,6 +295,15 @@ func New(cfg *Config) (*Tatanka, error) {
 	if err != nil {
 		return nil, fmt.Errorf("error starting TPC server:: %v", err)
 	}
+	t.noiseSrv, err = noise.NewServer(&noise.ServerConfig{
+		ListenAddrs: cfg.NoiseListeners,
+		PrivateKey:  priv,
+		Allow:       t.whitelisted,
+		Logger:      cfg.Logger.SubLogger("NOISE"),
+	}, &tcpCore{t})
+	if err != nil {
+		return nil, fmt.Errorf("error creating noise server: %v", err)
+	}
 
 	return t, nil
 }
@@ -286,9 +348,11 @@ func (t *Tatanka) prepareHandlers() {
 	for route, handler := range map[string]interface{}{
 		mj.RouteSubscribe: t.handleSubscription,
 		// mj.RouteUnsubscribe: t.handleUnsubscribe,
-		mj.RouteBroadcast: t.handleBroadcast,
-		mj.RouteTankagram: t.handleTankagram,
-		mj.RouteSetScore:  t.handleSetScore,
+		mj.RouteBroadcast:  t.handleBroadcast,
+		mj.RouteTankagram:  t.handleTankagram,
+		mj.RouteSetScore:   t.handleSetScore,
+		mj.RouteAuditSwap:  t.handleAuditSwap,
+		mj.RouteReputation: t.handleReputation,
 	} {
 		registerClientHandler(route, handler)
 	}
@@ -352,7 +416,22 @@ func (t *Tatanka) Connect(ctx context.Context) (_ *sync.WaitGroup, err error) {
 		wg.Done()
 	}()
 
-	// Start a ticker to clean up the recent relays map.
+	// Start the noise server for node-to-node connections.
+	noiseCM := dex.NewConnectionMaster(t.noiseSrv)
+	if err := noiseCM.ConnectOnce(ctx); err != nil {
+		cm.Disconnect()
+		cm.Wait()
+		return nil, fmt.Errorf("error connecting noise server: %v", err)
+	}
+
+	wg.Add(1)
+	go func() {
+		noiseCM.Wait()
+		wg.Done()
+	}()
+
+	// Start a ticker to clean up the recent relays map, the broadcast log, and
+	// the broadcast rate limiters.
 	wg.Add(1)
 	go func() {
 		defer wg.Done()
@@ -367,6 +446,9 @@ func (t *Tatanka) Connect(ctx context.Context) (_ *sync.WaitGroup, err error) {
 					}
 				}
 				t.relayMtx.Unlock()
+				t.bcastLog.prune()
+				t.bcastLimiter.prune()
+				t.relayLimiter.prune()
 			case <-ctx.Done():
 				return
 			}
@@ -384,6 +466,8 @@ func (t *Tatanka) Connect(ctx context.Context) (_ *sync.WaitGroup, err error) {
 		if !success {
 			cm.Disconnect()
 			cm.Wait()
+			noiseCM.Disconnect()
+			noiseCM.Wait()
 		}
 	}()
 
@@ -428,7 +512,8 @@ func (t *Tatanka) Connect(ctx context.Context) (_ *sync.WaitGroup, err error) {
 // tries again.
 func (t *Tatanka) runWhitelistLoop(ctx context.Context) {
 	connectWhitelist := func() {
-		for proto, n := range t.whitelist {
+		for _, n := range t.whitelistNodes() {
+			proto := n.protocol
 			t.tatankasMtx.RLock()
 			_, exists := t.tatankas[n.peerID]
 			t.tatankasMtx.RUnlock()
@@ -436,6 +521,11 @@ func (t *Tatanka) runWhitelistLoop(ctx context.Context) {
 				continue
 			}
 
+			if ban := t.banned(n.peerID); ban != nil {
+				t.log.Debugf("not connecting to banned boot node %s: %s", n.peerID, ban.Reason)
+				continue
+			}
+
 			p, err := t.db.Peer(n.peerID)
 			if err != nil {
 				t.log.Errorf("error getting peer info for boot node at %q (proto %q): %v", string(n.cfg), proto, err)
@@ -463,6 +553,8 @@ func (t *Tatanka) runWhitelistLoop(ctx context.Context) {
 			switch n.protocol {
 			case "ws", "wss":
 				cl, err = t.tcpSrv.ConnectBootNode(ctx, n.cfg, handleMessage, handleDisconnect)
+			case noise.Protocol:
+				cl, err = t.noiseSrv.ConnectBootNode(ctx, n.cfg, n.peerID, handleMessage, handleDisconnect)
 			default:
 				t.log.Errorf("unknown boot node network protocol: %s", proto)
 				continue
@@ -496,6 +588,7 @@ func (t *Tatanka) runWhitelistLoop(ctx context.Context) {
 
 		select {
 		case <-time.After(time.Minute * 5):
+		case <-t.whitelistCh:
 		case <-ctx.Done():
 			return
 		}
@@ -10,6 +10,7 @@ import (
 	"decred.org/dcrdex/dex/msgjson"
 	"decred.org/dcrdex/tatanka/mj"
 	"decred.org/dcrdex/tatanka/tanka"
+	"decred.org/dcrdex/tatanka/tcp/noise"
 )
 
 // handleInboundTatankaConnect handles an inbound tatanka connection.
@@ -19,10 +20,20 @@ func (t *Tatanka) handleInboundTatankaConnect(cl tanka.Sender, msg *msgjson.Mess
 		return msgjson.NewError(mj.ErrBadRequest, "unmarshal error: %v", err)
 	}
 
-	if _, found := t.whitelist[cfg.ID]; !found {
+	// A noise connection is already authenticated with the remote static key,
+	// so the claimed ID must be the key used in the handshake.
+	if nc, is := cl.(*noise.Conn); is && nc.PeerID() != cfg.ID {
+		return msgjson.NewError(mj.ErrAuth, "peer ID does not match handshake key")
+	}
+
+	if !t.whitelisted(cfg.ID) {
 		return msgjson.NewError(mj.ErrAuth, "not whitelisted")
 	}
 
+	if ban := t.banned(cfg.ID); ban != nil {
+		return msgjson.NewError(mj.ErrBannned, "banned: %s", ban.Reason)
+	}
+
 	p, err := t.db.Peer(cfg.ID)
 	if err != nil {
 		return msgjson.NewError(mj.ErrInternal, "error finding peer: %v", err)
@@ -276,6 +287,32 @@ func (t *Tatanka) handleRelayBroadcast(tt *remoteTatanka, msg *msgjson.Message)
 		return
 	}
 
+	if err := checkMarketBroadcast(bcast); err != nil {
+		t.log.Errorf("Ignoring relayed market broadcast received from %s: %v", tt.ID, err)
+		return
+	}
+
+	if err := checkBroadcastSize(bcast, msg.Payload); err != nil {
+		t.log.Errorf("Ignoring relayed broadcast from %s received from %s: %v", bcast.PeerID, tt.ID, err)
+		return
+	}
+
+	if t.banned(bcast.PeerID) != nil {
+		t.log.Debugf("Dropping broadcast from banned peer %s relayed by %s", bcast.PeerID, tt.ID)
+		return
+	}
+
+	// Relayed spam is dropped, but not penalized. Our view of the
+	// broadcaster's tier is only an estimate, which is why the relay limits
+	// have slack, and the tatanka node that the broadcaster is connected to
+	// enforces the exact limits, penalizes the broadcaster, and shares the
+	// score with us.
+	tier := func() int64 { return t.remoteTier(bcast.PeerID) }
+	if ok, _ := t.relayLimiter.allow(bcast, tier); !ok {
+		t.log.Debugf("Dropping rate-limited broadcast from %s relayed by %s", bcast.PeerID, tt.ID)
+		return
+	}
+
 	t.registerRemoteClient(tt.ID, tt.ID)
 
 	if msgErr := t.distributeBroadcastedMessage(bcast, false); msgErr != nil {
@@ -290,6 +327,11 @@ func (t *Tatanka) handleShareScore(tt *remoteTatanka, msg *msgjson.Message) {
 		t.log.Errorf("error unmarshaling shared score: %v", err)
 		return
 	}
+	if ss.Standing != nil {
+		if err := t.db.StoreSharedStanding(ss.Scorer, ss.Standing); err != nil {
+			t.log.Errorf("error storing shared standing for %s: %v", ss.Scorer, err)
+		}
+	}
 	if err := t.db.SetScore(ss.Scored, ss.Scorer, ss.Score, time.Now()); err != nil {
 		return
 	}
@@ -3,14 +3,18 @@ package tatanka
 import (
 	"bytes"
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"sync"
 	"testing"
 	"time"
 
 	"decred.org/dcrdex/dex"
 	"decred.org/dcrdex/dex/encode"
 	"decred.org/dcrdex/dex/msgjson"
+	"decred.org/dcrdex/tatanka/chain"
+	"decred.org/dcrdex/tatanka/db"
 	"decred.org/dcrdex/tatanka/mj"
 	"decred.org/dcrdex/tatanka/tanka"
 	"github.com/decred/dcrd/dcrec/secp256k1/v4"
@@ -87,14 +91,20 @@ func tNewTatanka() *Tatanka {
 		net: dex.Simnet,
 		log: dex.StdOutLogger("T", dex.LevelTrace),
 		// db:            db,
-		priv: priv,
-		id:   peerID,
+		priv:        priv,
+		id:          peerID,
+		whitelist:   make(map[tanka.PeerID]*parsedBootNode),
+		whitelistCh: make(chan struct{}, 1),
+		bans:        make(map[tanka.PeerID]*db.Ban),
 		// chains:        chains,
 		tatankas:        make(map[tanka.PeerID]*remoteTatanka),
 		clients:         make(map[tanka.PeerID]*client),
 		remoteClients:   make(map[tanka.PeerID]map[tanka.PeerID]struct{}),
 		topics:          make(map[tanka.Topic]*Topic),
 		recentRelays:    make(map[[32]byte]time.Time),
+		bcastLog:        newBroadcastLog(),
+		bcastLimiter:    newBroadcastLimiter(1),
+		relayLimiter:    newBroadcastLimiter(relayLimitSlack),
 		clientJobs:      make(chan *clientJob, 128),
 		clientHandlers:  make(map[string]interface{}),
 		tatankaHandlers: make(map[string]interface{}),
@@ -125,6 +135,8 @@ func tNewRemoteTatanka(id byte) (*remoteTatanka, *tSender) {
 
 func tNewClient(id byte) (*client, *tSender) {
 	p, s := tNewPeer(id)
+	p.Bonds = []*tanka.Bond{{Strength: 1, Expiration: time.Now().Add(time.Hour)}}
+	p.Reputation = &tanka.Reputation{}
 	return &client{peer: p}, s
 }
 
@@ -221,3 +233,768 @@ func TestPrepareHandlers(t *testing.T) {
 	// Incorrect call signatures will cause a panic in prepareHandlers.
 	tNewTatanka().prepareHandlers()
 }
+
+func TestCheckMarketBroadcast(t *testing.T) {
+	var peerID tanka.PeerID
+	copy(peerID[:], encode.RandomBytes(33))
+	now := time.Now()
+	newOrder := func() *tanka.Order {
+		return &tanka.Order{
+			From:       peerID,
+			BaseID:     42,
+			QuoteID:    0,
+			Qty:        1 << 20,
+			Rate:       1e8,
+			LotSize:    1 << 10,
+			Stamp:      now,
+			Expiration: now.Add(tanka.MaxOrderLifetime),
+		}
+	}
+	newUpdate := func() *tanka.OrderUpdate {
+		return &tanka.OrderUpdate{
+			From:       peerID,
+			Qty:        1 << 10,
+			Stamp:      now,
+			Expiration: now.Add(tanka.MaxOrderLifetime),
+		}
+	}
+	bcast := func(msgType mj.BroadcastMessageType, thing any) *mj.Broadcast {
+		b, _ := json.Marshal(thing)
+		return &mj.Broadcast{
+			PeerID:      peerID,
+			Topic:       mj.TopicMarket,
+			Subject:     "dcr_btc",
+			MessageType: msgType,
+			Payload:     b,
+			Stamp:       now,
+		}
+	}
+
+	var otherPeer tanka.PeerID
+	copy(otherPeer[:], encode.RandomBytes(33))
+	tooLong, expired, notOurs, future := newOrder(), newOrder(), newOrder(), newOrder()
+	tooLong.Expiration = tooLong.Stamp.Add(tanka.MaxOrderLifetime + time.Second)
+	future.Stamp = now.Add(tanka.MaxClockSkew + time.Minute)
+	future.Expiration = future.Stamp.Add(time.Minute)
+	expired.Stamp = now.Add(-tanka.MaxOrderLifetime)
+	expired.Expiration = now.Add(-time.Second)
+	notOurs.From = otherPeer
+	expiredUpdate := newUpdate()
+	expiredUpdate.Stamp = now.Add(-tanka.MaxOrderLifetime)
+	expiredUpdate.Expiration = now.Add(-time.Second)
+	badUpdate := newUpdate()
+	badUpdate.Expiration = badUpdate.Stamp
+
+	tests := []struct {
+		name    string
+		bcast   *mj.Broadcast
+		wantErr bool
+	}{{
+		name:  "ok order",
+		bcast: bcast(mj.MessageTypeNewOrder, newOrder()),
+	}, {
+		name:  "ok update",
+		bcast: bcast(mj.MessageTypeUpdateOrder, newUpdate()),
+	}, {
+		name:  "other message type",
+		bcast: bcast(mj.MessageTypeTrollBox, &mj.Troll{Msg: "hi"}),
+	}, {
+		name:    "expiration too far in the future",
+		bcast:   bcast(mj.MessageTypeNewOrder, tooLong),
+		wantErr: true,
+	}, {
+		name:    "stamp in the future",
+		bcast:   bcast(mj.MessageTypeNewOrder, future),
+		wantErr: true,
+	}, {
+		name:    "expired order",
+		bcast:   bcast(mj.MessageTypeNewOrder, expired),
+		wantErr: true,
+	}, {
+		name:    "order from another peer",
+		bcast:   bcast(mj.MessageTypeNewOrder, notOurs),
+		wantErr: true,
+	}, {
+		name:    "expired update",
+		bcast:   bcast(mj.MessageTypeUpdateOrder, expiredUpdate),
+		wantErr: true,
+	}, {
+		name:    "expiration not after stamp",
+		bcast:   bcast(mj.MessageTypeUpdateOrder, badUpdate),
+		wantErr: true,
+	}}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := checkMarketBroadcast(test.bcast)
+			if test.wantErr {
+				if err == nil {
+					t.Fatal("expected error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+		})
+	}
+}
+
+type tChain struct {
+	audits   map[string]bool
+	auditErr error
+}
+
+func (c *tChain) Connect(context.Context) (*sync.WaitGroup, error) { return &sync.WaitGroup{}, nil }
+func (c *tChain) Connected() bool                                  { return true }
+func (c *tChain) CheckBond(*tanka.Bond) error                      { return nil }
+func (c *tChain) AuditHTLC(a *tanka.HTLCAudit) (bool, error) {
+	return c.audits[string(a.CoinID)], c.auditErr
+}
+
+type tNonAuditingChain struct{}
+
+func (c *tNonAuditingChain) Connect(context.Context) (*sync.WaitGroup, error) {
+	return &sync.WaitGroup{}, nil
+}
+func (c *tNonAuditingChain) Connected() bool             { return true }
+func (c *tNonAuditingChain) CheckBond(*tanka.Bond) error { return nil }
+
+func tNewSigningClient() (*client, *tSender, *secp256k1.PrivateKey) {
+	priv, _ := secp256k1.GeneratePrivateKey()
+	c, s := tNewClient(0)
+	copy(c.ID[:], priv.PubKey().SerializeCompressed())
+	s.id = c.ID
+	return c, s, priv
+}
+
+func TestHandleAuditSwap(t *testing.T) {
+	const dcrID, btcID, ethID = 42, 0, 60
+	dcrChain := &tChain{audits: make(map[string]bool)}
+	btcChain := &tChain{audits: make(map[string]bool)}
+	good, bad := []byte("good"), []byte("bad")
+	dcrChain.audits[string(good)] = true
+	btcChain.audits[string(good)] = true
+
+	srv := tNewTatanka()
+	var err error
+	if srv.db, err = db.New(t.TempDir(), srv.log); err != nil {
+		t.Fatalf("error creating db: %v", err)
+	}
+	srv.chains = map[uint32]chain.Chain{
+		dcrID: dcrChain,
+		btcID: btcChain,
+		ethID: &tNonAuditingChain{},
+	}
+	c, s, priv := tNewSigningClient()
+	counterparty, _, counterpartyPriv := tNewSigningClient()
+	outsider, _, _ := tNewSigningClient()
+	srv.clients[counterparty.ID] = counterparty
+	// The disputers' scores have full weight.
+	for _, id := range []tanka.PeerID{c.ID, outsider.ID} {
+		if err := srv.db.StoreSharedStanding(id, &tanka.Standing{
+			BondTier:       tanka.FullWeightTier,
+			BondExpiration: time.Now().Add(time.Hour),
+			FirstBond:      time.Now().Add(-tanka.ScorerMaturity),
+		}); err != nil {
+			t.Fatalf("StoreSharedStanding error: %v", err)
+		}
+	}
+
+	// The disputer is the maker of a buy order, so they send the quote asset.
+	stamp := time.Now().Add(-tanka.TakerLockTime - time.Minute).Truncate(time.Millisecond)
+	newMatch := func(baseID, quoteID uint32, stamp time.Time, signer *secp256k1.PrivateKey) *tanka.SignedMatch {
+		o := &tanka.Order{
+			From:    c.ID,
+			BaseID:  baseID,
+			QuoteID: quoteID,
+			Qty:     4e8,
+			Rate:    2e8,
+			LotSize: 1e8,
+			Stamp:   stamp,
+		}
+		sm := &tanka.SignedMatch{
+			Order: o,
+			Match: &tanka.Match{
+				From:    counterparty.ID,
+				OrderID: o.ID(),
+				Qty:     2e8,
+				Stamp:   stamp,
+			},
+			MakerAddress: "maker",
+			TakerAddress: "taker",
+		}
+		sm.Sign(signer)
+		return sm
+	}
+	secretHash := encode.RandomBytes(32)
+	newDispute := func(sm *tanka.SignedMatch, ours, theirs []byte) *mj.SwapDispute {
+		makerContract, takerContract := sm.Contracts()
+		d := &mj.SwapDispute{Match: sm, Ours: makerContract}
+		d.Ours.CoinID, d.Ours.SecretHash = ours, secretHash
+		if theirs != nil {
+			d.Theirs = takerContract
+			d.Theirs.CoinID, d.Theirs.SecretHash = theirs, secretHash
+		}
+		return d
+	}
+	sm := newMatch(btcID, dcrID, stamp, counterpartyPriv)
+	tweak := func(d *mj.SwapDispute, f func(d *mj.SwapDispute)) *mj.SwapDispute {
+		f(d)
+		return d
+	}
+
+	tests := []struct {
+		name      string
+		from      *client
+		dispute   *mj.SwapDispute
+		auditErr  error
+		wantFault bool
+		wantScore int64
+		wantErr   bool
+	}{{
+		name:    "counterparty funded",
+		dispute: newDispute(sm, good, good),
+	}, {
+		name:      "counterparty contract failed audit",
+		dispute:   newDispute(sm, good, bad),
+		wantFault: true,
+		wantScore: int64(tanka.ScoreSwapFault),
+	}, {
+		name:    "counterparty contract omitted",
+		dispute: newDispute(sm, good, nil),
+		wantErr: true,
+	}, {
+		name:    "our contract failed audit",
+		dispute: newDispute(sm, bad, good),
+		wantErr: true,
+	}, {
+		name:    "no match",
+		dispute: tweak(newDispute(sm, good, good), func(d *mj.SwapDispute) { d.Match = nil }),
+		wantErr: true,
+	}, {
+		name:    "no contract",
+		dispute: &mj.SwapDispute{Match: sm},
+		wantErr: true,
+	}, {
+		name:    "not a party to the match",
+		from:    outsider,
+		dispute: newDispute(sm, good, good),
+		wantErr: true,
+	}, {
+		name:    "not signed by the counterparty",
+		dispute: newDispute(newMatch(btcID, dcrID, stamp, priv), good, good),
+		wantErr: true,
+	}, {
+		name:    "before the swap deadline",
+		dispute: newDispute(newMatch(btcID, dcrID, time.Now().Truncate(time.Millisecond), counterpartyPriv), good, good),
+		wantErr: true,
+	}, {
+		name:    "wrong recipient",
+		dispute: tweak(newDispute(sm, good, good), func(d *mj.SwapDispute) { d.Ours.Recipient = "maker" }),
+		wantErr: true,
+	}, {
+		name:    "wrong value",
+		dispute: tweak(newDispute(sm, good, good), func(d *mj.SwapDispute) { d.Ours.Value /= 2 }),
+		wantErr: true,
+	}, {
+		name:    "wrong asset",
+		dispute: tweak(newDispute(sm, good, good), func(d *mj.SwapDispute) { d.Ours.AssetID = btcID }),
+		wantErr: true,
+	}, {
+		name: "different secret hashes",
+		dispute: tweak(newDispute(sm, good, good), func(d *mj.SwapDispute) {
+			d.Theirs.SecretHash = encode.RandomBytes(32)
+		}),
+		wantErr: true,
+	}, {
+		name:    "same asset",
+		dispute: newDispute(newMatch(dcrID, dcrID, stamp, counterpartyPriv), good, good),
+		wantErr: true,
+	}, {
+		name:    "unknown asset",
+		dispute: newDispute(newMatch(btcID, 1, stamp, counterpartyPriv), good, good),
+		wantErr: true,
+	}, {
+		name:    "auditing not supported",
+		dispute: newDispute(newMatch(btcID, ethID, stamp, counterpartyPriv), good, good),
+		wantErr: true,
+	}, {
+		name:     "audit error",
+		dispute:  newDispute(sm, good, good),
+		auditErr: errors.New("test error"),
+		wantErr:  true,
+	}}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			dcrChain.auditErr = test.auditErr
+			from := c
+			if test.from != nil {
+				from = test.from
+			}
+			msg := mj.MustRequest(mj.RouteAuditSwap, test.dispute)
+			msgErr := srv.handleAuditSwap(from, msg)
+			if test.wantErr {
+				if msgErr == nil {
+					t.Fatal("expected error")
+				}
+				return
+			}
+			if msgErr != nil {
+				t.Fatalf("unexpected error: %v", msgErr)
+			}
+			resp := s.received()
+			if resp == nil {
+				t.Fatal("no response")
+			}
+			var res mj.SwapDisputeResult
+			if err := resp.UnmarshalResult(&res); err != nil {
+				t.Fatalf("error unmarshaling result: %v", err)
+			}
+			if res.Fault != test.wantFault {
+				t.Fatalf("wanted fault = %t, got %t", test.wantFault, res.Fault)
+			}
+			if res.Reputation.Score != test.wantScore {
+				t.Fatalf("wanted score %d, got %d", test.wantScore, res.Reputation.Score)
+			}
+			if test.wantFault && counterparty.Reputation.Score != test.wantScore {
+				t.Fatalf("counterparty reputation not updated")
+			}
+		})
+	}
+
+	fault, err := srv.db.SwapFault(sm.Match.ID())
+	if err != nil {
+		t.Fatalf("SwapFault error: %v", err)
+	}
+	if fault == nil || fault.PeerID != counterparty.ID {
+		t.Fatalf("swap fault not recorded for the counterparty")
+	}
+	// Disputing the match again doesn't record another fault.
+	dcrChain.auditErr = nil
+	scoreBefore := counterparty.Reputation.Score
+	if msgErr := srv.handleAuditSwap(c, mj.MustRequest(mj.RouteAuditSwap, newDispute(sm, good, bad))); msgErr != nil {
+		t.Fatalf("unexpected error: %v", msgErr)
+	}
+	s.received()
+	if refault, _ := srv.db.SwapFault(sm.Match.ID()); !refault.Stamp.Equal(fault.Stamp) {
+		t.Fatalf("swap fault recorded twice")
+	}
+
+	// Leaving out the contract of an honest counterparty doesn't fault them.
+	honest := newMatch(btcID, dcrID, stamp.Add(-time.Second), counterpartyPriv)
+	if msgErr := srv.handleAuditSwap(c, mj.MustRequest(mj.RouteAuditSwap, newDispute(honest, good, nil))); msgErr == nil {
+		t.Fatalf("no error for dispute without the counterparty's contract")
+	}
+	if f, _ := srv.db.SwapFault(honest.Match.ID()); f != nil {
+		t.Fatalf("fault recorded for omitted counterparty contract")
+	}
+	if counterparty.Reputation.Score != scoreBefore {
+		t.Fatalf("counterparty score changed by omitted contract")
+	}
+}
+
+func TestHandleReputation(t *testing.T) {
+	srv := tNewTatanka()
+	var err error
+	if srv.db, err = db.New(t.TempDir(), srv.log); err != nil {
+		t.Fatalf("error creating db: %v", err)
+	}
+	c, s := tNewClient(1)
+	scorer, scored := tanka.PeerID{0x01}, tanka.PeerID{0x02}
+	if err := srv.db.StoreSharedStanding(scorer, &tanka.Standing{
+		BondTier:       tanka.FullWeightTier,
+		BondExpiration: time.Now().Add(time.Hour),
+		FirstBond:      time.Now().Add(-tanka.ScorerMaturity),
+	}); err != nil {
+		t.Fatalf("StoreSharedStanding error: %v", err)
+	}
+	if err := srv.db.SetScore(scored, scorer, 50, time.Now()); err != nil {
+		t.Fatalf("SetScore error: %v", err)
+	}
+
+	msg := mj.MustRequest(mj.RouteReputation, &mj.ReputationRequest{PeerIDs: []tanka.PeerID{scored, scorer}})
+	if msgErr := srv.handleReputation(c, msg); msgErr != nil {
+		t.Fatalf("handleReputation error: %v", msgErr)
+	}
+	var reps []*tanka.Reputation
+	if err := s.received().UnmarshalResult(&reps); err != nil {
+		t.Fatalf("error unmarshaling result: %v", err)
+	}
+	if len(reps) != 2 || reps[0].Score != 50 || reps[0].Depth != 1 || reps[1].Depth != 0 {
+		t.Fatalf("wrong reputations %+v", reps)
+	}
+
+	for _, peerIDs := range [][]tanka.PeerID{nil, make([]tanka.PeerID, mj.MaxReputationRequest+1)} {
+		msg := mj.MustRequest(mj.RouteReputation, &mj.ReputationRequest{PeerIDs: peerIDs})
+		if srv.handleReputation(c, msg) == nil {
+			t.Fatalf("no error for %d peers", len(peerIDs))
+		}
+	}
+}
+
+func TestBroadcastReplay(t *testing.T) {
+	srv := tNewTatanka()
+	c0, c0s := tNewClient(1)
+	c1, c1s := tNewClient(2)
+	srv.clients[c0.ID] = c0
+	srv.clients[c1.ID] = c1
+
+	const subject = "dcr_btc"
+	subscribe := func(c *client, s *tSender, replay *mj.ReplayRequest) *msgjson.Message {
+		t.Helper()
+		msg := mj.MustRequest(mj.RouteSubscribe, &mj.Subscription{Topic: mj.TopicMarket, Subject: subject, Replay: replay})
+		if msgErr := srv.handleSubscription(c, msg); msgErr != nil {
+			t.Fatalf("handleSubscription error: %v", msgErr)
+		}
+		// Skip new_subscriber broadcasts.
+		for resp := s.received(); resp != nil; resp = s.received() {
+			if resp.Type == msgjson.Response {
+				return resp
+			}
+		}
+		t.Fatalf("no subscription response")
+		return nil
+	}
+	replay := func(req *mj.ReplayRequest) *mj.SubscriptionReplay {
+		t.Helper()
+		var r mj.SubscriptionReplay
+		if err := subscribe(c1, c1s, req).UnmarshalResult(&r); err != nil {
+			t.Fatalf("error unmarshaling replay: %v", err)
+		}
+		return &r
+	}
+
+	// A subscription without a replay request gets the usual result.
+	var ok bool
+	if err := subscribe(c0, c0s, nil).UnmarshalResult(&ok); err != nil || !ok {
+		t.Fatalf("wrong subscription result, err = %v", err)
+	}
+
+	const n = 3
+	for i := 0; i < n; i++ {
+		troll, _ := json.Marshal(&mj.Troll{Msg: fmt.Sprintf("msg %d", i)})
+		msg := mj.MustRequest(mj.RouteBroadcast, &mj.Broadcast{
+			PeerID:      c0.ID,
+			Topic:       mj.TopicMarket,
+			Subject:     subject,
+			MessageType: mj.MessageTypeTrollBox,
+			Payload:     troll,
+			Stamp:       time.Now(),
+		})
+		if msgErr := srv.handleBroadcast(c0, msg); msgErr != nil {
+			t.Fatalf("handleBroadcast error: %v", msgErr)
+		}
+		// The subscriber gets the broadcast with the sequence number.
+		var bcast mj.Broadcast
+		if err := c0s.received().Unmarshal(&bcast); err != nil || bcast.Seq == 0 {
+			t.Fatalf("broadcast not distributed with sequence number, err = %v", err)
+		}
+		c0s.received() // result
+	}
+
+	// Everything.
+	r := replay(&mj.ReplayRequest{})
+	if len(r.Broadcasts) != n || r.Seq != r.Broadcasts[n-1].Seq {
+		t.Fatalf("wrong full replay")
+	}
+	for i := 1; i < n; i++ {
+		if r.Broadcasts[i].Seq <= r.Broadcasts[i-1].Seq {
+			t.Fatalf("replay out of order")
+		}
+	}
+	first := r.Broadcasts[0]
+
+	// Since a sequence number.
+	if r = replay(&mj.ReplayRequest{Seq: first.Seq}); !r.Complete || len(r.Broadcasts) != n-1 {
+		t.Fatalf("wrong replay since sequence number. complete = %t, %d broadcasts", r.Complete, len(r.Broadcasts))
+	}
+
+	// Since a time.
+	if r = replay(&mj.ReplayRequest{Since: first.Stamp}); !r.Complete || len(r.Broadcasts) != n-1 {
+		t.Fatalf("wrong replay since time. complete = %t, %d broadcasts", r.Complete, len(r.Broadcasts))
+	}
+
+	// Unknown sequence number gets everything, but is incomplete.
+	if r = replay(&mj.ReplayRequest{Seq: r.Seq + 1}); r.Complete || len(r.Broadcasts) != n {
+		t.Fatalf("wrong replay for unknown sequence number")
+	}
+
+	// Pruned broadcasts make the replay incomplete.
+	srv.bcastLog.subjects[subjectKey{mj.TopicMarket, subject}].dropFirst(1)
+	if r = replay(&mj.ReplayRequest{Seq: first.Seq - 1}); r.Complete || len(r.Broadcasts) != n-1 {
+		t.Fatalf("wrong replay after pruning")
+	}
+	if r = replay(&mj.ReplayRequest{Seq: first.Seq}); !r.Complete {
+		t.Fatalf("replay after pruned broadcast not complete")
+	}
+
+	// Relayed broadcasts for subjects without local subscribers are not
+	// logged.
+	relayed := &mj.Broadcast{PeerID: c0.ID, Topic: mj.TopicMarket, Subject: "eth_btc", Stamp: time.Now()}
+	srv.distributeBroadcastedMessage(relayed, false)
+	relayed.Topic = "unknown"
+	srv.distributeBroadcastedMessage(relayed, false)
+	if len(srv.bcastLog.subjects) != 1 {
+		t.Fatalf("broadcast to unknown subject logged")
+	}
+}
+
+func TestBroadcastLog(t *testing.T) {
+	l := newBroadcastLog()
+	start := l.seq
+	bcast := &mj.Broadcast{Topic: mj.TopicMarket, Subject: "dcr_btc", Stamp: time.Now()}
+	for i := 0; i < broadcastLogSize+1; i++ {
+		l.add(bcast)
+	}
+	sl := l.subjects[subjectKey{bcast.Topic, bcast.Subject}]
+	if len(sl.bcasts) != broadcastLogSize || sl.pruned != start+1 {
+		t.Fatalf("log not bounded")
+	}
+	if bcast.Seq != 0 {
+		t.Fatalf("logged broadcast not copied")
+	}
+
+	// Old broadcasts are pruned, and the subject is forgotten.
+	for _, b := range sl.bcasts {
+		b.Stamp = time.Now().Add(-broadcastLogAge - time.Second)
+	}
+	l.prune()
+	if len(l.subjects) != 0 || l.forgotten != l.seq {
+		t.Fatalf("subject not pruned")
+	}
+	// Replays for anything before the forgotten broadcasts are incomplete.
+	if r := l.replay(bcast.Topic, bcast.Subject, &mj.ReplayRequest{Seq: start + 1}); r.Complete || len(r.Broadcasts) != 0 {
+		t.Fatalf("replay of forgotten subject complete")
+	}
+	if r := l.replay(bcast.Topic, bcast.Subject, &mj.ReplayRequest{Seq: l.seq}); !r.Complete {
+		t.Fatalf("replay after forgotten subject not complete")
+	}
+
+	// The number of subjects is capped, and the subject with the oldest
+	// broadcast is forgotten first.
+	l = newBroadcastLog()
+	start = l.seq
+	for i := 0; i < maxLoggedSubjects+1; i++ {
+		l.add(&mj.Broadcast{Topic: mj.TopicMarket, Subject: tanka.Subject(fmt.Sprint(i)), Stamp: time.Now()})
+	}
+	if len(l.subjects) != maxLoggedSubjects {
+		t.Fatalf("expected %d subjects, got %d", maxLoggedSubjects, len(l.subjects))
+	}
+	if _, found := l.subjects[subjectKey{mj.TopicMarket, "0"}]; found {
+		t.Fatalf("oldest subject not forgotten")
+	}
+	if l.forgotten != start+1 {
+		t.Fatalf("forgotten sequence not updated")
+	}
+}
+
+func TestBroadcastLimiter(t *testing.T) {
+	l := newBroadcastLimiter(1)
+	var peerID tanka.PeerID
+	peerID[0] = 0x01
+	bcast := &mj.Broadcast{PeerID: peerID, Topic: mj.TopicMarket, Subject: "dcr_btc"}
+	tier := int64(1)
+	tierFunc := func() int64 { return tier }
+
+	// Tier 1 gets the base burst.
+	burst := bcastTopicLimits[mj.TopicMarket].burst
+	for i := 0; i < burst; i++ {
+		if ok, _ := l.allow(bcast, tierFunc); !ok {
+			t.Fatalf("broadcast %d not allowed", i)
+		}
+	}
+	if ok, _ := l.allow(bcast, tierFunc); ok {
+		t.Fatalf("broadcast beyond burst allowed")
+	}
+
+	// Other topics and the troll box are limited separately.
+	if ok, _ := l.allow(&mj.Broadcast{PeerID: peerID, Topic: "other"}, tierFunc); !ok {
+		t.Fatalf("broadcast to other topic not allowed")
+	}
+	trollBox := &mj.Broadcast{PeerID: peerID, Topic: mj.TopicMarket, MessageType: mj.MessageTypeTrollBox}
+	for i := 0; i < trollBoxLimit.burst; i++ {
+		if ok, _ := l.allow(trollBox, tierFunc); !ok {
+			t.Fatalf("troll box message %d not allowed", i)
+		}
+	}
+	if ok, _ := l.allow(trollBox, tierFunc); ok {
+		t.Fatalf("troll box message beyond burst allowed")
+	}
+
+	// Repeated strikes result in a penalty.
+	var penalized bool
+	for i := 0; i < bcastStrikeLimit && !penalized; i++ {
+		_, penalized = l.allow(bcast, tierFunc)
+	}
+	if !penalized {
+		t.Fatalf("not penalized after %d strikes", bcastStrikeLimit)
+	}
+	if l.strikes[peerID] != nil {
+		t.Fatalf("strikes not reset after penalty")
+	}
+
+	// Other refused broadcasts count toward the same limit.
+	for i := 1; i < bcastStrikeLimit; i++ {
+		if l.strike(peerID) {
+			t.Fatalf("penalized after %d strikes", i)
+		}
+	}
+	if _, penalized = l.allow(bcast, tierFunc); !penalized {
+		t.Fatalf("not penalized after mixed strikes")
+	}
+
+	// The penalty takes the peer to tier 0, and they can no longer broadcast.
+	tier = 0
+	if ok, _ := l.allow(&mj.Broadcast{PeerID: peerID, Topic: "other"}, tierFunc); ok {
+		t.Fatalf("tier 0 broadcast allowed")
+	}
+
+	// Idle limiters are pruned.
+	for _, lim := range l.limiters {
+		lim.lastHit = time.Now().Add(-bcastLimiterExpiry - time.Second)
+	}
+	l.strike(peerID)
+	l.strikes[peerID].lastHit = time.Now().Add(-bcastLimiterExpiry - time.Second)
+	l.prune()
+	if len(l.limiters) != 0 || len(l.strikes) != 0 {
+		t.Fatalf("limiters not pruned")
+	}
+
+	// Higher tiers get a proportionally larger burst.
+	tier = 3
+	for i := 0; i < burst*3; i++ {
+		if ok, _ := l.allow(bcast, tierFunc); !ok {
+			t.Fatalf("tier 3 broadcast %d not allowed", i)
+		}
+	}
+}
+
+func TestCheckBroadcastSize(t *testing.T) {
+	bcast := &mj.Broadcast{Topic: mj.TopicMarket}
+	if err := checkBroadcastSize(bcast, make([]byte, mj.MaxBroadcastSize)); err != nil {
+		t.Fatalf("max size broadcast rejected: %v", err)
+	}
+	if err := checkBroadcastSize(bcast, make([]byte, mj.MaxBroadcastSize+1)); err == nil {
+		t.Fatalf("oversized broadcast accepted")
+	}
+	bcast.MessageType = mj.MessageTypeTrollBox
+	if err := checkBroadcastSize(bcast, make([]byte, mj.MaxTrollBoxSize+1)); err == nil {
+		t.Fatalf("oversized troll box message accepted")
+	}
+}
+
+func TestAdmin(t *testing.T) {
+	srv, shutdown := tNewRunningTatanka()
+	defer shutdown()
+	var err error
+	if srv.db, err = db.New(t.TempDir(), srv.log); err != nil {
+		t.Fatalf("error creating db: %v", err)
+	}
+
+	c0, _ := tNewClient(1)
+	c1, _ := tNewClient(2)
+	srv.clients[c0.ID] = c0
+	srv.clients[c1.ID] = c1
+	tt, _ := tNewRemoteTatanka(3)
+	srv.tatankas[tt.ID] = tt
+	remoteClient := tanka.PeerID{0x04}
+	srv.registerRemoteClient(tt.ID, remoteClient)
+
+	const subject = "dcr_btc"
+	for _, c := range []*client{c0, c1} {
+		msg := mj.MustRequest(mj.RouteSubscribe, &mj.Subscription{Topic: mj.TopicMarket, Subject: subject})
+		if msgErr := srv.handleSubscription(c, msg); msgErr != nil {
+			t.Fatalf("handleSubscription error: %v", msgErr)
+		}
+	}
+
+	if clients := srv.Clients(); len(clients) != 2 || len(clients[0].Topics) != 1 || clients[0].Tier != 1 {
+		t.Fatalf("wrong clients")
+	}
+	if tatankas := srv.Tatankas(); len(tatankas) != 1 || tatankas[0].Clients != 1 {
+		t.Fatalf("wrong tatankas")
+	}
+	topics := srv.Topics()
+	if len(topics) != 1 || topics[0].Subscribers != 2 || topics[0].Subjects[subject] != 2 {
+		t.Fatalf("wrong topics")
+	}
+
+	// Banning a client disconnects them and unsubscribes them.
+	if err := srv.Ban(c0.ID, "spam"); err != nil {
+		t.Fatalf("Ban error: %v", err)
+	}
+	if srv.clientNode(c0.ID) != nil {
+		t.Fatalf("banned client not disconnected")
+	}
+	if topics = srv.Topics(); topics[0].Subscribers != 1 {
+		t.Fatalf("banned client not unsubscribed")
+	}
+	if bans := srv.Bans(); len(bans) != 1 || bans[0].Reason != "spam" {
+		t.Fatalf("wrong bans")
+	}
+	if srv.Ban(srv.id, "") == nil {
+		t.Fatalf("no error for banning ourselves")
+	}
+	// Bans are persisted.
+	if bans, _ := srv.db.Bans(); bans[c0.ID] == nil {
+		t.Fatalf("ban not stored")
+	}
+	if err := srv.Unban(c0.ID); err != nil {
+		t.Fatalf("Unban error: %v", err)
+	}
+	if srv.banned(c0.ID) != nil {
+		t.Fatalf("still banned")
+	}
+	if srv.Unban(c0.ID) == nil {
+		t.Fatalf("no error for unbanning peer that's not banned")
+	}
+
+	// Whitelist management.
+	var nodeID tanka.PeerID
+	nodeID[0] = 0x05
+	node := &BootNode{Protocol: "wss", PeerID: nodeID[:], Config: []byte(`{}`)}
+	if err := srv.AddWhitelistNode(node); err != nil {
+		t.Fatalf("AddWhitelistNode error: %v", err)
+	}
+	if !srv.whitelisted(nodeID) || len(srv.Whitelist()) != 1 {
+		t.Fatalf("node not whitelisted")
+	}
+	select {
+	case <-srv.whitelistCh:
+	default:
+		t.Fatalf("whitelist loop not signaled")
+	}
+	for _, n := range []*BootNode{
+		{Protocol: "http", PeerID: nodeID[:], Config: []byte(`{}`)},
+		{Protocol: "wss", PeerID: nodeID[:1], Config: []byte(`{}`)},
+		{Protocol: "wss", PeerID: nodeID[:]},
+		{Protocol: "wss", PeerID: srv.id[:], Config: []byte(`{}`)},
+	} {
+		if srv.AddWhitelistNode(n) == nil {
+			t.Fatalf("no error for bad boot node %+v", n)
+		}
+	}
+	// Removing a connected node disconnects it.
+	srv.whitelist[tt.ID] = &parsedBootNode{peerID: tt.ID}
+	if err := srv.RemoveWhitelistNode(tt.ID); err != nil {
+		t.Fatalf("RemoveWhitelistNode error: %v", err)
+	}
+	if srv.whitelisted(tt.ID) || srv.tatankaNode(tt.ID) != nil {
+		t.Fatalf("removed node still whitelisted or connected")
+	}
+	if srv.RemoveWhitelistNode(tt.ID) == nil {
+		t.Fatalf("no error for removing unknown node")
+	}
+
+	// Whitelist edits are stored.
+	edits, err := srv.db.WhitelistEdits()
+	if err != nil {
+		t.Fatalf("WhitelistEdits error: %v", err)
+	}
+	if edits[nodeID] == nil || edits[nodeID].Removed || edits[nodeID].Protocol != "wss" {
+		t.Fatalf("whitelist addition not stored")
+	}
+	if edits[tt.ID] == nil || !edits[tt.ID].Removed {
+		t.Fatalf("whitelist removal not stored")
+	}
+}
@@ -24,6 +24,8 @@ type Client struct {
 	cl     comms.WsConn
 	cm     *dex.ConnectionMaster
 	handle func(*msgjson.Message)
+	// reconnected is optional.
+	reconnected func()
 }
 
 type Config struct {
@@ -32,6 +34,9 @@ type Config struct {
 	Cert          []byte
 	PrivateKey    *secp256k1.PrivateKey
 	HandleMessage func(*msgjson.Message)
+	// HandleReconnect is optional, and is called after the connection is
+	// re-established following a disconnect.
+	HandleReconnect func()
 }
 
 func New(cfg *Config) (*Client, error) {
@@ -45,10 +50,11 @@ func New(cfg *Config) (*Client, error) {
 		return nil, fmt.Errorf("protocol should be 'ws' or 'wss', not %q", u.Scheme)
 	}
 	return &Client{
-		log:    cfg.Logger,
-		url:    u,
-		cert:   cfg.Cert,
-		handle: cfg.HandleMessage,
+		log:         cfg.Logger,
+		url:         u,
+		cert:        cfg.Cert,
+		handle:      cfg.HandleMessage,
+		reconnected: cfg.HandleReconnect,
 	}, nil
 }
 
@@ -59,7 +65,10 @@ func (c *Client) Connect(ctx context.Context) (_ *sync.WaitGroup, err error) {
 		PingWait: 20 * time.Second,
 		Cert:     c.cert,
 		ReconnectSync: func() {
-			fmt.Println("## RECONNECTED RECONNECTED RECONNECTED RECONNECTED ")
+			c.log.Infof("WebSockets client for %s has reconnected", c.url)
+			if c.reconnected != nil {
+				c.reconnected()
+			}
 		},
 		ConnectEventFunc: func(status comms.ConnectionStatus) {
 			if status == comms.Disconnected {
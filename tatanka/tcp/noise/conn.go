@@ -0,0 +1,249 @@
+// This code is available on the terms of the project LICENSE.md file,
+// also available online at https://blueoakcouncil.org/license/1.0.0.
+
+package noise
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"decred.org/dcrdex/dex"
+	"decred.org/dcrdex/dex/msgjson"
+	"decred.org/dcrdex/tatanka/mj"
+	"decred.org/dcrdex/tatanka/tanka"
+)
+
+const (
+	// maxFrameSize is the largest frame that will be read from a peer.
+	maxFrameSize = 1 << 22 // 4 MiB
+	// handshakeTimeout is how long a peer has to complete the handshake.
+	handshakeTimeout = time.Second * 10
+	// requestTimeout is how long to wait for a response to a request.
+	requestTimeout = time.Second * 30
+)
+
+// ErrConnectionClosed is returned when sending on a closed Conn.
+const ErrConnectionClosed = dex.ErrorKind("connection closed")
+
+// writeFrame writes the length-prefixed frame.
+func writeFrame(w io.Writer, b []byte) error {
+	if len(b) > maxFrameSize {
+		return fmt.Errorf("frame size %d exceeds limit %d", len(b), maxFrameSize)
+	}
+	buf := make([]byte, 4+len(b))
+	binary.BigEndian.PutUint32(buf[:4], uint32(len(b)))
+	copy(buf[4:], b)
+	_, err := w.Write(buf)
+	return err
+}
+
+// readFrame reads a length-prefixed frame.
+func readFrame(r io.Reader) ([]byte, error) {
+	var lenB [4]byte
+	if _, err := io.ReadFull(r, lenB[:]); err != nil {
+		return nil, err
+	}
+	l := binary.BigEndian.Uint32(lenB[:])
+	if l > maxFrameSize {
+		return nil, fmt.Errorf("frame size %d exceeds limit %d", l, maxFrameSize)
+	}
+	b := make([]byte, l)
+	if _, err := io.ReadFull(r, b); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+type responseHandler struct {
+	f      func(*msgjson.Message)
+	expire *time.Timer
+}
+
+// Conn is an encrypted connection to a tatanka node. Conn implements
+// tanka.Sender.
+type Conn struct {
+	conn   net.Conn
+	log    dex.Logger
+	id     atomic.Value // tanka.PeerID
+	handle func(*Conn, *msgjson.Message)
+	onDone func()
+
+	writeMtx sync.Mutex
+	send     *cipherState
+
+	recv *cipherState
+
+	respMtx      sync.Mutex
+	respHandlers map[uint64]*responseHandler
+
+	closeOnce sync.Once
+	done      chan struct{}
+}
+
+var _ tanka.Sender = (*Conn)(nil)
+
+func newConn(conn net.Conn, peerID tanka.PeerID, send, recv *cipherState, handle func(*Conn, *msgjson.Message), onDone func(), log dex.Logger) *Conn {
+	c := &Conn{
+		conn:         conn,
+		log:          log,
+		handle:       handle,
+		onDone:       onDone,
+		send:         send,
+		recv:         recv,
+		respHandlers: make(map[uint64]*responseHandler),
+		done:         make(chan struct{}),
+	}
+	c.id.Store(peerID)
+	return c
+}
+
+// Send sends the message.
+func (c *Conn) Send(msg *msgjson.Message) error {
+	b, err := json.Marshal(msg)
+	if err != nil {
+		return fmt.Errorf("error marshaling message: %w", err)
+	}
+	return c.SendRaw(b)
+}
+
+// SendRaw sends the serialized message.
+func (c *Conn) SendRaw(b []byte) error {
+	c.writeMtx.Lock()
+	defer c.writeMtx.Unlock()
+	select {
+	case <-c.done:
+		return ErrConnectionClosed
+	default:
+	}
+	if err := writeFrame(c.conn, c.send.encrypt(nil, b)); err != nil {
+		go c.Disconnect()
+		return fmt.Errorf("error writing to %s: %w", c.conn.RemoteAddr(), err)
+	}
+	return nil
+}
+
+// Request sends the request. The response handler will be called with the
+// response, or with a timeout error if no response is received.
+func (c *Conn) Request(msg *msgjson.Message, respHandler func(*msgjson.Message)) error {
+	b, err := json.Marshal(msg)
+	if err != nil {
+		return fmt.Errorf("error marshaling request: %w", err)
+	}
+	return c.RequestRaw(msg.ID, b, respHandler)
+}
+
+// RequestRaw sends the serialized request with the specified message ID.
+func (c *Conn) RequestRaw(msgID uint64, rawMsg []byte, respHandler func(*msgjson.Message)) error {
+	c.respMtx.Lock()
+	c.respHandlers[msgID] = &responseHandler{
+		f: respHandler,
+		expire: time.AfterFunc(requestTimeout, func() {
+			if h := c.responseHandler(msgID); h != nil {
+				h.f(mj.MustResponse(msgID, nil, msgjson.NewError(mj.ErrTimeout, "request timed out")))
+			}
+		}),
+	}
+	c.respMtx.Unlock()
+	if err := c.SendRaw(rawMsg); err != nil {
+		if h := c.responseHandler(msgID); h != nil {
+			h.expire.Stop()
+		}
+		return err
+	}
+	return nil
+}
+
+// responseHandler removes and returns the handler for the message ID, or nil
+// if there is no handler.
+func (c *Conn) responseHandler(msgID uint64) *responseHandler {
+	c.respMtx.Lock()
+	defer c.respMtx.Unlock()
+	h, found := c.respHandlers[msgID]
+	if !found {
+		return nil
+	}
+	delete(c.respHandlers, msgID)
+	return h
+}
+
+// SetPeerID sets the peer ID.
+func (c *Conn) SetPeerID(peerID tanka.PeerID) {
+	c.id.Store(peerID)
+}
+
+// PeerID is the peer ID. The peer ID is the static key authenticated during
+// the handshake, unless it is changed with SetPeerID.
+func (c *Conn) PeerID() tanka.PeerID {
+	return c.id.Load().(tanka.PeerID)
+}
+
+// Disconnect closes the connection. Any outstanding requests are failed.
+func (c *Conn) Disconnect() {
+	c.closeOnce.Do(func() {
+		c.conn.Close()
+		c.writeMtx.Lock()
+		close(c.done)
+		c.writeMtx.Unlock()
+
+		c.respMtx.Lock()
+		handlers := c.respHandlers
+		c.respHandlers = make(map[uint64]*responseHandler)
+		c.respMtx.Unlock()
+		for msgID, h := range handlers {
+			h.expire.Stop()
+			h.f(mj.MustResponse(msgID, nil, msgjson.NewError(mj.ErrTimeout, "connection closed")))
+		}
+		if c.onDone != nil {
+			c.onDone()
+		}
+	})
+}
+
+// Done returns a channel that is closed when the connection is closed.
+func (c *Conn) Done() <-chan struct{} {
+	return c.done
+}
+
+// readLoop reads messages until the connection is closed. Responses are
+// routed to their response handlers, and all other messages are passed to the
+// Conn's message handler.
+func (c *Conn) readLoop() {
+	defer c.Disconnect()
+	for {
+		b, err := readFrame(c.conn)
+		if err != nil {
+			if !errors.Is(err, io.EOF) && !errors.Is(err, net.ErrClosed) {
+				c.log.Debugf("Error reading from %s: %v", c.conn.RemoteAddr(), err)
+			}
+			return
+		}
+		b, err = c.recv.decrypt(nil, b)
+		if err != nil {
+			c.log.Errorf("Message authentication failed for %s. Disconnecting", c.PeerID())
+			return
+		}
+		msg, err := msgjson.DecodeMessage(b)
+		if err != nil {
+			c.log.Errorf("Error decoding message from %s: %v", c.PeerID(), err)
+			continue
+		}
+		if msg.Type == msgjson.Response {
+			h := c.responseHandler(msg.ID)
+			if h == nil {
+				c.log.Debugf("No response handler for message ID %d from %s", msg.ID, c.PeerID())
+				continue
+			}
+			h.expire.Stop()
+			h.f(msg)
+			continue
+		}
+		c.handle(c, msg)
+	}
+}
@@ -0,0 +1,260 @@
+// This code is available on the terms of the project LICENSE.md file,
+// also available online at https://blueoakcouncil.org/license/1.0.0.
+
+package noise
+
+import (
+	"crypto/cipher"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+
+	"decred.org/dcrdex/tatanka/tanka"
+	"github.com/decred/dcrd/dcrec/secp256k1/v4"
+	"golang.org/x/crypto/chacha20poly1305"
+	"golang.org/x/crypto/hkdf"
+)
+
+// protocolName is hashed into the initial handshake state, so that peers
+// running a different handshake pattern or cipher suite cannot complete a
+// handshake with us.
+const protocolName = "Noise_IK_secp256k1_ChaChaPoly_SHA256"
+
+const (
+	pubKeyLength = secp256k1.PubKeyBytesLenCompressed
+	tagLength    = chacha20poly1305.Overhead
+	// initiatorMsgLength is the length of the initiator's handshake message,
+	// e || encrypted s || encrypted empty payload.
+	initiatorMsgLength = pubKeyLength + pubKeyLength + tagLength + tagLength
+	// responderMsgLength is the length of the responder's handshake message,
+	// e || encrypted empty payload.
+	responderMsgLength = pubKeyLength + tagLength
+)
+
+// cipherState is an AEAD and its nonce counter. A cipherState is not safe for
+// concurrent use.
+type cipherState struct {
+	aead cipher.AEAD
+	n    uint64
+}
+
+func newCipherState(k []byte) (*cipherState, error) {
+	aead, err := chacha20poly1305.New(k)
+	if err != nil {
+		return nil, err
+	}
+	return &cipherState{aead: aead}, nil
+}
+
+func (cs *cipherState) nonce() []byte {
+	var nonce [chacha20poly1305.NonceSize]byte
+	binary.LittleEndian.PutUint64(nonce[4:], cs.n)
+	cs.n++
+	return nonce[:]
+}
+
+func (cs *cipherState) encrypt(ad, plaintext []byte) []byte {
+	return cs.aead.Seal(nil, cs.nonce(), plaintext, ad)
+}
+
+func (cs *cipherState) decrypt(ad, ciphertext []byte) ([]byte, error) {
+	return cs.aead.Open(nil, cs.nonce(), ciphertext, ad)
+}
+
+// symmetricState is the Noise symmetric state used during the handshake.
+type symmetricState struct {
+	ck [32]byte
+	h  [32]byte
+	cs *cipherState
+}
+
+// newSymmetricState initializes the handshake state with the responder's
+// static public key, which the initiator knows ahead of time from the peer ID.
+func newSymmetricState(responderPub []byte) *symmetricState {
+	h := sha256.Sum256([]byte(protocolName))
+	s := &symmetricState{ck: h, h: h}
+	s.mixHash(responderPub)
+	return s
+}
+
+func (s *symmetricState) mixHash(b []byte) {
+	s.h = sha256.Sum256(append(s.h[:], b...))
+}
+
+func (s *symmetricState) mixKey(ikm []byte) error {
+	r := hkdf.New(sha256.New, ikm, s.ck[:], nil)
+	var k [32]byte
+	if _, err := io.ReadFull(r, s.ck[:]); err != nil {
+		return err
+	}
+	if _, err := io.ReadFull(r, k[:]); err != nil {
+		return err
+	}
+	cs, err := newCipherState(k[:])
+	if err != nil {
+		return err
+	}
+	s.cs = cs
+	return nil
+}
+
+func (s *symmetricState) encryptAndHash(plaintext []byte) []byte {
+	c := s.cs.encrypt(s.h[:], plaintext)
+	s.mixHash(c)
+	return c
+}
+
+func (s *symmetricState) decryptAndHash(ciphertext []byte) ([]byte, error) {
+	plaintext, err := s.cs.decrypt(s.h[:], ciphertext)
+	if err != nil {
+		return nil, err
+	}
+	s.mixHash(ciphertext)
+	return plaintext, nil
+}
+
+// split generates the transport cipher states. The first is used for messages
+// from the initiator, the second for messages from the responder.
+func (s *symmetricState) split() (initiator, responder *cipherState, err error) {
+	r := hkdf.New(sha256.New, nil, s.ck[:], nil)
+	var k1, k2 [32]byte
+	if _, err = io.ReadFull(r, k1[:]); err != nil {
+		return nil, nil, err
+	}
+	if _, err = io.ReadFull(r, k2[:]); err != nil {
+		return nil, nil, err
+	}
+	if initiator, err = newCipherState(k1[:]); err != nil {
+		return nil, nil, err
+	}
+	if responder, err = newCipherState(k2[:]); err != nil {
+		return nil, nil, err
+	}
+	return initiator, responder, nil
+}
+
+func dh(priv *secp256k1.PrivateKey, pub *secp256k1.PublicKey) []byte {
+	return secp256k1.GenerateSharedSecret(priv, pub)
+}
+
+// initiatorHandshake performs the initiator side of a Noise IK handshake with
+// the node identified by remoteID. The node's static key is the peer ID, so
+// a successful handshake authenticates the remote node. Returns the cipher
+// states for sending and receiving.
+func initiatorHandshake(rw io.ReadWriter, priv *secp256k1.PrivateKey, remoteID tanka.PeerID) (send, recv *cipherState, err error) {
+	rs, err := remoteID.PublicKey()
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid remote peer ID: %w", err)
+	}
+	e, err := secp256k1.GeneratePrivateKey()
+	if err != nil {
+		return nil, nil, err
+	}
+	s := newSymmetricState(remoteID[:])
+
+	// -> e, es, s, ss
+	ePub := e.PubKey().SerializeCompressed()
+	s.mixHash(ePub)
+	if err := s.mixKey(dh(e, rs)); err != nil {
+		return nil, nil, err
+	}
+	msg := append(ePub, s.encryptAndHash(priv.PubKey().SerializeCompressed())...)
+	if err := s.mixKey(dh(priv, rs)); err != nil {
+		return nil, nil, err
+	}
+	msg = append(msg, s.encryptAndHash(nil)...)
+	if err := writeFrame(rw, msg); err != nil {
+		return nil, nil, fmt.Errorf("error writing handshake: %w", err)
+	}
+
+	// <- e, ee, se
+	msg, err = readFrame(rw)
+	if err != nil {
+		return nil, nil, fmt.Errorf("error reading handshake response: %w", err)
+	}
+	if len(msg) != responderMsgLength {
+		return nil, nil, fmt.Errorf("wrong handshake response length %d", len(msg))
+	}
+	re, err := secp256k1.ParsePubKey(msg[:pubKeyLength])
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid remote ephemeral key: %w", err)
+	}
+	s.mixHash(msg[:pubKeyLength])
+	if err := s.mixKey(dh(e, re)); err != nil {
+		return nil, nil, err
+	}
+	if err := s.mixKey(dh(priv, re)); err != nil {
+		return nil, nil, err
+	}
+	if _, err := s.decryptAndHash(msg[pubKeyLength:]); err != nil {
+		return nil, nil, errors.New("handshake response authentication failed")
+	}
+	send, recv, err = s.split()
+	return send, recv, err
+}
+
+// responderHandshake performs the responder side of a Noise IK handshake. The
+// initiator's static key is learned from the first handshake message, and
+// allow is called with the corresponding peer ID before the handshake is
+// completed. Returns the cipher states for sending and receiving, along with
+// the authenticated peer ID of the initiator.
+func responderHandshake(rw io.ReadWriter, priv *secp256k1.PrivateKey, allow func(tanka.PeerID) bool) (send, recv *cipherState, peerID tanka.PeerID, err error) {
+	msg, err := readFrame(rw)
+	if err != nil {
+		return nil, nil, peerID, fmt.Errorf("error reading handshake: %w", err)
+	}
+	if len(msg) != initiatorMsgLength {
+		return nil, nil, peerID, fmt.Errorf("wrong handshake length %d", len(msg))
+	}
+	s := newSymmetricState(priv.PubKey().SerializeCompressed())
+
+	// -> e, es, s, ss
+	re, err := secp256k1.ParsePubKey(msg[:pubKeyLength])
+	if err != nil {
+		return nil, nil, peerID, fmt.Errorf("invalid remote ephemeral key: %w", err)
+	}
+	s.mixHash(msg[:pubKeyLength])
+	if err := s.mixKey(dh(priv, re)); err != nil {
+		return nil, nil, peerID, err
+	}
+	msg = msg[pubKeyLength:]
+	rsB, err := s.decryptAndHash(msg[:pubKeyLength+tagLength])
+	if err != nil {
+		return nil, nil, peerID, errors.New("handshake decryption failed")
+	}
+	rs, err := secp256k1.ParsePubKey(rsB)
+	if err != nil {
+		return nil, nil, peerID, fmt.Errorf("invalid remote static key: %w", err)
+	}
+	if err := s.mixKey(dh(priv, rs)); err != nil {
+		return nil, nil, peerID, err
+	}
+	if _, err := s.decryptAndHash(msg[pubKeyLength+tagLength:]); err != nil {
+		return nil, nil, peerID, errors.New("handshake authentication failed")
+	}
+	copy(peerID[:], rs.SerializeCompressed())
+	if !allow(peerID) {
+		return nil, nil, peerID, fmt.Errorf("peer %s not allowed", peerID)
+	}
+
+	// <- e, ee, se
+	e, err := secp256k1.GeneratePrivateKey()
+	if err != nil {
+		return nil, nil, peerID, err
+	}
+	ePub := e.PubKey().SerializeCompressed()
+	s.mixHash(ePub)
+	if err := s.mixKey(dh(e, re)); err != nil {
+		return nil, nil, peerID, err
+	}
+	if err := s.mixKey(dh(e, rs)); err != nil {
+		return nil, nil, peerID, err
+	}
+	if err := writeFrame(rw, append(ePub, s.encryptAndHash(nil)...)); err != nil {
+		return nil, nil, peerID, fmt.Errorf("error writing handshake response: %w", err)
+	}
+	recv, send, err = s.split()
+	return send, recv, peerID, err
+}
@@ -0,0 +1,242 @@
+package noise
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"testing"
+	"time"
+
+	"decred.org/dcrdex/dex"
+	"decred.org/dcrdex/dex/msgjson"
+	"decred.org/dcrdex/tatanka/mj"
+	"decred.org/dcrdex/tatanka/tanka"
+	"github.com/decred/dcrd/dcrec/secp256k1/v4"
+)
+
+var tLogger = dex.StdOutLogger("T", dex.LevelInfo)
+
+func tNewKey(t *testing.T) (*secp256k1.PrivateKey, tanka.PeerID) {
+	t.Helper()
+	priv, err := secp256k1.GeneratePrivateKey()
+	if err != nil {
+		t.Fatalf("GeneratePrivateKey error: %v", err)
+	}
+	var peerID tanka.PeerID
+	copy(peerID[:], priv.PubKey().SerializeCompressed())
+	return priv, peerID
+}
+
+type handshakeResult struct {
+	send, recv *cipherState
+	peerID     tanka.PeerID
+	err        error
+}
+
+func tHandshake(t *testing.T, initPriv, respPriv *secp256k1.PrivateKey, respID tanka.PeerID, allow func(tanka.PeerID) bool) (init, resp *handshakeResult) {
+	t.Helper()
+	c1, c2 := net.Pipe()
+	defer c1.Close()
+	defer c2.Close()
+	respC := make(chan *handshakeResult, 1)
+	go func() {
+		send, recv, peerID, err := responderHandshake(c2, respPriv, allow)
+		if err != nil {
+			c2.Close()
+		}
+		respC <- &handshakeResult{send, recv, peerID, err}
+	}()
+	send, recv, err := initiatorHandshake(c1, initPriv, respID)
+	if err != nil {
+		c1.Close()
+	}
+	return &handshakeResult{send: send, recv: recv, err: err}, <-respC
+}
+
+func TestHandshake(t *testing.T) {
+	initPriv, initID := tNewKey(t)
+	respPriv, respID := tNewKey(t)
+	allowAll := func(tanka.PeerID) bool { return true }
+
+	init, resp := tHandshake(t, initPriv, respPriv, respID, allowAll)
+	if init.err != nil || resp.err != nil {
+		t.Fatalf("handshake errors: initiator = %v, responder = %v", init.err, resp.err)
+	}
+	if resp.peerID != initID {
+		t.Fatalf("responder learned wrong peer ID")
+	}
+	// Each side can read the other's messages.
+	for i := 0; i < 3; i++ {
+		b, err := resp.recv.decrypt(nil, init.send.encrypt(nil, []byte("ping")))
+		if err != nil || string(b) != "ping" {
+			t.Fatalf("responder decrypt error: %v", err)
+		}
+		b, err = init.recv.decrypt(nil, resp.send.encrypt(nil, []byte("pong")))
+		if err != nil || string(b) != "pong" {
+			t.Fatalf("initiator decrypt error: %v", err)
+		}
+	}
+	// Out-of-order or tampered frames fail.
+	init.send.encrypt(nil, []byte("skipped"))
+	if _, err := resp.recv.decrypt(nil, init.send.encrypt(nil, []byte("ping"))); err == nil {
+		t.Fatalf("no error for skipped nonce")
+	}
+
+	// Not allowed.
+	init, resp = tHandshake(t, initPriv, respPriv, respID, func(peerID tanka.PeerID) bool { return peerID != initID })
+	if init.err == nil || resp.err == nil {
+		t.Fatalf("no error for disallowed initiator")
+	}
+
+	// Initiator expects a different responder.
+	_, otherID := tNewKey(t)
+	init, resp = tHandshake(t, initPriv, respPriv, otherID, allowAll)
+	if init.err == nil || resp.err == nil {
+		t.Fatalf("no error for wrong responder peer ID")
+	}
+}
+
+type tCore struct {
+	msgs chan *msgjson.Message
+}
+
+func (c *tCore) HandleMessage(cl tanka.Sender, msg *msgjson.Message) *msgjson.Error {
+	switch msg.Route {
+	case "echo":
+		var s string
+		msg.Unmarshal(&s)
+		cl.Send(mj.MustResponse(msg.ID, s, nil))
+	case "fail":
+		return msgjson.NewError(mj.ErrBadRequest, "failed")
+	default:
+		c.msgs <- msg
+	}
+	return nil
+}
+
+func tNewServer(t *testing.T, ctx context.Context, priv *secp256k1.PrivateKey, allow func(tanka.PeerID) bool) (*Server, *tCore) {
+	t.Helper()
+	core := &tCore{msgs: make(chan *msgjson.Message, 1)}
+	srv, err := NewServer(&ServerConfig{
+		ListenAddrs: []string{"127.0.0.1:0"},
+		PrivateKey:  priv,
+		Allow:       allow,
+		Logger:      tLogger,
+	}, core)
+	if err != nil {
+		t.Fatalf("NewServer error: %v", err)
+	}
+	if _, err := srv.Connect(ctx); err != nil {
+		t.Fatalf("Connect error: %v", err)
+	}
+	return srv, core
+}
+
+func tRequest(t *testing.T, cl tanka.Sender, msg *msgjson.Message) *msgjson.Message {
+	t.Helper()
+	respC := make(chan *msgjson.Message, 1)
+	if err := cl.Request(msg, func(resp *msgjson.Message) { respC <- resp }); err != nil {
+		t.Fatalf("Request error: %v", err)
+	}
+	select {
+	case resp := <-respC:
+		return resp
+	case <-time.After(time.Second * 5):
+		t.Fatalf("no response to %s request", msg.Route)
+	}
+	return nil
+}
+
+func TestServer(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	privA, idA := tNewKey(t)
+	privB, idB := tNewKey(t)
+	srvA, coreA := tNewServer(t, ctx, privA, func(peerID tanka.PeerID) bool { return peerID == idB })
+	srvB, _ := tNewServer(t, ctx, privB, func(tanka.PeerID) bool { return false })
+
+	cfg, _ := json.Marshal(&RemoteNodeConfig{Addr: srvA.Addrs()[0].String()})
+	outboundMsgs := make(chan *msgjson.Message, 1)
+	disconnected := make(chan struct{})
+	cl, err := srvB.ConnectBootNode(ctx, cfg, idA, func(cl tanka.Sender, msg *msgjson.Message) {
+		outboundMsgs <- msg
+	}, func() { close(disconnected) })
+	if err != nil {
+		t.Fatalf("ConnectBootNode error: %v", err)
+	}
+	if cl.PeerID() != idA {
+		t.Fatalf("wrong outbound peer ID")
+	}
+
+	// Request and response.
+	resp := tRequest(t, cl, mj.MustRequest("echo", "hello"))
+	var s string
+	if err := resp.UnmarshalResult(&s); err != nil || s != "hello" {
+		t.Fatalf("wrong echo response %q, err = %v", s, err)
+	}
+
+	// Handler errors are returned for requests.
+	resp = tRequest(t, cl, mj.MustRequest("fail", nil))
+	if err := resp.UnmarshalResult(&s); err == nil {
+		t.Fatalf("no error for failed request")
+	}
+
+	// Notifications from the outbound side.
+	if err := cl.Send(mj.MustNotification("note", nil)); err != nil {
+		t.Fatalf("Send error: %v", err)
+	}
+	var inbound tanka.Sender
+	select {
+	case <-coreA.msgs:
+	case <-time.After(time.Second * 5):
+		t.Fatalf("notification not received")
+	}
+	srvA.mtx.Lock()
+	for c := range srvA.conns {
+		inbound = c
+	}
+	srvA.mtx.Unlock()
+	if inbound == nil || inbound.PeerID() != idB {
+		t.Fatalf("inbound connection not tracked with authenticated peer ID")
+	}
+
+	// And from the inbound side.
+	if err := inbound.Send(mj.MustNotification("note", nil)); err != nil {
+		t.Fatalf("inbound Send error: %v", err)
+	}
+	select {
+	case msg := <-outboundMsgs:
+		if msg.Route != "note" {
+			t.Fatalf("wrong route %q", msg.Route)
+		}
+	case <-time.After(time.Second * 5):
+		t.Fatalf("notification not received from inbound connection")
+	}
+
+	// Unlisted peers can't connect.
+	cfg, _ = json.Marshal(&RemoteNodeConfig{Addr: srvB.Addrs()[0].String()})
+	if _, err := srvA.ConnectBootNode(ctx, cfg, idB, func(tanka.Sender, *msgjson.Message) {}, func() {}); err == nil {
+		t.Fatalf("no error connecting to a node that doesn't allow us")
+	}
+
+	// Disconnecting the inbound side is detected by the outbound side, and
+	// outstanding requests are failed.
+	respC := make(chan *msgjson.Message, 1)
+	if err := cl.Request(mj.MustRequest("note", nil), func(resp *msgjson.Message) { respC <- resp }); err != nil {
+		t.Fatalf("Request error: %v", err)
+	}
+	<-coreA.msgs
+	inbound.Disconnect()
+	select {
+	case <-disconnected:
+	case <-time.After(time.Second * 5):
+		t.Fatalf("disconnect not detected")
+	}
+	if resp := <-respC; resp.UnmarshalResult(&s) == nil {
+		t.Fatalf("no error for request outstanding at disconnect")
+	}
+	if err := cl.Send(mj.MustNotification("note", nil)); err == nil {
+		t.Fatalf("no error sending on closed connection")
+	}
+}
@@ -0,0 +1,262 @@
+// This code is available on the terms of the project LICENSE.md file,
+// also available online at https://blueoakcouncil.org/license/1.0.0.
+
+// Package noise implements a tatanka node-to-node transport over plain TCP.
+// Connections are encrypted and mutually authenticated with a Noise IK
+// handshake, where the static keys are the nodes' secp256k1 peer ID keys.
+// After the handshake, msgjson messages are exchanged as length-prefixed,
+// ChaCha20-Poly1305-encrypted frames.
+package noise
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+
+	"decred.org/dcrdex/dex"
+	"decred.org/dcrdex/dex/msgjson"
+	"decred.org/dcrdex/tatanka/mj"
+	"decred.org/dcrdex/tatanka/tanka"
+	"github.com/decred/dcrd/dcrec/secp256k1/v4"
+)
+
+// Protocol is the BootNode protocol identifier for the noise transport.
+const Protocol = "tcp"
+
+// TankaCore handles inbound messages.
+type TankaCore interface {
+	HandleMessage(tanka.Sender, *msgjson.Message) *msgjson.Error
+}
+
+// RemoteNodeConfig is the BootNode configuration for the noise transport.
+type RemoteNodeConfig struct {
+	Addr string `json:"addr"`
+}
+
+// ServerConfig is the configuration for the Server.
+type ServerConfig struct {
+	// ListenAddrs are the addresses on which to accept connections. If there
+	// are no ListenAddrs, the Server can still be used to make outbound
+	// connections.
+	ListenAddrs []string
+	// PrivateKey is the node's private key. The corresponding public key is
+	// the node's peer ID.
+	PrivateKey *secp256k1.PrivateKey
+	// Allow is called with the authenticated peer ID of inbound connections.
+	// Connections are refused unless Allow returns true.
+	Allow  func(tanka.PeerID) bool
+	Logger dex.Logger
+}
+
+// Server accepts inbound noise connections and makes outbound connections to
+// boot nodes.
+type Server struct {
+	cfg  *ServerConfig
+	core TankaCore
+	log  dex.Logger
+
+	mtx       sync.Mutex
+	listeners []net.Listener
+	conns     map[*Conn]struct{}
+}
+
+// NewServer is a constructor for a Server.
+func NewServer(cfg *ServerConfig, core TankaCore) (*Server, error) {
+	if cfg.PrivateKey == nil {
+		return nil, errors.New("no private key")
+	}
+	if cfg.Allow == nil {
+		return nil, errors.New("no Allow function")
+	}
+	return &Server{
+		cfg:   cfg,
+		core:  core,
+		log:   cfg.Logger,
+		conns: make(map[*Conn]struct{}),
+	}, nil
+}
+
+// Connect starts listening on the configured addresses. Connect is part of the
+// dex.Connector interface.
+func (s *Server) Connect(ctx context.Context) (*sync.WaitGroup, error) {
+	var wg sync.WaitGroup
+	var lc net.ListenConfig
+	listeners := make([]net.Listener, 0, len(s.cfg.ListenAddrs))
+	for _, addr := range s.cfg.ListenAddrs {
+		l, err := lc.Listen(ctx, "tcp", addr)
+		if err != nil {
+			for _, l := range listeners {
+				l.Close()
+			}
+			return nil, fmt.Errorf("error listening on %s: %w", addr, err)
+		}
+		s.log.Infof("Listening for noise connections on %s", l.Addr())
+		listeners = append(listeners, l)
+	}
+	s.mtx.Lock()
+	s.listeners = listeners
+	s.mtx.Unlock()
+
+	for _, l := range listeners {
+		wg.Add(1)
+		go func(l net.Listener) {
+			defer wg.Done()
+			s.acceptLoop(ctx, l, &wg)
+		}(l)
+	}
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		<-ctx.Done()
+		s.mtx.Lock()
+		for _, l := range s.listeners {
+			l.Close()
+		}
+		conns := make([]*Conn, 0, len(s.conns))
+		for c := range s.conns {
+			conns = append(conns, c)
+		}
+		s.conns = nil
+		s.mtx.Unlock()
+		for _, c := range conns {
+			c.Disconnect()
+		}
+	}()
+
+	return &wg, nil
+}
+
+// Addrs are the addresses of the listeners.
+func (s *Server) Addrs() []net.Addr {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	addrs := make([]net.Addr, 0, len(s.listeners))
+	for _, l := range s.listeners {
+		addrs = append(addrs, l.Addr())
+	}
+	return addrs
+}
+
+func (s *Server) acceptLoop(ctx context.Context, l net.Listener, wg *sync.WaitGroup) {
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			if ctx.Err() == nil && !errors.Is(err, net.ErrClosed) {
+				s.log.Errorf("Accept error on %s: %v", l.Addr(), err)
+			}
+			return
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			s.handleInbound(conn)
+		}()
+	}
+}
+
+func (s *Server) handleInbound(conn net.Conn) {
+	conn.SetDeadline(time.Now().Add(handshakeTimeout))
+	send, recv, peerID, err := responderHandshake(conn, s.cfg.PrivateKey, s.cfg.Allow)
+	if err != nil {
+		s.log.Debugf("Inbound handshake with %s failed: %v", conn.RemoteAddr(), err)
+		conn.Close()
+		return
+	}
+	conn.SetDeadline(time.Time{})
+
+	s.log.Debugf("Accepted noise connection from %s at %s", peerID, conn.RemoteAddr())
+	var c *Conn
+	c = newConn(conn, peerID, send, recv, s.handleMessage, func() { s.removeConn(c) }, s.log)
+	if !s.addConn(c) {
+		c.Disconnect()
+		return
+	}
+	c.readLoop()
+}
+
+// handleMessage handles a request or notification on an inbound connection.
+func (s *Server) handleMessage(c *Conn, msg *msgjson.Message) {
+	msgErr := s.core.HandleMessage(c, msg)
+	if msgErr == nil {
+		return
+	}
+	if msg.Type != msgjson.Request {
+		s.log.Debugf("Error handling %q notification from %s: %s", msg.Route, c.PeerID(), msgErr.Message)
+		return
+	}
+	if err := c.Send(mj.MustResponse(msg.ID, nil, msgErr)); err != nil {
+		s.log.Errorf("Error sending error response to %s: %v", c.PeerID(), err)
+	}
+}
+
+// addConn adds the connection to the map of connections, which are closed on
+// shutdown. addConn returns false if the server is shutting down.
+func (s *Server) addConn(c *Conn) bool {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	if s.conns == nil {
+		return false
+	}
+	s.conns[c] = struct{}{}
+	return true
+}
+
+func (s *Server) removeConn(c *Conn) {
+	s.mtx.Lock()
+	delete(s.conns, c)
+	s.mtx.Unlock()
+}
+
+// ConnectBootNode connects to the boot node with the specified peer ID. The
+// handshake authenticates the node with its peer ID, so no TLS certificate is
+// needed.
+func (s *Server) ConnectBootNode(
+	ctx context.Context,
+	rawCfg json.RawMessage,
+	peerID tanka.PeerID,
+	handleMessage func(cl tanka.Sender, msg *msgjson.Message),
+	disconnect func(),
+) (tanka.Sender, error) {
+
+	var n RemoteNodeConfig
+	if err := json.Unmarshal(rawCfg, &n); err != nil {
+		return nil, fmt.Errorf("error reading boot node configuration: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, handshakeTimeout)
+	defer cancel()
+
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "tcp", n.Addr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to boot node %q: %w", n.Addr, err)
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+	send, recv, err := initiatorHandshake(conn, s.cfg.PrivateKey, peerID)
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("handshake with boot node %q failed: %w", n.Addr, err)
+	}
+	conn.SetDeadline(time.Time{})
+
+	var c *Conn
+	c = newConn(conn, peerID, send, recv, func(c *Conn, msg *msgjson.Message) {
+		handleMessage(c, msg)
+	}, func() {
+		s.removeConn(c)
+		disconnect()
+	}, s.log)
+	if !s.addConn(c) {
+		conn.Close()
+		return nil, errors.New("server is shutting down")
+	}
+	go c.readLoop()
+	return c, nil
+}